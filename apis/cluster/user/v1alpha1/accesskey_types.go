@@ -31,6 +31,11 @@ import (
 type AccessKeySpec struct {
 	xpv2.ClusterManagedResourceSpec `json:",inline"`
 	ForProvider                     userv1alpha1common.AccessKeyParameters `json:"forProvider"`
+
+	// StatusMirror is a ConfigMap to mirror the conditions and non-secret
+	// status of this AccessKey to.
+	// +optional
+	StatusMirror *userv1alpha1common.StatusMirror `json:"statusMirror,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -0,0 +1,31 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	userv1alpha1common "github.com/statnett/provider-cloudian/apis/common/user/v1alpha1"
+)
+
+// GetAccessKeyParameters returns the configurable fields of this AccessKey.
+func (mg *AccessKey) GetAccessKeyParameters() *userv1alpha1common.AccessKeyParameters {
+	return &mg.Spec.ForProvider
+}
+
+// GetAccessKeyObservation returns the observable fields of this AccessKey.
+func (mg *AccessKey) GetAccessKeyObservation() *userv1alpha1common.AccessKeyObservation {
+	return &mg.Status.AtProvider
+}
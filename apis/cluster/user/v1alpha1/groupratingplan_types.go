@@ -0,0 +1,68 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"reflect"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
+
+	userv1alpha1common "github.com/statnett/provider-cloudian/apis/common/user/v1alpha1"
+)
+
+// A GroupRatingPlanSpec defines the desired state of a GroupRatingPlan.
+type GroupRatingPlanSpec struct {
+	xpv2.ClusterManagedResourceSpec `json:",inline"`
+	ForProvider                     userv1alpha1common.GroupRatingPlanParameters `json:"forProvider"`
+}
+
+// +kubebuilder:object:root=true
+
+// GroupRatingPlan represents the rating plan assigned to a Cloudian group, within a region.
+// +kubebuilder:printcolumn:name="READY",type="string",JSONPath=".status.conditions[?(@.type=='Ready')].status"
+// +kubebuilder:printcolumn:name="SYNCED",type="string",JSONPath=".status.conditions[?(@.type=='Synced')].status"
+// +kubebuilder:printcolumn:name="EXTERNAL-NAME",type="string",JSONPath=".metadata.annotations.crossplane\\.io/external-name"
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,categories={crossplane,managed,cloudian}
+type GroupRatingPlan struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GroupRatingPlanSpec                      `json:"spec"`
+	Status userv1alpha1common.GroupRatingPlanStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// GroupRatingPlanList contains a list of GroupRatingPlan
+type GroupRatingPlanList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GroupRatingPlan `json:"items"`
+}
+
+// GroupRatingPlan type metadata.
+var (
+	GroupRatingPlanKind             = reflect.TypeOf(GroupRatingPlan{}).Name()
+	GroupRatingPlanGroupKind        = schema.GroupKind{Group: MetadataGroup, Kind: GroupRatingPlanKind}.String()
+	GroupRatingPlanKindAPIVersion   = GroupRatingPlanKind + "." + SchemeGroupVersion.String()
+	GroupRatingPlanGroupVersionKind = SchemeGroupVersion.WithKind(GroupRatingPlanKind)
+)
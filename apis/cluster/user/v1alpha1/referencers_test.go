@@ -0,0 +1,169 @@
+package v1alpha1
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	userv1alpha1common "github.com/statnett/provider-cloudian/apis/common/user/v1alpha1"
+)
+
+var teamLabels = map[string]string{"team": "storage"}
+
+func newClient(t *testing.T, objs ...client.Object) client.Client {
+	t.Helper()
+	s := runtime.NewScheme()
+	if err := SchemeBuilder.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	return fake.NewClientBuilder().WithScheme(s).WithObjects(objs...).Build()
+}
+
+func newGroup(externalName string) *Group {
+	g := &Group{ObjectMeta: metav1.ObjectMeta{Name: "qa", Labels: teamLabels}}
+	if externalName != "" {
+		meta.SetExternalName(g, externalName)
+	}
+	return g
+}
+
+func newUser(externalName string) *User {
+	u := &User{
+		ObjectMeta: metav1.ObjectMeta{Name: "alice", Labels: teamLabels},
+		Spec: UserSpec{
+			ForProvider: userv1alpha1common.UserParameters{GroupID: "QA"},
+		},
+	}
+	if externalName != "" {
+		meta.SetExternalName(u, externalName)
+	}
+	return u
+}
+
+func TestGroupQualityOfServiceLimitsResolveReferences(t *testing.T) {
+	cases := map[string]struct {
+		group     *Group
+		params    userv1alpha1common.GroupQualityOfServiceLimitsParameters
+		want      string
+		wantErrIn string
+	}{
+		"Reference": {
+			group:  newGroup("QA"),
+			params: userv1alpha1common.GroupQualityOfServiceLimitsParameters{GroupIDRef: &xpv2.Reference{Name: "qa"}},
+			want:   "QA",
+		},
+		"Selector": {
+			group:  newGroup("QA"),
+			params: userv1alpha1common.GroupQualityOfServiceLimitsParameters{GroupIDSelector: &xpv2.Selector{MatchLabels: teamLabels}},
+			want:   "QA",
+		},
+		"GroupNotReady": {
+			group:     newGroup(""),
+			params:    userv1alpha1common.GroupQualityOfServiceLimitsParameters{GroupIDRef: &xpv2.Reference{Name: "qa"}},
+			wantErrIn: "may not yet be ready",
+		},
+		"GroupMissing": {
+			group:     &Group{ObjectMeta: metav1.ObjectMeta{Name: "other"}},
+			params:    userv1alpha1common.GroupQualityOfServiceLimitsParameters{GroupIDRef: &xpv2.Reference{Name: "qa"}},
+			wantErrIn: "cannot get referenced resource",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			mg := &GroupQualityOfServiceLimits{
+				ObjectMeta: metav1.ObjectMeta{Name: "qa"},
+				Spec:       GroupQualityOfServiceLimitsSpec{ForProvider: tc.params},
+			}
+			err := mg.ResolveReferences(context.Background(), newClient(t, tc.group))
+			if tc.wantErrIn != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErrIn) {
+					t.Fatalf("ResolveReferences(...): want error containing %q, got %v", tc.wantErrIn, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveReferences(...): %v", err)
+			}
+			if got := mg.Spec.ForProvider.GroupID; got != tc.want {
+				t.Errorf("GroupID: want %q, got %q", tc.want, got)
+			}
+			if mg.Spec.ForProvider.GroupIDRef == nil || mg.Spec.ForProvider.GroupIDRef.Name != "qa" {
+				t.Errorf("GroupIDRef: want reference to %q, got %v", "qa", mg.Spec.ForProvider.GroupIDRef)
+			}
+		})
+	}
+}
+
+func TestAccessKeyResolveReferences(t *testing.T) {
+	cases := map[string]struct {
+		user      *User
+		params    userv1alpha1common.AccessKeyParameters
+		wantErrIn string
+	}{
+		"Reference": {
+			user:   newUser("alice"),
+			params: userv1alpha1common.AccessKeyParameters{UserIDRef: &xpv2.Reference{Name: "alice"}},
+		},
+		"Selector": {
+			user:   newUser("alice"),
+			params: userv1alpha1common.AccessKeyParameters{UserIDSelector: &xpv2.Selector{MatchLabels: teamLabels}},
+		},
+		"UserNotReady": {
+			user:      newUser(""),
+			params:    userv1alpha1common.AccessKeyParameters{UserIDRef: &xpv2.Reference{Name: "alice"}},
+			wantErrIn: `User "alice" has no external-name yet`,
+		},
+		"UserNotReadySelected": {
+			user:      newUser(""),
+			params:    userv1alpha1common.AccessKeyParameters{UserIDSelector: &xpv2.Selector{MatchLabels: teamLabels}},
+			wantErrIn: `User "alice" has no external-name yet`,
+		},
+		"UserWithoutGroup": {
+			user: func() *User {
+				u := newUser("alice")
+				u.Spec.ForProvider.GroupID = ""
+				return u
+			}(),
+			params:    userv1alpha1common.AccessKeyParameters{UserIDRef: &xpv2.Reference{Name: "alice"}},
+			wantErrIn: `User "alice" has no groupId yet`,
+		},
+		"UserMissing": {
+			user:      &User{ObjectMeta: metav1.ObjectMeta{Name: "other"}},
+			params:    userv1alpha1common.AccessKeyParameters{UserIDRef: &xpv2.Reference{Name: "alice"}},
+			wantErrIn: "unable to resolve spec.forProvider.userId: cannot get referenced resource",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			mg := &AccessKey{
+				ObjectMeta: metav1.ObjectMeta{Name: "alice"},
+				Spec:       AccessKeySpec{ForProvider: tc.params},
+			}
+			err := mg.ResolveReferences(context.Background(), newClient(t, tc.user))
+			if tc.wantErrIn != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErrIn) {
+					t.Fatalf("ResolveReferences(...): want error containing %q, got %v", tc.wantErrIn, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveReferences(...): %v", err)
+			}
+			if got := mg.Spec.ForProvider.UserID; got != "alice" {
+				t.Errorf("UserID: want %q, got %q", "alice", got)
+			}
+			if got := mg.Spec.ForProvider.GroupID; got != "QA" {
+				t.Errorf("GroupID: want %q, got %q", "QA", got)
+			}
+		})
+	}
+}
@@ -30,6 +30,11 @@ import (
 type UserSpec struct {
 	xpv2.ClusterManagedResourceSpec `json:",inline"`
 	ForProvider                     userv1alpha1common.UserParameters `json:"forProvider"`
+
+	// StatusMirror is a ConfigMap to mirror the conditions and non-secret
+	// status of this User to.
+	// +optional
+	StatusMirror *userv1alpha1common.StatusMirror `json:"statusMirror,omitempty"`
 }
 
 // +kubebuilder:object:root=true
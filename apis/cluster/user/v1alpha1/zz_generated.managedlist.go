@@ -46,6 +46,24 @@ func (l *GroupQualityOfServiceLimitsList) GetItems() []resource.Managed {
 	return items
 }
 
+// GetItems of this GroupRatingPlanList.
+func (l *GroupRatingPlanList) GetItems() []resource.Managed {
+	items := make([]resource.Managed, len(l.Items))
+	for i := range l.Items {
+		items[i] = &l.Items[i]
+	}
+	return items
+}
+
+// GetItems of this IAMUserList.
+func (l *IAMUserList) GetItems() []resource.Managed {
+	items := make([]resource.Managed, len(l.Items))
+	for i := range l.Items {
+		items[i] = &l.Items[i]
+	}
+	return items
+}
+
 // GetItems of this UserList.
 func (l *UserList) GetItems() []resource.Managed {
 	items := make([]resource.Managed, len(l.Items))
@@ -44,6 +44,7 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
 		&ProviderConfig{}, &ProviderConfigList{},
 		&ProviderConfigUsage{}, &ProviderConfigUsageList{},
+		&ProviderStatus{}, &ProviderStatusList{},
 	)
 
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
@@ -29,6 +29,7 @@ import (
 
 // A ProviderConfig configures a Cloudian provider.
 // +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="HEALTHY",type="string",JSONPath=".status.conditions[?(@.type=='Healthy')].status"
 // +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
 // +kubebuilder:printcolumn:name="SECRET-NAME",type="string",JSONPath=".spec.authHeader.secretRef.name",priority=1
 // +kubebuilder:resource:scope=Cluster
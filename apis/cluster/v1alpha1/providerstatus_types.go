@@ -0,0 +1,145 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"reflect"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ProviderStatusName is the name of the single ProviderStatus maintained by
+// the provider.
+const ProviderStatusName = "provider-cloudian"
+
+// A KindSummary counts the managed resources of a kind.
+type KindSummary struct {
+	// Kind and API group of the managed resources.
+	Kind string `json:"kind"`
+
+	// Total is the number of managed resources of the kind.
+	Total int64 `json:"total"`
+
+	// Ready is the number of them whose Ready condition is true.
+	Ready int64 `json:"ready"`
+
+	// Synced is the number of them whose Synced condition is true.
+	Synced int64 `json:"synced"`
+}
+
+// A ProviderConfigHealth is the result of the last health check of a
+// ProviderConfig.
+type ProviderConfigHealth struct {
+	// Kind and API group of the ProviderConfig.
+	Kind string `json:"kind"`
+
+	// Namespace of the ProviderConfig, if namespaced.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Name of the ProviderConfig.
+	Name string `json:"name"`
+
+	// Healthy is the status of the Healthy condition of the ProviderConfig,
+	// which is Unknown until it has been checked.
+	Healthy corev1.ConditionStatus `json:"healthy"`
+
+	// Message of the Healthy condition, explaining why the Cloudian admin
+	// API cannot be reached.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// LastTransitionTime of the Healthy condition.
+	// +optional
+	LastTransitionTime *metav1.Time `json:"lastTransitionTime,omitempty"`
+}
+
+// An ErrorSummary counts the managed resources of a kind that failed to sync
+// for the same reason.
+type ErrorSummary struct {
+	// Kind and API group of the managed resources.
+	Kind string `json:"kind"`
+
+	// Reason of their Synced condition.
+	Reason string `json:"reason"`
+
+	// Count is the number of managed resources failing for the reason.
+	Count int64 `json:"count"`
+
+	// Message of the most recent of their Synced conditions.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// LastTransitionTime of the most recent of their Synced conditions.
+	// +optional
+	LastTransitionTime *metav1.Time `json:"lastTransitionTime,omitempty"`
+}
+
+// A ProviderStatusStatus summarizes the state of the provider.
+type ProviderStatusStatus struct {
+	// RefreshTime is when the summary was last refreshed.
+	// +optional
+	RefreshTime *metav1.Time `json:"refreshTime,omitempty"`
+
+	// Kinds counts the managed resources of each kind.
+	// +optional
+	Kinds []KindSummary `json:"kinds,omitempty"`
+
+	// ProviderConfigs are the health of each ProviderConfig.
+	// +optional
+	ProviderConfigs []ProviderConfigHealth `json:"providerConfigs,omitempty"`
+
+	// Errors are the most common reasons managed resources fail to sync,
+	// most common first.
+	// +optional
+	Errors []ErrorSummary `json:"errors,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// A ProviderStatus summarizes the managed resources, the health of the
+// ProviderConfigs and the errors of the provider. The provider maintains a
+// single one, named provider-cloudian, when enabled.
+// +kubebuilder:printcolumn:name="REFRESHED",type="date",JSONPath=".status.refreshTime"
+// +kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,categories={crossplane,provider,cloudian}
+type ProviderStatus struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status ProviderStatusStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ProviderStatusList contains a list of ProviderStatus.
+type ProviderStatusList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ProviderStatus `json:"items"`
+}
+
+// ProviderStatus type metadata.
+var (
+	ProviderStatusKind             = reflect.TypeOf(ProviderStatus{}).Name()
+	ProviderStatusGroupKind        = schema.GroupKind{Group: Group, Kind: ProviderStatusKind}.String()
+	ProviderStatusKindAPIVersion   = ProviderStatusKind + "." + SchemeGroupVersion.String()
+	ProviderStatusGroupVersionKind = SchemeGroupVersion.WithKind(ProviderStatusKind)
+)
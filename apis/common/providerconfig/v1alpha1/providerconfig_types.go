@@ -20,14 +20,146 @@ package v1alpha1
 
 import (
 	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // A ProviderConfigSpec defines the desired state of a ProviderConfig.
+// +kubebuilder:validation:XValidation:rule="!has(self.claimOwnership) || !self.claimOwnership || has(self.clusterId)",message="clusterId is required when claimOwnership is true"
 type ProviderConfigSpec struct {
-	// Endpoint is an url with protocol, hostname and port (no slash at the end) of the Cloudian API.
+	// Endpoint is an url with protocol, hostname and port of the Cloudian API,
+	// optionally with the path it is served at. A slash at the end is ignored.
 	Endpoint string `json:"endpoint"`
+	// PathPrefix is joined to the path of Endpoint and of each of Regions,
+	// as when the admin API is served behind a reverse proxy under a path
+	// such as /cloudian-admin.
+	// +optional
+	PathPrefix string `json:"pathPrefix,omitempty"`
+	// Regions are the admin API endpoints of the regions of the Cloudian
+	// installation, by region name, when each region has its own. Resources
+	// in the default region use Endpoint. When set, resources in regions not
+	// listed here are refused. When unset, Endpoint is used for every region.
+	// +optional
+	Regions map[string]string `json:"regions,omitempty"`
 	// AuthHeader is the value of the Authorization header in requests to Cloudian API.
+	// It may be read from a Secret, an environment variable or a mounted
+	// file. Trailing newlines are trimmed.
 	AuthHeader ProviderCredentials `json:"authHeader"`
+	// RequestsPerSecond limits the rate of requests sent to the Cloudian API.
+	// Unlimited if unspecified.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	RequestsPerSecond *int32 `json:"requestsPerSecond,omitempty"`
+	// RequestBurst is the number of requests that may be sent in a burst when
+	// RequestsPerSecond is set. Defaults to RequestsPerSecond.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	RequestBurst *int32 `json:"requestBurst,omitempty"`
+	// BackgroundRequestPercent is the percentage of RequestsPerSecond and
+	// RequestBurst that background work, such as deleting every member of a
+	// group, may use, so that it does not delay regular reconciles. Defaults
+	// to 50.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	BackgroundRequestPercent *int32 `json:"backgroundRequestPercent,omitempty"`
+	// CABundle is used to verify the TLS certificate of the Cloudian API
+	// instead of the system CA certificates.
+	// +optional
+	CABundle *CABundle `json:"caBundle,omitempty"`
+	// TLS configures how the TLS connection to the Cloudian API is verified.
+	// +optional
+	TLS *TLSConfig `json:"tls,omitempty"`
+	// Retries configures retrying requests to the Cloudian API that fail
+	// transiently, such as when Cloudian is unavailable or throttles them.
+	// Failed requests are not retried if unspecified, and are left to the
+	// next reconcile.
+	// +optional
+	Retries *RetryConfig `json:"retries,omitempty"`
+	// ClaimOwnership marks Cloudian users created or adopted through this
+	// ProviderConfig with ClusterID, and refuses to modify users marked by
+	// another cluster. The marker is stored in the address2 field of the user.
+	// Cloudian groups have no free text field and are not marked. Instead,
+	// groups not created through this ProviderConfig are not updated or
+	// deleted while their users are marked by other clusters only.
+	// +optional
+	ClaimOwnership bool `json:"claimOwnership,omitempty"`
+	// ClusterID identifies this cluster among all clusters managing the same
+	// Cloudian installation. Required when ClaimOwnership is true.
+	// +optional
+	ClusterID string `json:"clusterId,omitempty"`
+	// S3Endpoints are all S3 endpoints of the Cloudian cluster. Cloudian may
+	// report a group allowed every endpoint as allowed "ALL" or as allowed
+	// each of them, and groups are compared accordingly. When unset, the S3
+	// endpoints listed by the system configuration of Cloudian are used, if
+	// the credentials may read it. These apply to HTTP, HTTPS and website
+	// endpoints alike.
+	// +optional
+	S3Endpoints []string `json:"s3Endpoints,omitempty"`
+	// MaxKeysPerUser is the most access keys a Cloudian user may have. An
+	// AccessKey whose user already has as many is not created, and gets a
+	// KeyQuotaExceeded condition. Only the limit of Cloudian applies if
+	// unspecified.
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	MaxKeysPerUser *int32 `json:"maxKeysPerUser,omitempty"`
+	// IAMEndpoint is an url with protocol, hostname and port of the IAM API
+	// of Cloudian, such as https://iam.example.com:16443. Required to manage
+	// IAMUsers, whose requests are signed with an access key of their parent
+	// user rather than with AuthHeader. It is verified like Endpoint.
+	// +optional
+	IAMEndpoint string `json:"iamEndpoint,omitempty"`
+	// StrictCredentialCheck refuses to delete a User whose access keys cannot
+	// be listed because the credentials are not permitted to. By default the
+	// User is deleted, along with any access keys, with a warning event.
+	// +optional
+	StrictCredentialCheck bool `json:"strictCredentialCheck,omitempty"`
+	// DefaultGroupNames sets the name of each Cloudian group whose Group
+	// leaves GroupName unset to the name of the Group, followed by a
+	// managed-by marker naming its claim, if any. Groups are updated when
+	// their name differs. A GroupName set in the Group is always kept.
+	// +optional
+	DefaultGroupNames bool `json:"defaultGroupNames,omitempty"`
+}
+
+// A CABundle is a bundle of PEM encoded CA certificates, either given inline
+// or read from a credentials source. Base64 encoded PEM is decoded.
+type CABundle struct {
+	// PEM encoded CA certificates.
+	// +optional
+	PEM string `json:"pem,omitempty"`
+	// Source of the CA certificates when not given inline. The certificates
+	// may be PEM encoded or base64 encoded PEM.
+	// +optional
+	// +kubebuilder:validation:Enum=None;Secret;Environment;Filesystem
+	Source xpv2.CredentialsSource `json:"source,omitempty"`
+
+	xpv2.CommonCredentialSelectors `json:",inline"`
+}
+
+// A TLSConfig configures how the TLS connection to the Cloudian API is
+// verified.
+type TLSConfig struct {
+	// ServerName the certificate of the Cloudian API must be valid for, when
+	// it differs from the hostname of Endpoint, such as when Endpoint is an
+	// IP address or an internal load balancer. The certificate chain is still
+	// verified.
+	// +optional
+	ServerName string `json:"serverName,omitempty"`
+}
+
+// A RetryConfig configures how requests to the Cloudian API failing
+// transiently are retried. Only requests that are safe to repeat are retried
+// once they may have reached Cloudian.
+type RetryConfig struct {
+	// Attempts is the most times each request is sent, including the first.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=10
+	Attempts int32 `json:"attempts"`
+	// Backoff is how long to wait before the first retry, twice as long
+	// before each next one, or as long as Cloudian asks when throttled.
+	// Defaults to 1s.
+	// +optional
+	Backoff *metav1.Duration `json:"backoff,omitempty"`
 }
 
 // ProviderCredentials required to authenticate.
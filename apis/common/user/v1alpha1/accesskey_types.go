@@ -42,12 +42,39 @@ type AccessKeyParameters struct {
 	// UserIDSelector selects a user to retrieve its groupId and userId.
 	// +optional
 	UserIDSelector *xpv2.Selector `json:"userIdSelector,omitempty"`
+
+	// Labels attribute the access key, e.g. to a team. Cloudian has no
+	// metadata on access keys, so they are kept as labels of the AccessKey and
+	// annotations of its connection secret, and can be selected with
+	// `kubectl get accesskeys -l`. Keys in the crossplane.io, kubernetes.io and
+	// k8s.io domains are reserved.
+	// +optional
+	// +kubebuilder:validation:MaxProperties=32
+	// +kubebuilder:validation:XValidation:rule="self.all(k, !k.matches('^([a-z0-9.-]+[.])?(crossplane[.]io|kubernetes[.]io|k8s[.]io)/'))",message="label keys in the crossplane.io, kubernetes.io and k8s.io domains are reserved"
+	Labels map[string]string `json:"labels,omitempty"`
 }
 
 // AccessKeyObservation are the observable fields of a AccessKey.
 type AccessKeyObservation struct {
-	// ID is the S3 Access Key ID, with a corresponding SecretKey.
+	// AccessKeyID is the S3 Access Key ID, with a corresponding SecretKey.
+	AccessKeyID string `json:"accessKeyId,omitempty"`
+
+	// ID is the S3 Access Key ID.
+	//
+	// Deprecated: Use AccessKeyID.
 	ID string `json:"id,omitempty"`
+
+	// Labels are the labels last applied to the AccessKey and its connection
+	// secret.
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// SetAccessKeyID sets the observed S3 Access Key ID, including the deprecated
+// field still read by existing consumers.
+func (o *AccessKeyObservation) SetAccessKeyID(id string) {
+	o.AccessKeyID = id
+	o.ID = id
 }
 
 // A AccessKeyStatus represents the observed state of a AccessKey.
@@ -0,0 +1,51 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+// Field paths of the observed state of the managed resources, for composition
+// functions and other consumers reading it. Observed fields are lowerCamel and
+// IDs are suffixed Id. Renamed fields are kept, deprecated, alongside the
+// fields replacing them until the next API version.
+const (
+	FieldPathUserCanonicalID    = "status.atProvider.canonicalId"
+	FieldPathUserStatus         = "status.atProvider.status"
+	FieldPathUserType           = "status.atProvider.userType"
+	FieldPathUserAccessKeyCount = "status.atProvider.accessKeyCount"
+	FieldPathUserAccessKeyIDs   = "status.atProvider.accessKeyIds"
+	FieldPathUserBuckets        = "status.atProvider.buckets"
+
+	FieldPathAccessKeyID     = "status.atProvider.accessKeyId"
+	FieldPathAccessKeyLabels = "status.atProvider.labels"
+
+	FieldPathGroupID            = "status.atProvider.groupId"
+	FieldPathGroupName          = "status.atProvider.groupName"
+	FieldPathGroupActive        = "status.atProvider.active"
+	FieldPathGroupS3Endpoints   = "status.atProvider.s3Endpoints"
+	FieldPathGroupLDAPEnabled   = "status.atProvider.ldapEnabled"
+	FieldPathGroupLDAPGroup     = "status.atProvider.ldapGroup"
+	FieldPathGroupLDAPServerURL = "status.atProvider.ldapServerURL"
+	FieldPathGroupUserCount     = "status.atProvider.userCount"
+	FieldPathGroupReverts       = "status.atProvider.reverts"
+
+	FieldPathGroupRatingPlanID = "status.atProvider.ratingPlanId"
+
+	FieldPathIAMUserID          = "status.atProvider.iamUserId"
+	FieldPathIAMUserARN         = "status.atProvider.arn"
+	FieldPathIAMUserAccessKeyID = "status.atProvider.accessKeyId"
+
+	FieldPathQualityOfServiceLimitsNormalized = "status.atProvider.normalized"
+)
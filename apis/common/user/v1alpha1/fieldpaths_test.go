@@ -0,0 +1,107 @@
+package v1alpha1
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/fieldpath"
+	"k8s.io/utils/ptr"
+)
+
+// observedFields maps each field path to a status setting the field it names,
+// so that renaming a field without updating its path fails to compile or test.
+var observedFields = []struct {
+	path   string
+	status any
+}{
+	{FieldPathUserCanonicalID, UserStatus{AtProvider: UserObservation{CanonicalID: "x"}}},
+	{FieldPathUserStatus, UserStatus{AtProvider: UserObservation{Status: "x"}}},
+	{FieldPathUserType, UserStatus{AtProvider: UserObservation{UserType: "x"}}},
+	{FieldPathUserAccessKeyCount, UserStatus{AtProvider: UserObservation{AccessKeyCount: 1}}},
+	{FieldPathUserAccessKeyIDs, UserStatus{AtProvider: UserObservation{AccessKeyIDs: []string{"x"}}}},
+	{FieldPathUserBuckets, UserStatus{AtProvider: UserObservation{Buckets: []Bucket{{Name: "x"}}}}},
+	{FieldPathAccessKeyID, AccessKeyStatus{AtProvider: AccessKeyObservation{AccessKeyID: "x"}}},
+	{FieldPathAccessKeyLabels, AccessKeyStatus{AtProvider: AccessKeyObservation{Labels: map[string]string{"team": "x"}}}},
+	{FieldPathGroupID, GroupStatus{AtProvider: GroupObservation{GroupID: "x"}}},
+	{FieldPathGroupName, GroupStatus{AtProvider: GroupObservation{GroupName: "x"}}},
+	{FieldPathGroupActive, GroupStatus{AtProvider: GroupObservation{Active: true}}},
+	{FieldPathGroupS3Endpoints, GroupStatus{AtProvider: GroupObservation{S3Endpoints: &S3Endpoints{HTTP: []string{"x"}}}}},
+	{FieldPathGroupLDAPEnabled, GroupStatus{AtProvider: GroupObservation{LDAPEnabled: true}}},
+	{FieldPathGroupLDAPGroup, GroupStatus{AtProvider: GroupObservation{LDAPGroup: "x"}}},
+	{FieldPathGroupLDAPServerURL, GroupStatus{AtProvider: GroupObservation{LDAPServerURL: "x"}}},
+	{FieldPathGroupUserCount, GroupStatus{AtProvider: GroupObservation{UserCount: ptr.To[int64](1)}}},
+	{FieldPathGroupReverts, GroupStatus{AtProvider: GroupObservation{Reverts: &Reverts{AppliedHash: "x"}}}},
+	{FieldPathGroupRatingPlanID, GroupRatingPlanStatus{AtProvider: GroupRatingPlanObservation{RatingPlanID: "x"}}},
+	{FieldPathIAMUserID, IAMUserStatus{AtProvider: IAMUserObservation{IAMUserID: "x"}}},
+	{FieldPathIAMUserARN, IAMUserStatus{AtProvider: IAMUserObservation{ARN: "x"}}},
+	{FieldPathIAMUserAccessKeyID, IAMUserStatus{AtProvider: IAMUserObservation{AccessKeyID: "x"}}},
+	{FieldPathQualityOfServiceLimitsNormalized, GroupQualityOfServiceLimitsStatus{AtProvider: GroupQualityOfServiceLimitsObservation{Normalized: NormalizedQOS{Hard: &NormalizedQualityOfServiceLimits{}}}}},
+	{FieldPathQualityOfServiceLimitsNormalized, UserQualityOfServiceLimitsStatus{AtProvider: UserQualityOfServiceLimitsObservation{Normalized: NormalizedQOS{Hard: &NormalizedQualityOfServiceLimits{}}}}},
+}
+
+// deprecatedFields are the JSON names of observed fields replaced by fields
+// with field paths.
+var deprecatedFields = map[reflect.Type][]string{
+	reflect.TypeFor[AccessKeyObservation](): {"id"},
+}
+
+var lowerCamel = regexp.MustCompile(`^[a-z][a-zA-Z0-9]*$`)
+
+func TestFieldPaths(t *testing.T) {
+	for _, f := range observedFields {
+		b, err := json.Marshal(map[string]any{"status": f.status})
+		if err != nil {
+			t.Fatal(err)
+		}
+		obj := map[string]any{}
+		if err := json.Unmarshal(b, &obj); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fieldpath.Pave(obj).GetValue(f.path); err != nil {
+			t.Errorf("%T: %s: %v", f.status, f.path, err)
+		}
+	}
+}
+
+func TestObservedFieldNames(t *testing.T) {
+	covered := map[reflect.Type]map[string]bool{}
+	for _, f := range observedFields {
+		obs := reflect.ValueOf(f.status).FieldByName("AtProvider").Type()
+		if covered[obs] == nil {
+			covered[obs] = map[string]bool{}
+		}
+		covered[obs][strings.TrimPrefix(f.path, "status.atProvider.")] = true
+	}
+	for obs, names := range deprecatedFields {
+		for _, name := range names {
+			if covered[obs] == nil {
+				covered[obs] = map[string]bool{}
+			}
+			covered[obs][name] = true
+		}
+	}
+
+	for obs := range covered {
+		for i := range obs.NumField() {
+			field := obs.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if !covered[obs][name] {
+				t.Errorf("%s.%s: no field path for %q", obs.Name(), field.Name, name)
+			}
+			if slices.Contains(deprecatedFields[obs], name) {
+				continue
+			}
+			if !lowerCamel.MatchString(name) {
+				t.Errorf("%s.%s: %q is not lowerCamel", obs.Name(), field.Name, name)
+			}
+			if strings.HasSuffix(field.Name, "ID") && !strings.HasSuffix(name, "Id") ||
+				strings.HasSuffix(field.Name, "IDs") && !strings.HasSuffix(name, "Ids") {
+				t.Errorf("%s.%s: %q is not suffixed Id", obs.Name(), field.Name, name)
+			}
+		}
+	}
+}
@@ -20,10 +20,18 @@ package v1alpha1
 
 import (
 	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // GroupParameters are the configurable fields of a Group.
 type GroupParameters struct {
+	// GroupID is the ID of the group in Cloudian. Defaults to the external-name,
+	// and must match it when both are set.
+	// +optional
+	// +immutable
+	// +kubebuilder:validation:MaxLength=64
+	// +kubebuilder:validation:Pattern=`^[A-Za-z0-9_-]+$`
+	GroupID string `json:"groupId,omitempty"`
 	// Active determines whether the group is enabled (true) or disabled (false) in the system.
 	//+optional
 	//+kubebuilder:default=true
@@ -52,10 +60,95 @@ type GroupParameters struct {
 	// LDAPUserDNTemplate specifies how users within this group will be authenticated against the LDAP system when they log into the CMC.
 	//+optional
 	LDAPUserDNTemplate *string `json:"ldapUserDNTemplate,omitempty"`
+	// S3Endpoints restricts the S3 endpoints members of the group may use.
+	// Restrictions configured in Cloudian are kept if unspecified.
+	//+optional
+	S3Endpoints *S3Endpoints `json:"s3Endpoints,omitempty"`
+	// RecursiveDelete deletes all users of the group when the group is deleted.
+	// Users that still have access keys are kept unless ForceRecursiveDelete is true.
+	//+optional
+	RecursiveDelete bool `json:"recursiveDelete,omitempty"`
+	// ForceRecursiveDelete also deletes users that still have access keys
+	// when RecursiveDelete is true.
+	//+optional
+	ForceRecursiveDelete bool `json:"forceRecursiveDelete,omitempty"`
+}
+
+// S3Endpoints are the S3 endpoints members of a group may use, by protocol.
+// Each list allows every endpoint if it is empty or includes "ALL". The order
+// and duplicates of the lists are irrelevant.
+type S3Endpoints struct {
+	// HTTP are the S3 endpoints that may be used over HTTP.
+	//+optional
+	HTTP []string `json:"http,omitempty"`
+	// HTTPS are the S3 endpoints that may be used over HTTPS.
+	//+optional
+	HTTPS []string `json:"https,omitempty"`
+	// Website are the S3 website endpoints that may be used.
+	//+optional
+	Website []string `json:"website,omitempty"`
+}
+
+// GroupID returns the authoritative Cloudian ID of a group. This is the
+// spec's groupId, falling back to the external-name for groups that have not
+// been initialized yet.
+func GroupID(externalName string, p GroupParameters) string {
+	if p.GroupID != "" {
+		return p.GroupID
+	}
+	return externalName
 }
 
 // GroupObservation are the observable fields of a Group.
 type GroupObservation struct {
+	// GroupID is the ID of the group in Cloudian.
+	GroupID string `json:"groupId,omitempty"`
+
+	// GroupName is the name of the group in Cloudian.
+	GroupName string `json:"groupName,omitempty"`
+
+	// Active is whether the group is active in Cloudian.
+	Active bool `json:"active,omitempty"`
+
+	// S3Endpoints are the S3 endpoints members of the group may use, as
+	// configured in Cloudian. ALL allows every endpoint.
+	// +optional
+	S3Endpoints *S3Endpoints `json:"s3Endpoints,omitempty"`
+
+	// LDAPEnabled is whether LDAP authentication is enabled in Cloudian,
+	// whether or not it is managed by the provider.
+	LDAPEnabled bool `json:"ldapEnabled,omitempty"`
+
+	// LDAPGroup is the LDAP group configured in Cloudian.
+	LDAPGroup string `json:"ldapGroup,omitempty"`
+
+	// LDAPServerURL is the LDAP server configured in Cloudian.
+	LDAPServerURL string `json:"ldapServerURL,omitempty"`
+
+	// UserCount is the number of users of the group in Cloudian, when the
+	// provider observes it.
+	// +optional
+	UserCount *int64 `json:"userCount,omitempty"`
+
+	// Reverts records the group being changed back outside the provider
+	// after it was updated.
+	// +optional
+	Reverts *Reverts `json:"reverts,omitempty"`
+}
+
+// Reverts records parameters being changed back outside the provider after
+// it applied them, so that an external system fighting the provider is
+// detected.
+type Reverts struct {
+	// AppliedHash identifies the parameters last applied, until they are
+	// found changed back.
+	// +optional
+	AppliedHash string `json:"appliedHash,omitempty"`
+
+	// Times are when the applied parameters were found changed back, oldest
+	// first. Only the most recent are kept.
+	// +optional
+	Times []metav1.Time `json:"times,omitempty"`
 }
 
 // A GroupStatus represents the observed state of a Group.
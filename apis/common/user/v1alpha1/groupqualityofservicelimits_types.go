@@ -47,6 +47,9 @@ type GroupQualityOfServiceLimitsParameters struct {
 
 // GroupQualityOfServiceLimitsObservation are the observable fields of a GroupQualityOfServiceLimits.
 type GroupQualityOfServiceLimitsObservation struct {
+	// Normalized are the byte limits in KiB, as sent to Cloudian.
+	// +optional
+	Normalized NormalizedQOS `json:"normalized,omitempty"`
 }
 
 // A GroupQualityOfServiceLimitsStatus represents the observed state of a GroupQualityOfServiceLimits.
@@ -0,0 +1,62 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+// +kubebuilder:object:generate=true
+
+import (
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
+)
+
+// GroupRatingPlanParameters are the configurable fields of a GroupRatingPlan.
+type GroupRatingPlanParameters struct {
+	// GroupID of the group to assign the rating plan to.
+	// +optional
+	// +immutable
+	GroupID string `json:"groupId,omitempty"`
+
+	// GroupIDRef references a group to retrieve its groupId.
+	// +optional
+	// +immutable
+	GroupIDRef *xpv2.Reference `json:"groupIdRef,omitempty"`
+
+	// GroupIDSelector selects a group to retrieve its groupId.
+	// +optional
+	GroupIDSelector *xpv2.Selector `json:"groupIdSelector,omitempty"`
+
+	// RatingPlanID is the ID of the rating plan to assign. The default rating
+	// plan if unspecified.
+	// +optional
+	RatingPlanID string `json:"ratingPlanId,omitempty"`
+
+	// Region in which to assign the rating plan. Default region if unspecified.
+	// +optional
+	Region string `json:"region,omitempty"`
+}
+
+// GroupRatingPlanObservation are the observable fields of a GroupRatingPlan.
+type GroupRatingPlanObservation struct {
+	// RatingPlanID is the ID of the rating plan assigned to the group.
+	// +optional
+	RatingPlanID string `json:"ratingPlanId,omitempty"`
+}
+
+// A GroupRatingPlanStatus represents the observed state of a GroupRatingPlan.
+type GroupRatingPlanStatus struct {
+	xpv2.ManagedResourceStatus `json:",inline"`
+	AtProvider                 GroupRatingPlanObservation `json:"atProvider,omitempty"`
+}
@@ -0,0 +1,67 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+// +kubebuilder:object:generate=true
+
+import (
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
+)
+
+// IAMUserParameters are the configurable fields of an IAMUser.
+type IAMUserParameters struct {
+	// GroupID of the parent user of the IAM user.
+	// +optional
+	// +immutable
+	GroupID string `json:"groupId,omitempty"`
+
+	// UserID of the parent user of the IAM user. The parent user must have an
+	// access key, which the requests to the IAM API are signed with.
+	// +optional
+	// +immutable
+	UserID string `json:"userId,omitempty"`
+
+	// UserIDRef references the parent user to retrieve its groupId and
+	// userId.
+	// +optional
+	// +immutable
+	UserIDRef *xpv2.Reference `json:"userIdRef,omitempty"`
+
+	// UserIDSelector selects the parent user to retrieve its groupId and
+	// userId.
+	// +optional
+	UserIDSelector *xpv2.Selector `json:"userIdSelector,omitempty"`
+}
+
+// IAMUserObservation are the observable fields of an IAMUser.
+type IAMUserObservation struct {
+	// IAMUserID is the unique ID Cloudian assigned to the IAM user.
+	IAMUserID string `json:"iamUserId,omitempty"`
+
+	// ARN of the IAM user.
+	ARN string `json:"arn,omitempty"`
+
+	// AccessKeyID is the ID of the access key created with the IAM user, whose
+	// secret key is in the connection secret.
+	AccessKeyID string `json:"accessKeyId,omitempty"`
+}
+
+// An IAMUserStatus represents the observed state of an IAMUser.
+type IAMUserStatus struct {
+	xpv2.ManagedResourceStatus `json:",inline"`
+	AtProvider                 IAMUserObservation `json:"atProvider,omitempty"`
+}
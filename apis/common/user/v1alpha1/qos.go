@@ -1,11 +1,21 @@
 package v1alpha1
 
 // +kubebuilder:object:generate=true
-import resource "k8s.io/apimachinery/pkg/api/resource"
+import (
+	"fmt"
+	"math/big"
+	"strconv"
 
+	resource "k8s.io/apimachinery/pkg/api/resource"
+)
+
+// A Quantity of bytes. Only whole binary multiples are accepted, so that the
+// value is always representable as whole KiB without rounding.
 // +kubebuilder:validation:Pattern=`^(0|((0|[1-9][0-9]*)[KMGT]i))$`
 type Quantity string
 
+// ToKiB converts the quantity to KiB. Values that are not a whole number of
+// KiB are rejected rather than rounded.
 func (q *Quantity) ToKiB() (*int64, error) {
 	if q == nil {
 		return nil, nil
@@ -16,11 +26,49 @@ func (q *Quantity) ToKiB() (*int64, error) {
 		return nil, err
 	}
 
-	i := rq.ScaledValue(0) / 1024
+	// Large quantities are not kept as int64 by resource.Quantity, so the
+	// bytes are counted exactly.
+	d := rq.AsDec()
+	exp := int64(d.Scale())
+	if exp < 0 {
+		exp = -exp
+	}
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(exp), nil)
+	bytes, divisor := new(big.Int).Set(d.UnscaledBig()), big.NewInt(1024)
+	if d.Scale() < 0 {
+		bytes.Mul(bytes, scale)
+	} else {
+		divisor.Mul(divisor, scale)
+	}
+	kib, rem := new(big.Int).QuoRem(bytes, divisor, new(big.Int))
+	if rem.Sign() != 0 {
+		return nil, fmt.Errorf("quantity %q is not a whole number of KiB", string(*q))
+	}
+	if !kib.IsInt64() {
+		return nil, fmt.Errorf("quantity %q is too large", string(*q))
+	}
+
+	i := kib.Int64()
 	return &i, nil
 }
 
-// QualityOfService configures data limits. The value -1 indicates unlimited.
+// QuantityFromKiB returns a quantity of `kib` KiB, in the largest unit that
+// keeps it whole.
+func QuantityFromKiB(kib int64) Quantity {
+	if kib == 0 {
+		return "0"
+	}
+	units := []string{"Ki", "Mi", "Gi", "Ti"}
+	unit := 0
+	for unit < len(units)-1 && kib%1024 == 0 {
+		kib /= 1024
+		unit++
+	}
+	return Quantity(strconv.FormatInt(kib, 10) + units[unit])
+}
+
+// QualityOfService configures data limits. Limits that are not set are
+// unlimited, and are removed from Cloudian when removed from the spec.
 type QualityOfServiceLimits struct {
 	// StorageQuotaBytes is the limit for total stored data in bytes.
 	// +optional
@@ -53,3 +101,28 @@ type QOS struct {
 	// +optional
 	Hard *QualityOfServiceLimits `json:"hard,omitempty"`
 }
+
+// NormalizedQualityOfServiceLimits are quality of service limits as sent to
+// Cloudian. Unset limits are unlimited.
+type NormalizedQualityOfServiceLimits struct {
+	// StorageQuotaKiBs is the limit for total stored data in KiB.
+	// +optional
+	StorageQuotaKiBs *int64 `json:"storageQuotaKiBs,omitempty"`
+	// InboundKiBsPerMin is the limit for inbound data per minute in KiB.
+	// +optional
+	InboundKiBsPerMin *int64 `json:"inboundKiBsPerMin,omitempty"`
+	// OutboundKiBsPerMin is the limit for outbound data per minute in KiB.
+	// +optional
+	OutboundKiBsPerMin *int64 `json:"outboundKiBsPerMin,omitempty"`
+}
+
+// NormalizedQOS are the byte limits of a QOS normalized to KiB.
+type NormalizedQOS struct {
+	// Warning is the soft limit that triggers a warning.
+	// +optional
+	Warning *NormalizedQualityOfServiceLimits `json:"warning,omitempty"`
+
+	// Hard is the hard limit.
+	// +optional
+	Hard *NormalizedQualityOfServiceLimits `json:"hard,omitempty"`
+}
@@ -0,0 +1,16 @@
+package v1alpha1
+
+// A StatusMirror references a ConfigMap that mirrors the conditions and
+// non-secret status of a managed resource, for tenants that cannot read the
+// managed resource itself.
+type StatusMirror struct {
+	// Namespace of the ConfigMap. Required for cluster scoped managed
+	// resources. Namespaced managed resources may only mirror to their own
+	// namespace, which is the default.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Name of the ConfigMap.
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
@@ -20,6 +20,7 @@ package v1alpha1
 
 import (
 	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // UserParameters are the configurable fields of a User.
@@ -27,6 +28,8 @@ type UserParameters struct {
 	// Group for the new user.
 	// +optional
 	// +immutable
+	// +kubebuilder:validation:MaxLength=64
+	// +kubebuilder:validation:Pattern=`^[A-Za-z0-9_-]+$`
 	GroupID string `json:"groupId,omitempty"`
 
 	// GroupIDRef is a reference to a group to retrieve its groupId.
@@ -37,11 +40,66 @@ type UserParameters struct {
 	// GroupIDSelector selects reference to a group to retrieve its groupId.
 	// +optional
 	GroupIDSelector *xpv2.Selector `json:"groupIdSelector,omitempty"`
+
+	// Status of the user. Inactive and locked users cannot access Cloudian.
+	// Left as is in Cloudian if unspecified.
+	// +optional
+	// +kubebuilder:validation:Enum=Active;Inactive;Locked
+	Status *string `json:"status,omitempty"`
+
+	// UserType of the user. Cloudian cannot change the type of a user, so a
+	// user of another type must be deleted and recreated. Defaults to User.
+	// +optional
+	// +immutable
+	// +kubebuilder:validation:Enum=User;GroupAdmin
+	UserType *string `json:"userType,omitempty"`
+
+	// DeleteKeysOnDelete deletes the access keys of the user when the User is
+	// deleted. Otherwise a user with access keys is not deleted until they
+	// are deleted, such as by deleting their AccessKeys.
+	// +optional
+	DeleteKeysOnDelete bool `json:"deleteKeysOnDelete,omitempty"`
+
+	// RetainInitialAccessKey keeps the access key Cloudian creates along with
+	// the user, and publishes its ID and secret in the connection secret of
+	// the User. Otherwise the access key is deleted when the user is created,
+	// so that it only has the access keys of AccessKeys.
+	// +optional
+	// +immutable
+	RetainInitialAccessKey bool `json:"retainInitialAccessKey,omitempty"`
 }
 
 // UserObservation are the observable fields of a User.
 type UserObservation struct {
 	CanonicalID string `json:"canonicalId,omitempty"`
+
+	// Status of the user as reported by Cloudian.
+	Status string `json:"status,omitempty"`
+
+	// UserType of the user as reported by Cloudian.
+	UserType string `json:"userType,omitempty"`
+
+	// AccessKeyCount is the number of access keys of the user.
+	AccessKeyCount int `json:"accessKeyCount,omitempty"`
+
+	// AccessKeyIDs are the sorted IDs of the access keys of the user.
+	AccessKeyIDs []string `json:"accessKeyIds,omitempty"`
+
+	// Buckets owned by the user, when the provider observes them.
+	Buckets []Bucket `json:"buckets,omitempty"`
+}
+
+// A Bucket is a bucket owned by a user, with its usage.
+type Bucket struct {
+	Name string `json:"name"`
+
+	// CreationDate of the bucket, if Cloudian reports it.
+	CreationDate *metav1.Time `json:"creationDate,omitempty"`
+
+	ObjectCount int64 `json:"objectCount"`
+
+	// Bytes stored in the bucket.
+	Bytes int64 `json:"bytes"`
 }
 
 // A UserStatus represents the observed state of a User.
@@ -52,6 +52,9 @@ type UserQualityOfServiceLimitsParameters struct {
 
 // UserQualityOfServiceLimitsObservation are the observable fields of a UserQualityOfServiceLimits.
 type UserQualityOfServiceLimitsObservation struct {
+	// Normalized are the byte limits in KiB, as sent to Cloudian.
+	// +optional
+	Normalized NormalizedQOS `json:"normalized,omitempty"`
 }
 
 // A UserQualityOfServiceLimitsStatus represents the observed state of a UserQualityOfServiceLimits.
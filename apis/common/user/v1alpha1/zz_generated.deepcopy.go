@@ -22,11 +22,19 @@ package v1alpha1
 
 import (
 	"github.com/crossplane/crossplane/apis/v2/core/v2"
+	"k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *AccessKeyObservation) DeepCopyInto(out *AccessKeyObservation) {
 	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessKeyObservation.
@@ -52,6 +60,13 @@ func (in *AccessKeyParameters) DeepCopyInto(out *AccessKeyParameters) {
 		*out = new(v2.Selector)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessKeyParameters.
@@ -68,7 +83,7 @@ func (in *AccessKeyParameters) DeepCopy() *AccessKeyParameters {
 func (in *AccessKeyStatus) DeepCopyInto(out *AccessKeyStatus) {
 	*out = *in
 	in.ManagedResourceStatus.DeepCopyInto(&out.ManagedResourceStatus)
-	out.AtProvider = in.AtProvider
+	in.AtProvider.DeepCopyInto(&out.AtProvider)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessKeyStatus.
@@ -81,9 +96,43 @@ func (in *AccessKeyStatus) DeepCopy() *AccessKeyStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Bucket) DeepCopyInto(out *Bucket) {
+	*out = *in
+	if in.CreationDate != nil {
+		in, out := &in.CreationDate, &out.CreationDate
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Bucket.
+func (in *Bucket) DeepCopy() *Bucket {
+	if in == nil {
+		return nil
+	}
+	out := new(Bucket)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GroupObservation) DeepCopyInto(out *GroupObservation) {
 	*out = *in
+	if in.S3Endpoints != nil {
+		in, out := &in.S3Endpoints, &out.S3Endpoints
+		*out = new(S3Endpoints)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.UserCount != nil {
+		in, out := &in.UserCount, &out.UserCount
+		*out = new(int64)
+		**out = **in
+	}
+	if in.Reverts != nil {
+		in, out := &in.Reverts, &out.Reverts
+		*out = new(Reverts)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GroupObservation.
@@ -134,6 +183,11 @@ func (in *GroupParameters) DeepCopyInto(out *GroupParameters) {
 		*out = new(string)
 		**out = **in
 	}
+	if in.S3Endpoints != nil {
+		in, out := &in.S3Endpoints, &out.S3Endpoints
+		*out = new(S3Endpoints)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GroupParameters.
@@ -149,6 +203,7 @@ func (in *GroupParameters) DeepCopy() *GroupParameters {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GroupQualityOfServiceLimitsObservation) DeepCopyInto(out *GroupQualityOfServiceLimitsObservation) {
 	*out = *in
+	in.Normalized.DeepCopyInto(&out.Normalized)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GroupQualityOfServiceLimitsObservation.
@@ -191,7 +246,7 @@ func (in *GroupQualityOfServiceLimitsParameters) DeepCopy() *GroupQualityOfServi
 func (in *GroupQualityOfServiceLimitsStatus) DeepCopyInto(out *GroupQualityOfServiceLimitsStatus) {
 	*out = *in
 	in.ManagedResourceStatus.DeepCopyInto(&out.ManagedResourceStatus)
-	out.AtProvider = in.AtProvider
+	in.AtProvider.DeepCopyInto(&out.AtProvider)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GroupQualityOfServiceLimitsStatus.
@@ -205,12 +260,69 @@ func (in *GroupQualityOfServiceLimitsStatus) DeepCopy() *GroupQualityOfServiceLi
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *GroupStatus) DeepCopyInto(out *GroupStatus) {
+func (in *GroupRatingPlanObservation) DeepCopyInto(out *GroupRatingPlanObservation) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GroupRatingPlanObservation.
+func (in *GroupRatingPlanObservation) DeepCopy() *GroupRatingPlanObservation {
+	if in == nil {
+		return nil
+	}
+	out := new(GroupRatingPlanObservation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GroupRatingPlanParameters) DeepCopyInto(out *GroupRatingPlanParameters) {
+	*out = *in
+	if in.GroupIDRef != nil {
+		in, out := &in.GroupIDRef, &out.GroupIDRef
+		*out = new(v2.Reference)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GroupIDSelector != nil {
+		in, out := &in.GroupIDSelector, &out.GroupIDSelector
+		*out = new(v2.Selector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GroupRatingPlanParameters.
+func (in *GroupRatingPlanParameters) DeepCopy() *GroupRatingPlanParameters {
+	if in == nil {
+		return nil
+	}
+	out := new(GroupRatingPlanParameters)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GroupRatingPlanStatus) DeepCopyInto(out *GroupRatingPlanStatus) {
 	*out = *in
 	in.ManagedResourceStatus.DeepCopyInto(&out.ManagedResourceStatus)
 	out.AtProvider = in.AtProvider
 }
 
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GroupRatingPlanStatus.
+func (in *GroupRatingPlanStatus) DeepCopy() *GroupRatingPlanStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(GroupRatingPlanStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GroupStatus) DeepCopyInto(out *GroupStatus) {
+	*out = *in
+	in.ManagedResourceStatus.DeepCopyInto(&out.ManagedResourceStatus)
+	in.AtProvider.DeepCopyInto(&out.AtProvider)
+}
+
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GroupStatus.
 func (in *GroupStatus) DeepCopy() *GroupStatus {
 	if in == nil {
@@ -221,6 +333,118 @@ func (in *GroupStatus) DeepCopy() *GroupStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IAMUserObservation) DeepCopyInto(out *IAMUserObservation) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IAMUserObservation.
+func (in *IAMUserObservation) DeepCopy() *IAMUserObservation {
+	if in == nil {
+		return nil
+	}
+	out := new(IAMUserObservation)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IAMUserParameters) DeepCopyInto(out *IAMUserParameters) {
+	*out = *in
+	if in.UserIDRef != nil {
+		in, out := &in.UserIDRef, &out.UserIDRef
+		*out = new(v2.Reference)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.UserIDSelector != nil {
+		in, out := &in.UserIDSelector, &out.UserIDSelector
+		*out = new(v2.Selector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IAMUserParameters.
+func (in *IAMUserParameters) DeepCopy() *IAMUserParameters {
+	if in == nil {
+		return nil
+	}
+	out := new(IAMUserParameters)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IAMUserStatus) DeepCopyInto(out *IAMUserStatus) {
+	*out = *in
+	in.ManagedResourceStatus.DeepCopyInto(&out.ManagedResourceStatus)
+	out.AtProvider = in.AtProvider
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IAMUserStatus.
+func (in *IAMUserStatus) DeepCopy() *IAMUserStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(IAMUserStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NormalizedQOS) DeepCopyInto(out *NormalizedQOS) {
+	*out = *in
+	if in.Warning != nil {
+		in, out := &in.Warning, &out.Warning
+		*out = new(NormalizedQualityOfServiceLimits)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Hard != nil {
+		in, out := &in.Hard, &out.Hard
+		*out = new(NormalizedQualityOfServiceLimits)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NormalizedQOS.
+func (in *NormalizedQOS) DeepCopy() *NormalizedQOS {
+	if in == nil {
+		return nil
+	}
+	out := new(NormalizedQOS)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NormalizedQualityOfServiceLimits) DeepCopyInto(out *NormalizedQualityOfServiceLimits) {
+	*out = *in
+	if in.StorageQuotaKiBs != nil {
+		in, out := &in.StorageQuotaKiBs, &out.StorageQuotaKiBs
+		*out = new(int64)
+		**out = **in
+	}
+	if in.InboundKiBsPerMin != nil {
+		in, out := &in.InboundKiBsPerMin, &out.InboundKiBsPerMin
+		*out = new(int64)
+		**out = **in
+	}
+	if in.OutboundKiBsPerMin != nil {
+		in, out := &in.OutboundKiBsPerMin, &out.OutboundKiBsPerMin
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NormalizedQualityOfServiceLimits.
+func (in *NormalizedQualityOfServiceLimits) DeepCopy() *NormalizedQualityOfServiceLimits {
+	if in == nil {
+		return nil
+	}
+	out := new(NormalizedQualityOfServiceLimits)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *QOS) DeepCopyInto(out *QOS) {
 	*out = *in
@@ -286,9 +510,88 @@ func (in *QualityOfServiceLimits) DeepCopy() *QualityOfServiceLimits {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Reverts) DeepCopyInto(out *Reverts) {
+	*out = *in
+	if in.Times != nil {
+		in, out := &in.Times, &out.Times
+		*out = make([]v1.Time, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Reverts.
+func (in *Reverts) DeepCopy() *Reverts {
+	if in == nil {
+		return nil
+	}
+	out := new(Reverts)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *S3Endpoints) DeepCopyInto(out *S3Endpoints) {
+	*out = *in
+	if in.HTTP != nil {
+		in, out := &in.HTTP, &out.HTTP
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.HTTPS != nil {
+		in, out := &in.HTTPS, &out.HTTPS
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Website != nil {
+		in, out := &in.Website, &out.Website
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new S3Endpoints.
+func (in *S3Endpoints) DeepCopy() *S3Endpoints {
+	if in == nil {
+		return nil
+	}
+	out := new(S3Endpoints)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StatusMirror) DeepCopyInto(out *StatusMirror) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StatusMirror.
+func (in *StatusMirror) DeepCopy() *StatusMirror {
+	if in == nil {
+		return nil
+	}
+	out := new(StatusMirror)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *UserObservation) DeepCopyInto(out *UserObservation) {
 	*out = *in
+	if in.AccessKeyIDs != nil {
+		in, out := &in.AccessKeyIDs, &out.AccessKeyIDs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Buckets != nil {
+		in, out := &in.Buckets, &out.Buckets
+		*out = make([]Bucket, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UserObservation.
@@ -314,6 +617,16 @@ func (in *UserParameters) DeepCopyInto(out *UserParameters) {
 		*out = new(v2.Selector)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Status != nil {
+		in, out := &in.Status, &out.Status
+		*out = new(string)
+		**out = **in
+	}
+	if in.UserType != nil {
+		in, out := &in.UserType, &out.UserType
+		*out = new(string)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UserParameters.
@@ -329,6 +642,7 @@ func (in *UserParameters) DeepCopy() *UserParameters {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *UserQualityOfServiceLimitsObservation) DeepCopyInto(out *UserQualityOfServiceLimitsObservation) {
 	*out = *in
+	in.Normalized.DeepCopyInto(&out.Normalized)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UserQualityOfServiceLimitsObservation.
@@ -371,7 +685,7 @@ func (in *UserQualityOfServiceLimitsParameters) DeepCopy() *UserQualityOfService
 func (in *UserQualityOfServiceLimitsStatus) DeepCopyInto(out *UserQualityOfServiceLimitsStatus) {
 	*out = *in
 	in.ManagedResourceStatus.DeepCopyInto(&out.ManagedResourceStatus)
-	out.AtProvider = in.AtProvider
+	in.AtProvider.DeepCopyInto(&out.AtProvider)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UserQualityOfServiceLimitsStatus.
@@ -388,7 +702,7 @@ func (in *UserQualityOfServiceLimitsStatus) DeepCopy() *UserQualityOfServiceLimi
 func (in *UserStatus) DeepCopyInto(out *UserStatus) {
 	*out = *in
 	in.ManagedResourceStatus.DeepCopyInto(&out.ManagedResourceStatus)
-	out.AtProvider = in.AtProvider
+	in.AtProvider.DeepCopyInto(&out.AtProvider)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UserStatus.
@@ -0,0 +1,46 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/reference"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+
+	userv1alpha1common "github.com/statnett/provider-cloudian/apis/common/user/v1alpha1"
+)
+
+// GetGroupID returns the Cloudian ID of this Group.
+func (mg *Group) GetGroupID() string {
+	return userv1alpha1common.GroupID(meta.GetExternalName(mg), mg.Spec.ForProvider)
+}
+
+// GetGroupParameters returns the configurable fields of this Group.
+func (mg *Group) GetGroupParameters() *userv1alpha1common.GroupParameters {
+	return &mg.Spec.ForProvider
+}
+
+// GroupID extracts the Cloudian ID of a referenced Group.
+func GroupID() reference.ExtractValueFn {
+	return func(mg resource.Managed) string {
+		g, ok := mg.(*Group)
+		if !ok {
+			return ""
+		}
+		return g.GetGroupID()
+	}
+}
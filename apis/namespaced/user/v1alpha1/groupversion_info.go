@@ -45,6 +45,8 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		&AccessKey{}, &AccessKeyList{},
 		&Group{}, &GroupList{},
 		&GroupQualityOfServiceLimits{}, &GroupQualityOfServiceLimitsList{},
+		&GroupRatingPlan{}, &GroupRatingPlanList{},
+		&IAMUser{}, &IAMUserList{},
 		&User{}, &UserList{},
 		&UserQualityOfServiceLimits{}, &UserQualityOfServiceLimitsList{},
 	)
@@ -21,6 +21,7 @@ import (
 
 	"github.com/crossplane/crossplane-runtime/v2/pkg/reference"
 	resource "github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
 	"github.com/pkg/errors"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -34,7 +35,7 @@ func (mg *User) ResolveReferences(ctx context.Context, c client.Reader) error {
 		Reference:    mg.Spec.ForProvider.GroupIDRef,
 		Selector:     mg.Spec.ForProvider.GroupIDSelector,
 		To:           reference.To{Managed: &Group{}, List: &GroupList{}},
-		Extract:      reference.ExternalName(),
+		Extract:      GroupID(),
 	})
 	if err != nil {
 		return errors.Wrap(err, "unable to resolve spec.forProvider.groupId")
@@ -55,7 +56,7 @@ func (mg *GroupQualityOfServiceLimits) ResolveReferences(ctx context.Context, c
 		Reference:    mg.Spec.ForProvider.GroupIDRef,
 		Selector:     mg.Spec.ForProvider.GroupIDSelector,
 		To:           reference.To{Managed: &Group{}, List: &GroupList{}},
-		Extract:      reference.ExternalName(),
+		Extract:      GroupID(),
 	})
 	if err != nil {
 		return errors.Wrap(err, "unable to resolve spec.forProvider.groupId")
@@ -67,66 +68,69 @@ func (mg *GroupQualityOfServiceLimits) ResolveReferences(ctx context.Context, c
 	return nil
 }
 
-// ResolveReferences of this AccessKey
-func (mg *AccessKey) ResolveReferences(ctx context.Context, c client.Reader) error {
+// ResolveReferences of this GroupRatingPlan
+func (mg *GroupRatingPlan) ResolveReferences(ctx context.Context, c client.Reader) error {
 	r := reference.NewAPIResolver(c, mg)
 
 	rsp, err := r.Resolve(ctx, reference.ResolutionRequest{
-		CurrentValue: mg.Spec.ForProvider.UserID,
-		Reference:    mg.Spec.ForProvider.UserIDRef,
-		Selector:     mg.Spec.ForProvider.UserIDSelector,
-		To:           reference.To{Managed: &User{}, List: &UserList{}},
-		Extract:      reference.ExternalName(),
-	})
-	if err != nil {
-		return errors.Wrap(err, "unable to resolve spec.forProvider.userId")
-	}
-
-	mg.Spec.ForProvider.UserID = rsp.ResolvedValue
-	mg.Spec.ForProvider.UserIDRef = rsp.ResolvedReference
-
-	rsp, err = r.Resolve(ctx, reference.ResolutionRequest{
-		Reference: mg.Spec.ForProvider.UserIDRef,
-		Selector:  mg.Spec.ForProvider.UserIDSelector,
-		To:        reference.To{Managed: &User{}, List: &UserList{}},
-		Extract: func(mg resource.Managed) string {
-			user, ok := mg.(*User)
-			if !ok {
-				return ""
-			}
-			return user.Spec.ForProvider.GroupID
-		},
+		CurrentValue: mg.Spec.ForProvider.GroupID,
+		Reference:    mg.Spec.ForProvider.GroupIDRef,
+		Selector:     mg.Spec.ForProvider.GroupIDSelector,
+		To:           reference.To{Managed: &Group{}, List: &GroupList{}},
+		Extract:      GroupID(),
 	})
 	if err != nil {
 		return errors.Wrap(err, "unable to resolve spec.forProvider.groupId")
 	}
 
 	mg.Spec.ForProvider.GroupID = rsp.ResolvedValue
+	mg.Spec.ForProvider.GroupIDRef = rsp.ResolvedReference
 
 	return nil
 }
 
+// ResolveReferences of this AccessKey
+func (mg *AccessKey) ResolveReferences(ctx context.Context, c client.Reader) error {
+	p := &mg.Spec.ForProvider
+	return resolveUser(ctx, reference.NewAPIResolver(c, mg), &p.UserID, &p.GroupID, &p.UserIDRef, p.UserIDSelector)
+}
+
+// ResolveReferences of this IAMUser
+func (mg *IAMUser) ResolveReferences(ctx context.Context, c client.Reader) error {
+	p := &mg.Spec.ForProvider
+	return resolveUser(ctx, reference.NewAPIResolver(c, mg), &p.UserID, &p.GroupID, &p.UserIDRef, p.UserIDSelector)
+}
+
 // ResolveReferences of this UserQualityOfServiceLimits
 func (mg *UserQualityOfServiceLimits) ResolveReferences(ctx context.Context, c client.Reader) error {
-	r := reference.NewAPIResolver(c, mg)
+	p := &mg.Spec.ForProvider
+	return resolveUser(ctx, reference.NewAPIResolver(c, mg), &p.UserID, &p.GroupID, &p.UserIDRef, p.UserIDSelector)
+}
 
+// resolveUser resolves a reference to a User into its user ID, which is its
+// external-name, and its group ID. A User that has neither yet is reported
+// as such, so that the referencing resource explains what it is waiting for.
+func resolveUser(ctx context.Context, r *reference.APIResolver, userID, groupID *string, ref **xpv2.Reference, sel *xpv2.Selector) error {
 	rsp, err := r.Resolve(ctx, reference.ResolutionRequest{
-		CurrentValue: mg.Spec.ForProvider.UserID,
-		Reference:    mg.Spec.ForProvider.UserIDRef,
-		Selector:     mg.Spec.ForProvider.UserIDSelector,
+		CurrentValue: *userID,
+		Reference:    *ref,
+		Selector:     sel,
 		To:           reference.To{Managed: &User{}, List: &UserList{}},
 		Extract:      reference.ExternalName(),
 	})
+	if err != nil && rsp.ResolvedReference != nil {
+		return errors.Wrapf(err, "unable to resolve spec.forProvider.userId: User %q has no external-name yet", rsp.ResolvedReference.Name)
+	}
 	if err != nil {
 		return errors.Wrap(err, "unable to resolve spec.forProvider.userId")
 	}
 
-	mg.Spec.ForProvider.UserID = rsp.ResolvedValue
-	mg.Spec.ForProvider.UserIDRef = rsp.ResolvedReference
+	*userID = rsp.ResolvedValue
+	*ref = rsp.ResolvedReference
 
 	rsp, err = r.Resolve(ctx, reference.ResolutionRequest{
-		Reference: mg.Spec.ForProvider.UserIDRef,
-		Selector:  mg.Spec.ForProvider.UserIDSelector,
+		Reference: *ref,
+		Selector:  sel,
 		To:        reference.To{Managed: &User{}, List: &UserList{}},
 		Extract: func(mg resource.Managed) string {
 			user, ok := mg.(*User)
@@ -136,11 +140,14 @@ func (mg *UserQualityOfServiceLimits) ResolveReferences(ctx context.Context, c c
 			return user.Spec.ForProvider.GroupID
 		},
 	})
+	if err != nil && rsp.ResolvedReference != nil {
+		return errors.Wrapf(err, "unable to resolve spec.forProvider.groupId: User %q has no groupId yet", rsp.ResolvedReference.Name)
+	}
 	if err != nil {
 		return errors.Wrap(err, "unable to resolve spec.forProvider.groupId")
 	}
 
-	mg.Spec.ForProvider.GroupID = rsp.ResolvedValue
+	*groupID = rsp.ResolvedValue
 
 	return nil
 }
@@ -0,0 +1,49 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"strconv"
+
+	userv1alpha1common "github.com/statnett/provider-cloudian/apis/common/user/v1alpha1"
+)
+
+// GetStatusMirror returns the ConfigMap to mirror the status of this User to.
+func (mg *User) GetStatusMirror() *userv1alpha1common.StatusMirror {
+	return mg.Spec.StatusMirror
+}
+
+// GetMirroredStatus returns the non-secret status fields of this User.
+func (mg *User) GetMirroredStatus() map[string]string {
+	return map[string]string{
+		"canonicalId":    mg.Status.AtProvider.CanonicalID,
+		"status":         mg.Status.AtProvider.Status,
+		"accessKeyCount": strconv.Itoa(mg.Status.AtProvider.AccessKeyCount),
+	}
+}
+
+// GetStatusMirror returns the ConfigMap to mirror the status of this AccessKey to.
+func (mg *AccessKey) GetStatusMirror() *userv1alpha1common.StatusMirror {
+	return mg.Spec.StatusMirror
+}
+
+// GetMirroredStatus returns the non-secret status fields of this AccessKey.
+func (mg *AccessKey) GetMirroredStatus() map[string]string {
+	return map[string]string{
+		"accessKeyId": mg.Status.AtProvider.AccessKeyID,
+	}
+}
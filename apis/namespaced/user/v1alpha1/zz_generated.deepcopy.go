@@ -21,6 +21,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	userv1alpha1 "github.com/statnett/provider-cloudian/apis/common/user/v1alpha1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -88,6 +89,11 @@ func (in *AccessKeySpec) DeepCopyInto(out *AccessKeySpec) {
 	*out = *in
 	in.ManagedResourceSpec.DeepCopyInto(&out.ManagedResourceSpec)
 	in.ForProvider.DeepCopyInto(&out.ForProvider)
+	if in.StatusMirror != nil {
+		in, out := &in.StatusMirror, &out.StatusMirror
+		*out = new(userv1alpha1.StatusMirror)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AccessKeySpec.
@@ -235,6 +241,82 @@ func (in *GroupQualityOfServiceLimitsSpec) DeepCopy() *GroupQualityOfServiceLimi
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GroupRatingPlan) DeepCopyInto(out *GroupRatingPlan) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GroupRatingPlan.
+func (in *GroupRatingPlan) DeepCopy() *GroupRatingPlan {
+	if in == nil {
+		return nil
+	}
+	out := new(GroupRatingPlan)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GroupRatingPlan) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GroupRatingPlanList) DeepCopyInto(out *GroupRatingPlanList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]GroupRatingPlan, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GroupRatingPlanList.
+func (in *GroupRatingPlanList) DeepCopy() *GroupRatingPlanList {
+	if in == nil {
+		return nil
+	}
+	out := new(GroupRatingPlanList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GroupRatingPlanList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GroupRatingPlanSpec) DeepCopyInto(out *GroupRatingPlanSpec) {
+	*out = *in
+	in.ManagedResourceSpec.DeepCopyInto(&out.ManagedResourceSpec)
+	in.ForProvider.DeepCopyInto(&out.ForProvider)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GroupRatingPlanSpec.
+func (in *GroupRatingPlanSpec) DeepCopy() *GroupRatingPlanSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GroupRatingPlanSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GroupSpec) DeepCopyInto(out *GroupSpec) {
 	*out = *in
@@ -252,6 +334,82 @@ func (in *GroupSpec) DeepCopy() *GroupSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IAMUser) DeepCopyInto(out *IAMUser) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IAMUser.
+func (in *IAMUser) DeepCopy() *IAMUser {
+	if in == nil {
+		return nil
+	}
+	out := new(IAMUser)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *IAMUser) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IAMUserList) DeepCopyInto(out *IAMUserList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]IAMUser, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IAMUserList.
+func (in *IAMUserList) DeepCopy() *IAMUserList {
+	if in == nil {
+		return nil
+	}
+	out := new(IAMUserList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *IAMUserList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *IAMUserSpec) DeepCopyInto(out *IAMUserSpec) {
+	*out = *in
+	in.ManagedResourceSpec.DeepCopyInto(&out.ManagedResourceSpec)
+	in.ForProvider.DeepCopyInto(&out.ForProvider)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new IAMUserSpec.
+func (in *IAMUserSpec) DeepCopy() *IAMUserSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(IAMUserSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *User) DeepCopyInto(out *User) {
 	*out = *in
@@ -392,6 +550,11 @@ func (in *UserSpec) DeepCopyInto(out *UserSpec) {
 	*out = *in
 	in.ManagedResourceSpec.DeepCopyInto(&out.ManagedResourceSpec)
 	in.ForProvider.DeepCopyInto(&out.ForProvider)
+	if in.StatusMirror != nil {
+		in, out := &in.StatusMirror, &out.StatusMirror
+		*out = new(userv1alpha1.StatusMirror)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UserSpec.
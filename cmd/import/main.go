@@ -0,0 +1,65 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Command import prints the manifests of User and AccessKey managed resources
+// that adopt the existing users of a Cloudian group.
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/alecthomas/kingpin/v2"
+
+	"github.com/statnett/provider-cloudian/internal/importer"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
+)
+
+func main() {
+	var (
+		app = kingpin.New(filepath.Base(os.Args[0]), "Print manifests adopting existing Cloudian users and access keys.").DefaultEnvars()
+
+		endpoint   = app.Flag("endpoint", "The Cloudian admin API endpoint, e.g. https://s3-admin.company.com:19443.").Required().String()
+		authHeader = app.Flag("auth-header", "The Authorization header of admin API requests, e.g. Basic c3lzYWRtaW46cGFzc3dvcmQ=.").Required().String()
+		caBundle   = app.Flag("ca-bundle", "A PEM file of certificates to trust in addition to the system ones.").ExistingFile()
+
+		groupID = app.Flag("group", "The group to import users from.").Required().String()
+		prefix  = app.Flag("prefix", "Only import users whose user ID starts with this prefix.").String()
+
+		providerConfig            = app.Flag("provider-config", "The ProviderConfig of the managed resources.").Default("default").String()
+		observeOnly               = app.Flag("observe-only", "Only observe the imported users and access keys, by setting managementPolicies to [\"Observe\"].").Bool()
+		connectionSecretNamespace = app.Flag("connection-secret-namespace", "Write the access keys as connection secrets to this namespace.").String()
+	)
+	kingpin.MustParse(app.Parse(os.Args[1:]))
+
+	var opts []func(*cloudian.Client)
+	if *caBundle != "" {
+		pem, err := os.ReadFile(*caBundle)
+		kingpin.FatalIfError(err, "Cannot read CA bundle")
+		opts = append(opts, cloudian.WithCACert(pem))
+	}
+	client, err := cloudian.NewClientWithValidation(*endpoint, *authHeader, opts...)
+	kingpin.FatalIfError(err, "Cannot use endpoint")
+
+	mrs, err := importer.Import(context.Background(), client, *groupID, *prefix, importer.Options{
+		ProviderConfig:            *providerConfig,
+		ObserveOnly:               *observeOnly,
+		ConnectionSecretNamespace: *connectionSecretNamespace,
+	})
+	kingpin.FatalIfError(err, "Cannot import users of group %s", *groupID)
+	kingpin.FatalIfError(importer.Write(os.Stdout, mrs), "Cannot write manifests")
+}
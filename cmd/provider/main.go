@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net"
 	"os"
 	"path/filepath"
 	"time"
@@ -28,6 +29,10 @@ import (
 	"github.com/crossplane/crossplane-runtime/v2/pkg/errors"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/gate"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/customresourcesgate"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 	authv1 "k8s.io/api/authorization/v1"
@@ -36,9 +41,11 @@ import (
 	"k8s.io/client-go/tools/leaderelection/resourcelock"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
 	changelogsv1alpha1 "github.com/crossplane/crossplane-runtime/v2/apis/changelogs/proto/v1alpha1"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/controller"
@@ -49,12 +56,23 @@ import (
 	"github.com/crossplane/crossplane-runtime/v2/pkg/statemetrics"
 
 	apiscluster "github.com/statnett/provider-cloudian/apis/cluster"
+	apisv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/v1alpha1"
 	apisnamespaced "github.com/statnett/provider-cloudian/apis/namespaced"
 	controllercluster "github.com/statnett/provider-cloudian/internal/controller/cluster"
+	controllercommon "github.com/statnett/provider-cloudian/internal/controller/common"
 	controllernamespaced "github.com/statnett/provider-cloudian/internal/controller/namespaced"
+	"github.com/statnett/provider-cloudian/internal/features"
+	"github.com/statnett/provider-cloudian/internal/inventory"
+	"github.com/statnett/provider-cloudian/internal/providerstatus"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
+	"github.com/statnett/provider-cloudian/internal/selfcheck"
+	"github.com/statnett/provider-cloudian/internal/storageversion"
+	"github.com/statnett/provider-cloudian/internal/strictfields"
 	"github.com/statnett/provider-cloudian/internal/version"
 )
 
+const selfCheckTimeout = 30 * time.Second
+
 func main() {
 	var (
 		app            = kingpin.New(filepath.Base(os.Args[0]), "Cloudian support for Crossplane.").DefaultEnvars()
@@ -65,11 +83,29 @@ func main() {
 		pollInterval            = app.Flag("poll", "How often individual resources will be checked for drift from the desired state").Default("1m").Duration()
 		pollStateMetricInterval = app.Flag("poll-state-metric", "State metric recording interval").Default("5s").Duration()
 
+		userTimeout      = app.Flag("user-timeout", "The maximum duration of a single reconcile of a User.").Default(controllercommon.DefaultTimeouts.User.String()).Duration()
+		accessKeyTimeout = app.Flag("access-key-timeout", "The maximum duration of a single reconcile of an AccessKey.").Default(controllercommon.DefaultTimeouts.AccessKey.String()).Duration()
+		groupTimeout     = app.Flag("group-timeout", "The maximum duration of a single reconcile of a Group.").Default(controllercommon.DefaultTimeouts.Group.String()).Duration()
+		qosTimeout       = app.Flag("qos-timeout", "The maximum duration of a single reconcile of quality of service limits.").Default(controllercommon.DefaultTimeouts.QualityOfServiceLimits.String()).Duration()
+
 		maxReconcileRate = app.Flag("max-reconcile-rate", "The global maximum rate per second at which resources may checked for drift from the desired state.").Default("10").Int()
 
 		enableManagementPolicies = app.Flag("enable-management-policies", "Enable support for Management Policies.").Default("true").Envar("ENABLE_MANAGEMENT_POLICIES").Bool()
 		enableChangeLogs         = app.Flag("enable-changelogs", "Enable support for capturing change logs during reconciliation.").Default("false").Envar("ENABLE_CHANGE_LOGS").Bool()
 		changelogsSocketPath     = app.Flag("changelogs-socket-path", "Path for changelogs socket (if enabled)").Default("/var/run/changelogs/changelogs.sock").Envar("CHANGELOGS_SOCKET_PATH").String()
+
+		enableUnmanagedAccessKeyCheck = app.Flag("enable-unmanaged-access-key-check", "Warn about access keys of users not managed by an AccessKey. Lists the access keys of every user on each poll.").Default("true").Envar("ENABLE_UNMANAGED_ACCESS_KEY_CHECK").Bool()
+		enableBucketObservation       = app.Flag("enable-bucket-observation", "Show the buckets of users and their usage in the status of Users. Lists the buckets of every user on each poll.").Default("false").Envar("ENABLE_BUCKET_OBSERVATION").Bool()
+		enableGroupUserCount          = app.Flag("enable-group-user-count", "Show the number of users of groups in the status of Groups. Lists the users of every group on each poll.").Default("false").Envar("ENABLE_GROUP_USER_COUNT").Bool()
+		enableProviderStatus          = app.Flag("enable-provider-status", "Maintain a ProviderStatus named provider-cloudian summarizing the managed resources, the health of ProviderConfigs and the errors of the provider, refreshed every poll interval from the informer caches.").Default("false").Envar("ENABLE_PROVIDER_STATUS").Bool()
+
+		selfCheck              = app.Flag("self-check", "Verify CRDs, RBAC and Cloudian admin API reachability at startup. Exit on failure when strict.").Default(selfcheck.ModeOff).Envar("SELF_CHECK").Enum(selfcheck.ModeOff, selfcheck.ModeOn, selfcheck.ModeStrict)
+		migrateStorageVersions = app.Flag("migrate-storage-versions", "Rewrite the custom resources of the provider in the storage version of their CRD once elected leader, so that older API versions can be removed.").Default("false").Envar("MIGRATE_STORAGE_VERSIONS").Bool()
+		strictFields           = app.Flag("strict-fields", "Reject Groups and quality of service limits applied by kubectl with spec fields unknown to their API, which the API server silently drops. Serves a validating webhook.").Default("false").Envar("STRICT_FIELDS").Bool()
+		webhookCertDir         = app.Flag("webhook-cert-dir", "Directory of the TLS certificate and key of the webhook server.").Default("/tls/server").Envar("TLS_SERVER_CERTS_DIR").String()
+		namespace              = app.Flag("namespace", "Namespace the provider is running in.").Default("crossplane-system").Envar("POD_NAMESPACE").String()
+		devEndpointOverride    = app.Flag("dev-endpoint-override", "Connect to this host:port instead of the admin API endpoints of every ProviderConfig, such as a local kubectl port-forward, when running the provider out of cluster. The endpoints still name the host of requests and of the TLS certificate of Cloudian.").Envar("DEV_ENDPOINT_OVERRIDE").String()
+		enableTracing          = app.Flag("enable-tracing", "Export OpenTelemetry traces of reconciles and of their requests to the Cloudian admin API over OTLP, configured by the standard OTEL_EXPORTER_OTLP_* environment variables.").Default("false").Envar("ENABLE_TRACING").Bool()
 	)
 	kingpin.MustParse(app.Parse(os.Args[1:]))
 
@@ -86,6 +122,13 @@ func main() {
 		ctrl.SetLogger(zap.New(zap.WriteTo(io.Discard)))
 	}
 
+	if *devEndpointOverride != "" {
+		_, _, err := net.SplitHostPort(*devEndpointOverride)
+		kingpin.FatalIfError(err, "Cannot use --dev-endpoint-override, which must be a host:port")
+		log.Info("Connecting to the admin API of every ProviderConfig through the endpoint override, for development only", "address", *devEndpointOverride)
+		controllercommon.EndpointOverride = *devEndpointOverride
+	}
+
 	cfg, err := ctrl.GetConfig()
 	kingpin.FatalIfError(err, "Cannot get API server rest config")
 
@@ -108,6 +151,9 @@ func main() {
 		LeaderElectionResourceLock: resourcelock.LeasesResourceLock,
 		LeaseDuration:              func() *time.Duration { d := 60 * time.Second; return &d }(),
 		RenewDeadline:              func() *time.Duration { d := 50 * time.Second; return &d }(),
+
+		// The webhook server is only started once a webhook is registered.
+		WebhookServer: webhook.NewServer(webhook.Options{CertDir: *webhookCertDir}),
 	})
 	kingpin.FatalIfError(err, "Cannot create controller manager")
 
@@ -115,12 +161,47 @@ func main() {
 	kingpin.FatalIfError(apiscluster.AddToScheme(mgr.GetScheme()), "Cannot add cluster-scoped Cloudian APIs to scheme")
 	kingpin.FatalIfError(apisnamespaced.AddToScheme(mgr.GetScheme()), "Cannot add namespace-scoped Cloudian APIs to scheme")
 
+	if *selfCheck != selfcheck.ModeOff {
+		kube, err := client.New(cfg, client.Options{Scheme: mgr.GetScheme()})
+		kingpin.FatalIfError(err, "Cannot create self-check client")
+
+		ctx, cancel := context.WithTimeout(context.Background(), selfCheckTimeout)
+		failed := selfcheck.Report(log, selfcheck.New(kube, *namespace).Run(ctx))
+		cancel()
+		if failed && *selfCheck == selfcheck.ModeStrict {
+			kingpin.Fatalf("Self-check failed")
+		}
+	}
+
+	if *migrateStorageVersions {
+		kube, err := client.New(cfg, client.Options{Scheme: mgr.GetScheme()})
+		kingpin.FatalIfError(err, "Cannot create storage version migration client")
+
+		migrator := storageversion.New(kube, log)
+		metrics.Registry.MustRegister(migrator)
+		kingpin.FatalIfError(mgr.Add(migrator), "Cannot add storage version migration")
+	}
+
+	if *strictFields {
+		strictfields.Setup(mgr)
+		log.Info("Rejecting unknown spec fields", "path", strictfields.Path)
+	}
+
 	metricRecorder := managed.NewMRMetricRecorder()
 	stateMetrics := statemetrics.NewMRStateMetrics()
 
 	metrics.Registry.MustRegister(metricRecorder)
 	metrics.Registry.MustRegister(stateMetrics)
 
+	inv := inventory.New()
+	kingpin.FatalIfError(inv.Watch(context.Background(), mgr.GetCache()), "Cannot watch managed resources for inventory metrics")
+	metrics.Registry.MustRegister(inv)
+
+	if *enableProviderStatus {
+		kingpin.FatalIfError(mgr.Add(providerstatus.New(mgr.GetClient(), log, providerstatus.WithInterval(*pollInterval))), "Cannot add ProviderStatus aggregation")
+		log.Info("Maintaining a ProviderStatus", "name", apisv1alpha1cluster.ProviderStatusName)
+	}
+
 	o := controller.Options{
 		Logger:                  log,
 		MaxConcurrentReconciles: *maxReconcileRate,
@@ -139,6 +220,21 @@ func main() {
 		log.Info("Beta feature enabled", "flag", feature.EnableBetaManagementPolicies)
 	}
 
+	if *enableUnmanagedAccessKeyCheck {
+		o.Features.Enable(features.EnableUnmanagedAccessKeyCheck)
+		log.Info("Feature enabled", "flag", features.EnableUnmanagedAccessKeyCheck)
+	}
+
+	if *enableBucketObservation {
+		o.Features.Enable(features.EnableBucketObservation)
+		log.Info("Feature enabled", "flag", features.EnableBucketObservation)
+	}
+
+	if *enableGroupUserCount {
+		o.Features.Enable(features.EnableGroupUserCount)
+		log.Info("Feature enabled", "flag", features.EnableGroupUserCount)
+	}
+
 	if *enableChangeLogs {
 		o.Features.Enable(feature.EnableAlphaChangeLogs)
 		log.Info("Alpha feature enabled", "flag", feature.EnableAlphaChangeLogs)
@@ -154,6 +250,28 @@ func main() {
 		o.ChangeLogOptions = &clo
 	}
 
+	controllercommon.ReconcileTimeouts = controllercommon.Timeouts{
+		User:                   *userTimeout,
+		AccessKey:              *accessKeyTimeout,
+		Group:                  *groupTimeout,
+		QualityOfServiceLimits: *qosTimeout,
+	}
+	// Groups rarely change outside the provider, and the provider invalidates
+	// the groups it mutates, so cache them for half a poll interval.
+	controllercommon.GroupCacheTTL = *pollInterval / 2
+	controllercommon.MetricsRegistry = metrics.Registry
+	controllercommon.ProviderNamespace = *namespace
+	if *enableTracing {
+		tp, err := newTracerProvider(context.Background())
+		kingpin.FatalIfError(err, "Cannot create OpenTelemetry tracer provider")
+		kingpin.FatalIfError(mgr.Add(manager.RunnableFunc(func(ctx context.Context) error {
+			<-ctx.Done()
+			return tp.Shutdown(context.Background())
+		})), "Cannot add OpenTelemetry tracer provider shutdown")
+		controllercommon.TracerProvider = tp
+	}
+	log.Info("Identifying requests to the Cloudian admin API", "userAgent", cloudian.DefaultUserAgent)
+
 	canSafeStart, err := canWatchCRD(context.Background(), mgr)
 	kingpin.FatalIfError(err, "SafeStart precheck failed")
 
@@ -171,6 +289,25 @@ func main() {
 	kingpin.FatalIfError(mgr.Start(ctrl.SetupSignalHandler()), "Cannot start controller manager")
 }
 
+// newTracerProvider returns a tracer provider exporting spans over OTLP to
+// the collector configured by the OTEL_EXPORTER_OTLP_* environment variables.
+// The service name defaults to provider-cloudian, unless OTEL_SERVICE_NAME
+// says otherwise.
+func newTracerProvider(ctx context.Context) (*sdktrace.TracerProvider, error) {
+	exporter, err := otlptracegrpc.New(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create OTLP trace exporter")
+	}
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", "provider-cloudian"), attribute.String("service.version", version.Version)),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot describe the provider to the trace collector")
+	}
+	return sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res)), nil
+}
+
 func canWatchCRD(ctx context.Context, mgr manager.Manager) (bool, error) {
 	if err := authv1.AddToScheme(mgr.GetScheme()); err != nil {
 		return false, err
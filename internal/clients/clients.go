@@ -0,0 +1,107 @@
+// Package clients shares Cloudian clients between reconciles, so that
+// connections to the Cloudian admin API are reused.
+package clients
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	pcv1alpha1common "github.com/statnett/provider-cloudian/apis/common/providerconfig/v1alpha1"
+	controllercommon "github.com/statnett/provider-cloudian/internal/controller/common"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
+)
+
+// Shared is the cache shared by all controllers of the provider.
+var Shared = NewCache(controllercommon.NewCloudianService)
+
+type entry struct {
+	hash   string
+	client *cloudian.Client
+}
+
+// A Cache holds one Cloudian client per ProviderConfig, until the
+// ProviderConfig is deleted.
+type Cache struct {
+	mu           sync.Mutex
+	clients      map[types.UID]entry
+	newServiceFn func(spec pcv1alpha1common.ProviderConfigSpec, creds controllercommon.Credentials) (*cloudian.Client, error)
+}
+
+// NewCache returns an empty Cache that creates clients using the supplied function.
+func NewCache(newServiceFn func(spec pcv1alpha1common.ProviderConfigSpec, creds controllercommon.Credentials) (*cloudian.Client, error)) *Cache {
+	return &Cache{
+		clients:      map[types.UID]entry{},
+		newServiceFn: newServiceFn,
+	}
+}
+
+// Get returns the client of the ProviderConfig with the supplied UID. The
+// client is replaced when the spec or credentials of the ProviderConfig have
+// changed since it was created.
+func (c *Cache) Get(uid types.UID, spec pcv1alpha1common.ProviderConfigSpec, creds controllercommon.Credentials) (*cloudian.Client, error) {
+	h, err := hash(spec, creds)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.clients[uid]; ok && e.hash == h {
+		return e.client, nil
+	}
+
+	svc, err := c.newServiceFn(spec, creds)
+	if err != nil {
+		return nil, err
+	}
+	if e, ok := c.clients[uid]; ok {
+		e.client.CloseIdleConnections()
+	}
+	c.clients[uid] = entry{hash: h, client: svc}
+	return svc, nil
+}
+
+// Evict drops the client of the ProviderConfig with the supplied UID, and
+// closes its idle connections.
+func (c *Cache) Evict(uid types.UID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.clients[uid]; ok {
+		e.client.CloseIdleConnections()
+		delete(c.clients, uid)
+	}
+}
+
+// EvictOnDelete returns an event handler of ProviderConfigs that evicts the
+// client of each ProviderConfig deleted, as its UID is never used again. It
+// enqueues no requests.
+func (c *Cache) EvictOnDelete() handler.EventHandler {
+	return handler.Funcs{
+		DeleteFunc: func(_ context.Context, e event.DeleteEvent, _ workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+			c.Evict(e.Object.GetUID())
+		},
+	}
+}
+
+func hash(spec pcv1alpha1common.ProviderConfigSpec, creds controllercommon.Credentials) (string, error) {
+	b, err := json.Marshal(struct {
+		Spec  pcv1alpha1common.ProviderConfigSpec
+		Creds controllercommon.Credentials
+	}{spec, creds})
+	if err != nil {
+		return "", err
+	}
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:]), nil
+}
@@ -0,0 +1,88 @@
+package clients
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	apisv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/v1alpha1"
+	pcv1alpha1common "github.com/statnett/provider-cloudian/apis/common/providerconfig/v1alpha1"
+	controllercommon "github.com/statnett/provider-cloudian/internal/controller/common"
+)
+
+func TestGet(t *testing.T) {
+	spec := pcv1alpha1common.ProviderConfigSpec{Endpoint: "https://cloudian.example.com:19443"}
+	cache := NewCache(controllercommon.NewCloudianService)
+
+	first, err := cache.Get("uid", spec, controllercommon.Credentials{AuthHeader: "Basic Zm9vOmJhcg=="})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	same, err := cache.Get("uid", spec, controllercommon.Credentials{AuthHeader: "Basic Zm9vOmJhcg=="})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if same != first {
+		t.Error("Get(...): expected the same client for an identical config")
+	}
+
+	other, err := cache.Get("other-uid", spec, controllercommon.Credentials{AuthHeader: "Basic Zm9vOmJhcg=="})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == first {
+		t.Error("Get(...): expected a new client for another ProviderConfig")
+	}
+
+	rotated, err := cache.Get("uid", spec, controllercommon.Credentials{AuthHeader: "Basic YmFyOmJheg=="})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated == first {
+		t.Error("Get(...): expected a new client after the auth header changed")
+	}
+
+	spec.RequestsPerSecond = ptr.To[int32](10)
+	limited, err := cache.Get("uid", spec, controllercommon.Credentials{AuthHeader: "Basic YmFyOmJheg=="})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limited == rotated {
+		t.Error("Get(...): expected a new client after the spec changed")
+	}
+}
+
+func TestEvictOnDelete(t *testing.T) {
+	spec := pcv1alpha1common.ProviderConfigSpec{Endpoint: "https://cloudian.example.com:19443"}
+	creds := controllercommon.Credentials{AuthHeader: "Basic Zm9vOmJhcg=="}
+	cache := NewCache(controllercommon.NewCloudianService)
+
+	first, err := cache.Get("uid", spec, creds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.Get("other-uid", spec, creds); err != nil {
+		t.Fatal(err)
+	}
+
+	pc := &apisv1alpha1cluster.ProviderConfig{ObjectMeta: metav1.ObjectMeta{Name: "default", UID: "uid"}}
+	cache.EvictOnDelete().Delete(context.Background(), event.DeleteEvent{Object: pc}, nil)
+	if _, ok := cache.clients["uid"]; ok {
+		t.Error("EvictOnDelete(): want the client of the deleted ProviderConfig evicted")
+	}
+	if _, ok := cache.clients["other-uid"]; !ok {
+		t.Error("EvictOnDelete(): want the clients of other ProviderConfigs kept")
+	}
+
+	again, err := cache.Get("uid", spec, creds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again == first {
+		t.Error("Get(...): expected a new client after the ProviderConfig was evicted")
+	}
+}
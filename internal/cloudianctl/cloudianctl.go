@@ -0,0 +1,389 @@
+// Package cloudianctl is an admin CLI of Cloudian built on the SDK, for the
+// break-glass operations on groups, users and credentials needed while
+// Crossplane cannot make them, such as rotating an access key during an
+// outage of the provider.
+package cloudianctl
+
+import (
+	"context"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	pcv1alpha1common "github.com/statnett/provider-cloudian/apis/common/providerconfig/v1alpha1"
+	controllercommon "github.com/statnett/provider-cloudian/internal/controller/common"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
+	pkgclient "github.com/statnett/provider-cloudian/pkg/client"
+)
+
+// Exit codes of Run.
+const (
+	ExitOK = 0
+	// ExitFailure is the exit code of commands that failed.
+	ExitFailure = 1
+	// ExitUsage is the exit code of invalid command lines.
+	ExitUsage = 2
+	// ExitNotFound is the exit code of commands whose group, user or
+	// credentials do not exist.
+	ExitNotFound = 4
+)
+
+const (
+	errSource         = "either --endpoint and --auth-header, or --provider-config, is required"
+	errSources        = "--endpoint and --provider-config are mutually exclusive"
+	errReadCABundle   = "cannot read CA bundle"
+	errEndpoint       = "cannot use endpoint"
+	errKubeconfig     = "cannot load kubeconfig"
+	errNewKubeClient  = "cannot create Kubernetes client"
+	errGetPC          = "cannot get ProviderConfig %s"
+	errGetCreds       = "cannot get credentials of ProviderConfig %s"
+	errNewClient      = "cannot create Cloudian client of ProviderConfig %s"
+	errGetGroup       = "cannot get group %s"
+	errCreateGroup    = "cannot create group %s"
+	errDeleteGroup    = "cannot delete group %s"
+	errListUsers      = "cannot list users of group %s"
+	errCreateUser     = "cannot create user %s"
+	errDeleteUser     = "cannot delete user %s"
+	errListCreds      = "cannot list access keys of user %s"
+	errCreateCreds    = "cannot create access key of user %s"
+	errDeleteCreds    = "cannot delete access key %s"
+	errNotUserKey     = "access key %s of user %s"
+	errDeleteReplaced = "created access key %s, but cannot delete access key %s"
+)
+
+// cli is the command line of cloudianctl, once parsed.
+type cli struct {
+	stdout, stderr io.Writer
+
+	endpoint       string
+	authHeader     string
+	caBundle       string
+	providerConfig string
+	namespace      string
+	kubeconfig     string
+	kubeContext    string
+	output         string
+
+	groupID     string
+	userID      string
+	accessKey   string
+	groupName   string
+	userType    string
+	prefix      string
+	showSecrets bool
+}
+
+// Run runs cloudianctl with the supplied arguments, without the name of the
+// program, and returns its exit code.
+func Run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	c := &cli{stdout: stdout, stderr: stderr}
+	app := kingpin.New("cloudianctl", "Manage Cloudian groups, users and access keys through the admin API, without Crossplane.").DefaultEnvars()
+
+	terminated := -1
+	app.UsageWriter(stdout).ErrorWriter(stderr).Terminate(func(code int) {
+		if terminated < 0 {
+			terminated = code
+		}
+	})
+
+	app.Flag("endpoint", "The Cloudian admin API endpoint, e.g. https://s3-admin.company.com:19443.").StringVar(&c.endpoint)
+	app.Flag("auth-header", "The Authorization header of admin API requests, e.g. Basic c3lzYWRtaW46cGFzc3dvcmQ=.").StringVar(&c.authHeader)
+	app.Flag("ca-bundle", "A PEM file of certificates to trust in addition to the system ones.").ExistingFileVar(&c.caBundle)
+	app.Flag("provider-config", "Use the endpoint and credentials of this ProviderConfig, rather than --endpoint and --auth-header.").StringVar(&c.providerConfig)
+	app.Flag("namespace", "The namespace of a namespaced --provider-config. Cluster scoped ProviderConfigs have none.").StringVar(&c.namespace)
+	app.Flag("kubeconfig", "The kubeconfig of the cluster of --provider-config. Defaults to $KUBECONFIG or ~/.kube/config.").StringVar(&c.kubeconfig)
+	app.Flag("context", "The kubeconfig context of the cluster of --provider-config.").StringVar(&c.kubeContext)
+	app.Flag("output", "The output format.").Short('o').Default(outputTable).EnumVar(&c.output, outputTable, outputJSON)
+
+	group := app.Command("group", "Manage groups.")
+	groupGet := group.Command("get", "Print a group.")
+	groupGet.Arg("group", "The ID of the group.").Required().StringVar(&c.groupID)
+	groupCreate := group.Command("create", "Create a group.")
+	groupCreate.Arg("group", "The ID of the group.").Required().StringVar(&c.groupID)
+	groupCreate.Flag("name", "The name of the group.").StringVar(&c.groupName)
+	groupDelete := group.Command("delete", "Delete a group without users.")
+	groupDelete.Arg("group", "The ID of the group.").Required().StringVar(&c.groupID)
+
+	user := app.Command("user", "Manage the users of a group.")
+	user.Flag("group", "The ID of the group of the users.").Required().StringVar(&c.groupID)
+	userList := user.Command("list", "List the users of the group.")
+	userList.Flag("prefix", "Only list users whose user ID starts with this prefix.").StringVar(&c.prefix)
+	userCreate := user.Command("create", "Create a user. Cloudian creates an access key along with it, printed by credentials list.")
+	userCreate.Arg("user", "The ID of the user.").Required().StringVar(&c.userID)
+	userCreate.Flag("type", "The type of the user.").Default(string(cloudian.UserTypeStandard)).
+		EnumVar(&c.userType, string(cloudian.UserTypeStandard), string(cloudian.UserTypeGroupAdmin), string(cloudian.UserTypeSystemAdmin))
+	userDelete := user.Command("delete", "Delete a user and its access keys.")
+	userDelete.Arg("user", "The ID of the user.").Required().StringVar(&c.userID)
+
+	creds := app.Command("credentials", "Manage the access keys of a user.")
+	credsList := creds.Command("list", "List the access keys of a user, with their secret keys redacted.")
+	credsList.Flag("group", "The ID of the group of the user.").Required().StringVar(&c.groupID)
+	credsList.Arg("user", "The ID of the user.").Required().StringVar(&c.userID)
+	credsList.Flag("show-secrets", "Print the secret keys rather than redacting them.").BoolVar(&c.showSecrets)
+	credsCreate := creds.Command("create", "Create an access key of a user, and print it with its secret key.")
+	credsCreate.Flag("group", "The ID of the group of the user.").Required().StringVar(&c.groupID)
+	credsCreate.Arg("user", "The ID of the user.").Required().StringVar(&c.userID)
+	credsDelete := creds.Command("delete", "Delete an access key.")
+	credsDelete.Arg("access-key", "The access key.").Required().StringVar(&c.accessKey)
+	credsRotate := creds.Command("rotate", "Replace an access key of a user with a new one, printed with its secret key.")
+	credsRotate.Flag("group", "The ID of the group of the user.").Required().StringVar(&c.groupID)
+	credsRotate.Arg("user", "The ID of the user.").Required().StringVar(&c.userID)
+	credsRotate.Arg("access-key", "The access key to replace.").Required().StringVar(&c.accessKey)
+
+	cmd, err := app.Parse(args)
+	switch {
+	case terminated >= 0:
+		return terminated
+	case err != nil:
+		app.Errorf("%s", err)
+		return ExitUsage
+	}
+
+	svc, err := c.connect(ctx)
+	if err != nil {
+		app.Errorf("%s", err)
+		return exitCode(err)
+	}
+
+	commands := map[string]func(context.Context, *cloudian.Client) error{
+		groupGet.FullCommand():    c.groupGet,
+		groupCreate.FullCommand(): c.groupCreate,
+		groupDelete.FullCommand(): c.groupDelete,
+		userList.FullCommand():    c.userList,
+		userCreate.FullCommand():  c.userCreate,
+		userDelete.FullCommand():  c.userDelete,
+		credsList.FullCommand():   c.credentialsList,
+		credsCreate.FullCommand(): c.credentialsCreate,
+		credsDelete.FullCommand(): c.credentialsDelete,
+		credsRotate.FullCommand(): c.credentialsRotate,
+	}
+	if err := commands[cmd](ctx, svc); err != nil {
+		app.Errorf("%s", err)
+		return exitCode(err)
+	}
+	return ExitOK
+}
+
+// exitCode returns the exit code of a command that failed with `err`.
+func exitCode(err error) int {
+	var usage usageError
+	switch {
+	case errors.As(err, &usage):
+		return ExitUsage
+	case errors.Is(err, cloudian.ErrNotFound):
+		return ExitNotFound
+	default:
+		return ExitFailure
+	}
+}
+
+// A usageError is an invalid combination of flags.
+type usageError string
+
+func (e usageError) Error() string {
+	return string(e)
+}
+
+// connect returns a client of the admin API, from either the endpoint and
+// auth header flags or the referenced ProviderConfig.
+func (c *cli) connect(ctx context.Context) (*cloudian.Client, error) {
+	switch {
+	case c.providerConfig != "" && c.endpoint != "":
+		return nil, usageError(errSources)
+	case c.providerConfig != "":
+		kube, err := c.kubeClient()
+		if err != nil {
+			return nil, err
+		}
+		return serviceFromProviderConfig(ctx, kube, c.providerConfig, c.namespace)
+	case c.endpoint == "" || c.authHeader == "":
+		return nil, usageError(errSource)
+	}
+
+	var opts []func(*cloudian.Client)
+	if c.caBundle != "" {
+		pem, err := os.ReadFile(c.caBundle)
+		if err != nil {
+			return nil, errors.Wrap(err, errReadCABundle)
+		}
+		opts = append(opts, cloudian.WithCACert(pem))
+	}
+	svc, err := cloudian.NewClientWithValidation(c.endpoint, c.authHeader, opts...)
+	return svc, errors.Wrap(err, errEndpoint)
+}
+
+// kubeClient returns a client of the cluster of the kubeconfig, reading the
+// ProviderConfigs of the provider and the secrets they refer to.
+func (c *cli) kubeClient() (client.WithWatch, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = c.kubeconfig
+	cfg, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{CurrentContext: c.kubeContext}).ClientConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, errKubeconfig)
+	}
+	s, err := pkgclient.NewScheme()
+	if err != nil {
+		return nil, err
+	}
+	if err := corev1.AddToScheme(s); err != nil {
+		return nil, errors.Wrap(err, errNewKubeClient)
+	}
+	kube, err := client.NewWithWatch(cfg, client.Options{Scheme: s})
+	return kube, errors.Wrap(err, errNewKubeClient)
+}
+
+// serviceFromProviderConfig returns a client of the admin API configured like
+// the provider's are by the ProviderConfig `name`, which is cluster scoped
+// unless it has a namespace.
+func serviceFromProviderConfig(ctx context.Context, kube client.WithWatch, name, namespace string) (*cloudian.Client, error) {
+	cs := pkgclient.NewForClient(kube)
+	key := client.ObjectKey{Namespace: namespace, Name: name}
+	ref := name
+	var spec pcv1alpha1common.ProviderConfigSpec
+	if namespace == "" {
+		pc, err := cs.Cluster.ProviderConfigs.Get(ctx, key)
+		if err != nil {
+			return nil, errors.Wrapf(err, errGetPC, ref)
+		}
+		spec = pc.Spec
+	} else {
+		ref = key.String()
+		pc, err := cs.Namespaced.ProviderConfigs.Get(ctx, key)
+		if err != nil {
+			return nil, errors.Wrapf(err, errGetPC, ref)
+		}
+		spec = pc.Spec
+	}
+
+	creds, err := controllercommon.ExtractCredentials(ctx, kube, spec)
+	if err != nil {
+		return nil, errors.Wrapf(err, errGetCreds, ref)
+	}
+	svc, err := controllercommon.NewCloudianService(spec, creds)
+	return svc, errors.Wrapf(err, errNewClient, ref)
+}
+
+func (c *cli) groupGet(ctx context.Context, svc *cloudian.Client) error {
+	group, err := svc.GetGroup(ctx, c.groupID)
+	if err != nil {
+		return errors.Wrapf(err, errGetGroup, c.groupID)
+	}
+	return c.write(group, groupTable(*group))
+}
+
+func (c *cli) groupCreate(ctx context.Context, svc *cloudian.Client) error {
+	group := cloudian.NewGroup(c.groupID)
+	group.GroupName = c.groupName
+	group.Active = true
+	created, err := svc.CreateGroup(ctx, group)
+	if err != nil {
+		return errors.Wrapf(err, errCreateGroup, c.groupID)
+	}
+	return c.write(created, groupTable(*created))
+}
+
+func (c *cli) groupDelete(ctx context.Context, svc *cloudian.Client) error {
+	if err := svc.DeleteGroup(ctx, c.groupID); err != nil {
+		return errors.Wrapf(err, errDeleteGroup, c.groupID)
+	}
+	c.deleted("group", c.groupID)
+	return nil
+}
+
+// userList lists the users of a group. A group without users is checked to
+// exist, so that a misspelled group is not mistaken for an empty one.
+func (c *cli) userList(ctx context.Context, svc *cloudian.Client) error {
+	users, err := svc.SearchUsers(ctx, c.groupID, c.prefix)
+	if err != nil {
+		return errors.Wrapf(err, errListUsers, c.groupID)
+	}
+	if len(users) == 0 {
+		if _, err := svc.GetGroup(ctx, c.groupID); err != nil {
+			return errors.Wrapf(err, errGetGroup, c.groupID)
+		}
+	}
+	return c.write(nonNil(users), userTable(users...))
+}
+
+func (c *cli) userCreate(ctx context.Context, svc *cloudian.Client) error {
+	created, err := svc.CreateUser(ctx, cloudian.User{GroupUserID: c.guid(), UserType: cloudian.UserType(c.userType)})
+	if err != nil {
+		return errors.Wrapf(err, errCreateUser, c.userID)
+	}
+	return c.write(created, userTable(*created))
+}
+
+func (c *cli) userDelete(ctx context.Context, svc *cloudian.Client) error {
+	if err := svc.DeleteUser(ctx, c.guid()); err != nil {
+		return errors.Wrapf(err, errDeleteUser, c.userID)
+	}
+	c.deleted("user", c.userID)
+	return nil
+}
+
+func (c *cli) credentialsList(ctx context.Context, svc *cloudian.Client) error {
+	list, err := svc.ListUserCredentials(ctx, c.guid())
+	if err != nil {
+		return errors.Wrapf(err, errListCreds, c.userID)
+	}
+	creds := make([]credentials, 0, len(list))
+	for _, info := range list {
+		creds = append(creds, newCredentials(c.guid(), info, c.showSecrets))
+	}
+	return c.write(creds, credentialsTable(creds...))
+}
+
+func (c *cli) credentialsCreate(ctx context.Context, svc *cloudian.Client) error {
+	info, err := svc.CreateUserCredentials(ctx, c.guid())
+	if err != nil {
+		return errors.Wrapf(err, errCreateCreds, c.userID)
+	}
+	creds := newCredentials(c.guid(), *info, true)
+	return c.write(creds, credentialsTable(creds))
+}
+
+func (c *cli) credentialsDelete(ctx context.Context, svc *cloudian.Client) error {
+	if err := svc.DeleteUserCredentials(ctx, c.accessKey); err != nil {
+		return errors.Wrapf(err, errDeleteCreds, c.accessKey)
+	}
+	c.deleted("access key", c.accessKey)
+	return nil
+}
+
+// credentialsRotate replaces an access key of a user. The new access key is
+// printed before the old one is deleted, so that it is not lost when the
+// deletion fails.
+func (c *cli) credentialsRotate(ctx context.Context, svc *cloudian.Client) error {
+	list, err := svc.ListUserCredentials(ctx, c.guid())
+	if err != nil {
+		return errors.Wrapf(err, errListCreds, c.userID)
+	}
+	if !slices.ContainsFunc(list, func(info cloudian.SecurityInfo) bool { return info.AccessKey == c.accessKey }) {
+		return errors.Wrapf(cloudian.ErrNotFound, errNotUserKey, c.accessKey, c.userID)
+	}
+
+	info, err := svc.CreateUserCredentials(ctx, c.guid())
+	if err != nil {
+		return errors.Wrapf(err, errCreateCreds, c.userID)
+	}
+	creds := newCredentials(c.guid(), *info, true)
+	if err := c.write(creds, credentialsTable(creds)); err != nil {
+		return err
+	}
+
+	if err := svc.DeleteUserCredentials(ctx, c.accessKey); err != nil {
+		return errors.Wrapf(err, errDeleteReplaced, info.AccessKey, c.accessKey)
+	}
+	c.deleted("access key", c.accessKey)
+	return nil
+}
+
+func (c *cli) guid() cloudian.GroupUserID {
+	return cloudian.GroupUserID{GroupID: c.groupID, UserID: c.userID}
+}
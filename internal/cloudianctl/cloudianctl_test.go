@@ -0,0 +1,270 @@
+package cloudianctl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	apisv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/v1alpha1"
+	pcv1alpha1common "github.com/statnett/provider-cloudian/apis/common/providerconfig/v1alpha1"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian/cloudiantest"
+	pkgclient "github.com/statnett/provider-cloudian/pkg/client"
+)
+
+// run runs cloudianctl against the fake server, and returns its exit code,
+// stdout and stderr.
+func run(t *testing.T, srv *cloudiantest.FakeServer, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	args = append([]string{"--endpoint", srv.URL, "--auth-header", "Basic Zm9vOmJhcg=="}, args...)
+	code := Run(context.Background(), args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func newServer(t *testing.T) *cloudiantest.FakeServer {
+	t.Helper()
+	srv := cloudiantest.NewFakeServer(t)
+	srv.AddGroup("QA")
+	srv.AddGroup("Empty")
+	srv.AddUser(cloudiantest.User{GroupID: "QA", UserID: "alice", UserType: "User", Status: "Active", CanonicalID: "c1"})
+	srv.AddUser(cloudiantest.User{GroupID: "QA", UserID: "bob", UserType: "GroupAdmin", Status: "Active", CanonicalID: "c2"})
+	srv.AddCredentials("QA", "alice", "00AABBCC", "alice-secret")
+	return srv
+}
+
+func TestRun(t *testing.T) {
+	cases := map[string]struct {
+		args   []string
+		code   int
+		stdout string
+		stderr string
+		// usage is whether stdout is the usage of cloudianctl, rather
+		// than `stdout`.
+		usage bool
+	}{
+		"UserList": {
+			args: []string{"user", "list", "--group", "QA"},
+			stdout: `GROUP   USER    TYPE         STATUS   CANONICAL ID
+QA      alice   User         Active   c1
+QA      bob     GroupAdmin   Active   c2
+`,
+		},
+		"UserListPrefixJSON": {
+			args: []string{"user", "list", "--group", "QA", "--prefix", "al", "-o", "json"},
+			stdout: `[
+  {
+    "groupId": "QA",
+    "userId": "alice",
+    "userType": "User",
+    "canonicalUserId": "c1",
+    "userStatus": "Active"
+  }
+]
+`,
+		},
+		"UserListEmptyGroup": {
+			args:   []string{"user", "list", "--group", "Empty", "-o", "json"},
+			stdout: "[]\n",
+		},
+		"UserListUnknownGroup": {
+			args:   []string{"user", "list", "--group", "Typo"},
+			code:   ExitNotFound,
+			stderr: "cloudianctl: error: cannot get group Typo: not found\n",
+		},
+		"GroupGet": {
+			args: []string{"group", "get", "QA"},
+			stdout: `GROUP   NAME   ACTIVE
+QA             true
+`,
+		},
+		"GroupGetNotFound": {
+			args:   []string{"group", "get", "Typo"},
+			code:   ExitNotFound,
+			stderr: "cloudianctl: error: cannot get group Typo: not found\n",
+		},
+		"GroupDeleteNotEmpty": {
+			args:   []string{"group", "delete", "QA"},
+			code:   ExitFailure,
+			stderr: "cloudianctl: error: cannot delete group QA: group still has users: DELETE /group unexpected status: 409: Group is not empty, delete its users first\n",
+		},
+		"GroupDelete": {
+			args:   []string{"group", "delete", "Empty"},
+			stderr: "Deleted group Empty\n",
+		},
+		"UserDeleteNotFound": {
+			args:   []string{"user", "--group", "QA", "delete", "carol"},
+			code:   ExitNotFound,
+			stderr: "cloudianctl: error: cannot delete user carol: not found\n",
+		},
+		"CredentialsListRedacted": {
+			args: []string{"credentials", "list", "--group", "QA", "alice"},
+			stdout: `GROUP   USER    ACCESS KEY   SECRET KEY
+QA      alice   00AABBCC     REDACTED
+`,
+		},
+		"CredentialsListRedactedJSON": {
+			args: []string{"credentials", "list", "--group", "QA", "alice", "-o", "json"},
+			stdout: `[
+  {
+    "groupId": "QA",
+    "userId": "alice",
+    "accessKey": "00AABBCC",
+    "secretKey": "REDACTED"
+  }
+]
+`,
+		},
+		"CredentialsListShowSecrets": {
+			args: []string{"credentials", "list", "--group", "QA", "alice", "--show-secrets"},
+			stdout: `GROUP   USER    ACCESS KEY   SECRET KEY
+QA      alice   00AABBCC     alice-secret
+`,
+		},
+		"CredentialsDeleteNotFound": {
+			args:   []string{"credentials", "delete", "FFFFFFFF"},
+			code:   ExitNotFound,
+			stderr: "cloudianctl: error: cannot delete access key FFFFFFFF: not found\n",
+		},
+		"CredentialsRotateOtherUser": {
+			args:   []string{"credentials", "rotate", "--group", "QA", "bob", "00AABBCC"},
+			code:   ExitNotFound,
+			stderr: "cloudianctl: error: access key 00AABBCC of user bob: not found\n",
+		},
+		"MissingGroup": {
+			args:   []string{"user", "list"},
+			code:   ExitUsage,
+			stderr: "cloudianctl: error: required flag(s) '--group' not provided\n",
+		},
+		"Help": {
+			args:  []string{"--help"},
+			usage: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			code, stdout, stderr := run(t, newServer(t), tc.args...)
+			if code != tc.code {
+				t.Errorf("Run(...): want exit code %d, got %d (stderr %q)", tc.code, code, stderr)
+			}
+			switch {
+			case tc.usage:
+				if !strings.Contains(stdout, "credentials rotate") {
+					t.Errorf("Run(...): want usage on stdout, got %q", stdout)
+				}
+			default:
+				if diff := cmp.Diff(tc.stdout, stdout); diff != "" {
+					t.Errorf("Run(...): -want stdout, +got stdout:\n%s", diff)
+				}
+			}
+			if diff := cmp.Diff(tc.stderr, stderr); diff != "" {
+				t.Errorf("Run(...): -want stderr, +got stderr:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestRunWithoutEndpoint(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if code := Run(context.Background(), []string{"group", "get", "QA"}, &stdout, &stderr); code != ExitUsage {
+		t.Errorf("Run(...): want exit code %d, got %d", ExitUsage, code)
+	}
+	if want := "cloudianctl: error: " + errSource + "\n"; stderr.String() != want {
+		t.Errorf("Run(...): want stderr %q, got %q", want, stderr.String())
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	srv := newServer(t)
+
+	if code, _, stderr := run(t, srv, "group", "create", "Ops", "--name", "Operations"); code != ExitOK {
+		t.Fatalf("group create: exit code %d: %s", code, stderr)
+	}
+	if code, _, stderr := run(t, srv, "user", "--group", "Ops", "create", "carol"); code != ExitOK {
+		t.Fatalf("user create: exit code %d: %s", code, stderr)
+	}
+	initial := srv.AccessKeys("Ops", "carol")
+	if len(initial) != 1 {
+		t.Fatalf("user create: want the access key Cloudian creates with the user, got %v", initial)
+	}
+
+	code, stdout, stderr := run(t, srv, "credentials", "rotate", "--group", "Ops", "carol", initial[0], "-o", "json")
+	if code != ExitOK {
+		t.Fatalf("credentials rotate: exit code %d: %s", code, stderr)
+	}
+	var rotated credentials
+	if err := json.Unmarshal([]byte(stdout), &rotated); err != nil {
+		t.Fatalf("credentials rotate: %v", err)
+	}
+	if rotated.SecretKey == "" || rotated.SecretKey == "REDACTED" {
+		t.Errorf("credentials rotate: want the secret key of the new access key, got %q", rotated.SecretKey)
+	}
+	if keys := srv.AccessKeys("Ops", "carol"); !slices.Equal(keys, []string{rotated.AccessKey}) {
+		t.Errorf("credentials rotate: want access keys [%s], got %v", rotated.AccessKey, keys)
+	}
+
+	if code, _, stderr := run(t, srv, "user", "--group", "Ops", "delete", "carol"); code != ExitOK {
+		t.Fatalf("user delete: exit code %d: %s", code, stderr)
+	}
+	if code, _, stderr := run(t, srv, "group", "delete", "Ops"); code != ExitOK {
+		t.Fatalf("group delete: exit code %d: %s", code, stderr)
+	}
+	if srv.HasGroup("Ops") {
+		t.Error("group delete: want group Ops deleted")
+	}
+}
+
+func TestServiceFromProviderConfig(t *testing.T) {
+	srv := newServer(t)
+	s, err := pkgclient.NewScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := corev1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	pc := &apisv1alpha1cluster.ProviderConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Spec: pcv1alpha1common.ProviderConfigSpec{
+			Endpoint: srv.URL,
+			AuthHeader: pcv1alpha1common.ProviderCredentials{
+				Source: xpv2.CredentialsSourceSecret,
+				CommonCredentialSelectors: xpv2.CommonCredentialSelectors{
+					SecretRef: &xpv2.SecretKeySelector{
+						SecretReference: xpv2.SecretReference{Namespace: "crossplane-system", Name: "cloudian"},
+						Key:             "authHeader",
+					},
+				},
+			},
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "crossplane-system", Name: "cloudian"},
+		Data:       map[string][]byte{"authHeader": []byte("Basic Zm9vOmJhcg==\n")},
+	}
+	kube := fake.NewClientBuilder().WithScheme(s).WithObjects(pc, secret).Build()
+
+	svc, err := serviceFromProviderConfig(context.Background(), kube, "default", "")
+	if err != nil {
+		t.Fatalf("serviceFromProviderConfig(...): %v", err)
+	}
+	users, err := svc.SearchUsers(context.Background(), "QA", "")
+	if err != nil {
+		t.Fatalf("svc.SearchUsers(...): %v", err)
+	}
+	if len(users) != 2 {
+		t.Errorf("svc.SearchUsers(...): want 2 users, got %+v", users)
+	}
+
+	if _, err := serviceFromProviderConfig(context.Background(), kube, "default", "team-a"); err == nil {
+		t.Error("serviceFromProviderConfig(...): want an error getting a namespaced ProviderConfig that does not exist")
+	}
+}
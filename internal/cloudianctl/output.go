@@ -0,0 +1,92 @@
+package cloudianctl
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
+)
+
+// Output formats of the --output flag.
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// A table is the output of a command in the table format: a header and the
+// rows below it.
+type table struct {
+	header []string
+	rows   [][]string
+}
+
+// credentials are a set of credentials of a user as printed, with the secret
+// key redacted like cloudian.Secret is when formatted, unless it is shown.
+type credentials struct {
+	cloudian.GroupUserID `json:",inline"`
+
+	AccessKey string `json:"accessKey"`
+	SecretKey string `json:"secretKey"`
+}
+
+func newCredentials(guid cloudian.GroupUserID, info cloudian.SecurityInfo, showSecret bool) credentials {
+	secret := info.SecretKey.String()
+	if showSecret {
+		secret = info.SecretKey.Value()
+	}
+	return credentials{GroupUserID: guid, AccessKey: info.AccessKey, SecretKey: secret}
+}
+
+// write prints `v` as JSON, or `t` as an aligned table.
+func (c *cli) write(v any, t table) error {
+	if c.output == outputJSON {
+		enc := json.NewEncoder(c.stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	w := tabwriter.NewWriter(c.stdout, 0, 0, 3, ' ', 0)
+	for _, row := range append([][]string{t.header}, t.rows...) {
+		_, _ = fmt.Fprintln(w, strings.Join(row, "\t"))
+	}
+	return w.Flush()
+}
+
+// deleted tells what was deleted on stderr, leaving stdout empty.
+func (c *cli) deleted(kind, id string) {
+	_, _ = fmt.Fprintf(c.stderr, "Deleted %s %s\n", kind, id)
+}
+
+func groupTable(g cloudian.Group) table {
+	return table{
+		header: []string{"GROUP", "NAME", "ACTIVE"},
+		rows:   [][]string{{g.GroupID, g.GroupName, strconv.FormatBool(g.Active)}},
+	}
+}
+
+func userTable(users ...cloudian.User) table {
+	t := table{header: []string{"GROUP", "USER", "TYPE", "STATUS", "CANONICAL ID"}}
+	for _, u := range users {
+		t.rows = append(t.rows, []string{u.GroupID, u.UserID, string(u.UserType), string(u.Status), u.CanonicalID})
+	}
+	return t
+}
+
+func credentialsTable(creds ...credentials) table {
+	t := table{header: []string{"GROUP", "USER", "ACCESS KEY", "SECRET KEY"}}
+	for _, c := range creds {
+		t.rows = append(t.rows, []string{c.GroupID, c.UserID, c.AccessKey, c.SecretKey})
+	}
+	return t
+}
+
+// nonNil returns `s`, or an empty slice if it is nil, so that an empty list is
+// printed as [] rather than null in JSON.
+func nonNil[S ~[]E, E any](s S) S {
+	if s == nil {
+		return S{}
+	}
+	return s
+}
@@ -18,24 +18,34 @@ package accesskey
 
 import (
 	"context"
+	"maps"
 
 	"github.com/pkg/errors"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/crossplane/crossplane-runtime/v2/pkg/controller"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/event"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
-	"github.com/crossplane/crossplane-runtime/v2/pkg/ratelimiter"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/managed"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
 	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
 
 	userv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/user/v1alpha1"
 	apisv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/v1alpha1"
+	pcv1alpha1common "github.com/statnett/provider-cloudian/apis/common/providerconfig/v1alpha1"
+	"github.com/statnett/provider-cloudian/internal/clients"
 	controllercommon "github.com/statnett/provider-cloudian/internal/controller/common"
 	accesskeycontrollercommon "github.com/statnett/provider-cloudian/internal/controller/common/accesskey"
+	"github.com/statnett/provider-cloudian/internal/controller/common/degraded"
+	"github.com/statnett/provider-cloudian/internal/controller/common/identity"
+	"github.com/statnett/provider-cloudian/internal/controller/common/referrers"
+	"github.com/statnett/provider-cloudian/internal/controller/common/statusmirror"
 	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
 )
 
@@ -49,6 +59,8 @@ const (
 	errCreateAccessKey = "cannot create AccessKey"
 	errDeleteAccessKey = "cannot delete AccessKey"
 	errGetAccessKey    = "cannot get AccessKey"
+
+	errIndexAccessKeys = "cannot index AccessKeys by access key ID"
 )
 
 // SetupGated registers controller setup with the gate, waiting for the
@@ -64,33 +76,67 @@ func SetupGated(mgr ctrl.Manager, o controller.Options) error {
 
 // Setup adds a controller that reconciles AccessKey managed resources.
 func Setup(mgr ctrl.Manager, o controller.Options) error {
-	name := managed.ControllerName(userv1alpha1cluster.AccessKeyGroupKind)
-
-	r := managed.NewReconciler(mgr,
-		resource.ManagedKind(userv1alpha1cluster.AccessKeyGroupVersionKind),
-		managed.WithExternalConnector(&connector{
-			kube:         mgr.GetClient(),
-			usage:        resource.NewLegacyProviderConfigUsageTracker(mgr.GetClient(), &apisv1alpha1cluster.ProviderConfigUsage{}),
-			newServiceFn: controllercommon.NewCloudianService}),
-		managed.WithLogger(o.Logger.WithValues("controller", name)),
-		managed.WithPollInterval(o.PollInterval),
-		//nolint:staticcheck // SA1004 crossplane-runtime still depends on deprecated API
-		managed.WithRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor(name))))
-
-	return ctrl.NewControllerManagedBy(mgr).
-		Named(name).
-		WithOptions(o.ForControllerRuntime()).
-		WithEventFilter(resource.DesiredStateChanged()).
-		For(&userv1alpha1cluster.AccessKey{}).
-		Complete(ratelimiter.NewReconciler(name, r, o.GlobalRateLimiter))
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &userv1alpha1cluster.AccessKey{}, accesskeycontrollercommon.IndexField, accesskeycontrollercommon.IndexKeyID); err != nil {
+		return errors.Wrap(err, errIndexAccessKeys)
+	}
+
+	if err := controllercommon.SetupManaged[userv1alpha1cluster.AccessKey](mgr, o, controllercommon.ManagedKind{
+		GroupVersionKind: userv1alpha1cluster.AccessKeyGroupVersionKind,
+		Timeout:          controllercommon.ReconcileTimeouts.AccessKey,
+		NewConnector: func(recorder event.Recorder) managed.ExternalConnector {
+			return &connector{
+				kube:         mgr.GetClient(),
+				usage:        resource.NewLegacyProviderConfigUsageTracker(mgr.GetClient(), &apisv1alpha1cluster.ProviderConfigUsage{}),
+				newServiceFn: clients.Shared.Get,
+				recorder:     recorder,
+			}
+		},
+		Options: []managed.ReconcilerOption{
+			managed.WithInitializers(
+				accesskeycontrollercommon.NewKeyInitializer(mgr.GetClient()),
+				accesskeycontrollercommon.NewLabelInitializer(mgr.GetClient())),
+			managed.WithReferenceResolver(managed.NewAPISimpleReferenceResolver(mgr.GetClient())),
+		},
+		Watches: []controllercommon.Watch{{
+			Object:     &userv1alpha1cluster.User{},
+			Map:        accessKeysOfUser(mgr.GetClient()),
+			Predicates: []predicate.Predicate{referrers.Resolvable()},
+		}},
+	}); err != nil {
+		return err
+	}
+
+	return statusmirror.Setup(mgr, o, userv1alpha1cluster.AccessKeyGroupVersionKind, func() statusmirror.Mirrored { return &userv1alpha1cluster.AccessKey{} })
+}
+
+// accessKeysOfUser maps a User to the AccessKeys of its Cloudian user or
+// referring to it, so that they are created as soon as it is ready rather than
+// at their next poll. AccessKeys are left to their poll when they cannot be
+// listed.
+func accessKeysOfUser(kube client.Reader) handler.MapFunc {
+	return func(ctx context.Context, o client.Object) []reconcile.Request {
+		u, ok := o.(*userv1alpha1cluster.User)
+		if !ok {
+			return nil
+		}
+		guid := cloudian.GroupUserID{GroupID: u.Spec.ForProvider.GroupID, UserID: meta.GetExternalName(u)}
+		aks := &userv1alpha1cluster.AccessKeyList{}
+		if err := kube.List(ctx, aks); err != nil {
+			return nil
+		}
+		return referrers.Requests(aks.Items, func(ak *userv1alpha1cluster.AccessKey) bool {
+			return accesskeycontrollercommon.RefersToUser(ak.Spec.ForProvider, u, guid)
+		})
+	}
 }
 
 // A connector is expected to produce an ExternalClient when its Connect method
 // is called.
 type connector struct {
 	kube         client.Client
-	usage        *resource.LegacyProviderConfigUsageTracker
-	newServiceFn func(providerConfigEndpoint string, authHeader string) (*cloudian.Client, error)
+	usage        resource.LegacyTracker
+	newServiceFn func(uid types.UID, spec pcv1alpha1common.ProviderConfigSpec, creds controllercommon.Credentials) (*cloudian.Client, error)
+	recorder     event.Recorder
 }
 
 // Connect typically produces an ExternalClient by:
@@ -113,18 +159,22 @@ func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.E
 		return nil, errors.Wrap(err, errGetPC)
 	}
 
-	cd := pc.Spec.AuthHeader
-	authHeader, err := resource.CommonCredentialExtractor(ctx, cd.Source, c.kube, cd.CommonCredentialSelectors)
+	creds, err := controllercommon.ExtractCredentials(ctx, c.kube, pc.Spec)
 	if err != nil {
 		return nil, errors.Wrap(err, errGetCreds)
 	}
 
-	svc, err := c.newServiceFn(pc.Spec.Endpoint, string(authHeader))
+	svc, err := c.newServiceFn(pc.GetUID(), pc.Spec, creds)
 	if err != nil {
 		return nil, errors.Wrap(err, errNewClient)
 	}
 
-	return &external{cloudianService: svc}, nil
+	return &external{
+		cloudianService: svc,
+		kube:            c.kube,
+		recorder:        c.recorder,
+		maxKeysPerUser:  int(ptr.Deref(pc.Spec.MaxKeysPerUser, 0)),
+	}, nil
 }
 
 // An ExternalClient observes, then either creates, updates, or deletes an
@@ -133,6 +183,11 @@ type external struct {
 	// A 'client' used to connect to the external resource API. In practice this
 	// would be something like an AWS SDK client.
 	cloudianService *cloudian.Client
+	kube            client.Client
+	recorder        event.Recorder
+	// maxKeysPerUser is the most access keys a user may have, or zero for
+	// the limit of Cloudian only.
+	maxKeysPerUser int
 }
 
 func (c *external) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
@@ -150,12 +205,27 @@ func (c *external) Observe(ctx context.Context, mg resource.Managed) (managed.Ex
 		return managed.ExternalObservation{ResourceExists: false}, nil
 	}
 	if err != nil {
-		return managed.ExternalObservation{}, errors.Wrap(err, errGetAccessKey)
+		// A failed read is no evidence that the access key is gone.
+		guid := cloudian.GroupUserID{GroupID: cr.Spec.ForProvider.GroupID, UserID: cr.Spec.ForProvider.UserID}
+		o, err := accesskeycontrollercommon.ObserveWithoutCredentials(ctx, c.cloudianService, cr, guid, err)
+		return o, errors.Wrap(err, errGetAccessKey)
+	}
+	// A complete observation clears a degraded one.
+	var observation degraded.Observation
+	observation.SetCondition(cr)
+	if ref := cr.GetWriteConnectionSecretToReference(); ref != nil {
+		if err := accesskeycontrollercommon.CheckPublished(ctx, c.kube, c.recorder, cr, types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, creds); err != nil {
+			return managed.ExternalObservation{}, err
+		}
 	}
 
-	cr.Status.AtProvider.ID = meta.GetExternalName(cr)
+	cr.Status.AtProvider.SetAccessKeyID(meta.GetExternalName(cr))
+	cr.Status.AtProvider.Labels = maps.Clone(cr.Spec.ForProvider.Labels)
 	cr.SetConditions(xpv2.Available())
 
+	// The label is persisted along with the late initialized fields.
+	labeled := accesskeycontrollercommon.SetKeyIDLabel(cr, meta.GetExternalName(cr))
+
 	return managed.ExternalObservation{
 		// Return false when the external resource does not exist. This lets
 		// the managed resource reconciler know that it needs to call Create to
@@ -168,6 +238,8 @@ func (c *external) Observe(ctx context.Context, mg resource.Managed) (managed.Ex
 		// TODO: Check Inactivate / Activate
 		ResourceUpToDate: true,
 
+		ResourceLateInitialized: labeled,
+
 		// Return any details that may be required to connect to the external
 		// resource. These will be stored as the connection secret.
 		ConnectionDetails: accesskeycontrollercommon.ConnectionDetails(creds),
@@ -180,17 +252,43 @@ func (c *external) Create(ctx context.Context, mg resource.Managed) (managed.Ext
 		return managed.ExternalCreation{}, errors.New(errNotAccessKey)
 	}
 
+	defer identity.Mutations.Lock(cr.Spec.ForProvider.GroupID, cr.Spec.ForProvider.UserID)()
+
 	cr.SetConditions(xpv2.Creating())
 
-	creds, err := c.cloudianService.CreateUserCredentials(ctx, cloudian.GroupUserID{
+	guid := cloudian.GroupUserID{
 		GroupID: cr.Spec.ForProvider.GroupID,
 		UserID:  cr.Spec.ForProvider.UserID,
-	})
-	if err != nil {
-		return managed.ExternalCreation{}, errors.Wrap(err, errCreateAccessKey)
 	}
 
-	meta.SetExternalName(cr, creds.AccessKey)
+	// Refuse before Cloudian does, explaining the quota of the ProviderConfig.
+	if err := accesskeycontrollercommon.CheckKeyQuota(ctx, c.cloudianService, guid, meta.GetExternalName(cr), c.maxKeysPerUser); err != nil {
+		return managed.ExternalCreation{}, errors.Wrap(accesskeycontrollercommon.SetKeyQuotaCondition(cr, err), errCreateAccessKey)
+	}
+
+	if !accesskeycontrollercommon.IsGenerated(cr) {
+		// Initialized before the provider generated access keys, so let
+		// Cloudian generate them.
+		creds, err := c.cloudianService.CreateUserCredentials(ctx, guid)
+		if err = accesskeycontrollercommon.SetKeyQuotaCondition(cr, err); err != nil {
+			return managed.ExternalCreation{}, errors.Wrap(err, errCreateAccessKey)
+		}
+
+		meta.SetExternalName(cr, creds.AccessKey)
+		accesskeycontrollercommon.SetKeyIDLabel(cr, creds.AccessKey)
+
+		return managed.ExternalCreation{ConnectionDetails: accesskeycontrollercommon.ConnectionDetails(creds)}, nil
+	}
+
+	creds := &cloudian.SecurityInfo{
+		AccessKey: meta.GetExternalName(cr),
+		SecretKey: cloudian.NewSecret(accesskeycontrollercommon.NewSecretKey()),
+	}
+	err := c.cloudianService.CreateUserCredentialsWithKey(ctx, guid, creds.AccessKey, creds.SecretKey)
+	if err = accesskeycontrollercommon.SetKeyQuotaCondition(cr, err); err != nil {
+		return managed.ExternalCreation{}, errors.Wrap(err, errCreateAccessKey)
+	}
+	accesskeycontrollercommon.SetKeyIDLabel(cr, creds.AccessKey)
 
 	return managed.ExternalCreation{
 		// Optionally return any details that may be required to connect to the
@@ -220,6 +318,8 @@ func (c *external) Delete(ctx context.Context, mg resource.Managed) (managed.Ext
 		return managed.ExternalDelete{}, errors.New(errNotAccessKey)
 	}
 
+	defer identity.Mutations.Lock(cr.Spec.ForProvider.GroupID, cr.Spec.ForProvider.UserID)()
+
 	cr.SetConditions(xpv2.Deleting())
 
 	err := c.cloudianService.DeleteUserCredentials(ctx, meta.GetExternalName(cr))
@@ -18,13 +18,42 @@ package accesskey
 
 import (
 	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
 	"testing"
 
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
 	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	"github.com/crossplane/crossplane-runtime/v2/pkg/event"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/managed"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/test"
+
+	apiscluster "github.com/statnett/provider-cloudian/apis/cluster"
+	userv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/user/v1alpha1"
+	apisv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/v1alpha1"
+	pcv1alpha1common "github.com/statnett/provider-cloudian/apis/common/providerconfig/v1alpha1"
+	userv1alpha1common "github.com/statnett/provider-cloudian/apis/common/user/v1alpha1"
+	"github.com/statnett/provider-cloudian/internal/clients"
+	controllercommon "github.com/statnett/provider-cloudian/internal/controller/common"
+	accesskeycontrollercommon "github.com/statnett/provider-cloudian/internal/controller/common/accesskey"
+	"github.com/statnett/provider-cloudian/internal/controller/common/apierror"
+	"github.com/statnett/provider-cloudian/internal/controller/common/connecttest"
+	"github.com/statnett/provider-cloudian/internal/controller/common/degraded"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian/cloudiantest"
 )
 
 // Unlike many Kubernetes projects Crossplane does not use third party testing
@@ -71,3 +100,487 @@ func TestObserve(t *testing.T) {
 		})
 	}
 }
+
+func TestConnect(t *testing.T) {
+	mg := &userv1alpha1cluster.AccessKey{}
+	mg.SetProviderConfigReference(&xpv2.Reference{Name: connecttest.ProviderConfigName})
+	pc := func(spec pcv1alpha1common.ProviderConfigSpec) client.Object {
+		return &apisv1alpha1cluster.ProviderConfig{ObjectMeta: metav1.ObjectMeta{Name: connecttest.ProviderConfigName}, Spec: spec}
+	}
+
+	connecttest.TestConnect(t, connecttest.Connector{
+		New: func(kube client.Client, newService connecttest.NewServiceFn) managed.ExternalConnecter {
+			return &connector{kube: kube, usage: resource.LegacyTrackerFn(func(context.Context, resource.LegacyManaged) error { return nil }), newServiceFn: newService}
+		},
+		Managed:        mg,
+		Other:          &userv1alpha1cluster.User{},
+		ProviderConfig: pc,
+		Connected: func(ext managed.ExternalClient) bool {
+			e, ok := ext.(*external)
+			return ok && e.cloudianService != nil
+		},
+		ErrNotManaged: errNotAccessKey,
+		ErrGetPC:      errGetPC,
+		ErrGetCreds:   errGetCreds,
+	})
+}
+
+func TestCreate(t *testing.T) {
+	srv := cloudiantest.NewFakeServer(t)
+	srv.AddUser(cloudiantest.User{GroupID: "QA", UserID: "alice"})
+	e := external{cloudianService: cloudian.NewClient(srv.URL, "")}
+
+	newAccessKey := func(externalName string) *userv1alpha1cluster.AccessKey {
+		mg := &userv1alpha1cluster.AccessKey{ObjectMeta: metav1.ObjectMeta{Name: "alice"}}
+		mg.Spec.ForProvider.GroupID = "QA"
+		mg.Spec.ForProvider.UserID = "alice"
+		meta.SetExternalName(mg, externalName)
+		return mg
+	}
+
+	// A create whose outcome is lost is retried with the same access key.
+	for range 2 {
+		mg := newAccessKey("00a1b2c3d4e5f6a7b8c9")
+		if _, err := e.Create(context.Background(), mg); err != nil {
+			t.Fatalf("e.Create(...): %v", err)
+		}
+		if got := meta.GetExternalName(mg); got != "00a1b2c3d4e5f6a7b8c9" {
+			t.Errorf("e.Create(...): want external-name to be kept, got %q", got)
+		}
+	}
+	if diff := cmp.Diff([]string{"00a1b2c3d4e5f6a7b8c9"}, srv.AccessKeys("QA", "alice")); diff != "" {
+		t.Errorf("e.Create(...): -want access keys, +got:\n%s", diff)
+	}
+
+	// AccessKeys initialized before access keys were generated have their
+	// name as external-name, and have Cloudian generate the access key.
+	mg := newAccessKey("alice")
+	if _, err := e.Create(context.Background(), mg); err != nil {
+		t.Fatalf("e.Create(...): %v", err)
+	}
+	if got := meta.GetExternalName(mg); got == "alice" || !slices.Contains(srv.AccessKeys("QA", "alice"), got) {
+		t.Errorf("e.Create(...): want external-name to be the access key generated by Cloudian, got %q", got)
+	}
+}
+
+func TestCreateKeyQuota(t *testing.T) {
+	cases := map[string]struct {
+		reason         string
+		maxKeysPerUser int
+		maxCredentials int
+	}{
+		"ProviderConfig": {
+			reason:         "An access key beyond the quota of the ProviderConfig should not be created.",
+			maxKeysPerUser: 1,
+		},
+		"Cloudian": {
+			reason:         "An access key refused by Cloudian for exceeding its quota should get the same condition.",
+			maxCredentials: 1,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			srv := cloudiantest.NewFakeServer(t)
+			srv.AddUser(cloudiantest.User{GroupID: "QA", UserID: "alice"})
+			srv.AddCredentials("QA", "alice", "00112233445566778899", "secret")
+			srv.SetMaxCredentials(tc.maxCredentials)
+			e := external{cloudianService: cloudian.NewClient(srv.URL, ""), maxKeysPerUser: tc.maxKeysPerUser}
+
+			mg := &userv1alpha1cluster.AccessKey{ObjectMeta: metav1.ObjectMeta{Name: "alice"}}
+			mg.Spec.ForProvider.GroupID = "QA"
+			mg.Spec.ForProvider.UserID = "alice"
+			meta.SetExternalName(mg, "99887766554433221100")
+
+			_, err := e.Create(context.Background(), mg)
+			if !errors.Is(err, cloudian.ErrKeyQuotaExceeded) || !strings.HasPrefix(err.Error(), errCreateAccessKey) {
+				t.Errorf("\n%s\ne.Create(...): want %q error, got %v", tc.reason, cloudian.ErrKeyQuotaExceeded, err)
+			}
+			if got := mg.GetCondition(accesskeycontrollercommon.TypeKeyQuota); got.Reason != accesskeycontrollercommon.ReasonKeyQuotaExceeded {
+				t.Errorf("\n%s\ne.Create(...): want condition %s, got %+v", tc.reason, accesskeycontrollercommon.ReasonKeyQuotaExceeded, got)
+			}
+			if got := srv.AccessKeys("QA", "alice"); len(got) != 1 {
+				t.Errorf("\n%s\ne.Create(...): want no access key created, got %v", tc.reason, got)
+			}
+
+			// Deleting the access key of the user makes room for another.
+			if err := e.cloudianService.DeleteUserCredentials(context.Background(), "00112233445566778899"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := e.Create(context.Background(), mg); err != nil {
+				t.Fatalf("\n%s\ne.Create(...): %v", tc.reason, err)
+			}
+			if got := mg.GetCondition(accesskeycontrollercommon.TypeKeyQuota); got.Reason != accesskeycontrollercommon.ReasonWithinKeyQuota {
+				t.Errorf("\n%s\ne.Create(...): want condition %s, got %+v", tc.reason, accesskeycontrollercommon.ReasonWithinKeyQuota, got)
+			}
+		})
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	srv := cloudiantest.NewFakeServer(t)
+	srv.AddUser(cloudiantest.User{GroupID: "QA", UserID: "alice"})
+	e := external{cloudianService: cloudian.NewClient(srv.URL, "")}
+	ctx := context.Background()
+
+	mg := &userv1alpha1cluster.AccessKey{ObjectMeta: metav1.ObjectMeta{Name: "alice"}}
+	mg.Spec.ForProvider.GroupID = "QA"
+	mg.Spec.ForProvider.UserID = "alice"
+	meta.SetExternalName(mg, "00112233445566778899")
+
+	if o, err := e.Observe(ctx, mg); err != nil || o.ResourceExists {
+		t.Fatalf("e.Observe(...): want non-existing access key, got %+v, %v", o, err)
+	}
+
+	c, err := e.Create(ctx, mg)
+	if err != nil {
+		t.Fatalf("e.Create(...): %v", err)
+	}
+	o, err := e.Observe(ctx, mg)
+	if err != nil || !o.ResourceExists {
+		t.Fatalf("e.Observe(...): want existing access key, got %+v, %v", o, err)
+	}
+	if diff := cmp.Diff(c.ConnectionDetails, o.ConnectionDetails); diff != "" {
+		t.Errorf("e.Observe(...): want the connection details of the created access key, -want, +got:\n%s", diff)
+	}
+
+	// Both the credentials and the user they belong to fail to be read.
+	srv.FailNext(2)
+	if _, err := e.Observe(ctx, mg); err == nil || !strings.Contains(err.Error(), errGetAccessKey) {
+		t.Errorf("e.Observe(...): want error explaining %q when the admin API fails, got %v", errGetAccessKey, err)
+	}
+
+	if _, err := e.Delete(ctx, mg); err != nil {
+		t.Fatalf("e.Delete(...): %v", err)
+	}
+	if got := srv.AccessKeys("QA", "alice"); len(got) != 0 {
+		t.Errorf("e.Delete(...): want the access key deleted, got %v", got)
+	}
+	if o, err := e.Observe(ctx, mg); err != nil || o.ResourceExists {
+		t.Errorf("e.Observe(...): want deleted access key, got %+v, %v", o, err)
+	}
+	// Deleting an access key that is already gone succeeds.
+	if _, err := e.Delete(ctx, mg); err != nil {
+		t.Errorf("e.Delete(...) of a deleted access key: %v", err)
+	}
+}
+
+func TestKeyIDLabel(t *testing.T) {
+	srv := cloudiantest.NewFakeServer(t)
+	srv.AddUser(cloudiantest.User{GroupID: "QA", UserID: "alice"})
+	e := external{cloudianService: cloudian.NewClient(srv.URL, "")}
+	ctx := context.Background()
+
+	// Initialized before access keys were generated, so Cloudian generates
+	// the access key.
+	mg := &userv1alpha1cluster.AccessKey{ObjectMeta: metav1.ObjectMeta{Name: "alice"}}
+	mg.Spec.ForProvider.GroupID = "QA"
+	mg.Spec.ForProvider.UserID = "alice"
+	meta.SetExternalName(mg, "alice")
+
+	if _, err := e.Create(ctx, mg); err != nil {
+		t.Fatalf("e.Create(...): %v", err)
+	}
+	created := meta.GetExternalName(mg)
+	if got := mg.GetLabels()[accesskeycontrollercommon.KeyIDLabel]; got != created {
+		t.Errorf("e.Create(...): want label %s=%s, got %q", accesskeycontrollercommon.KeyIDLabel, created, got)
+	}
+
+	o, err := e.Observe(ctx, mg)
+	if err != nil || !o.ResourceExists {
+		t.Fatalf("e.Observe(...): want existing access key, got %+v, %v", o, err)
+	}
+	if o.ResourceLateInitialized {
+		t.Errorf("e.Observe(...): want no update of an AccessKey already labeled")
+	}
+
+	// The access key is replaced, and the label still names the previous one.
+	if _, err := e.Delete(ctx, mg); err != nil {
+		t.Fatalf("e.Delete(...): %v", err)
+	}
+	meta.SetExternalName(mg, "00112233445566778899")
+	if _, err := e.Create(ctx, mg); err != nil {
+		t.Fatalf("e.Create(...): %v", err)
+	}
+	mg.Labels[accesskeycontrollercommon.KeyIDLabel] = created
+
+	o, err = e.Observe(ctx, mg)
+	if err != nil || !o.ResourceExists {
+		t.Fatalf("e.Observe(...): want existing access key, got %+v, %v", o, err)
+	}
+	if !o.ResourceLateInitialized {
+		t.Errorf("e.Observe(...): want the AccessKey updated with the rotated access key ID")
+	}
+	if got := mg.GetLabels()[accesskeycontrollercommon.KeyIDLabel]; got != "00112233445566778899" {
+		t.Errorf("e.Observe(...): want label %s=00112233445566778899, got %q", accesskeycontrollercommon.KeyIDLabel, got)
+	}
+}
+
+type recorder []event.Event
+
+func (r *recorder) Event(_ runtime.Object, e event.Event) { *r = append(*r, e) }
+
+func (r *recorder) WithAnnotations(...string) event.Recorder { return r }
+
+func TestObservePublishedSecretKey(t *testing.T) {
+	const accessKey = "00112233445566778899"
+	published := func(secretKey string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "alice-key", Namespace: "crossplane-system"},
+			Data:       map[string][]byte{"secretKey": []byte(secretKey)},
+		}
+	}
+
+	cases := map[string]struct {
+		reason     string
+		secretKey  string
+		objs       []client.Object
+		wantExists bool
+		wantEvents int
+	}{
+		"Match": {
+			reason:     "An access key whose secret key is published should not be warned about.",
+			secretKey:  "secret",
+			objs:       []client.Object{published("secret")},
+			wantExists: true,
+		},
+		"Mismatch": {
+			reason:     "A secret key regenerated outside the provider should be warned about and published.",
+			secretKey:  "regenerated",
+			objs:       []client.Object{published("secret")},
+			wantExists: true,
+			wantEvents: 1,
+		},
+		"NotPublished": {
+			reason:     "An access key whose connection secret is not yet published should not be warned about.",
+			secretKey:  "secret",
+			wantExists: true,
+		},
+		"KeyMissing": {
+			reason: "An access key missing in Cloudian should not exist, whatever secret key is published.",
+			objs:   []client.Object{published("secret")},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			srv := cloudiantest.NewFakeServer(t)
+			if tc.secretKey != "" {
+				srv.AddCredentials("QA", "alice", accessKey, tc.secretKey)
+			}
+			var events recorder
+			e := external{
+				cloudianService: cloudian.NewClient(srv.URL, ""),
+				kube:            &test.MockClient{MockGet: connecttest.MockGet(tc.objs...)},
+				recorder:        &events,
+			}
+
+			mg := &userv1alpha1cluster.AccessKey{ObjectMeta: metav1.ObjectMeta{Name: "alice"}}
+			mg.Spec.ForProvider.GroupID = "QA"
+			mg.Spec.ForProvider.UserID = "alice"
+			mg.SetWriteConnectionSecretToReference(&xpv2.SecretReference{Name: "alice-key", Namespace: "crossplane-system"})
+			meta.SetExternalName(mg, accessKey)
+
+			o, err := e.Observe(context.Background(), mg)
+			if err != nil {
+				t.Fatalf("\n%s\ne.Observe(...): %v", tc.reason, err)
+			}
+			if o.ResourceExists != tc.wantExists {
+				t.Errorf("\n%s\ne.Observe(...): want ResourceExists %t, got %t", tc.reason, tc.wantExists, o.ResourceExists)
+			}
+			if tc.wantExists && string(o.ConnectionDetails["secretKey"]) != tc.secretKey {
+				t.Errorf("\n%s\ne.Observe(...): want the secret key in Cloudian to be published", tc.reason)
+			}
+			if len(events) != tc.wantEvents {
+				t.Fatalf("\n%s\ne.Observe(...): want %d events, got %v", tc.reason, tc.wantEvents, events)
+			}
+			for _, ev := range events {
+				if ev.Reason != accesskeycontrollercommon.ReasonSecretKeyChanged || strings.Contains(ev.Message, tc.secretKey) {
+					t.Errorf("\n%s\ne.Observe(...): want a %s event not leaking the secret key, got %+v", tc.reason, accesskeycontrollercommon.ReasonSecretKeyChanged, ev)
+				}
+			}
+		})
+	}
+}
+
+func TestObserveAPIErrors(t *testing.T) {
+	caBundle := connecttest.CABundle(t)
+	pc := &apisv1alpha1cluster.ProviderConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: connecttest.ProviderConfigName},
+		Spec:       connecttest.ProviderConfigSpec(xpv2.CredentialsSourceSecret),
+	}
+	mg := &userv1alpha1cluster.AccessKey{}
+	mg.SetProviderConfigReference(&xpv2.Reference{Name: connecttest.ProviderConfigName})
+	meta.SetExternalName(mg, "00112233445566778899")
+
+	for reason, status := range connecttest.APIErrors {
+		t.Run(string(reason), func(t *testing.T) {
+			c := apierror.NewHandler(event.NewNopRecorder()).Connector(&connector{
+				kube:  &test.MockClient{MockGet: connecttest.MockGet(pc, connecttest.Secret(caBundle))},
+				usage: resource.LegacyTrackerFn(func(context.Context, resource.LegacyManaged) error { return nil }),
+				newServiceFn: func(types.UID, pcv1alpha1common.ProviderConfigSpec, controllercommon.Credentials) (*cloudian.Client, error) {
+					return connecttest.StatusService(t, status), nil
+				},
+			})
+			ext, err := c.Connect(context.Background(), mg)
+			if err != nil {
+				t.Fatalf("c.Connect(...): %v", err)
+			}
+			_, err = ext.Observe(context.Background(), mg)
+			if want := string(reason) + ": "; err == nil || !strings.HasPrefix(err.Error(), want) || !strings.Contains(err.Error(), errGetAccessKey) {
+				t.Errorf("ext.Observe(...): want error starting with %q explaining %q, got %v", want, errGetAccessKey, err)
+			}
+		})
+	}
+}
+
+func TestObserveDegraded(t *testing.T) {
+	cases := map[string]struct {
+		reason     string
+		user       bool
+		userDown   bool
+		wantExists bool
+		wantErr    bool
+	}{
+		"UserExists": {
+			reason:     "An access key should be assumed to exist while its credentials may not be read, but its user exists.",
+			user:       true,
+			wantExists: true,
+		},
+		"UserGone": {
+			reason: "An access key should not exist once Cloudian confirms its user does not.",
+		},
+		"UserUnknown": {
+			reason:   "An access key should not be observed when neither its credentials nor its user may be read.",
+			user:     true,
+			userDown: true,
+			wantErr:  true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			srv := cloudiantest.NewFakeServer(t)
+			if tc.user {
+				srv.AddUser(cloudiantest.User{GroupID: "QA", UserID: "alice"})
+				srv.AddCredentials("QA", "alice", "00112233445566778899", "secret")
+			}
+			srv.Script("/user/credentials", cloudiantest.Respond(http.StatusServiceUnavailable, ""))
+			if tc.userDown {
+				srv.Script("/user", cloudiantest.Respond(http.StatusServiceUnavailable, ""))
+			}
+			e := external{cloudianService: cloudian.NewClient(srv.URL, "")}
+			cr := &userv1alpha1cluster.AccessKey{}
+			cr.Spec.ForProvider.GroupID = "QA"
+			cr.Spec.ForProvider.UserID = "alice"
+			meta.SetExternalName(cr, "00112233445566778899")
+
+			got, err := e.Observe(context.Background(), cr)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("\n%s\ne.Observe(...): want error %t, got %v", tc.reason, tc.wantErr, err)
+			}
+			if got.ResourceExists != tc.wantExists {
+				t.Errorf("\n%s\ne.Observe(...): want exists %t, got %t", tc.reason, tc.wantExists, got.ResourceExists)
+			}
+			if !tc.wantExists {
+				return
+			}
+			if !got.ResourceUpToDate || len(got.ConnectionDetails) != 0 {
+				t.Errorf("\n%s\ne.Observe(...): want up to date without connection details, got %+v", tc.reason, got)
+			}
+			if c := cr.GetCondition(degraded.TypeDegradedObservation); c.Status != corev1.ConditionTrue || !strings.Contains(c.Message, "GetUserCredentials") {
+				t.Errorf("\n%s\ne.Observe(...): want a degraded observation naming GetUserCredentials, got %+v", tc.reason, c)
+			}
+			if c := cr.GetCondition(xpv2.TypeReady); c.Reason == xpv2.ReasonAvailable {
+				t.Errorf("\n%s\ne.Observe(...): want the access key not available while degraded", tc.reason)
+			}
+		})
+	}
+}
+
+func TestDeleteAfterCredentialRotation(t *testing.T) {
+	const rotatedAuthHeader = "Basic cm90YXRlZDpwYXNzd29yZA=="
+	deleted := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Header.Get("Authorization") != rotatedAuthHeader:
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.Method == http.MethodDelete && r.URL.Path == "/user/credentials":
+			deleted = r.URL.Query().Get("accessKey") == "00112233445566778899"
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	pc := &apisv1alpha1cluster.ProviderConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: connecttest.ProviderConfigName},
+		Spec:       connecttest.ProviderConfigSpec(xpv2.CredentialsSourceSecret),
+	}
+	pc.Spec.Endpoint = srv.URL
+	secret := connecttest.Secret(connecttest.CABundle(t))
+	mg := &userv1alpha1cluster.AccessKey{}
+	mg.SetProviderConfigReference(&xpv2.Reference{Name: connecttest.ProviderConfigName})
+	meta.SetExternalName(mg, "00112233445566778899")
+
+	c := apierror.NewHandler(event.NewNopRecorder()).Connector(&connector{
+		kube:         &test.MockClient{MockGet: connecttest.MockGet(pc, secret)},
+		usage:        resource.LegacyTrackerFn(func(context.Context, resource.LegacyManaged) error { return nil }),
+		newServiceFn: clients.NewCache(controllercommon.NewCloudianService).Get,
+	})
+	// Every reconcile connects anew, so that it uses the current credentials.
+	reconcileDelete := func() error {
+		ext, err := c.Connect(context.Background(), mg)
+		if err != nil {
+			return err
+		}
+		_, err = ext.Delete(context.Background(), mg)
+		return err
+	}
+
+	want := string(apierror.ReasonInvalidProviderCredentials) + ": "
+	if err := reconcileDelete(); err == nil || !strings.HasPrefix(err.Error(), want) {
+		t.Fatalf("ext.Delete(...): want error starting with %q before the credentials are rotated, got %v", want, err)
+	}
+
+	secret.Data[connecttest.AuthHeaderKey] = []byte(rotatedAuthHeader)
+	if err := reconcileDelete(); err != nil {
+		t.Fatalf("ext.Delete(...): want the rotated credentials to be used, got %v", err)
+	}
+	if !deleted {
+		t.Error("ext.Delete(...): want the access key to be deleted")
+	}
+}
+
+func TestAccessKeysOfUser(t *testing.T) {
+	user := &userv1alpha1cluster.User{ObjectMeta: metav1.ObjectMeta{Name: "alice", Labels: map[string]string{"team": "a"}}}
+	user.Spec.ForProvider.GroupID = "QA"
+	meta.SetExternalName(user, "alice")
+
+	newAccessKey := func(name string, p userv1alpha1common.AccessKeyParameters) *userv1alpha1cluster.AccessKey {
+		ak := &userv1alpha1cluster.AccessKey{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		ak.Spec.ForProvider = p
+		return ak
+	}
+	s := runtime.NewScheme()
+	if err := apiscluster.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	kube := fake.NewClientBuilder().WithScheme(s).WithObjects(
+		newAccessKey("by-id", userv1alpha1common.AccessKeyParameters{GroupID: "QA", UserID: "alice"}),
+		newAccessKey("by-ref", userv1alpha1common.AccessKeyParameters{UserIDRef: &xpv2.Reference{Name: "alice"}}),
+		newAccessKey("by-selector", userv1alpha1common.AccessKeyParameters{UserIDSelector: &xpv2.Selector{MatchLabels: map[string]string{"team": "a"}}}),
+		newAccessKey("other-group", userv1alpha1common.AccessKeyParameters{GroupID: "Ops", UserID: "alice"}),
+		newAccessKey("other-ref", userv1alpha1common.AccessKeyParameters{UserIDRef: &xpv2.Reference{Name: "bob"}}),
+	).Build()
+
+	got := accessKeysOfUser(kube)(context.Background(), user)
+	want := []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Name: "by-id"}},
+		{NamespacedName: types.NamespacedName{Name: "by-ref"}},
+		{NamespacedName: types.NamespacedName{Name: "by-selector"}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("accessKeysOfUser(...): -want requests, +got requests:\n%s", diff)
+	}
+}
@@ -25,6 +25,7 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 
 	apisv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/v1alpha1"
+	"github.com/statnett/provider-cloudian/internal/controller/common/health"
 )
 
 // SetupGated registers controller setup with the gate, waiting for the
@@ -39,7 +40,7 @@ func SetupGated(mgr ctrl.Manager, o controller.Options) error {
 }
 
 // Setup adds a controller that reconciles ProviderConfigs by accounting for
-// their current usage.
+// their current usage, and one that checks their health.
 func Setup(mgr ctrl.Manager, o controller.Options) error {
 	name := providerconfig.ControllerName(apisv1alpha1cluster.ProviderConfigGroupKind)
 
@@ -54,10 +55,14 @@ func Setup(mgr ctrl.Manager, o controller.Options) error {
 		//nolint:staticcheck // SA1004 crossplane-runtime still depends on deprecated API
 		providerconfig.WithRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor(name))))
 
-	return ctrl.NewControllerManagedBy(mgr).
+	if err := ctrl.NewControllerManagedBy(mgr).
 		Named(name).
 		WithOptions(o.ForControllerRuntime()).
 		For(&apisv1alpha1cluster.ProviderConfig{}).
 		Watches(&apisv1alpha1cluster.ProviderConfigUsage{}, &resource.EnqueueRequestForProviderConfig{}).
-		Complete(ratelimiter.NewReconciler(name, r, o.GlobalRateLimiter))
+		Complete(ratelimiter.NewReconciler(name, r, o.GlobalRateLimiter)); err != nil {
+		return err
+	}
+
+	return health.Setup(mgr, o, apisv1alpha1cluster.ProviderConfigGroupVersionKind, func() health.ProviderConfig { return &apisv1alpha1cluster.ProviderConfig{} })
 }
@@ -21,21 +21,30 @@ import (
 
 	"github.com/pkg/errors"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/crossplane/crossplane-runtime/v2/pkg/controller"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/event"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
-	"github.com/crossplane/crossplane-runtime/v2/pkg/ratelimiter"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/managed"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
 	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
 
 	userv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/user/v1alpha1"
 	apisv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/v1alpha1"
+	pcv1alpha1common "github.com/statnett/provider-cloudian/apis/common/providerconfig/v1alpha1"
+	"github.com/statnett/provider-cloudian/internal/clients"
 	controllercommon "github.com/statnett/provider-cloudian/internal/controller/common"
+	"github.com/statnett/provider-cloudian/internal/controller/common/conflict"
+	"github.com/statnett/provider-cloudian/internal/controller/common/degraded"
 	groupcontrollercommon "github.com/statnett/provider-cloudian/internal/controller/common/group"
+	"github.com/statnett/provider-cloudian/internal/controller/common/identity"
+	"github.com/statnett/provider-cloudian/internal/controller/common/ownership"
+	"github.com/statnett/provider-cloudian/internal/controller/common/tombstone"
+	"github.com/statnett/provider-cloudian/internal/features"
 	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
 )
 
@@ -49,7 +58,13 @@ const (
 	errCreateGroup = "cannot create Group"
 	errDeleteGroup = "cannot delete Group"
 	errGetGroup    = "cannot get Group"
+	errInvalidID   = "invalid Cloudian group ID"
 	errUpdateGroup = "cannot update Group"
+	errIgnore      = "cannot tell which fields to ignore"
+	errTombstone   = "cannot tell whether the Group was recently deleted"
+	errRecordTomb  = "cannot record the deletion of the Group"
+	errListUsers   = "cannot list the users of the Group"
+	errLeftover    = "group %s was deleted less than %s ago and still exists in Cloudian, waiting for its deletion to complete"
 )
 
 // SetupGated registers controller setup with the gate, waiting for the
@@ -65,33 +80,39 @@ func SetupGated(mgr ctrl.Manager, o controller.Options) error {
 
 // Setup adds a controller that reconciles Group managed resources.
 func Setup(mgr ctrl.Manager, o controller.Options) error {
-	name := managed.ControllerName(userv1alpha1cluster.GroupGroupKind)
-
-	r := managed.NewReconciler(mgr,
-		resource.ManagedKind(userv1alpha1cluster.GroupGroupVersionKind),
-		managed.WithExternalConnector(&connector{
-			kube:         mgr.GetClient(),
-			usage:        resource.NewLegacyProviderConfigUsageTracker(mgr.GetClient(), &apisv1alpha1cluster.ProviderConfigUsage{}),
-			newServiceFn: controllercommon.NewCloudianService}),
-		managed.WithLogger(o.Logger.WithValues("controller", name)),
-		managed.WithPollInterval(o.PollInterval),
-		//nolint:staticcheck // SA1004 crossplane-runtime still depends on deprecated API
-		managed.WithRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor(name))))
-
-	return ctrl.NewControllerManagedBy(mgr).
-		Named(name).
-		WithOptions(o.ForControllerRuntime()).
-		WithEventFilter(resource.DesiredStateChanged()).
-		For(&userv1alpha1cluster.Group{}).
-		Complete(ratelimiter.NewReconciler(name, r, o.GlobalRateLimiter))
+	return controllercommon.SetupManaged[userv1alpha1cluster.Group](mgr, o, controllercommon.ManagedKind{
+		GroupVersionKind: userv1alpha1cluster.GroupGroupVersionKind,
+		Timeout:          controllercommon.ReconcileTimeouts.Group,
+		NewConnector: func(recorder event.Recorder) managed.ExternalConnector {
+			return &connector{
+				kube:         mgr.GetClient(),
+				usage:        resource.NewLegacyProviderConfigUsageTracker(mgr.GetClient(), &apisv1alpha1cluster.ProviderConfigUsage{}),
+				newServiceFn: clients.Shared.Get,
+				recorder:     recorder,
+				tombstones:   tombstone.NewStore(mgr.GetAPIReader(), mgr.GetClient(), controllercommon.ProviderNamespace, clock.RealClock{}),
+				clock:        clock.RealClock{},
+				countUsers:   o.Features.Enabled(features.EnableGroupUserCount),
+			}
+		},
+		Options: []managed.ReconcilerOption{
+			managed.WithInitializers(groupcontrollercommon.NewGroupIDInitializer(mgr.GetClient())),
+			managed.WithPollIntervalHook(conflict.PollIntervalHook),
+		},
+	})
 }
 
 // A connector is expected to produce an ExternalClient when its Connect method
 // is called.
 type connector struct {
 	kube         client.Client
-	usage        *resource.LegacyProviderConfigUsageTracker
-	newServiceFn func(providerConfigEndpoint string, authHeader string) (*cloudian.Client, error)
+	usage        resource.LegacyTracker
+	newServiceFn func(uid types.UID, spec pcv1alpha1common.ProviderConfigSpec, creds controllercommon.Credentials) (*cloudian.Client, error)
+	recorder     event.Recorder
+	tombstones   *tombstone.Store
+	// clock dates the reverts of the group by other systems.
+	clock clock.PassiveClock
+	// countUsers enables counting the users of the group in its status.
+	countUsers bool
 }
 
 // Connect typically produces an ExternalClient by:
@@ -114,18 +135,17 @@ func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.E
 		return nil, errors.Wrap(err, errGetPC)
 	}
 
-	cd := pc.Spec.AuthHeader
-	authHeader, err := resource.CommonCredentialExtractor(ctx, cd.Source, c.kube, cd.CommonCredentialSelectors)
+	creds, err := controllercommon.ExtractCredentials(ctx, c.kube, pc.Spec)
 	if err != nil {
 		return nil, errors.Wrap(err, errGetCreds)
 	}
 
-	svc, err := c.newServiceFn(pc.Spec.Endpoint, string(authHeader))
+	svc, err := c.newServiceFn(pc.GetUID(), pc.Spec, creds)
 	if err != nil {
 		return nil, errors.Wrap(err, errNewClient)
 	}
 
-	return &external{cloudianService: svc}, nil
+	return &external{cloudianService: svc, allEndpoints: pc.Spec.S3Endpoints, recorder: c.recorder, tombstones: c.tombstones, clock: c.clock, endpoint: pc.Spec.Endpoint, clusterID: ownership.ClusterID(pc.Spec), countUsers: c.countUsers, defaultName: pc.Spec.DefaultGroupNames}, nil
 }
 
 // An ExternalClient observes, then either creates, updates, or deletes an
@@ -134,6 +154,27 @@ type external struct {
 	// A 'client' used to connect to the external resource API. In practice this
 	// would be something like an AWS SDK client.
 	cloudianService *cloudian.Client
+	// allEndpoints are the S3 endpoints of the cluster listed by the
+	// ProviderConfig, if any.
+	allEndpoints []string
+	recorder     event.Recorder
+	// tombstones of the groups recently deleted, identified by endpoint and
+	// group ID.
+	tombstones *tombstone.Store
+	// clock dates the reverts of the group by other systems.
+	clock      clock.PassiveClock
+	endpoint   string
+	countUsers bool
+	// defaultName defaults the name of groups left unset to the name of
+	// their managed resource.
+	defaultName bool
+	// clusterID is the cluster claiming ownership through the ProviderConfig,
+	// if any. Groups whose marked users all belong to other clusters are not
+	// modified.
+	clusterID string
+	// observed is the group as Observe found it, which Update only
+	// overwrites if it is still unchanged.
+	observed *cloudian.Group
 }
 
 func (c *external) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
@@ -142,12 +183,20 @@ func (c *external) Observe(ctx context.Context, mg resource.Managed) (managed.Ex
 		return managed.ExternalObservation{}, errors.New(errNotGroup)
 	}
 
-	externalName := meta.GetExternalName(cr)
-	if externalName == "" {
+	groupID := cr.GetGroupID()
+	if groupID == "" {
 		return managed.ExternalObservation{}, nil
 	}
 
-	observedGroup, err := c.cloudianService.GetGroup(ctx, externalName)
+	if err := cloudian.ValidateGroupID(groupID); err != nil {
+		if meta.WasDeleted(cr) {
+			// Cloudian would have rejected the group, so there is nothing to delete.
+			return managed.ExternalObservation{}, nil
+		}
+		return managed.ExternalObservation{}, errors.Wrap(err, errInvalidID)
+	}
+
+	observedGroup, err := c.cloudianService.GetGroup(ctx, groupID)
 	if errors.Is(err, cloudian.ErrNotFound) {
 		return managed.ExternalObservation{ResourceExists: false}, nil
 	}
@@ -155,8 +204,49 @@ func (c *external) Observe(ctx context.Context, mg resource.Managed) (managed.Ex
 		return managed.ExternalObservation{}, errors.Wrap(err, errGetGroup)
 	}
 
+	leftover, err := c.tombstones.Leftover(ctx, cr, tombstone.Identity(c.endpoint, groupID))
+	if err != nil {
+		return managed.ExternalObservation{}, errors.Wrap(err, errTombstone)
+	}
+	if leftover {
+		// What remains of a group deleted with a predecessor of this managed
+		// resource is not adopted, lest its users be inherited. Create waits
+		// for the deletion to complete instead.
+		return managed.ExternalObservation{ResourceExists: false}, nil
+	}
+
+	ignored, err := groupcontrollercommon.IgnoredFields(cr)
+	if err != nil && !meta.WasDeleted(cr) {
+		return managed.ExternalObservation{}, errors.Wrap(err, errIgnore)
+	}
+
+	c.observed = observedGroup
+	lateInitialized := groupcontrollercommon.LateInitialize(&cr.Spec.ForProvider, *observedGroup)
+
+	groupcontrollercommon.Observe(&cr.Status.AtProvider, groupID, *observedGroup)
+	// The group is known to exist, so its users failing to be counted only
+	// leaves their count as last observed.
+	var observation degraded.Observation
+	if c.countUsers {
+		count, err := c.cloudianService.CountUsers(ctx, groupID)
+		if err != nil {
+			observation.Fail("CountUsers", err)
+		} else {
+			cr.Status.AtProvider.UserCount = ptr.To(int64(count))
+		}
+	}
+	observation.SetCondition(cr)
 	cr.SetConditions(xpv2.Available())
 
+	allEndpoints := groupcontrollercommon.ClusterEndpoints(ctx, c.cloudianService, c.allEndpoints)
+	desired := groupcontrollercommon.Desired(cr, cr.Spec.ForProvider, c.defaultName)
+	upToDate := groupcontrollercommon.IsUpToDate(groupID, desired, *observedGroup, allEndpoints, ignored)
+	now := c.clock.Now()
+	conflict.Observe(cr.Status.AtProvider.Reverts, conflict.Hash(desired), upToDate, now)
+	if conflict.SetCondition(cr, cr.Status.AtProvider.Reverts, now) {
+		c.recorder.Event(cr, conflict.Warning(cr.GetCondition(conflict.TypeExternalConflict)))
+	}
+
 	return managed.ExternalObservation{
 		// Return false when the external resource does not exist. This lets
 		// the managed resource reconciler know that it needs to call Create to
@@ -166,7 +256,11 @@ func (c *external) Observe(ctx context.Context, mg resource.Managed) (managed.Ex
 		// Return false when the external resource exists, but it not up to date
 		// with the desired managed resource state. This lets the managed
 		// resource reconciler know that it needs to call Update.
-		ResourceUpToDate: groupcontrollercommon.IsUpToDate(meta.GetExternalName(mg), cr.Spec.ForProvider, *observedGroup),
+		ResourceUpToDate: upToDate,
+
+		// Return true when the spec was updated with observed values that
+		// were left unset.
+		ResourceLateInitialized: lateInitialized,
 
 		// Return any details that may be required to connect to the external
 		// resource. These will be stored as the connection secret.
@@ -180,9 +274,29 @@ func (c *external) Create(ctx context.Context, mg resource.Managed) (managed.Ext
 		return managed.ExternalCreation{}, errors.New(errNotGroup)
 	}
 
+	defer identity.Mutations.Lock(cr.GetGroupID())()
+
+	if err := cloudian.ValidateGroupID(cr.GetGroupID()); err != nil {
+		return managed.ExternalCreation{}, errors.Wrap(err, errInvalidID)
+	}
+
+	leftover, err := c.tombstones.Leftover(ctx, cr, tombstone.Identity(c.endpoint, cr.GetGroupID()))
+	if err != nil {
+		return managed.ExternalCreation{}, errors.Wrap(err, errTombstone)
+	}
+	if leftover {
+		_, err := c.cloudianService.GetGroup(ctx, cr.GetGroupID())
+		if err == nil {
+			return managed.ExternalCreation{}, errors.Errorf(errLeftover, cr.GetGroupID(), tombstone.Window)
+		}
+		if !errors.Is(err, cloudian.ErrNotFound) {
+			return managed.ExternalCreation{}, errors.Wrap(err, errGetGroup)
+		}
+	}
+
 	cr.SetConditions(xpv2.Creating())
 
-	if err := c.cloudianService.CreateGroup(ctx, groupcontrollercommon.NewCloudianGroup(meta.GetExternalName(mg), cr.Spec.ForProvider)); err != nil {
+	if _, err := c.cloudianService.CreateGroup(ctx, groupcontrollercommon.NewCloudianGroup(cr.GetGroupID(), groupcontrollercommon.Desired(cr, cr.Spec.ForProvider, c.defaultName))); err != nil {
 		return managed.ExternalCreation{}, errors.Wrap(err, errCreateGroup)
 	}
 
@@ -199,9 +313,36 @@ func (c *external) Update(ctx context.Context, mg resource.Managed) (managed.Ext
 		return managed.ExternalUpdate{}, errors.New(errNotGroup)
 	}
 
-	if err := c.cloudianService.UpdateGroup(ctx, groupcontrollercommon.NewCloudianGroup(meta.GetExternalName(mg), cr.Spec.ForProvider)); err != nil {
+	defer identity.Mutations.Lock(cr.GetGroupID())()
+
+	ignored, err := groupcontrollercommon.IgnoredFields(cr)
+	if err != nil {
+		return managed.ExternalUpdate{}, errors.Wrap(err, errIgnore)
+	}
+
+	if err := c.checkOwnership(ctx, cr); err != nil {
+		return managed.ExternalUpdate{}, err
+	}
+
+	observedGroup := c.observed
+	if observedGroup == nil {
+		observedGroup, err = c.cloudianService.GetGroup(ctx, cr.GetGroupID())
+		if err != nil {
+			return managed.ExternalUpdate{}, errors.Wrap(err, errGetGroup)
+		}
+	}
+
+	desired := groupcontrollercommon.Desired(cr, cr.Spec.ForProvider, c.defaultName)
+	err = c.cloudianService.UpdateGroupIfMatches(ctx, groupcontrollercommon.MergeObserved(cr.GetGroupID(), desired, *observedGroup, ignored), *observedGroup)
+	if errors.Is(err, cloudian.ErrConflict) {
+		// The group changed since it was observed, as when another replica
+		// of the provider updated it. The next reconcile observes it again.
+		return managed.ExternalUpdate{}, nil
+	}
+	if err != nil {
 		return managed.ExternalUpdate{}, errors.Wrap(err, errUpdateGroup)
 	}
+	conflict.Applied(&cr.Status.AtProvider.Reverts, conflict.Hash(desired))
 
 	return managed.ExternalUpdate{
 		// Optionally return any details that may be required to connect to the
@@ -216,15 +357,60 @@ func (c *external) Delete(ctx context.Context, mg resource.Managed) (managed.Ext
 		return managed.ExternalDelete{}, errors.New(errNotGroup)
 	}
 
+	defer identity.Mutations.Lock(cr.GetGroupID())()
+
 	cr.SetConditions(xpv2.Deleting())
 
-	if err := c.cloudianService.DeleteGroup(ctx, meta.GetExternalName(mg)); err != nil {
-		return managed.ExternalDelete{}, errors.Wrap(err, errDeleteGroup)
+	if err := c.checkOwnership(ctx, cr); err != nil {
+		return managed.ExternalDelete{}, err
 	}
 
+	// Managed resources recreated with the same group ID must not adopt the
+	// group while Cloudian finishes deleting it. The tombstone is recorded
+	// first, so that a group is never deleted without one.
+	if err := c.tombstones.Record(ctx, tombstone.Identity(c.endpoint, cr.GetGroupID())); err != nil {
+		return managed.ExternalDelete{}, errors.Wrap(err, errRecordTomb)
+	}
+
+	if cr.Spec.ForProvider.RecursiveDelete {
+		report, err := c.cloudianService.DeleteGroupRecursive(ctx, cr.GetGroupID(), cr.Spec.ForProvider.ForceRecursiveDelete)
+		if err != nil && !errors.Is(err, cloudian.ErrNotFound) {
+			return managed.ExternalDelete{}, errors.Wrap(groupcontrollercommon.Summarize(report, err), errDeleteGroup)
+		}
+	} else if err := c.cloudianService.DeleteGroup(ctx, cr.GetGroupID()); err != nil && !errors.Is(err, cloudian.ErrNotFound) {
+		if errors.Is(err, cloudian.ErrGroupNotEmpty) {
+			err = groupcontrollercommon.NotEmpty(cr, err)
+		}
+		return managed.ExternalDelete{}, errors.Wrap(err, errDeleteGroup)
+	}
 	return managed.ExternalDelete{}, nil
 }
 
+// checkOwnership refuses to modify groups whose users are owned by another
+// cluster only, unless this cluster created the group. Groups that are only
+// observed may be owned by any cluster.
+func (c *external) checkOwnership(ctx context.Context, cr *userv1alpha1cluster.Group) error {
+	if c.clusterID == "" || controllercommon.ObserveOnly(cr) || !meta.GetExternalCreateSucceeded(cr).IsZero() {
+		return nil
+	}
+	users, err := c.cloudianService.ListUsers(ctx, cr.GetGroupID(), nil)
+	if errors.Is(err, cloudian.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, errListUsers)
+	}
+	fields := make([]string, len(users))
+	for i, user := range users {
+		fields[i] = user.Address2
+	}
+	if err := ownership.CheckMembers(fields, c.clusterID); err != nil {
+		cr.SetConditions(ownership.OwnedByAnotherCluster(err))
+		return err
+	}
+	return nil
+}
+
 func (c *external) Disconnect(ctx context.Context) error {
 	return nil
 }
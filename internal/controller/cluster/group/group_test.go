@@ -18,15 +18,53 @@ package group
 
 import (
 	"context"
+	"errors"
+	"net/http"
+	"strings"
 	"testing"
+	"time"
 
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
 	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	testingclock "k8s.io/utils/clock/testing"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	"github.com/crossplane/crossplane-runtime/v2/pkg/event"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/managed"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	xpfake "github.com/crossplane/crossplane-runtime/v2/pkg/resource/fake"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/test"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/xcrd"
+
+	apiscluster "github.com/statnett/provider-cloudian/apis/cluster"
+	userv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/user/v1alpha1"
+	apisv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/v1alpha1"
+	pcv1alpha1common "github.com/statnett/provider-cloudian/apis/common/providerconfig/v1alpha1"
+	userv1alpha1common "github.com/statnett/provider-cloudian/apis/common/user/v1alpha1"
+	controllercommon "github.com/statnett/provider-cloudian/internal/controller/common"
+	"github.com/statnett/provider-cloudian/internal/controller/common/apierror"
+	"github.com/statnett/provider-cloudian/internal/controller/common/conflict"
+	"github.com/statnett/provider-cloudian/internal/controller/common/connecttest"
+	"github.com/statnett/provider-cloudian/internal/controller/common/degraded"
+	groupcontrollercommon "github.com/statnett/provider-cloudian/internal/controller/common/group"
+	"github.com/statnett/provider-cloudian/internal/controller/common/ownership"
+	"github.com/statnett/provider-cloudian/internal/controller/common/tombstone"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian/cloudiantest"
 )
 
+// epoch is the time the clocks of the tests start at.
+var epoch = time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
 // Unlike many Kubernetes projects Crossplane does not use third party testing
 // libraries, per the common Go test review comments. Crossplane encourages the
 // use of table driven unit tests. The tests of the crossplane-runtime project
@@ -72,3 +110,841 @@ func TestObserve(t *testing.T) {
 		})
 	}
 }
+
+func TestUpdateKeepsLDAP(t *testing.T) {
+	ctx := context.Background()
+
+	// LDAP configured in Cloudian outside the provider.
+	configured := cloudian.Group{
+		Active:        true,
+		GroupID:       "QA",
+		GroupName:     "Quality Assurance",
+		LDAPEnabled:   true,
+		LDAPGroup:     "qa",
+		LDAPServerURL: "ldaps://ldap.example.com",
+	}
+
+	newGroup := func() *userv1alpha1cluster.Group {
+		cr := &userv1alpha1cluster.Group{ObjectMeta: metav1.ObjectMeta{Name: "QA"}}
+		cr.Spec.ForProvider.GroupID = "QA"
+		cr.Spec.ForProvider.Active = true
+		cr.Spec.ForProvider.GroupName = "Renamed"
+		return cr
+	}
+
+	cases := map[string]struct {
+		reason  string
+		observe bool
+	}{
+		"LateInitialized": {
+			reason:  "LDAP settings late initialized by Observe should be sent back unchanged.",
+			observe: true,
+		},
+		"NotLateInitialized": {
+			reason: "LDAP settings left unset in the spec should keep their values in Cloudian when late initialization is disabled.",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			srv := cloudiantest.NewFakeServer(t)
+			svc := cloudian.NewClient(srv.URL, "")
+			if _, err := svc.CreateGroup(ctx, configured); err != nil {
+				t.Fatal(err)
+			}
+			e := &external{clock: testingclock.NewFakePassiveClock(epoch), cloudianService: svc}
+
+			cr := newGroup()
+			if tc.observe {
+				o, err := e.Observe(ctx, cr)
+				if err != nil {
+					t.Fatalf("e.Observe(...): %v", err)
+				}
+				if o.ResourceUpToDate {
+					t.Errorf("\n%s\ne.Observe(...): want renamed group to need an update", tc.reason)
+				}
+				if !o.ResourceLateInitialized {
+					t.Errorf("\n%s\ne.Observe(...): want the LDAP settings late initialized into the spec", tc.reason)
+				}
+				all := []string{cloudian.AllEndpoints}
+				want := userv1alpha1common.GroupObservation{
+					GroupID:       "QA",
+					GroupName:     "Quality Assurance",
+					Active:        true,
+					S3Endpoints:   &userv1alpha1common.S3Endpoints{HTTP: all, HTTPS: all, Website: all},
+					LDAPEnabled:   true,
+					LDAPGroup:     "qa",
+					LDAPServerURL: "ldaps://ldap.example.com",
+				}
+				if diff := cmp.Diff(want, cr.Status.AtProvider); diff != "" {
+					t.Errorf("\n%s\ne.Observe(...): -want observation, +got:\n%s", tc.reason, diff)
+				}
+			}
+
+			if _, err := e.Update(ctx, cr); err != nil {
+				t.Fatalf("e.Update(...): %v", err)
+			}
+			got, err := svc.GetGroup(ctx, "QA")
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := configured
+			want.GroupName = "Renamed"
+			if !want.Equal(*got) {
+				t.Errorf("\n%s\ne.Update(...): want group %+v, got %+v", tc.reason, want, *got)
+			}
+		})
+	}
+}
+
+func TestIgnoreFields(t *testing.T) {
+	srv := cloudiantest.NewFakeServer(t)
+	svc := cloudian.NewClient(srv.URL, "")
+	ctx := context.Background()
+
+	// The group name is managed in Cloudian outside the provider.
+	configured := cloudian.Group{Active: true, GroupID: "QA", GroupName: "Quality Assurance", LDAPSearch: "(uid={userId})"}
+	if _, err := svc.CreateGroup(ctx, configured); err != nil {
+		t.Fatal(err)
+	}
+
+	e := &external{clock: testingclock.NewFakePassiveClock(epoch), cloudianService: svc}
+	newGroup := func(ignore string) *userv1alpha1cluster.Group {
+		cr := &userv1alpha1cluster.Group{ObjectMeta: metav1.ObjectMeta{
+			Name:        "QA",
+			Annotations: map[string]string{groupcontrollercommon.AnnotationIgnoreFields: ignore},
+		}}
+		cr.Spec.ForProvider.GroupID = "QA"
+		cr.Spec.ForProvider.Active = true
+		cr.Spec.ForProvider.GroupName = "quality assurance"
+		cr.Spec.ForProvider.LDAPSearch = ptr.To("(uid=*)")
+		return cr
+	}
+
+	cr := newGroup("groupName")
+	o, err := e.Observe(ctx, cr)
+	if err != nil {
+		t.Fatalf("e.Observe(...): %v", err)
+	}
+	if o.ResourceUpToDate {
+		t.Error("e.Observe(...): want the managed LDAP search to need an update")
+	}
+	if _, err := e.Update(ctx, cr); err != nil {
+		t.Fatalf("e.Update(...): %v", err)
+	}
+	got, err := svc.GetGroup(ctx, "QA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := configured
+	want.LDAPSearch = "(uid=*)"
+	if !want.Equal(*got) {
+		t.Errorf("e.Update(...): want the ignored group name kept, want group %+v, got %+v", want, *got)
+	}
+
+	if o, err := e.Observe(ctx, newGroup("groupName")); err != nil || !o.ResourceUpToDate {
+		t.Errorf("e.Observe(...): want the ignored group name to be up to date, got %t, %v", o.ResourceUpToDate, err)
+	}
+
+	if _, err := e.Observe(ctx, newGroup("groupname")); err == nil {
+		t.Error("e.Observe(...): want an error for an unknown field")
+	}
+	if _, err := e.Update(ctx, newGroup("groupname")); err == nil {
+		t.Error("e.Update(...): want an error for an unknown field")
+	}
+}
+
+func TestObserveUserCount(t *testing.T) {
+	srv := cloudiantest.NewFakeServer(t)
+	srv.AddGroup("QA")
+	srv.AddGroup("empty")
+	srv.AddUser(cloudiantest.User{GroupID: "QA", UserID: "alice"})
+	srv.AddUser(cloudiantest.User{GroupID: "QA", UserID: "bob"})
+	svc := cloudian.NewClient(srv.URL, "")
+
+	cases := map[string]struct {
+		reason     string
+		groupID    string
+		countUsers bool
+		want       *int64
+	}{
+		"Members": {
+			reason:     "The users of the group should be counted when enabled.",
+			groupID:    "QA",
+			countUsers: true,
+			want:       ptr.To[int64](2),
+		},
+		"Empty": {
+			reason:     "An empty group should be observed to have no users, rather than an unknown number.",
+			groupID:    "empty",
+			countUsers: true,
+			want:       ptr.To[int64](0),
+		},
+		"Disabled": {
+			reason:  "The users of the group should not be listed unless enabled.",
+			groupID: "QA",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cr := &userv1alpha1cluster.Group{ObjectMeta: metav1.ObjectMeta{Name: tc.groupID}}
+			cr.Spec.ForProvider.GroupID = tc.groupID
+			e := &external{clock: testingclock.NewFakePassiveClock(epoch), cloudianService: svc, countUsers: tc.countUsers}
+			if _, err := e.Observe(context.Background(), cr); err != nil {
+				t.Fatalf("\n%s\ne.Observe(...): %v", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want, cr.Status.AtProvider.UserCount); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want user count, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestObserveUserCountDegraded(t *testing.T) {
+	srv := cloudiantest.NewFakeServer(t)
+	srv.AddGroup("QA")
+	srv.AddUser(cloudiantest.User{GroupID: "QA", UserID: "alice"})
+	srv.Script("/user/list", cloudiantest.Respond(http.StatusServiceUnavailable, ""))
+	e := &external{clock: testingclock.NewFakePassiveClock(epoch), cloudianService: cloudian.NewClient(srv.URL, ""), countUsers: true}
+
+	cr := &userv1alpha1cluster.Group{ObjectMeta: metav1.ObjectMeta{Name: "QA"}}
+	cr.Spec.ForProvider.GroupID = "QA"
+	cr.Status.AtProvider.UserCount = ptr.To[int64](3)
+	got, err := e.Observe(context.Background(), cr)
+	if err != nil {
+		t.Fatalf("e.Observe(...): want the group observed despite failing to count its users, got %v", err)
+	}
+	if !got.ResourceExists {
+		t.Error("e.Observe(...): want the group to exist")
+	}
+	if c := cr.GetCondition(degraded.TypeDegradedObservation); c.Status != corev1.ConditionTrue || !strings.Contains(c.Message, "CountUsers") {
+		t.Errorf("e.Observe(...): want a degraded observation naming CountUsers, got %+v", c)
+	}
+	if diff := cmp.Diff(ptr.To[int64](3), cr.Status.AtProvider.UserCount); diff != "" {
+		t.Errorf("e.Observe(...): want the user count kept as last observed, -want +got:\n%s", diff)
+	}
+
+	if _, err := e.Observe(context.Background(), cr); err != nil {
+		t.Fatalf("e.Observe(...): %v", err)
+	}
+	if c := cr.GetCondition(degraded.TypeDegradedObservation); c.Status != corev1.ConditionFalse {
+		t.Errorf("e.Observe(...): want the observation complete once the admin API recovers, got %+v", c)
+	}
+	if diff := cmp.Diff(ptr.To[int64](1), cr.Status.AtProvider.UserCount); diff != "" {
+		t.Errorf("e.Observe(...): -want user count, +got:\n%s", diff)
+	}
+}
+
+func TestObserveClusterEndpoints(t *testing.T) {
+	srv := cloudiantest.NewFakeServer(t)
+	srv.SetS3Endpoints(
+		cloudiantest.RegionS3Endpoints{Region: "region1", HTTPS: []string{"s3-a.example.com"}},
+		cloudiantest.RegionS3Endpoints{Region: "region2", HTTPS: []string{"s3-b.example.com"}, Website: []string{"s3-website-b.example.com"}},
+	)
+	svc := cloudian.NewClient(srv.URL, "")
+	ctx := context.Background()
+	if _, err := svc.CreateGroup(ctx, cloudian.Group{Active: true, GroupID: "QA", S3EndpointsHTTPS: []string{"s3-b.example.com", "s3-a.example.com"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]struct {
+		reason     string
+		configured []string
+		want       bool
+		wantReads  int
+	}{
+		"Reported": {
+			reason:    "Allowing every HTTPS endpoint Cloudian reports should match allowing ALL.",
+			want:      true,
+			wantReads: 1,
+		},
+		"Configured": {
+			reason:     "The S3 endpoints of the ProviderConfig should be used instead of those Cloudian reports.",
+			configured: []string{"s3-a.example.com", "s3-c.example.com"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			reads := srv.Requests("/system/s3endpoints")
+			cr := &userv1alpha1cluster.Group{ObjectMeta: metav1.ObjectMeta{Name: "QA"}}
+			cr.Spec.ForProvider.GroupID = "QA"
+			cr.Spec.ForProvider.Active = true
+			all := []string{cloudian.AllEndpoints}
+			cr.Spec.ForProvider.S3Endpoints = &userv1alpha1common.S3Endpoints{HTTP: all, HTTPS: all, Website: all}
+
+			e := &external{clock: testingclock.NewFakePassiveClock(epoch), cloudianService: svc, allEndpoints: tc.configured}
+			o, err := e.Observe(ctx, cr)
+			if err != nil {
+				t.Fatalf("\n%s\ne.Observe(...): %v", tc.reason, err)
+			}
+			if o.ResourceUpToDate != tc.want {
+				t.Errorf("\n%s\ne.Observe(...): want up to date %t, got %t", tc.reason, tc.want, o.ResourceUpToDate)
+			}
+			if got := srv.Requests("/system/s3endpoints") - reads; got != tc.wantReads {
+				t.Errorf("\n%s\ne.Observe(...): want %d reads of the S3 endpoints, got %d", tc.reason, tc.wantReads, got)
+			}
+		})
+	}
+}
+
+func TestDefaultGroupName(t *testing.T) {
+	const defaulted = "qa-x7k2p managed-by=crossplane/team-a/qa"
+
+	cases := map[string]struct {
+		reason      string
+		defaultName bool
+		groupName   string
+		// observed is the name of the group in Cloudian before the update.
+		observed string
+		want     string
+	}{
+		"Defaulted": {
+			reason:      "A group without a name should get the default name.",
+			defaultName: true,
+			want:        defaulted,
+		},
+		"Explicit": {
+			reason:      "An explicit name should replace the default name.",
+			defaultName: true,
+			groupName:   "Quality Assurance",
+			observed:    defaulted,
+			want:        "Quality Assurance",
+		},
+		"ExplicitRemoved": {
+			reason:      "Removing the explicit name should bring back the default name.",
+			defaultName: true,
+			observed:    "Quality Assurance",
+			want:        defaulted,
+		},
+		"Disabled": {
+			reason: "A group without a name should keep its name without defaulting.",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			srv := cloudiantest.NewFakeServer(t)
+			svc := cloudian.NewClient(srv.URL, "")
+			ctx := context.Background()
+			if _, err := svc.CreateGroup(ctx, cloudian.Group{Active: true, GroupID: "QA", GroupName: tc.observed}); err != nil {
+				t.Fatal(err)
+			}
+
+			cr := &userv1alpha1cluster.Group{ObjectMeta: metav1.ObjectMeta{
+				Name:   "qa-x7k2p",
+				Labels: map[string]string{xcrd.LabelKeyClaimNamespace: "team-a", xcrd.LabelKeyClaimName: "qa"},
+			}}
+			cr.Spec.ForProvider.GroupID = "QA"
+			cr.Spec.ForProvider.Active = true
+			cr.Spec.ForProvider.GroupName = tc.groupName
+
+			e := &external{clock: testingclock.NewFakePassiveClock(epoch), cloudianService: svc, recorder: &recorder{}, defaultName: tc.defaultName}
+			o, err := e.Observe(ctx, cr)
+			if err != nil {
+				t.Fatalf("\n%s\ne.Observe(...): %v", tc.reason, err)
+			}
+			if o.ResourceUpToDate != (tc.observed == tc.want) {
+				t.Errorf("\n%s\ne.Observe(...): want up to date %t, got %t", tc.reason, tc.observed == tc.want, o.ResourceUpToDate)
+			}
+			if !o.ResourceUpToDate {
+				if _, err := e.Update(ctx, cr); err != nil {
+					t.Fatalf("\n%s\ne.Update(...): %v", tc.reason, err)
+				}
+			}
+
+			got, err := svc.GetGroup(ctx, "QA")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.GroupName != tc.want {
+				t.Errorf("\n%s\nCloudian group: want name %q, got %q", tc.reason, tc.want, got.GroupName)
+			}
+			if cr.Spec.ForProvider.GroupName != tc.groupName {
+				t.Errorf("\n%s\ne.Observe(...): want spec GroupName %q unchanged, got %q", tc.reason, tc.groupName, cr.Spec.ForProvider.GroupName)
+			}
+			if o, err := e.Observe(ctx, cr); err != nil || !o.ResourceUpToDate {
+				t.Errorf("\n%s\ne.Observe(...): want up to date after the update, got %t, %v", tc.reason, o.ResourceUpToDate, err)
+			}
+		})
+	}
+}
+
+func TestCreateDefaultGroupName(t *testing.T) {
+	srv := cloudiantest.NewFakeServer(t)
+	svc := cloudian.NewClient(srv.URL, "")
+	ctx := context.Background()
+
+	cr := &userv1alpha1cluster.Group{ObjectMeta: metav1.ObjectMeta{Name: "qa"}}
+	cr.Spec.ForProvider.GroupID = "QA"
+	cr.Spec.ForProvider.Active = true
+
+	e := &external{clock: testingclock.NewFakePassiveClock(epoch), cloudianService: svc, recorder: &recorder{}, defaultName: true}
+	if _, err := e.Create(ctx, cr); err != nil {
+		t.Fatalf("e.Create(...): %v", err)
+	}
+	got, err := svc.GetGroup(ctx, "QA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.GroupName != "qa" {
+		t.Errorf("e.Create(...): want the group named after the managed resource, got %q", got.GroupName)
+	}
+}
+
+func TestCreateErrorReason(t *testing.T) {
+	srv := cloudiantest.NewFakeServer(t)
+	srv.Script("/group", cloudiantest.Respond(http.StatusBadRequest, `{"reason":"InvalidRegion","message":"Region eu-north-9 does not exist"}`))
+	cr := &userv1alpha1cluster.Group{ObjectMeta: metav1.ObjectMeta{Name: "qa"}}
+	cr.Spec.ForProvider.GroupID = "QA"
+
+	e := &external{clock: testingclock.NewFakePassiveClock(epoch), cloudianService: cloudian.NewClient(srv.URL, ""), recorder: &recorder{}}
+	_, err := e.Create(context.Background(), cr)
+	if want := "InvalidRegion: Region eu-north-9 does not exist"; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("e.Create(...): want error explaining %q, got %v", want, err)
+	}
+}
+
+func TestDeleteNotEmpty(t *testing.T) {
+	cases := map[string]struct {
+		reason    string
+		group     bool
+		users     bool
+		wantErr   error
+		wantBlock bool
+	}{
+		"Empty": {
+			reason: "An empty group should be deleted.",
+			group:  true,
+		},
+		"NotEmpty": {
+			reason:    "A group with users should not be deleted, and its deletion should be retried.",
+			group:     true,
+			users:     true,
+			wantErr:   cloudian.ErrGroupNotEmpty,
+			wantBlock: true,
+		},
+		"AlreadyDeleted": {
+			reason: "A group that is already deleted should be deleted.",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			srv := cloudiantest.NewFakeServer(t)
+			if tc.group {
+				srv.AddGroup("QA")
+			}
+			if tc.users {
+				srv.AddUser(cloudiantest.User{GroupID: "QA", UserID: "alice"})
+			}
+			svc := cloudian.NewClient(srv.URL, "")
+
+			cr := &userv1alpha1cluster.Group{ObjectMeta: metav1.ObjectMeta{Name: "QA"}}
+			cr.Spec.ForProvider.GroupID = "QA"
+			e := &external{clock: testingclock.NewFakePassiveClock(epoch), cloudianService: svc}
+			_, err := e.Delete(context.Background(), cr)
+			if !errors.Is(err, tc.wantErr) || (tc.wantErr == nil && err != nil) {
+				t.Errorf("\n%s\ne.Delete(...): want error %v, got %v", tc.reason, tc.wantErr, err)
+			}
+			if got := srv.HasGroup("QA"); got != tc.users {
+				t.Errorf("\n%s\ne.Delete(...): want group kept %t, got %t", tc.reason, tc.users, got)
+			}
+			blocked := cr.GetCondition(groupcontrollercommon.TypeDeletionBlocked)
+			if got := blocked.Status == corev1.ConditionTrue; got != tc.wantBlock {
+				t.Errorf("\n%s\ne.Delete(...): want deletion blocked %t, got condition %v", tc.reason, tc.wantBlock, blocked)
+			}
+			if tc.wantBlock && (blocked.Reason != groupcontrollercommon.ReasonGroupNotEmpty || !strings.Contains(err.Error(), "recursiveDelete")) {
+				t.Errorf("\n%s\ne.Delete(...): want the operator told to enable recursiveDelete, got %v and %v", tc.reason, blocked, err)
+			}
+		})
+	}
+}
+
+func TestUpdateConcurrentChange(t *testing.T) {
+	srv := cloudiantest.NewFakeServer(t)
+	svc := cloudian.NewClient(srv.URL, "")
+	ctx := context.Background()
+	if _, err := svc.CreateGroup(ctx, cloudian.Group{Active: true, GroupID: "QA", GroupName: "Quality"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cr := &userv1alpha1cluster.Group{ObjectMeta: metav1.ObjectMeta{Name: "QA"}}
+	cr.Spec.ForProvider.GroupID = "QA"
+	cr.Spec.ForProvider.Active = true
+	cr.Spec.ForProvider.GroupName = "Quality Assurance"
+
+	e := &external{clock: testingclock.NewFakePassiveClock(epoch), cloudianService: svc, recorder: &recorder{}}
+	o, err := e.Observe(ctx, cr)
+	if err != nil || o.ResourceUpToDate {
+		t.Fatalf("e.Observe(...): want the group outdated, got %t, %v", o.ResourceUpToDate, err)
+	}
+
+	// Another replica of the provider, with an older spec, updates the group
+	// between the Observe and the Update of this one.
+	if err := svc.UpdateGroup(ctx, cloudian.Group{Active: false, GroupID: "QA", GroupName: "Quality"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := e.Update(ctx, cr); err != nil {
+		t.Fatalf("e.Update(...): want no error when the group changed since it was observed, got %v", err)
+	}
+	got, err := svc.GetGroup(ctx, "QA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Active || got.GroupName != "Quality" {
+		t.Errorf("e.Update(...): want the change of the other replica kept until observed, got %+v", got)
+	}
+	if r := cr.Status.AtProvider.Reverts; r != nil && r.AppliedHash != "" {
+		t.Errorf("e.Update(...): want nothing recorded as applied, got %q", r.AppliedHash)
+	}
+
+	// The next reconcile observes the change and applies the spec.
+	e = &external{clock: testingclock.NewFakePassiveClock(epoch), cloudianService: svc, recorder: &recorder{}}
+	if o, err := e.Observe(ctx, cr); err != nil || o.ResourceUpToDate {
+		t.Fatalf("e.Observe(...): want the group outdated, got %t, %v", o.ResourceUpToDate, err)
+	}
+	if _, err := e.Update(ctx, cr); err != nil {
+		t.Fatalf("e.Update(...): %v", err)
+	}
+	if got, err := svc.GetGroup(ctx, "QA"); err != nil || !got.Active || got.GroupName != "Quality Assurance" {
+		t.Errorf("e.Update(...): want the spec applied, got %+v, %v", got, err)
+	}
+}
+
+// recorder records the events of the resources it is given.
+type recorder []event.Event
+
+func (r *recorder) Event(_ runtime.Object, e event.Event) { *r = append(*r, e) }
+
+func (r *recorder) WithAnnotations(...string) event.Recorder { return r }
+
+func TestExternalConflict(t *testing.T) {
+	srv := cloudiantest.NewFakeServer(t)
+	svc := cloudian.NewClient(srv.URL, "")
+	ctx := context.Background()
+
+	// A nightly script renames the group back after every update.
+	original := cloudian.Group{Active: true, GroupID: "QA", GroupName: "Quality Assurance"}
+	if _, err := svc.CreateGroup(ctx, original); err != nil {
+		t.Fatal(err)
+	}
+
+	events := &recorder{}
+	clock := testingclock.NewFakePassiveClock(epoch)
+	e := &external{clock: clock, cloudianService: svc, recorder: events}
+	cr := &userv1alpha1cluster.Group{ObjectMeta: metav1.ObjectMeta{Name: "QA"}}
+	cr.Spec.ForProvider.GroupID = "QA"
+	cr.Spec.ForProvider.Active = true
+	cr.Spec.ForProvider.GroupName = "Renamed"
+
+	for i := range conflict.Threshold + 1 {
+		o, err := e.Observe(ctx, cr)
+		if err != nil {
+			t.Fatalf("e.Observe(...): %v", err)
+		}
+		if o.ResourceUpToDate {
+			t.Fatalf("e.Observe(...): poll %d: want the reverted group to need an update", i)
+		}
+		if got := cr.GetCondition(conflict.TypeExternalConflict).Status; (got == corev1.ConditionTrue) != (i >= conflict.Threshold) {
+			t.Errorf("e.Observe(...): poll %d: want a conflict only after %d reverts, got condition %s", i, conflict.Threshold, got)
+		}
+		if _, err := e.Update(ctx, cr); err != nil {
+			t.Fatalf("e.Update(...): %v", err)
+		}
+		if err := svc.UpdateGroup(ctx, original); err != nil {
+			t.Fatal(err)
+		}
+		clock.SetTime(clock.Now().Add(time.Hour))
+	}
+	if len(*events) != 1 {
+		t.Errorf("e.Observe(...): want one event when the conflict is detected, got %v", *events)
+	}
+
+	// The script stops, and the reverts leave the window.
+	clock.SetTime(clock.Now().Add(conflict.Window))
+	if _, err := e.Observe(ctx, cr); err != nil {
+		t.Fatalf("e.Observe(...): %v", err)
+	}
+	if got := cr.GetCondition(conflict.TypeExternalConflict).Status; got != corev1.ConditionFalse {
+		t.Errorf("e.Observe(...): want the conflict resolved once its reverts are older than %s, got condition %s", conflict.Window, got)
+	}
+}
+
+func TestConnect(t *testing.T) {
+	mg := &userv1alpha1cluster.Group{}
+	mg.SetProviderConfigReference(&xpv2.Reference{Name: connecttest.ProviderConfigName})
+	pc := func(spec pcv1alpha1common.ProviderConfigSpec) client.Object {
+		return &apisv1alpha1cluster.ProviderConfig{ObjectMeta: metav1.ObjectMeta{Name: connecttest.ProviderConfigName}, Spec: spec}
+	}
+
+	connecttest.TestConnect(t, connecttest.Connector{
+		New: func(kube client.Client, newService connecttest.NewServiceFn) managed.ExternalConnecter {
+			return &connector{kube: kube, usage: resource.LegacyTrackerFn(func(context.Context, resource.LegacyManaged) error { return nil }), newServiceFn: newService}
+		},
+		Managed:        mg,
+		Other:          &userv1alpha1cluster.User{},
+		ProviderConfig: pc,
+		Connected: func(ext managed.ExternalClient) bool {
+			e, ok := ext.(*external)
+			return ok && e.cloudianService != nil
+		},
+		ErrNotManaged: errNotGroup,
+		ErrGetPC:      errGetPC,
+		ErrGetCreds:   errGetCreds,
+	})
+}
+
+func TestObserveAPIErrors(t *testing.T) {
+	caBundle := connecttest.CABundle(t)
+	pc := &apisv1alpha1cluster.ProviderConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: connecttest.ProviderConfigName},
+		Spec:       connecttest.ProviderConfigSpec(xpv2.CredentialsSourceSecret),
+	}
+	mg := &userv1alpha1cluster.Group{}
+	mg.SetProviderConfigReference(&xpv2.Reference{Name: connecttest.ProviderConfigName})
+	meta.SetExternalName(mg, "QA")
+
+	for reason, status := range connecttest.APIErrors {
+		t.Run(string(reason), func(t *testing.T) {
+			c := apierror.NewHandler(event.NewNopRecorder()).Connector(&connector{
+				kube:  &test.MockClient{MockGet: connecttest.MockGet(pc, connecttest.Secret(caBundle))},
+				usage: resource.LegacyTrackerFn(func(context.Context, resource.LegacyManaged) error { return nil }),
+				newServiceFn: func(types.UID, pcv1alpha1common.ProviderConfigSpec, controllercommon.Credentials) (*cloudian.Client, error) {
+					return connecttest.StatusService(t, status), nil
+				},
+			})
+			ext, err := c.Connect(context.Background(), mg)
+			if err != nil {
+				t.Fatalf("c.Connect(...): %v", err)
+			}
+			_, err = ext.Observe(context.Background(), mg)
+			if want := string(reason) + ": "; err == nil || !strings.HasPrefix(err.Error(), want) || !strings.Contains(err.Error(), errGetGroup) {
+				t.Errorf("ext.Observe(...): want error starting with %q explaining %q, got %v", want, errGetGroup, err)
+			}
+		})
+	}
+}
+
+func TestRecreateAfterDelete(t *testing.T) {
+	srv := cloudiantest.NewFakeServer(t)
+	svc := cloudian.NewClient(srv.URL, "")
+	ctx := context.Background()
+	kube := fake.NewClientBuilder().Build()
+	clock := testingclock.NewFakePassiveClock(epoch)
+	e := &external{clock: testingclock.NewFakePassiveClock(epoch), cloudianService: svc, tombstones: tombstone.NewStore(kube, kube, "crossplane-system", clock), endpoint: srv.URL}
+
+	newGroup := func() *userv1alpha1cluster.Group {
+		cr := &userv1alpha1cluster.Group{ObjectMeta: metav1.ObjectMeta{Name: "QA", CreationTimestamp: metav1.NewTime(clock.Now())}}
+		cr.Spec.ForProvider.GroupID = "QA"
+		cr.Spec.ForProvider.Active = true
+		return cr
+	}
+
+	cr := newGroup()
+	if _, err := e.Create(ctx, cr); err != nil {
+		t.Fatalf("e.Create(...): %v", err)
+	}
+
+	// CI deletes and recreates the Group in quick succession, while Cloudian
+	// has yet to finish deleting the group.
+	for i := range 3 {
+		meta.SetExternalCreateSucceeded(cr, cr.GetCreationTimestamp().Time)
+		if _, err := e.Delete(ctx, cr); err != nil {
+			t.Fatalf("cycle %d: e.Delete(...): %v", i, err)
+		}
+		srv.AddGroup("QA")
+
+		cr = newGroup()
+		o, err := e.Observe(ctx, cr)
+		if err != nil {
+			t.Fatalf("cycle %d: e.Observe(...): %v", i, err)
+		}
+		if o.ResourceExists {
+			t.Fatalf("cycle %d: e.Observe(...): want the half-deleted group not to be adopted", i)
+		}
+		if _, err := e.Create(ctx, cr); err == nil {
+			t.Fatalf("cycle %d: e.Create(...): want an error while the group is still being deleted", i)
+		}
+
+		if err := svc.DeleteGroup(ctx, "QA"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := e.Create(ctx, cr); err != nil {
+			t.Fatalf("cycle %d: e.Create(...): %v", i, err)
+		}
+		if !srv.HasGroup("QA") {
+			t.Fatalf("cycle %d: e.Create(...): want the group recreated", i)
+		}
+	}
+}
+
+func TestDeleteWithoutTombstone(t *testing.T) {
+	srv := cloudiantest.NewFakeServer(t)
+	srv.AddGroup("QA")
+	kube := fake.NewClientBuilder().WithInterceptorFuncs(interceptor.Funcs{
+		Create: func(context.Context, client.WithWatch, client.Object, ...client.CreateOption) error {
+			return errors.New("boom")
+		},
+	}).Build()
+	e := &external{
+		clock:           testingclock.NewFakePassiveClock(epoch),
+		cloudianService: cloudian.NewClient(srv.URL, ""),
+		tombstones:      tombstone.NewStore(kube, kube, "crossplane-system", testingclock.NewFakePassiveClock(epoch)),
+		endpoint:        srv.URL,
+	}
+
+	cr := &userv1alpha1cluster.Group{ObjectMeta: metav1.ObjectMeta{Name: "QA"}}
+	cr.Spec.ForProvider.GroupID = "QA"
+	if _, err := e.Delete(context.Background(), cr); err == nil || !strings.HasPrefix(err.Error(), errRecordTomb) {
+		t.Errorf("e.Delete(...): want error starting with %q, got %v", errRecordTomb, err)
+	}
+	if !srv.HasGroup("QA") {
+		t.Error("e.Delete(...): want the group kept until its tombstone is recorded")
+	}
+}
+
+func TestOwnership(t *testing.T) {
+	srv := cloudiantest.NewFakeServer(t)
+	ctx := context.Background()
+
+	// Two clusters managing the same Cloudian installation, each with a
+	// Group of the same group ID.
+	newExternal := func(clusterID string) *external {
+		kube := fake.NewClientBuilder().Build()
+		clock := testingclock.NewFakePassiveClock(epoch)
+		return &external{
+			clock:           clock,
+			cloudianService: cloudian.NewClient(srv.URL, ""),
+			recorder:        &recorder{},
+			tombstones:      tombstone.NewStore(kube, kube, "crossplane-system", clock),
+			endpoint:        srv.URL,
+			clusterID:       clusterID,
+		}
+	}
+	a, b := newExternal("cluster-a"), newExternal("cluster-b")
+	newGroup := func(name string) *userv1alpha1cluster.Group {
+		cr := &userv1alpha1cluster.Group{ObjectMeta: metav1.ObjectMeta{Name: "QA"}}
+		cr.Spec.ForProvider.GroupID = "QA"
+		cr.Spec.ForProvider.Active = true
+		cr.Spec.ForProvider.GroupName = name
+		return cr
+	}
+
+	// An empty group is adopted by the first cluster updating it.
+	srv.AddGroup("QA")
+	if _, err := a.Update(ctx, newGroup("Quality Assurance")); err != nil {
+		t.Fatalf("a.Update(...) of an empty group: %v", err)
+	}
+	srv.AddUser(cloudiantest.User{GroupID: "QA", UserID: "alice", UserType: string(cloudian.UserTypeStandard), Address2: ownership.Marker("cluster-a")})
+	srv.AddUser(cloudiantest.User{GroupID: "QA", UserID: "unmarked", UserType: string(cloudian.UserTypeStandard)})
+
+	cr := newGroup("Renamed")
+	if _, err := b.Update(ctx, cr); !errors.Is(err, ownership.ErrOwnedByAnotherCluster) {
+		t.Errorf("b.Update(...): want ErrOwnedByAnotherCluster, got %v", err)
+	}
+	if got := cr.GetCondition(ownership.TypeOwned); got.Reason != ownership.ReasonOwnedByAnotherCluster || got.Status != corev1.ConditionFalse {
+		t.Errorf("b.Update(...): want condition %s, got %+v", ownership.ReasonOwnedByAnotherCluster, got)
+	}
+	if _, err := b.Delete(ctx, newGroup("Renamed")); !errors.Is(err, ownership.ErrOwnedByAnotherCluster) {
+		t.Errorf("b.Delete(...): want ErrOwnedByAnotherCluster, got %v", err)
+	}
+	group, err := a.cloudianService.GetGroup(ctx, "QA")
+	if err != nil {
+		t.Fatalf("GetGroup(): want the group owned by another cluster kept, got %v", err)
+	}
+	if group.GroupName != "Quality Assurance" {
+		t.Errorf("b.Update(...): want the name of the group owned by another cluster kept, got %q", group.GroupName)
+	}
+
+	// The cluster owning its users may modify it.
+	if _, err := a.Update(ctx, newGroup("QA team")); err != nil {
+		t.Fatalf("a.Update(...): %v", err)
+	}
+
+	// Once the other cluster shares the group, it may modify it too.
+	srv.AddUser(cloudiantest.User{GroupID: "QA", UserID: "bob", UserType: string(cloudian.UserTypeStandard), Address2: ownership.Marker("cluster-b")})
+	if _, err := b.Update(ctx, newGroup("Renamed")); err != nil {
+		t.Errorf("b.Update(...) of a shared group: %v", err)
+	}
+
+	// A group created by a cluster is its own, whoever owns its users, here
+	// once bob is no longer marked.
+	srv.AddUser(cloudiantest.User{GroupID: "QA", UserID: "bob", UserType: string(cloudian.UserTypeStandard)})
+	created := newGroup("Quality Assurance")
+	meta.SetExternalCreateSucceeded(created, epoch)
+	if _, err := b.Update(ctx, created); err != nil {
+		t.Errorf("b.Update(...) of a group it created: %v", err)
+	}
+
+	// A ProviderConfig not claiming ownership is not checked.
+	c := newExternal("")
+	if _, err := c.Update(ctx, newGroup("Renamed")); err != nil {
+		t.Errorf("c.Update(...) without claiming ownership: %v", err)
+	}
+}
+
+func TestObserveOnly(t *testing.T) {
+	srv := cloudiantest.NewFakeServer(t)
+	svc := cloudian.NewClient(srv.URL, "")
+	ctx := context.Background()
+	existing := cloudian.Group{GroupID: "QA", GroupName: "Quality Assurance", Active: true, S3EndpointsHTTPS: []string{"s3.example.com"}}
+	if _, err := svc.CreateGroup(ctx, existing); err != nil {
+		t.Fatal(err)
+	}
+
+	// The spec differs from the group, which would be updated if managed.
+	cr := &userv1alpha1cluster.Group{ObjectMeta: metav1.ObjectMeta{Name: "qa"}}
+	cr.Spec.ForProvider.GroupID = "QA"
+	cr.Spec.ForProvider.GroupName = "Renamed"
+	cr.SetManagementPolicies(xpv2.ManagementPolicies{xpv2.ManagementActionObserve})
+
+	s := runtime.NewScheme()
+	if err := apiscluster.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	kube := fake.NewClientBuilder().WithScheme(s).WithObjects(cr).WithStatusSubresource(cr).Build()
+	r := managed.NewReconciler(&xpfake.Manager{Client: kube, Scheme: s}, resource.ManagedKind(userv1alpha1cluster.GroupGroupVersionKind),
+		managed.WithManagementPolicies(),
+		managed.WithExternalConnector(managed.ExternalConnectorFn(func(context.Context, resource.Managed) (managed.ExternalClient, error) {
+			return &external{clock: testingclock.NewFakePassiveClock(epoch), cloudianService: svc, tombstones: tombstone.NewStore(kube, kube, "crossplane-system", testingclock.NewFakePassiveClock(epoch)), endpoint: srv.URL}, nil
+		})),
+	)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: "qa"}}
+	setup := len(srv.Mutations())
+
+	// The first reconcile adds the finalizer, which discards the status.
+	for range 2 {
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatalf("r.Reconcile(...): %v", err)
+		}
+	}
+	got := &userv1alpha1cluster.Group{}
+	if err := kube.Get(ctx, req.NamespacedName, got); err != nil {
+		t.Fatal(err)
+	}
+	want := userv1alpha1common.GroupObservation{
+		GroupID:     "QA",
+		GroupName:   "Quality Assurance",
+		Active:      true,
+		S3Endpoints: &userv1alpha1common.S3Endpoints{HTTP: []string{cloudian.AllEndpoints}, HTTPS: []string{"s3.example.com"}, Website: []string{cloudian.AllEndpoints}},
+	}
+	if diff := cmp.Diff(want, got.Status.AtProvider); diff != "" {
+		t.Errorf("r.Reconcile(...): -want observation, +got:\n%s", diff)
+	}
+
+	if err := kube.Delete(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("r.Reconcile(...) of the deleted Group: %v", err)
+	}
+	if !srv.HasGroup("QA") {
+		t.Error("r.Reconcile(...): want the observed group kept when the Group is deleted")
+	}
+	if m := srv.Mutations()[setup:]; len(m) > 0 {
+		t.Errorf("r.Reconcile(...): want no changes to an observed group, got %v", m)
+	}
+}
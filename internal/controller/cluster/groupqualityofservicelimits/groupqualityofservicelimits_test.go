@@ -18,13 +18,33 @@ package groupqualityofservicelimits
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
 	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/crossplane/crossplane-runtime/v2/pkg/event"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/managed"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/test"
+
+	userv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/user/v1alpha1"
+	apisv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/v1alpha1"
+	pcv1alpha1common "github.com/statnett/provider-cloudian/apis/common/providerconfig/v1alpha1"
+	userv1alpha1common "github.com/statnett/provider-cloudian/apis/common/user/v1alpha1"
+	controllercommon "github.com/statnett/provider-cloudian/internal/controller/common"
+	"github.com/statnett/provider-cloudian/internal/controller/common/apierror"
+	"github.com/statnett/provider-cloudian/internal/controller/common/connecttest"
+	qoslimitscommon "github.com/statnett/provider-cloudian/internal/controller/common/qualityofservicelimits"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
 )
 
 // Unlike many Kubernetes projects Crossplane does not use third party testing
@@ -35,7 +55,24 @@ import (
 // https://github.com/golang/go/wiki/TestComments
 // https://github.com/crossplane/crossplane/blob/master/CONTRIBUTING.md#contributing-code
 
+func newLimits(groupID string, annotations map[string]string) *userv1alpha1cluster.GroupQualityOfServiceLimits {
+	cr := &userv1alpha1cluster.GroupQualityOfServiceLimits{
+		ObjectMeta: metav1.ObjectMeta{Name: "limits", Annotations: annotations},
+	}
+	cr.Spec.ForProvider.GroupID = groupID
+	cr.Spec.ForProvider.Hard = &userv1alpha1common.QualityOfServiceLimits{RequestsPerMin: ptr.To[uint32](10)}
+	return cr
+}
+
 func TestObserve(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"qosLimitList":[{"type":"REQUEST_RATE_LH","value":10}]}`))
+	}))
+	defer server.Close()
+
+	deleted := newLimits("0", nil)
+	deleted.SetDeletionTimestamp(ptr.To(metav1.Now()))
+
 	type fields struct {
 	}
 
@@ -55,12 +92,31 @@ func TestObserve(t *testing.T) {
 		args   args
 		want   want
 	}{
-		// TODO: Add test cases.
+		"UnsupportedGroup": {
+			reason: "Limits of a group that does not enforce them should not be applied.",
+			args:   args{ctx: context.Background(), mg: newLimits("0", nil)},
+			want:   want{err: errors.Wrapf(qoslimitscommon.ErrUnsupportedGroup, "group %q", "0")},
+		},
+		"UnsupportedGroupDeleted": {
+			reason: "Deleting limits of a group that does not enforce them should not be blocked.",
+			args:   args{ctx: context.Background(), mg: deleted},
+		},
+		"UnsupportedGroupOverridden": {
+			reason: "Limits of a group that does not enforce them should be observed when overridden by annotation.",
+			args: args{ctx: context.Background(), mg: newLimits("0", map[string]string{
+				qoslimitscommon.AnnotationAllowUnsupportedGroup: "true",
+			})},
+			want: want{o: managed.ExternalObservation{
+				ResourceExists:    true,
+				ResourceUpToDate:  true,
+				ConnectionDetails: managed.ConnectionDetails{},
+			}},
+		},
 	}
 
 	for name, tc := range cases {
 		t.Run(name, func(t *testing.T) {
-			e := external{}
+			e := external{cloudianService: cloudian.NewClient(server.URL, "")}
 			got, err := e.Observe(tc.args.ctx, tc.args.mg)
 			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
 				t.Errorf("\n%s\ne.Observe(...): -want error, +got error:\n%s\n", tc.reason, diff)
@@ -71,3 +127,72 @@ func TestObserve(t *testing.T) {
 		})
 	}
 }
+
+func TestConnect(t *testing.T) {
+	mg := &userv1alpha1cluster.GroupQualityOfServiceLimits{}
+	mg.SetProviderConfigReference(&xpv2.Reference{Name: connecttest.ProviderConfigName})
+	pc := func(spec pcv1alpha1common.ProviderConfigSpec) client.Object {
+		return &apisv1alpha1cluster.ProviderConfig{ObjectMeta: metav1.ObjectMeta{Name: connecttest.ProviderConfigName}, Spec: spec}
+	}
+
+	connecttest.TestConnect(t, connecttest.Connector{
+		New: func(kube client.Client, newService connecttest.NewServiceFn) managed.ExternalConnecter {
+			return &connector{kube: kube, usage: resource.LegacyTrackerFn(func(context.Context, resource.LegacyManaged) error { return nil }), newServiceFn: newService}
+		},
+		Managed:        mg,
+		Other:          &userv1alpha1cluster.Group{},
+		ProviderConfig: pc,
+		Connected: func(ext managed.ExternalClient) bool {
+			e, ok := ext.(*external)
+			return ok && e.cloudianService != nil
+		},
+		ErrNotManaged: errNotGroupQualityOfServiceLimits,
+		ErrGetPC:      errGetPC,
+		ErrGetCreds:   errGetCreds,
+		Cases: func(caBundle []byte) map[string]connecttest.ConnectCase {
+			spec := connecttest.ProviderConfigSpec(xpv2.CredentialsSourceSecret)
+			spec.Regions = map[string]string{"eu": "https://eu.cloudian.example.com:19443"}
+			unknownRegion := mg.DeepCopy()
+			unknownRegion.Spec.ForProvider.Region = "us"
+			return map[string]connecttest.ConnectCase{
+				"UnknownRegion": {
+					Reason: "Connect should return an error if the ProviderConfig has no endpoint for the region",
+					MG:     unknownRegion,
+					Objs:   []client.Object{pc(spec), connecttest.Secret(caBundle)},
+					Want:   connecttest.ConnectWant{Err: errRegion},
+				},
+			}
+		},
+	})
+}
+
+func TestObserveAPIErrors(t *testing.T) {
+	caBundle := connecttest.CABundle(t)
+	pc := &apisv1alpha1cluster.ProviderConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: connecttest.ProviderConfigName},
+		Spec:       connecttest.ProviderConfigSpec(xpv2.CredentialsSourceSecret),
+	}
+	mg := &userv1alpha1cluster.GroupQualityOfServiceLimits{}
+	mg.SetProviderConfigReference(&xpv2.Reference{Name: connecttest.ProviderConfigName})
+	mg.Spec.ForProvider.GroupID = "QA"
+
+	for reason, status := range connecttest.APIErrors {
+		t.Run(string(reason), func(t *testing.T) {
+			c := apierror.NewHandler(event.NewNopRecorder()).Connector(&connector{
+				kube:  &test.MockClient{MockGet: connecttest.MockGet(pc, connecttest.Secret(caBundle))},
+				usage: resource.LegacyTrackerFn(func(context.Context, resource.LegacyManaged) error { return nil }),
+				newServiceFn: func(types.UID, pcv1alpha1common.ProviderConfigSpec, controllercommon.Credentials) (*cloudian.Client, error) {
+					return connecttest.StatusService(t, status), nil
+				},
+			})
+			ext, err := c.Connect(context.Background(), mg)
+			if err != nil {
+				t.Fatalf("c.Connect(...): %v", err)
+			}
+			_, err = ext.Observe(context.Background(), mg)
+			if want := string(reason) + ": "; err == nil || !strings.HasPrefix(err.Error(), want) || !strings.Contains(err.Error(), errGetQOS) {
+				t.Errorf("ext.Observe(...): want error starting with %q explaining %q, got %v", want, errGetQOS, err)
+			}
+		})
+	}
+}
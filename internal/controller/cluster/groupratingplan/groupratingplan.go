@@ -0,0 +1,234 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package groupratingplan
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/controller"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/event"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
+
+	userv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/user/v1alpha1"
+	apisv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/v1alpha1"
+	pcv1alpha1common "github.com/statnett/provider-cloudian/apis/common/providerconfig/v1alpha1"
+	"github.com/statnett/provider-cloudian/internal/clients"
+	controllercommon "github.com/statnett/provider-cloudian/internal/controller/common"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
+)
+
+const (
+	errNotGroupRatingPlan = "managed resource is not a GroupRatingPlan custom resource"
+	errTrackPCUsage       = "cannot track ProviderConfig usage"
+	errGetPC              = "cannot get ProviderConfig"
+	errGetCreds           = "cannot get credentials"
+
+	errNewClient        = "cannot create new Service"
+	errRegion           = "cannot select region"
+	errAssignRatingPlan = "cannot assign rating plan"
+	errGetRatingPlan    = "cannot get rating plan"
+)
+
+// SetupGated registers controller setup with the gate, waiting for the
+// required CRD
+func SetupGated(mgr ctrl.Manager, o controller.Options) error {
+	o.Gate.Register(func() {
+		if err := Setup(mgr, o); err != nil {
+			panic(err)
+		}
+	}, userv1alpha1cluster.GroupRatingPlanGroupVersionKind)
+	return nil
+}
+
+// Setup adds a controller that reconciles GroupRatingPlan managed resources.
+func Setup(mgr ctrl.Manager, o controller.Options) error {
+	return controllercommon.SetupManaged[userv1alpha1cluster.GroupRatingPlan](mgr, o, controllercommon.ManagedKind{
+		GroupVersionKind: userv1alpha1cluster.GroupRatingPlanGroupVersionKind,
+		Timeout:          controllercommon.ReconcileTimeouts.Group,
+		NewConnector: func(event.Recorder) managed.ExternalConnector {
+			return &connector{
+				kube:         mgr.GetClient(),
+				usage:        resource.NewLegacyProviderConfigUsageTracker(mgr.GetClient(), &apisv1alpha1cluster.ProviderConfigUsage{}),
+				newServiceFn: clients.Shared.Get,
+			}
+		},
+		Options: []managed.ReconcilerOption{
+			managed.WithReferenceResolver(managed.NewAPISimpleReferenceResolver(mgr.GetClient())),
+		},
+	})
+}
+
+// A connector is expected to produce an ExternalClient when its Connect method
+// is called.
+type connector struct {
+	kube         client.Client
+	usage        resource.LegacyTracker
+	newServiceFn func(uid types.UID, spec pcv1alpha1common.ProviderConfigSpec, creds controllercommon.Credentials) (*cloudian.Client, error)
+}
+
+// Connect typically produces an ExternalClient by:
+// 1. Tracking that the managed resource is using a ProviderConfig.
+// 2. Getting the managed resource's ProviderConfig.
+// 3. Getting the credentials specified by the ProviderConfig.
+// 4. Using the credentials to form a client.
+func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
+	cr, ok := mg.(*userv1alpha1cluster.GroupRatingPlan)
+	if !ok {
+		return nil, errors.New(errNotGroupRatingPlan)
+	}
+
+	if err := c.usage.Track(ctx, cr); err != nil {
+		return nil, errors.Wrap(err, errTrackPCUsage)
+	}
+
+	pc := &apisv1alpha1cluster.ProviderConfig{}
+	if err := c.kube.Get(ctx, types.NamespacedName{Name: cr.GetProviderConfigReference().Name}, pc); err != nil {
+		return nil, errors.Wrap(err, errGetPC)
+	}
+
+	creds, err := controllercommon.ExtractCredentials(ctx, c.kube, pc.Spec)
+	if err != nil {
+		return nil, errors.Wrap(err, errGetCreds)
+	}
+
+	svc, err := c.newServiceFn(pc.GetUID(), pc.Spec, creds)
+	if err != nil {
+		return nil, errors.Wrap(err, errNewClient)
+	}
+	svc, err = svc.ForRegion(cr.Spec.ForProvider.Region)
+	if err != nil {
+		return nil, errors.Wrap(err, errRegion)
+	}
+
+	return &external{cloudianService: svc}, nil
+}
+
+// An ExternalClient observes, then either creates, updates, or deletes an
+// external resource to ensure it reflects the managed resource's desired state.
+type external struct {
+	// A 'client' used to connect to the external resource API. In practice this
+	// would be something like an AWS SDK client.
+	cloudianService *cloudian.Client
+}
+
+// ratingPlanID returns the rating plan ID, or the default rating plan if unset.
+func ratingPlanID(id string) string {
+	if id == "" {
+		return cloudian.DefaultRatingPlanID
+	}
+	return id
+}
+
+func (c *external) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
+	cr, ok := mg.(*userv1alpha1cluster.GroupRatingPlan)
+	if !ok {
+		return managed.ExternalObservation{}, errors.New(errNotGroupRatingPlan)
+	}
+
+	groupID := cr.Spec.ForProvider.GroupID
+	if groupID == "" {
+		return managed.ExternalObservation{}, nil
+	}
+
+	observed, err := c.cloudianService.GetGroupRatingPlan(ctx, groupID, cr.Spec.ForProvider.Region)
+	if errors.Is(err, cloudian.ErrNotFound) {
+		return managed.ExternalObservation{ResourceExists: false}, nil
+	}
+	if err != nil {
+		return managed.ExternalObservation{}, errors.Wrap(err, errGetRatingPlan)
+	}
+	observed = ratingPlanID(observed)
+
+	cr.Status.AtProvider.RatingPlanID = observed
+	cr.SetConditions(xpv2.Available())
+
+	return managed.ExternalObservation{
+		// A group always has a rating plan. The assignment only ceases to
+		// exist when the group is back on the default rating plan after the
+		// managed resource has been deleted.
+		ResourceExists: !meta.WasDeleted(cr) || observed != cloudian.DefaultRatingPlanID,
+
+		// The default rating plan is up to date when none is specified.
+		ResourceUpToDate: observed == ratingPlanID(cr.Spec.ForProvider.RatingPlanID),
+
+		ConnectionDetails: managed.ConnectionDetails{},
+	}, nil
+}
+
+func (c *external) Create(ctx context.Context, mg resource.Managed) (managed.ExternalCreation, error) {
+	cr, ok := mg.(*userv1alpha1cluster.GroupRatingPlan)
+	if !ok {
+		return managed.ExternalCreation{}, errors.New(errNotGroupRatingPlan)
+	}
+
+	cr.SetConditions(xpv2.Creating())
+
+	if err := c.cloudianService.AssignRatingPlanToGroup(ctx, cr.Spec.ForProvider.GroupID, ratingPlanID(cr.Spec.ForProvider.RatingPlanID), cr.Spec.ForProvider.Region); err != nil {
+		return managed.ExternalCreation{}, errors.Wrap(err, errAssignRatingPlan)
+	}
+
+	return managed.ExternalCreation{
+		// Optionally return any details that may be required to connect to the
+		// external resource. These will be stored as the connection secret.
+		ConnectionDetails: managed.ConnectionDetails{},
+	}, nil
+}
+
+func (c *external) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
+	cr, ok := mg.(*userv1alpha1cluster.GroupRatingPlan)
+	if !ok {
+		return managed.ExternalUpdate{}, errors.New(errNotGroupRatingPlan)
+	}
+
+	if err := c.cloudianService.AssignRatingPlanToGroup(ctx, cr.Spec.ForProvider.GroupID, ratingPlanID(cr.Spec.ForProvider.RatingPlanID), cr.Spec.ForProvider.Region); err != nil {
+		return managed.ExternalUpdate{}, errors.Wrap(err, errAssignRatingPlan)
+	}
+
+	return managed.ExternalUpdate{
+		// Optionally return any details that may be required to connect to the
+		// external resource. These will be stored as the connection secret.
+		ConnectionDetails: managed.ConnectionDetails{},
+	}, nil
+}
+
+// Delete puts the group back on the default rating plan.
+func (c *external) Delete(ctx context.Context, mg resource.Managed) (managed.ExternalDelete, error) {
+	cr, ok := mg.(*userv1alpha1cluster.GroupRatingPlan)
+	if !ok {
+		return managed.ExternalDelete{}, errors.New(errNotGroupRatingPlan)
+	}
+
+	cr.SetConditions(xpv2.Deleting())
+
+	if err := c.cloudianService.AssignRatingPlanToGroup(ctx, cr.Spec.ForProvider.GroupID, cloudian.DefaultRatingPlanID, cr.Spec.ForProvider.Region); err != nil {
+		return managed.ExternalDelete{}, errors.Wrap(err, errAssignRatingPlan)
+	}
+
+	return managed.ExternalDelete{}, nil
+}
+
+func (c *external) Disconnect(ctx context.Context) error {
+	return nil
+}
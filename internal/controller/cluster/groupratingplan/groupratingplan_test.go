@@ -0,0 +1,144 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package groupratingplan
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/test"
+
+	userv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/user/v1alpha1"
+	apisv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/v1alpha1"
+	pcv1alpha1common "github.com/statnett/provider-cloudian/apis/common/providerconfig/v1alpha1"
+	"github.com/statnett/provider-cloudian/internal/controller/common/connecttest"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
+)
+
+func newRatingPlan(groupID, ratingPlanID string, deleted bool) *userv1alpha1cluster.GroupRatingPlan {
+	cr := &userv1alpha1cluster.GroupRatingPlan{ObjectMeta: metav1.ObjectMeta{Name: "plan"}}
+	cr.Spec.ForProvider.GroupID = groupID
+	cr.Spec.ForProvider.RatingPlanID = ratingPlanID
+	if deleted {
+		cr.SetDeletionTimestamp(ptr.To(metav1.Now()))
+	}
+	return cr
+}
+
+func TestObserve(t *testing.T) {
+	// The "gold" group is on the Gold-RP rating plan, the "plain" group on
+	// the default rating plan, and any other group does not exist.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("groupId") {
+		case "gold":
+			_, _ = w.Write([]byte("Gold-RP"))
+		case "plain":
+			_, _ = w.Write([]byte(cloudian.DefaultRatingPlanID))
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	}))
+	defer server.Close()
+
+	type want struct {
+		o   managed.ExternalObservation
+		err error
+	}
+
+	cases := map[string]struct {
+		reason string
+		mg     resource.Managed
+		want   want
+	}{
+		"UpToDate": {
+			reason: "The assigned rating plan should be up to date.",
+			mg:     newRatingPlan("gold", "Gold-RP", false),
+			want:   want{o: managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true, ConnectionDetails: managed.ConnectionDetails{}}},
+		},
+		"Drifted": {
+			reason: "Another rating plan than the assigned one should need an update.",
+			mg:     newRatingPlan("gold", "Silver-RP", false),
+			want:   want{o: managed.ExternalObservation{ResourceExists: true, ConnectionDetails: managed.ConnectionDetails{}}},
+		},
+		"DefaultUnspecified": {
+			reason: "The default rating plan should be up to date when none is specified.",
+			mg:     newRatingPlan("plain", "", false),
+			want:   want{o: managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true, ConnectionDetails: managed.ConnectionDetails{}}},
+		},
+		"DeletedBackOnDefault": {
+			reason: "A deleted assignment should no longer exist once the group is back on the default rating plan.",
+			mg:     newRatingPlan("plain", "Gold-RP", true),
+			want:   want{o: managed.ExternalObservation{ConnectionDetails: managed.ConnectionDetails{}}},
+		},
+		"DeletedNotYetReset": {
+			reason: "A deleted assignment should exist until the group is back on the default rating plan.",
+			mg:     newRatingPlan("gold", "Gold-RP", true),
+			want:   want{o: managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true, ConnectionDetails: managed.ConnectionDetails{}}},
+		},
+		"GroupNotFound": {
+			reason: "The assignment should not exist when the group does not exist.",
+			mg:     newRatingPlan("missing", "Gold-RP", false),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := external{cloudianService: cloudian.NewClient(server.URL, "")}
+			got, err := e.Observe(context.Background(), tc.mg)
+			if diff := cmp.Diff(tc.want.err, err, test.EquateErrors()); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want error, +got error:\n%s\n", tc.reason, diff)
+			}
+			if diff := cmp.Diff(tc.want.o, got); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestConnect(t *testing.T) {
+	mg := &userv1alpha1cluster.GroupRatingPlan{}
+	mg.SetProviderConfigReference(&xpv2.Reference{Name: connecttest.ProviderConfigName})
+	pc := func(spec pcv1alpha1common.ProviderConfigSpec) client.Object {
+		return &apisv1alpha1cluster.ProviderConfig{ObjectMeta: metav1.ObjectMeta{Name: connecttest.ProviderConfigName}, Spec: spec}
+	}
+
+	connecttest.TestConnect(t, connecttest.Connector{
+		New: func(kube client.Client, newService connecttest.NewServiceFn) managed.ExternalConnecter {
+			return &connector{kube: kube, usage: resource.LegacyTrackerFn(func(context.Context, resource.LegacyManaged) error { return nil }), newServiceFn: newService}
+		},
+		Managed:        mg,
+		Other:          &userv1alpha1cluster.Group{},
+		ProviderConfig: pc,
+		Connected: func(ext managed.ExternalClient) bool {
+			e, ok := ext.(*external)
+			return ok && e.cloudianService != nil
+		},
+		ErrNotManaged: errNotGroupRatingPlan,
+		ErrGetPC:      errGetPC,
+		ErrGetCreds:   errGetCreds,
+	})
+}
@@ -0,0 +1,265 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iamuser
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/test"
+
+	userv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/user/v1alpha1"
+	apisv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/v1alpha1"
+	pcv1alpha1common "github.com/statnett/provider-cloudian/apis/common/providerconfig/v1alpha1"
+	controllercommon "github.com/statnett/provider-cloudian/internal/controller/common"
+	"github.com/statnett/provider-cloudian/internal/controller/common/connecttest"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian/cloudiantest"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian/iam"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian/iam/iamtest"
+)
+
+// parentKey is the access key of the parent user, which signs the requests.
+const parentKey = "00112233445566778899"
+
+var parent = cloudian.GroupUserID{GroupID: "QA", UserID: "alice"}
+
+func newIAMUser(name string, deleted bool) *userv1alpha1cluster.IAMUser {
+	cr := &userv1alpha1cluster.IAMUser{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	cr.SetProviderConfigReference(&xpv2.Reference{Name: connecttest.ProviderConfigName})
+	cr.Spec.ForProvider.GroupID = parent.GroupID
+	cr.Spec.ForProvider.UserID = parent.UserID
+	meta.SetExternalName(cr, name)
+	if deleted {
+		cr.SetDeletionTimestamp(ptr.To(metav1.Now()))
+	}
+	return cr
+}
+
+func newExternal(srv *iamtest.FakeServer) *external {
+	creds := iam.Credentials{AccessKeyID: parentKey, SecretKey: cloudian.NewSecret("secret")}
+	return &external{iamService: iam.NewClient(srv.URL, iam.StaticCredentials(creds)), parent: parent}
+}
+
+func TestObserve(t *testing.T) {
+	srv := iamtest.NewFakeServer(t)
+	srv.AddUser(parentKey, iamtest.User{Name: "ci"})
+
+	cases := map[string]struct {
+		reason string
+		e      *external
+		mg     *userv1alpha1cluster.IAMUser
+		want   managed.ExternalObservation
+		status string
+	}{
+		"Exists": {
+			reason: "An existing IAM user should be up to date, with its ID observed.",
+			e:      newExternal(srv),
+			mg:     newIAMUser("ci", false),
+			want:   managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true, ConnectionDetails: managed.ConnectionDetails{}},
+			status: parentKey + ":ci",
+		},
+		"NotFound": {
+			reason: "A missing IAM user should not exist.",
+			e:      newExternal(srv),
+			mg:     newIAMUser("missing", false),
+		},
+		"ParentGone": {
+			reason: "A deleted IAM user whose parent is gone should not exist.",
+			e:      &external{parent: parent},
+			mg:     newIAMUser("ci", true),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := tc.e.Observe(context.Background(), tc.mg)
+			if err != nil {
+				t.Fatalf("\n%s\ne.Observe(...): %v", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want, +got:\n%s\n", tc.reason, diff)
+			}
+			if tc.mg.Status.AtProvider.IAMUserID != tc.status {
+				t.Errorf("\n%s\ne.Observe(...): want IAMUserID %q, got %q", tc.reason, tc.status, tc.mg.Status.AtProvider.IAMUserID)
+			}
+		})
+	}
+}
+
+func TestCreate(t *testing.T) {
+	srv := iamtest.NewFakeServer(t)
+	e := newExternal(srv)
+	cr := newIAMUser("ci", false)
+
+	got, err := e.Create(context.Background(), cr)
+	if err != nil {
+		t.Fatalf("e.Create(...): %v", err)
+	}
+	users := srv.Users(parentKey)
+	if len(users) != 1 || len(users[0].AccessKeys) != 1 {
+		t.Fatalf("e.Create(...): want an IAM user with an access key, got %+v", users)
+	}
+	key := users[0].AccessKeys[0]
+	if cr.Status.AtProvider.AccessKeyID != key {
+		t.Errorf("e.Create(...): want AccessKeyID %q, got %q", key, cr.Status.AtProvider.AccessKeyID)
+	}
+	if got := string(got.ConnectionDetails["secretKey"]); got != "secret-"+key {
+		t.Errorf("e.Create(...): want the secret key in the connection details, got %q", got)
+	}
+
+	if _, err := e.Create(context.Background(), cr); err == nil || !strings.HasPrefix(err.Error(), errCreateIAMUser) {
+		t.Errorf("e.Create(...) of an existing IAM user: want error %q, got %v", errCreateIAMUser, err)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	srv := iamtest.NewFakeServer(t)
+	srv.AddUser(parentKey, iamtest.User{Name: "ci", AccessKeys: []string{"AKIA1"}})
+	e := newExternal(srv)
+
+	for _, name := range []string{"ci", "missing"} {
+		if _, err := e.Delete(context.Background(), newIAMUser(name, true)); err != nil {
+			t.Errorf("e.Delete(%s): %v", name, err)
+		}
+	}
+	if users := srv.Users(parentKey); len(users) > 0 {
+		t.Errorf("e.Delete(...): want no IAM users, got %+v", users)
+	}
+}
+
+func TestConnect(t *testing.T) {
+	iamSrv := iamtest.NewFakeServer(t)
+	admin := cloudiantest.NewFakeServer(t)
+	admin.AddUser(cloudiantest.User{GroupID: parent.GroupID, UserID: parent.UserID})
+	admin.AddCredentials(parent.GroupID, parent.UserID, parentKey, "secret")
+	mg := newIAMUser("ci", false)
+	pc := func(spec pcv1alpha1common.ProviderConfigSpec) client.Object {
+		spec.IAMEndpoint = iamSrv.URL
+		return &apisv1alpha1cluster.ProviderConfig{ObjectMeta: metav1.ObjectMeta{Name: connecttest.ProviderConfigName}, Spec: spec}
+	}
+
+	connecttest.TestConnect(t, connecttest.Connector{
+		New: func(kube client.Client, newService connecttest.NewServiceFn) managed.ExternalConnecter {
+			return &connector{kube: kube, usage: resource.LegacyTrackerFn(func(context.Context, resource.LegacyManaged) error { return nil }), newServiceFn: newService}
+		},
+		NewService: func(types.UID, pcv1alpha1common.ProviderConfigSpec, controllercommon.Credentials) (*cloudian.Client, error) {
+			return cloudian.NewClient(admin.URL, ""), nil
+		},
+		Managed:        mg,
+		Other:          &userv1alpha1cluster.User{},
+		ProviderConfig: pc,
+		Connected: func(ext managed.ExternalClient) bool {
+			e, ok := ext.(*external)
+			return ok && e.iamService != nil
+		},
+		ErrNotManaged: errNotIAMUser,
+		ErrGetPC:      errGetPC,
+		ErrGetCreds:   errGetCreds,
+	})
+}
+
+func TestConnectParent(t *testing.T) {
+	caBundle := connecttest.CABundle(t)
+	iamSrv := iamtest.NewFakeServer(t)
+
+	spec := connecttest.ProviderConfigSpec(xpv2.CredentialsSourceSecret)
+	spec.IAMEndpoint = iamSrv.URL
+	pc := &apisv1alpha1cluster.ProviderConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: connecttest.ProviderConfigName},
+		Spec:       spec,
+	}
+	noIAM := &apisv1alpha1cluster.ProviderConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: connecttest.ProviderConfigName},
+		Spec:       connecttest.ProviderConfigSpec(xpv2.CredentialsSourceSecret),
+	}
+
+	cases := map[string]struct {
+		reason     string
+		mg         resource.Managed
+		objs       []client.Object
+		parentKeys bool
+		want       string
+		parentGone bool
+	}{
+		"NoIAMEndpoint": {
+			reason: "Connect should return an error if the ProviderConfig has no IAM endpoint",
+			mg:     newIAMUser("ci", false),
+			objs:   []client.Object{noIAM, connecttest.Secret(caBundle)},
+			want:   errNewIAMClient,
+		},
+		"NoParentCredentials": {
+			reason: "Connect should return an error if the parent user has no access key",
+			mg:     newIAMUser("ci", false),
+			objs:   []client.Object{pc, connecttest.Secret(caBundle)},
+			want:   errNewIAMClient,
+		},
+		"DeletedWithoutParentCredentials": {
+			reason:     "Connect should let a deleted IAMUser go when the parent user has no access key",
+			mg:         newIAMUser("ci", true),
+			objs:       []client.Object{pc, connecttest.Secret(caBundle)},
+			parentGone: true,
+		},
+		"Success": {
+			reason:     "Connect should build an IAM client signing with the access key of the parent user",
+			mg:         newIAMUser("ci", false),
+			objs:       []client.Object{pc, connecttest.Secret(caBundle)},
+			parentKeys: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			admin := cloudiantest.NewFakeServer(t)
+			admin.AddUser(cloudiantest.User{GroupID: parent.GroupID, UserID: parent.UserID})
+			if tc.parentKeys {
+				admin.AddCredentials(parent.GroupID, parent.UserID, parentKey, "secret")
+			}
+			c := &connector{
+				kube:  &test.MockClient{MockGet: connecttest.MockGet(tc.objs...)},
+				usage: resource.LegacyTrackerFn(func(context.Context, resource.LegacyManaged) error { return nil }),
+				newServiceFn: func(types.UID, pcv1alpha1common.ProviderConfigSpec, controllercommon.Credentials) (*cloudian.Client, error) {
+					return cloudian.NewClient(admin.URL, ""), nil
+				},
+			}
+			ext, err := c.Connect(context.Background(), tc.mg)
+			if tc.want != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tc.want) {
+					t.Fatalf("\n%s\nc.Connect(...): want error %q, got %v", tc.reason, tc.want, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("\n%s\nc.Connect(...): %v", tc.reason, err)
+			}
+			if gone := ext.(*external).iamService == nil; gone != tc.parentGone {
+				t.Errorf("\n%s\nc.Connect(...): want parent gone %t, got %t", tc.reason, tc.parentGone, gone)
+			}
+		})
+	}
+}
@@ -18,13 +18,44 @@ package user
 
 import (
 	"context"
+	"errors"
+	"net/http"
+	"strings"
 	"testing"
+	"time"
 
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
 	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
+	"github.com/crossplane/crossplane-runtime/v2/pkg/event"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/managed"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	xpfake "github.com/crossplane/crossplane-runtime/v2/pkg/resource/fake"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/test"
+
+	apiscluster "github.com/statnett/provider-cloudian/apis/cluster"
+	userv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/user/v1alpha1"
+	apisv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/v1alpha1"
+	pcv1alpha1common "github.com/statnett/provider-cloudian/apis/common/providerconfig/v1alpha1"
+	userv1alpha1common "github.com/statnett/provider-cloudian/apis/common/user/v1alpha1"
+	controllercommon "github.com/statnett/provider-cloudian/internal/controller/common"
+	"github.com/statnett/provider-cloudian/internal/controller/common/apierror"
+	"github.com/statnett/provider-cloudian/internal/controller/common/connecttest"
+	"github.com/statnett/provider-cloudian/internal/controller/common/degraded"
+	"github.com/statnett/provider-cloudian/internal/controller/common/identity"
+	"github.com/statnett/provider-cloudian/internal/controller/common/ownership"
+	usercontrollercommon "github.com/statnett/provider-cloudian/internal/controller/common/user"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian/cloudiantest"
 )
 
 // Unlike many Kubernetes projects Crossplane does not use third party testing
@@ -71,3 +102,740 @@ func TestObserve(t *testing.T) {
 		})
 	}
 }
+
+// newKube returns a fake client holding the supplied Users, indexed like the
+// controller indexes them.
+func newKube(t *testing.T, users ...client.Object) client.Client {
+	t.Helper()
+	s := runtime.NewScheme()
+	if err := apiscluster.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	return fake.NewClientBuilder().
+		WithScheme(s).
+		WithObjects(users...).
+		WithIndex(&userv1alpha1cluster.User{}, identity.IndexField, externalIdentity).
+		Build()
+}
+
+func newUser(name string) *userv1alpha1cluster.User {
+	cr := &userv1alpha1cluster.User{}
+	cr.SetName(name)
+	meta.SetExternalName(cr, name)
+	cr.Spec.ForProvider.GroupID = "group"
+	return cr
+}
+
+func TestUsersOfGroup(t *testing.T) {
+	group := &userv1alpha1cluster.Group{ObjectMeta: metav1.ObjectMeta{Name: "qa", Labels: map[string]string{"team": "a"}}}
+	meta.SetExternalName(group, "QA")
+
+	member := newUser("member")
+	member.Spec.ForProvider.GroupID = "QA"
+	byRef := newUser("by-ref")
+	byRef.Spec.ForProvider.GroupID = ""
+	byRef.Spec.ForProvider.GroupIDRef = &xpv2.Reference{Name: "qa"}
+	bySelector := newUser("by-selector")
+	bySelector.Spec.ForProvider.GroupID = ""
+	bySelector.Spec.ForProvider.GroupIDSelector = &xpv2.Selector{MatchLabels: map[string]string{"team": "a"}}
+	// A resolved reference to another group takes precedence over a
+	// selector matching this one.
+	otherRef := newUser("other-ref")
+	otherRef.Spec.ForProvider.GroupID = "Ops"
+	otherRef.Spec.ForProvider.GroupIDRef = &xpv2.Reference{Name: "ops"}
+	otherRef.Spec.ForProvider.GroupIDSelector = &xpv2.Selector{MatchLabels: map[string]string{"team": "a"}}
+	other := newUser("other")
+
+	kube := newKube(t, member, byRef, bySelector, otherRef, other)
+	got := usersOfGroup(kube)(context.Background(), group)
+	want := []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Name: "by-ref"}},
+		{NamespacedName: types.NamespacedName{Name: "by-selector"}},
+		{NamespacedName: types.NamespacedName{Name: "member"}},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("usersOfGroup(...): -want requests, +got requests:\n%s", diff)
+	}
+
+	if got := usersOfGroup(kube)(context.Background(), member); got != nil {
+		t.Errorf("usersOfGroup(...): want no requests for a User, got %v", got)
+	}
+}
+
+func TestCreateInMissingGroup(t *testing.T) {
+	srv := cloudiantest.NewFakeServer(t)
+	e := external{cloudianService: cloudian.NewClient(srv.URL, ""), kube: newKube(t)}
+	srv.Script("/user", cloudiantest.Respond(http.StatusBadRequest, "Group does not exist"))
+
+	_, err := e.Create(context.Background(), newUser("alice"))
+	if !errors.Is(err, cloudian.ErrGroupNotFound) {
+		t.Fatalf("e.Create(...): want cloudian.ErrGroupNotFound, got %v", err)
+	}
+	if got, ok := apierror.Reason(err); !ok || got != apierror.ReasonGroupNotFound {
+		t.Errorf("apierror.Reason(...): want %q, got %q", apierror.ReasonGroupNotFound, got)
+	}
+}
+
+func TestOwnership(t *testing.T) {
+	srv := cloudiantest.NewFakeServer(t)
+	srv.AddUser(cloudiantest.User{GroupID: "group", UserID: "unmarked", UserType: string(cloudian.UserTypeStandard)})
+
+	// Two clusters managing the same Cloudian installation.
+	a := &external{cloudianService: cloudian.NewClient(srv.URL, ""), clusterID: "cluster-a", kube: newKube(t)}
+	b := &external{cloudianService: cloudian.NewClient(srv.URL, ""), clusterID: "cluster-b", kube: newKube(t)}
+	ctx := context.Background()
+
+	alice := newUser("alice")
+	if _, err := a.Create(ctx, alice); err != nil {
+		t.Fatalf("a.Create(...): %v", err)
+	}
+	created, _ := srv.User("group", "alice")
+	if got := alice.Status.AtProvider.CanonicalID; got != created.CanonicalID {
+		t.Errorf("a.Create(...): want canonical ID %q in status, got %q", created.CanonicalID, got)
+	}
+	if got := created.Address2; got != ownership.Marker("cluster-a") {
+		t.Errorf("a.Create(...): want marker %q, got %q", ownership.Marker("cluster-a"), got)
+	}
+
+	o, err := a.Observe(ctx, newUser("alice"))
+	if err != nil {
+		t.Fatalf("a.Observe(...): %v", err)
+	}
+	if !o.ResourceExists || !o.ResourceUpToDate {
+		t.Errorf("a.Observe(...): want existing and up to date user, got %+v", o)
+	}
+
+	cr := newUser("alice")
+	if _, err := b.Observe(ctx, cr); !errors.Is(err, ownership.ErrOwnedByAnotherCluster) {
+		t.Errorf("b.Observe(...): want ErrOwnedByAnotherCluster, got %v", err)
+	}
+	if got := cr.GetCondition(ownership.TypeOwned); got.Reason != ownership.ReasonOwnedByAnotherCluster || got.Status != corev1.ConditionFalse {
+		t.Errorf("b.Observe(...): want condition %s, got %+v", ownership.ReasonOwnedByAnotherCluster, got)
+	}
+	if _, err := b.Update(ctx, newUser("alice")); !errors.Is(err, ownership.ErrOwnedByAnotherCluster) {
+		t.Errorf("b.Update(...): want ErrOwnedByAnotherCluster, got %v", err)
+	}
+	if _, err := b.Delete(ctx, newUser("alice")); !errors.Is(err, ownership.ErrOwnedByAnotherCluster) {
+		t.Errorf("b.Delete(...): want ErrOwnedByAnotherCluster, got %v", err)
+	}
+	if _, ok := srv.User("group", "alice"); !ok {
+		t.Error("b.Delete(...): user owned by another cluster was deleted")
+	}
+
+	// An unmarked user is adopted by the first cluster updating it.
+	o, err = b.Observe(ctx, newUser("unmarked"))
+	if err != nil {
+		t.Fatalf("b.Observe(...): %v", err)
+	}
+	if o.ResourceUpToDate {
+		t.Error("b.Observe(...): want unmarked user to need an update")
+	}
+	if _, err := b.Update(ctx, newUser("unmarked")); err != nil {
+		t.Fatalf("b.Update(...): %v", err)
+	}
+	if _, err := a.Observe(ctx, newUser("unmarked")); !errors.Is(err, ownership.ErrOwnedByAnotherCluster) {
+		t.Errorf("a.Observe(...): want ErrOwnedByAnotherCluster after adoption by b, got %v", err)
+	}
+
+	if _, err := a.Delete(ctx, newUser("alice")); err != nil {
+		t.Fatalf("a.Delete(...): %v", err)
+	}
+	if _, ok := srv.User("group", "alice"); ok {
+		t.Error("a.Delete(...): user was not deleted")
+	}
+}
+
+func TestStatus(t *testing.T) {
+	srv := cloudiantest.NewFakeServer(t)
+	srv.AddUser(cloudiantest.User{GroupID: "group", UserID: "alice", Status: string(cloudian.UserStatusActive)})
+	srv.AddUser(cloudiantest.User{GroupID: "group", UserID: "bob", Status: "Suspended"})
+
+	e := &external{cloudianService: cloudian.NewClient(srv.URL, ""), kube: newKube(t)}
+	ctx := context.Background()
+
+	cr := newUser("alice")
+	cr.Spec.ForProvider.Status = ptr.To(string(cloudian.UserStatusInactive))
+	o, err := e.Observe(ctx, cr)
+	if err != nil {
+		t.Fatalf("e.Observe(...): %v", err)
+	}
+	if o.ResourceUpToDate {
+		t.Error("e.Observe(...): want active user to need an update to become inactive")
+	}
+	if cr.Status.AtProvider.Status != string(cloudian.UserStatusActive) {
+		t.Errorf("e.Observe(...): want observed status %q, got %q", cloudian.UserStatusActive, cr.Status.AtProvider.Status)
+	}
+
+	if _, err := e.Update(ctx, cr); err != nil {
+		t.Fatalf("e.Update(...): %v", err)
+	}
+	if got, _ := srv.User("group", "alice"); got.Status != string(cloudian.UserStatusInactive) {
+		t.Errorf("e.Update(...): want status %q, got %q", cloudian.UserStatusInactive, got.Status)
+	}
+	if o, err := e.Observe(ctx, cr); err != nil || !o.ResourceUpToDate {
+		t.Errorf("e.Observe(...): want up to date user after update, got %+v, %v", o, err)
+	}
+
+	// A status unknown to the provider is surfaced verbatim.
+	cr = newUser("bob")
+	if _, err := e.Observe(ctx, cr); err != nil {
+		t.Fatalf("e.Observe(...): %v", err)
+	}
+	if cr.Status.AtProvider.Status != "Suspended" {
+		t.Errorf("e.Observe(...): want observed status %q, got %q", "Suspended", cr.Status.AtProvider.Status)
+	}
+}
+
+func TestBuckets(t *testing.T) {
+	srv := cloudiantest.NewFakeServer(t)
+	srv.AddUser(cloudiantest.User{GroupID: "group", UserID: "alice"})
+	srv.AddBucket("group", "alice", cloudiantest.Bucket{Name: "reports", CreateTime: "2024-05-03T10:00:00Z", ObjectCount: 3, ByteCount: 2048})
+
+	cases := map[string]struct {
+		reason         string
+		observeBuckets bool
+		want           []userv1alpha1common.Bucket
+	}{
+		"Enabled": {
+			reason:         "The buckets of a user should be observed when enabled.",
+			observeBuckets: true,
+			want: []userv1alpha1common.Bucket{{
+				Name:         "reports",
+				CreationDate: &metav1.Time{Time: time.Date(2024, 5, 3, 10, 0, 0, 0, time.UTC)},
+				ObjectCount:  3,
+				Bytes:        2048,
+			}},
+		},
+		"Disabled": {
+			reason: "The buckets of a user should not be listed unless enabled.",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			e := &external{cloudianService: cloudian.NewClient(srv.URL, ""), kube: newKube(t), observeBuckets: tc.observeBuckets}
+			cr := newUser("alice")
+			if _, err := e.Observe(context.Background(), cr); err != nil {
+				t.Fatalf("\n%s\ne.Observe(...): %v", tc.reason, err)
+			}
+			if diff := cmp.Diff(tc.want, cr.Status.AtProvider.Buckets); diff != "" {
+				t.Errorf("\n%s\ne.Observe(...): -want buckets, +got buckets:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestObserveDegraded(t *testing.T) {
+	srv := cloudiantest.NewFakeServer(t)
+	srv.AddUser(cloudiantest.User{GroupID: "group", UserID: "alice", UserType: string(cloudian.UserTypeStandard)})
+	srv.AddCredentials("group", "alice", "00AABBCC", "secret")
+	srv.Script("/user/credentials/list", cloudiantest.Respond(http.StatusServiceUnavailable, ""))
+	srv.Script("/system/bucketlist", cloudiantest.Respond(http.StatusServiceUnavailable, ""))
+	e := &external{cloudianService: cloudian.NewClient(srv.URL, ""), kube: newKube(t), recorder: &recorder{}, checkAccessKeys: true, observeBuckets: true}
+
+	cr := newUser("alice")
+	cr.Status.AtProvider.AccessKeyIDs = []string{"00AABBCC"}
+	cr.Status.AtProvider.Buckets = []userv1alpha1common.Bucket{{Name: "reports"}}
+	got, err := e.Observe(context.Background(), cr)
+	if err != nil {
+		t.Fatalf("e.Observe(...): want the user observed despite failing to list its access keys and buckets, got %v", err)
+	}
+	if !got.ResourceExists {
+		t.Error("e.Observe(...): want the user to exist")
+	}
+	c := cr.GetCondition(degraded.TypeDegradedObservation)
+	if c.Status != corev1.ConditionTrue || !strings.Contains(c.Message, "ListUserCredentials") || !strings.Contains(c.Message, "ListUserBuckets") {
+		t.Errorf("e.Observe(...): want a degraded observation naming ListUserCredentials and ListUserBuckets, got %+v", c)
+	}
+	if len(cr.Status.AtProvider.AccessKeyIDs) != 1 || len(cr.Status.AtProvider.Buckets) != 1 {
+		t.Errorf("e.Observe(...): want the access keys and buckets kept as last observed, got %v and %v", cr.Status.AtProvider.AccessKeyIDs, cr.Status.AtProvider.Buckets)
+	}
+
+	if _, err := e.Observe(context.Background(), cr); err != nil {
+		t.Fatalf("e.Observe(...): %v", err)
+	}
+	if c := cr.GetCondition(degraded.TypeDegradedObservation); c.Status != corev1.ConditionFalse || c.Reason != degraded.ReasonObservationComplete {
+		t.Errorf("e.Observe(...): want the observation complete once the admin API recovers, got %+v", c)
+	}
+	if len(cr.Status.AtProvider.Buckets) != 0 {
+		t.Errorf("e.Observe(...): want the buckets observed once the admin API recovers, got %v", cr.Status.AtProvider.Buckets)
+	}
+}
+
+func TestUserType(t *testing.T) {
+	srv := cloudiantest.NewFakeServer(t)
+	srv.AddUser(cloudiantest.User{GroupID: "group", UserID: "bob", UserType: string(cloudian.UserTypeStandard)})
+
+	e := &external{cloudianService: cloudian.NewClient(srv.URL, ""), kube: newKube(t)}
+	ctx := context.Background()
+
+	cr := newUser("alice")
+	cr.Spec.ForProvider.UserType = ptr.To(string(cloudian.UserTypeGroupAdmin))
+	if _, err := e.Create(ctx, cr); err != nil {
+		t.Fatalf("e.Create(...): %v", err)
+	}
+	if got, _ := srv.User("group", "alice"); got.UserType != string(cloudian.UserTypeGroupAdmin) {
+		t.Errorf("e.Create(...): want user type %q, got %q", cloudian.UserTypeGroupAdmin, got.UserType)
+	}
+	if o, err := e.Observe(ctx, cr); err != nil || !o.ResourceUpToDate {
+		t.Errorf("e.Observe(...): want up to date group admin, got %+v, %v", o, err)
+	}
+	if got := cr.Status.AtProvider.UserType; got != string(cloudian.UserTypeGroupAdmin) {
+		t.Errorf("e.Observe(...): want observed user type %q, got %q", cloudian.UserTypeGroupAdmin, got)
+	}
+
+	// Cloudian cannot change the type of a user.
+	cr = newUser("bob")
+	cr.Spec.ForProvider.UserType = ptr.To(string(cloudian.UserTypeGroupAdmin))
+	o, err := e.Observe(ctx, cr)
+	if err != nil {
+		t.Fatalf("e.Observe(...): %v", err)
+	}
+	if o.ResourceUpToDate {
+		t.Error("e.Observe(...): want user of another type to not be up to date")
+	}
+	if got := cr.GetCondition(usercontrollercommon.TypeUserType); got.Reason != usercontrollercommon.ReasonUserTypeMismatch || got.Status != corev1.ConditionFalse {
+		t.Errorf("e.Observe(...): want condition %s, got %+v", usercontrollercommon.ReasonUserTypeMismatch, got)
+	}
+	if _, err := e.Update(ctx, cr); err == nil || !strings.Contains(err.Error(), "deleted and recreated") {
+		t.Errorf("e.Update(...): want error explaining the user must be recreated, got %v", err)
+	}
+	if got, _ := srv.User("group", "bob"); got.UserType != string(cloudian.UserTypeStandard) {
+		t.Errorf("e.Update(...): want user type left as %q, got %q", cloudian.UserTypeStandard, got.UserType)
+	}
+
+	cr.Spec.ForProvider.UserType = nil
+	if o, err := e.Observe(ctx, cr); err != nil || !o.ResourceUpToDate {
+		t.Errorf("e.Observe(...): want up to date user once the types match, got %+v, %v", o, err)
+	}
+	if got := cr.GetCondition(usercontrollercommon.TypeUserType); got.Reason != usercontrollercommon.ReasonUserTypeMatch {
+		t.Errorf("e.Observe(...): want condition %s, got %+v", usercontrollercommon.ReasonUserTypeMatch, got)
+	}
+
+	cr = newUser("carol")
+	cr.Spec.ForProvider.UserType = ptr.To(string(cloudian.UserTypeSystemAdmin))
+	if _, err := e.Create(ctx, cr); err == nil {
+		t.Error("e.Create(...): want system admins to be refused")
+	}
+	if _, ok := srv.User("group", "carol"); ok {
+		t.Error("e.Create(...): system admin was created")
+	}
+}
+
+func TestConnectionDetails(t *testing.T) {
+	srv := cloudiantest.NewFakeServer(t)
+
+	e := &external{cloudianService: cloudian.NewClient(srv.URL, ""), kube: newKube(t)}
+	ctx := context.Background()
+
+	cr := newUser("alice")
+	c, err := e.Create(ctx, cr)
+	if err != nil {
+		t.Fatalf("e.Create(...): %v", err)
+	}
+	created, _ := srv.User("group", "alice")
+	want := managed.ConnectionDetails{
+		"canonicalId": []byte(created.CanonicalID),
+		"groupId":     []byte("group"),
+		"userId":      []byte("alice"),
+	}
+	if diff := cmp.Diff(want, c.ConnectionDetails); diff != "" {
+		t.Errorf("e.Create(...): -want connection details, +got:\n%s", diff)
+	}
+
+	// Cloudian assigns a new canonical ID when a user is deleted and recreated.
+	created.CanonicalID = "canonical-alice-2"
+	srv.AddUser(created)
+
+	o, err := e.Observe(ctx, cr)
+	if err != nil {
+		t.Fatalf("e.Observe(...): %v", err)
+	}
+	want["canonicalId"] = []byte("canonical-alice-2")
+	if diff := cmp.Diff(want, o.ConnectionDetails); diff != "" {
+		t.Errorf("e.Observe(...): -want connection details, +got:\n%s", diff)
+	}
+}
+
+func TestDuplicateExternalIdentity(t *testing.T) {
+	srv := cloudiantest.NewFakeServer(t)
+	srv.AddUser(cloudiantest.User{GroupID: "group", UserID: "alice"})
+
+	now := time.Now()
+	first := newUser("first")
+	first.SetCreationTimestamp(metav1.NewTime(now.Add(-time.Hour)))
+	first.SetUID("first")
+	meta.SetExternalName(first, "alice")
+	second := newUser("second")
+	second.SetCreationTimestamp(metav1.NewTime(now))
+	second.SetUID("second")
+	meta.SetExternalName(second, "alice")
+
+	kube := newKube(t, first.DeepCopy(), second.DeepCopy())
+	e := &external{cloudianService: cloudian.NewClient(srv.URL, ""), kube: kube}
+	ctx := context.Background()
+
+	if _, err := e.Observe(ctx, first.DeepCopy()); err != nil {
+		t.Errorf("e.Observe(first): %v", err)
+	}
+
+	cr := second.DeepCopy()
+	if _, err := e.Observe(ctx, cr); err == nil {
+		t.Error("e.Observe(second): want error for duplicate external identity")
+	}
+	if got := cr.GetCondition(identity.TypeUnique); got.Reason != identity.ReasonDuplicateExternalIdentity || !strings.Contains(got.Message, "first") {
+		t.Errorf("e.Observe(second): want condition %s naming the first User, got %+v", identity.ReasonDuplicateExternalIdentity, got)
+	}
+
+	// Deleting the duplicate must not delete the Cloudian user of the first.
+	deleting := cr.DeepCopy()
+	deleting.SetDeletionTimestamp(&metav1.Time{Time: now})
+	if o, err := e.Observe(ctx, deleting); err != nil || o.ResourceExists {
+		t.Errorf("e.Observe(deleted second): want non-existing user, got %+v, %v", o, err)
+	}
+
+	// Once the first User is gone, the second takes over.
+	if err := kube.Delete(ctx, first.DeepCopy()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.Observe(ctx, cr); err != nil {
+		t.Errorf("e.Observe(second) after first was deleted: %v", err)
+	}
+	if got := cr.GetCondition(identity.TypeUnique); got.Reason != identity.ReasonUniqueExternalIdentity {
+		t.Errorf("e.Observe(second) after first was deleted: want condition %s, got %+v", identity.ReasonUniqueExternalIdentity, got)
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	srv := cloudiantest.NewFakeServer(t)
+	e := &external{cloudianService: cloudian.NewClient(srv.URL, ""), kube: newKube(t)}
+	ctx := context.Background()
+
+	cr := newUser("alice")
+	if o, err := e.Observe(ctx, cr); err != nil || o.ResourceExists {
+		t.Fatalf("e.Observe(...): want non-existing user, got %+v, %v", o, err)
+	}
+
+	if _, err := e.Create(ctx, cr); err != nil {
+		t.Fatalf("e.Create(...): %v", err)
+	}
+	if got := srv.AccessKeys("group", "alice"); len(got) != 0 {
+		t.Errorf("e.Create(...): want the access key Cloudian creates with the user deleted, got %v", got)
+	}
+	if o, err := e.Observe(ctx, cr); err != nil || !o.ResourceExists || !o.ResourceUpToDate {
+		t.Errorf("e.Observe(...): want existing and up to date user, got %+v, %v", o, err)
+	}
+
+	srv.FailNext(1)
+	if _, err := e.Observe(ctx, cr); err == nil || !strings.Contains(err.Error(), errGetUser) {
+		t.Errorf("e.Observe(...): want error explaining %q when the admin API fails, got %v", errGetUser, err)
+	}
+
+	srv.AddCredentials("group", "alice", "00112233445566778899", "secret")
+	if _, err := e.Delete(ctx, cr); err == nil {
+		t.Error("e.Delete(...): want user with access keys to be kept")
+	}
+	if _, ok := srv.User("group", "alice"); !ok {
+		t.Error("e.Delete(...): user with access keys was deleted")
+	}
+
+	if err := e.cloudianService.DeleteUserCredentials(ctx, "00112233445566778899"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.Delete(ctx, cr); err != nil {
+		t.Fatalf("e.Delete(...): %v", err)
+	}
+	if o, err := e.Observe(ctx, cr); err != nil || o.ResourceExists {
+		t.Errorf("e.Observe(...): want deleted user, got %+v, %v", o, err)
+	}
+	// Deleting a user that is already gone succeeds.
+	if _, err := e.Delete(ctx, cr); err != nil {
+		t.Errorf("e.Delete(...) of a deleted user: %v", err)
+	}
+}
+
+func TestCreateInitialAccessKey(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		retain bool
+	}{
+		"Deleted": {
+			reason: "The access key Cloudian creates with a user should be deleted by default.",
+		},
+		"Retained": {
+			reason: "The access key Cloudian creates with a user should be kept and published when retained.",
+			retain: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			srv := cloudiantest.NewFakeServer(t)
+			e := &external{cloudianService: cloudian.NewClient(srv.URL, ""), kube: newKube(t)}
+			cr := newUser("alice")
+			cr.Spec.ForProvider.RetainInitialAccessKey = tc.retain
+
+			got, err := e.Create(context.Background(), cr)
+			if err != nil {
+				t.Fatalf("\n%s\ne.Create(...): %v", tc.reason, err)
+			}
+			keys := srv.AccessKeys("group", "alice")
+			if tc.retain != (len(keys) == 1) {
+				t.Fatalf("\n%s\ne.Create(...): want the access key retained %t, got access keys %v", tc.reason, tc.retain, keys)
+			}
+			if !tc.retain {
+				if _, ok := got.ConnectionDetails["secretAccessKey"]; ok {
+					t.Errorf("\n%s\ne.Create(...): want no secret of a deleted access key published", tc.reason)
+				}
+				return
+			}
+			creds, err := e.cloudianService.GetUserCredentials(context.Background(), keys[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(got.ConnectionDetails["accessKeyId"]); got != keys[0] {
+				t.Errorf("\n%s\ne.Create(...): want access key ID %q published, got %q", tc.reason, keys[0], got)
+			}
+			if got := string(got.ConnectionDetails["secretAccessKey"]); got != creds.SecretKey.Value() {
+				t.Errorf("\n%s\ne.Create(...): want the secret of the retained access key published", tc.reason)
+			}
+			if got := string(got.ConnectionDetails["userId"]); got != "alice" {
+				t.Errorf("\n%s\ne.Create(...): want the user published along with its access key, got user %q", tc.reason, got)
+			}
+		})
+	}
+}
+
+func TestDeleteKeysOnDelete(t *testing.T) {
+	srv := cloudiantest.NewFakeServer(t)
+	srv.AddUser(cloudiantest.User{GroupID: "group", UserID: "alice", UserType: string(cloudian.UserTypeStandard)})
+	for _, key := range []string{"00000000000000000001", "00000000000000000002", "00000000000000000003"} {
+		srv.AddCredentials("group", "alice", key, "secret")
+	}
+	e := &external{cloudianService: cloudian.NewClient(srv.URL, ""), kube: newKube(t)}
+	ctx := context.Background()
+
+	cr := newUser("alice")
+	cr.Spec.ForProvider.DeleteKeysOnDelete = true
+
+	// One access key fails to be deleted, so the user is kept for a retry.
+	srv.Script("/user/credentials", cloudiantest.Respond(http.StatusInternalServerError, "").Times(1))
+	if _, err := e.Delete(ctx, cr); err == nil {
+		t.Error("e.Delete(...): want an error when an access key cannot be deleted")
+	}
+	if got := srv.AccessKeys("group", "alice"); len(got) != 1 {
+		t.Errorf("e.Delete(...): want the other access keys deleted, got %v", got)
+	}
+	if _, ok := srv.User("group", "alice"); !ok {
+		t.Fatal("e.Delete(...): user with an access key left was deleted")
+	}
+
+	if _, err := e.Delete(ctx, cr); err != nil {
+		t.Fatalf("e.Delete(...): %v", err)
+	}
+	if _, ok := srv.User("group", "alice"); ok {
+		t.Error("e.Delete(...): user was not deleted along with its access keys")
+	}
+}
+
+// recorder records the events of the resources it is given.
+type recorder []event.Event
+
+func (r *recorder) Event(_ runtime.Object, e event.Event) { *r = append(*r, e) }
+
+func (r *recorder) WithAnnotations(...string) event.Recorder { return r }
+
+func TestDeleteCredentialCheckForbidden(t *testing.T) {
+	cases := map[string]struct {
+		reason      string
+		strict      bool
+		wantErr     bool
+		wantDeleted bool
+		wantEvents  int
+	}{
+		"Permissive": {
+			reason:      "The user should be deleted with a warning when its access keys may not be listed.",
+			wantDeleted: true,
+			wantEvents:  1,
+		},
+		"Strict": {
+			reason:  "The user should be kept when its access keys may not be listed and the check is strict.",
+			strict:  true,
+			wantErr: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			srv := cloudiantest.NewFakeServer(t)
+			srv.AddUser(cloudiantest.User{GroupID: "group", UserID: "alice", UserType: string(cloudian.UserTypeStandard)})
+			srv.Script("/user/credentials/list", cloudiantest.Respond(http.StatusForbidden, ""))
+			events := &recorder{}
+			e := &external{cloudianService: cloudian.NewClient(srv.URL, ""), strictCredentialCheck: tc.strict, kube: newKube(t), recorder: events}
+
+			_, err := e.Delete(context.Background(), newUser("alice"))
+			if (err != nil) != tc.wantErr {
+				t.Errorf("\n%s\ne.Delete(...): want error %t, got %v", tc.reason, tc.wantErr, err)
+			}
+			if _, ok := srv.User("group", "alice"); ok == tc.wantDeleted {
+				t.Errorf("\n%s\ne.Delete(...): want deleted %t", tc.reason, tc.wantDeleted)
+			}
+			if len(*events) != tc.wantEvents {
+				t.Fatalf("\n%s\ne.Delete(...): want %d events, got %v", tc.reason, tc.wantEvents, *events)
+			}
+			if tc.wantEvents > 0 && (*events)[0].Reason != usercontrollercommon.ReasonCredentialCheckSkipped {
+				t.Errorf("\n%s\ne.Delete(...): want a %s event, got %v", tc.reason, usercontrollercommon.ReasonCredentialCheckSkipped, (*events)[0])
+			}
+		})
+	}
+}
+
+func TestCreateLicenseExceeded(t *testing.T) {
+	srv := cloudiantest.NewFakeServer(t)
+	srv.Script("/user", cloudiantest.Respond(http.StatusForbidden, "License capacity exceeded"))
+	c := apierror.NewHandler(event.NewNopRecorder()).Connector(managed.ExternalConnectorFn(func(context.Context, resource.Managed) (managed.ExternalClient, error) {
+		return &external{cloudianService: cloudian.NewClient(srv.URL, ""), kube: newKube(t)}, nil
+	}))
+	ext, err := c.Connect(context.Background(), newUser("alice"))
+	if err != nil {
+		t.Fatalf("c.Connect(...): %v", err)
+	}
+
+	_, err = ext.Create(context.Background(), newUser("alice"))
+	if want := string(apierror.ReasonLicenseExceeded) + ": "; err == nil || !strings.HasPrefix(err.Error(), want) || !strings.Contains(err.Error(), errCreateUser) {
+		t.Errorf("ext.Create(...): want error starting with %q explaining %q, got %v", want, errCreateUser, err)
+	}
+}
+
+func TestConnect(t *testing.T) {
+	mg := &userv1alpha1cluster.User{}
+	mg.SetProviderConfigReference(&xpv2.Reference{Name: connecttest.ProviderConfigName})
+	pc := func(spec pcv1alpha1common.ProviderConfigSpec) client.Object {
+		return &apisv1alpha1cluster.ProviderConfig{ObjectMeta: metav1.ObjectMeta{Name: connecttest.ProviderConfigName}, Spec: spec}
+	}
+
+	connecttest.TestConnect(t, connecttest.Connector{
+		New: func(kube client.Client, newService connecttest.NewServiceFn) managed.ExternalConnecter {
+			return &connector{kube: kube, usage: resource.LegacyTrackerFn(func(context.Context, resource.LegacyManaged) error { return nil }), newServiceFn: newService}
+		},
+		Managed:        mg,
+		Other:          &userv1alpha1cluster.Group{},
+		ProviderConfig: pc,
+		Connected: func(ext managed.ExternalClient) bool {
+			e, ok := ext.(*external)
+			return ok && e.cloudianService != nil
+		},
+		ErrNotManaged: errNotUser,
+		ErrGetPC:      errGetPC,
+		ErrGetCreds:   errGetCreds,
+	})
+}
+
+func TestObserveAPIErrors(t *testing.T) {
+	caBundle := connecttest.CABundle(t)
+	pc := &apisv1alpha1cluster.ProviderConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: connecttest.ProviderConfigName},
+		Spec:       connecttest.ProviderConfigSpec(xpv2.CredentialsSourceSecret),
+	}
+	mg := &userv1alpha1cluster.User{}
+	mg.SetProviderConfigReference(&xpv2.Reference{Name: connecttest.ProviderConfigName})
+	mg.Spec.ForProvider.GroupID = "QA"
+	meta.SetExternalName(mg, "alice")
+
+	for reason, status := range connecttest.APIErrors {
+		t.Run(string(reason), func(t *testing.T) {
+			c := apierror.NewHandler(event.NewNopRecorder()).Connector(&connector{
+				kube: &test.MockClient{
+					MockGet:  connecttest.MockGet(pc, connecttest.Secret(caBundle)),
+					MockList: test.NewMockListFn(nil),
+				},
+				usage: resource.LegacyTrackerFn(func(context.Context, resource.LegacyManaged) error { return nil }),
+				newServiceFn: func(types.UID, pcv1alpha1common.ProviderConfigSpec, controllercommon.Credentials) (*cloudian.Client, error) {
+					return connecttest.StatusService(t, status), nil
+				},
+			})
+			ext, err := c.Connect(context.Background(), mg)
+			if err != nil {
+				t.Fatalf("c.Connect(...): %v", err)
+			}
+			_, err = ext.Observe(context.Background(), mg)
+			if want := string(reason) + ": "; err == nil || !strings.HasPrefix(err.Error(), want) || !strings.Contains(err.Error(), errGetUser) {
+				t.Errorf("ext.Observe(...): want error starting with %q explaining %q, got %v", want, errGetUser, err)
+			}
+		})
+	}
+}
+
+func TestObserveOnly(t *testing.T) {
+	srv := cloudiantest.NewFakeServer(t)
+	srv.AddUser(cloudiantest.User{
+		GroupID:     "group",
+		UserID:      "alice",
+		UserType:    string(cloudian.UserTypeStandard),
+		CanonicalID: "0123456789abcdef",
+		Address2:    ownership.Marker("cluster-b"),
+		Status:      string(cloudian.UserStatusActive),
+	})
+	srv.AddCredentials("group", "alice", "99887766554433221100", "secret")
+	srv.AddCredentials("group", "alice", "00112233445566778899", "secret")
+	ctx := context.Background()
+
+	// The user is owned by another cluster, and its status differs from the
+	// spec, so it would be refused or updated if managed.
+	cr := newUser("alice")
+	cr.Spec.ForProvider.Status = ptr.To(string(cloudian.UserStatusInactive))
+	cr.SetManagementPolicies(xpv2.ManagementPolicies{xpv2.ManagementActionObserve})
+
+	s := runtime.NewScheme()
+	if err := apiscluster.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	kube := fake.NewClientBuilder().
+		WithScheme(s).
+		WithObjects(cr).
+		WithStatusSubresource(cr).
+		WithIndex(&userv1alpha1cluster.User{}, identity.IndexField, externalIdentity).
+		Build()
+	e := &external{cloudianService: cloudian.NewClient(srv.URL, ""), clusterID: "cluster-a", kube: kube, recorder: event.NewNopRecorder()}
+	r := managed.NewReconciler(&xpfake.Manager{Client: kube, Scheme: s}, resource.ManagedKind(userv1alpha1cluster.UserGroupVersionKind),
+		managed.WithManagementPolicies(),
+		managed.WithExternalConnector(managed.ExternalConnectorFn(func(context.Context, resource.Managed) (managed.ExternalClient, error) {
+			return e, nil
+		})),
+	)
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: "alice"}}
+	setup := len(srv.Mutations())
+
+	// The first reconcile adds the finalizer, which discards the status.
+	for range 2 {
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatalf("r.Reconcile(...): %v", err)
+		}
+	}
+	got := &userv1alpha1cluster.User{}
+	if err := kube.Get(ctx, req.NamespacedName, got); err != nil {
+		t.Fatal(err)
+	}
+	want := userv1alpha1common.UserObservation{
+		CanonicalID:    "0123456789abcdef",
+		Status:         string(cloudian.UserStatusActive),
+		UserType:       string(cloudian.UserTypeStandard),
+		AccessKeyCount: 2,
+		AccessKeyIDs:   []string{"00112233445566778899", "99887766554433221100"},
+	}
+	if diff := cmp.Diff(want, got.Status.AtProvider); diff != "" {
+		t.Errorf("r.Reconcile(...): -want observation, +got:\n%s", diff)
+	}
+
+	if err := kube.Delete(ctx, got); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("r.Reconcile(...) of the deleted User: %v", err)
+	}
+	if _, ok := srv.User("group", "alice"); !ok {
+		t.Error("r.Reconcile(...): want the observed user kept when the User is deleted")
+	}
+	if m := srv.Mutations()[setup:]; len(m) > 0 {
+		t.Errorf("r.Reconcile(...): want no changes to an observed user, got %v", m)
+	}
+}
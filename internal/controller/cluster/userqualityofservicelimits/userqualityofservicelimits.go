@@ -26,13 +26,15 @@ import (
 
 	"github.com/crossplane/crossplane-runtime/v2/pkg/controller"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/event"
-	"github.com/crossplane/crossplane-runtime/v2/pkg/ratelimiter"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/managed"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
 	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
 
 	userv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/user/v1alpha1"
 	apisv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/v1alpha1"
+	pcv1alpha1common "github.com/statnett/provider-cloudian/apis/common/providerconfig/v1alpha1"
+	"github.com/statnett/provider-cloudian/internal/clients"
 	controllercommon "github.com/statnett/provider-cloudian/internal/controller/common"
 	qoslimitscommon "github.com/statnett/provider-cloudian/internal/controller/common/qualityofservicelimits"
 	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
@@ -45,6 +47,7 @@ const (
 	errGetCreds                      = "cannot get credentials"
 
 	errNewClient = "cannot create new Service"
+	errRegion    = "cannot select region"
 	errCreateQOS = "cannot create QOS"
 	errDeleteQOS = "cannot delete QOS"
 	errGetQOS    = "cannot get QOS"
@@ -63,34 +66,28 @@ func SetupGated(mgr ctrl.Manager, o controller.Options) error {
 
 // Setup adds a controller that reconciles UserQualityOfServiceLimits managed resources.
 func Setup(mgr ctrl.Manager, o controller.Options) error {
-	name := managed.ControllerName(userv1alpha1cluster.UserQualityOfServiceLimitsGroupKind)
-
-	r := managed.NewReconciler(mgr,
-		resource.ManagedKind(userv1alpha1cluster.UserQualityOfServiceLimitsGroupVersionKind),
-		managed.WithExternalConnector(&connector{
-			kube:         mgr.GetClient(),
-			usage:        resource.NewLegacyProviderConfigUsageTracker(mgr.GetClient(), &apisv1alpha1cluster.ProviderConfigUsage{}),
-			newServiceFn: controllercommon.NewCloudianService,
-		}),
-		managed.WithLogger(o.Logger.WithValues("controller", name)),
-		managed.WithPollInterval(o.PollInterval),
-		//nolint:staticcheck // SA1004 crossplane-runtime still depends on deprecated API
-		managed.WithRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor(name))))
-
-	return ctrl.NewControllerManagedBy(mgr).
-		Named(name).
-		WithOptions(o.ForControllerRuntime()).
-		WithEventFilter(resource.DesiredStateChanged()).
-		For(&userv1alpha1cluster.UserQualityOfServiceLimits{}).
-		Complete(ratelimiter.NewReconciler(name, r, o.GlobalRateLimiter))
+	return controllercommon.SetupManaged[userv1alpha1cluster.UserQualityOfServiceLimits](mgr, o, controllercommon.ManagedKind{
+		GroupVersionKind: userv1alpha1cluster.UserQualityOfServiceLimitsGroupVersionKind,
+		Timeout:          controllercommon.ReconcileTimeouts.QualityOfServiceLimits,
+		NewConnector: func(event.Recorder) managed.ExternalConnector {
+			return &connector{
+				kube:         mgr.GetClient(),
+				usage:        resource.NewLegacyProviderConfigUsageTracker(mgr.GetClient(), &apisv1alpha1cluster.ProviderConfigUsage{}),
+				newServiceFn: clients.Shared.Get,
+			}
+		},
+		Options: []managed.ReconcilerOption{
+			managed.WithReferenceResolver(managed.NewAPISimpleReferenceResolver(mgr.GetClient())),
+		},
+	})
 }
 
 // A connector is expected to produce an ExternalClient when its Connect method
 // is called.
 type connector struct {
 	kube         client.Client
-	usage        *resource.LegacyProviderConfigUsageTracker
-	newServiceFn func(providerConfigEndpoint string, authHeader string) (*cloudian.Client, error)
+	usage        resource.LegacyTracker
+	newServiceFn func(uid types.UID, spec pcv1alpha1common.ProviderConfigSpec, creds controllercommon.Credentials) (*cloudian.Client, error)
 }
 
 // Connect typically produces an ExternalClient by:
@@ -113,16 +110,19 @@ func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.E
 		return nil, errors.Wrap(err, errGetPC)
 	}
 
-	cd := pc.Spec.AuthHeader
-	authHeader, err := resource.CommonCredentialExtractor(ctx, cd.Source, c.kube, cd.CommonCredentialSelectors)
+	creds, err := controllercommon.ExtractCredentials(ctx, c.kube, pc.Spec)
 	if err != nil {
 		return nil, errors.Wrap(err, errGetCreds)
 	}
 
-	svc, err := c.newServiceFn(pc.Spec.Endpoint, string(authHeader))
+	svc, err := c.newServiceFn(pc.GetUID(), pc.Spec, creds)
 	if err != nil {
 		return nil, errors.Wrap(err, errNewClient)
 	}
+	svc, err = svc.ForRegion(cr.Spec.ForProvider.Region)
+	if err != nil {
+		return nil, errors.Wrap(err, errRegion)
+	}
 
 	return &external{cloudianService: svc}, nil
 }
@@ -150,6 +150,24 @@ func (c *external) Observe(ctx context.Context, mg resource.Managed) (managed.Ex
 		return managed.ExternalObservation{}, nil
 	}
 
+	if err := qoslimitscommon.CheckSupported(cr, groupID); err != nil {
+		cr.SetConditions(qoslimitscommon.Unsupported(err))
+		if meta.WasDeleted(cr) {
+			// The limits were never applied, so there is nothing to delete.
+			return managed.ExternalObservation{}, nil
+		}
+		return managed.ExternalObservation{}, err
+	}
+
+	// Limits that cannot be sent to Cloudian fail every reconcile until the
+	// spec is fixed, so they are checked before asking Cloudian.
+	expected, err := qoslimitscommon.ToCloudianQOS(cr.Spec.ForProvider.QOS)
+	if err != nil {
+		cr.SetConditions(qoslimitscommon.InvalidLimits(err))
+		return managed.ExternalObservation{}, err
+	}
+	cr.SetConditions(qoslimitscommon.ValidLimits())
+
 	guid := cloudian.GroupUserID{
 		GroupID: groupID,
 		UserID:  userID,
@@ -164,11 +182,7 @@ func (c *external) Observe(ctx context.Context, mg resource.Managed) (managed.Ex
 	}
 
 	cr.SetConditions(xpv2.Available())
-
-	expected, err := qoslimitscommon.ToCloudianQOS(cr.Spec.ForProvider.QOS)
-	if err != nil {
-		return managed.ExternalObservation{}, err
-	}
+	cr.Status.AtProvider.Normalized = qoslimitscommon.Normalize(expected)
 
 	return managed.ExternalObservation{
 		// Return false when the external resource does not exist. This lets
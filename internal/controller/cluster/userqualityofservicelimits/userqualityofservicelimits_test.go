@@ -18,13 +18,30 @@ package userqualityofservicelimits
 
 import (
 	"context"
+	"strings"
 	"testing"
 
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
 	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/crossplane/crossplane-runtime/v2/pkg/event"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/managed"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/test"
+
+	userv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/user/v1alpha1"
+	apisv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/v1alpha1"
+	pcv1alpha1common "github.com/statnett/provider-cloudian/apis/common/providerconfig/v1alpha1"
+	userv1alpha1common "github.com/statnett/provider-cloudian/apis/common/user/v1alpha1"
+	controllercommon "github.com/statnett/provider-cloudian/internal/controller/common"
+	"github.com/statnett/provider-cloudian/internal/controller/common/apierror"
+	"github.com/statnett/provider-cloudian/internal/controller/common/connecttest"
+	qoslimitscommon "github.com/statnett/provider-cloudian/internal/controller/common/qualityofservicelimits"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian/cloudiantest"
 )
 
 // Unlike many Kubernetes projects Crossplane does not use third party testing
@@ -71,3 +88,110 @@ func TestObserve(t *testing.T) {
 		})
 	}
 }
+
+func TestConnect(t *testing.T) {
+	mg := &userv1alpha1cluster.UserQualityOfServiceLimits{}
+	mg.SetProviderConfigReference(&xpv2.Reference{Name: connecttest.ProviderConfigName})
+	pc := func(spec pcv1alpha1common.ProviderConfigSpec) client.Object {
+		return &apisv1alpha1cluster.ProviderConfig{ObjectMeta: metav1.ObjectMeta{Name: connecttest.ProviderConfigName}, Spec: spec}
+	}
+
+	connecttest.TestConnect(t, connecttest.Connector{
+		New: func(kube client.Client, newService connecttest.NewServiceFn) managed.ExternalConnecter {
+			return &connector{kube: kube, usage: resource.LegacyTrackerFn(func(context.Context, resource.LegacyManaged) error { return nil }), newServiceFn: newService}
+		},
+		Managed:        mg,
+		Other:          &userv1alpha1cluster.User{},
+		ProviderConfig: pc,
+		Connected: func(ext managed.ExternalClient) bool {
+			e, ok := ext.(*external)
+			return ok && e.cloudianService != nil
+		},
+		ErrNotManaged: errNotUserQualityOfServiceLimits,
+		ErrGetPC:      errGetPC,
+		ErrGetCreds:   errGetCreds,
+		Cases: func(caBundle []byte) map[string]connecttest.ConnectCase {
+			spec := connecttest.ProviderConfigSpec(xpv2.CredentialsSourceSecret)
+			spec.Regions = map[string]string{"eu": "https://eu.cloudian.example.com:19443"}
+			unknownRegion := mg.DeepCopy()
+			unknownRegion.Spec.ForProvider.Region = "us"
+			return map[string]connecttest.ConnectCase{
+				"UnknownRegion": {
+					Reason: "Connect should return an error if the ProviderConfig has no endpoint for the region",
+					MG:     unknownRegion,
+					Objs:   []client.Object{pc(spec), connecttest.Secret(caBundle)},
+					Want:   connecttest.ConnectWant{Err: errRegion},
+				},
+			}
+		},
+	})
+}
+
+func TestObserveAPIErrors(t *testing.T) {
+	caBundle := connecttest.CABundle(t)
+	pc := &apisv1alpha1cluster.ProviderConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: connecttest.ProviderConfigName},
+		Spec:       connecttest.ProviderConfigSpec(xpv2.CredentialsSourceSecret),
+	}
+	mg := &userv1alpha1cluster.UserQualityOfServiceLimits{}
+	mg.SetProviderConfigReference(&xpv2.Reference{Name: connecttest.ProviderConfigName})
+	mg.Spec.ForProvider.GroupID = "QA"
+	mg.Spec.ForProvider.UserID = "alice"
+
+	for reason, status := range connecttest.APIErrors {
+		t.Run(string(reason), func(t *testing.T) {
+			c := apierror.NewHandler(event.NewNopRecorder()).Connector(&connector{
+				kube:  &test.MockClient{MockGet: connecttest.MockGet(pc, connecttest.Secret(caBundle))},
+				usage: resource.LegacyTrackerFn(func(context.Context, resource.LegacyManaged) error { return nil }),
+				newServiceFn: func(types.UID, pcv1alpha1common.ProviderConfigSpec, controllercommon.Credentials) (*cloudian.Client, error) {
+					return connecttest.StatusService(t, status), nil
+				},
+			})
+			ext, err := c.Connect(context.Background(), mg)
+			if err != nil {
+				t.Fatalf("c.Connect(...): %v", err)
+			}
+			_, err = ext.Observe(context.Background(), mg)
+			if want := string(reason) + ": "; err == nil || !strings.HasPrefix(err.Error(), want) || !strings.Contains(err.Error(), errGetQOS) {
+				t.Errorf("ext.Observe(...): want error starting with %q explaining %q, got %v", want, errGetQOS, err)
+			}
+		})
+	}
+}
+
+func TestObserveInvalidLimits(t *testing.T) {
+	srv := cloudiantest.NewFakeServer(t)
+	e := &external{cloudianService: cloudian.NewClient(srv.URL, "")}
+
+	mg := &userv1alpha1cluster.UserQualityOfServiceLimits{}
+	mg.Spec.ForProvider.GroupID = "QA"
+	mg.Spec.ForProvider.UserID = "alice"
+	storage := userv1alpha1common.Quantity("1.5Ki")
+	mg.Spec.ForProvider.QOS.Hard = &userv1alpha1common.QualityOfServiceLimits{StorageQuotaBytes: &storage}
+
+	if _, err := e.Observe(context.Background(), mg); err == nil {
+		t.Fatal("e.Observe(...): want an error for limits that cannot be sent to Cloudian")
+	}
+	if got := mg.GetCondition(qoslimitscommon.TypeValidLimits); got.Reason != qoslimitscommon.ReasonInvalidLimits {
+		t.Errorf("e.Observe(...): want a %s condition, got %+v", qoslimitscommon.ReasonInvalidLimits, got)
+	}
+	if got := srv.Requests("/qos/limits"); got != 0 {
+		t.Errorf("e.Observe(...): want Cloudian not asked for invalid limits, got %d requests", got)
+	}
+
+	// Once fixed, the limits are created and read back up to date.
+	storage = "2Ki"
+	o, err := e.Observe(context.Background(), mg)
+	if err != nil || o.ResourceExists {
+		t.Fatalf("e.Observe(...): want the limits not to exist yet, got %+v, %v", o, err)
+	}
+	if got := mg.GetCondition(qoslimitscommon.TypeValidLimits); got.Reason != qoslimitscommon.ReasonValidLimits {
+		t.Errorf("e.Observe(...): want a %s condition, got %+v", qoslimitscommon.ReasonValidLimits, got)
+	}
+	if _, err := e.Create(context.Background(), mg); err != nil {
+		t.Fatalf("e.Create(...): %v", err)
+	}
+	if o, err := e.Observe(context.Background(), mg); err != nil || !o.ResourceExists || !o.ResourceUpToDate {
+		t.Errorf("e.Observe(...): want the created limits up to date, got %+v, %v", o, err)
+	}
+}
@@ -1,22 +1,106 @@
 package accesskey
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"slices"
 
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	userv1alpha1common "github.com/statnett/provider-cloudian/apis/common/user/v1alpha1"
+	"github.com/statnett/provider-cloudian/internal/controller/common/referrers"
 	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
 )
 
+const errUpdateManaged = "cannot update managed resource"
+
 func ConnectionDetails(creds *cloudian.SecurityInfo) managed.ConnectionDetails {
 	return managed.ConnectionDetails{
-		"secretKey": []byte(creds.SecretKey),
+		"secretKey": []byte(creds.SecretKey.Value()),
 		"config.toml": []byte(fmt.Sprintf(
 			`[default]
 aws_access_key_id = %s
 aws_secret_access_key = %s`,
 			creds.AccessKey,
-			creds.SecretKey,
+			creds.SecretKey.Value(),
 		)),
 	}
 }
+
+// Unmanaged returns the IDs of the observed access keys that are not managed
+// by any AccessKey.
+func Unmanaged(observed []cloudian.SecurityInfo, managed []string) []string {
+	var unmanaged []string
+	for _, key := range observed {
+		if !slices.Contains(managed, key.AccessKey) {
+			unmanaged = append(unmanaged, key.AccessKey)
+		}
+	}
+	return unmanaged
+}
+
+// NewAccessKey returns a random access key, formatted like the access keys
+// generated by Cloudian.
+func NewAccessKey() string {
+	return hex.EncodeToString(randomBytes(10))
+}
+
+// NewSecretKey returns a random secret key, formatted like the secret keys
+// generated by Cloudian.
+func NewSecretKey() string {
+	return base64.StdEncoding.EncodeToString(randomBytes(30))
+}
+
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	// Read never returns an error, see crypto/rand.
+	_, _ = rand.Read(b)
+	return b
+}
+
+// IsGenerated returns whether the access key of an AccessKey was generated by
+// KeyInitializer. AccessKeys initialized before the provider generated access
+// keys have their name as external-name until they have been created.
+func IsGenerated(mg resource.Managed) bool {
+	externalName := meta.GetExternalName(mg)
+	return externalName != "" && externalName != mg.GetName()
+}
+
+// KeyInitializer sets the external-name of an AccessKey without one to a
+// newly generated access key. The external-name is persisted before the
+// access key is created in Cloudian, so that a create that fails or whose
+// outcome is lost is retried with the same access key.
+type KeyInitializer struct {
+	kube client.Client
+}
+
+// NewKeyInitializer returns a new KeyInitializer.
+func NewKeyInitializer(kube client.Client) *KeyInitializer {
+	return &KeyInitializer{kube: kube}
+}
+
+// Initialize the given AccessKey.
+func (i *KeyInitializer) Initialize(ctx context.Context, mg resource.Managed) error {
+	if meta.GetExternalName(mg) != "" {
+		return nil
+	}
+	meta.SetExternalName(mg, NewAccessKey())
+	return errors.Wrap(i.kube.Update(ctx, mg), errUpdateManaged)
+}
+
+// RefersToUser returns whether an AccessKey with the supplied parameters
+// belongs to the User `u`, which manages the Cloudian user `guid`, or may
+// resolve its user from it.
+func RefersToUser(p userv1alpha1common.AccessKeyParameters, u client.Object, guid cloudian.GroupUserID) bool {
+	if guid.GroupID != "" && guid.UserID != "" && p.GroupID == guid.GroupID && p.UserID == guid.UserID {
+		return true
+	}
+	return referrers.Refers(p.UserIDRef, p.UserIDSelector, u)
+}
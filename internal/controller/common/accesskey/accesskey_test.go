@@ -0,0 +1,77 @@
+package accesskey
+
+import (
+	"context"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/test"
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	userv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/user/v1alpha1"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
+)
+
+func TestUnmanaged(t *testing.T) {
+	observed := []cloudian.SecurityInfo{{AccessKey: "managed"}, {AccessKey: "console"}}
+
+	if diff := cmp.Diff([]string{"console"}, Unmanaged(observed, []string{"managed", "deleted"})); diff != "" {
+		t.Errorf("Unmanaged(...): -want, +got:\n%s", diff)
+	}
+	if got := Unmanaged(observed, []string{"managed", "console"}); got != nil {
+		t.Errorf("Unmanaged(...): want none, got %v", got)
+	}
+}
+
+func TestNewKeys(t *testing.T) {
+	if got := NewAccessKey(); len(got) != 20 || got == NewAccessKey() {
+		t.Errorf("NewAccessKey(): want 20 random characters, got %q", got)
+	}
+	if got := NewSecretKey(); len(got) != 40 || got == NewSecretKey() {
+		t.Errorf("NewSecretKey(): want 40 random characters, got %q", got)
+	}
+}
+
+func TestKeyInitializer(t *testing.T) {
+	cases := map[string]struct {
+		externalName string
+		wantUpdate   bool
+	}{
+		"Generate":       {wantUpdate: true},
+		"KeepExisting":   {externalName: "00a1b2c3d4e5f6a7b8c9"},
+		"KeepLegacyName": {externalName: "alice"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			mg := &userv1alpha1cluster.AccessKey{ObjectMeta: metav1.ObjectMeta{Name: "alice"}}
+			if tc.externalName != "" {
+				meta.SetExternalName(mg, tc.externalName)
+			}
+			updated := false
+			kube := &test.MockClient{MockUpdate: func(context.Context, client.Object, ...client.UpdateOption) error {
+				updated = true
+				return nil
+			}}
+
+			if err := NewKeyInitializer(kube).Initialize(context.Background(), mg); err != nil {
+				t.Fatalf("Initialize(...): %v", err)
+			}
+			if updated != tc.wantUpdate {
+				t.Errorf("Initialize(...): want update %t, got %t", tc.wantUpdate, updated)
+			}
+			got := meta.GetExternalName(mg)
+			if tc.externalName != "" && got != tc.externalName {
+				t.Errorf("Initialize(...): want external-name %q, got %q", tc.externalName, got)
+			}
+			if tc.wantUpdate && len(got) != 20 {
+				t.Errorf("Initialize(...): want generated external-name, got %q", got)
+			}
+			if want := tc.externalName != "alice"; IsGenerated(mg) != want {
+				t.Errorf("IsGenerated(...): want %t, got %t", want, !want)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package accesskey
+
+import (
+	"context"
+	"errors"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+
+	"github.com/statnett/provider-cloudian/internal/controller/common/degraded"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
+)
+
+// ObserveWithoutCredentials observes an access key of the user `guid` whose
+// credentials failed to be read with `err`, from the user instead. The access
+// key is only found not to exist when Cloudian confirms its user does not,
+// since the access keys of a user are deleted along with it. While the user
+// exists the access key is assumed to exist and be up to date, without
+// connection details, so that the published ones are kept, and the
+// observation is degraded. `err` is returned when the user cannot be read
+// either.
+func ObserveWithoutCredentials(ctx context.Context, svc *cloudian.Client, cr resource.Conditioned, guid cloudian.GroupUserID, err error) (managed.ExternalObservation, error) {
+	if guid.GroupID == "" || guid.UserID == "" {
+		return managed.ExternalObservation{}, err
+	}
+	_, userErr := svc.GetUser(ctx, guid)
+	if errors.Is(userErr, cloudian.ErrNotFound) {
+		return managed.ExternalObservation{ResourceExists: false}, nil
+	}
+	if userErr != nil {
+		return managed.ExternalObservation{}, err
+	}
+
+	var observation degraded.Observation
+	observation.Fail("GetUserCredentials", err)
+	observation.SetCondition(cr)
+	return managed.ExternalObservation{ResourceExists: true, ResourceUpToDate: true}, nil
+}
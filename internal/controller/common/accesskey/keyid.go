@@ -0,0 +1,58 @@
+package accesskey
+
+import (
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	userv1alpha1common "github.com/statnett/provider-cloudian/apis/common/user/v1alpha1"
+)
+
+// KeyIDLabel is the label with the access key ID of an AccessKey, so that
+// the AccessKey of an access key ID seen in Cloudian can be found with
+// `kubectl get accesskeys -l cloudian.crossplane.io/key-id=<id>`. Field
+// selectors of custom resources are limited to the fields declared by their
+// CRD.
+const KeyIDLabel = "cloudian.crossplane.io/key-id"
+
+// IndexField is the field index of the observed access key ID of AccessKeys.
+// The deprecated status.atProvider.id has the same value.
+const IndexField = userv1alpha1common.FieldPathAccessKeyID
+
+// IndexKeyID returns the observed access key ID of an AccessKey as index key,
+// if any.
+func IndexKeyID(obj client.Object) []string {
+	ak, ok := obj.(AccessKey)
+	if !ok {
+		return nil
+	}
+	if id := ak.GetAccessKeyObservation().AccessKeyID; id != "" {
+		return []string{id}
+	}
+	return nil
+}
+
+// SetKeyIDLabel sets the KeyIDLabel of an AccessKey to the supplied access
+// key ID. The label is removed when the ID is empty, or is not a valid label
+// value and thus cannot be looked up by label. It returns whether the labels
+// were changed.
+func SetKeyIDLabel(obj client.Object, id string) bool {
+	labels := obj.GetLabels()
+	cur, ok := labels[KeyIDLabel]
+	if id == "" || len(validation.IsValidLabelValue(id)) > 0 {
+		if !ok {
+			return false
+		}
+		delete(labels, KeyIDLabel)
+		obj.SetLabels(labels)
+		return true
+	}
+	if ok && cur == id {
+		return false
+	}
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[KeyIDLabel] = id
+	obj.SetLabels(labels)
+	return true
+}
@@ -0,0 +1,104 @@
+package accesskey
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	userv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/user/v1alpha1"
+	apisnamespaced "github.com/statnett/provider-cloudian/apis/namespaced"
+	userv1alpha1namespaced "github.com/statnett/provider-cloudian/apis/namespaced/user/v1alpha1"
+)
+
+func TestSetKeyIDLabel(t *testing.T) {
+	cases := map[string]struct {
+		reason      string
+		labels      map[string]string
+		id          string
+		wantLabels  map[string]string
+		wantChanged bool
+	}{
+		"Added": {
+			reason:      "The label should be added to an AccessKey without labels.",
+			id:          "00112233445566778899",
+			wantLabels:  map[string]string{KeyIDLabel: "00112233445566778899"},
+			wantChanged: true,
+		},
+		"Unchanged": {
+			reason:     "The label of the current access key should be left alone.",
+			labels:     map[string]string{KeyIDLabel: "00112233445566778899", "team": "storage"},
+			id:         "00112233445566778899",
+			wantLabels: map[string]string{KeyIDLabel: "00112233445566778899", "team": "storage"},
+		},
+		"Rotated": {
+			reason:      "The label should follow the access key when it is replaced.",
+			labels:      map[string]string{KeyIDLabel: "00112233445566778899", "team": "storage"},
+			id:          "99887766554433221100",
+			wantLabels:  map[string]string{KeyIDLabel: "99887766554433221100", "team": "storage"},
+			wantChanged: true,
+		},
+		"Removed": {
+			reason:      "The label should be removed when the access key is not known.",
+			labels:      map[string]string{KeyIDLabel: "00112233445566778899", "team": "storage"},
+			wantLabels:  map[string]string{"team": "storage"},
+			wantChanged: true,
+		},
+		"InvalidValue": {
+			reason:      "An access key ID that is not a valid label value should not be labeled, rather than fail the update.",
+			labels:      map[string]string{KeyIDLabel: "00112233445566778899"},
+			id:          "not/a+label=value",
+			wantLabels:  map[string]string{},
+			wantChanged: true,
+		},
+		"NothingToRemove": {
+			reason: "An AccessKey without access key nor label should be left alone.",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			mg := &userv1alpha1cluster.AccessKey{ObjectMeta: metav1.ObjectMeta{Name: "alice", Labels: tc.labels}}
+			if got := SetKeyIDLabel(mg, tc.id); got != tc.wantChanged {
+				t.Errorf("\n%s\nSetKeyIDLabel(...): want changed %t, got %t", tc.reason, tc.wantChanged, got)
+			}
+			if diff := cmp.Diff(tc.wantLabels, mg.GetLabels()); diff != "" {
+				t.Errorf("\n%s\nSetKeyIDLabel(...): -want labels, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func scheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+	s := runtime.NewScheme()
+	if err := apisnamespaced.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestIndexKeyID(t *testing.T) {
+	newAccessKey := func(name, id string) *userv1alpha1namespaced.AccessKey {
+		mg := &userv1alpha1namespaced.AccessKey{ObjectMeta: metav1.ObjectMeta{Namespace: "team", Name: name}}
+		mg.Status.AtProvider.SetAccessKeyID(id)
+		return mg
+	}
+	kube := fake.NewClientBuilder().
+		WithScheme(scheme(t)).
+		WithIndex(&userv1alpha1namespaced.AccessKey{}, IndexField, IndexKeyID).
+		WithObjects(newAccessKey("alice", "00112233445566778899"), newAccessKey("bob", "99887766554433221100"), newAccessKey("pending", "")).
+		Build()
+
+	l := &userv1alpha1namespaced.AccessKeyList{}
+	if err := kube.List(context.Background(), l, client.MatchingFields{IndexField: "99887766554433221100"}); err != nil {
+		t.Fatalf("kube.List(...): %v", err)
+	}
+	if len(l.Items) != 1 || l.Items[0].GetName() != "bob" {
+		t.Errorf("kube.List(...): want the AccessKey of the access key ID, got %v", l.Items)
+	}
+}
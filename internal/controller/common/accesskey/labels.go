@@ -0,0 +1,148 @@
+package accesskey
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	userv1alpha1common "github.com/statnett/provider-cloudian/apis/common/user/v1alpha1"
+)
+
+const (
+	errInvalidLabel   = "invalid label %q"
+	errReservedLabel  = "label %q is in a reserved domain"
+	errGetSecret      = "cannot get connection secret"
+	errAnnotateSecret = "cannot annotate connection secret"
+	errNotAccessKey   = "managed resource is not an AccessKey"
+)
+
+// reservedLabel matches the label keys in domains reserved by Crossplane and
+// Kubernetes, which AccessKey labels must not override.
+var reservedLabel = regexp.MustCompile(`^([a-z0-9.-]+[.])?(crossplane[.]io|kubernetes[.]io|k8s[.]io)/`)
+
+// An AccessKey is a managed resource with the parameters and observation of
+// an access key.
+type AccessKey interface {
+	resource.Managed
+	GetAccessKeyParameters() *userv1alpha1common.AccessKeyParameters
+	GetAccessKeyObservation() *userv1alpha1common.AccessKeyObservation
+}
+
+// ValidateLabels returns an error if any of the labels of an AccessKey is not
+// a valid Kubernetes label, or is in a reserved domain.
+func ValidateLabels(labels map[string]string) error {
+	for k, v := range labels {
+		if errs := append(validation.IsQualifiedName(k), validation.IsValidLabelValue(v)...); len(errs) > 0 {
+			return errors.Errorf(errInvalidLabel+": %s", k, strings.Join(errs, "; "))
+		}
+		if reservedLabel.MatchString(k) {
+			return errors.Errorf(errReservedLabel, k)
+		}
+	}
+	return nil
+}
+
+// LabelInitializer applies the labels of an AccessKey to its metadata, so
+// that AccessKeys can be selected by label, and as annotations of its
+// connection secret. Labels removed from the spec since they were last
+// observed are removed too, unless they have been changed by someone else.
+// The connection secret is annotated once it has been published.
+type LabelInitializer struct {
+	kube client.Client
+}
+
+// NewLabelInitializer returns a new LabelInitializer.
+func NewLabelInitializer(kube client.Client) *LabelInitializer {
+	return &LabelInitializer{kube: kube}
+}
+
+// Initialize the given AccessKey.
+func (i *LabelInitializer) Initialize(ctx context.Context, mg resource.Managed) error {
+	ak, ok := mg.(AccessKey)
+	if !ok {
+		return errors.New(errNotAccessKey)
+	}
+
+	desired := ak.GetAccessKeyParameters().Labels
+	if err := ValidateLabels(desired); err != nil {
+		return err
+	}
+	applied := ak.GetAccessKeyObservation().Labels
+
+	if labels, changed := mergeLabels(ak.GetLabels(), desired, applied); changed {
+		ak.SetLabels(labels)
+		if err := i.kube.Update(ctx, ak); err != nil {
+			return errors.Wrap(err, errUpdateManaged)
+		}
+	}
+
+	key, ok := connectionSecret(ak)
+	if !ok {
+		return nil
+	}
+	s := &corev1.Secret{}
+	if err := i.kube.Get(ctx, key, s); err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil
+		}
+		return errors.Wrap(err, errGetSecret)
+	}
+	if !metav1.IsControlledBy(s, ak) {
+		// Publishing the connection secret will fail instead.
+		return nil
+	}
+	if annotations, changed := mergeLabels(s.GetAnnotations(), desired, applied); changed {
+		s.SetAnnotations(annotations)
+		return errors.Wrap(i.kube.Update(ctx, s), errAnnotateSecret)
+	}
+	return nil
+}
+
+// mergeLabels sets the desired labels in `m`, and removes the applied labels
+// that are no longer desired. It returns the resulting map, and whether it
+// was changed.
+func mergeLabels(m, desired, applied map[string]string) (map[string]string, bool) {
+	changed := false
+	for k, v := range applied {
+		if _, ok := desired[k]; !ok && m[k] == v {
+			delete(m, k)
+			changed = true
+		}
+	}
+	for k, v := range desired {
+		if cur, ok := m[k]; ok && cur == v {
+			continue
+		}
+		if m == nil {
+			m = map[string]string{}
+		}
+		m[k] = v
+		changed = true
+	}
+	return m, changed
+}
+
+// connectionSecret returns the connection secret of an AccessKey of either
+// scope, or false if it does not write one.
+func connectionSecret(mg resource.Managed) (types.NamespacedName, bool) {
+	switch o := mg.(type) {
+	case resource.LocalConnectionSecretOwner:
+		if ref := o.GetWriteConnectionSecretToReference(); ref != nil {
+			return types.NamespacedName{Namespace: o.GetNamespace(), Name: ref.Name}, true
+		}
+	case resource.ConnectionSecretOwner:
+		if ref := o.GetWriteConnectionSecretToReference(); ref != nil {
+			return types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, true
+		}
+	}
+	return types.NamespacedName{}, false
+}
@@ -0,0 +1,151 @@
+package accesskey
+
+import (
+	"context"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/test"
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	userv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/user/v1alpha1"
+	userv1alpha1namespaced "github.com/statnett/provider-cloudian/apis/namespaced/user/v1alpha1"
+)
+
+var errNotFound = kerrors.NewNotFound(schema.GroupResource{Resource: "secrets"}, "alice-key")
+
+func TestValidateLabels(t *testing.T) {
+	cases := map[string]struct {
+		labels  map[string]string
+		wantErr bool
+	}{
+		"Valid":          {labels: map[string]string{"team": "storage", "example.com/cost-center": "42"}},
+		"InvalidKey":     {labels: map[string]string{"team name": "storage"}, wantErr: true},
+		"InvalidValue":   {labels: map[string]string{"team": "storage/backup"}, wantErr: true},
+		"Crossplane":     {labels: map[string]string{"crossplane.io/claim-name": "x"}, wantErr: true},
+		"KubernetesSub":  {labels: map[string]string{"app.kubernetes.io/name": "x"}, wantErr: true},
+		"LookalikeValid": {labels: map[string]string{"notk8s.io/name": "x"}},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if err := ValidateLabels(tc.labels); (err != nil) != tc.wantErr {
+				t.Errorf("ValidateLabels(%v): want error %t, got %v", tc.labels, tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLabelInitializer(t *testing.T) {
+	newAccessKey := func() *userv1alpha1cluster.AccessKey {
+		mg := &userv1alpha1cluster.AccessKey{ObjectMeta: metav1.ObjectMeta{
+			Name:   "alice",
+			UID:    "alice-uid",
+			Labels: map[string]string{"env": "prod", "team": "storage", "owner": "bob"},
+		}}
+		mg.Spec.ForProvider.Labels = map[string]string{"team": "backup", "tier": "gold"}
+		// "owner" was applied last time but is no longer desired, while "env"
+		// was set by someone else.
+		mg.Status.AtProvider.Labels = map[string]string{"team": "storage", "owner": "bob"}
+		mg.SetWriteConnectionSecretToReference(&xpv2.SecretReference{Namespace: "team", Name: "alice-key"})
+		return mg
+	}
+	wantLabels := map[string]string{"env": "prod", "team": "backup", "tier": "gold"}
+
+	cases := map[string]struct {
+		secret          *corev1.Secret
+		wantAnnotations map[string]string
+	}{
+		"Annotated": {
+			secret: &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{"owner": "bob", "other": "kept"},
+			}},
+			wantAnnotations: map[string]string{"other": "kept", "team": "backup", "tier": "gold"},
+		},
+		"NotPublished": {},
+		"NotControlled": {
+			secret: &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+				OwnerReferences: []metav1.OwnerReference{{UID: "other-uid", Controller: new(bool)}},
+				Annotations:     map[string]string{"owner": "bob"},
+			}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			mg := newAccessKey()
+			if tc.secret != nil && tc.secret.OwnerReferences == nil {
+				meta.AddControllerReference(tc.secret, meta.AsController(meta.TypedReferenceTo(mg, userv1alpha1cluster.AccessKeyGroupVersionKind)))
+			}
+
+			var updatedSecret *corev1.Secret
+			kube := &test.MockClient{
+				MockGet: func(_ context.Context, key client.ObjectKey, obj client.Object) error {
+					if key != (client.ObjectKey{Namespace: "team", Name: "alice-key"}) {
+						t.Errorf("Get(...): want the connection secret, got %s", key)
+					}
+					if tc.secret == nil {
+						return errNotFound
+					}
+					tc.secret.DeepCopyInto(obj.(*corev1.Secret))
+					return nil
+				},
+				MockUpdate: func(_ context.Context, obj client.Object, _ ...client.UpdateOption) error {
+					if s, ok := obj.(*corev1.Secret); ok {
+						updatedSecret = s
+					}
+					return nil
+				},
+			}
+
+			if err := NewLabelInitializer(kube).Initialize(context.Background(), mg); err != nil {
+				t.Fatalf("Initialize(...): %v", err)
+			}
+			if diff := cmp.Diff(wantLabels, mg.GetLabels()); diff != "" {
+				t.Errorf("Initialize(...): -want labels, +got labels:\n%s", diff)
+			}
+			var gotAnnotations map[string]string
+			if updatedSecret != nil {
+				gotAnnotations = updatedSecret.GetAnnotations()
+			}
+			if diff := cmp.Diff(tc.wantAnnotations, gotAnnotations); diff != "" {
+				t.Errorf("Initialize(...): -want secret annotations, +got secret annotations:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestLabelInitializerNamespaced(t *testing.T) {
+	mg := &userv1alpha1namespaced.AccessKey{ObjectMeta: metav1.ObjectMeta{Namespace: "team", Name: "alice"}}
+	mg.Spec.ForProvider.Labels = map[string]string{"team": "backup"}
+	mg.SetWriteConnectionSecretToReference(&xpv2.LocalSecretReference{Name: "alice-key"})
+
+	var got client.ObjectKey
+	kube := &test.MockClient{
+		MockGet: func(_ context.Context, key client.ObjectKey, _ client.Object) error {
+			got = key
+			return errNotFound
+		},
+		MockUpdate: test.NewMockUpdateFn(nil),
+	}
+	if err := NewLabelInitializer(kube).Initialize(context.Background(), mg); err != nil {
+		t.Fatalf("Initialize(...): %v", err)
+	}
+	if want := (client.ObjectKey{Namespace: "team", Name: "alice-key"}); got != want {
+		t.Errorf("Initialize(...): want connection secret %s in the namespace of the AccessKey, got %s", want, got)
+	}
+}
+
+func TestLabelInitializerRejectsReservedLabels(t *testing.T) {
+	mg := &userv1alpha1cluster.AccessKey{}
+	mg.Spec.ForProvider.Labels = map[string]string{"crossplane.io/composite": "x"}
+	if err := NewLabelInitializer(&test.MockClient{}).Initialize(context.Background(), mg); err == nil {
+		t.Error("Initialize(...): want an error for a reserved label")
+	}
+}
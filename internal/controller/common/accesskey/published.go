@@ -0,0 +1,41 @@
+package accesskey
+
+import (
+	"context"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/event"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
+)
+
+// ReasonSecretKeyChanged is the reason of the event warning that the
+// published secret key is stale.
+const ReasonSecretKeyChanged event.Reason = "SecretKeyChanged"
+
+const (
+	errGetPublished     = "cannot get published connection secret"
+	errSecretKeyChanged = "secret key was changed outside the provider, publishing the new one"
+)
+
+// CheckPublished warns when the secret key published in the connection secret
+// with the supplied key is not the one of the credentials in Cloudian, such
+// as when it was regenerated outside the provider. The managed reconciler
+// then replaces it by publishing the ConnectionDetails of the credentials.
+// Connection secrets not yet published are not checked.
+func CheckPublished(ctx context.Context, kube client.Reader, recorder event.Recorder, mg resource.Managed, key types.NamespacedName, creds *cloudian.SecurityInfo) error {
+	s := &corev1.Secret{}
+	if err := kube.Get(ctx, key, s); err != nil {
+		return errors.Wrap(client.IgnoreNotFound(err), errGetPublished)
+	}
+	published, ok := s.Data["secretKey"]
+	if !ok || cloudian.NewSecret(string(published)).Equal(creds.SecretKey) {
+		return nil
+	}
+	recorder.Event(mg, event.Warning(ReasonSecretKeyChanged, errors.New(errSecretKeyChanged)))
+	return nil
+}
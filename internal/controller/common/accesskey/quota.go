@@ -0,0 +1,79 @@
+package accesskey
+
+import (
+	"context"
+	"slices"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/statnett/provider-cloudian/internal/controller/common/apierror"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
+)
+
+const (
+	// TypeKeyQuota indicates whether the user of an AccessKey may have
+	// another access key.
+	TypeKeyQuota xpv2.ConditionType = "KeyQuota"
+
+	// ReasonKeyQuotaExceeded means the user of an AccessKey already has as
+	// many access keys as allowed, so the access key was not created.
+	ReasonKeyQuotaExceeded = apierror.ReasonKeyQuotaExceeded
+
+	// ReasonWithinKeyQuota means the access key of an AccessKey was created
+	// within the quota of its user.
+	ReasonWithinKeyQuota xpv2.ConditionReason = "WithinKeyQuota"
+)
+
+const (
+	errListCredentials = "cannot list access keys of user"
+	errKeyQuota        = "user %s has %d access keys, the most allowed by the ProviderConfig"
+)
+
+// CheckKeyQuota returns an error wrapping cloudian.ErrKeyQuotaExceeded if a
+// user has at least `maxKeys` access keys, unless `accessKey` is one of them
+// as when a create is retried. Zero allows any number.
+func CheckKeyQuota(ctx context.Context, svc *cloudian.Client, guid cloudian.GroupUserID, accessKey string, maxKeys int) error {
+	if maxKeys <= 0 {
+		return nil
+	}
+	keys, err := svc.ListUserCredentials(ctx, guid)
+	if errors.Is(err, cloudian.ErrTooManyCredentials) {
+		return errors.Wrapf(cloudian.ErrKeyQuotaExceeded, errKeyQuota, guid.UserID, cloudian.MaxKeys)
+	}
+	if err != nil {
+		return errors.Wrap(err, errListCredentials)
+	}
+	if len(keys) < maxKeys || slices.ContainsFunc(keys, func(k cloudian.SecurityInfo) bool { return k.AccessKey == accessKey }) {
+		return nil
+	}
+	return errors.Wrapf(cloudian.ErrKeyQuotaExceeded, errKeyQuota, guid.UserID, len(keys))
+}
+
+// SetKeyQuotaCondition sets the KeyQuota condition of an AccessKey whose
+// access key failed to be created with the supplied error, or was created if
+// the error is nil. The condition is only set once the quota was exceeded.
+// It returns the error.
+func SetKeyQuotaCondition(cr resource.Conditioned, err error) error {
+	switch {
+	case errors.Is(err, cloudian.ErrKeyQuotaExceeded):
+		cr.SetConditions(xpv2.Condition{
+			Type:               TypeKeyQuota,
+			Status:             corev1.ConditionFalse,
+			LastTransitionTime: metav1.Now(),
+			Reason:             ReasonKeyQuotaExceeded,
+			Message:            err.Error(),
+		})
+	case err == nil && cr.GetCondition(TypeKeyQuota).Reason == ReasonKeyQuotaExceeded:
+		cr.SetConditions(xpv2.Condition{
+			Type:               TypeKeyQuota,
+			Status:             corev1.ConditionTrue,
+			LastTransitionTime: metav1.Now(),
+			Reason:             ReasonWithinKeyQuota,
+		})
+	}
+	return err
+}
@@ -0,0 +1,102 @@
+package accesskey
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
+	corev1 "k8s.io/api/core/v1"
+
+	userv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/user/v1alpha1"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian/cloudiantest"
+)
+
+func TestCheckKeyQuota(t *testing.T) {
+	guid := cloudian.GroupUserID{GroupID: "QA", UserID: "alice"}
+
+	cases := map[string]struct {
+		reason    string
+		keys      int
+		accessKey string
+		maxKeys   int
+		want      error
+	}{
+		"Unlimited": {
+			reason: "Any number of access keys should be allowed without a quota.",
+			keys:   3,
+		},
+		"Within": {
+			reason:  "A user with fewer access keys than the quota should get another.",
+			keys:    2,
+			maxKeys: 3,
+		},
+		"Exceeded": {
+			reason:  "A user with as many access keys as the quota should not get another.",
+			keys:    3,
+			maxKeys: 3,
+			want:    cloudian.ErrKeyQuotaExceeded,
+		},
+		"Retried": {
+			reason:    "A create whose access key already exists should be retried even at the quota.",
+			keys:      3,
+			accessKey: fmt.Sprintf("%020d", 2),
+			maxKeys:   3,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			srv := cloudiantest.NewFakeServer(t)
+			srv.AddUser(cloudiantest.User{GroupID: guid.GroupID, UserID: guid.UserID})
+			for i := range tc.keys {
+				srv.AddCredentials(guid.GroupID, guid.UserID, fmt.Sprintf("%020d", i), "secret")
+			}
+
+			err := CheckKeyQuota(context.Background(), cloudian.NewClient(srv.URL, ""), guid, tc.accessKey, tc.maxKeys)
+			if !errors.Is(err, tc.want) || (tc.want == nil && err != nil) {
+				t.Errorf("\n%s\nCheckKeyQuota(...): want %v, got %v", tc.reason, tc.want, err)
+			}
+		})
+	}
+
+	srv := cloudiantest.NewFakeServer(t)
+	srv.Script("/user/credentials/list", cloudiantest.Respond(http.StatusInternalServerError, ""))
+	err := CheckKeyQuota(context.Background(), cloudian.NewClient(srv.URL, ""), guid, "", 3)
+	if err == nil || errors.Is(err, cloudian.ErrKeyQuotaExceeded) {
+		t.Errorf("CheckKeyQuota(...): want the failure to list access keys, got %v", err)
+	}
+}
+
+func TestSetKeyQuotaCondition(t *testing.T) {
+	mg := &userv1alpha1cluster.AccessKey{}
+
+	if err := SetKeyQuotaCondition(mg, nil); err != nil {
+		t.Fatalf("SetKeyQuotaCondition(...): %v", err)
+	}
+	if got := mg.GetCondition(TypeKeyQuota); got.Status != corev1.ConditionUnknown {
+		t.Errorf("SetKeyQuotaCondition(...): want no condition before the quota was exceeded, got %+v", got)
+	}
+
+	exceeded := fmt.Errorf("cannot create: %w", cloudian.ErrKeyQuotaExceeded)
+	if err := SetKeyQuotaCondition(mg, exceeded); !errors.Is(err, exceeded) {
+		t.Errorf("SetKeyQuotaCondition(...): want the error returned, got %v", err)
+	}
+	if got := mg.GetCondition(TypeKeyQuota); got.Status != corev1.ConditionFalse || got.Reason != ReasonKeyQuotaExceeded || got.Message != exceeded.Error() {
+		t.Errorf("SetKeyQuotaCondition(...): want condition %s, got %+v", ReasonKeyQuotaExceeded, got)
+	}
+
+	// Other errors leave the condition alone.
+	_ = SetKeyQuotaCondition(mg, errors.New("boom"))
+	if got := mg.GetCondition(TypeKeyQuota); got.Reason != ReasonKeyQuotaExceeded {
+		t.Errorf("SetKeyQuotaCondition(...): want condition %s kept, got %+v", ReasonKeyQuotaExceeded, got)
+	}
+
+	_ = SetKeyQuotaCondition(mg, nil)
+	if got := mg.GetCondition(TypeKeyQuota); !got.Equal(xpv2.Condition{Type: TypeKeyQuota, Status: corev1.ConditionTrue, Reason: ReasonWithinKeyQuota}) {
+		t.Errorf("SetKeyQuotaCondition(...): want condition %s once created, got %+v", ReasonWithinKeyQuota, got)
+	}
+}
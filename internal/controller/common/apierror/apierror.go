@@ -0,0 +1,229 @@
+// Package apierror explains errors returned by the Cloudian admin API on the
+// managed resources they affect, and backs off when the API is throttling, the
+// HyperStore license capacity is exceeded or a user has too many access keys.
+// Resources waiting for their group or user to be created are retried shortly.
+package apierror
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/event"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
+)
+
+const (
+	// ReasonInvalidProviderCredentials means the admin API rejected the
+	// credentials of the ProviderConfig, or they lack admin rights.
+	ReasonInvalidProviderCredentials xpv2.ConditionReason = "InvalidProviderCredentials"
+
+	// ReasonThrottled means the admin API is rate limiting requests.
+	ReasonThrottled xpv2.ConditionReason = "Throttled"
+
+	// ReasonCloudianUnavailable means the admin API failed to serve a request.
+	ReasonCloudianUnavailable xpv2.ConditionReason = "CloudianUnavailable"
+
+	// ReasonLicenseExceeded means the admin API refused a request because the
+	// capacity of the HyperStore license is exceeded.
+	ReasonLicenseExceeded xpv2.ConditionReason = "LicenseExceeded"
+
+	// ReasonKeyQuotaExceeded means a user already has as many access keys as
+	// allowed, by Cloudian or the ProviderConfig.
+	ReasonKeyQuotaExceeded xpv2.ConditionReason = "KeyQuotaExceeded"
+
+	// ReasonGroupNotFound means the admin API refused to create a user
+	// because its group does not exist yet.
+	ReasonGroupNotFound xpv2.ConditionReason = "GroupNotFound"
+
+	// ReasonUserNotFound means the admin API refused to create an access key
+	// because its user does not exist yet.
+	ReasonUserNotFound xpv2.ConditionReason = "UserNotFound"
+)
+
+// ThrottledRequeue is how long a managed resource waits before it is
+// reconciled again after the admin API throttled it without saying how long
+// to wait in a Retry-After header.
+var ThrottledRequeue = 2 * time.Minute
+
+// LicenseExceededRequeue is how long a managed resource waits before it is
+// reconciled again after the HyperStore license capacity was exceeded, which
+// lasts until capacity is added.
+var LicenseExceededRequeue = 30 * time.Minute
+
+// KeyQuotaExceededRequeue is how long a managed resource waits before it is
+// reconciled again after its user exceeded its access key quota, which lasts
+// until access keys of the user are deleted.
+var KeyQuotaExceededRequeue = 10 * time.Minute
+
+// NotFoundRequeue is how long a managed resource waits before it is
+// reconciled again after its group or user did not exist yet, which is
+// usually created moments later by another managed resource.
+var NotFoundRequeue = 10 * time.Second
+
+var explanations = map[xpv2.ConditionReason]string{
+	ReasonInvalidProviderCredentials: "the credentials of the ProviderConfig were rejected or lack admin rights",
+	ReasonThrottled:                  "the Cloudian admin API is throttling requests",
+	ReasonCloudianUnavailable:        "the Cloudian admin API is unavailable",
+	ReasonLicenseExceeded:            "the capacity of the HyperStore license is exceeded, retrying until capacity is added",
+	ReasonKeyQuotaExceeded:           "the user has as many access keys as allowed, retrying until some are deleted",
+	ReasonGroupNotFound:              "the group of the user does not exist yet, retrying shortly",
+	ReasonUserNotFound:               "the user of the access key does not exist yet, retrying shortly",
+}
+
+// Reason returns the reason for an error returned by the admin API, or false
+// if the error has no known reason.
+func Reason(err error) (xpv2.ConditionReason, bool) {
+	if errors.Is(err, cloudian.ErrLicenseExceeded) {
+		return ReasonLicenseExceeded, true
+	}
+	if errors.Is(err, cloudian.ErrKeyQuotaExceeded) {
+		return ReasonKeyQuotaExceeded, true
+	}
+	if errors.Is(err, cloudian.ErrGroupNotFound) {
+		return ReasonGroupNotFound, true
+	}
+	if errors.Is(err, cloudian.ErrUserNotFound) {
+		return ReasonUserNotFound, true
+	}
+	var statusErr *cloudian.StatusError
+	if !errors.As(err, &statusErr) {
+		return "", false
+	}
+	switch code := statusErr.StatusCode; {
+	case code == http.StatusUnauthorized, code == http.StatusForbidden:
+		return ReasonInvalidProviderCredentials, true
+	case code == http.StatusTooManyRequests:
+		return ReasonThrottled, true
+	case code >= http.StatusInternalServerError:
+		return ReasonCloudianUnavailable, true
+	default:
+		return "", false
+	}
+}
+
+// Explain prefixes an error returned by the admin API with its reason and
+// what it means, so that it is actionable in the Synced condition message.
+// Errors without a known reason are returned as is.
+func Explain(err error) error {
+	reason, ok := Reason(err)
+	if !ok {
+		return err
+	}
+	return errors.Wrapf(err, "%s: %s", reason, explanations[reason])
+}
+
+// A Handler explains the admin API errors of the external clients of a
+// controller, and requeues the managed resources that were throttled or
+// exceeded the license capacity later, and those whose group or user does not
+// exist yet sooner.
+type Handler struct {
+	recorder event.Recorder
+
+	mu      sync.Mutex
+	backoff map[types.NamespacedName]time.Duration
+}
+
+// NewHandler returns a Handler that records admin API errors as events.
+func NewHandler(recorder event.Recorder) *Handler {
+	return &Handler{recorder: recorder, backoff: map[types.NamespacedName]time.Duration{}}
+}
+
+// Connector wraps an ExternalConnector so that the errors of its external
+// clients are handled.
+func (h *Handler) Connector(c managed.ExternalConnector) managed.ExternalConnector {
+	return managed.ExternalConnectorFn(func(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
+		ext, err := c.Connect(ctx, mg)
+		if err != nil {
+			return nil, err
+		}
+		return &external{ExternalClient: ext, handler: h}, nil
+	})
+}
+
+// Reconciler wraps a Reconciler so that managed resources throttled by the
+// admin API are requeued after the Retry-After of the response, or after
+// ThrottledRequeue if it has none, those that exceeded the license capacity
+// after LicenseExceededRequeue, those whose user exceeded its access key
+// quota after KeyQuotaExceededRequeue, and those whose group or user does not
+// exist yet after NotFoundRequeue, instead of backing off.
+func (h *Handler) Reconciler(r reconcile.Reconciler) reconcile.Reconciler {
+	return reconcile.Func(func(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+		result, err := r.Reconcile(ctx, req)
+		if after := h.takeBackoff(req.NamespacedName); after > 0 && err == nil && result.Requeue {
+			return reconcile.Result{RequeueAfter: after}, nil
+		}
+		return result, err
+	})
+}
+
+func (h *Handler) handle(mg resource.Managed, err error) error {
+	reason, ok := Reason(err)
+	if !ok {
+		return err
+	}
+	err = Explain(err)
+	h.recorder.Event(mg, event.Warning(event.Reason(reason), err))
+	var after time.Duration
+	switch reason {
+	case ReasonThrottled:
+		after = ThrottledRequeue
+		var throttled *cloudian.ThrottledError
+		if errors.As(err, &throttled) && throttled.RetryAfter > 0 {
+			after = throttled.RetryAfter
+		}
+	case ReasonLicenseExceeded:
+		after = LicenseExceededRequeue
+	case ReasonKeyQuotaExceeded:
+		after = KeyQuotaExceededRequeue
+	case ReasonGroupNotFound, ReasonUserNotFound:
+		after = NotFoundRequeue
+	default:
+		return err
+	}
+	h.mu.Lock()
+	h.backoff[types.NamespacedName{Namespace: mg.GetNamespace(), Name: mg.GetName()}] = after
+	h.mu.Unlock()
+	return err
+}
+
+func (h *Handler) takeBackoff(nn types.NamespacedName) time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	after := h.backoff[nn]
+	delete(h.backoff, nn)
+	return after
+}
+
+type external struct {
+	managed.ExternalClient
+	handler *Handler
+}
+
+func (e *external) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
+	o, err := e.ExternalClient.Observe(ctx, mg)
+	return o, e.handler.handle(mg, err)
+}
+
+func (e *external) Create(ctx context.Context, mg resource.Managed) (managed.ExternalCreation, error) {
+	c, err := e.ExternalClient.Create(ctx, mg)
+	return c, e.handler.handle(mg, err)
+}
+
+func (e *external) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
+	u, err := e.ExternalClient.Update(ctx, mg)
+	return u, e.handler.handle(mg, err)
+}
+
+func (e *external) Delete(ctx context.Context, mg resource.Managed) (managed.ExternalDelete, error) {
+	d, err := e.ExternalClient.Delete(ctx, mg)
+	return d, e.handler.handle(mg, err)
+}
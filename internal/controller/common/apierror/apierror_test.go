@@ -0,0 +1,232 @@
+package apierror
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/event"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource/fake"
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
+)
+
+// eventRecorder records the events it is asked to record.
+type eventRecorder struct {
+	events []event.Event
+}
+
+func (r *eventRecorder) Event(_ runtime.Object, e event.Event) {
+	r.events = append(r.events, e)
+}
+
+func (r *eventRecorder) WithAnnotations(_ ...string) event.Recorder {
+	return r
+}
+
+func statusError(code int) error {
+	return errors.Wrap(&cloudian.StatusError{Method: http.MethodGet, Path: "/group", StatusCode: code}, "cannot get Group")
+}
+
+func throttled(retryAfter time.Duration) error {
+	err := fmt.Errorf("%w: %w", &cloudian.ThrottledError{RetryAfter: retryAfter}, &cloudian.StatusError{Method: http.MethodGet, Path: "/group", StatusCode: http.StatusTooManyRequests})
+	return errors.Wrap(err, "cannot get Group")
+}
+
+func licenseExceeded() error {
+	err := fmt.Errorf("%w: %w", cloudian.ErrLicenseExceeded, &cloudian.StatusError{Method: http.MethodPut, Path: "/user", StatusCode: http.StatusForbidden})
+	return errors.Wrap(err, "cannot create User")
+}
+
+func keyQuotaExceeded() error {
+	err := fmt.Errorf("%w: %w", cloudian.ErrKeyQuotaExceeded, &cloudian.StatusError{Method: http.MethodPut, Path: "/user/credentials", StatusCode: http.StatusBadRequest})
+	return errors.Wrap(err, "cannot create AccessKey")
+}
+
+func groupNotFound() error {
+	err := fmt.Errorf("%w: %w", cloudian.ErrGroupNotFound, &cloudian.StatusError{Method: http.MethodPut, Path: "/user", StatusCode: http.StatusBadRequest})
+	return errors.Wrap(err, "cannot create User")
+}
+
+func TestReason(t *testing.T) {
+	cases := map[string]struct {
+		err    error
+		want   xpv2.ConditionReason
+		wantOk bool
+	}{
+		"Unauthorized":   {err: statusError(http.StatusUnauthorized), want: ReasonInvalidProviderCredentials, wantOk: true},
+		"Forbidden":      {err: statusError(http.StatusForbidden), want: ReasonInvalidProviderCredentials, wantOk: true},
+		"TooMany":        {err: statusError(http.StatusTooManyRequests), want: ReasonThrottled, wantOk: true},
+		"InternalError":  {err: statusError(http.StatusInternalServerError), want: ReasonCloudianUnavailable, wantOk: true},
+		"Unavailable":    {err: statusError(http.StatusServiceUnavailable), want: ReasonCloudianUnavailable, wantOk: true},
+		"BadRequest":     {err: statusError(http.StatusBadRequest)},
+		"License":        {err: licenseExceeded(), want: ReasonLicenseExceeded, wantOk: true},
+		"KeyQuota":       {err: keyQuotaExceeded(), want: ReasonKeyQuotaExceeded, wantOk: true},
+		"KeyQuotaCheck":  {err: errors.Wrap(cloudian.ErrKeyQuotaExceeded, "user has 5 access keys"), want: ReasonKeyQuotaExceeded, wantOk: true},
+		"GroupNotFound":  {err: groupNotFound(), want: ReasonGroupNotFound, wantOk: true},
+		"UserNotFound":   {err: errors.Wrap(cloudian.ErrUserNotFound, "cannot create AccessKey"), want: ReasonUserNotFound, wantOk: true},
+		"NotStatusError": {err: errors.New("boom")},
+		"Nil":            {},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, ok := Reason(tc.err)
+			if got != tc.want || ok != tc.wantOk {
+				t.Errorf("Reason(%v): want %q, %t, got %q, %t", tc.err, tc.want, tc.wantOk, got, ok)
+			}
+		})
+	}
+}
+
+func TestHandler(t *testing.T) {
+	mg := &fake.Managed{ObjectMeta: metav1.ObjectMeta{Namespace: "team", Name: "qa"}}
+
+	cases := map[string]struct {
+		err           error
+		wantErrPrefix string
+		wantEvent     event.Reason
+		wantRequeue   time.Duration
+	}{
+		"InvalidCredentials": {
+			err:           statusError(http.StatusForbidden),
+			wantErrPrefix: "InvalidProviderCredentials: ",
+			wantEvent:     event.Reason(ReasonInvalidProviderCredentials),
+		},
+		"Throttled": {
+			err:           statusError(http.StatusTooManyRequests),
+			wantErrPrefix: "Throttled: ",
+			wantEvent:     event.Reason(ReasonThrottled),
+			wantRequeue:   ThrottledRequeue,
+		},
+		"ThrottledRetryAfter": {
+			err:           throttled(30 * time.Second),
+			wantErrPrefix: "Throttled: ",
+			wantEvent:     event.Reason(ReasonThrottled),
+			wantRequeue:   30 * time.Second,
+		},
+		"LicenseExceeded": {
+			err:           licenseExceeded(),
+			wantErrPrefix: "LicenseExceeded: ",
+			wantEvent:     event.Reason(ReasonLicenseExceeded),
+			wantRequeue:   LicenseExceededRequeue,
+		},
+		"KeyQuotaExceeded": {
+			err:           keyQuotaExceeded(),
+			wantErrPrefix: "KeyQuotaExceeded: ",
+			wantEvent:     event.Reason(ReasonKeyQuotaExceeded),
+			wantRequeue:   KeyQuotaExceededRequeue,
+		},
+		"GroupNotFound": {
+			err:           groupNotFound(),
+			wantErrPrefix: "GroupNotFound: ",
+			wantEvent:     event.Reason(ReasonGroupNotFound),
+			wantRequeue:   NotFoundRequeue,
+		},
+		"Unavailable": {
+			err:           statusError(http.StatusBadGateway),
+			wantErrPrefix: "CloudianUnavailable: ",
+			wantEvent:     event.Reason(ReasonCloudianUnavailable),
+		},
+		"OtherError": {
+			err:           statusError(http.StatusConflict),
+			wantErrPrefix: "cannot get Group: ",
+		},
+		"NoError": {},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			recorder := &eventRecorder{}
+			h := NewHandler(recorder)
+			c := h.Connector(managed.ExternalConnectorFn(func(context.Context, resource.Managed) (managed.ExternalClient, error) {
+				return &managed.ExternalClientFns{
+					ObserveFn: func(context.Context, resource.Managed) (managed.ExternalObservation, error) {
+						return managed.ExternalObservation{}, tc.err
+					},
+				}, nil
+			}))
+
+			var observeErr error
+			r := h.Reconciler(reconcile.Func(func(ctx context.Context, _ reconcile.Request) (reconcile.Result, error) {
+				ext, err := c.Connect(ctx, mg)
+				if err != nil {
+					return reconcile.Result{}, err
+				}
+				_, observeErr = ext.Observe(ctx, mg)
+				return reconcile.Result{Requeue: observeErr != nil}, nil
+			}))
+
+			result, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "team", Name: "qa"}})
+			if err != nil {
+				t.Fatalf("r.Reconcile(...): %v", err)
+			}
+
+			switch {
+			case tc.err == nil && observeErr != nil:
+				t.Errorf("ext.Observe(...): want no error, got %v", observeErr)
+			case tc.err != nil && (observeErr == nil || !strings.HasPrefix(observeErr.Error(), tc.wantErrPrefix)):
+				t.Errorf("ext.Observe(...): want error starting with %q, got %v", tc.wantErrPrefix, observeErr)
+			case tc.err != nil && !errors.Is(observeErr, errors.Cause(tc.err)):
+				t.Errorf("ext.Observe(...): want error wrapping %v, got %v", tc.err, observeErr)
+			}
+
+			var gotEvent event.Reason
+			if len(recorder.events) > 0 {
+				gotEvent = recorder.events[0].Reason
+			}
+			if gotEvent != tc.wantEvent || len(recorder.events) > 1 {
+				t.Errorf("events: want reason %q, got %v", tc.wantEvent, recorder.events)
+			}
+
+			if result.RequeueAfter != tc.wantRequeue {
+				t.Errorf("r.Reconcile(...): want RequeueAfter %s, got %s", tc.wantRequeue, result.RequeueAfter)
+			}
+			if tc.wantRequeue == 0 && tc.err != nil && !result.Requeue {
+				t.Errorf("r.Reconcile(...): want the result of the wrapped reconciler, got %+v", result)
+			}
+		})
+	}
+}
+
+func TestHandlerForgetsThrottling(t *testing.T) {
+	h := NewHandler(&eventRecorder{})
+	mg := &fake.Managed{ObjectMeta: metav1.ObjectMeta{Name: "qa"}}
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: "qa"}}
+
+	throttled := true
+	c := h.Connector(managed.ExternalConnectorFn(func(context.Context, resource.Managed) (managed.ExternalClient, error) {
+		return &managed.ExternalClientFns{
+			ObserveFn: func(context.Context, resource.Managed) (managed.ExternalObservation, error) {
+				if throttled {
+					return managed.ExternalObservation{}, statusError(http.StatusTooManyRequests)
+				}
+				return managed.ExternalObservation{}, statusError(http.StatusConflict)
+			},
+		}, nil
+	}))
+	r := h.Reconciler(reconcile.Func(func(ctx context.Context, _ reconcile.Request) (reconcile.Result, error) {
+		ext, _ := c.Connect(ctx, mg)
+		_, err := ext.Observe(ctx, mg)
+		return reconcile.Result{Requeue: err != nil}, nil
+	}))
+
+	if got, _ := r.Reconcile(context.Background(), req); got.RequeueAfter != ThrottledRequeue {
+		t.Fatalf("r.Reconcile(...): want RequeueAfter %s while throttled, got %+v", ThrottledRequeue, got)
+	}
+	throttled = false
+	if got, _ := r.Reconcile(context.Background(), req); got.RequeueAfter != 0 || !got.Requeue {
+		t.Errorf("r.Reconcile(...): want the default requeue once no longer throttled, got %+v", got)
+	}
+}
@@ -1,10 +1,211 @@
 package common
 
-import "github.com/statnett/provider-cloudian/internal/sdk/cloudian"
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
 
-func NewCloudianService(providerConfigEndpoint string, authHeader string) (*cloudian.Client, error) {
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	pcv1alpha1common "github.com/statnett/provider-cloudian/apis/common/providerconfig/v1alpha1"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
+)
+
+const (
+	errGetAuthHeader = "cannot get auth header"
+	errGetCABundle   = "cannot get CA bundle"
+	errParseCABundle = "cannot parse CA bundle: no PEM encoded certificates found"
+	errUseCABundle   = "cannot use the CA bundle in %s"
+
+	errEndpoint       = "cannot use endpoint"
+	errRegionEndpoint = "cannot use endpoint of region %s"
+)
+
+// Credentials are the secret values a ProviderConfig refers to.
+type Credentials struct {
+	AuthHeader string
+	CABundle   []byte
+}
+
+// ExtractCredentials reads the credentials referred to by a ProviderConfig,
+// from any of the sources of xpv2.CommonCredentialSelectors. Trailing newlines
+// are trimmed from the auth header, as mounted files and secrets created from
+// them usually end with one, which would make the header invalid.
+func ExtractCredentials(ctx context.Context, kube client.Client, spec pcv1alpha1common.ProviderConfigSpec) (Credentials, error) {
+	cd := spec.AuthHeader
+	authHeader, err := resource.CommonCredentialExtractor(ctx, cd.Source, kube, cd.CommonCredentialSelectors)
+	if err != nil {
+		return Credentials{}, errors.Wrap(err, errGetAuthHeader)
+	}
+	creds := Credentials{AuthHeader: strings.TrimRight(string(authHeader), "\r\n")}
+
+	ca := spec.CABundle
+	if ca == nil {
+		return creds, nil
+	}
+	data := []byte(ca.PEM)
+	if ca.PEM == "" {
+		data, err = resource.CommonCredentialExtractor(ctx, ca.Source, kube, ca.CommonCredentialSelectors)
+		if err != nil {
+			return Credentials{}, errors.Wrap(err, errGetCABundle)
+		}
+	}
+	creds.CABundle, err = normalizeCABundle(data)
+	if err != nil {
+		return Credentials{}, errors.Wrapf(err, errUseCABundle, caBundleSource(ca))
+	}
+	return creds, nil
+}
+
+// normalizeCABundle returns the PEM encoded certificates of a CA bundle,
+// decoding it first when it is base64 encoded PEM, as when the PEM of a
+// secret was encoded once more than the data of secrets are.
+func normalizeCABundle(data []byte) ([]byte, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, errors.New("the CA bundle is empty")
+	}
+	if hasCertificates(data) {
+		return data, nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(string(data)), ""))
+	if err != nil {
+		return nil, errors.New("found neither PEM encoded certificates nor base64 encoded PEM")
+	}
+	if !hasCertificates(decoded) {
+		return nil, errors.New("found no PEM encoded certificates in the base64 decoded CA bundle")
+	}
+	return decoded, nil
+}
+
+func hasCertificates(pem []byte) bool {
+	return x509.NewCertPool().AppendCertsFromPEM(pem)
+}
+
+// caBundleSource describes where a CA bundle was read from.
+func caBundleSource(ca *pcv1alpha1common.CABundle) string {
+	switch {
+	case ca.PEM != "":
+		return "caBundle.pem"
+	case ca.Source == xpv2.CredentialsSourceSecret && ca.SecretRef != nil:
+		return fmt.Sprintf("key %q of secret %s/%s", ca.SecretRef.Key, ca.SecretRef.Namespace, ca.SecretRef.Name)
+	case ca.Source == xpv2.CredentialsSourceFilesystem && ca.Fs != nil:
+		return fmt.Sprintf("file %s", ca.Fs.Path)
+	case ca.Source == xpv2.CredentialsSourceEnvironment && ca.Env != nil:
+		return fmt.Sprintf("environment variable %s", ca.Env.Name)
+	default:
+		return fmt.Sprintf("source %s", ca.Source)
+	}
+}
+
+func NewCloudianService(spec pcv1alpha1common.ProviderConfigSpec, creds Credentials) (*cloudian.Client, error) {
+	opts := ClientOptions(spec)
+	if len(creds.CABundle) > 0 {
+		if !hasCertificates(creds.CABundle) {
+			return nil, errors.New(errParseCABundle)
+		}
+		opts = append(opts, cloudian.WithCACert(creds.CABundle))
+	}
+
+	// Each region has a client of its own, sharing the options of the
+	// default one.
+	regionOpts := slices.Clone(opts)
+	for region, endpoint := range spec.Regions {
+		baseURL, err := cloudian.BaseURL(endpoint, spec.PathPrefix)
+		if err != nil {
+			return nil, errors.Wrapf(err, errRegionEndpoint, region)
+		}
+		opts = append(opts, cloudian.WithRegion(region, cloudian.NewClient(baseURL, creds.AuthHeader, regionOpts...)))
+	}
+
+	baseURL, err := cloudian.BaseURL(spec.Endpoint, spec.PathPrefix)
+	if err != nil {
+		return nil, errors.Wrap(err, errEndpoint)
+	}
 	return cloudian.NewClient(
-		providerConfigEndpoint,
-		authHeader,
+		baseURL,
+		creds.AuthHeader,
+		opts...,
 	), nil
 }
+
+// GroupCacheTTL is how long each client caches the groups it reads. Zero
+// disables the cache. It must be set before the controllers are set up.
+var GroupCacheTTL time.Duration
+
+// ProviderNamespace is the namespace the provider runs in, where it keeps the
+// objects of its own. It must be set before the controllers are set up.
+var ProviderNamespace = "crossplane-system"
+
+// MetricsRegistry is where each client registers the metrics of its requests
+// to Cloudian. Nil disables them. It must be set before the controllers are
+// set up.
+var MetricsRegistry prometheus.Registerer
+
+// TracerProvider records the spans of the requests of each client to Cloudian
+// and of the reconciles they are made by. Nil disables tracing. It must be set
+// before the controllers are set up.
+var TracerProvider trace.TracerProvider
+
+// EndpointOverride is the address, as host:port, that each client connects to
+// instead of the endpoints of the admin API of its ProviderConfig, as when
+// the provider runs out of cluster against Cloudian reached through kubectl
+// port-forward. Requests are still sent to the endpoints, whose hostnames the
+// TLS certificate of Cloudian is verified against. Empty connects to the
+// endpoints. It must be set before the controllers are set up.
+var EndpointOverride string
+
+// ClientOptions returns the Cloudian client options configured by a ProviderConfig.
+func ClientOptions(spec pcv1alpha1common.ProviderConfigSpec) []func(*cloudian.Client) {
+	opts := []func(*cloudian.Client){cloudian.WithRequestIDs()}
+	if spec.RequestsPerSecond != nil {
+		burst := ptr.Deref(spec.RequestBurst, *spec.RequestsPerSecond)
+		opts = append(opts, cloudian.WithRateLimit(float64(*spec.RequestsPerSecond), int(burst)))
+	}
+	if spec.BackgroundRequestPercent != nil {
+		opts = append(opts, cloudian.WithBackgroundBudget(float64(*spec.BackgroundRequestPercent)/100))
+	}
+	if spec.TLS != nil && spec.TLS.ServerName != "" {
+		opts = append(opts, cloudian.WithServerName(spec.TLS.ServerName))
+	}
+	if spec.Retries != nil {
+		backoff := time.Second
+		if spec.Retries.Backoff != nil {
+			backoff = spec.Retries.Backoff.Duration
+		}
+		opts = append(opts, cloudian.WithRetries(int(spec.Retries.Attempts), backoff))
+	}
+	if GroupCacheTTL > 0 {
+		opts = append(opts, cloudian.WithGroupCache(GroupCacheTTL))
+	}
+	if MetricsRegistry != nil {
+		opts = append(opts, cloudian.WithMetrics(MetricsRegistry))
+	}
+	if TracerProvider != nil {
+		opts = append(opts, cloudian.WithTracerProvider(TracerProvider))
+	}
+	if EndpointOverride != "" {
+		opts = append(opts, cloudian.WithDialContext(dialOverride(EndpointOverride)))
+	}
+	return opts
+}
+
+// dialOverride returns a DialContextFunc connecting to `addr` whatever the
+// address asked for.
+func dialOverride(addr string) cloudian.DialContextFunc {
+	var dialer net.Dialer
+	return func(ctx context.Context, network, _ string) (net.Conn, error) {
+		return dialer.DialContext(ctx, network, addr)
+	}
+}
@@ -0,0 +1,271 @@
+package common
+
+import (
+	"context"
+	"encoding/base64"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/test"
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	pcv1alpha1common "github.com/statnett/provider-cloudian/apis/common/providerconfig/v1alpha1"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian/cloudiantest"
+)
+
+const caPEM = `-----BEGIN CERTIFICATE-----
+MIIBhTCCASugAwIBAgIQIRi6zePL6mKjOipn+dNuaTAKBggqhkjOPQQDAjASMRAw
+DgYDVQQKEwdBY21lIENvMB4XDTE3MTAyMDE5NDMwNloXDTE4MTAyMDE5NDMwNlow
+EjEQMA4GA1UEChMHQWNtZSBDbzBZMBMGByqGSM49AgEGCCqGSM49AwEHA0IABD0d
+7VNhbWvZLWPuj/RtHFjvtJBEwOkhbN/BnnE8rnZR8+sbwnc/KhCk3FhnpHZnQz7B
+5aETbbIgmuvewdjvSBSjYzBhMA4GA1UdDwEB/wQEAwICpDATBgNVHSUEDDAKBggr
+BgEFBQcDATAPBgNVHRMBAf8EBTADAQH/MCkGA1UdEQQiMCCCDmxvY2FsaG9zdDo1
+NDUzgg4xMjcuMC4wLjE6NTQ1MzAKBggqhkjOPQQDAgNIADBFAiEA2zpJEPQyz6/l
+Wf86aX6PepsntZv2GYlA5UpabfT2EZICICpJ5h/iI+i341gBmLiAFQOyTDT+/wQc
+6MF9+Yw1Yy0t
+-----END CERTIFICATE-----
+`
+
+func secretCredentials(key string) xpv2.CommonCredentialSelectors {
+	return xpv2.CommonCredentialSelectors{
+		SecretRef: &xpv2.SecretKeySelector{
+			SecretReference: xpv2.SecretReference{Namespace: "crossplane-system", Name: "cloudian"},
+			Key:             key,
+		},
+	}
+}
+
+func TestExtractCredentials(t *testing.T) {
+	encoded := base64.StdEncoding.EncodeToString([]byte(caPEM))
+	kube := &test.MockClient{
+		MockGet: func(_ context.Context, _ client.ObjectKey, obj client.Object) error {
+			obj.(*corev1.Secret).Data = map[string][]byte{
+				"authHeader":      []byte("Basic Zm9vOmJhcg=="),
+				"authHeader.crlf": []byte("Basic Zm9vOmJhcg==\r\n"),
+				"ca.crt":          []byte(caPEM),
+				"ca.b64":          []byte(encoded),
+				"ca.wrapped":      []byte(encoded[:64] + "\n" + encoded[64:] + "\n"),
+				"corrupt":         []byte(strings.Replace(caPEM, "MIIB", "!!!!", 1)),
+				"notPEM":          []byte(base64.StdEncoding.EncodeToString([]byte("not a certificate"))),
+			}
+			return nil
+		},
+	}
+	authHeader := pcv1alpha1common.ProviderCredentials{
+		Source:                    xpv2.CredentialsSourceSecret,
+		CommonCredentialSelectors: secretCredentials("authHeader"),
+	}
+	fromSecret := func(key string) *pcv1alpha1common.CABundle {
+		return &pcv1alpha1common.CABundle{
+			Source:                    xpv2.CredentialsSourceSecret,
+			CommonCredentialSelectors: secretCredentials(key),
+		}
+	}
+	fromFile := func(content string) *pcv1alpha1common.CABundle {
+		path := filepath.Join(t.TempDir(), "ca.crt")
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return &pcv1alpha1common.CABundle{
+			Source:                    xpv2.CredentialsSourceFilesystem,
+			CommonCredentialSelectors: xpv2.CommonCredentialSelectors{Fs: &xpv2.FsSelector{Path: path}},
+		}
+	}
+
+	cases := map[string]struct {
+		authHeader *pcv1alpha1common.ProviderCredentials
+		caBundle   *pcv1alpha1common.CABundle
+		want       Credentials
+		wantErr    string
+	}{
+		"NoCABundle": {
+			want: Credentials{AuthHeader: "Basic Zm9vOmJhcg=="},
+		},
+		"TrailingNewlineAuthHeader": {
+			authHeader: &pcv1alpha1common.ProviderCredentials{
+				Source:                    xpv2.CredentialsSourceSecret,
+				CommonCredentialSelectors: secretCredentials("authHeader.crlf"),
+			},
+			want: Credentials{AuthHeader: "Basic Zm9vOmJhcg=="},
+		},
+		"InlineCABundle": {
+			caBundle: &pcv1alpha1common.CABundle{PEM: caPEM},
+			want:     Credentials{AuthHeader: "Basic Zm9vOmJhcg==", CABundle: []byte(caPEM)},
+		},
+		"SecretCABundle": {
+			caBundle: fromSecret("ca.crt"),
+			want:     Credentials{AuthHeader: "Basic Zm9vOmJhcg==", CABundle: []byte(caPEM)},
+		},
+		"Base64SecretCABundle": {
+			caBundle: fromSecret("ca.b64"),
+			want:     Credentials{AuthHeader: "Basic Zm9vOmJhcg==", CABundle: []byte(caPEM)},
+		},
+		"WrappedBase64SecretCABundle": {
+			caBundle: fromSecret("ca.wrapped"),
+			want:     Credentials{AuthHeader: "Basic Zm9vOmJhcg==", CABundle: []byte(caPEM)},
+		},
+		"FileCABundle": {
+			caBundle: fromFile(caPEM),
+			want:     Credentials{AuthHeader: "Basic Zm9vOmJhcg==", CABundle: []byte(caPEM)},
+		},
+		"Base64FileCABundle": {
+			caBundle: fromFile(encoded),
+			want:     Credentials{AuthHeader: "Basic Zm9vOmJhcg==", CABundle: []byte(caPEM)},
+		},
+		"CorruptSecretCABundle": {
+			caBundle: fromSecret("corrupt"),
+			wantErr:  `cannot use the CA bundle in key "corrupt" of secret crossplane-system/cloudian: found neither PEM encoded certificates nor base64 encoded PEM`,
+		},
+		"Base64NotPEM": {
+			caBundle: fromSecret("notPEM"),
+			wantErr:  `cannot use the CA bundle in key "notPEM" of secret crossplane-system/cloudian: found no PEM encoded certificates in the base64 decoded CA bundle`,
+		},
+		"MissingKey": {
+			caBundle: fromSecret("missing"),
+			wantErr:  `cannot use the CA bundle in key "missing" of secret crossplane-system/cloudian: the CA bundle is empty`,
+		},
+		"CorruptInlineCABundle": {
+			caBundle: &pcv1alpha1common.CABundle{PEM: "not a certificate"},
+			wantErr:  "cannot use the CA bundle in caBundle.pem",
+		},
+		"MissingFile": {
+			caBundle: &pcv1alpha1common.CABundle{
+				Source:                    xpv2.CredentialsSourceFilesystem,
+				CommonCredentialSelectors: xpv2.CommonCredentialSelectors{Fs: &xpv2.FsSelector{Path: filepath.Join(t.TempDir(), "missing")}},
+			},
+			wantErr: "cannot get CA bundle",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			spec := pcv1alpha1common.ProviderConfigSpec{AuthHeader: authHeader, CABundle: tc.caBundle}
+			if tc.authHeader != nil {
+				spec.AuthHeader = *tc.authHeader
+			}
+			got, err := ExtractCredentials(context.Background(), kube, spec)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("ExtractCredentials(...): want error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ExtractCredentials(...): %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("ExtractCredentials(...): -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewCloudianServiceInvalidCABundle(t *testing.T) {
+	spec := pcv1alpha1common.ProviderConfigSpec{Endpoint: "https://cloudian.example.com:19443"}
+	_, err := NewCloudianService(spec, Credentials{CABundle: []byte("not a certificate")})
+	if err == nil {
+		t.Error("NewCloudianService(...): expected error for invalid CA bundle")
+	}
+
+	if _, err := NewCloudianService(spec, Credentials{CABundle: []byte(caPEM)}); err != nil {
+		t.Errorf("NewCloudianService(...): %v", err)
+	}
+}
+
+func TestNewCloudianServiceInvalidEndpoint(t *testing.T) {
+	cases := map[string]struct {
+		spec    pcv1alpha1common.ProviderConfigSpec
+		wantErr string
+	}{
+		"NoScheme": {
+			spec:    pcv1alpha1common.ProviderConfigSpec{Endpoint: "cloudian.example.com:19443"},
+			wantErr: "cannot use endpoint: ",
+		},
+		"InvalidRegion": {
+			spec: pcv1alpha1common.ProviderConfigSpec{
+				Endpoint: "https://cloudian.example.com:19443",
+				Regions:  map[string]string{"north": "https://cloudian north.example.com"},
+			},
+			wantErr: "cannot use endpoint of region north: ",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewCloudianService(tc.spec, Credentials{})
+			if err == nil || !strings.HasPrefix(err.Error(), tc.wantErr) {
+				t.Errorf("NewCloudianService(...): want error starting with %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestRetries(t *testing.T) {
+	srv := cloudiantest.NewFakeServer(t)
+	srv.AddGroup("QA")
+
+	cases := map[string]struct {
+		retries *pcv1alpha1common.RetryConfig
+		wantErr bool
+	}{
+		"Unspecified": {
+			wantErr: true,
+		},
+		"Retried": {
+			retries: &pcv1alpha1common.RetryConfig{Attempts: 2, Backoff: &metav1.Duration{Duration: time.Millisecond}},
+		},
+		"TooFewAttempts": {
+			retries: &pcv1alpha1common.RetryConfig{Attempts: 1},
+			wantErr: true,
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			svc, err := NewCloudianService(pcv1alpha1common.ProviderConfigSpec{Endpoint: srv.URL, Retries: tc.retries}, Credentials{})
+			if err != nil {
+				t.Fatalf("NewCloudianService(...): %v", err)
+			}
+			srv.FailNext(1)
+			if _, err := svc.GetGroup(context.Background(), "QA"); (err != nil) != tc.wantErr {
+				t.Errorf("GetGroup() after a failure: want error %t, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestEndpointOverride(t *testing.T) {
+	srv := cloudiantest.NewFakeServer(t)
+	srv.AddGroup("QA")
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	EndpointOverride = u.Host
+	t.Cleanup(func() { EndpointOverride = "" })
+
+	spec := pcv1alpha1common.ProviderConfigSpec{
+		Endpoint: "http://cloudian.invalid:19443",
+		Regions:  map[string]string{"region2": "http://cloudian-region2.invalid:19443"},
+	}
+	svc, err := NewCloudianService(spec, Credentials{})
+	if err != nil {
+		t.Fatalf("NewCloudianService(...): %v", err)
+	}
+	if _, err := svc.GetGroup(context.Background(), "QA"); err != nil {
+		t.Errorf("GetGroup(): want the endpoint overridden, got %v", err)
+	}
+	regional, err := svc.ForRegion("region2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := regional.GetGroup(context.Background(), "QA"); err != nil {
+		t.Errorf("GetGroup() in region2: want the endpoint of the region overridden, got %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+// Package conflict detects external systems that keep changing back the
+// parameters the provider applies to Cloudian, so that the provider stops
+// silently updating them every poll and tells the operator instead.
+package conflict
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/event"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	userv1alpha1common "github.com/statnett/provider-cloudian/apis/common/user/v1alpha1"
+)
+
+const (
+	// MaxReverts is how many reverts are kept in the status.
+	MaxReverts = 5
+	// Threshold is how many reverts within the Window are a conflict.
+	Threshold = 3
+	// Window is how long a revert counts towards the Threshold.
+	Window = 7 * 24 * time.Hour
+	// SlowdownFactor multiplies the poll interval of conflicting resources.
+	SlowdownFactor = 4
+)
+
+const (
+	// TypeExternalConflict indicates whether an external system keeps
+	// changing back what the provider applies.
+	TypeExternalConflict xpv2.ConditionType = "ExternalConflict"
+	// ReasonExternalConflictDetected means the applied parameters were
+	// changed back at least Threshold times within the Window.
+	ReasonExternalConflictDetected xpv2.ConditionReason = "ExternalConflictDetected"
+	// ReasonNoExternalConflict means the applied parameters are no longer
+	// being changed back.
+	ReasonNoExternalConflict xpv2.ConditionReason = "NoExternalConflict"
+)
+
+// Hash identifies the supplied parameters. Parameters that cannot be encoded
+// have no hash, and are never found reverted.
+func Hash(params any) string {
+	b, err := json.Marshal(params)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:8])
+}
+
+// Applied records that the parameters with the supplied hash were applied.
+func Applied(r **userv1alpha1common.Reverts, hash string) {
+	if *r == nil {
+		*r = &userv1alpha1common.Reverts{}
+	}
+	(*r).AppliedHash = hash
+}
+
+// Observe records whether the parameters with the supplied hash, which are
+// not `upToDate`, were changed back since they were applied. Each applied
+// parameters count once. Reverts of earlier parameters are forgotten when
+// other parameters are desired.
+func Observe(r *userv1alpha1common.Reverts, hash string, upToDate bool, now time.Time) {
+	if r == nil || r.AppliedHash == "" {
+		return
+	}
+	if r.AppliedHash != hash {
+		r.AppliedHash = ""
+		r.Times = nil
+		return
+	}
+	if upToDate {
+		return
+	}
+	r.AppliedHash = ""
+	r.Times = append(r.Times, metav1.NewTime(now))
+	if len(r.Times) > MaxReverts {
+		r.Times = r.Times[len(r.Times)-MaxReverts:]
+	}
+}
+
+// Recent returns how many reverts are within the Window.
+func Recent(r *userv1alpha1common.Reverts, now time.Time) int {
+	if r == nil {
+		return 0
+	}
+	n := 0
+	for _, t := range r.Times {
+		if now.Sub(t.Time) < Window {
+			n++
+		}
+	}
+	return n
+}
+
+// Detected returns a condition indicating that `n` reverts were found within
+// the Window.
+func Detected(n int) xpv2.Condition {
+	return xpv2.Condition{
+		Type:               TypeExternalConflict,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonExternalConflictDetected,
+		Message:            fmt.Sprintf("changed back outside the provider %d times in %d days after being updated", n, Window/(24*time.Hour)),
+	}
+}
+
+// Resolved returns a condition indicating that no conflict is detected.
+func Resolved() xpv2.Condition {
+	return xpv2.Condition{
+		Type:               TypeExternalConflict,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonNoExternalConflict,
+	}
+}
+
+// SetCondition sets the ExternalConflict condition of the managed resource
+// from its reverts, and returns whether a conflict was newly detected. The
+// condition is only added once a conflict is detected.
+func SetCondition(mg resource.Managed, r *userv1alpha1common.Reverts, now time.Time) bool {
+	was := mg.GetCondition(TypeExternalConflict).Status == corev1.ConditionTrue
+	n := Recent(r, now)
+	switch {
+	case n >= Threshold:
+		mg.SetConditions(Detected(n))
+		return !was
+	case was:
+		mg.SetConditions(Resolved())
+	}
+	return false
+}
+
+// Warning returns a warning event about the conflict of a condition returned
+// by Detected.
+func Warning(c xpv2.Condition) event.Event {
+	return event.Warning(event.Reason(ReasonExternalConflictDetected), errors.New(c.Message))
+}
+
+// PollIntervalHook polls resources with a detected conflict SlowdownFactor
+// times less often, so that the provider fights the external system less.
+func PollIntervalHook(mg resource.Managed, pollInterval time.Duration) time.Duration {
+	if mg.GetCondition(TypeExternalConflict).Status == corev1.ConditionTrue {
+		return pollInterval * SlowdownFactor
+	}
+	return pollInterval
+}
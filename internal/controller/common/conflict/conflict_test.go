@@ -0,0 +1,113 @@
+package conflict
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	userv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/user/v1alpha1"
+	userv1alpha1common "github.com/statnett/provider-cloudian/apis/common/user/v1alpha1"
+)
+
+// A step is a poll of a resource: Observe finds it up to date or not, and
+// Update applies the desired parameters when it is not.
+type step struct {
+	hash     string
+	upToDate bool
+	after    time.Duration
+}
+
+func TestObserve(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		steps  []step
+		want   int
+	}{
+		"Reverted": {
+			reason: "Parameters found changed back after every update should count every time.",
+			steps:  []step{{hash: "a"}, {hash: "a"}, {hash: "a"}, {hash: "a"}},
+			want:   3,
+		},
+		"Kept": {
+			reason: "Parameters that stay applied should not count.",
+			steps:  []step{{hash: "a"}, {hash: "a", upToDate: true}, {hash: "a", upToDate: true}},
+		},
+		"RevertedOnce": {
+			reason: "Parameters changed back once should count once, however long they stay reverted.",
+			steps:  []step{{hash: "a"}, {hash: "a", upToDate: true}, {hash: "a"}},
+			want:   1,
+		},
+		"SpecChanged": {
+			reason: "Reverts of earlier parameters should be forgotten when other parameters are desired.",
+			steps:  []step{{hash: "a"}, {hash: "a"}, {hash: "a"}, {hash: "b"}, {hash: "b"}},
+			want:   1,
+		},
+		"Capped": {
+			reason: "Only the most recent reverts should be kept.",
+			steps:  []step{{hash: "a"}, {hash: "a"}, {hash: "a"}, {hash: "a"}, {hash: "a"}, {hash: "a"}, {hash: "a"}, {hash: "a"}},
+			want:   MaxReverts,
+		},
+		"Expired": {
+			reason: "Reverts older than the window should not count.",
+			steps:  []step{{hash: "a"}, {hash: "a"}, {hash: "a"}, {hash: "a", upToDate: true, after: Window}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var r *userv1alpha1common.Reverts
+			now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+			for _, s := range tc.steps {
+				now = now.Add(time.Minute + s.after)
+				Observe(r, s.hash, s.upToDate, now)
+				if !s.upToDate {
+					Applied(&r, s.hash)
+				}
+			}
+			if got := Recent(r, now); got != tc.want {
+				t.Errorf("\n%s\nRecent(...): want %d, got %d", tc.reason, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestSetCondition(t *testing.T) {
+	now := time.Now()
+	reverts := func(n int) *userv1alpha1common.Reverts {
+		r := &userv1alpha1common.Reverts{}
+		for range n {
+			r.Times = append(r.Times, metav1.NewTime(now))
+		}
+		return r
+	}
+
+	mg := &userv1alpha1cluster.Group{}
+	if SetCondition(mg, reverts(Threshold-1), now) {
+		t.Error("SetCondition(...): want no conflict below the threshold")
+	}
+	if got := mg.GetCondition(TypeExternalConflict); got.Reason != "" {
+		t.Errorf("SetCondition(...): want no condition before a conflict is detected, got %v", got)
+	}
+	if !SetCondition(mg, reverts(Threshold), now) {
+		t.Error("SetCondition(...): want a conflict at the threshold")
+	}
+	if SetCondition(mg, reverts(Threshold+1), now) {
+		t.Error("SetCondition(...): want a conflict detected only once")
+	}
+	if got := PollIntervalHook(mg, time.Minute); got != SlowdownFactor*time.Minute {
+		t.Errorf("PollIntervalHook(...): want %s while conflicting, got %s", SlowdownFactor*time.Minute, got)
+	}
+
+	SetCondition(mg, nil, now)
+	if got := mg.GetCondition(TypeExternalConflict); got.Status != corev1.ConditionFalse || got.Reason != ReasonNoExternalConflict {
+		t.Errorf("SetCondition(...): want the conflict resolved, got %v", got)
+	}
+	if got := PollIntervalHook(mg, time.Minute); got != time.Minute {
+		t.Errorf("PollIntervalHook(...): want %s once resolved, got %s", time.Minute, got)
+	}
+	if got := Warning(Detected(3)); got.Type != "Warning" || got.Reason != "ExternalConflictDetected" {
+		t.Errorf("Warning(...): got %v", got)
+	}
+}
@@ -0,0 +1,308 @@
+// Package connecttest contains fixtures for testing the connectors of the
+// managed resource controllers.
+package connecttest
+
+import (
+	"context"
+	"encoding/pem"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/test"
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	pcv1alpha1common "github.com/statnett/provider-cloudian/apis/common/providerconfig/v1alpha1"
+	controllercommon "github.com/statnett/provider-cloudian/internal/controller/common"
+	"github.com/statnett/provider-cloudian/internal/controller/common/apierror"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
+)
+
+// Fixture values.
+const (
+	ProviderConfigName = "example"
+	SecretNamespace    = "crossplane-system"
+	SecretName         = "cloudian-credentials"
+	AuthHeaderKey      = "authHeader"
+	CABundleKey        = "ca.crt"
+	AuthHeader         = "Basic dXNlcjpwYXNzd29yZA=="
+	AuthHeaderEnv      = "CLOUDIAN_AUTH_HEADER"
+)
+
+// APIErrors are the status codes of each class of admin API error, by the
+// reason they are explained with.
+var APIErrors = map[xpv2.ConditionReason]int{
+	apierror.ReasonInvalidProviderCredentials: http.StatusForbidden,
+	apierror.ReasonThrottled:                  http.StatusTooManyRequests,
+	apierror.ReasonCloudianUnavailable:        http.StatusServiceUnavailable,
+}
+
+// MockGet returns a MockGetFn that gets copies of the supplied objects by
+// namespace, name and type. It returns NotFound for any other object.
+func MockGet(objs ...client.Object) test.MockGetFn {
+	return func(_ context.Context, key client.ObjectKey, obj client.Object) error {
+		for _, o := range objs {
+			if client.ObjectKeyFromObject(o) == key && reflect.TypeOf(o) == reflect.TypeOf(obj) {
+				reflect.ValueOf(obj).Elem().Set(reflect.ValueOf(o.DeepCopyObject()).Elem())
+				return nil
+			}
+		}
+		return kerrors.NewNotFound(schema.GroupResource{}, key.Name)
+	}
+}
+
+// CABundle returns a PEM encoded self-signed certificate.
+func CABundle(t *testing.T) []byte {
+	t.Helper()
+	srv := httptest.NewTLSServer(nil)
+	defer srv.Close()
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+}
+
+// StatusService returns a Cloudian client whose admin API responds to every
+// request with the supplied status code.
+func StatusService(t *testing.T, code int) *cloudian.Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(code)
+	}))
+	t.Cleanup(srv.Close)
+	return cloudian.NewClient(srv.URL, AuthHeader)
+}
+
+// Secret returns the credentials Secret referred to by ProviderConfigSpec.
+func Secret(caBundle []byte) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: SecretNamespace, Name: SecretName},
+		Data: map[string][]byte{
+			AuthHeaderKey: []byte(AuthHeader),
+			CABundleKey:   caBundle,
+		},
+	}
+}
+
+// ProviderConfigSpec returns a ProviderConfigSpec that reads its auth header
+// from the supplied source, and its CA bundle from Secret.
+func ProviderConfigSpec(source xpv2.CredentialsSource) pcv1alpha1common.ProviderConfigSpec {
+	selector := func(key string) xpv2.CommonCredentialSelectors {
+		return xpv2.CommonCredentialSelectors{SecretRef: &xpv2.SecretKeySelector{
+			SecretReference: xpv2.SecretReference{Namespace: SecretNamespace, Name: SecretName},
+			Key:             key,
+		}}
+	}
+	return pcv1alpha1common.ProviderConfigSpec{
+		Endpoint: "https://cloudian.example.com:19443",
+		AuthHeader: pcv1alpha1common.ProviderCredentials{
+			Source:                    source,
+			CommonCredentialSelectors: selector(AuthHeaderKey),
+		},
+		CABundle: &pcv1alpha1common.CABundle{
+			Source:                    xpv2.CredentialsSourceSecret,
+			CommonCredentialSelectors: selector(CABundleKey),
+		},
+	}
+}
+
+// EnvAuthHeader sets AuthHeaderEnv to AuthHeader for the duration of the test,
+// and returns credentials that read the auth header from it.
+func EnvAuthHeader(t *testing.T) pcv1alpha1common.ProviderCredentials {
+	t.Helper()
+	t.Setenv(AuthHeaderEnv, AuthHeader)
+	return pcv1alpha1common.ProviderCredentials{
+		Source:                    xpv2.CredentialsSourceEnvironment,
+		CommonCredentialSelectors: xpv2.CommonCredentialSelectors{Env: &xpv2.EnvSelector{Name: AuthHeaderEnv}},
+	}
+}
+
+// FileAuthHeader writes the supplied content to a file, as if mounted from a
+// secret, and returns credentials that read the auth header from it.
+func FileAuthHeader(t *testing.T, content string) pcv1alpha1common.ProviderCredentials {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), AuthHeaderKey)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return pcv1alpha1common.ProviderCredentials{
+		Source:                    xpv2.CredentialsSourceFilesystem,
+		CommonCredentialSelectors: xpv2.CommonCredentialSelectors{Fs: &xpv2.FsSelector{Path: path}},
+	}
+}
+
+// NewServiceFn builds the Cloudian client of a connector.
+type NewServiceFn func(uid types.UID, spec pcv1alpha1common.ProviderConfigSpec, creds controllercommon.Credentials) (*cloudian.Client, error)
+
+// A Connector describes the connector of a managed resource controller to
+// TestConnect.
+type Connector struct {
+	// New returns the connector, getting objects from `kube` and building
+	// Cloudian clients with `newService`.
+	New func(kube client.Client, newService NewServiceFn) managed.ExternalConnecter
+	// NewService builds the Cloudian clients, controllercommon's
+	// NewCloudianService if nil.
+	NewService NewServiceFn
+	// Managed is a managed resource of the connector, referring to the
+	// ProviderConfig ProviderConfigName. A namespaced managed resource must
+	// find it in its own namespace.
+	Managed resource.Managed
+	// Other is a managed resource of another kind.
+	Other resource.Managed
+	// ProviderConfig returns the ProviderConfig of Managed with the supplied
+	// spec.
+	ProviderConfig func(spec pcv1alpha1common.ProviderConfigSpec) client.Object
+	// Connected returns whether a connected external client is usable.
+	Connected func(managed.ExternalClient) bool
+	// ErrNotManaged, ErrGetPC and ErrGetCreds are the errors of Connect for
+	// Other, a missing ProviderConfig and missing credentials.
+	ErrNotManaged, ErrGetPC, ErrGetCreds string
+	// Cases are the cases specific to the connector, given the CA bundle of
+	// Secret.
+	Cases func(caBundle []byte) map[string]ConnectCase
+}
+
+// A ConnectCase is a case of TestConnect.
+type ConnectCase struct {
+	Reason string
+	MG     resource.Managed
+	Objs   []client.Object
+	Want   ConnectWant
+}
+
+// ConnectWant is the outcome a ConnectCase wants. An empty Err wants the
+// connector to connect with Creds.
+type ConnectWant struct {
+	Err      string
+	NotFound bool
+	Creds    controllercommon.Credentials
+}
+
+// TestConnect tests that a connector reads its ProviderConfig and the
+// credentials it refers to from every source, and builds a usable external
+// client from them.
+func TestConnect(t *testing.T, c Connector) {
+	t.Helper()
+	caBundle := CABundle(t)
+	pc := func(mutate func(*pcv1alpha1common.ProviderConfigSpec)) client.Object {
+		spec := ProviderConfigSpec(xpv2.CredentialsSourceSecret)
+		mutate(&spec)
+		return c.ProviderConfig(spec)
+	}
+	good := pc(func(*pcv1alpha1common.ProviderConfigSpec) {})
+	creds := controllercommon.Credentials{AuthHeader: AuthHeader, CABundle: caBundle}
+
+	cases := map[string]ConnectCase{
+		"NotManaged": {
+			Reason: "Connect should return an error if the managed resource is of another kind",
+			MG:     c.Other,
+			Want:   ConnectWant{Err: c.ErrNotManaged},
+		},
+		"MissingProviderConfig": {
+			Reason: "Connect should return an error if the ProviderConfig does not exist",
+			MG:     c.Managed,
+			Objs:   []client.Object{Secret(caBundle)},
+			Want:   ConnectWant{Err: c.ErrGetPC, NotFound: true},
+		},
+		"MissingSecret": {
+			Reason: "Connect should return an error if the credentials Secret does not exist",
+			MG:     c.Managed,
+			Objs:   []client.Object{good},
+			Want:   ConnectWant{Err: c.ErrGetCreds, NotFound: true},
+		},
+		"BadCredentialsSource": {
+			Reason: "Connect should return an error if the credentials source is not supported",
+			MG:     c.Managed,
+			Objs: []client.Object{pc(func(spec *pcv1alpha1common.ProviderConfigSpec) {
+				spec.AuthHeader.Source = xpv2.CredentialsSourceInjectedIdentity
+			}), Secret(caBundle)},
+			Want: ConnectWant{Err: c.ErrGetCreds},
+		},
+		"Success": {
+			Reason: "Connect should build a client trusting the CA bundle of the ProviderConfig",
+			MG:     c.Managed,
+			Objs:   []client.Object{good, Secret(caBundle)},
+			Want:   ConnectWant{Creds: creds},
+		},
+		"EnvironmentAuthHeader": {
+			Reason: "Connect should read the auth header from an environment variable",
+			MG:     c.Managed,
+			Objs: []client.Object{pc(func(spec *pcv1alpha1common.ProviderConfigSpec) {
+				spec.AuthHeader = EnvAuthHeader(t)
+			}), Secret(caBundle)},
+			Want: ConnectWant{Creds: creds},
+		},
+		"FilesystemAuthHeader": {
+			Reason: "Connect should read the auth header from a file",
+			MG:     c.Managed,
+			Objs: []client.Object{pc(func(spec *pcv1alpha1common.ProviderConfigSpec) {
+				spec.AuthHeader = FileAuthHeader(t, AuthHeader)
+			}), Secret(caBundle)},
+			Want: ConnectWant{Creds: creds},
+		},
+		"MountedAuthHeader": {
+			Reason: "Connect should trim the trailing newline of an auth header read from a mounted file",
+			MG:     c.Managed,
+			Objs: []client.Object{pc(func(spec *pcv1alpha1common.ProviderConfigSpec) {
+				spec.AuthHeader = FileAuthHeader(t, AuthHeader+"\n")
+			}), Secret(caBundle)},
+			Want: ConnectWant{Creds: creds},
+		},
+	}
+	if ns := c.Managed.GetNamespace(); ns != "" {
+		elsewhere := pc(func(*pcv1alpha1common.ProviderConfigSpec) {})
+		elsewhere.SetNamespace(ns + "-other")
+		cases["ProviderConfigInOtherNamespace"] = ConnectCase{
+			Reason: "Connect should return an error if the ProviderConfig is not in the namespace of the managed resource",
+			MG:     c.Managed,
+			Objs:   []client.Object{elsewhere, Secret(caBundle)},
+			Want:   ConnectWant{Err: c.ErrGetPC, NotFound: true},
+		}
+	}
+	if c.Cases != nil {
+		maps.Copy(cases, c.Cases(caBundle))
+	}
+	newService := c.NewService
+	if newService == nil {
+		newService = func(_ types.UID, spec pcv1alpha1common.ProviderConfigSpec, creds controllercommon.Credentials) (*cloudian.Client, error) {
+			return controllercommon.NewCloudianService(spec, creds)
+		}
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var got controllercommon.Credentials
+			conn := c.New(&test.MockClient{MockGet: MockGet(tc.Objs...)}, func(uid types.UID, spec pcv1alpha1common.ProviderConfigSpec, creds controllercommon.Credentials) (*cloudian.Client, error) {
+				got = creds
+				return newService(uid, spec, creds)
+			})
+			ext, err := conn.Connect(context.Background(), tc.MG)
+			if tc.Want.Err != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tc.Want.Err) || kerrors.IsNotFound(err) != tc.Want.NotFound {
+					t.Fatalf("\n%s\nc.Connect(...): want error %q (not found %t), got %v", tc.Reason, tc.Want.Err, tc.Want.NotFound, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("\n%s\nc.Connect(...): %v", tc.Reason, err)
+			}
+			if !c.Connected(ext) {
+				t.Errorf("\n%s\nc.Connect(...): want a usable external client, got %#v", tc.Reason, ext)
+			}
+			if diff := cmp.Diff(tc.Want.Creds, got); diff != "" {
+				t.Errorf("\n%s\nc.Connect(...): -want credentials, +got credentials:\n%s", tc.Reason, diff)
+			}
+		})
+	}
+}
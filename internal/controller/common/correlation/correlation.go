@@ -0,0 +1,117 @@
+// Package correlation gives each reconcile of a managed resource a
+// correlation ID, shared by the requests it makes to the admin API, the log
+// lines of the reconciler and the events it records, so that they can be
+// found together.
+package correlation
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/event"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/logging"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
+)
+
+// Key is the key of the correlation ID in log lines and the annotation of
+// events carrying it.
+const Key = "correlationId"
+
+// A Tracker keeps the correlation ID of each managed resource of a controller
+// being reconciled. A resource is never reconciled twice at once, so its name
+// identifies the reconcile.
+type Tracker struct {
+	newID func() string
+
+	mu  sync.Mutex
+	ids map[types.NamespacedName]string
+}
+
+// NewTracker returns a Tracker generating request IDs of the SDK as
+// correlation IDs, so that the requests of a reconcile carry it.
+func NewTracker() *Tracker {
+	return &Tracker{newID: cloudian.NewRequestID, ids: map[types.NamespacedName]string{}}
+}
+
+// ID returns the correlation ID of the managed resource being reconciled
+// under the supplied name, if any.
+func (t *Tracker) ID(nn types.NamespacedName) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	id, ok := t.ids[nn]
+	return id, ok
+}
+
+// Reconciler wraps a Reconciler so that each reconcile has a correlation ID,
+// set as the request ID of its context for the SDK to send along.
+func (t *Tracker) Reconciler(r reconcile.Reconciler) reconcile.Reconciler {
+	return reconcile.Func(func(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+		id := t.newID()
+		t.mu.Lock()
+		t.ids[req.NamespacedName] = id
+		t.mu.Unlock()
+		defer func() {
+			t.mu.Lock()
+			delete(t.ids, req.NamespacedName)
+			t.mu.Unlock()
+		}()
+		return r.Reconcile(cloudian.WithRequestID(ctx, id), req)
+	})
+}
+
+// Recorder wraps a Recorder so that the events of a managed resource being
+// reconciled are annotated with its correlation ID.
+func (t *Tracker) Recorder(r event.Recorder) event.Recorder {
+	return &recorder{Recorder: r, tracker: t}
+}
+
+// Logger wraps a Logger so that the log lines of a reconcile include its
+// correlation ID. The managed reconciler adds the reconcile request to its
+// logger as the "request" value, which identifies the reconcile.
+func (t *Tracker) Logger(l logging.Logger) logging.Logger {
+	return &logger{Logger: l, tracker: t}
+}
+
+type recorder struct {
+	event.Recorder
+	tracker *Tracker
+}
+
+func (r *recorder) Event(obj runtime.Object, e event.Event) {
+	if o, ok := obj.(client.Object); ok {
+		if id, ok := r.tracker.ID(client.ObjectKeyFromObject(o)); ok {
+			r.Recorder.WithAnnotations(Key, id).Event(obj, e)
+			return
+		}
+	}
+	r.Recorder.Event(obj, e)
+}
+
+func (r *recorder) WithAnnotations(keysAndValues ...string) event.Recorder {
+	return &recorder{Recorder: r.Recorder.WithAnnotations(keysAndValues...), tracker: r.tracker}
+}
+
+type logger struct {
+	logging.Logger
+	tracker *Tracker
+}
+
+func (l *logger) WithValues(keysAndValues ...any) logging.Logger {
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		req, ok := keysAndValues[i+1].(reconcile.Request)
+		if !ok || keysAndValues[i] != "request" {
+			continue
+		}
+		if id, ok := l.tracker.ID(req.NamespacedName); ok {
+			keysAndValues = append(slices.Clone(keysAndValues), Key, id)
+		}
+		break
+	}
+	return &logger{Logger: l.Logger.WithValues(keysAndValues...), tracker: l.tracker}
+}
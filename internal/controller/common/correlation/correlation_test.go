@@ -0,0 +1,136 @@
+package correlation
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/event"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian/cloudiantest"
+)
+
+// logRecorder records the values of the log lines it is asked to log.
+type logRecorder struct {
+	values []any
+	lines  *[][]any
+}
+
+func (l *logRecorder) Info(_ string, keysAndValues ...any) {
+	*l.lines = append(*l.lines, slices.Concat(l.values, keysAndValues))
+}
+
+func (l *logRecorder) Debug(msg string, keysAndValues ...any) { l.Info(msg, keysAndValues...) }
+
+func (l *logRecorder) WithValues(keysAndValues ...any) logging.Logger {
+	return &logRecorder{values: slices.Concat(l.values, keysAndValues), lines: l.lines}
+}
+
+// eventRecorder records the annotations of the events it is asked to record.
+type eventRecorder struct {
+	annotations map[string]string
+	events      *[]map[string]string
+}
+
+func (r *eventRecorder) Event(runtime.Object, event.Event) {
+	*r.events = append(*r.events, r.annotations)
+}
+
+func (r *eventRecorder) WithAnnotations(keysAndValues ...string) event.Recorder {
+	annotations := map[string]string{}
+	maps.Copy(annotations, r.annotations)
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		annotations[keysAndValues[i]] = keysAndValues[i+1]
+	}
+	return &eventRecorder{annotations: annotations, events: r.events}
+}
+
+// value returns the value of `key` in a log line.
+func value(line []any, key string) any {
+	for i := 0; i+1 < len(line); i += 2 {
+		if line[i] == key {
+			return line[i+1]
+		}
+	}
+	return nil
+}
+
+func TestReconciler(t *testing.T) {
+	var mu sync.Mutex
+	var headers []string
+	srv := cloudiantest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		headers = append(headers, r.Header.Get(cloudian.RequestIDHeader))
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	svc := cloudian.NewClient(srv.URL, "", cloudian.WithRequestIDs())
+
+	var lines [][]any
+	var events []map[string]string
+	tracker := NewTracker()
+	log := tracker.Logger(&logRecorder{lines: &lines})
+	recorder := tracker.Recorder(&eventRecorder{events: &events})
+	mg := &fake.Managed{ObjectMeta: metav1.ObjectMeta{Name: "qa"}}
+
+	// Like the managed reconciler, which logs with the request and records
+	// events of the managed resource.
+	r := tracker.Reconciler(reconcile.Func(func(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+		log := log.WithValues("request", req)
+		_, _ = svc.GetGroup(ctx, "QA")
+		_, _ = svc.GetGroup(ctx, "QA")
+		log.Debug("Observed")
+		recorder.Event(mg, event.Normal("Observed", "observed"))
+		return reconcile.Result{}, nil
+	}))
+
+	req := reconcile.Request{NamespacedName: types.NamespacedName{Name: "qa"}}
+	for range 2 {
+		if _, err := r.Reconcile(context.Background(), req); err != nil {
+			t.Fatalf("r.Reconcile(...): %v", err)
+		}
+	}
+
+	if len(headers) != 4 || len(lines) != 2 || len(events) != 2 {
+		t.Fatalf("r.Reconcile(...): want 4 requests, 2 log lines and 2 events, got %d, %d and %d", len(headers), len(lines), len(events))
+	}
+	for i := range 2 {
+		id := headers[2*i]
+		if id == "" || headers[2*i+1] != id {
+			t.Errorf("reconcile %d: want every request with the same %s, got %q", i, cloudian.RequestIDHeader, headers[2*i:2*i+2])
+		}
+		if got := value(lines[i], Key); got != id {
+			t.Errorf("reconcile %d: want the log line with %s %q, got %v", i, Key, id, got)
+		}
+		if got := events[i][Key]; got != id {
+			t.Errorf("reconcile %d: want the event annotated with %s %q, got %q", i, Key, id, got)
+		}
+	}
+	if headers[0] == headers[2] {
+		t.Errorf("r.Reconcile(...): want each reconcile with its own correlation ID, got %q twice", headers[0])
+	}
+
+	// Outside a reconcile, events are not annotated and requests get fresh
+	// request IDs.
+	recorder.Event(mg, event.Normal("Observed", "observed"))
+	if got, ok := events[2][Key]; ok {
+		t.Errorf("recorder.Event(...): want no correlation ID outside a reconcile, got %q", got)
+	}
+	if _, ok := tracker.ID(req.NamespacedName); ok {
+		t.Error("tracker.ID(...): want the correlation ID forgotten after the reconcile")
+	}
+	_, _ = svc.GetGroup(context.Background(), "QA")
+	if got := headers[4]; got == "" || got == headers[2] {
+		t.Errorf("GetGroup(): want a fresh request ID outside a reconcile, got %q", got)
+	}
+}
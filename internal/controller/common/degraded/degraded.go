@@ -0,0 +1,76 @@
+// Package degraded lets Observe report what it could determine about a
+// Cloudian resource when some of the requests observing it fail, rather than
+// failing altogether while the admin API is partially unavailable.
+package degraded
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// TypeDegradedObservation indicates whether the last observation of the
+	// resource was incomplete, because some of its requests failed.
+	TypeDegradedObservation xpv2.ConditionType = "DegradedObservation"
+	// ReasonSubCallsFailed means some requests of the observation failed,
+	// so the status they fill in may be out of date.
+	ReasonSubCallsFailed xpv2.ConditionReason = "SubCallsFailed"
+	// ReasonObservationComplete means every request of the observation
+	// succeeded again.
+	ReasonObservationComplete xpv2.ConditionReason = "ObservationComplete"
+)
+
+// An Observation collects the requests of an observation that failed. The
+// zero value is an observation where nothing failed.
+type Observation struct {
+	failed []string
+}
+
+// Fail records that the request observing `call` failed with `err`.
+func (o *Observation) Fail(call string, err error) {
+	o.failed = append(o.failed, fmt.Sprintf("%s: %v", call, err))
+}
+
+// Degraded returns whether any request of the observation failed.
+func (o *Observation) Degraded() bool {
+	return len(o.failed) > 0
+}
+
+// SetCondition sets the DegradedObservation condition of the resource from
+// the observation. The condition is only added once an observation is
+// degraded.
+func (o *Observation) SetCondition(cr resource.Conditioned) {
+	switch {
+	case o.Degraded():
+		cr.SetConditions(Failed(o.failed))
+	case cr.GetCondition(TypeDegradedObservation).Status == corev1.ConditionTrue:
+		cr.SetConditions(Complete())
+	}
+}
+
+// Failed returns a condition indicating that the supplied requests of the
+// observation failed.
+func Failed(calls []string) xpv2.Condition {
+	return xpv2.Condition{
+		Type:               TypeDegradedObservation,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonSubCallsFailed,
+		Message:            "status may be out of date, as requests failed: " + strings.Join(calls, "; "),
+	}
+}
+
+// Complete returns a condition indicating that the observation is complete.
+func Complete() xpv2.Condition {
+	return xpv2.Condition{
+		Type:               TypeDegradedObservation,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonObservationComplete,
+	}
+}
@@ -0,0 +1,64 @@
+package degraded
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource/fake"
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/utils/ptr"
+)
+
+func TestSetCondition(t *testing.T) {
+	cases := map[string]struct {
+		before     *xpv2.Condition
+		failed     []string
+		wantStatus corev1.ConditionStatus
+		wantReason xpv2.ConditionReason
+		wantMsg    string
+	}{
+		"Complete": {},
+		"Degraded": {
+			failed:     []string{"access keys", "buckets"},
+			wantStatus: corev1.ConditionTrue,
+			wantReason: ReasonSubCallsFailed,
+			wantMsg:    "status may be out of date, as requests failed: access keys: boom; buckets: boom",
+		},
+		"Recovered": {
+			before:     ptr.To(Failed([]string{"buckets: boom"})),
+			wantStatus: corev1.ConditionFalse,
+			wantReason: ReasonObservationComplete,
+		},
+		"StillComplete": {
+			before:     ptr.To(Complete()),
+			wantStatus: corev1.ConditionFalse,
+			wantReason: ReasonObservationComplete,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cr := &fake.Managed{}
+			if tc.before != nil {
+				cr.SetConditions(*tc.before)
+			}
+			var o Observation
+			for _, call := range tc.failed {
+				o.Fail(call, errors.New("boom"))
+			}
+			o.SetCondition(cr)
+
+			got := cr.GetCondition(TypeDegradedObservation)
+			if tc.before == nil && tc.wantStatus == "" {
+				if got.Reason != "" {
+					t.Errorf("o.SetCondition(...): want no condition, got %+v", got)
+				}
+				return
+			}
+			if got.Status != tc.wantStatus || got.Reason != tc.wantReason || got.Message != tc.wantMsg {
+				t.Errorf("o.SetCondition(...): want %s %s %q, got %s %s %q", tc.wantStatus, tc.wantReason, tc.wantMsg, got.Status, got.Reason, got.Message)
+			}
+		})
+	}
+}
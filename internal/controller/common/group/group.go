@@ -1,17 +1,137 @@
 package group
 
 import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/xcrd"
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	userv1alpha1common "github.com/statnett/provider-cloudian/apis/common/user/v1alpha1"
 	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
 )
 
-func IsUpToDate(name string, desired userv1alpha1common.GroupParameters, observed cloudian.Group) bool {
-	return NewCloudianGroup(name, desired) == observed
+const (
+	// TypeDeletionBlocked indicates whether Cloudian refuses to delete the
+	// group of a Group.
+	TypeDeletionBlocked xpv2.ConditionType = "DeletionBlocked"
+
+	// ReasonGroupNotEmpty means Cloudian refuses to delete the group, as it
+	// still has users.
+	ReasonGroupNotEmpty xpv2.ConditionReason = "GroupNotEmpty"
+)
+
+const (
+	errUpdateManaged = "cannot update managed resource"
+	errGroupNotEmpty = "group still has users; delete them, or set recursiveDelete to delete them along with the group"
+)
+
+// IsUpToDate returns whether the observed group matches the desired
+// parameters. LDAP settings and S3 endpoints left unset in the parameters
+// match any observed value, as do the `ignored` parameters. `allEndpoints`
+// are the S3 endpoints of the cluster, if known, so that allowing each of
+// them matches allowing all.
+func IsUpToDate(name string, desired userv1alpha1common.GroupParameters, observed cloudian.Group, allEndpoints cloudian.S3Endpoints, ignored []string) bool {
+	return MergeObserved(name, desired, observed, ignored).EqualIn(observed, allEndpoints)
+}
+
+// ClusterEndpoints returns the S3 endpoints of the cluster to compare groups
+// with: those of the ProviderConfig when it lists them, otherwise those the
+// system configuration of Cloudian lists. Groups are compared without them
+// when neither is known, as when the credentials may not read the system
+// configuration, rather than failing to observe the group.
+func ClusterEndpoints(ctx context.Context, svc *cloudian.Client, configured []string) cloudian.S3Endpoints {
+	if len(configured) > 0 {
+		return cloudian.UniformS3Endpoints(configured)
+	}
+	endpoints, err := svc.GetS3Endpoints(ctx)
+	if err != nil {
+		return cloudian.S3Endpoints{}
+	}
+	return endpoints
+}
+
+// MergeObserved returns the group to update the observed group with. LDAP
+// settings and S3 endpoints left unset in the parameters keep their observed values, so that
+// LDAP configured outside the provider is not cleared when late
+// initialization is disabled by the management policies. The `ignored`
+// parameters keep their observed values too.
+func MergeObserved(name string, desired userv1alpha1common.GroupParameters, observed cloudian.Group, ignored []string) cloudian.Group {
+	merged := *desired.DeepCopy()
+	LateInitialize(&merged, observed)
+	g := NewCloudianGroup(name, merged)
+	ignore(&g, observed, ignored)
+	return g
+}
+
+// LateInitialize sets the LDAP settings and S3 endpoints left unset in the
+// parameters to their observed values, and returns whether any were set. S3
+// endpoints are only late initialized when the group is restricted to some.
+func LateInitialize(gp *userv1alpha1common.GroupParameters, observed cloudian.Group) bool {
+	li := false
+	lateInit := func(field **string, value string) {
+		if *field == nil && value != "" {
+			*field = ptr.To(value)
+			li = true
+		}
+	}
+
+	if gp.LDAPEnabled == nil && observed.LDAPEnabled {
+		gp.LDAPEnabled = ptr.To(true)
+		li = true
+	}
+	lateInit(&gp.LDAPGroup, observed.LDAPGroup)
+	lateInit(&gp.LDAPMatchAttribute, observed.LDAPMatchAttribute)
+	lateInit(&gp.LDAPSearch, observed.LDAPSearch)
+	lateInit(&gp.LDAPSearchUserBase, observed.LDAPSearchUserBase)
+	lateInit(&gp.LDAPServerURL, observed.LDAPServerURL)
+	lateInit(&gp.LDAPUserDNTemplate, observed.LDAPUserDNTemplate)
+	if gp.S3Endpoints == nil && !allEndpoints(observed) {
+		gp.S3Endpoints = &userv1alpha1common.S3Endpoints{
+			HTTP:    slices.Clone(observed.S3EndpointsHTTP),
+			HTTPS:   slices.Clone(observed.S3EndpointsHTTPS),
+			Website: slices.Clone(observed.S3WebSiteEndpoints),
+		}
+		li = true
+	}
+	return li
+}
+
+func allEndpoints(g cloudian.Group) bool {
+	return cloudian.EqualEndpoints(g.S3EndpointsHTTP, nil) &&
+		cloudian.EqualEndpoints(g.S3EndpointsHTTPS, nil) &&
+		cloudian.EqualEndpoints(g.S3WebSiteEndpoints, nil)
+}
+
+// Observe records the observed group in the status of a Group, so that
+// Groups that only observe their group are useful to reference.
+func Observe(obs *userv1alpha1common.GroupObservation, groupID string, observed cloudian.Group) {
+	obs.GroupID = groupID
+	obs.GroupName = observed.GroupName
+	obs.Active = observed.Active
+	obs.S3Endpoints = &userv1alpha1common.S3Endpoints{
+		HTTP:    cloudian.NormalizeEndpoints(observed.S3EndpointsHTTP),
+		HTTPS:   cloudian.NormalizeEndpoints(observed.S3EndpointsHTTPS),
+		Website: cloudian.NormalizeEndpoints(observed.S3WebSiteEndpoints),
+	}
+	obs.LDAPEnabled = observed.LDAPEnabled
+	obs.LDAPGroup = observed.LDAPGroup
+	obs.LDAPServerURL = observed.LDAPServerURL
 }
 
 func NewCloudianGroup(name string, gp userv1alpha1common.GroupParameters) cloudian.Group {
+	var endpoints userv1alpha1common.S3Endpoints
+	if gp.S3Endpoints != nil {
+		endpoints = *gp.S3Endpoints
+	}
 	return cloudian.Group{
 		Active:             gp.Active,
 		GroupID:            name,
@@ -23,5 +143,134 @@ func NewCloudianGroup(name string, gp userv1alpha1common.GroupParameters) cloudi
 		LDAPSearchUserBase: ptr.Deref(gp.LDAPSearchUserBase, ""),
 		LDAPServerURL:      ptr.Deref(gp.LDAPServerURL, ""),
 		LDAPUserDNTemplate: ptr.Deref(gp.LDAPUserDNTemplate, ""),
+		S3EndpointsHTTP:    endpoints.HTTP,
+		S3EndpointsHTTPS:   endpoints.HTTPS,
+		S3WebSiteEndpoints: endpoints.Website,
+	}
+}
+
+// MaxGroupNameLength is the longest group name Cloudian accepts.
+const MaxGroupNameLength = 64
+
+// DefaultName returns the name of the group of managed resource `mg` when
+// its parameters leave it unset: the name of the managed resource, followed
+// by a managed-by marker naming the claim it was created for, if any, so that
+// admins can tell which Kubernetes object owns the group. It is cut to
+// MaxGroupNameLength.
+func DefaultName(mg metav1.Object) string {
+	name := mg.GetName()
+	labels := mg.GetLabels()
+	if claim := labels[xcrd.LabelKeyClaimName]; claim != "" {
+		name += " managed-by=crossplane/" + labels[xcrd.LabelKeyClaimNamespace] + "/" + claim
+	}
+	if len(name) > MaxGroupNameLength {
+		name = name[:MaxGroupNameLength]
+	}
+	return name
+}
+
+// Desired returns the parameters to apply to the group of managed resource
+// `mg`. When `defaultName` is set, as by the DefaultGroupNames of the
+// ProviderConfig, a GroupName left unset defaults to the DefaultName of the
+// managed resource. An explicit GroupName is always kept.
+func Desired(mg metav1.Object, params userv1alpha1common.GroupParameters, defaultName bool) userv1alpha1common.GroupParameters {
+	if defaultName && params.GroupName == "" {
+		params.GroupName = DefaultName(mg)
+	}
+	return params
+}
+
+// A Group is a managed resource with a Cloudian group ID.
+type Group interface {
+	resource.Managed
+	GetGroupID() string
+	GetGroupParameters() *userv1alpha1common.GroupParameters
+}
+
+// ResolveGroupID reconciles the external-name and spec groupId of a group,
+// returning the ID both should be set to. The managed resource name is used
+// when neither is set. Conflicting values are rejected.
+func ResolveGroupID(name, externalName, groupID string) (string, error) {
+	switch {
+	case externalName != "" && groupID != "" && externalName != groupID:
+		return "", fmt.Errorf("spec.forProvider.groupId %q conflicts with external-name %q", groupID, externalName)
+	case groupID != "":
+		return groupID, nil
+	case externalName != "":
+		return externalName, nil
+	default:
+		return name, nil
+	}
+}
+
+// GroupIDInitializer defaults the external-name and spec groupId of a group
+// from each other, so that both always hold the same Cloudian group ID.
+type GroupIDInitializer struct {
+	kube client.Client
+}
+
+// NewGroupIDInitializer returns a new GroupIDInitializer.
+func NewGroupIDInitializer(kube client.Client) *GroupIDInitializer {
+	return &GroupIDInitializer{kube: kube}
+}
+
+// Initialize the given group.
+func (i *GroupIDInitializer) Initialize(ctx context.Context, mg resource.Managed) error {
+	g, ok := mg.(Group)
+	if !ok {
+		return errors.New("managed resource does not have a group ID")
+	}
+
+	externalName := meta.GetExternalName(g)
+	params := g.GetGroupParameters()
+	id, err := ResolveGroupID(g.GetName(), externalName, params.GroupID)
+	if err != nil {
+		return err
+	}
+	if id == externalName && id == params.GroupID {
+		return nil
+	}
+
+	meta.SetExternalName(g, id)
+	params.GroupID = id
+
+	return errors.Wrap(i.kube.Update(ctx, g), errUpdateManaged)
+}
+
+// NotEmpty sets a condition on a Group whose group Cloudian refuses to delete
+// as it still has users, and returns the error of the deletion explaining
+// how to unblock it. The deletion is retried until the group is empty.
+func NotEmpty(cr resource.Conditioned, err error) error {
+	cr.SetConditions(xpv2.Condition{
+		Type:               TypeDeletionBlocked,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonGroupNotEmpty,
+		Message:            errGroupNotEmpty,
+	})
+	return errors.Wrap(err, errGroupNotEmpty)
+}
+
+// MaxReportedMembers is how many members that could not be deleted are named
+// in the error of a recursive delete.
+const MaxReportedMembers = 5
+
+// summarized is an error of a bulk operation whose message is the summary of
+// its report, while the errors of the report remain available to errors.Is
+// and errors.As.
+type summarized struct {
+	err     error
+	summary string
+}
+
+func (s *summarized) Error() string { return s.summary }
+func (s *summarized) Unwrap() error { return s.err }
+
+// Summarize shortens the error of a recursive delete to the summary of its
+// report, so that the Synced condition stays readable for large groups.
+func Summarize(report cloudian.Report, err error) error {
+	if err == nil || len(report.Failed) == 0 {
+		return err
 	}
+	return &summarized{err: err, summary: "cannot delete members: " + report.Summary(MaxReportedMembers)}
 }
@@ -0,0 +1,478 @@
+package group
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/test"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/xcrd"
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	userv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/user/v1alpha1"
+	userv1alpha1common "github.com/statnett/provider-cloudian/apis/common/user/v1alpha1"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
+)
+
+func newGroup(externalName, groupID string) *userv1alpha1cluster.Group {
+	g := &userv1alpha1cluster.Group{ObjectMeta: metav1.ObjectMeta{Name: "name"}}
+	if externalName != "" {
+		meta.SetExternalName(g, externalName)
+	}
+	g.Spec.ForProvider.GroupID = groupID
+	return g
+}
+
+func TestGroupIDInitializer(t *testing.T) {
+	cases := map[string]struct {
+		externalName     string
+		groupID          string
+		wantID           string
+		wantGetGroupID   string
+		wantErr          bool
+		wantUpdate       bool
+		wantExternalName string
+	}{
+		"NeitherSet": {
+			wantGetGroupID:   "",
+			wantID:           "name",
+			wantExternalName: "name",
+			wantUpdate:       true,
+		},
+		"ExternalNameOnly": {
+			externalName:     "ext",
+			wantGetGroupID:   "ext",
+			wantID:           "ext",
+			wantExternalName: "ext",
+			wantUpdate:       true,
+		},
+		"GroupIDOnly": {
+			groupID:          "gid",
+			wantGetGroupID:   "gid",
+			wantID:           "gid",
+			wantExternalName: "gid",
+			wantUpdate:       true,
+		},
+		"BothEqual": {
+			externalName:     "gid",
+			groupID:          "gid",
+			wantGetGroupID:   "gid",
+			wantID:           "gid",
+			wantExternalName: "gid",
+		},
+		"Conflicting": {
+			externalName:     "ext",
+			groupID:          "gid",
+			wantGetGroupID:   "gid",
+			wantID:           "gid",
+			wantExternalName: "ext",
+			wantErr:          true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			g := newGroup(tc.externalName, tc.groupID)
+			if got := g.GetGroupID(); got != tc.wantGetGroupID {
+				t.Errorf("GetGroupID() before initialization: want %q, got %q", tc.wantGetGroupID, got)
+			}
+
+			updated := false
+			kube := &test.MockClient{MockUpdate: func(_ context.Context, _ client.Object, _ ...client.UpdateOption) error {
+				updated = true
+				return nil
+			}}
+
+			err := NewGroupIDInitializer(kube).Initialize(context.Background(), g)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Initialize(...): want error %t, got %v", tc.wantErr, err)
+			}
+			if updated != tc.wantUpdate {
+				t.Errorf("Initialize(...): want update %t, got %t", tc.wantUpdate, updated)
+			}
+			if diff := cmp.Diff(tc.wantExternalName, meta.GetExternalName(g)); diff != "" {
+				t.Errorf("Initialize(...): external-name -want, +got:\n%s", diff)
+			}
+			if tc.wantErr {
+				return
+			}
+			if diff := cmp.Diff(tc.wantID, g.Spec.ForProvider.GroupID); diff != "" {
+				t.Errorf("Initialize(...): groupId -want, +got:\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantID, g.GetGroupID()); diff != "" {
+				t.Errorf("GetGroupID() after initialization: -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGroupIDExtractor(t *testing.T) {
+	g := newGroup("ext", "")
+	if got := userv1alpha1cluster.GroupID()(g); got != "ext" {
+		t.Errorf("GroupID()(...): want %q, got %q", "ext", got)
+	}
+
+	g = newGroup("", "gid")
+	if got := userv1alpha1cluster.GroupID()(g); got != "gid" {
+		t.Errorf("GroupID()(...): want %q, got %q", "gid", got)
+	}
+
+	if got := userv1alpha1cluster.GroupID()(&userv1alpha1cluster.User{}); got != "" {
+		t.Errorf("GroupID()(...) of non-Group: want empty, got %q", got)
+	}
+}
+
+// ldapGroup is a group whose LDAP settings were configured in the CMC.
+var ldapGroup = cloudian.Group{
+	Active:             true,
+	GroupID:            "QA",
+	GroupName:          "Quality Assurance",
+	LDAPEnabled:        true,
+	LDAPGroup:          "qa",
+	LDAPMatchAttribute: "memberOf",
+	LDAPSearch:         "(uid={userId})",
+	LDAPSearchUserBase: "ou=people,dc=example,dc=com",
+	LDAPServerURL:      "ldaps://ldap.example.com",
+	LDAPUserDNTemplate: "uid={userId},ou=people,dc=example,dc=com",
+}
+
+func TestLateInitialize(t *testing.T) {
+	// ldapParameters are the parameters of ldapGroup.
+	ldapParameters := userv1alpha1common.GroupParameters{
+		Active:             true,
+		GroupName:          "Quality Assurance",
+		LDAPEnabled:        ptr.To(true),
+		LDAPGroup:          ptr.To("qa"),
+		LDAPMatchAttribute: ptr.To("memberOf"),
+		LDAPSearch:         ptr.To("(uid={userId})"),
+		LDAPSearchUserBase: ptr.To("ou=people,dc=example,dc=com"),
+		LDAPServerURL:      ptr.To("ldaps://ldap.example.com"),
+		LDAPUserDNTemplate: ptr.To("uid={userId},ou=people,dc=example,dc=com"),
+	}
+
+	cases := map[string]struct {
+		reason   string
+		spec     userv1alpha1common.GroupParameters
+		observed cloudian.Group
+		want     userv1alpha1common.GroupParameters
+		wantLI   bool
+	}{
+		"Nil": {
+			reason:   "LDAP settings left unset should be late initialized from the observed group.",
+			spec:     userv1alpha1common.GroupParameters{Active: true, GroupName: "Quality Assurance"},
+			observed: ldapGroup,
+			want:     ldapParameters,
+			wantLI:   true,
+		},
+		"Set": {
+			reason:   "Nothing should be late initialized when every LDAP setting is set.",
+			spec:     ldapParameters,
+			observed: ldapGroup,
+			want:     ldapParameters,
+		},
+		"Conflicting": {
+			reason: "LDAP settings set in the spec should never be overridden by the observed values.",
+			spec: userv1alpha1common.GroupParameters{
+				Active:      true,
+				GroupName:   "Renamed",
+				LDAPEnabled: ptr.To(false),
+				LDAPGroup:   ptr.To("other"),
+			},
+			observed: ldapGroup,
+			want: func() userv1alpha1common.GroupParameters {
+				gp := *ldapParameters.DeepCopy()
+				gp.GroupName = "Renamed"
+				gp.LDAPEnabled = ptr.To(false)
+				gp.LDAPGroup = ptr.To("other")
+				return gp
+			}(),
+			wantLI: true,
+		},
+		"ExplicitlyEmpty": {
+			reason: "LDAP settings explicitly cleared in the spec should stay cleared.",
+			spec: func() userv1alpha1common.GroupParameters {
+				gp := *ldapParameters.DeepCopy()
+				gp.LDAPGroup = ptr.To("")
+				return gp
+			}(),
+			observed: ldapGroup,
+			want: func() userv1alpha1common.GroupParameters {
+				gp := *ldapParameters.DeepCopy()
+				gp.LDAPGroup = ptr.To("")
+				return gp
+			}(),
+		},
+		"ObservedUnset": {
+			reason:   "LDAP settings unset in Cloudian should be left unset rather than late initialized to empty values.",
+			spec:     userv1alpha1common.GroupParameters{Active: true, GroupName: "Quality Assurance"},
+			observed: cloudian.Group{Active: true, GroupID: "QA", GroupName: "Quality Assurance"},
+			want:     userv1alpha1common.GroupParameters{Active: true, GroupName: "Quality Assurance"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			gp := *tc.spec.DeepCopy()
+			if got := LateInitialize(&gp, tc.observed); got != tc.wantLI {
+				t.Errorf("\n%s\nLateInitialize(...): want %t, got %t", tc.reason, tc.wantLI, got)
+			}
+			if diff := cmp.Diff(tc.want, gp); diff != "" {
+				t.Errorf("\n%s\nLateInitialize(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if LateInitialize(&gp, tc.observed) {
+				t.Errorf("\n%s\nLateInitialize(...): want nothing left to late initialize", tc.reason)
+			}
+		})
+	}
+}
+
+func TestMergeObserved(t *testing.T) {
+	cases := map[string]struct {
+		reason  string
+		desired userv1alpha1common.GroupParameters
+		want    cloudian.Group
+	}{
+		"LateInitializeOff": {
+			reason:  "LDAP settings left unset when late initialization is disabled should keep their observed values",
+			desired: userv1alpha1common.GroupParameters{Active: true, GroupName: "Renamed"},
+			want: func() cloudian.Group {
+				g := ldapGroup
+				g.GroupName = "Renamed"
+				return g
+			}(),
+		},
+		"LateInitializeOn": {
+			reason: "Late initialized LDAP settings should be sent as is",
+			desired: func() userv1alpha1common.GroupParameters {
+				gp := userv1alpha1common.GroupParameters{Active: true, GroupName: "Renamed"}
+				LateInitialize(&gp, ldapGroup)
+				return gp
+			}(),
+			want: func() cloudian.Group {
+				g := ldapGroup
+				g.GroupName = "Renamed"
+				return g
+			}(),
+		},
+		"Disable": {
+			reason:  "LDAP settings set in the spec should override the observed values",
+			desired: userv1alpha1common.GroupParameters{Active: true, GroupName: "Quality Assurance", LDAPEnabled: ptr.To(false), LDAPGroup: ptr.To("")},
+			want: func() cloudian.Group {
+				g := ldapGroup
+				g.LDAPEnabled = false
+				g.LDAPGroup = ""
+				return g
+			}(),
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			desired := *tc.desired.DeepCopy()
+			if diff := cmp.Diff(tc.want, MergeObserved("QA", tc.desired, ldapGroup, nil)); diff != "" {
+				t.Errorf("\n%s\nMergeObserved(...): -want, +got:\n%s", tc.reason, diff)
+			}
+			if diff := cmp.Diff(desired, tc.desired); diff != "" {
+				t.Errorf("\n%s\nMergeObserved(...) modified the desired parameters: -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+func TestIsUpToDateIgnoresUnsetLDAP(t *testing.T) {
+	if !IsUpToDate("QA", userv1alpha1common.GroupParameters{Active: true, GroupName: "Quality Assurance"}, ldapGroup, cloudian.S3Endpoints{}, nil) {
+		t.Error("IsUpToDate(...): want LDAP settings left unset to be up to date")
+	}
+	if IsUpToDate("QA", userv1alpha1common.GroupParameters{Active: true, GroupName: "Renamed"}, ldapGroup, cloudian.S3Endpoints{}, nil) {
+		t.Error("IsUpToDate(...): want renamed group to be outdated")
+	}
+}
+
+func TestIsUpToDateS3Endpoints(t *testing.T) {
+	all := cloudian.UniformS3Endpoints([]string{"s3-a.example.com", "s3-b.example.com"})
+	restricted := cloudian.Group{
+		GroupID:            "QA",
+		S3EndpointsHTTP:    []string{cloudian.AllEndpoints},
+		S3EndpointsHTTPS:   []string{"s3-b.example.com", "s3-a.example.com"},
+		S3WebSiteEndpoints: []string{"s3-a.example.com"},
+	}
+
+	cases := map[string]struct {
+		reason    string
+		endpoints *userv1alpha1common.S3Endpoints
+		all       cloudian.S3Endpoints
+		want      bool
+	}{
+		"Unset": {
+			reason: "S3 endpoints left unset should match any observed restriction",
+			want:   true,
+		},
+		"Normalized": {
+			reason: "S3 endpoints should match regardless of order, and empty lists should match ALL",
+			endpoints: &userv1alpha1common.S3Endpoints{
+				HTTPS:   []string{"s3-a.example.com", "s3-b.example.com", "s3-a.example.com"},
+				Website: []string{"s3-a.example.com"},
+			},
+			want: true,
+		},
+		"EveryEndpoint": {
+			reason: "Allowing ALL should match allowing each S3 endpoint of the cluster",
+			endpoints: &userv1alpha1common.S3Endpoints{
+				HTTPS:   []string{cloudian.AllEndpoints},
+				Website: []string{"s3-a.example.com"},
+			},
+			all:  all,
+			want: true,
+		},
+		"EveryEndpointByKind": {
+			reason: "Allowing ALL should match allowing each S3 endpoint of the cluster of the same kind",
+			endpoints: &userv1alpha1common.S3Endpoints{
+				HTTPS:   []string{cloudian.AllEndpoints},
+				Website: []string{cloudian.AllEndpoints},
+			},
+			all: cloudian.S3Endpoints{Regions: []cloudian.RegionS3Endpoints{
+				{Region: "region1", HTTPS: []string{"s3-a.example.com"}, Website: []string{"s3-a.example.com"}},
+				{Region: "region2", HTTPS: []string{"s3-b.example.com"}},
+			}},
+			want: true,
+		},
+		"EveryEndpointUnknown": {
+			reason: "Allowing ALL should not match a list of S3 endpoints when those of the cluster are not known",
+			endpoints: &userv1alpha1common.S3Endpoints{
+				HTTPS:   []string{cloudian.AllEndpoints},
+				Website: []string{"s3-a.example.com"},
+			},
+		},
+		"Different": {
+			reason: "As many S3 endpoints, but not the same, should be an update",
+			endpoints: &userv1alpha1common.S3Endpoints{
+				HTTPS:   []string{"s3-a.example.com", "s3-c.example.com"},
+				Website: []string{"s3-a.example.com"},
+			},
+		},
+		"Restricted": {
+			reason: "Restricting the S3 endpoints further should be an update",
+			endpoints: &userv1alpha1common.S3Endpoints{
+				HTTPS:   []string{"s3-a.example.com"},
+				Website: []string{"s3-a.example.com"},
+			},
+			all: all,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			desired := userv1alpha1common.GroupParameters{S3Endpoints: tc.endpoints}
+			if got := IsUpToDate("QA", desired, restricted, tc.all, nil); got != tc.want {
+				t.Errorf("\n%s\nIsUpToDate(...): want %t, got %t", tc.reason, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestLateInitializeS3Endpoints(t *testing.T) {
+	gp := userv1alpha1common.GroupParameters{}
+	if LateInitialize(&gp, cloudian.Group{S3EndpointsHTTP: []string{cloudian.AllEndpoints}}) {
+		t.Errorf("LateInitialize(...): want groups allowed every S3 endpoint not to be late initialized, got %+v", gp.S3Endpoints)
+	}
+
+	observed := cloudian.Group{S3EndpointsHTTPS: []string{"s3-a.example.com"}}
+	if !LateInitialize(&gp, observed) {
+		t.Fatal("LateInitialize(...): want restricted S3 endpoints to be late initialized")
+	}
+	want := &userv1alpha1common.S3Endpoints{HTTPS: []string{"s3-a.example.com"}}
+	if diff := cmp.Diff(want, gp.S3Endpoints); diff != "" {
+		t.Errorf("LateInitialize(...): -want, +got:\n%s", diff)
+	}
+}
+
+func TestDesired(t *testing.T) {
+	claimed := map[string]string{xcrd.LabelKeyClaimNamespace: "team-a", xcrd.LabelKeyClaimName: "qa"}
+	cases := map[string]struct {
+		reason      string
+		name        string
+		labels      map[string]string
+		groupName   string
+		defaultName bool
+		want        string
+	}{
+		"Disabled": {
+			reason: "GroupName should be left unset unless defaulting is enabled.",
+			name:   "qa-x7k2p",
+			labels: claimed,
+		},
+		"Explicit": {
+			reason:      "An explicit GroupName should always be kept.",
+			name:        "qa-x7k2p",
+			labels:      claimed,
+			groupName:   "Quality Assurance",
+			defaultName: true,
+			want:        "Quality Assurance",
+		},
+		"Claimed": {
+			reason:      "GroupName should default to the managed resource name and its claim.",
+			name:        "qa-x7k2p",
+			labels:      claimed,
+			defaultName: true,
+			want:        "qa-x7k2p managed-by=crossplane/team-a/qa",
+		},
+		"Unclaimed": {
+			reason:      "GroupName should default to the managed resource name alone without a claim.",
+			name:        "qa",
+			defaultName: true,
+			want:        "qa",
+		},
+		"TooLong": {
+			reason:      "GroupName should be cut to the longest name Cloudian accepts.",
+			name:        strings.Repeat("q", 60),
+			labels:      claimed,
+			defaultName: true,
+			want:        strings.Repeat("q", 60) + " man",
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			mg := &userv1alpha1cluster.Group{ObjectMeta: metav1.ObjectMeta{Name: tc.name, Labels: tc.labels}}
+			params := userv1alpha1common.GroupParameters{GroupName: tc.groupName}
+			if got := Desired(mg, params, tc.defaultName).GroupName; got != tc.want {
+				t.Errorf("\n%s\nDesired(...): want GroupName %q, got %q", tc.reason, tc.want, got)
+			}
+			if params.GroupName != tc.groupName {
+				t.Errorf("\n%s\nDesired(...): want the parameters unchanged, got GroupName %q", tc.reason, params.GroupName)
+			}
+		})
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	statusErr := &cloudian.StatusError{Method: "DELETE", Path: "/user", StatusCode: 500}
+	report := cloudian.Report{Failed: map[string]error{}}
+	for i := range MaxReportedMembers + 2 {
+		id := fmt.Sprintf("user%d", i)
+		report.Failed[id] = fmt.Errorf("error deleting user %s: %w", id, statusErr)
+	}
+
+	err := Summarize(report, report.Err())
+	want := "cannot delete members: " + report.Summary(MaxReportedMembers)
+	if err.Error() != want {
+		t.Errorf("Summarize(...): want %q, got %q", want, err.Error())
+	}
+	if !strings.HasSuffix(err.Error(), "; and 2 more") {
+		t.Errorf("Summarize(...): want the members left out counted, got %q", err.Error())
+	}
+	var got *cloudian.StatusError
+	if !errors.As(err, &got) {
+		t.Errorf("Summarize(...): want the errors of the report kept, got %v", err)
+	}
+
+	listErr := errors.New("error listing users")
+	if err := Summarize(cloudian.Report{}, listErr); err != listErr {
+		t.Errorf("Summarize(...): want errors without failed members unchanged, got %v", err)
+	}
+}
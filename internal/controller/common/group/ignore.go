@@ -0,0 +1,65 @@
+package group
+
+import (
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
+)
+
+// AnnotationIgnoreFields lists the parameters of a group, separated by commas,
+// that are managed outside the provider. Ignored parameters are neither
+// compared with nor changed in Cloudian.
+const AnnotationIgnoreFields = "cloudian.crossplane.io/ignore-fields"
+
+const errUnknownField = "annotation %s: unknown field %q, must be one of %s"
+
+// ignorable copies each parameter that may be ignored, by the name of its
+// field in the spec, from the observed group.
+var ignorable = map[string]func(g *cloudian.Group, observed cloudian.Group){
+	"active":             func(g *cloudian.Group, o cloudian.Group) { g.Active = o.Active },
+	"groupName":          func(g *cloudian.Group, o cloudian.Group) { g.GroupName = o.GroupName },
+	"ldapEnabled":        func(g *cloudian.Group, o cloudian.Group) { g.LDAPEnabled = o.LDAPEnabled },
+	"ldapGroup":          func(g *cloudian.Group, o cloudian.Group) { g.LDAPGroup = o.LDAPGroup },
+	"ldapMatchAttribute": func(g *cloudian.Group, o cloudian.Group) { g.LDAPMatchAttribute = o.LDAPMatchAttribute },
+	"ldapSearch":         func(g *cloudian.Group, o cloudian.Group) { g.LDAPSearch = o.LDAPSearch },
+	"ldapSearchUserBase": func(g *cloudian.Group, o cloudian.Group) { g.LDAPSearchUserBase = o.LDAPSearchUserBase },
+	"ldapServerURL":      func(g *cloudian.Group, o cloudian.Group) { g.LDAPServerURL = o.LDAPServerURL },
+	"ldapUserDNTemplate": func(g *cloudian.Group, o cloudian.Group) { g.LDAPUserDNTemplate = o.LDAPUserDNTemplate },
+	"s3Endpoints": func(g *cloudian.Group, o cloudian.Group) {
+		g.S3EndpointsHTTP = slices.Clone(o.S3EndpointsHTTP)
+		g.S3EndpointsHTTPS = slices.Clone(o.S3EndpointsHTTPS)
+		g.S3WebSiteEndpoints = slices.Clone(o.S3WebSiteEndpoints)
+	},
+}
+
+// IgnoredFields returns the parameters the supplied group is annotated to
+// ignore. Unknown parameters are rejected.
+func IgnoredFields(o metav1.Object) ([]string, error) {
+	value := o.GetAnnotations()[AnnotationIgnoreFields]
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	var fields []string
+	for field := range strings.SplitSeq(value, ",") {
+		field = strings.TrimSpace(field)
+		if _, ok := ignorable[field]; !ok {
+			known := slices.Sorted(maps.Keys(ignorable))
+			return nil, errors.Errorf(errUnknownField, AnnotationIgnoreFields, field, strings.Join(known, ", "))
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// ignore sets the ignored parameters of the group to their observed values.
+func ignore(g *cloudian.Group, observed cloudian.Group, fields []string) {
+	for _, field := range fields {
+		ignorable[field](g, observed)
+	}
+}
@@ -0,0 +1,105 @@
+package group
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+
+	userv1alpha1common "github.com/statnett/provider-cloudian/apis/common/user/v1alpha1"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
+)
+
+func TestIgnoredFields(t *testing.T) {
+	cases := map[string]struct {
+		value   string
+		want    []string
+		wantErr bool
+	}{
+		"None":    {},
+		"Blank":   {value: " "},
+		"Fields":  {value: "ldapSearch, groupName", want: []string{"ldapSearch", "groupName"}},
+		"Unknown": {value: "groupName,ldapsearch", wantErr: true},
+		"Empty":   {value: "groupName,", wantErr: true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			o := &metav1.ObjectMeta{Annotations: map[string]string{AnnotationIgnoreFields: tc.value}}
+			got, err := IgnoredFields(o)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("IgnoredFields(%q): want error %t, got %v", tc.value, tc.wantErr, err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("IgnoredFields(%q): -want, +got:\n%s", tc.value, diff)
+			}
+		})
+	}
+}
+
+func TestIgnoredFieldsAreKnown(t *testing.T) {
+	gp := userv1alpha1common.GroupParameters{
+		Active:             true,
+		GroupName:          "Quality Assurance",
+		LDAPEnabled:        ptr.To(true),
+		LDAPGroup:          ptr.To("qa"),
+		LDAPMatchAttribute: ptr.To("uid"),
+		LDAPSearch:         ptr.To("(objectClass=person)"),
+		LDAPSearchUserBase: ptr.To("ou=people"),
+		LDAPServerURL:      ptr.To("ldaps://ldap.example.com"),
+		LDAPUserDNTemplate: ptr.To("uid={userId},ou=people"),
+		S3Endpoints:        &userv1alpha1common.S3Endpoints{HTTP: []string{"s3.example.com"}},
+	}
+	// Every parameter sent to Cloudian may be ignored.
+	for field := range ignorable {
+		g := NewCloudianGroup("QA", gp)
+		ignore(&g, cloudian.Group{GroupID: "QA"}, []string{field})
+		if g.Equal(NewCloudianGroup("QA", gp)) {
+			t.Errorf("ignore(%q): want the observed value, got the desired one", field)
+		}
+	}
+}
+
+func TestIsUpToDateIgnoredFields(t *testing.T) {
+	desired := userv1alpha1common.GroupParameters{Active: true, GroupName: "quality assurance", LDAPSearch: ptr.To("(uid=*)")}
+
+	cases := map[string]struct {
+		reason  string
+		ignored []string
+		want    bool
+	}{
+		"Managed": {
+			reason: "Fields that are not ignored should be compared.",
+			want:   false,
+		},
+		"SomeIgnored": {
+			reason:  "Fields that are not ignored should still be compared when others are.",
+			ignored: []string{"groupName"},
+			want:    false,
+		},
+		"AllIgnored": {
+			reason:  "Ignored fields should match any observed value.",
+			ignored: []string{"groupName", "ldapSearch"},
+			want:    true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := IsUpToDate("QA", desired, ldapGroup, cloudian.S3Endpoints{}, tc.ignored); got != tc.want {
+				t.Errorf("\n%s\nIsUpToDate(...): want %t, got %t", tc.reason, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestMergeObservedIgnoredFields(t *testing.T) {
+	desired := userv1alpha1common.GroupParameters{Active: true, GroupName: "quality assurance", LDAPSearch: ptr.To("(uid=*)")}
+
+	want := ldapGroup
+	want.LDAPSearch = "(uid=*)"
+	if diff := cmp.Diff(want, MergeObserved("QA", desired, ldapGroup, []string{"groupName"})); diff != "" {
+		t.Errorf("MergeObserved(...): want the observed value of ignored fields, -want, +got:\n%s", diff)
+	}
+}
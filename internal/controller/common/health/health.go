@@ -0,0 +1,174 @@
+// Package health periodically checks that ProviderConfigs can reach the
+// Cloudian admin API, so that a mistyped endpoint or expired credentials show
+// on the ProviderConfig rather than on the first managed resource using it.
+package health
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/controller"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	pcv1alpha1common "github.com/statnett/provider-cloudian/apis/common/providerconfig/v1alpha1"
+	"github.com/statnett/provider-cloudian/internal/clients"
+	controllercommon "github.com/statnett/provider-cloudian/internal/controller/common"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
+)
+
+const (
+	// TypeHealthy indicates whether the Cloudian admin API can be reached
+	// with the endpoint and credentials of a ProviderConfig.
+	TypeHealthy xpv2.ConditionType = "Healthy"
+	// ReasonReachable means the admin API answered the last check.
+	ReasonReachable xpv2.ConditionReason = "Reachable"
+	// ReasonUnreachable means the last check of the admin API failed.
+	ReasonUnreachable xpv2.ConditionReason = "Unreachable"
+)
+
+// DefaultPollInterval is how often ProviderConfigs are checked by default.
+const DefaultPollInterval = time.Minute
+
+const (
+	errGetPC        = "cannot get ProviderConfig"
+	errGetCreds     = "cannot get credentials"
+	errNewClient    = "cannot create new Service"
+	errPing         = "cannot reach Cloudian admin API at %s"
+	errUpdateStatus = "cannot update ProviderConfig status"
+)
+
+// A ProviderConfig configures how to reach the Cloudian admin API.
+type ProviderConfig interface {
+	resource.ProviderConfig
+	GetProviderConfigSpec() pcv1alpha1common.ProviderConfigSpec
+}
+
+// Healthy returns a condition indicating that the admin API can be reached.
+func Healthy() xpv2.Condition {
+	return xpv2.Condition{
+		Type:               TypeHealthy,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonReachable,
+	}
+}
+
+// Unhealthy returns a condition indicating that the admin API cannot be
+// reached, and why.
+func Unhealthy(err error) xpv2.Condition {
+	return xpv2.Condition{
+		Type:               TypeHealthy,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonUnreachable,
+		Message:            err.Error(),
+	}
+}
+
+// A Reconciler checks the health of ProviderConfigs of one kind.
+type Reconciler struct {
+	kube         client.Client
+	newPC        func() ProviderConfig
+	newServiceFn func(uid types.UID, spec pcv1alpha1common.ProviderConfigSpec, creds controllercommon.Credentials) (*cloudian.Client, error)
+	pollInterval time.Duration
+}
+
+// A ReconcilerOption configures a Reconciler.
+type ReconcilerOption func(*Reconciler)
+
+// WithPollInterval sets how often each ProviderConfig is checked.
+func WithPollInterval(d time.Duration) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.pollInterval = d
+	}
+}
+
+// WithNewServiceFn sets how clients of the admin API are created.
+func WithNewServiceFn(fn func(uid types.UID, spec pcv1alpha1common.ProviderConfigSpec, creds controllercommon.Credentials) (*cloudian.Client, error)) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.newServiceFn = fn
+	}
+}
+
+// NewReconciler returns a Reconciler of ProviderConfigs returned by newPC.
+func NewReconciler(kube client.Client, newPC func() ProviderConfig, opts ...ReconcilerOption) *Reconciler {
+	r := &Reconciler{
+		kube:         kube,
+		newPC:        newPC,
+		newServiceFn: clients.Shared.Get,
+		pollInterval: DefaultPollInterval,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Setup adds a controller that checks the health of ProviderConfigs of the
+// supplied kind, returned by newPC, every poll interval of the options.
+func Setup(mgr ctrl.Manager, o controller.Options, gvk schema.GroupVersionKind, newPC func() ProviderConfig) error {
+	var opts []ReconcilerOption
+	if o.PollInterval > 0 {
+		opts = append(opts, WithPollInterval(o.PollInterval))
+	}
+	// Status updates, including those of this controller, do not need
+	// another check before the next poll. The shared client of a deleted
+	// ProviderConfig is evicted, so that it does not outlive it.
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("health/"+strings.ToLower(gvk.GroupKind().String())).
+		WithOptions(o.ForControllerRuntime()).
+		For(newPC(), builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(newPC(), clients.Shared.EvictOnDelete()).
+		Complete(NewReconciler(mgr.GetClient(), newPC, opts...))
+}
+
+// Reconcile checks the health of a ProviderConfig.
+func (r *Reconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	pc := r.newPC()
+	if err := r.kube.Get(ctx, req.NamespacedName, pc); err != nil {
+		return reconcile.Result{}, errors.Wrap(client.IgnoreNotFound(err), errGetPC)
+	}
+	if meta.WasDeleted(pc) {
+		return reconcile.Result{}, nil
+	}
+
+	c := Healthy()
+	if err := r.check(ctx, pc); err != nil {
+		c = Unhealthy(err)
+	}
+	if !pc.GetCondition(TypeHealthy).Equal(c) {
+		pc.SetConditions(c)
+		if err := r.kube.Status().Update(ctx, pc); err != nil {
+			return reconcile.Result{}, errors.Wrap(err, errUpdateStatus)
+		}
+	}
+	return reconcile.Result{RequeueAfter: r.pollInterval}, nil
+}
+
+func (r *Reconciler) check(ctx context.Context, pc ProviderConfig) error {
+	spec := pc.GetProviderConfigSpec()
+	creds, err := controllercommon.ExtractCredentials(ctx, r.kube, spec)
+	if err != nil {
+		return errors.Wrap(err, errGetCreds)
+	}
+
+	svc, err := r.newServiceFn(pc.GetUID(), spec, creds)
+	if err != nil {
+		return errors.Wrap(err, errNewClient)
+	}
+
+	return errors.Wrapf(svc.Ping(ctx), errPing, spec.Endpoint)
+}
@@ -0,0 +1,145 @@
+package health
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	apiscluster "github.com/statnett/provider-cloudian/apis/cluster"
+	apisv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/v1alpha1"
+	pcv1alpha1common "github.com/statnett/provider-cloudian/apis/common/providerconfig/v1alpha1"
+	controllercommon "github.com/statnett/provider-cloudian/internal/controller/common"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian/cloudiantest"
+)
+
+const namespace = "crossplane-system"
+
+func scheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+	s := runtime.NewScheme()
+	for _, add := range []func(*runtime.Scheme) error{clientgoscheme.AddToScheme, apiscluster.AddToScheme} {
+		if err := add(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s
+}
+
+func newProviderConfig(endpoint string) *apisv1alpha1cluster.ProviderConfig {
+	return &apisv1alpha1cluster.ProviderConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "default", UID: "1234"},
+		Spec: pcv1alpha1common.ProviderConfigSpec{
+			Endpoint: endpoint,
+			AuthHeader: pcv1alpha1common.ProviderCredentials{
+				Source: xpv2.CredentialsSourceSecret,
+				CommonCredentialSelectors: xpv2.CommonCredentialSelectors{
+					SecretRef: &xpv2.SecretKeySelector{
+						SecretReference: xpv2.SecretReference{Namespace: namespace, Name: "cloudian"},
+						Key:             "authHeader",
+					},
+				},
+			},
+		},
+	}
+}
+
+func credentials() *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "cloudian"},
+		Data:       map[string][]byte{"authHeader": []byte("Basic Zm9vOmJhcg==")},
+	}
+}
+
+func newService(_ types.UID, spec pcv1alpha1common.ProviderConfigSpec, creds controllercommon.Credentials) (*cloudian.Client, error) {
+	return controllercommon.NewCloudianService(spec, creds)
+}
+
+func TestReconcile(t *testing.T) {
+	cases := map[string]struct {
+		reason      string
+		status      int
+		objects     []client.Object
+		wantStatus  corev1.ConditionStatus
+		wantMessage string
+	}{
+		"Healthy": {
+			reason:     "A ProviderConfig whose endpoint answers should be healthy.",
+			status:     http.StatusOK,
+			objects:    []client.Object{credentials()},
+			wantStatus: corev1.ConditionTrue,
+		},
+		"Unauthorized": {
+			reason:      "A ProviderConfig whose credentials are rejected should be unhealthy.",
+			status:      http.StatusUnauthorized,
+			objects:     []client.Object{credentials()},
+			wantStatus:  corev1.ConditionFalse,
+			wantMessage: "cannot reach Cloudian admin API",
+		},
+		"MissingSecret": {
+			reason:      "A ProviderConfig whose credentials cannot be read should be unhealthy.",
+			status:      http.StatusOK,
+			wantStatus:  corev1.ConditionFalse,
+			wantMessage: "cannot get credentials",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			server := cloudiantest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte("8.1.2"))
+			}))
+			pc := newProviderConfig(server.URL)
+			kube := fake.NewClientBuilder().
+				WithScheme(scheme(t)).
+				WithObjects(append(tc.objects, pc)...).
+				WithStatusSubresource(pc).
+				Build()
+			r := NewReconciler(kube, func() ProviderConfig { return &apisv1alpha1cluster.ProviderConfig{} },
+				WithPollInterval(time.Minute), WithNewServiceFn(newService))
+
+			res, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: types.NamespacedName{Name: "default"}})
+			if err != nil {
+				t.Fatalf("\n%s\nReconcile(...): %v", tc.reason, err)
+			}
+			if res.RequeueAfter != time.Minute {
+				t.Errorf("\n%s\nReconcile(...): want RequeueAfter %s, got %s", tc.reason, time.Minute, res.RequeueAfter)
+			}
+
+			got := &apisv1alpha1cluster.ProviderConfig{}
+			if err := kube.Get(context.Background(), types.NamespacedName{Name: "default"}, got); err != nil {
+				t.Fatal(err)
+			}
+			c := got.GetCondition(TypeHealthy)
+			if c.Status != tc.wantStatus {
+				t.Errorf("\n%s\nReconcile(...): want condition %s %s, got %s", tc.reason, TypeHealthy, tc.wantStatus, c.Status)
+			}
+			if !strings.Contains(c.Message, tc.wantMessage) {
+				t.Errorf("\n%s\nReconcile(...): want message containing %q, got %q", tc.reason, tc.wantMessage, c.Message)
+			}
+		})
+	}
+}
+
+func TestReconcileNotFound(t *testing.T) {
+	kube := fake.NewClientBuilder().WithScheme(scheme(t)).Build()
+	r := NewReconciler(kube, func() ProviderConfig { return &apisv1alpha1cluster.ProviderConfig{} })
+
+	res, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: types.NamespacedName{Name: "default"}})
+	if err != nil || res.RequeueAfter != 0 {
+		t.Errorf("Reconcile(...): want no error and no requeue for a deleted ProviderConfig, got %v, %s", err, res.RequeueAfter)
+	}
+}
@@ -0,0 +1,54 @@
+// Package iamuser contains the logic shared by the IAMUser controllers.
+package iamuser
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	pcv1alpha1common "github.com/statnett/provider-cloudian/apis/common/providerconfig/v1alpha1"
+	userv1alpha1common "github.com/statnett/provider-cloudian/apis/common/user/v1alpha1"
+	controllercommon "github.com/statnett/provider-cloudian/internal/controller/common"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian/iam"
+)
+
+const (
+	errNoIAMEndpoint     = "the ProviderConfig has no iamEndpoint, which IAMUsers require"
+	errParentCredentials = "cannot get an access key of the parent user to sign IAM requests with"
+)
+
+// NewService returns a client of the IAM API configured by a ProviderConfig.
+// Its requests are signed with an access key of the parent user, read once
+// with the admin API, so that reconciling an IAMUser reads it once.
+func NewService(ctx context.Context, admin *cloudian.Client, spec pcv1alpha1common.ProviderConfigSpec, creds controllercommon.Credentials, parent cloudian.GroupUserID) (*iam.Client, error) {
+	if spec.IAMEndpoint == "" {
+		return nil, errors.New(errNoIAMEndpoint)
+	}
+	parentCreds, err := iam.ParentCredentials(ctx, admin, parent)
+	if err != nil {
+		return nil, errors.Wrap(err, errParentCredentials)
+	}
+
+	var opts []func(*iam.Client)
+	if len(creds.CABundle) > 0 {
+		opts = append(opts, iam.WithCACert(creds.CABundle))
+	}
+	if spec.TLS != nil && spec.TLS.ServerName != "" {
+		opts = append(opts, iam.WithServerName(spec.TLS.ServerName))
+	}
+	return iam.NewClient(spec.IAMEndpoint, iam.StaticCredentials(parentCreds), opts...), nil
+}
+
+// ParentGone returns whether an error of NewService means that the parent
+// user has no access keys left, or is gone. An IAMUser being deleted is then
+// gone too, as it can no longer be reached, and goes with its parent.
+func ParentGone(err error) bool {
+	return errors.Is(err, iam.ErrNoParentCredentials) || errors.Is(err, cloudian.ErrNotFound)
+}
+
+// Observe records the observed IAM user in the status of an IAMUser.
+func Observe(obs *userv1alpha1common.IAMUserObservation, user iam.User) {
+	obs.IAMUserID = user.ID
+	obs.ARN = user.ARN
+}
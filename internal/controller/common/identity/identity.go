@@ -0,0 +1,85 @@
+// Package identity detects managed resources sharing the same external
+// identity in Cloudian, so that only one of them manages it, and serializes
+// the mutations of each external identity.
+package identity
+
+import (
+	"slices"
+	"strings"
+
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// IndexField is the field index of the external identity of managed resources.
+const IndexField = "externalIdentity"
+
+const (
+	// TypeUnique indicates whether a managed resource is the only one
+	// managing its external identity.
+	TypeUnique xpv2.ConditionType = "Unique"
+
+	// ReasonDuplicateExternalIdentity means another managed resource manages
+	// the same external identity.
+	ReasonDuplicateExternalIdentity xpv2.ConditionReason = "DuplicateExternalIdentity"
+
+	// ReasonUniqueExternalIdentity means no other managed resource manages the
+	// same external identity.
+	ReasonUniqueExternalIdentity xpv2.ConditionReason = "UniqueExternalIdentity"
+)
+
+// Key returns the index key of an external identity made up of the supplied
+// parts, or nil if any part is not yet known.
+func Key(parts ...string) []string {
+	if slices.Contains(parts, "") {
+		return nil
+	}
+	return []string{strings.Join(parts, "/")}
+}
+
+// First returns the claimant that first claimed an external identity, which
+// is the oldest one. Claimants being deleted are ignored, unless all are.
+func First(claimants []client.Object) client.Object {
+	var first client.Object
+	for _, c := range claimants {
+		if first == nil || before(c, first) {
+			first = c
+		}
+	}
+	return first
+}
+
+func before(a, b client.Object) bool {
+	if aDeleted, bDeleted := a.GetDeletionTimestamp() != nil, b.GetDeletionTimestamp() != nil; aDeleted != bDeleted {
+		return bDeleted
+	}
+	if at, bt := a.GetCreationTimestamp(), b.GetCreationTimestamp(); !at.Equal(&bt) {
+		return at.Before(&bt)
+	}
+	return client.ObjectKeyFromObject(a).String() < client.ObjectKeyFromObject(b).String()
+}
+
+// Duplicate returns a condition indicating that another managed resource
+// manages the same external identity.
+func Duplicate(err error) xpv2.Condition {
+	return xpv2.Condition{
+		Type:               TypeUnique,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonDuplicateExternalIdentity,
+		Message:            err.Error(),
+	}
+}
+
+// Unique returns a condition indicating that no other managed resource
+// manages the same external identity.
+func Unique() xpv2.Condition {
+	return xpv2.Condition{
+		Type:               TypeUnique,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonUniqueExternalIdentity,
+	}
+}
@@ -0,0 +1,57 @@
+package identity
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	userv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/user/v1alpha1"
+)
+
+func newUser(name string, created time.Time, deleted bool) *userv1alpha1cluster.User {
+	u := &userv1alpha1cluster.User{ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(created)}}
+	if deleted {
+		u.SetDeletionTimestamp(&metav1.Time{Time: created})
+	}
+	return u
+}
+
+func TestFirst(t *testing.T) {
+	now := time.Now()
+	older := newUser("older", now.Add(-time.Hour), false)
+	newer := newUser("newer", now, false)
+	twin := newUser("twin", now, false)
+	deleted := newUser("deleted", now.Add(-2*time.Hour), true)
+
+	cases := map[string]struct {
+		claimants []client.Object
+		want      client.Object
+	}{
+		"None":              {},
+		"Single":            {claimants: []client.Object{newer}, want: newer},
+		"OldestWins":        {claimants: []client.Object{newer, older}, want: older},
+		"NameBreaksTie":     {claimants: []client.Object{twin, newer}, want: newer},
+		"DeletedIgnored":    {claimants: []client.Object{deleted, newer}, want: newer},
+		"AllDeleted":        {claimants: []client.Object{deleted}, want: deleted},
+		"OrderIndependence": {claimants: []client.Object{older, newer, twin}, want: older},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := First(tc.claimants); got != tc.want {
+				t.Errorf("First(...): want %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestKey(t *testing.T) {
+	if got := Key("group", ""); got != nil {
+		t.Errorf("Key(...): want nil for unknown part, got %v", got)
+	}
+	if got := Key("group", "user"); len(got) != 1 || got[0] != "group/user" {
+		t.Errorf("Key(...): want [group/user], got %v", got)
+	}
+}
@@ -0,0 +1,36 @@
+package identity
+
+import (
+	"hash/fnv"
+	"strings"
+	"sync"
+)
+
+// stripes is the number of mutexes external identities are spread over.
+const stripes = 256
+
+// A Locker serializes operations on the same external identity. Cloudian has
+// no transactions, so managed resources sharing an external identity could
+// otherwise interleave their mutations. Distinct identities only wait for each
+// other when they share a stripe.
+type Locker struct {
+	mu [stripes]sync.Mutex
+}
+
+// Mutations is the Locker the external clients hold while mutating Cloudian.
+// It only serializes the mutations made by this provider process.
+var Mutations = &Locker{}
+
+// Lock locks the external identity made up of the supplied parts, and returns
+// a function unlocking it.
+func (l *Locker) Lock(parts ...string) (unlock func()) {
+	m := &l.mu[stripe(strings.Join(parts, "/"))]
+	m.Lock()
+	return m.Unlock
+}
+
+func stripe(key string) uint32 {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return h.Sum32() % stripes
+}
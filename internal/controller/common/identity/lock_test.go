@@ -0,0 +1,87 @@
+package identity
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLockSerializesKey(t *testing.T) {
+	l := &Locker{}
+
+	var holders, maxHolders atomic.Int32
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			unlock := l.Lock("QA", "alice")
+			defer unlock()
+
+			n := holders.Add(1)
+			for {
+				m := maxHolders.Load()
+				if n <= m || maxHolders.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			holders.Add(-1)
+		}()
+	}
+	wg.Wait()
+
+	if got := maxHolders.Load(); got != 1 {
+		t.Errorf("Lock(...): want at most 1 concurrent holder of a key, got %d", got)
+	}
+}
+
+func TestLockParallelAcrossKeys(t *testing.T) {
+	l := &Locker{}
+
+	keys := []string{"QA/alice"}
+	for _, key := range []string{"QA/bob", "QA/carol", "QA/dave"} {
+		if stripe(key) != stripe(keys[0]) {
+			keys = append(keys, key)
+			break
+		}
+	}
+	if len(keys) != 2 {
+		t.Fatalf("stripe(...): want keys on distinct stripes, got all on %d", stripe(keys[0]))
+	}
+
+	unlock := l.Lock(keys[0])
+	defer unlock()
+
+	locked := make(chan struct{})
+	go func() {
+		l.Lock(keys[1])()
+		close(locked)
+	}()
+
+	select {
+	case <-locked:
+	case <-time.After(5 * time.Second):
+		t.Errorf("Lock(%q): want it not to wait for %q", keys[1], keys[0])
+	}
+}
+
+func TestLockParts(t *testing.T) {
+	l := &Locker{}
+	unlock := l.Lock("QA", "alice")
+
+	locked := make(chan struct{})
+	go func() {
+		l.Lock("QA/alice")()
+		close(locked)
+	}()
+
+	select {
+	case <-locked:
+		t.Fatal("Lock(...): want parts to lock the same key as when joined")
+	case <-time.After(10 * time.Millisecond):
+	}
+	unlock()
+	<-locked
+}
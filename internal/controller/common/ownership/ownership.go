@@ -0,0 +1,94 @@
+// Package ownership marks Cloudian objects with the cluster managing them, so
+// that several clusters sharing a Cloudian installation do not fight over the
+// same objects.
+package ownership
+
+import (
+	"strings"
+
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	pcv1alpha1common "github.com/statnett/provider-cloudian/apis/common/providerconfig/v1alpha1"
+)
+
+const markerPrefix = "crossplane-owner:"
+
+const (
+	// TypeOwned indicates whether a Cloudian object is owned by this cluster.
+	TypeOwned xpv2.ConditionType = "Owned"
+
+	// ReasonOwnedByAnotherCluster means the Cloudian object carries the
+	// marker of another cluster.
+	ReasonOwnedByAnotherCluster xpv2.ConditionReason = "OwnedByAnotherCluster"
+)
+
+// ErrOwnedByAnotherCluster is returned when a Cloudian object carries the
+// marker of another cluster.
+var ErrOwnedByAnotherCluster = errors.New("owned by another cluster")
+
+// Marker returns the marker of the supplied cluster.
+func Marker(clusterID string) string {
+	return markerPrefix + clusterID
+}
+
+// Owner returns the cluster in the marker held by the supplied field, if any.
+func Owner(field string) (string, bool) {
+	return strings.CutPrefix(field, markerPrefix)
+}
+
+// Check returns ErrOwnedByAnotherCluster if the supplied field holds the marker
+// of another cluster than the supplied one. Unmarked objects may be adopted.
+func Check(field, clusterID string) error {
+	owner, ok := Owner(field)
+	if !ok || owner == clusterID {
+		return nil
+	}
+	return errors.Wrapf(ErrOwnedByAnotherCluster, "cluster %q", owner)
+}
+
+// CheckMembers returns ErrOwnedByAnotherCluster if the supplied fields of the
+// members of an object hold markers of other clusters, but none of the
+// supplied one. Cloudian groups have no free text field to hold a marker, so
+// they are owned by the clusters owning their users. Objects whose members
+// are all unmarked may be adopted.
+func CheckMembers(fields []string, clusterID string) error {
+	other := ""
+	for _, field := range fields {
+		owner, ok := Owner(field)
+		switch {
+		case !ok:
+		case owner == clusterID:
+			return nil
+		case other == "":
+			other = owner
+		}
+	}
+	if other == "" {
+		return nil
+	}
+	return errors.Wrapf(ErrOwnedByAnotherCluster, "cluster %q owning its users", other)
+}
+
+// OwnedByAnotherCluster returns a condition indicating that the Cloudian
+// object is owned by another cluster and will not be modified.
+func OwnedByAnotherCluster(err error) xpv2.Condition {
+	return xpv2.Condition{
+		Type:               TypeOwned,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonOwnedByAnotherCluster,
+		Message:            err.Error(),
+	}
+}
+
+// ClusterID returns the cluster to mark Cloudian objects with, or an empty
+// string if the ProviderConfig does not claim ownership.
+func ClusterID(spec pcv1alpha1common.ProviderConfigSpec) string {
+	if !spec.ClaimOwnership {
+		return ""
+	}
+	return spec.ClusterID
+}
@@ -0,0 +1,61 @@
+package ownership
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestOwner(t *testing.T) {
+	owner, ok := Owner(Marker("cluster-a"))
+	if !ok || owner != "cluster-a" {
+		t.Errorf("Owner(Marker(...)): want %q, got %q (marked %t)", "cluster-a", owner, ok)
+	}
+
+	if _, ok := Owner("Storgata 1"); ok {
+		t.Error("Owner(...): free text should not be a marker")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	cases := map[string]struct {
+		field   string
+		wantErr bool
+	}{
+		"Unmarked":     {field: ""},
+		"FreeText":     {field: "Storgata 1"},
+		"OwnMarker":    {field: Marker("cluster-a")},
+		"OtherMarker":  {field: Marker("cluster-b"), wantErr: true},
+		"PrefixMarker": {field: Marker("cluster-a-2"), wantErr: true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := Check(tc.field, "cluster-a")
+			if got := errors.Is(err, ErrOwnedByAnotherCluster); got != tc.wantErr {
+				t.Errorf("Check(...): want ErrOwnedByAnotherCluster %t, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCheckMembers(t *testing.T) {
+	cases := map[string]struct {
+		fields  []string
+		wantErr bool
+	}{
+		"NoMembers":   {},
+		"Unmarked":    {fields: []string{"", "Storgata 1"}},
+		"OwnMarker":   {fields: []string{Marker("cluster-a"), ""}},
+		"OtherMarker": {fields: []string{"", Marker("cluster-b")}, wantErr: true},
+		"Shared":      {fields: []string{Marker("cluster-b"), Marker("cluster-a")}},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			err := CheckMembers(tc.fields, "cluster-a")
+			if got := errors.Is(err, ErrOwnedByAnotherCluster); got != tc.wantErr {
+				t.Errorf("CheckMembers(...): want ErrOwnedByAnotherCluster %t, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
@@ -1,25 +1,49 @@
 package qualityofservicelimits
 
 import (
+	"math"
+
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/utils/ptr"
 
 	userv1alpha1common "github.com/statnett/provider-cloudian/apis/common/user/v1alpha1"
 	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
 )
 
+const (
+	// TypeValidLimits indicates whether the limits of the spec can be sent
+	// to Cloudian.
+	TypeValidLimits xpv2.ConditionType = "ValidLimits"
+	// ReasonInvalidLimits means a limit of the spec cannot be sent to
+	// Cloudian, such as a quantity that is not a whole number of KiB.
+	// Retrying does not help until the spec is fixed.
+	ReasonInvalidLimits xpv2.ConditionReason = "InvalidLimits"
+	// ReasonValidLimits means every limit of the spec can be sent to
+	// Cloudian.
+	ReasonValidLimits xpv2.ConditionReason = "ValidLimits"
+)
+
+const errOverflow = "%s of %d does not fit in the spec"
+
+// ToCloudianQOS converts the limits of a spec to the limits sent to Cloudian,
+// whose byte limits are in KiB. Blocks that are not set have no limits.
 func ToCloudianQOS(qos userv1alpha1common.QOS) (cloudian.QualityOfService, error) {
 	var err error
 	cQOS := cloudian.QualityOfService{}
 
 	if cQOS.Warning, err = ToCloudianLimits(qos.Warning); err != nil {
-		return cloudian.QualityOfService{}, err
+		return cloudian.QualityOfService{}, errors.Wrap(err, "warning")
 	}
 	if cQOS.Hard, err = ToCloudianLimits(qos.Hard); err != nil {
-		return cloudian.QualityOfService{}, err
+		return cloudian.QualityOfService{}, errors.Wrap(err, "hard")
 	}
 	return cQOS, nil
 }
 
+// ToCloudianLimits converts one block of limits of a spec, see ToCloudianQOS.
 func ToCloudianLimits(limits *userv1alpha1common.QualityOfServiceLimits) (cloudian.QualityOfServiceLimits, error) {
 	if limits == nil {
 		return cloudian.QualityOfServiceLimits{}, nil
@@ -29,13 +53,13 @@ func ToCloudianLimits(limits *userv1alpha1common.QualityOfServiceLimits) (cloudi
 	qosl := cloudian.QualityOfServiceLimits{}
 
 	if qosl.StorageQuotaKiBs, err = limits.StorageQuotaBytes.ToKiB(); err != nil {
-		return cloudian.QualityOfServiceLimits{}, err
+		return cloudian.QualityOfServiceLimits{}, errors.Wrap(err, "storageQuotaBytes")
 	}
 	if qosl.InboundKiBsPerMin, err = limits.InboundBytesPerMin.ToKiB(); err != nil {
-		return cloudian.QualityOfServiceLimits{}, err
+		return cloudian.QualityOfServiceLimits{}, errors.Wrap(err, "inboundBytesPerMin")
 	}
 	if qosl.OutboundKiBsPerMin, err = limits.OutboundBytesPerMin.ToKiB(); err != nil {
-		return cloudian.QualityOfServiceLimits{}, err
+		return cloudian.QualityOfServiceLimits{}, errors.Wrap(err, "outboundBytesPerMin")
 	}
 
 	if limits.StorageQuotaCount != nil {
@@ -47,3 +71,165 @@ func ToCloudianLimits(limits *userv1alpha1common.QualityOfServiceLimits) (cloudi
 
 	return qosl, nil
 }
+
+// FromCloudianQOS is the inverse of ToCloudianQOS, converting the limits
+// enforced by Cloudian to a spec. Limits that are not set or Unlimited are
+// left unset, as are blocks without limits. Limits that do not fit in the
+// spec, such as counts beyond the range of uint32, are an error.
+func FromCloudianQOS(qos cloudian.QualityOfService) (userv1alpha1common.QOS, error) {
+	var err error
+	spec := userv1alpha1common.QOS{}
+
+	if spec.Warning, err = FromCloudianLimits(qos.Warning); err != nil {
+		return userv1alpha1common.QOS{}, errors.Wrap(err, "warning")
+	}
+	if spec.Hard, err = FromCloudianLimits(qos.Hard); err != nil {
+		return userv1alpha1common.QOS{}, errors.Wrap(err, "hard")
+	}
+	return spec, nil
+}
+
+// FromCloudianLimits converts one block of limits enforced by Cloudian, see
+// FromCloudianQOS.
+func FromCloudianLimits(l cloudian.QualityOfServiceLimits) (*userv1alpha1common.QualityOfServiceLimits, error) {
+	var err error
+	limits := &userv1alpha1common.QualityOfServiceLimits{}
+
+	if limits.StorageQuotaBytes, err = fromKiB("storageQuotaBytes", l.StorageQuotaKiBs); err != nil {
+		return nil, err
+	}
+	if limits.InboundBytesPerMin, err = fromKiB("inboundBytesPerMin", l.InboundKiBsPerMin); err != nil {
+		return nil, err
+	}
+	if limits.OutboundBytesPerMin, err = fromKiB("outboundBytesPerMin", l.OutboundKiBsPerMin); err != nil {
+		return nil, err
+	}
+	if limits.StorageQuotaCount, err = fromCount("storageQuotaCount", l.StorageQuotaCount); err != nil {
+		return nil, err
+	}
+	if limits.RequestsPerMin, err = fromCount("requestsPerMin", l.RequestsPerMin); err != nil {
+		return nil, err
+	}
+
+	if *limits == (userv1alpha1common.QualityOfServiceLimits{}) {
+		return nil, nil
+	}
+	return limits, nil
+}
+
+// fromKiB returns a limit in KiB as a quantity of bytes.
+func fromKiB(name string, kib *int64) (*userv1alpha1common.Quantity, error) {
+	if kib == nil || *kib == Unlimited {
+		return nil, nil
+	}
+	if *kib < 0 {
+		return nil, errors.Errorf(errOverflow, name, *kib)
+	}
+	return ptr.To(userv1alpha1common.QuantityFromKiB(*kib)), nil
+}
+
+// fromCount returns a limit counting objects or requests as the uint32 of the
+// spec.
+func fromCount(name string, count *int64) (*uint32, error) {
+	if count == nil || *count == Unlimited {
+		return nil, nil
+	}
+	if *count < 0 || *count > math.MaxUint32 {
+		return nil, errors.Errorf(errOverflow, name, *count)
+	}
+	return ptr.To(uint32(*count)), nil
+}
+
+// InvalidLimits returns a condition indicating that the limits of the spec
+// cannot be sent to Cloudian.
+func InvalidLimits(err error) xpv2.Condition {
+	return xpv2.Condition{
+		Type:               TypeValidLimits,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonInvalidLimits,
+		Message:            err.Error(),
+	}
+}
+
+// ValidLimits returns a condition indicating that the limits of the spec can
+// be sent to Cloudian.
+func ValidLimits() xpv2.Condition {
+	return xpv2.Condition{
+		Type:               TypeValidLimits,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonValidLimits,
+	}
+}
+
+// Unlimited is the value Cloudian reports for a limit that is not enforced.
+const Unlimited int64 = -1
+
+// Explicit returns the supplied quality of service with every limit that is
+// not set made Unlimited, so that limits removed from the spec are removed in
+// Cloudian too.
+func Explicit(qos cloudian.QualityOfService) cloudian.QualityOfService {
+	return cloudian.QualityOfService{
+		Warning: mapLimits(qos.Warning, func(v *int64) *int64 { return ptr.To(ptr.Deref(v, Unlimited)) }),
+		Hard:    mapLimits(qos.Hard, func(v *int64) *int64 { return ptr.To(ptr.Deref(v, Unlimited)) }),
+	}
+}
+
+// IsUpToDate returns whether the observed quality of service enforces the
+// desired limits. Limits that are not set are desired to be Unlimited, so a
+// spec without limits is up to date when nothing is enforced.
+// Unlike the LDAP settings of groups, limits are never late initialized,
+// since an unset limit asks for no limit rather than for the observed one.
+func IsUpToDate(desired userv1alpha1common.QOS, observed cloudian.QualityOfService) (bool, error) {
+	expected, err := ToCloudianQOS(desired)
+	if err != nil {
+		return false, err
+	}
+	expected, observed = implicit(expected), implicit(observed)
+	return expected.Warning.Equal(observed.Warning) && expected.Hard.Equal(observed.Hard), nil
+}
+
+// implicit is the inverse of Explicit, leaving Unlimited limits unset.
+func implicit(qos cloudian.QualityOfService) cloudian.QualityOfService {
+	unset := func(v *int64) *int64 {
+		if v == nil || *v == Unlimited {
+			return nil
+		}
+		return v
+	}
+	return cloudian.QualityOfService{
+		Warning: mapLimits(qos.Warning, unset),
+		Hard:    mapLimits(qos.Hard, unset),
+	}
+}
+
+func mapLimits(l cloudian.QualityOfServiceLimits, fn func(*int64) *int64) cloudian.QualityOfServiceLimits {
+	return cloudian.QualityOfServiceLimits{
+		StorageQuotaKiBs:   fn(l.StorageQuotaKiBs),
+		StorageQuotaCount:  fn(l.StorageQuotaCount),
+		RequestsPerMin:     fn(l.RequestsPerMin),
+		InboundKiBsPerMin:  fn(l.InboundKiBsPerMin),
+		OutboundKiBsPerMin: fn(l.OutboundKiBsPerMin),
+	}
+}
+
+// Normalize returns the byte limits of the supplied quality of service in KiB,
+// for reporting in status.
+func Normalize(qos cloudian.QualityOfService) userv1alpha1common.NormalizedQOS {
+	return userv1alpha1common.NormalizedQOS{
+		Warning: normalizeLimits(qos.Warning),
+		Hard:    normalizeLimits(qos.Hard),
+	}
+}
+
+func normalizeLimits(l cloudian.QualityOfServiceLimits) *userv1alpha1common.NormalizedQualityOfServiceLimits {
+	if l.StorageQuotaKiBs == nil && l.InboundKiBsPerMin == nil && l.OutboundKiBsPerMin == nil {
+		return nil
+	}
+	return &userv1alpha1common.NormalizedQualityOfServiceLimits{
+		StorageQuotaKiBs:   l.StorageQuotaKiBs,
+		InboundKiBsPerMin:  l.InboundKiBsPerMin,
+		OutboundKiBsPerMin: l.OutboundKiBsPerMin,
+	}
+}
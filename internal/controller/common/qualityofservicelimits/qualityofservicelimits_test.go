@@ -0,0 +1,356 @@
+package qualityofservicelimits
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/utils/ptr"
+
+	userv1alpha1common "github.com/statnett/provider-cloudian/apis/common/user/v1alpha1"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian/cloudiantest"
+)
+
+func quantity(s string) *userv1alpha1common.Quantity {
+	q := userv1alpha1common.Quantity(s)
+	return &q
+}
+
+func TestToKiB(t *testing.T) {
+	cases := map[string]struct {
+		q       *userv1alpha1common.Quantity
+		want    *int64
+		wantErr bool
+	}{
+		"Aligned": {
+			q:    quantity("1Gi"),
+			want: ptr.To[int64](1024 * 1024),
+		},
+		"Misaligned": {
+			q:       quantity("1.5Ki"),
+			wantErr: true,
+		},
+		"MisalignedBytes": {
+			q:       quantity("1500"),
+			wantErr: true,
+		},
+		"Zero": {
+			q:    quantity("0"),
+			want: ptr.To[int64](0),
+		},
+		"Large": {
+			q:    quantity("177Ti"),
+			want: ptr.To[int64](177 * 1024 * 1024 * 1024),
+		},
+		"Decimal": {
+			q:       quantity("2k"),
+			wantErr: true,
+		},
+		"TooLarge": {
+			q:       quantity("8192Pi"),
+			wantErr: true,
+		},
+		"Unlimited": {
+			q: nil,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := tc.q.ToKiB()
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ToKiB(): want error %t, got %v", tc.wantErr, err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("ToKiB(): -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	qos, err := ToCloudianQOS(userv1alpha1common.QOS{
+		Hard: &userv1alpha1common.QualityOfServiceLimits{
+			StorageQuotaBytes:  quantity("2Mi"),
+			InboundBytesPerMin: quantity("0"),
+			RequestsPerMin:     ptr.To[uint32](10),
+		},
+	})
+	if err != nil {
+		t.Fatalf("ToCloudianQOS(...): %v", err)
+	}
+
+	want := userv1alpha1common.NormalizedQOS{
+		Hard: &userv1alpha1common.NormalizedQualityOfServiceLimits{
+			StorageQuotaKiBs:  ptr.To[int64](2048),
+			InboundKiBsPerMin: ptr.To[int64](0),
+		},
+	}
+	if diff := cmp.Diff(want, Normalize(qos)); diff != "" {
+		t.Errorf("Normalize(...): -want, +got:\n%s", diff)
+	}
+
+	if diff := cmp.Diff(userv1alpha1common.NormalizedQOS{}, Normalize(cloudian.QualityOfService{})); diff != "" {
+		t.Errorf("Normalize(...) of unlimited: -want, +got:\n%s", diff)
+	}
+}
+
+func TestExplicit(t *testing.T) {
+	qos := Explicit(cloudian.QualityOfService{Hard: cloudian.QualityOfServiceLimits{StorageQuotaKiBs: ptr.To[int64](1024)}})
+	unlimited := ptr.To(Unlimited)
+	want := cloudian.QualityOfService{
+		Warning: cloudian.QualityOfServiceLimits{
+			StorageQuotaKiBs:   unlimited,
+			StorageQuotaCount:  unlimited,
+			RequestsPerMin:     unlimited,
+			InboundKiBsPerMin:  unlimited,
+			OutboundKiBsPerMin: unlimited,
+		},
+		Hard: cloudian.QualityOfServiceLimits{
+			StorageQuotaKiBs:   ptr.To[int64](1024),
+			StorageQuotaCount:  unlimited,
+			RequestsPerMin:     unlimited,
+			InboundKiBsPerMin:  unlimited,
+			OutboundKiBsPerMin: unlimited,
+		},
+	}
+	if diff := cmp.Diff(want, qos); diff != "" {
+		t.Errorf("Explicit(...): -want, +got:\n%s", diff)
+	}
+}
+
+func TestIsUpToDate(t *testing.T) {
+	cases := map[string]struct {
+		desired  userv1alpha1common.QOS
+		observed cloudian.QualityOfService
+		want     bool
+		wantErr  bool
+	}{
+		"AllUnlimited": {
+			desired: userv1alpha1common.QOS{},
+			want:    true,
+		},
+		"AllUnlimitedObservedExplicitly": {
+			desired: userv1alpha1common.QOS{},
+			observed: cloudian.QualityOfService{
+				Warning: cloudian.QualityOfServiceLimits{RequestsPerMin: ptr.To(Unlimited)},
+			},
+			want: true,
+		},
+		"AllUnlimitedObservedLimit": {
+			desired: userv1alpha1common.QOS{},
+			observed: cloudian.QualityOfService{
+				Hard: cloudian.QualityOfServiceLimits{RequestsPerMin: ptr.To[int64](10)},
+			},
+		},
+		"Bytes": {
+			desired: userv1alpha1common.QOS{
+				Hard: &userv1alpha1common.QualityOfServiceLimits{StorageQuotaBytes: quantity("1Gi")},
+			},
+			observed: cloudian.QualityOfService{
+				Hard: cloudian.QualityOfServiceLimits{StorageQuotaKiBs: ptr.To[int64](1024 * 1024)},
+			},
+			want: true,
+		},
+		"BytesChanged": {
+			desired: userv1alpha1common.QOS{
+				Hard: &userv1alpha1common.QualityOfServiceLimits{StorageQuotaBytes: quantity("2Gi")},
+			},
+			observed: cloudian.QualityOfService{
+				Hard: cloudian.QualityOfServiceLimits{StorageQuotaKiBs: ptr.To[int64](1024 * 1024)},
+			},
+		},
+		"BytesRemoved": {
+			desired: userv1alpha1common.QOS{
+				Hard: &userv1alpha1common.QualityOfServiceLimits{InboundBytesPerMin: quantity("1Mi")},
+			},
+			observed: cloudian.QualityOfService{
+				Hard: cloudian.QualityOfServiceLimits{
+					StorageQuotaKiBs:  ptr.To[int64](1024),
+					InboundKiBsPerMin: ptr.To[int64](1024),
+				},
+			},
+		},
+		"BytesMisaligned": {
+			desired: userv1alpha1common.QOS{
+				Warning: &userv1alpha1common.QualityOfServiceLimits{OutboundBytesPerMin: quantity("1500")},
+			},
+			wantErr: true,
+		},
+		"Counts": {
+			desired: userv1alpha1common.QOS{
+				Warning: &userv1alpha1common.QualityOfServiceLimits{
+					StorageQuotaCount: ptr.To[uint32](1000),
+					RequestsPerMin:    ptr.To[uint32](60),
+				},
+			},
+			observed: cloudian.QualityOfService{
+				Warning: cloudian.QualityOfServiceLimits{
+					StorageQuotaCount:  ptr.To[int64](1000),
+					RequestsPerMin:     ptr.To[int64](60),
+					InboundKiBsPerMin:  ptr.To(Unlimited),
+					OutboundKiBsPerMin: ptr.To(Unlimited),
+				},
+			},
+			want: true,
+		},
+		"CountsInWrongLimit": {
+			desired: userv1alpha1common.QOS{
+				Warning: &userv1alpha1common.QualityOfServiceLimits{RequestsPerMin: ptr.To[uint32](60)},
+			},
+			observed: cloudian.QualityOfService{
+				Hard: cloudian.QualityOfServiceLimits{RequestsPerMin: ptr.To[int64](60)},
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := IsUpToDate(tc.desired, tc.observed)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("IsUpToDate(...): want error %t, got %v", tc.wantErr, err)
+			}
+			if got != tc.want {
+				t.Errorf("IsUpToDate(...): want %t, got %t", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestFromCloudianQOS(t *testing.T) {
+	cases := map[string]struct {
+		qos     cloudian.QualityOfService
+		want    userv1alpha1common.QOS
+		wantErr bool
+	}{
+		"Unset": {},
+		"Unlimited": {
+			qos: cloudian.QualityOfService{Hard: cloudian.QualityOfServiceLimits{StorageQuotaKiBs: ptr.To(Unlimited), RequestsPerMin: ptr.To(Unlimited)}},
+		},
+		"Limits": {
+			qos: cloudian.QualityOfService{
+				Warning: cloudian.QualityOfServiceLimits{RequestsPerMin: ptr.To[int64](100)},
+				Hard:    cloudian.QualityOfServiceLimits{StorageQuotaKiBs: ptr.To[int64](1024 * 1024), StorageQuotaCount: ptr.To[int64](1000)},
+			},
+			want: userv1alpha1common.QOS{
+				Warning: &userv1alpha1common.QualityOfServiceLimits{RequestsPerMin: ptr.To[uint32](100)},
+				Hard:    &userv1alpha1common.QualityOfServiceLimits{StorageQuotaBytes: quantity("1Gi"), StorageQuotaCount: ptr.To[uint32](1000)},
+			},
+		},
+		"CountOverflow": {
+			qos:     cloudian.QualityOfService{Hard: cloudian.QualityOfServiceLimits{StorageQuotaCount: ptr.To[int64](math.MaxUint32 + 1)}},
+			wantErr: true,
+		},
+		"LargeBytes": {
+			qos:  cloudian.QualityOfService{Warning: cloudian.QualityOfServiceLimits{InboundKiBsPerMin: ptr.To[int64](1024 * 1024 * 1024 * 1024)}},
+			want: userv1alpha1common.QOS{Warning: &userv1alpha1common.QualityOfServiceLimits{InboundBytesPerMin: quantity("1024Ti")}},
+		},
+		"NegativeBytes": {
+			qos:     cloudian.QualityOfService{Warning: cloudian.QualityOfServiceLimits{InboundKiBsPerMin: ptr.To[int64](-2)}},
+			wantErr: true,
+		},
+		"NegativeCount": {
+			qos:     cloudian.QualityOfService{Hard: cloudian.QualityOfServiceLimits{RequestsPerMin: ptr.To[int64](-2)}},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := FromCloudianQOS(tc.qos)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("FromCloudianQOS(...): want error %t, got %v", tc.wantErr, err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("FromCloudianQOS(...): -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestToCloudianQOSInvalid(t *testing.T) {
+	_, err := ToCloudianQOS(userv1alpha1common.QOS{Hard: &userv1alpha1common.QualityOfServiceLimits{OutboundBytesPerMin: quantity("1.5Ki")}})
+	if err == nil || !strings.HasPrefix(err.Error(), "hard: outboundBytesPerMin: ") {
+		t.Errorf("ToCloudianQOS(...): want an error naming the limit, got %v", err)
+	}
+}
+
+// randomLimits returns a block of limits with each limit set at random, or
+// nil.
+func randomLimits(r *rand.Rand) *userv1alpha1common.QualityOfServiceLimits {
+	if r.IntN(4) == 0 {
+		return nil
+	}
+	bytes := func() *userv1alpha1common.Quantity {
+		if r.IntN(2) == 0 {
+			return nil
+		}
+		units := []string{"Ki", "Mi", "Gi", "Ti"}
+		return quantity(strconv.Itoa(r.IntN(1024)+1) + units[r.IntN(len(units))])
+	}
+	count := func() *uint32 {
+		if r.IntN(2) == 0 {
+			return nil
+		}
+		return ptr.To(r.Uint32())
+	}
+	limits := &userv1alpha1common.QualityOfServiceLimits{
+		StorageQuotaBytes:   bytes(),
+		StorageQuotaCount:   count(),
+		RequestsPerMin:      count(),
+		InboundBytesPerMin:  bytes(),
+		OutboundBytesPerMin: bytes(),
+	}
+	if *limits == (userv1alpha1common.QualityOfServiceLimits{}) {
+		return nil
+	}
+	return limits
+}
+
+// TestRoundTrip sets random limits in Cloudian and reads them back, which
+// must give the limits that were set.
+func TestRoundTrip(t *testing.T) {
+	srv := cloudiantest.NewFakeServer(t)
+	svc := cloudian.NewClient(srv.URL, "")
+	guid := cloudian.GroupUserID{GroupID: "QA", UserID: "alice"}
+	ctx := context.Background()
+
+	// Byte limits are compared in KiB, since Cloudian does not keep the unit
+	// they were given in.
+	equateKiB := cmp.Comparer(func(a, b userv1alpha1common.Quantity) bool {
+		x, errX := a.ToKiB()
+		y, errY := b.ToKiB()
+		return errX == nil && errY == nil && *x == *y
+	})
+
+	r := rand.New(rand.NewPCG(1, 2))
+	for i := range 100 {
+		spec := userv1alpha1common.QOS{Warning: randomLimits(r), Hard: randomLimits(r)}
+		qos, err := ToCloudianQOS(spec)
+		if err != nil {
+			t.Fatalf("%d: ToCloudianQOS(%+v): %v", i, spec, err)
+		}
+		if err := svc.SetQOS(ctx, guid, cloudian.DefaultRegion, Explicit(qos)); err != nil {
+			t.Fatalf("%d: SetQOS(...): %v", i, err)
+		}
+		observed, err := svc.GetQOS(ctx, guid, cloudian.DefaultRegion)
+		if err != nil {
+			t.Fatalf("%d: GetQOS(...): %v", i, err)
+		}
+		got, err := FromCloudianQOS(*observed)
+		if err != nil {
+			t.Fatalf("%d: FromCloudianQOS(...): %v", i, err)
+		}
+		if diff := cmp.Diff(spec, got, equateKiB); diff != "" {
+			t.Errorf("%d: -set, +read:\n%s", i, diff)
+		}
+		if upToDate, err := IsUpToDate(spec, *observed); err != nil || !upToDate {
+			t.Errorf("%d: IsUpToDate(...): want the limits read back up to date, got %t, %v", i, upToDate, err)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package qualityofservicelimits
+
+import (
+	"slices"
+
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AnnotationAllowUnsupportedGroup makes the quality of service limits
+// controllers apply limits to groups known not to enforce them.
+const AnnotationAllowUnsupportedGroup = "cloudian.crossplane.io/allow-unsupported-group"
+
+const (
+	// TypeSupported indicates whether Cloudian enforces quality of service
+	// limits for the group.
+	TypeSupported xpv2.ConditionType = "Supported"
+	// ReasonUnsupportedGroup means Cloudian accepts but ignores quality of
+	// service limits for the group.
+	ReasonUnsupportedGroup xpv2.ConditionReason = "UnsupportedGroup"
+)
+
+// ErrUnsupportedGroup is returned when quality of service limits are
+// configured for a group that does not enforce them.
+var ErrUnsupportedGroup = errors.New("quality of service limits are not enforced for this group")
+
+// UnsupportedGroupIDs are the IDs of the built-in groups for which Cloudian
+// accepts quality of service limits without enforcing them.
+var UnsupportedGroupIDs = []string{
+	// The system admin group.
+	"0",
+}
+
+// CheckSupported returns ErrUnsupportedGroup if Cloudian does not enforce
+// quality of service limits for the supplied group, unless the supplied
+// object is annotated to allow it.
+func CheckSupported(o metav1.Object, groupID string) error {
+	if !slices.Contains(UnsupportedGroupIDs, groupID) {
+		return nil
+	}
+	if o.GetAnnotations()[AnnotationAllowUnsupportedGroup] == "true" {
+		return nil
+	}
+	return errors.Wrapf(ErrUnsupportedGroup, "group %q", groupID)
+}
+
+// Unsupported returns a condition indicating that quality of service limits
+// are not applied because the group does not enforce them.
+func Unsupported(err error) xpv2.Condition {
+	return xpv2.Condition{
+		Type:               TypeSupported,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonUnsupportedGroup,
+		Message:            err.Error(),
+	}
+}
@@ -0,0 +1,42 @@
+package qualityofservicelimits
+
+import (
+	"errors"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCheckSupported(t *testing.T) {
+	cases := map[string]struct {
+		groupID     string
+		annotations map[string]string
+		wantErr     error
+	}{
+		"Supported": {
+			groupID: "QA",
+		},
+		"Unsupported": {
+			groupID: "0",
+			wantErr: ErrUnsupportedGroup,
+		},
+		"Overridden": {
+			groupID:     "0",
+			annotations: map[string]string{AnnotationAllowUnsupportedGroup: "true"},
+		},
+		"NotOverridden": {
+			groupID:     "0",
+			annotations: map[string]string{AnnotationAllowUnsupportedGroup: "false"},
+			wantErr:     ErrUnsupportedGroup,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			o := &metav1.ObjectMeta{Annotations: tc.annotations}
+			if err := CheckSupported(o, tc.groupID); !errors.Is(err, tc.wantErr) {
+				t.Errorf("CheckSupported(...): want error %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
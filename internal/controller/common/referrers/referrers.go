@@ -0,0 +1,68 @@
+// Package referrers requeues managed resources when a resource they refer to
+// becomes ready, such as the Group of a User or the User of an AccessKey, so
+// that they do not wait out the poll interval before being created.
+package referrers
+
+import (
+	"maps"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// Refers returns whether the reference or selector of a managed resource may
+// resolve to the object `o`. A resolved reference takes precedence over the
+// selector, as it does when resolving. Whether the selector requires the same
+// controller is ignored, since requeuing a resource needlessly is harmless.
+func Refers(ref *xpv2.Reference, sel *xpv2.Selector, o client.Object) bool {
+	if ref != nil {
+		return ref.Name == o.GetName()
+	}
+	return sel != nil && labels.SelectorFromSet(sel.MatchLabels).Matches(labels.Set(o.GetLabels()))
+}
+
+// Requests returns the requests reconciling the items for which `refers`
+// returns true.
+func Requests[T any, PT interface {
+	*T
+	client.Object
+}](items []T, refers func(PT) bool) []reconcile.Request {
+	var requests []reconcile.Request
+	for i := range items {
+		if o := PT(&items[i]); refers(o) {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(o)})
+		}
+	}
+	return requests
+}
+
+// Resolvable passes the events of referenced resources after which the
+// resources referring to them may resolve or be created: their creation, and
+// updates of their readiness, external name or labels.
+func Resolvable() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return ready(e.ObjectOld) != ready(e.ObjectNew) ||
+				meta.GetExternalName(e.ObjectOld) != meta.GetExternalName(e.ObjectNew) ||
+				!maps.Equal(e.ObjectOld.GetLabels(), e.ObjectNew.GetLabels())
+		},
+		DeleteFunc:  func(event.DeleteEvent) bool { return false },
+		GenericFunc: func(event.GenericEvent) bool { return false },
+	}
+}
+
+// ready returns the status of the Ready condition of an object, if it has
+// conditions.
+func ready(o client.Object) string {
+	c, ok := o.(resource.Conditioned)
+	if !ok {
+		return ""
+	}
+	return string(c.GetCondition(xpv2.TypeReady).Status)
+}
@@ -0,0 +1,76 @@
+package referrers
+
+import (
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+
+	userv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/user/v1alpha1"
+)
+
+func TestRefers(t *testing.T) {
+	g := &userv1alpha1cluster.Group{}
+	g.SetName("qa")
+	g.SetLabels(map[string]string{"team": "a"})
+
+	cases := map[string]struct {
+		ref  *xpv2.Reference
+		sel  *xpv2.Selector
+		want bool
+	}{
+		"Neither":          {},
+		"Ref":              {ref: &xpv2.Reference{Name: "qa"}, want: true},
+		"OtherRef":         {ref: &xpv2.Reference{Name: "ops"}},
+		"Selector":         {sel: &xpv2.Selector{MatchLabels: map[string]string{"team": "a"}}, want: true},
+		"OtherSelector":    {sel: &xpv2.Selector{MatchLabels: map[string]string{"team": "b"}}},
+		"RefOverSelector":  {ref: &xpv2.Reference{Name: "ops"}, sel: &xpv2.Selector{MatchLabels: map[string]string{"team": "a"}}},
+		"SelectorAnyLabel": {sel: &xpv2.Selector{}, want: true},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := Refers(tc.ref, tc.sel, g); got != tc.want {
+				t.Errorf("Refers(...): want %t, got %t", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestResolvable(t *testing.T) {
+	old := &userv1alpha1cluster.Group{}
+	old.SetName("qa")
+
+	ready := old.DeepCopy()
+	ready.SetConditions(xpv2.Available())
+	renamed := old.DeepCopy()
+	meta.SetExternalName(renamed, "QA")
+	labeled := old.DeepCopy()
+	labeled.SetLabels(map[string]string{"team": "a"})
+	annotated := old.DeepCopy()
+	annotated.SetAnnotations(map[string]string{"note": "x"})
+
+	cases := map[string]struct {
+		updated *userv1alpha1cluster.Group
+		want    bool
+	}{
+		"Ready":        {updated: ready, want: true},
+		"ExternalName": {updated: renamed, want: true},
+		"Labels":       {updated: labeled, want: true},
+		"Other":        {updated: annotated},
+	}
+	p := Resolvable()
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := p.Update(event.UpdateEvent{ObjectOld: old, ObjectNew: tc.updated}); got != tc.want {
+				t.Errorf("Update(...): want %t, got %t", tc.want, got)
+			}
+		})
+	}
+	if !p.Create(event.CreateEvent{Object: old}) {
+		t.Error("Create(...): want creations passed")
+	}
+	if p.Delete(event.DeleteEvent{Object: old}) {
+		t.Error("Delete(...): want deletions filtered")
+	}
+}
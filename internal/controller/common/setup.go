@@ -0,0 +1,124 @@
+package common
+
+import (
+	"context"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/controller"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/event"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/feature"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/ratelimiter"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/statnett/provider-cloudian/internal/controller/common/apierror"
+	"github.com/statnett/provider-cloudian/internal/controller/common/correlation"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
+)
+
+// A ManagedKind configures the controller of one kind of managed resource.
+type ManagedKind struct {
+	// GroupVersionKind of the managed resource.
+	GroupVersionKind schema.GroupVersionKind
+	// Timeout bounds each reconcile, usually one of ReconcileTimeouts.
+	Timeout time.Duration
+	// NewConnector returns the connector to the external resources, given the
+	// event recorder of the controller.
+	NewConnector func(recorder event.Recorder) managed.ExternalConnector
+	// Options of the managed reconciler specific to the kind, such as
+	// initializers and reference resolvers.
+	Options []managed.ReconcilerOption
+	// Watches requeue managed resources of the kind on events of other
+	// kinds, such as the resources they refer to.
+	Watches []Watch
+}
+
+// A Watch requeues the managed resources that Map returns for the events of
+// objects of another kind that pass its predicates.
+type Watch struct {
+	Object     client.Object
+	Map        handler.MapFunc
+	Predicates []predicate.Predicate
+}
+
+// SetupManaged adds a controller that reconciles managed resources of type T
+// and of the supplied kind. Options shared by every kind, such as the poll
+// interval, the handling of API errors, correlation IDs, tracing and rate
+// limiting, are applied here, as is the support of management policies when
+// the feature is enabled.
+func SetupManaged[T any, PT interface {
+	*T
+	resource.Managed
+}](mgr ctrl.Manager, o controller.Options, kind ManagedKind) error {
+	name := managed.ControllerName(kind.GroupVersionKind.GroupKind().String())
+	correlations := correlation.NewTracker()
+	//nolint:staticcheck // SA1004 crossplane-runtime still depends on deprecated API
+	recorder := correlations.Recorder(event.NewAPIRecorder(mgr.GetEventRecorderFor(name)))
+	apiErrors := apierror.NewHandler(recorder)
+
+	opts := append([]managed.ReconcilerOption{
+		managed.WithExternalConnector(apiErrors.Connector(kind.NewConnector(recorder))),
+		managed.WithLogger(correlations.Logger(o.Logger.WithValues("controller", name))),
+		managed.WithPollInterval(o.PollInterval),
+		managed.WithTimeout(kind.Timeout),
+		managed.WithRecorder(recorder),
+	}, kind.Options...)
+	if o.Features.Enabled(feature.EnableBetaManagementPolicies) {
+		opts = append(opts, managed.WithManagementPolicies())
+	}
+	r := managed.NewReconciler(mgr, resource.ManagedKind(kind.GroupVersionKind), opts...)
+
+	// The filter only applies to the managed resources, as the watched
+	// objects are filtered by their own predicates.
+	b := ctrl.NewControllerManagedBy(mgr).
+		Named(name).
+		WithOptions(o.ForControllerRuntime()).
+		For(PT(new(T)), builder.WithPredicates(resource.DesiredStateChanged()))
+	for _, w := range kind.Watches {
+		b = b.Watches(w.Object, handler.EnqueueRequestsFromMapFunc(w.Map), builder.WithPredicates(w.Predicates...))
+	}
+	return b.Complete(ratelimiter.NewReconciler(name, correlations.Reconciler(traced(name, apiErrors.Reconciler(r))), o.GlobalRateLimiter))
+}
+
+// traced wraps a Reconciler so that each reconcile has a span, the parent of
+// the spans of the requests it makes to Cloudian, when tracing.
+func traced(name string, r reconcile.Reconciler) reconcile.Reconciler {
+	if TracerProvider == nil {
+		return r
+	}
+	tracer := TracerProvider.Tracer("github.com/statnett/provider-cloudian/internal/controller")
+	return reconcile.Func(func(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+		ctx, span := tracer.Start(ctx, "Reconcile", trace.WithAttributes(
+			attribute.String("controller", name),
+			attribute.String("k8s.namespace.name", req.Namespace),
+			attribute.String("k8s.object.name", req.Name),
+			attribute.String(correlation.Key, cloudian.RequestID(ctx)),
+		))
+		defer span.End()
+
+		result, err := r.Reconcile(ctx, req)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		return result, err
+	})
+}
+
+// ObserveOnly returns whether the management policies of a managed resource
+// only allow observing its external resource.
+func ObserveOnly(mg resource.Managed) bool {
+	policies := mg.GetManagementPolicies()
+	return len(policies) == 1 && policies[0] == xpv2.ManagementActionObserve
+}
@@ -0,0 +1,104 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/controller"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/event"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/config"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	apiscluster "github.com/statnett/provider-cloudian/apis/cluster"
+	userv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/user/v1alpha1"
+	pcv1alpha1common "github.com/statnett/provider-cloudian/apis/common/providerconfig/v1alpha1"
+	"github.com/statnett/provider-cloudian/internal/controller/common/correlation"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian/cloudiantest"
+)
+
+func TestSetupManaged(t *testing.T) {
+	mgr, err := ctrl.NewManager(&rest.Config{Host: "https://127.0.0.1:1"}, ctrl.Options{
+		Metrics:    metricsserver.Options{BindAddress: "0"},
+		Controller: config.Controller{SkipNameValidation: ptr.To(true)},
+	})
+	if err != nil {
+		t.Fatalf("ctrl.NewManager(...): %v", err)
+	}
+	if err := apiscluster.AddToScheme(mgr.GetScheme()); err != nil {
+		t.Fatalf("AddToScheme(...): %v", err)
+	}
+
+	var recorders []event.Recorder
+	err = SetupManaged[userv1alpha1cluster.Group](mgr, controller.Options{Logger: logging.NewNopLogger()}, ManagedKind{
+		GroupVersionKind: userv1alpha1cluster.GroupGroupVersionKind,
+		Timeout:          ReconcileTimeouts.Group,
+		NewConnector: func(recorder event.Recorder) managed.ExternalConnector {
+			recorders = append(recorders, recorder)
+			return managed.ExternalConnectorFn(func(context.Context, resource.Managed) (managed.ExternalClient, error) {
+				return nil, nil
+			})
+		},
+		Watches: []Watch{{
+			Object: &userv1alpha1cluster.User{},
+			Map:    func(context.Context, client.Object) []reconcile.Request { return nil },
+		}},
+	})
+	if err != nil {
+		t.Fatalf("SetupManaged(...): %v", err)
+	}
+	if len(recorders) != 1 || recorders[0] == nil {
+		t.Errorf("SetupManaged(...): want the connector created once with the recorder of the controller, got %d recorders %v", len(recorders), recorders)
+	}
+}
+
+func TestTraced(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	TracerProvider = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { TracerProvider = nil })
+
+	srv := cloudiantest.NewFakeServer(t)
+	svc := cloudian.NewClient(srv.URL, "", ClientOptions(pcv1alpha1common.ProviderConfigSpec{})...)
+	r := traced("managed/group", reconcile.Func(func(ctx context.Context, _ reconcile.Request) (reconcile.Result, error) {
+		_, err := svc.GetGroup(ctx, "QA")
+		return reconcile.Result{}, err
+	}))
+
+	ctx := cloudian.WithRequestID(context.Background(), "1234")
+	if _, err := r.Reconcile(ctx, reconcile.Request{NamespacedName: types.NamespacedName{Name: "qa"}}); !errors.Is(err, cloudian.ErrNotFound) {
+		t.Fatalf("r.Reconcile(...): want ErrNotFound, got %v", err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 || spans[0].Name() != "GetGroup" || spans[1].Name() != "Reconcile" {
+		t.Fatalf("r.Reconcile(...): want a GetGroup and a Reconcile span, got %d spans", len(spans))
+	}
+	if spans[0].Parent().SpanID() != spans[1].SpanContext().SpanID() {
+		t.Error("r.Reconcile(...): want the span of the request a child of the span of the reconcile")
+	}
+	if spans[1].Status().Code != codes.Error {
+		t.Errorf("r.Reconcile(...): want the span of the failed reconcile an error, got %v", spans[1].Status())
+	}
+	var id string
+	for _, a := range spans[1].Attributes() {
+		if a.Key == correlation.Key {
+			id = a.Value.AsString()
+		}
+	}
+	if id != "1234" {
+		t.Errorf("r.Reconcile(...): want the span of the reconcile with %s %q, got %q", correlation.Key, "1234", id)
+	}
+}
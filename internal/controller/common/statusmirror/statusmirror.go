@@ -0,0 +1,305 @@
+// Package statusmirror mirrors the conditions and non-secret status of managed
+// resources to ConfigMaps, so that tenants who cannot read the managed
+// resources can see why they are not ready.
+package statusmirror
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/controller"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	userv1alpha1common "github.com/statnett/provider-cloudian/apis/common/user/v1alpha1"
+)
+
+const (
+	// AnnotationSource identifies the managed resource mirrored by a
+	// ConfigMap. ConfigMaps without it are never modified.
+	AnnotationSource = "cloudian.crossplane.io/status-mirror-of"
+
+	// KeyConditions is the ConfigMap key holding the mirrored conditions.
+	KeyConditions = "conditions"
+
+	// MaxMessageLength is the length condition messages are truncated to.
+	MaxMessageLength = 1024
+
+	// DefaultMinInterval is the default minimum time between two updates of
+	// the same ConfigMap.
+	DefaultMinInterval = 10 * time.Second
+)
+
+const (
+	errGetMR           = "cannot get managed resource"
+	errGetConfigMap    = "cannot get status mirror ConfigMap"
+	errCreateConfigMap = "cannot create status mirror ConfigMap"
+	errUpdateConfigMap = "cannot update status mirror ConfigMap"
+	errDeleteConfigMap = "cannot delete status mirror ConfigMap"
+	errMarshal         = "cannot marshal conditions"
+	errNotOwned        = "ConfigMap %s is not a status mirror of %s"
+	errOtherNamespace  = "namespaced managed resources can only mirror their status to their own namespace"
+	errNoNamespace     = "cluster scoped managed resources must specify the namespace of their status mirror"
+)
+
+// A Mirrored managed resource can mirror its status to a ConfigMap.
+type Mirrored interface {
+	resource.Managed
+	GetStatusMirror() *userv1alpha1common.StatusMirror
+	GetMirroredStatus() map[string]string
+}
+
+// Target returns the ConfigMap the supplied managed resource mirrors its
+// status to. Namespaced managed resources can only mirror to their own
+// namespace, so that tenants cannot use the provider to write to namespaces
+// they have no access to.
+func Target(mr Mirrored) (types.NamespacedName, error) {
+	ref := mr.GetStatusMirror()
+	switch {
+	case mr.GetNamespace() != "" && ref.Namespace != "" && ref.Namespace != mr.GetNamespace():
+		return types.NamespacedName{}, errors.New(errOtherNamespace)
+	case mr.GetNamespace() != "":
+		return types.NamespacedName{Namespace: mr.GetNamespace(), Name: ref.Name}, nil
+	case ref.Namespace == "":
+		return types.NamespacedName{}, errors.New(errNoNamespace)
+	default:
+		return types.NamespacedName{Namespace: ref.Namespace, Name: ref.Name}, nil
+	}
+}
+
+// condition is a mirrored condition.
+type condition struct {
+	Type               xpv2.ConditionType     `json:"type"`
+	Status             corev1.ConditionStatus `json:"status"`
+	Reason             xpv2.ConditionReason   `json:"reason,omitempty"`
+	Message            string                 `json:"message,omitempty"`
+	LastTransitionTime metav1.Time            `json:"lastTransitionTime"`
+}
+
+// Data returns the ConfigMap data mirroring the supplied managed resource.
+func Data(mr Mirrored, conditions []xpv2.Condition) (map[string]string, error) {
+	mirrored := make([]condition, 0, len(conditions))
+	for _, c := range conditions {
+		msg := c.Message
+		if len(msg) > MaxMessageLength {
+			msg = msg[:MaxMessageLength] + "..."
+		}
+		mirrored = append(mirrored, condition{
+			Type:               c.Type,
+			Status:             c.Status,
+			Reason:             c.Reason,
+			Message:            msg,
+			LastTransitionTime: c.LastTransitionTime,
+		})
+	}
+	raw, err := json.Marshal(mirrored)
+	if err != nil {
+		return nil, errors.Wrap(err, errMarshal)
+	}
+
+	data := map[string]string{KeyConditions: string(raw)}
+	maps.Copy(data, mr.GetMirroredStatus())
+	return data, nil
+}
+
+// A Reconciler mirrors the status of managed resources of one kind.
+type Reconciler struct {
+	kube        client.Client
+	gvk         schema.GroupVersionKind
+	newMR       func() Mirrored
+	minInterval time.Duration
+	clock       clock.PassiveClock
+
+	mu          sync.Mutex
+	lastUpdated map[types.NamespacedName]time.Time
+}
+
+// A ReconcilerOption configures a Reconciler.
+type ReconcilerOption func(*Reconciler)
+
+// WithMinInterval sets the minimum time between two updates of the same
+// ConfigMap. Changes within the interval are mirrored when it has passed.
+func WithMinInterval(d time.Duration) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.minInterval = d
+	}
+}
+
+// WithClock sets the time source of the Reconciler, so that tests control
+// when the minimum interval has passed.
+func WithClock(c clock.PassiveClock) ReconcilerOption {
+	return func(r *Reconciler) {
+		r.clock = c
+	}
+}
+
+// NewReconciler returns a Reconciler of managed resources of the supplied kind,
+// returned by newMR.
+func NewReconciler(kube client.Client, gvk schema.GroupVersionKind, newMR func() Mirrored, opts ...ReconcilerOption) *Reconciler {
+	r := &Reconciler{
+		kube:        kube,
+		gvk:         gvk,
+		newMR:       newMR,
+		minInterval: DefaultMinInterval,
+		clock:       clock.RealClock{},
+		lastUpdated: map[types.NamespacedName]time.Time{},
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Setup adds a controller that mirrors the status of managed resources of the
+// supplied kind, returned by newMR.
+func Setup(mgr ctrl.Manager, o controller.Options, gvk schema.GroupVersionKind, newMR func() Mirrored) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("statusmirror/" + strings.ToLower(gvk.GroupKind().String())).
+		WithOptions(o.ForControllerRuntime()).
+		For(newMR()).
+		Complete(NewReconciler(mgr.GetClient(), gvk, newMR))
+}
+
+// Reconcile the status mirror of a managed resource.
+func (r *Reconciler) Reconcile(ctx context.Context, req reconcile.Request) (reconcile.Result, error) {
+	mr := r.newMR()
+	if err := r.kube.Get(ctx, req.NamespacedName, mr); err != nil {
+		return reconcile.Result{}, errors.Wrap(client.IgnoreNotFound(err), errGetMR)
+	}
+	if mr.GetStatusMirror() == nil {
+		return reconcile.Result{}, nil
+	}
+
+	target, err := Target(mr)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	if meta.WasDeleted(mr) {
+		return reconcile.Result{}, r.delete(ctx, mr, target)
+	}
+
+	if wait := r.wait(target); wait > 0 {
+		return reconcile.Result{RequeueAfter: wait}, nil
+	}
+
+	data, err := Data(mr, conditions(mr))
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{}, r.sync(ctx, mr, target, data)
+}
+
+// conditions returns the conditions of the supplied managed resource.
+func conditions(mr Mirrored) []xpv2.Condition {
+	var cs []xpv2.Condition
+	for _, t := range []xpv2.ConditionType{xpv2.TypeReady, xpv2.TypeSynced} {
+		if c := mr.GetCondition(t); c.Status != corev1.ConditionUnknown || c.Reason != "" {
+			cs = append(cs, c)
+		}
+	}
+	return cs
+}
+
+// wait returns how long to wait before the target may be updated again.
+func (r *Reconciler) wait(target types.NamespacedName) time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	last, ok := r.lastUpdated[target]
+	if !ok {
+		return 0
+	}
+	return last.Add(r.minInterval).Sub(r.clock.Now())
+}
+
+func (r *Reconciler) updated(target types.NamespacedName) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastUpdated[target] = r.clock.Now()
+}
+
+// source identifies the supplied managed resource.
+func (r *Reconciler) source(mr Mirrored) string {
+	kind := r.gvk.GroupKind().String()
+	if mr.GetNamespace() == "" {
+		return fmt.Sprintf("%s %s", kind, mr.GetName())
+	}
+	return fmt.Sprintf("%s %s/%s", kind, mr.GetNamespace(), mr.GetName())
+}
+
+func (r *Reconciler) sync(ctx context.Context, mr Mirrored, target types.NamespacedName, data map[string]string) error {
+	cm := &corev1.ConfigMap{}
+	err := r.kube.Get(ctx, target, cm)
+	if kerrors.IsNotFound(err) {
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   target.Namespace,
+				Name:        target.Name,
+				Annotations: map[string]string{AnnotationSource: r.source(mr)},
+				// Garbage collect the mirror should the managed resource
+				// be deleted without the mirror being deleted first.
+				OwnerReferences: []metav1.OwnerReference{meta.AsOwner(meta.TypedReferenceTo(mr, r.gvk))},
+			},
+			Data: data,
+		}
+		if err := r.kube.Create(ctx, cm); err != nil {
+			return errors.Wrap(err, errCreateConfigMap)
+		}
+		r.updated(target)
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, errGetConfigMap)
+	}
+
+	if cm.GetAnnotations()[AnnotationSource] != r.source(mr) {
+		return errors.Errorf(errNotOwned, target, r.source(mr))
+	}
+	if maps.Equal(cm.Data, data) {
+		return nil
+	}
+
+	cm.Data = data
+	if err := r.kube.Update(ctx, cm); err != nil {
+		return errors.Wrap(err, errUpdateConfigMap)
+	}
+	r.updated(target)
+	return nil
+}
+
+func (r *Reconciler) delete(ctx context.Context, mr Mirrored, target types.NamespacedName) error {
+	cm := &corev1.ConfigMap{}
+	if err := r.kube.Get(ctx, target, cm); err != nil {
+		return errors.Wrap(client.IgnoreNotFound(err), errGetConfigMap)
+	}
+	if cm.GetAnnotations()[AnnotationSource] != r.source(mr) {
+		// Not ours to delete.
+		return nil
+	}
+	if err := r.kube.Delete(ctx, cm); client.IgnoreNotFound(err) != nil {
+		return errors.Wrap(err, errDeleteConfigMap)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.lastUpdated, target)
+	return nil
+}
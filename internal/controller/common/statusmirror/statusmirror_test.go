@@ -0,0 +1,231 @@
+package statusmirror
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/clock"
+	testingclock "k8s.io/utils/clock/testing"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	apiscluster "github.com/statnett/provider-cloudian/apis/cluster"
+	userv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/user/v1alpha1"
+	userv1alpha1common "github.com/statnett/provider-cloudian/apis/common/user/v1alpha1"
+	apisnamespaced "github.com/statnett/provider-cloudian/apis/namespaced"
+	userv1alpha1namespaced "github.com/statnett/provider-cloudian/apis/namespaced/user/v1alpha1"
+)
+
+const tenant = "tenant-a"
+
+var mirror = types.NamespacedName{Namespace: tenant, Name: "alice-status"}
+
+func scheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+	s := runtime.NewScheme()
+	for _, add := range []func(*runtime.Scheme) error{clientgoscheme.AddToScheme, apiscluster.AddToScheme, apisnamespaced.AddToScheme} {
+		if err := add(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s
+}
+
+func newUser(conditions ...xpv2.Condition) *userv1alpha1cluster.User {
+	u := &userv1alpha1cluster.User{
+		ObjectMeta: metav1.ObjectMeta{Name: "alice", UID: "1234"},
+		Spec: userv1alpha1cluster.UserSpec{
+			StatusMirror: &userv1alpha1common.StatusMirror{Namespace: mirror.Namespace, Name: mirror.Name},
+		},
+	}
+	u.Status.AtProvider.CanonicalID = "abc"
+	u.SetConditions(conditions...)
+	return u
+}
+
+func newReconciler(kube client.Client, c clock.PassiveClock) *Reconciler {
+	return NewReconciler(kube, userv1alpha1cluster.UserGroupVersionKind, func() Mirrored { return &userv1alpha1cluster.User{} }, WithMinInterval(time.Minute), WithClock(c))
+}
+
+func reconcileUser(t *testing.T, r *Reconciler) reconcile.Result {
+	t.Helper()
+	res, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: types.NamespacedName{Name: "alice"}})
+	if err != nil {
+		t.Fatalf("Reconcile(...): %v", err)
+	}
+	return res
+}
+
+func getMirror(t *testing.T, kube client.Client) *corev1.ConfigMap {
+	t.Helper()
+	cm := &corev1.ConfigMap{}
+	if err := kube.Get(context.Background(), mirror, cm); err != nil {
+		t.Fatalf("Get(%s): %v", mirror, err)
+	}
+	return cm
+}
+
+func mirroredConditions(t *testing.T, cm *corev1.ConfigMap) []condition {
+	t.Helper()
+	var cs []condition
+	if err := json.Unmarshal([]byte(cm.Data[KeyConditions]), &cs); err != nil {
+		t.Fatalf("Unmarshal(%q): %v", cm.Data[KeyConditions], err)
+	}
+	return cs
+}
+
+func TestReconcileCreate(t *testing.T) {
+	kube := fake.NewClientBuilder().WithScheme(scheme(t)).WithObjects(newUser(xpv2.Available(), xpv2.ReconcileSuccess())).Build()
+	reconcileUser(t, newReconciler(kube, testingclock.NewFakePassiveClock(time.Now())))
+
+	cm := getMirror(t, kube)
+	if got := cm.GetAnnotations()[AnnotationSource]; got != "User.user.cloudian.crossplane.io alice" {
+		t.Errorf("annotation %s: got %q", AnnotationSource, got)
+	}
+	if len(cm.GetOwnerReferences()) != 1 || cm.GetOwnerReferences()[0].UID != "1234" {
+		t.Errorf("owner references: got %v", cm.GetOwnerReferences())
+	}
+	if cm.Data["canonicalId"] != "abc" {
+		t.Errorf("canonicalId: want %q, got %q", "abc", cm.Data["canonicalId"])
+	}
+
+	var got []xpv2.ConditionType
+	for _, c := range mirroredConditions(t, cm) {
+		got = append(got, c.Type)
+	}
+	if diff := cmp.Diff([]xpv2.ConditionType{xpv2.TypeReady, xpv2.TypeSynced}, got); diff != "" {
+		t.Errorf("conditions: -want, +got:\n%s", diff)
+	}
+}
+
+func TestReconcileUpdate(t *testing.T) {
+	kube := fake.NewClientBuilder().WithScheme(scheme(t)).WithObjects(newUser(xpv2.Creating())).Build()
+	c := testingclock.NewFakePassiveClock(time.Now())
+	r := newReconciler(kube, c)
+	reconcileUser(t, r)
+
+	u := &userv1alpha1cluster.User{}
+	if err := kube.Get(context.Background(), types.NamespacedName{Name: "alice"}, u); err != nil {
+		t.Fatal(err)
+	}
+	u.SetConditions(xpv2.ReconcileError(kerrors.NewBadRequest(strings.Repeat("x", 2*MaxMessageLength))))
+	if err := kube.Update(context.Background(), u); err != nil {
+		t.Fatal(err)
+	}
+
+	// Updates within the minimum interval are delayed.
+	c.SetTime(c.Now().Add(10 * time.Second))
+	if res := reconcileUser(t, r); res.RequeueAfter != 50*time.Second {
+		t.Errorf("Reconcile(...): want RequeueAfter %s, got %s", 50*time.Second, res.RequeueAfter)
+	}
+	if got := mirroredConditions(t, getMirror(t, kube)); len(got) != 1 {
+		t.Errorf("conditions: want only Ready before the interval has passed, got %v", got)
+	}
+
+	c.SetTime(c.Now().Add(time.Minute))
+	if res := reconcileUser(t, r); res.RequeueAfter != 0 {
+		t.Errorf("Reconcile(...): want no requeue, got %s", res.RequeueAfter)
+	}
+	got := mirroredConditions(t, getMirror(t, kube))
+	if len(got) != 2 {
+		t.Fatalf("conditions: want Ready and Synced, got %v", got)
+	}
+	if len(got[1].Message) > MaxMessageLength+len("...") {
+		t.Errorf("message: want at most %d characters, got %d", MaxMessageLength+len("..."), len(got[1].Message))
+	}
+}
+
+func TestReconcileDelete(t *testing.T) {
+	u := newUser(xpv2.Deleting())
+	u.SetDeletionTimestamp(ptr.To(metav1.Now()))
+	u.SetFinalizers([]string{"finalizer.managedresource.crossplane.io"})
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+		Namespace:   mirror.Namespace,
+		Name:        mirror.Name,
+		Annotations: map[string]string{AnnotationSource: "User.user.cloudian.crossplane.io alice"},
+	}}
+	kube := fake.NewClientBuilder().WithScheme(scheme(t)).WithObjects(u, cm).Build()
+	reconcileUser(t, newReconciler(kube, testingclock.NewFakePassiveClock(time.Now())))
+
+	if err := kube.Get(context.Background(), mirror, &corev1.ConfigMap{}); !kerrors.IsNotFound(err) {
+		t.Errorf("Get(%s): want NotFound, got %v", mirror, err)
+	}
+}
+
+func TestReconcileForeignConfigMap(t *testing.T) {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: mirror.Namespace, Name: mirror.Name},
+		Data:       map[string]string{"important": "yes"},
+	}
+	kube := fake.NewClientBuilder().WithScheme(scheme(t)).WithObjects(newUser(xpv2.Available()), cm).Build()
+	r := newReconciler(kube, testingclock.NewFakePassiveClock(time.Now()))
+
+	if _, err := r.Reconcile(context.Background(), reconcile.Request{NamespacedName: types.NamespacedName{Name: "alice"}}); err == nil {
+		t.Error("Reconcile(...): want error overwriting a ConfigMap that is not a status mirror")
+	}
+	if diff := cmp.Diff(cm.Data, getMirror(t, kube).Data); diff != "" {
+		t.Errorf("data: -want, +got:\n%s", diff)
+	}
+}
+
+func TestTarget(t *testing.T) {
+	cases := map[string]struct {
+		mr      Mirrored
+		want    types.NamespacedName
+		wantErr bool
+	}{
+		"Cluster": {
+			mr:   newUser(),
+			want: mirror,
+		},
+		"ClusterWithoutNamespace": {
+			mr: &userv1alpha1cluster.User{Spec: userv1alpha1cluster.UserSpec{
+				StatusMirror: &userv1alpha1common.StatusMirror{Name: mirror.Name},
+			}},
+			wantErr: true,
+		},
+		"NamespacedDefault": {
+			mr: &userv1alpha1namespaced.User{
+				ObjectMeta: metav1.ObjectMeta{Namespace: tenant, Name: "alice"},
+				Spec: userv1alpha1namespaced.UserSpec{
+					StatusMirror: &userv1alpha1common.StatusMirror{Name: mirror.Name},
+				},
+			},
+			want: mirror,
+		},
+		"NamespacedOtherNamespace": {
+			mr: &userv1alpha1namespaced.User{
+				ObjectMeta: metav1.ObjectMeta{Namespace: tenant, Name: "alice"},
+				Spec: userv1alpha1namespaced.UserSpec{
+					StatusMirror: &userv1alpha1common.StatusMirror{Namespace: "kube-system", Name: mirror.Name},
+				},
+			},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := Target(tc.mr)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Target(...): want error %t, got %v", tc.wantErr, err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("Target(...): -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package common
+
+import "time"
+
+// Timeouts bound the time a single reconcile of a kind of managed resource
+// may take, including all calls to the Cloudian API.
+type Timeouts struct {
+	User                   time.Duration
+	AccessKey              time.Duration
+	Group                  time.Duration
+	QualityOfServiceLimits time.Duration
+}
+
+// DefaultTimeouts are long enough for the bulk work done by the Group and
+// QualityOfServiceLimits kinds.
+var DefaultTimeouts = Timeouts{
+	User:                   time.Minute,
+	AccessKey:              time.Minute,
+	Group:                  5 * time.Minute,
+	QualityOfServiceLimits: 5 * time.Minute,
+}
+
+// ReconcileTimeouts are the timeouts used by the controllers. They must be set
+// before the controllers are set up.
+var ReconcileTimeouts = DefaultTimeouts
@@ -0,0 +1,135 @@
+// Package tombstone remembers the external resources the provider recently
+// deleted, so that a managed resource recreated right after the deletion of
+// its predecessor does not adopt what Cloudian has yet to finish deleting.
+package tombstone
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ConfigMapName is the name of the ConfigMap the tombstones are kept in, in
+// the namespace of the provider.
+const ConfigMapName = "provider-cloudian-tombstones"
+
+// Window is how long a deleted external resource is not adopted.
+const Window = 10 * time.Minute
+
+const (
+	errGetConfigMap    = "cannot get tombstones"
+	errUpdateConfigMap = "cannot update tombstones"
+)
+
+// Identity returns the key of the tombstone of an external resource
+// identified by the supplied parts, such as the endpoint of the Cloudian API
+// and the ID of the resource.
+func Identity(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "/")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// A Store keeps the tombstones of deleted external resources in a ConfigMap.
+// A nil Store keeps none.
+type Store struct {
+	reader    client.Reader
+	writer    client.Writer
+	namespace string
+	clock     clock.PassiveClock
+}
+
+// NewStore returns a Store of tombstones in the supplied namespace, dated by
+// the supplied clock. Reading through the API server rather than the cache of
+// the manager spares the provider from caching every ConfigMap in the cluster.
+func NewStore(reader client.Reader, writer client.Writer, namespace string, clock clock.PassiveClock) *Store {
+	return &Store{reader: reader, writer: writer, namespace: namespace, clock: clock}
+}
+
+// Record records that the external resource with the supplied identity was
+// deleted now. Tombstones older than the Window are removed.
+func (s *Store) Record(ctx context.Context, identity string) error {
+	if s == nil {
+		return nil
+	}
+	// Concurrent deletions may create or update the ConfigMap meanwhile.
+	conflict := func(err error) bool { return kerrors.IsConflict(err) || kerrors.IsAlreadyExists(err) }
+	return retry.OnError(retry.DefaultRetry, conflict, func() error {
+		cm := &corev1.ConfigMap{}
+		err := s.reader.Get(ctx, client.ObjectKey{Namespace: s.namespace, Name: ConfigMapName}, cm)
+		create := kerrors.IsNotFound(err)
+		if err != nil && !create {
+			return errors.Wrap(err, errGetConfigMap)
+		}
+		if create {
+			cm = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: s.namespace, Name: ConfigMapName}}
+		}
+
+		s.prune(cm)
+		cm.Data[identity] = s.clock.Now().UTC().Format(time.RFC3339)
+		if create {
+			return errors.Wrap(s.writer.Create(ctx, cm), errUpdateConfigMap)
+		}
+		return errors.Wrap(s.writer.Update(ctx, cm), errUpdateConfigMap)
+	})
+}
+
+// Deleted returns whether the external resource with the supplied identity
+// was deleted within the Window.
+func (s *Store) Deleted(ctx context.Context, identity string) (bool, error) {
+	if s == nil {
+		return false, nil
+	}
+	cm := &corev1.ConfigMap{}
+	err := s.reader.Get(ctx, client.ObjectKey{Namespace: s.namespace, Name: ConfigMapName}, cm)
+	if kerrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrap(err, errGetConfigMap)
+	}
+	return s.recent(cm.Data[identity]), nil
+}
+
+// Leftover returns whether the observed external resource of the supplied
+// managed resource, with the supplied identity, may be what remains of one
+// deleted within the Window rather than one to adopt. Only managed resources
+// created within the Window that have yet to create their external resource
+// are concerned, so that others never read the tombstones.
+func (s *Store) Leftover(ctx context.Context, mg resource.Managed, identity string) (bool, error) {
+	if s == nil || meta.WasDeleted(mg) || !meta.GetExternalCreateSucceeded(mg).IsZero() {
+		return false, nil
+	}
+	if s.clock.Now().Sub(mg.GetCreationTimestamp().Time) >= Window {
+		return false, nil
+	}
+	return s.Deleted(ctx, identity)
+}
+
+// prune removes the tombstones older than the Window.
+func (s *Store) prune(cm *corev1.ConfigMap) {
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	for identity, deleted := range cm.Data {
+		if !s.recent(deleted) {
+			delete(cm.Data, identity)
+		}
+	}
+}
+
+func (s *Store) recent(deleted string) bool {
+	t, err := time.Parse(time.RFC3339, deleted)
+	return err == nil && s.clock.Now().Sub(t) < Window
+}
@@ -0,0 +1,144 @@
+package tombstone
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testingclock "k8s.io/utils/clock/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	userv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/user/v1alpha1"
+)
+
+const namespace = "crossplane-system"
+
+func TestIdentity(t *testing.T) {
+	if Identity("https://a", "QA") == Identity("https://b", "QA") {
+		t.Error("Identity(...): want the identities of groups of different endpoints to differ")
+	}
+	if Identity("https://a", "QA") != Identity("https://a", "QA") {
+		t.Error("Identity(...): want the identity of a group to be stable")
+	}
+}
+
+func TestRecord(t *testing.T) {
+	ctx := context.Background()
+	kube := fake.NewClientBuilder().Build()
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := testingclock.NewFakePassiveClock(now)
+	s := NewStore(kube, kube, namespace, clock)
+
+	if err := s.Record(ctx, "old"); err != nil {
+		t.Fatalf("s.Record(...): %v", err)
+	}
+	now = now.Add(Window)
+	clock.SetTime(now)
+	if err := s.Record(ctx, "new"); err != nil {
+		t.Fatalf("s.Record(...): %v", err)
+	}
+
+	cm := &corev1.ConfigMap{}
+	if err := kube.Get(ctx, client.ObjectKey{Namespace: namespace, Name: ConfigMapName}, cm); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := cm.Data["old"]; ok {
+		t.Errorf("s.Record(...): want tombstones older than %s pruned, got %v", Window, cm.Data)
+	}
+	if got, want := cm.Data["new"], now.Format(time.RFC3339); got != want {
+		t.Errorf("s.Record(...): want tombstone recorded at %s, got %q", want, got)
+	}
+}
+
+func TestLeftover(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	cases := map[string]struct {
+		reason  string
+		store   bool
+		deleted time.Duration
+		created time.Duration
+		mutate  func(cr *userv1alpha1cluster.Group)
+		want    bool
+	}{
+		"Recreated": {
+			reason:  "A resource created right after the deletion of its predecessor should not adopt its external resource.",
+			store:   true,
+			deleted: time.Minute,
+			created: time.Second,
+			want:    true,
+		},
+		"NoTombstone": {
+			reason:  "A resource with no recently deleted predecessor should adopt its external resource.",
+			store:   true,
+			created: time.Second,
+		},
+		"Expired": {
+			reason:  "A resource whose predecessor was deleted before the window should adopt its external resource.",
+			store:   true,
+			deleted: Window + time.Minute,
+			created: time.Second,
+		},
+		"OldResource": {
+			reason:  "A resource created before the window should never read the tombstones.",
+			store:   true,
+			deleted: time.Minute,
+			created: Window,
+		},
+		"Created": {
+			reason:  "A resource that created its external resource should keep observing it.",
+			store:   true,
+			deleted: time.Minute,
+			created: time.Second,
+			mutate:  func(cr *userv1alpha1cluster.Group) { meta.SetExternalCreateSucceeded(cr, now) },
+		},
+		"Deleting": {
+			reason:  "A resource being deleted should keep observing its external resource.",
+			store:   true,
+			deleted: time.Minute,
+			created: time.Second,
+			mutate:  func(cr *userv1alpha1cluster.Group) { cr.SetDeletionTimestamp(&metav1.Time{Time: now}) },
+		},
+		"NilStore": {
+			reason: "A nil Store should keep no tombstones.",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			var s *Store
+			if tc.store {
+				kube := fake.NewClientBuilder().Build()
+				clock := testingclock.NewFakePassiveClock(now.Add(-tc.deleted))
+				s = NewStore(kube, kube, namespace, clock)
+				if tc.deleted > 0 {
+					if err := s.Record(ctx, "QA"); err != nil {
+						t.Fatal(err)
+					}
+				}
+				clock.SetTime(now)
+			}
+
+			cr := &userv1alpha1cluster.Group{ObjectMeta: metav1.ObjectMeta{
+				Name:              "QA",
+				CreationTimestamp: metav1.Time{Time: now.Add(-tc.created)},
+			}}
+			if tc.mutate != nil {
+				tc.mutate(cr)
+			}
+
+			got, err := s.Leftover(ctx, cr, "QA")
+			if err != nil {
+				t.Fatalf("\n%s\ns.Leftover(...): %v", tc.reason, err)
+			}
+			if got != tc.want {
+				t.Errorf("\n%s\ns.Leftover(...): want %t, got %t", tc.reason, tc.want, got)
+			}
+		})
+	}
+}
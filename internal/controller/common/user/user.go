@@ -0,0 +1,169 @@
+// Package user contains the logic shared by the User controllers.
+package user
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/event"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/managed"
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	userv1alpha1common "github.com/statnett/provider-cloudian/apis/common/user/v1alpha1"
+	"github.com/statnett/provider-cloudian/internal/controller/common/referrers"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
+)
+
+const (
+	// TypeUserType indicates whether a Cloudian user has the desired type.
+	TypeUserType xpv2.ConditionType = "UserType"
+
+	// ReasonUserTypeMismatch means the Cloudian user has another type than
+	// the desired one, which Cloudian cannot change.
+	ReasonUserTypeMismatch xpv2.ConditionReason = "UserTypeMismatch"
+
+	// ReasonUserTypeMatch means the Cloudian user has the desired type.
+	ReasonUserTypeMatch xpv2.ConditionReason = "UserTypeMatch"
+
+	// ReasonCredentialCheckSkipped means a user was deleted without checking
+	// that it has no access keys, as the credentials may not list them.
+	ReasonCredentialCheckSkipped event.Reason = "CredentialCheckSkipped"
+)
+
+const (
+	errUnsupportedType = "cannot manage users of type %q"
+	errTypeMismatch    = "user is a %s, not a %s; Cloudian cannot change the type of a user, so it must be deleted and recreated"
+
+	errListAccessKeys   = "cannot list access keys of User"
+	errDeleteAccessKeys = "cannot delete access keys of User"
+	errHasAccessKeys    = "User has access keys and cannot be deleted"
+	errCheckSkipped     = "deleting User without checking for access keys, as the credentials may not list them; set strictCredentialCheck in the ProviderConfig to refuse instead"
+)
+
+// ConnectionDetails returns the details of a user published in its connection
+// secret, for consumers such as bucket policies. The canonical ID is left out
+// until Cloudian reports it.
+func ConnectionDetails(guid cloudian.GroupUserID, canonicalID string) managed.ConnectionDetails {
+	cd := managed.ConnectionDetails{
+		"groupId": []byte(guid.GroupID),
+		"userId":  []byte(guid.UserID),
+	}
+	if canonicalID != "" {
+		cd["canonicalId"] = []byte(canonicalID)
+	}
+	return cd
+}
+
+// InitialAccessKeyDetails returns the details of the access key Cloudian
+// created along with a user, published in its connection secret when the
+// access key is retained.
+func InitialAccessKeyDetails(creds cloudian.SecurityInfo) managed.ConnectionDetails {
+	return managed.ConnectionDetails{
+		"accessKeyId":     []byte(creds.AccessKey),
+		"secretAccessKey": []byte(creds.SecretKey.Value()),
+	}
+}
+
+// Type returns the desired Cloudian type of a user, which defaults to a
+// standard user. System admins cannot be managed.
+func Type(p userv1alpha1common.UserParameters) (cloudian.UserType, error) {
+	if p.UserType == nil {
+		return cloudian.UserTypeStandard, nil
+	}
+	switch t := cloudian.UserType(*p.UserType); t {
+	case cloudian.UserTypeStandard, cloudian.UserTypeGroupAdmin:
+		return t, nil
+	default:
+		return "", errors.Errorf(errUnsupportedType, t)
+	}
+}
+
+// CheckType returns an error if the observed type of a user is not the
+// desired one. Users of unknown type are assumed to have the desired type.
+func CheckType(desired, observed cloudian.UserType) error {
+	if observed == "" || observed == desired {
+		return nil
+	}
+	return errors.Errorf(errTypeMismatch, observed, desired)
+}
+
+// DeleteAccessKeys deletes the access keys of a user about to be deleted if
+// `deleteKeys` is true. Otherwise it returns an error if the user has any
+// access keys, as they would be lost with the user. If the credentials may not
+// list the access keys, the check is skipped and reported as such unless
+// `strict` is true, in which case the error is returned.
+func DeleteAccessKeys(ctx context.Context, svc *cloudian.Client, guid cloudian.GroupUserID, deleteKeys, strict bool) (skipped bool, err error) {
+	if deleteKeys {
+		_, err := svc.DeleteAllUserCredentials(ctx, guid)
+		return false, errors.Wrap(err, errDeleteAccessKeys)
+	}
+	creds, err := svc.ListUserCredentials(ctx, guid)
+	if isForbidden(err) && !strict {
+		return true, nil
+	}
+	if err != nil {
+		return false, errors.Wrap(err, errListAccessKeys)
+	}
+	if len(creds) > 0 {
+		return false, errors.New(errHasAccessKeys)
+	}
+	return false, nil
+}
+
+// CredentialCheckSkipped returns a warning event that a user is deleted
+// without checking that it has no access keys.
+func CredentialCheckSkipped() event.Event {
+	return event.Warning(ReasonCredentialCheckSkipped, errors.New(errCheckSkipped))
+}
+
+func isForbidden(err error) bool {
+	var statusErr *cloudian.StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusForbidden
+}
+
+// TypeMismatch returns a condition indicating that the Cloudian user has
+// another type than the desired one.
+func TypeMismatch(err error) xpv2.Condition {
+	return xpv2.Condition{
+		Type:               TypeUserType,
+		Status:             corev1.ConditionFalse,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonUserTypeMismatch,
+		Message:            err.Error(),
+	}
+}
+
+// TypeMatch returns a condition indicating that the Cloudian user has the
+// desired type.
+func TypeMatch() xpv2.Condition {
+	return xpv2.Condition{
+		Type:               TypeUserType,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Now(),
+		Reason:             ReasonUserTypeMatch,
+	}
+}
+
+// Buckets returns the observation of the buckets of a user.
+func Buckets(buckets []cloudian.Bucket) []userv1alpha1common.Bucket {
+	var observed []userv1alpha1common.Bucket
+	for _, b := range buckets {
+		o := userv1alpha1common.Bucket{Name: b.Name, ObjectCount: b.ObjectCount, Bytes: b.Bytes}
+		if !b.CreationDate.IsZero() {
+			o.CreationDate = &metav1.Time{Time: b.CreationDate}
+		}
+		observed = append(observed, o)
+	}
+	return observed
+}
+
+// RefersToGroup returns whether a User with the supplied parameters is a
+// member of the Group `g`, whose Cloudian ID is `groupID`, or may resolve its
+// group from it.
+func RefersToGroup(p userv1alpha1common.UserParameters, g client.Object, groupID string) bool {
+	return (groupID != "" && p.GroupID == groupID) || referrers.Refers(p.GroupIDRef, p.GroupIDSelector, g)
+}
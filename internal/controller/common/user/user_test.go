@@ -0,0 +1,56 @@
+package user
+
+import (
+	"testing"
+
+	"k8s.io/utils/ptr"
+
+	userv1alpha1common "github.com/statnett/provider-cloudian/apis/common/user/v1alpha1"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
+)
+
+func TestType(t *testing.T) {
+	cases := map[string]struct {
+		userType *string
+		want     cloudian.UserType
+		wantErr  bool
+	}{
+		"Default":     {want: cloudian.UserTypeStandard},
+		"User":        {userType: ptr.To("User"), want: cloudian.UserTypeStandard},
+		"GroupAdmin":  {userType: ptr.To("GroupAdmin"), want: cloudian.UserTypeGroupAdmin},
+		"SystemAdmin": {userType: ptr.To("SystemAdmin"), wantErr: true},
+		"Unknown":     {userType: ptr.To("Root"), wantErr: true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := Type(userv1alpha1common.UserParameters{UserType: tc.userType})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Type(...): want error %t, got %v", tc.wantErr, err)
+			}
+			if got != tc.want {
+				t.Errorf("Type(...): want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestCheckType(t *testing.T) {
+	cases := map[string]struct {
+		desired  cloudian.UserType
+		observed cloudian.UserType
+		wantErr  bool
+	}{
+		"Match":    {desired: cloudian.UserTypeGroupAdmin, observed: cloudian.UserTypeGroupAdmin},
+		"Unknown":  {desired: cloudian.UserTypeStandard},
+		"Mismatch": {desired: cloudian.UserTypeStandard, observed: cloudian.UserTypeGroupAdmin, wantErr: true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if err := CheckType(tc.desired, tc.observed); (err != nil) != tc.wantErr {
+				t.Errorf("CheckType(%q, %q): want error %t, got %v", tc.desired, tc.observed, tc.wantErr, err)
+			}
+		})
+	}
+}
@@ -20,11 +20,19 @@ import (
 	"context"
 	"testing"
 
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
 	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/managed"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/test"
+
+	pcv1alpha1common "github.com/statnett/provider-cloudian/apis/common/providerconfig/v1alpha1"
+	userv1alpha1namespaced "github.com/statnett/provider-cloudian/apis/namespaced/user/v1alpha1"
+	apisv1alpha1namespaced "github.com/statnett/provider-cloudian/apis/namespaced/v1alpha1"
+	"github.com/statnett/provider-cloudian/internal/controller/common/connecttest"
 )
 
 // Unlike many Kubernetes projects Crossplane does not use third party testing
@@ -71,3 +79,27 @@ func TestObserve(t *testing.T) {
 		})
 	}
 }
+
+func TestConnect(t *testing.T) {
+	mg := &userv1alpha1namespaced.AccessKey{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "example"}}
+	mg.SetProviderConfigReference(&xpv2.ProviderConfigReference{Kind: apisv1alpha1namespaced.ProviderConfigKind, Name: connecttest.ProviderConfigName})
+	pc := func(spec pcv1alpha1common.ProviderConfigSpec) client.Object {
+		return &apisv1alpha1namespaced.ProviderConfig{ObjectMeta: metav1.ObjectMeta{Namespace: mg.GetNamespace(), Name: connecttest.ProviderConfigName}, Spec: spec}
+	}
+
+	connecttest.TestConnect(t, connecttest.Connector{
+		New: func(kube client.Client, newService connecttest.NewServiceFn) managed.ExternalConnecter {
+			return &connector{kube: kube, usage: resource.ModernTrackerFn(func(context.Context, resource.ModernManaged) error { return nil }), newServiceFn: newService}
+		},
+		Managed:        mg,
+		Other:          &userv1alpha1namespaced.User{},
+		ProviderConfig: pc,
+		Connected: func(ext managed.ExternalClient) bool {
+			e, ok := ext.(*external)
+			return ok && e.cloudianService != nil
+		},
+		ErrNotManaged: errNotAccessKey,
+		ErrGetPC:      errGetPC,
+		ErrGetCreds:   errGetCreds,
+	})
+}
@@ -19,12 +19,27 @@ package group
 import (
 	"context"
 	"testing"
+	"time"
 
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
 	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	testingclock "k8s.io/utils/clock/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/crossplane/crossplane-runtime/v2/pkg/event"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/managed"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/test"
+
+	pcv1alpha1common "github.com/statnett/provider-cloudian/apis/common/providerconfig/v1alpha1"
+	userv1alpha1namespaced "github.com/statnett/provider-cloudian/apis/namespaced/user/v1alpha1"
+	apisv1alpha1namespaced "github.com/statnett/provider-cloudian/apis/namespaced/v1alpha1"
+	"github.com/statnett/provider-cloudian/internal/controller/common/conflict"
+	"github.com/statnett/provider-cloudian/internal/controller/common/connecttest"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian/cloudiantest"
 )
 
 // Unlike many Kubernetes projects Crossplane does not use third party testing
@@ -72,3 +87,71 @@ func TestObserve(t *testing.T) {
 		})
 	}
 }
+
+func TestExternalConflict(t *testing.T) {
+	srv := cloudiantest.NewFakeServer(t)
+	svc := cloudian.NewClient(srv.URL, "")
+	ctx := context.Background()
+
+	// A nightly script renames the group back after every update.
+	original := cloudian.Group{Active: true, GroupID: "QA", GroupName: "Quality Assurance"}
+	if _, err := svc.CreateGroup(ctx, original); err != nil {
+		t.Fatal(err)
+	}
+
+	clock := testingclock.NewFakePassiveClock(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC))
+	e := &external{clock: clock, cloudianService: svc, recorder: event.NewNopRecorder()}
+	cr := &userv1alpha1namespaced.Group{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "QA"}}
+	cr.Spec.ForProvider.GroupID = "QA"
+	cr.Spec.ForProvider.Active = true
+	cr.Spec.ForProvider.GroupName = "Renamed"
+
+	for i := range conflict.Threshold + 1 {
+		if _, err := e.Observe(ctx, cr); err != nil {
+			t.Fatalf("e.Observe(...): %v", err)
+		}
+		if got := cr.GetCondition(conflict.TypeExternalConflict).Status; (got == corev1.ConditionTrue) != (i >= conflict.Threshold) {
+			t.Errorf("e.Observe(...): poll %d: want a conflict only after %d reverts, got condition %s", i, conflict.Threshold, got)
+		}
+		if _, err := e.Update(ctx, cr); err != nil {
+			t.Fatalf("e.Update(...): %v", err)
+		}
+		if err := svc.UpdateGroup(ctx, original); err != nil {
+			t.Fatal(err)
+		}
+		clock.SetTime(clock.Now().Add(time.Hour))
+	}
+
+	// The script stops, and the reverts leave the window.
+	clock.SetTime(clock.Now().Add(conflict.Window))
+	if _, err := e.Observe(ctx, cr); err != nil {
+		t.Fatalf("e.Observe(...): %v", err)
+	}
+	if got := cr.GetCondition(conflict.TypeExternalConflict).Status; got != corev1.ConditionFalse {
+		t.Errorf("e.Observe(...): want the conflict resolved once its reverts are older than %s, got condition %s", conflict.Window, got)
+	}
+}
+
+func TestConnect(t *testing.T) {
+	mg := &userv1alpha1namespaced.Group{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "example"}}
+	mg.SetProviderConfigReference(&xpv2.ProviderConfigReference{Kind: apisv1alpha1namespaced.ProviderConfigKind, Name: connecttest.ProviderConfigName})
+	pc := func(spec pcv1alpha1common.ProviderConfigSpec) client.Object {
+		return &apisv1alpha1namespaced.ProviderConfig{ObjectMeta: metav1.ObjectMeta{Namespace: mg.GetNamespace(), Name: connecttest.ProviderConfigName}, Spec: spec}
+	}
+
+	connecttest.TestConnect(t, connecttest.Connector{
+		New: func(kube client.Client, newService connecttest.NewServiceFn) managed.ExternalConnecter {
+			return &connector{kube: kube, usage: resource.ModernTrackerFn(func(context.Context, resource.ModernManaged) error { return nil }), newServiceFn: newService}
+		},
+		Managed:        mg,
+		Other:          &userv1alpha1namespaced.User{},
+		ProviderConfig: pc,
+		Connected: func(ext managed.ExternalClient) bool {
+			e, ok := ext.(*external)
+			return ok && e.cloudianService != nil
+		},
+		ErrNotManaged: errNotGroup,
+		ErrGetPC:      errGetPC,
+		ErrGetCreds:   errGetCreds,
+	})
+}
@@ -0,0 +1,238 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iamuser
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/controller"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/event"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
+
+	pcv1alpha1common "github.com/statnett/provider-cloudian/apis/common/providerconfig/v1alpha1"
+	userv1alpha1namespaced "github.com/statnett/provider-cloudian/apis/namespaced/user/v1alpha1"
+	apisv1alpha1namespaced "github.com/statnett/provider-cloudian/apis/namespaced/v1alpha1"
+	"github.com/statnett/provider-cloudian/internal/clients"
+	controllercommon "github.com/statnett/provider-cloudian/internal/controller/common"
+	accesskeycontrollercommon "github.com/statnett/provider-cloudian/internal/controller/common/accesskey"
+	iamusercontrollercommon "github.com/statnett/provider-cloudian/internal/controller/common/iamuser"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian/iam"
+)
+
+const (
+	errNotIAMUser   = "managed resource is not an IAMUser custom resource"
+	errTrackPCUsage = "cannot track ProviderConfig usage"
+	errGetPC        = "cannot get ProviderConfig"
+	errGetCreds     = "cannot get credentials"
+
+	errNewClient       = "cannot create new Service"
+	errNewIAMClient    = "cannot create new IAM Service"
+	errGetIAMUser      = "cannot get IAMUser"
+	errCreateIAMUser   = "cannot create IAMUser"
+	errCreateAccessKey = "cannot create access key of IAMUser"
+	errDeleteIAMUser   = "cannot delete IAMUser"
+)
+
+// SetupGated registers controller setup with the gate, waiting for the
+// required CRD
+func SetupGated(mgr ctrl.Manager, o controller.Options) error {
+	o.Gate.Register(func() {
+		if err := Setup(mgr, o); err != nil {
+			panic(err)
+		}
+	}, userv1alpha1namespaced.IAMUserGroupVersionKind)
+	return nil
+}
+
+// Setup adds a controller that reconciles IAMUser managed resources.
+func Setup(mgr ctrl.Manager, o controller.Options) error {
+	return controllercommon.SetupManaged[userv1alpha1namespaced.IAMUser](mgr, o, controllercommon.ManagedKind{
+		GroupVersionKind: userv1alpha1namespaced.IAMUserGroupVersionKind,
+		Timeout:          controllercommon.ReconcileTimeouts.AccessKey,
+		NewConnector: func(event.Recorder) managed.ExternalConnector {
+			return &connector{
+				kube:         mgr.GetClient(),
+				usage:        resource.NewProviderConfigUsageTracker(mgr.GetClient(), &apisv1alpha1namespaced.ProviderConfigUsage{}),
+				newServiceFn: clients.Shared.Get,
+			}
+		},
+		Options: []managed.ReconcilerOption{
+			managed.WithReferenceResolver(managed.NewAPISimpleReferenceResolver(mgr.GetClient())),
+		},
+	})
+}
+
+// A connector is expected to produce an ExternalClient when its Connect method
+// is called.
+type connector struct {
+	kube         client.Client
+	usage        resource.ModernTracker
+	newServiceFn func(uid types.UID, spec pcv1alpha1common.ProviderConfigSpec, creds controllercommon.Credentials) (*cloudian.Client, error)
+}
+
+// Connect typically produces an ExternalClient by:
+// 1. Tracking that the managed resource is using a ProviderConfig.
+// 2. Getting the managed resource's ProviderConfig.
+// 3. Getting the credentials specified by the ProviderConfig.
+// 4. Using the credentials to form a client.
+// The IAM client signs its requests with an access key of the parent user.
+func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.ExternalClient, error) {
+	cr, ok := mg.(*userv1alpha1namespaced.IAMUser)
+	if !ok {
+		return nil, errors.New(errNotIAMUser)
+	}
+
+	if err := c.usage.Track(ctx, cr); err != nil {
+		return nil, errors.Wrap(err, errTrackPCUsage)
+	}
+
+	pc := &apisv1alpha1namespaced.ProviderConfig{}
+	if err := c.kube.Get(ctx, types.NamespacedName{Namespace: cr.GetNamespace(), Name: cr.GetProviderConfigReference().Name}, pc); err != nil {
+		return nil, errors.Wrap(err, errGetPC)
+	}
+
+	creds, err := controllercommon.ExtractCredentials(ctx, c.kube, pc.Spec)
+	if err != nil {
+		return nil, errors.Wrap(err, errGetCreds)
+	}
+
+	svc, err := c.newServiceFn(pc.GetUID(), pc.Spec, creds)
+	if err != nil {
+		return nil, errors.Wrap(err, errNewClient)
+	}
+
+	parent := cloudian.GroupUserID{GroupID: cr.Spec.ForProvider.GroupID, UserID: cr.Spec.ForProvider.UserID}
+	iamSvc, err := iamusercontrollercommon.NewService(ctx, svc, pc.Spec, creds, parent)
+	if iamusercontrollercommon.ParentGone(err) && meta.WasDeleted(cr) {
+		// Gone with its parent, or no longer reachable to delete.
+		return &external{parent: parent}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, errNewIAMClient)
+	}
+
+	return &external{iamService: iamSvc, parent: parent}, nil
+}
+
+// An ExternalClient observes, then either creates, updates, or deletes an
+// external resource to ensure it reflects the managed resource's desired state.
+type external struct {
+	// iamService is nil when the parent user of a deleted IAMUser is gone.
+	iamService *iam.Client
+	parent     cloudian.GroupUserID
+}
+
+func (c *external) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
+	cr, ok := mg.(*userv1alpha1namespaced.IAMUser)
+	if !ok {
+		return managed.ExternalObservation{}, errors.New(errNotIAMUser)
+	}
+
+	if c.iamService == nil || meta.GetExternalName(cr) == "" {
+		return managed.ExternalObservation{ResourceExists: false}, nil
+	}
+
+	user, err := c.iamService.GetIAMUser(ctx, c.parent, meta.GetExternalName(cr))
+	if errors.Is(err, iam.ErrNotFound) {
+		return managed.ExternalObservation{ResourceExists: false}, nil
+	}
+	if err != nil {
+		return managed.ExternalObservation{}, errors.Wrap(err, errGetIAMUser)
+	}
+
+	iamusercontrollercommon.Observe(&cr.Status.AtProvider, *user)
+	cr.SetConditions(xpv2.Available())
+
+	return managed.ExternalObservation{
+		ResourceExists: true,
+		// An IAM user has no configurable fields.
+		ResourceUpToDate:  true,
+		ConnectionDetails: managed.ConnectionDetails{},
+	}, nil
+}
+
+// Create creates the IAM user with an access key, which is published in the
+// connection secret. The user is deleted again if the key cannot be created,
+// as the secret key is only returned when created.
+func (c *external) Create(ctx context.Context, mg resource.Managed) (managed.ExternalCreation, error) {
+	cr, ok := mg.(*userv1alpha1namespaced.IAMUser)
+	if !ok {
+		return managed.ExternalCreation{}, errors.New(errNotIAMUser)
+	}
+
+	cr.SetConditions(xpv2.Creating())
+
+	name := meta.GetExternalName(cr)
+	user, err := c.iamService.CreateIAMUser(ctx, c.parent, name)
+	if err != nil {
+		return managed.ExternalCreation{}, errors.Wrap(err, errCreateIAMUser)
+	}
+	key, err := c.iamService.CreateIAMAccessKey(ctx, c.parent, name)
+	if err != nil {
+		_ = c.iamService.DeleteIAMUser(ctx, c.parent, name)
+		return managed.ExternalCreation{}, errors.Wrap(err, errCreateAccessKey)
+	}
+
+	iamusercontrollercommon.Observe(&cr.Status.AtProvider, *user)
+	cr.Status.AtProvider.AccessKeyID = key.AccessKey
+
+	return managed.ExternalCreation{
+		ConnectionDetails: accesskeycontrollercommon.ConnectionDetails(key),
+	}, nil
+}
+
+func (c *external) Update(ctx context.Context, mg resource.Managed) (managed.ExternalUpdate, error) {
+	_, ok := mg.(*userv1alpha1namespaced.IAMUser)
+	if !ok {
+		return managed.ExternalUpdate{}, errors.New(errNotIAMUser)
+	}
+
+	return managed.ExternalUpdate{
+		// Optionally return any details that may be required to connect to the
+		// external resource. These will be stored as the connection secret.
+		ConnectionDetails: managed.ConnectionDetails{},
+	}, nil
+}
+
+func (c *external) Delete(ctx context.Context, mg resource.Managed) (managed.ExternalDelete, error) {
+	cr, ok := mg.(*userv1alpha1namespaced.IAMUser)
+	if !ok {
+		return managed.ExternalDelete{}, errors.New(errNotIAMUser)
+	}
+
+	cr.SetConditions(xpv2.Deleting())
+
+	err := c.iamService.DeleteIAMUser(ctx, c.parent, meta.GetExternalName(cr))
+	if err != nil && !errors.Is(err, iam.ErrNotFound) {
+		return managed.ExternalDelete{}, errors.Wrap(err, errDeleteIAMUser)
+	}
+
+	return managed.ExternalDelete{}, nil
+}
+
+func (c *external) Disconnect(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2022 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package iamuser
+
+import (
+	"context"
+	"testing"
+
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/managed"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+
+	pcv1alpha1common "github.com/statnett/provider-cloudian/apis/common/providerconfig/v1alpha1"
+	userv1alpha1namespaced "github.com/statnett/provider-cloudian/apis/namespaced/user/v1alpha1"
+	apisv1alpha1namespaced "github.com/statnett/provider-cloudian/apis/namespaced/v1alpha1"
+	controllercommon "github.com/statnett/provider-cloudian/internal/controller/common"
+	"github.com/statnett/provider-cloudian/internal/controller/common/connecttest"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian/cloudiantest"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian/iam/iamtest"
+)
+
+// parentKey is the access key of the parent user, which signs the requests.
+const parentKey = "00112233445566778899"
+
+var parent = cloudian.GroupUserID{GroupID: "QA", UserID: "alice"}
+
+func TestConnect(t *testing.T) {
+	iamSrv := iamtest.NewFakeServer(t)
+	admin := cloudiantest.NewFakeServer(t)
+	admin.AddUser(cloudiantest.User{GroupID: parent.GroupID, UserID: parent.UserID})
+	admin.AddCredentials(parent.GroupID, parent.UserID, parentKey, "secret")
+	mg := &userv1alpha1namespaced.IAMUser{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "example"}}
+	mg.SetProviderConfigReference(&xpv2.ProviderConfigReference{Kind: apisv1alpha1namespaced.ProviderConfigKind, Name: connecttest.ProviderConfigName})
+	mg.Spec.ForProvider.GroupID = parent.GroupID
+	mg.Spec.ForProvider.UserID = parent.UserID
+	pc := func(spec pcv1alpha1common.ProviderConfigSpec) client.Object {
+		spec.IAMEndpoint = iamSrv.URL
+		return &apisv1alpha1namespaced.ProviderConfig{ObjectMeta: metav1.ObjectMeta{Namespace: mg.GetNamespace(), Name: connecttest.ProviderConfigName}, Spec: spec}
+	}
+
+	connecttest.TestConnect(t, connecttest.Connector{
+		New: func(kube client.Client, newService connecttest.NewServiceFn) managed.ExternalConnecter {
+			return &connector{kube: kube, usage: resource.ModernTrackerFn(func(context.Context, resource.ModernManaged) error { return nil }), newServiceFn: newService}
+		},
+		NewService: func(types.UID, pcv1alpha1common.ProviderConfigSpec, controllercommon.Credentials) (*cloudian.Client, error) {
+			return cloudian.NewClient(admin.URL, ""), nil
+		},
+		Managed:        mg,
+		Other:          &userv1alpha1namespaced.User{},
+		ProviderConfig: pc,
+		Connected: func(ext managed.ExternalClient) bool {
+			e, ok := ext.(*external)
+			return ok && e.iamService != nil
+		},
+		ErrNotManaged: errNotIAMUser,
+		ErrGetPC:      errGetPC,
+		ErrGetCreds:   errGetCreds,
+	})
+}
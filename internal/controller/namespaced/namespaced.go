@@ -24,6 +24,8 @@ import (
 	"github.com/statnett/provider-cloudian/internal/controller/namespaced/config"
 	"github.com/statnett/provider-cloudian/internal/controller/namespaced/group"
 	"github.com/statnett/provider-cloudian/internal/controller/namespaced/groupqualityofservicelimits"
+	"github.com/statnett/provider-cloudian/internal/controller/namespaced/groupratingplan"
+	"github.com/statnett/provider-cloudian/internal/controller/namespaced/iamuser"
 	"github.com/statnett/provider-cloudian/internal/controller/namespaced/user"
 	"github.com/statnett/provider-cloudian/internal/controller/namespaced/userqualityofservicelimits"
 )
@@ -36,6 +38,8 @@ func Setup(mgr ctrl.Manager, o controller.Options) error {
 		config.Setup,
 		group.Setup,
 		groupqualityofservicelimits.Setup,
+		groupratingplan.Setup,
+		iamuser.Setup,
 		user.Setup,
 		userqualityofservicelimits.Setup,
 	} {
@@ -54,6 +58,8 @@ func SetupGated(mgr ctrl.Manager, o controller.Options) error {
 		config.SetupGated,
 		group.SetupGated,
 		groupqualityofservicelimits.SetupGated,
+		groupratingplan.SetupGated,
+		iamuser.SetupGated,
 		user.SetupGated,
 		userqualityofservicelimits.SetupGated,
 	} {
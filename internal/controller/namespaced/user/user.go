@@ -18,24 +18,38 @@ package user
 
 import (
 	"context"
-	"fmt"
+	"maps"
+	"strings"
 
 	"github.com/pkg/errors"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/crossplane/crossplane-runtime/v2/pkg/controller"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/event"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
-	"github.com/crossplane/crossplane-runtime/v2/pkg/ratelimiter"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/reconciler/managed"
 	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
 	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
 
+	pcv1alpha1common "github.com/statnett/provider-cloudian/apis/common/providerconfig/v1alpha1"
+	userv1alpha1common "github.com/statnett/provider-cloudian/apis/common/user/v1alpha1"
 	userv1alpha1namespaced "github.com/statnett/provider-cloudian/apis/namespaced/user/v1alpha1"
 	apisv1alpha1namespaced "github.com/statnett/provider-cloudian/apis/namespaced/v1alpha1"
+	"github.com/statnett/provider-cloudian/internal/clients"
 	controllercommon "github.com/statnett/provider-cloudian/internal/controller/common"
+	accesskeycontrollercommon "github.com/statnett/provider-cloudian/internal/controller/common/accesskey"
+	"github.com/statnett/provider-cloudian/internal/controller/common/degraded"
+	"github.com/statnett/provider-cloudian/internal/controller/common/identity"
+	"github.com/statnett/provider-cloudian/internal/controller/common/ownership"
+	"github.com/statnett/provider-cloudian/internal/controller/common/referrers"
+	"github.com/statnett/provider-cloudian/internal/controller/common/statusmirror"
+	usercontrollercommon "github.com/statnett/provider-cloudian/internal/controller/common/user"
+	"github.com/statnett/provider-cloudian/internal/features"
 	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
 )
 
@@ -48,7 +62,18 @@ const (
 	errNewClient  = "cannot create new Service"
 	errCreateUser = "cannot create User"
 	errDeleteUser = "cannot delete User"
+	errUpdateUser = "cannot update User"
 	errGetUser    = "cannot get User"
+	errInvalidID  = "invalid Cloudian group or user ID"
+
+	errIndexUsers = "cannot index Users by external identity"
+	errListUsers  = "cannot list Users by external identity"
+	errDuplicate  = "the Cloudian user is already managed by User %s"
+
+	errListAccessKeyMRs    = "cannot list AccessKeys"
+	errGetInitialAccessKey = "cannot get initial access key of User"
+
+	reasonUnmanagedAccessKeys event.Reason = "UnmanagedAccessKeys"
 )
 
 // SetupGated registers controller setup with the gate, waiting for the
@@ -64,33 +89,68 @@ func SetupGated(mgr ctrl.Manager, o controller.Options) error {
 
 // Setup adds a controller that reconciles User managed resources.
 func Setup(mgr ctrl.Manager, o controller.Options) error {
-	name := managed.ControllerName(userv1alpha1namespaced.UserGroupKind)
-
-	r := managed.NewReconciler(mgr,
-		resource.ManagedKind(userv1alpha1namespaced.UserGroupVersionKind),
-		managed.WithExternalConnector(&connector{
-			kube:         mgr.GetClient(),
-			usage:        resource.NewProviderConfigUsageTracker(mgr.GetClient(), &apisv1alpha1namespaced.ProviderConfigUsage{}),
-			newServiceFn: controllercommon.NewCloudianService}),
-		managed.WithLogger(o.Logger.WithValues("controller", name)),
-		managed.WithPollInterval(o.PollInterval),
-		//nolint:staticcheck // SA1004 crossplane-runtime still depends on deprecated API
-		managed.WithRecorder(event.NewAPIRecorder(mgr.GetEventRecorderFor(name))))
-
-	return ctrl.NewControllerManagedBy(mgr).
-		Named(name).
-		WithOptions(o.ForControllerRuntime()).
-		WithEventFilter(resource.DesiredStateChanged()).
-		For(&userv1alpha1namespaced.User{}).
-		Complete(ratelimiter.NewReconciler(name, r, o.GlobalRateLimiter))
+	if err := mgr.GetFieldIndexer().IndexField(context.Background(), &userv1alpha1namespaced.User{}, identity.IndexField, externalIdentity); err != nil {
+		return errors.Wrap(err, errIndexUsers)
+	}
+
+	if err := controllercommon.SetupManaged[userv1alpha1namespaced.User](mgr, o, controllercommon.ManagedKind{
+		GroupVersionKind: userv1alpha1namespaced.UserGroupVersionKind,
+		Timeout:          controllercommon.ReconcileTimeouts.User,
+		NewConnector: func(recorder event.Recorder) managed.ExternalConnector {
+			return &connector{
+				kube:            mgr.GetClient(),
+				usage:           resource.NewProviderConfigUsageTracker(mgr.GetClient(), &apisv1alpha1namespaced.ProviderConfigUsage{}),
+				newServiceFn:    clients.Shared.Get,
+				recorder:        recorder,
+				checkAccessKeys: o.Features.Enabled(features.EnableUnmanagedAccessKeyCheck),
+				observeBuckets:  o.Features.Enabled(features.EnableBucketObservation),
+			}
+		},
+		Options: []managed.ReconcilerOption{
+			managed.WithReferenceResolver(managed.NewAPISimpleReferenceResolver(mgr.GetClient())),
+		},
+		Watches: []controllercommon.Watch{{
+			Object:     &userv1alpha1namespaced.Group{},
+			Map:        usersOfGroup(mgr.GetClient()),
+			Predicates: []predicate.Predicate{referrers.Resolvable()},
+		}},
+	}); err != nil {
+		return err
+	}
+
+	return statusmirror.Setup(mgr, o, userv1alpha1namespaced.UserGroupVersionKind, func() statusmirror.Mirrored { return &userv1alpha1namespaced.User{} })
+}
+
+// usersOfGroup maps a Group to the Users in its namespace that are its members or refer to
+// it, so that they are created as soon as it is ready rather than at their
+// next poll. Users are left to their poll when they cannot be listed.
+func usersOfGroup(kube client.Reader) handler.MapFunc {
+	return func(ctx context.Context, o client.Object) []reconcile.Request {
+		g, ok := o.(*userv1alpha1namespaced.Group)
+		if !ok {
+			return nil
+		}
+		users := &userv1alpha1namespaced.UserList{}
+		if err := kube.List(ctx, users, client.InNamespace(o.GetNamespace())); err != nil {
+			return nil
+		}
+		return referrers.Requests(users.Items, func(u *userv1alpha1namespaced.User) bool {
+			return usercontrollercommon.RefersToGroup(u.Spec.ForProvider, g, g.GetGroupID())
+		})
+	}
 }
 
 // A connector is expected to produce an ExternalClient when its Connect method
 // is called.
 type connector struct {
 	kube         client.Client
-	usage        *resource.ProviderConfigUsageTracker
-	newServiceFn func(providerConfigEndpoint string, authHeader string) (*cloudian.Client, error)
+	usage        resource.ModernTracker
+	newServiceFn func(uid types.UID, spec pcv1alpha1common.ProviderConfigSpec, creds controllercommon.Credentials) (*cloudian.Client, error)
+	recorder     event.Recorder
+	// checkAccessKeys enables warning about access keys not managed by an AccessKey.
+	checkAccessKeys bool
+	// observeBuckets enables listing the buckets of the user in its status.
+	observeBuckets bool
 }
 
 // Connect typically produces an ExternalClient by:
@@ -109,22 +169,29 @@ func (c *connector) Connect(ctx context.Context, mg resource.Managed) (managed.E
 	}
 
 	pc := &apisv1alpha1namespaced.ProviderConfig{}
-	if err := c.kube.Get(ctx, types.NamespacedName{Name: cr.GetProviderConfigReference().Name}, pc); err != nil {
+	if err := c.kube.Get(ctx, types.NamespacedName{Namespace: cr.GetNamespace(), Name: cr.GetProviderConfigReference().Name}, pc); err != nil {
 		return nil, errors.Wrap(err, errGetPC)
 	}
 
-	cd := pc.Spec.AuthHeader
-	authHeader, err := resource.CommonCredentialExtractor(ctx, cd.Source, c.kube, cd.CommonCredentialSelectors)
+	creds, err := controllercommon.ExtractCredentials(ctx, c.kube, pc.Spec)
 	if err != nil {
 		return nil, errors.Wrap(err, errGetCreds)
 	}
 
-	svc, err := c.newServiceFn(pc.Spec.Endpoint, string(authHeader))
+	svc, err := c.newServiceFn(pc.GetUID(), pc.Spec, creds)
 	if err != nil {
 		return nil, errors.Wrap(err, errNewClient)
 	}
 
-	return &external{cloudianService: svc}, nil
+	return &external{
+		cloudianService:       svc,
+		clusterID:             ownership.ClusterID(pc.Spec),
+		strictCredentialCheck: pc.Spec.StrictCredentialCheck,
+		kube:                  c.kube,
+		recorder:              c.recorder,
+		checkAccessKeys:       c.checkAccessKeys,
+		observeBuckets:        c.observeBuckets,
+	}, nil
 }
 
 // An ExternalClient observes, then either creates, updates, or deletes an
@@ -133,6 +200,17 @@ type external struct {
 	// A 'client' used to connect to the external resource API. In practice this
 	// would be something like an AWS SDK client.
 	cloudianService *cloudian.Client
+	// clusterID marks the users owned by this cluster. Users are not marked
+	// when empty.
+	clusterID string
+	// strictCredentialCheck refuses to delete a user whose access keys
+	// cannot be listed.
+	strictCredentialCheck bool
+	// kube is used to find the AccessKeys of the user.
+	kube            client.Client
+	recorder        event.Recorder
+	checkAccessKeys bool
+	observeBuckets  bool
 }
 
 func (c *external) Observe(ctx context.Context, mg resource.Managed) (managed.ExternalObservation, error) {
@@ -151,6 +229,31 @@ func (c *external) Observe(ctx context.Context, mg resource.Managed) (managed.Ex
 		return managed.ExternalObservation{}, nil
 	}
 
+	if err := validateIDs(group, externalName); err != nil {
+		if meta.WasDeleted(cr) {
+			// Cloudian would have rejected the user, so there is nothing to delete.
+			return managed.ExternalObservation{}, nil
+		}
+		return managed.ExternalObservation{}, err
+	}
+
+	first, err := c.firstClaimant(ctx, cr)
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
+	if first != cr {
+		if meta.WasDeleted(cr) {
+			// Let the duplicate go without deleting the Cloudian user managed by another User.
+			return managed.ExternalObservation{ResourceExists: false}, nil
+		}
+		err := errors.Errorf(errDuplicate, client.ObjectKeyFromObject(first))
+		cr.SetConditions(identity.Duplicate(err))
+		return managed.ExternalObservation{}, err
+	}
+	if cr.GetCondition(identity.TypeUnique).Reason == identity.ReasonDuplicateExternalIdentity {
+		cr.SetConditions(identity.Unique())
+	}
+
 	user, err := c.cloudianService.GetUser(ctx, cloudian.GroupUserID{
 		GroupID: group,
 		UserID:  externalName})
@@ -161,7 +264,41 @@ func (c *external) Observe(ctx context.Context, mg resource.Managed) (managed.Ex
 		return managed.ExternalObservation{}, errors.Wrap(err, errGetUser)
 	}
 
+	if err := c.checkOwnership(cr, *user); err != nil {
+		return managed.ExternalObservation{}, err
+	}
+
+	desiredType, err := usercontrollercommon.Type(cr.Spec.ForProvider)
+	if err != nil {
+		return managed.ExternalObservation{}, err
+	}
+	typeErr := usercontrollercommon.CheckType(desiredType, user.UserType)
+	if typeErr != nil {
+		cr.SetConditions(usercontrollercommon.TypeMismatch(typeErr))
+	} else if cr.GetCondition(usercontrollercommon.TypeUserType).Reason == usercontrollercommon.ReasonUserTypeMismatch {
+		cr.SetConditions(usercontrollercommon.TypeMatch())
+	}
+
 	cr.Status.AtProvider.CanonicalID = user.CanonicalID
+	cr.Status.AtProvider.Status = string(user.Status)
+	cr.Status.AtProvider.UserType = string(user.UserType)
+	// The user is known to exist, so the access keys and buckets failing to
+	// be listed only leave them as last observed.
+	var observation degraded.Observation
+	if c.checkAccessKeys || controllercommon.ObserveOnly(cr) {
+		if err := c.observeAccessKeys(ctx, cr, user.GroupUserID, &observation); err != nil {
+			return managed.ExternalObservation{}, err
+		}
+	}
+	if c.observeBuckets {
+		buckets, err := c.cloudianService.ListUserBuckets(ctx, user.GroupUserID)
+		if err != nil {
+			observation.Fail("ListUserBuckets", err)
+		} else {
+			cr.Status.AtProvider.Buckets = usercontrollercommon.Buckets(buckets)
+		}
+	}
+	observation.SetCondition(cr)
 	cr.SetConditions(xpv2.Available())
 
 	return managed.ExternalObservation{
@@ -173,32 +310,162 @@ func (c *external) Observe(ctx context.Context, mg resource.Managed) (managed.Ex
 		// Return false when the external resource exists, but it not up to date
 		// with the desired managed resource state. This lets the managed
 		// resource reconciler know that it needs to call Update.
-		ResourceUpToDate: true,
+		// A user of another type cannot be updated, only recreated.
+		ResourceUpToDate: typeErr == nil && c.isUpToDate(cr.Spec.ForProvider, *user),
 
 		// Return any details that may be required to connect to the external
-		// resource. These will be stored as the connection secret.
-		ConnectionDetails: managed.ConnectionDetails{},
+		// resource. These will be stored as the connection secret, and
+		// republished when they change.
+		ConnectionDetails: usercontrollercommon.ConnectionDetails(cloudian.GroupUserID{GroupID: group, UserID: externalName}, user.CanonicalID),
 	}, nil
 }
 
+// externalIdentity indexes a User by the Cloudian user it manages.
+func externalIdentity(o client.Object) []string {
+	cr, ok := o.(*userv1alpha1namespaced.User)
+	if !ok {
+		return nil
+	}
+	return identity.Key(cr.Spec.ForProvider.GroupID, meta.GetExternalName(cr))
+}
+
+// firstClaimant returns the first User to manage the same Cloudian user as
+// the supplied one. Other Users managing the same Cloudian user are refused.
+func (c *external) firstClaimant(ctx context.Context, cr *userv1alpha1namespaced.User) (client.Object, error) {
+	users := &userv1alpha1namespaced.UserList{}
+	if err := c.kube.List(ctx, users, client.MatchingFields{identity.IndexField: externalIdentity(cr)[0]}); err != nil {
+		return nil, errors.Wrap(err, errListUsers)
+	}
+	claimants := []client.Object{cr}
+	for i := range users.Items {
+		if users.Items[i].GetUID() != cr.GetUID() {
+			claimants = append(claimants, &users.Items[i])
+		}
+	}
+
+	return identity.First(claimants), nil
+}
+
+// observeAccessKeys records the access keys of the user in its status, and
+// warns about access keys not managed by an AccessKey when checking them.
+// Users that are only observed record their access keys regardless. Failing
+// to list the access keys degrades the observation.
+func (c *external) observeAccessKeys(ctx context.Context, cr *userv1alpha1namespaced.User, guid cloudian.GroupUserID, observation *degraded.Observation) error {
+	keys, err := c.cloudianService.ListUserCredentials(ctx, guid)
+	if err != nil {
+		observation.Fail("ListUserCredentials", err)
+		return nil
+	}
+	ids := make([]string, 0, len(keys))
+	for _, key := range keys {
+		ids = append(ids, key.AccessKey)
+	}
+	cr.Status.AtProvider.AccessKeyCount = len(ids)
+	cr.Status.AtProvider.AccessKeyIDs = cloudian.NormalizeSet(ids)
+	if !c.checkAccessKeys {
+		return nil
+	}
+
+	aks := &userv1alpha1namespaced.AccessKeyList{}
+	if err := c.kube.List(ctx, aks, client.InNamespace(cr.GetNamespace())); err != nil {
+		return errors.Wrap(err, errListAccessKeyMRs)
+	}
+	var managedIDs []string
+	for _, ak := range aks.Items {
+		if ak.Spec.ForProvider.GroupID == guid.GroupID && ak.Spec.ForProvider.UserID == guid.UserID {
+			managedIDs = append(managedIDs, meta.GetExternalName(&ak))
+		}
+	}
+
+	if unmanaged := accesskeycontrollercommon.Unmanaged(keys, managedIDs); len(unmanaged) > 0 {
+		c.recorder.Event(cr, event.Warning(reasonUnmanagedAccessKeys,
+			errors.Errorf("user has access keys not managed by an AccessKey: %s", strings.Join(unmanaged, ", "))))
+	}
+	return nil
+}
+
+// isUpToDate returns whether the observed user has the desired status and,
+// when claiming ownership, is marked as owned by this cluster.
+func (c *external) isUpToDate(desired userv1alpha1common.UserParameters, observed cloudian.User) bool {
+	if c.clusterID != "" && observed.Address2 != ownership.Marker(c.clusterID) {
+		return false
+	}
+	// Statuses unknown to the provider never match the desired one.
+	return desired.Status == nil || *desired.Status == string(observed.Status)
+}
+
+// validateIDs fails early for IDs that Cloudian would reject, rather than
+// sending them to the API.
+func validateIDs(groupID, userID string) error {
+	if err := cloudian.ValidateGroupID(groupID); err != nil {
+		return errors.Wrap(err, errInvalidID)
+	}
+	return errors.Wrap(cloudian.ValidateUserID(userID), errInvalidID)
+}
+
+// checkOwnership refuses to manage users marked by another cluster. Users
+// that are only observed may be marked by any cluster.
+func (c *external) checkOwnership(cr *userv1alpha1namespaced.User, user cloudian.User) error {
+	if c.clusterID == "" || controllercommon.ObserveOnly(cr) {
+		return nil
+	}
+	if err := ownership.Check(user.Address2, c.clusterID); err != nil {
+		cr.SetConditions(ownership.OwnedByAnotherCluster(err))
+		return err
+	}
+	return nil
+}
+
 func (c *external) Create(ctx context.Context, mg resource.Managed) (managed.ExternalCreation, error) {
 	cr, ok := mg.(*userv1alpha1namespaced.User)
 	if !ok {
 		return managed.ExternalCreation{}, errors.New(errNotUser)
 	}
 
+	defer identity.Mutations.Lock(cr.Spec.ForProvider.GroupID, meta.GetExternalName(cr))()
+
+	if err := validateIDs(cr.Spec.ForProvider.GroupID, meta.GetExternalName(mg)); err != nil {
+		return managed.ExternalCreation{}, err
+	}
+	userType, err := usercontrollercommon.Type(cr.Spec.ForProvider)
+	if err != nil {
+		return managed.ExternalCreation{}, err
+	}
+
 	user := cloudian.User{
 		GroupUserID: cloudian.GroupUserID{
 			GroupID: cr.Spec.ForProvider.GroupID,
 			UserID:  meta.GetExternalName(mg),
 		},
-		UserType: cloudian.UserTypeStandard,
+		UserType: userType,
 	}
-	if err := c.cloudianService.CreateUser(ctx, user); err != nil {
+	if c.clusterID != "" {
+		user.Address2 = ownership.Marker(c.clusterID)
+	}
+	if status := cr.Spec.ForProvider.Status; status != nil {
+		user.Status = cloudian.UserStatus(*status)
+	}
+	created, err := c.cloudianService.CreateUser(ctx, user)
+	if err != nil {
 		return managed.ExternalCreation{}, errors.Wrap(err, errCreateUser)
 	}
+	cr.Status.AtProvider.CanonicalID = created.CanonicalID
+	details := usercontrollercommon.ConnectionDetails(user.GroupUserID, created.CanonicalID)
 
 	// When Cloudian creates a user, a single access key is created inside it.
+	if cr.Spec.ForProvider.RetainInitialAccessKey {
+		// Keep the access key, and publish it along with the user.
+		creds, err := c.cloudianService.InitialUserCredentials(ctx, user.GroupUserID)
+		switch {
+		case errors.Is(err, cloudian.ErrNotFound):
+			// Cloudian created none, so there is nothing to publish.
+		case err != nil:
+			return managed.ExternalCreation{}, errors.Wrap(err, errGetInitialAccessKey)
+		default:
+			maps.Copy(details, usercontrollercommon.InitialAccessKeyDetails(*creds))
+		}
+		return managed.ExternalCreation{ConnectionDetails: details}, nil
+	}
 	// Delete the access key, so that the user does not have any non-managed access keys.
 	creds, err := c.cloudianService.ListUserCredentials(ctx, user.GroupUserID)
 	if err != nil {
@@ -213,7 +480,7 @@ func (c *external) Create(ctx context.Context, mg resource.Managed) (managed.Ext
 	return managed.ExternalCreation{
 		// Optionally return any details that may be required to connect to the
 		// external resource. These will be stored as the connection secret.
-		ConnectionDetails: managed.ConnectionDetails{},
+		ConnectionDetails: details,
 	}, nil
 }
 
@@ -223,7 +490,34 @@ func (c *external) Update(ctx context.Context, mg resource.Managed) (managed.Ext
 		return managed.ExternalUpdate{}, errors.New(errNotUser)
 	}
 
-	fmt.Printf("Pretending to Update (no managed fields to update): %+v", cr)
+	defer identity.Mutations.Lock(cr.Spec.ForProvider.GroupID, meta.GetExternalName(cr))()
+
+	user, err := c.cloudianService.GetUser(ctx, cloudian.GroupUserID{
+		GroupID: cr.Spec.ForProvider.GroupID,
+		UserID:  meta.GetExternalName(cr)})
+	if err != nil {
+		return managed.ExternalUpdate{}, errors.Wrap(err, errGetUser)
+	}
+	if err := c.checkOwnership(cr, *user); err != nil {
+		return managed.ExternalUpdate{}, err
+	}
+	desiredType, err := usercontrollercommon.Type(cr.Spec.ForProvider)
+	if err != nil {
+		return managed.ExternalUpdate{}, err
+	}
+	if err := usercontrollercommon.CheckType(desiredType, user.UserType); err != nil {
+		return managed.ExternalUpdate{}, err
+	}
+	if c.clusterID != "" {
+		// Adopt the user by marking it as owned by this cluster.
+		user.Address2 = ownership.Marker(c.clusterID)
+	}
+	if status := cr.Spec.ForProvider.Status; status != nil {
+		user.Status = cloudian.UserStatus(*status)
+	}
+	if err := c.cloudianService.UpdateUser(ctx, *user); err != nil {
+		return managed.ExternalUpdate{}, errors.Wrap(err, errUpdateUser)
+	}
 
 	return managed.ExternalUpdate{
 		// Optionally return any details that may be required to connect to the
@@ -238,20 +532,35 @@ func (c *external) Delete(ctx context.Context, mg resource.Managed) (managed.Ext
 		return managed.ExternalDelete{}, errors.New(errNotUser)
 	}
 
+	defer identity.Mutations.Lock(cr.Spec.ForProvider.GroupID, meta.GetExternalName(cr))()
+
 	guid := cloudian.GroupUserID{
 		GroupID: cr.Spec.ForProvider.GroupID,
 		UserID:  meta.GetExternalName(mg),
 	}
 
-	creds, err := c.cloudianService.ListUserCredentials(ctx, guid)
+	if c.clusterID != "" {
+		user, err := c.cloudianService.GetUser(ctx, guid)
+		if errors.Is(err, cloudian.ErrNotFound) {
+			return managed.ExternalDelete{}, nil
+		}
+		if err != nil {
+			return managed.ExternalDelete{}, errors.Wrap(err, errGetUser)
+		}
+		if err := c.checkOwnership(cr, *user); err != nil {
+			return managed.ExternalDelete{}, err
+		}
+	}
+
+	skipped, err := usercontrollercommon.DeleteAccessKeys(ctx, c.cloudianService, guid, cr.Spec.ForProvider.DeleteKeysOnDelete, c.strictCredentialCheck)
 	if err != nil {
 		return managed.ExternalDelete{}, err
 	}
-	if len(creds) > 0 {
-		return managed.ExternalDelete{}, errors.New("User has access keys and cannot be deleted")
+	if skipped {
+		c.recorder.Event(cr, usercontrollercommon.CredentialCheckSkipped())
 	}
 
-	if err := c.cloudianService.DeleteUser(ctx, guid); err != nil {
+	if err := c.cloudianService.DeleteUser(ctx, guid); err != nil && !errors.Is(err, cloudian.ErrNotFound) {
 		return managed.ExternalDelete{}, errors.Wrap(err, errDeleteUser)
 	}
 
@@ -0,0 +1,200 @@
+// Package examplesgen generates the example manifests of the provider from
+// typed objects, so that the examples change along with the API types and are
+// validated against the CRDs by the tests.
+package examplesgen
+
+//go:generate go run ./generate ../../examples/v1alpha1
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/yaml"
+
+	userv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/user/v1alpha1"
+	userv1alpha1common "github.com/statnett/provider-cloudian/apis/common/user/v1alpha1"
+)
+
+// header marks the example manifests as generated.
+const header = "# Code generated by internal/examplesgen. DO NOT EDIT.\n"
+
+const (
+	errMarshal = "cannot marshal %s %s"
+	errWrite   = "cannot write %s"
+)
+
+// providerConfig is the ProviderConfig of examples/provider/config.yaml.
+var providerConfig = xpv2.ClusterManagedResourceSpec{
+	ProviderConfigReference: &xpv2.Reference{Name: "example"},
+}
+
+// Files returns the objects of each example manifest, by file name.
+func Files() map[string][]runtime.Object {
+	return map[string][]runtime.Object{
+		"group.yaml": {group(), groupQualityOfServiceLimits(), groupRatingPlan()},
+		"user.yaml":  {user(), userQualityOfServiceLimits(), accessKey(), iamUser()},
+	}
+}
+
+func group() *userv1alpha1cluster.Group {
+	cr := &userv1alpha1cluster.Group{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}
+	cr.SetGroupVersionKind(userv1alpha1cluster.GroupGroupVersionKind)
+	cr.Spec.ClusterManagedResourceSpec = providerConfig
+	cr.Spec.ForProvider = userv1alpha1common.GroupParameters{
+		Active:    true,
+		GroupName: "crossplane provisioned group",
+	}
+	return cr
+}
+
+func groupQualityOfServiceLimits() *userv1alpha1cluster.GroupQualityOfServiceLimits {
+	cr := &userv1alpha1cluster.GroupQualityOfServiceLimits{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}
+	cr.SetGroupVersionKind(userv1alpha1cluster.GroupQualityOfServiceLimitsGroupVersionKind)
+	cr.Spec.ClusterManagedResourceSpec = providerConfig
+	cr.Spec.ForProvider = userv1alpha1common.GroupQualityOfServiceLimitsParameters{
+		GroupIDRef: &xpv2.Reference{Name: "foo"},
+		QOS:        qos("10Mi", "20Mi", 100, "4Ti", 100000, "2Ti"),
+	}
+	return cr
+}
+
+func groupRatingPlan() *userv1alpha1cluster.GroupRatingPlan {
+	cr := &userv1alpha1cluster.GroupRatingPlan{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}
+	cr.SetGroupVersionKind(userv1alpha1cluster.GroupRatingPlanGroupVersionKind)
+	cr.Spec.ClusterManagedResourceSpec = providerConfig
+	cr.Spec.ForProvider = userv1alpha1common.GroupRatingPlanParameters{
+		GroupIDRef:   &xpv2.Reference{Name: "foo"},
+		RatingPlanID: "Gold-RP",
+	}
+	return cr
+}
+
+func user() *userv1alpha1cluster.User {
+	cr := &userv1alpha1cluster.User{ObjectMeta: metav1.ObjectMeta{Name: "bar"}}
+	cr.SetGroupVersionKind(userv1alpha1cluster.UserGroupVersionKind)
+	cr.Spec.ClusterManagedResourceSpec = providerConfig
+	cr.Spec.ForProvider = userv1alpha1common.UserParameters{
+		GroupIDRef: &xpv2.Reference{Name: "foo"},
+	}
+	cr.Spec.StatusMirror = &userv1alpha1common.StatusMirror{Namespace: "team-foo", Name: "cloudian-user-bar-status"}
+	return cr
+}
+
+func userQualityOfServiceLimits() *userv1alpha1cluster.UserQualityOfServiceLimits {
+	cr := &userv1alpha1cluster.UserQualityOfServiceLimits{ObjectMeta: metav1.ObjectMeta{Name: "bar"}}
+	cr.SetGroupVersionKind(userv1alpha1cluster.UserQualityOfServiceLimitsGroupVersionKind)
+	cr.Spec.ClusterManagedResourceSpec = providerConfig
+	cr.Spec.ForProvider = userv1alpha1common.UserQualityOfServiceLimitsParameters{
+		UserIDRef: &xpv2.Reference{Name: "bar"},
+		QOS:       qos("5Mi", "10Mi", 50, "2Ti", 50000, "1Ti"),
+	}
+	return cr
+}
+
+func accessKey() *userv1alpha1cluster.AccessKey {
+	cr := &userv1alpha1cluster.AccessKey{ObjectMeta: metav1.ObjectMeta{Name: "bar"}}
+	cr.SetGroupVersionKind(userv1alpha1cluster.AccessKeyGroupVersionKind)
+	cr.Spec.ClusterManagedResourceSpec = providerConfig
+	cr.Spec.ForProvider = userv1alpha1common.AccessKeyParameters{
+		UserIDRef: &xpv2.Reference{Name: "bar"},
+		Labels:    map[string]string{"team": "foo"},
+	}
+	return cr
+}
+
+func iamUser() *userv1alpha1cluster.IAMUser {
+	cr := &userv1alpha1cluster.IAMUser{ObjectMeta: metav1.ObjectMeta{Name: "bar-ci"}}
+	cr.SetGroupVersionKind(userv1alpha1cluster.IAMUserGroupVersionKind)
+	cr.Spec.ClusterManagedResourceSpec = providerConfig
+	cr.Spec.ForProvider = userv1alpha1common.IAMUserParameters{
+		UserIDRef: &xpv2.Reference{Name: "bar"},
+	}
+	return cr
+}
+
+// qos returns hard limits of every kind, and a warning of the storage quota.
+func qos(inbound, outbound userv1alpha1common.Quantity, requests uint32, storage userv1alpha1common.Quantity, count uint32, warning userv1alpha1common.Quantity) userv1alpha1common.QOS {
+	return userv1alpha1common.QOS{
+		Hard: &userv1alpha1common.QualityOfServiceLimits{
+			InboundBytesPerMin:  &inbound,
+			OutboundBytesPerMin: &outbound,
+			RequestsPerMin:      ptr.To(requests),
+			StorageQuotaBytes:   &storage,
+			StorageQuotaCount:   ptr.To(count),
+		},
+		Warning: &userv1alpha1common.QualityOfServiceLimits{
+			StorageQuotaBytes: &warning,
+		},
+	}
+}
+
+// Manifest returns an object as it is written to an example manifest: without
+// status, creation timestamp or unset fields.
+func Manifest(obj runtime.Object) (map[string]any, error) {
+	u, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	delete(u, "status")
+	if metadata, ok := u["metadata"].(map[string]any); ok {
+		delete(metadata, "creationTimestamp")
+	}
+	return prune(u).(map[string]any), nil
+}
+
+// prune removes the null values and empty objects of unset fields.
+func prune(v any) any {
+	m, ok := v.(map[string]any)
+	if !ok {
+		return v
+	}
+	for k, field := range m {
+		field = prune(field)
+		if child, ok := field.(map[string]any); field == nil || (ok && len(child) == 0) {
+			delete(m, k)
+			continue
+		}
+		m[k] = field
+	}
+	return m
+}
+
+// Render returns the content of an example manifest of the supplied objects.
+func Render(objs []runtime.Object) ([]byte, error) {
+	buf := bytes.NewBufferString(header)
+	for _, obj := range objs {
+		gvk := obj.GetObjectKind().GroupVersionKind()
+		m, err := Manifest(obj)
+		if err != nil {
+			return nil, errors.Wrapf(err, errMarshal, gvk.Kind, gvk.GroupVersion())
+		}
+		b, err := yaml.Marshal(m)
+		if err != nil {
+			return nil, errors.Wrapf(err, errMarshal, gvk.Kind, gvk.GroupVersion())
+		}
+		buf.WriteString("---\n")
+		buf.Write(b)
+	}
+	return buf.Bytes(), nil
+}
+
+// Write writes the example manifests to the supplied directory.
+func Write(dir string) error {
+	for name, objs := range Files() {
+		b, err := Render(objs)
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, b, 0o644); err != nil { //nolint:gosec // Example manifests are meant to be read.
+			return errors.Wrapf(err, errWrite, path)
+		}
+	}
+	return nil
+}
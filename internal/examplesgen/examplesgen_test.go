@@ -0,0 +1,133 @@
+package examplesgen
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	structuralschema "k8s.io/apiextensions-apiserver/pkg/apiserver/schema"
+	"k8s.io/apiextensions-apiserver/pkg/apiserver/schema/pruning"
+	"k8s.io/apiextensions-apiserver/pkg/apiserver/validation"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	crdDir      = "../../package/crds"
+	examplesDir = "../../examples/v1alpha1"
+)
+
+// loadSchemas returns the OpenAPI schemas of the CRDs of the provider, by
+// group, version and kind.
+func loadSchemas(t *testing.T) map[schema.GroupVersionKind]*apiextensions.JSONSchemaProps {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join(crdDir, "*.yaml"))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("cannot find the CRDs in %s: %v", crdDir, err)
+	}
+	schemas := map[schema.GroupVersionKind]*apiextensions.JSONSchemaProps{}
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		crd := &apiextensionsv1.CustomResourceDefinition{}
+		if err := yaml.UnmarshalStrict(b, crd); err != nil {
+			t.Fatalf("cannot parse %s: %v", path, err)
+		}
+		for _, v := range crd.Spec.Versions {
+			props := &apiextensions.JSONSchemaProps{}
+			if err := apiextensionsv1.Convert_v1_JSONSchemaProps_To_apiextensions_JSONSchemaProps(v.Schema.OpenAPIV3Schema, props, nil); err != nil {
+				t.Fatalf("cannot convert the schema of %s: %v", path, err)
+			}
+			schemas[schema.GroupVersionKind{Group: crd.Spec.Group, Version: v.Name, Kind: crd.Spec.Names.Kind}] = props
+		}
+	}
+	return schemas
+}
+
+// validate returns the problems of a manifest according to the schema of its
+// CRD.
+func validate(t *testing.T, props *apiextensions.JSONSchemaProps, m map[string]any) []string {
+	t.Helper()
+	validator, _, err := validation.NewSchemaValidator(props)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var problems []string
+	for _, err := range validation.ValidateCustomResource(field.NewPath(""), m, validator) {
+		problems = append(problems, err.Error())
+	}
+
+	// Fields the schema does not know are pruned by the API server rather
+	// than rejected, and would silently be lost.
+	structural, err := structuralschema.NewStructural(props)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range pruning.PruneWithOptions(m, structural, true, structuralschema.UnknownFieldPathOptions{TrackUnknownFieldPaths: true}) {
+		problems = append(problems, "unknown field "+path)
+	}
+	return problems
+}
+
+func TestExamplesValid(t *testing.T) {
+	schemas := loadSchemas(t)
+
+	for file, objs := range Files() {
+		for _, obj := range objs {
+			gvk := obj.GetObjectKind().GroupVersionKind()
+			t.Run(file+"/"+gvk.Kind, func(t *testing.T) {
+				props, ok := schemas[gvk]
+				if !ok {
+					t.Fatalf("no CRD of %s", gvk)
+				}
+				m, err := Manifest(obj)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if problems := validate(t, props, m); len(problems) > 0 {
+					t.Errorf("%s does not match its CRD: %v", gvk.Kind, problems)
+				}
+			})
+		}
+	}
+}
+
+func TestValidateBrokenExample(t *testing.T) {
+	schemas := loadSchemas(t)
+	m, err := Manifest(group())
+	if err != nil {
+		t.Fatal(err)
+	}
+	forProvider := m["spec"].(map[string]any)["forProvider"].(map[string]any)
+	forProvider["groupName"] = 42
+	forProvider["removedField"] = true
+
+	problems := validate(t, schemas[group().GroupVersionKind()], m)
+	if len(problems) != 2 {
+		t.Errorf("validate(...): want a wrong type and an unknown field, got %v", problems)
+	}
+}
+
+func TestExamplesUpToDate(t *testing.T) {
+	for file, objs := range Files() {
+		t.Run(file, func(t *testing.T) {
+			want, err := Render(objs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile(filepath.Join(examplesDir, file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(string(want), string(got)); diff != "" {
+				t.Errorf("%s is out of date, run go generate ./internal/examplesgen: -want, +got:\n%s", file, diff)
+			}
+		})
+	}
+}
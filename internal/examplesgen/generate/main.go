@@ -0,0 +1,21 @@
+// Command generate writes the example manifests of the provider to the
+// directory given as its argument.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/statnett/provider-cloudian/internal/examplesgen"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: generate <directory>")
+		os.Exit(2)
+	}
+	if err := examplesgen.Write(os.Args[1]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,36 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package features defines the feature flags of the provider.
+package features
+
+import "github.com/crossplane/crossplane-runtime/v2/pkg/feature"
+
+// Feature flags.
+const (
+	// EnableUnmanagedAccessKeyCheck makes the User controllers list the
+	// access keys of each user when observing it, and warn about access keys
+	// not managed by an AccessKey.
+	EnableUnmanagedAccessKeyCheck feature.Flag = "EnableUnmanagedAccessKeyCheck"
+
+	// EnableBucketObservation makes the User controllers list the buckets of
+	// each user, with their usage, when observing it.
+	EnableBucketObservation feature.Flag = "EnableBucketObservation"
+
+	// EnableGroupUserCount makes the Group controllers count the users of
+	// each group when observing it.
+	EnableGroupUserCount feature.Flag = "EnableGroupUserCount"
+)
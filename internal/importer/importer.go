@@ -0,0 +1,152 @@
+// Package importer generates the manifests of managed resources that adopt
+// existing Cloudian users and their access keys, without recreating them.
+package importer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/meta"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+
+	userv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/user/v1alpha1"
+	userv1alpha1common "github.com/statnett/provider-cloudian/apis/common/user/v1alpha1"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
+)
+
+const (
+	errSearchUsers     = "cannot search users"
+	errListAccessKeys  = "cannot list access keys of user %q"
+	errDuplicateName   = "users %q and %q would both be named %q"
+	errConvertManifest = "cannot convert %s %q"
+	errMarshalManifest = "cannot marshal %s %q"
+)
+
+// Cloudian is the part of the Cloudian client used to find what to import.
+type Cloudian interface {
+	SearchUsers(ctx context.Context, groupID, prefix string) ([]cloudian.User, error)
+	ListUserCredentials(ctx context.Context, guid cloudian.GroupUserID) ([]cloudian.SecurityInfo, error)
+}
+
+// Options configure the generated managed resources.
+type Options struct {
+	// ProviderConfig is the name of the ProviderConfig of the managed
+	// resources.
+	ProviderConfig string
+
+	// ObserveOnly makes the managed resources only observe Cloudian, so that
+	// they can be reviewed before Crossplane takes over managing them.
+	ObserveOnly bool
+
+	// ConnectionSecretNamespace is the namespace the access keys are written
+	// to as connection secrets. They are not written if it is empty.
+	ConnectionSecretNamespace string
+}
+
+// Import returns the User and AccessKey managed resources adopting the users
+// of a group whose user ID starts with `prefix`, along with their access keys.
+// Only standard users are imported, as Crossplane cannot manage admins.
+func Import(ctx context.Context, c Cloudian, groupID, prefix string, o Options) ([]resource.Managed, error) {
+	users, err := c.SearchUsers(ctx, groupID, prefix)
+	if err != nil {
+		return nil, errors.Wrap(err, errSearchUsers)
+	}
+
+	var mrs []resource.Managed
+	names := map[string]string{}
+	for _, user := range users {
+		if user.UserType != cloudian.UserTypeStandard {
+			continue
+		}
+
+		name := Name(user.GroupID, user.UserID)
+		if other, ok := names[name]; ok {
+			return nil, errors.Errorf(errDuplicateName, other, user.UserID, name)
+		}
+		names[name] = user.UserID
+
+		keys, err := c.ListUserCredentials(ctx, user.GroupUserID)
+		if err != nil {
+			return nil, errors.Wrapf(err, errListAccessKeys, user.UserID)
+		}
+
+		mrs = append(mrs, newUser(name, user, o))
+		for _, key := range keys {
+			mrs = append(mrs, newAccessKey(Name(name, key.AccessKey), user, key.AccessKey, o))
+		}
+	}
+	return mrs, nil
+}
+
+func newUser(name string, user cloudian.User, o Options) *userv1alpha1cluster.User {
+	mr := &userv1alpha1cluster.User{
+		Spec: userv1alpha1cluster.UserSpec{
+			ForProvider: userv1alpha1common.UserParameters{GroupID: user.GroupID},
+		},
+	}
+	mr.SetGroupVersionKind(userv1alpha1cluster.UserGroupVersionKind)
+	configure(mr, name, user.UserID, o)
+	return mr
+}
+
+func newAccessKey(name string, user cloudian.User, accessKey string, o Options) *userv1alpha1cluster.AccessKey {
+	mr := &userv1alpha1cluster.AccessKey{
+		Spec: userv1alpha1cluster.AccessKeySpec{
+			ForProvider: userv1alpha1common.AccessKeyParameters{GroupID: user.GroupID, UserID: user.UserID},
+		},
+	}
+	mr.SetGroupVersionKind(userv1alpha1cluster.AccessKeyGroupVersionKind)
+	configure(mr, name, accessKey, o)
+	if o.ConnectionSecretNamespace != "" {
+		mr.SetWriteConnectionSecretToReference(&xpv2.SecretReference{Namespace: o.ConnectionSecretNamespace, Name: name})
+	}
+	return mr
+}
+
+func configure(mr resource.LegacyManaged, name, externalName string, o Options) {
+	mr.SetName(name)
+	meta.SetExternalName(mr, externalName)
+	mr.SetProviderConfigReference(&xpv2.Reference{Name: o.ProviderConfig})
+	if o.ObserveOnly {
+		mr.SetManagementPolicies(xpv2.ManagementPolicies{xpv2.ManagementActionObserve})
+	}
+}
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9.-]+`)
+
+// Name returns a valid Kubernetes object name made up of the supplied parts.
+func Name(parts ...string) string {
+	name := strings.ToLower(strings.Join(parts, "-"))
+	return strings.Trim(invalidNameChars.ReplaceAllString(name, "-"), "-.")
+}
+
+// Write writes managed resources to `w` as a stream of YAML documents, leaving
+// out their status and any unset metadata.
+func Write(w io.Writer, mrs []resource.Managed) error {
+	for _, mr := range mrs {
+		kind := mr.GetObjectKind().GroupVersionKind().Kind
+		obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(mr)
+		if err != nil {
+			return errors.Wrapf(err, errConvertManifest, kind, mr.GetName())
+		}
+		delete(obj, "status")
+		unstructured.RemoveNestedField(obj, "metadata", "creationTimestamp")
+
+		b, err := yaml.Marshal(obj)
+		if err != nil {
+			return errors.Wrapf(err, errMarshalManifest, kind, mr.GetName())
+		}
+		if _, err := fmt.Fprintf(w, "---\n%s", b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,198 @@
+package importer
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
+)
+
+type fakeCloudian struct {
+	users []cloudian.User
+	keys  map[string][]cloudian.SecurityInfo
+	err   error
+}
+
+func (f fakeCloudian) SearchUsers(_ context.Context, groupID, prefix string) ([]cloudian.User, error) {
+	var users []cloudian.User
+	for _, u := range f.users {
+		if u.GroupID == groupID && strings.HasPrefix(u.UserID, prefix) {
+			users = append(users, u)
+		}
+	}
+	return users, f.err
+}
+
+func (f fakeCloudian) ListUserCredentials(_ context.Context, guid cloudian.GroupUserID) ([]cloudian.SecurityInfo, error) {
+	return f.keys[guid.UserID], nil
+}
+
+func user(userID string, userType cloudian.UserType) cloudian.User {
+	return cloudian.User{GroupUserID: cloudian.GroupUserID{GroupID: "QA", UserID: userID}, UserType: userType}
+}
+
+func TestImport(t *testing.T) {
+	svc := fakeCloudian{
+		users: []cloudian.User{
+			user("Svc_Backup", cloudian.UserTypeStandard),
+			user("Svc-Admin", cloudian.UserTypeGroupAdmin),
+			user("alice", cloudian.UserTypeStandard),
+		},
+		keys: map[string][]cloudian.SecurityInfo{
+			"Svc_Backup": {{AccessKey: "00AABBCC", SecretKey: cloudian.NewSecret("secret")}},
+		},
+	}
+
+	cases := map[string]struct {
+		svc    fakeCloudian
+		prefix string
+		opts   Options
+		want   string
+		err    string
+	}{
+		"Adopt": {
+			svc:    svc,
+			prefix: "Svc",
+			opts:   Options{ProviderConfig: "default"},
+			want: `---
+apiVersion: user.cloudian.crossplane.io/v1alpha1
+kind: User
+metadata:
+  annotations:
+    crossplane.io/external-name: Svc_Backup
+  name: qa-svc-backup
+spec:
+  forProvider:
+    groupId: QA
+  providerConfigRef:
+    name: default
+---
+apiVersion: user.cloudian.crossplane.io/v1alpha1
+kind: AccessKey
+metadata:
+  annotations:
+    crossplane.io/external-name: 00AABBCC
+  name: qa-svc-backup-00aabbcc
+spec:
+  forProvider:
+    groupId: QA
+    userId: Svc_Backup
+  providerConfigRef:
+    name: default
+`,
+		},
+		"ObserveOnly": {
+			svc:    svc,
+			prefix: "a",
+			opts:   Options{ProviderConfig: "default", ObserveOnly: true},
+			want: `---
+apiVersion: user.cloudian.crossplane.io/v1alpha1
+kind: User
+metadata:
+  annotations:
+    crossplane.io/external-name: alice
+  name: qa-alice
+spec:
+  forProvider:
+    groupId: QA
+  managementPolicies:
+  - Observe
+  providerConfigRef:
+    name: default
+`,
+		},
+		"ConnectionSecrets": {
+			svc:    svc,
+			prefix: "Svc_",
+			opts:   Options{ProviderConfig: "default", ConnectionSecretNamespace: "team-qa"},
+			want: `---
+apiVersion: user.cloudian.crossplane.io/v1alpha1
+kind: User
+metadata:
+  annotations:
+    crossplane.io/external-name: Svc_Backup
+  name: qa-svc-backup
+spec:
+  forProvider:
+    groupId: QA
+  providerConfigRef:
+    name: default
+---
+apiVersion: user.cloudian.crossplane.io/v1alpha1
+kind: AccessKey
+metadata:
+  annotations:
+    crossplane.io/external-name: 00AABBCC
+  name: qa-svc-backup-00aabbcc
+spec:
+  forProvider:
+    groupId: QA
+    userId: Svc_Backup
+  providerConfigRef:
+    name: default
+  writeConnectionSecretToRef:
+    name: qa-svc-backup-00aabbcc
+    namespace: team-qa
+`,
+		},
+		"DuplicateNames": {
+			svc: fakeCloudian{users: []cloudian.User{
+				user("svc_backup", cloudian.UserTypeStandard),
+				user("Svc-Backup", cloudian.UserTypeStandard),
+			}},
+			err: `users "svc_backup" and "Svc-Backup" would both be named "qa-svc-backup"`,
+		},
+		"SearchFailed": {
+			svc: fakeCloudian{err: errors.New("boom")},
+			err: errSearchUsers + ": boom",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			mrs, err := Import(context.Background(), tc.svc, "QA", tc.prefix, tc.opts)
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("Import(...): want error %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Import(...): %v", err)
+			}
+
+			var out bytes.Buffer
+			if err := Write(&out, mrs); err != nil {
+				t.Fatalf("Write(...): %v", err)
+			}
+			if diff := cmp.Diff(tc.want, out.String()); diff != "" {
+				t.Errorf("Write(...): -want, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestName(t *testing.T) {
+	cases := map[string]struct {
+		parts []string
+		want  string
+	}{
+		"Lowercase":   {parts: []string{"QA", "Alice"}, want: "qa-alice"},
+		"Underscores": {parts: []string{"QA", "svc__backup"}, want: "qa-svc-backup"},
+		"Email":       {parts: []string{"QA", "alice@example.com"}, want: "qa-alice-example.com"},
+		"Trimmed":     {parts: []string{"_QA", "alice_"}, want: "qa-alice"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := Name(tc.parts...); got != tc.want {
+				t.Errorf("Name(%q): want %q, got %q", tc.parts, tc.want, got)
+			}
+		})
+	}
+}
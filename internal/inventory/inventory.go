@@ -0,0 +1,161 @@
+// Package inventory exports how many Users and AccessKeys the provider manages
+// in each Cloudian group as metrics. The counts are maintained from the
+// informer caches of the manager, and cost no calls to Cloudian.
+package inventory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/types"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	userv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/user/v1alpha1"
+	userv1alpha1namespaced "github.com/statnett/provider-cloudian/apis/namespaced/user/v1alpha1"
+)
+
+const errWatch = "cannot watch %T"
+
+// An Inventory counts the managed Users and AccessKeys of each Cloudian
+// group. Groups are only reported while they have any.
+type Inventory struct {
+	users      *counter
+	accessKeys *counter
+}
+
+// New returns an empty Inventory.
+func New() *Inventory {
+	return &Inventory{
+		users: newCounter(prometheus.GaugeOpts{
+			Name: "provider_cloudian_managed_users",
+			Help: "The number of Users managed by the provider, by Cloudian group.",
+		}),
+		accessKeys: newCounter(prometheus.GaugeOpts{
+			Name: "provider_cloudian_managed_accesskeys",
+			Help: "The number of AccessKeys managed by the provider, by Cloudian group.",
+		}),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (i *Inventory) Describe(ch chan<- *prometheus.Desc) {
+	i.users.gauge.Describe(ch)
+	i.accessKeys.gauge.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (i *Inventory) Collect(ch chan<- prometheus.Metric) {
+	i.users.gauge.Collect(ch)
+	i.accessKeys.gauge.Collect(ch)
+}
+
+// Watch keeps the Inventory up to date with the Users and AccessKeys of both
+// scopes in the supplied informers.
+func (i *Inventory) Watch(ctx context.Context, informers cache.Informers) error {
+	for obj, c := range map[client.Object]*counter{
+		&userv1alpha1cluster.User{}:         i.users,
+		&userv1alpha1namespaced.User{}:      i.users,
+		&userv1alpha1cluster.AccessKey{}:    i.accessKeys,
+		&userv1alpha1namespaced.AccessKey{}: i.accessKeys,
+	} {
+		inf, err := informers.GetInformer(ctx, obj)
+		if err != nil {
+			return errors.Wrapf(err, errWatch, obj)
+		}
+		if _, err := inf.AddEventHandler(c.handler()); err != nil {
+			return errors.Wrapf(err, errWatch, obj)
+		}
+	}
+	return nil
+}
+
+// groupID returns the Cloudian group of a User or AccessKey, which is empty
+// until a reference to the group is resolved.
+func groupID(obj any) (types.UID, string, bool) {
+	switch o := obj.(type) {
+	case *userv1alpha1cluster.User:
+		return o.GetUID(), o.Spec.ForProvider.GroupID, true
+	case *userv1alpha1namespaced.User:
+		return o.GetUID(), o.Spec.ForProvider.GroupID, true
+	case *userv1alpha1cluster.AccessKey:
+		return o.GetUID(), o.Spec.ForProvider.GroupID, true
+	case *userv1alpha1namespaced.AccessKey:
+		return o.GetUID(), o.Spec.ForProvider.GroupID, true
+	default:
+		return "", "", false
+	}
+}
+
+// A counter counts objects by group, remembering the group of each object so
+// that it is counted once however many times it is seen.
+type counter struct {
+	gauge *prometheus.GaugeVec
+
+	mu     sync.Mutex
+	groups map[types.UID]string
+	counts map[string]int
+}
+
+func newCounter(opts prometheus.GaugeOpts) *counter {
+	return &counter{
+		gauge:  prometheus.NewGaugeVec(opts, []string{"group"}),
+		groups: map[types.UID]string{},
+		counts: map[string]int{},
+	}
+}
+
+func (c *counter) handler() toolscache.ResourceEventHandler {
+	return toolscache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj any) {
+			if uid, group, ok := groupID(obj); ok {
+				c.set(uid, group)
+			}
+		},
+		UpdateFunc: func(_, obj any) {
+			if uid, group, ok := groupID(obj); ok {
+				c.set(uid, group)
+			}
+		},
+		DeleteFunc: func(obj any) {
+			if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if uid, _, ok := groupID(obj); ok {
+				c.set(uid, "")
+			}
+		},
+	}
+}
+
+// set counts an object in a group, or in none if the group is empty.
+func (c *counter) set(uid types.UID, group string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	old, ok := c.groups[uid]
+	if ok && old == group {
+		return
+	}
+	if ok {
+		delete(c.groups, uid)
+		c.add(old, -1)
+	}
+	if group != "" {
+		c.groups[uid] = group
+		c.add(group, 1)
+	}
+}
+
+func (c *counter) add(group string, delta int) {
+	c.counts[group] += delta
+	if c.counts[group] > 0 {
+		c.gauge.WithLabelValues(group).Set(float64(c.counts[group]))
+		return
+	}
+	delete(c.counts, group)
+	c.gauge.DeleteLabelValues(group)
+}
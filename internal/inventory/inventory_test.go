@@ -0,0 +1,111 @@
+package inventory
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllertest"
+
+	apiscluster "github.com/statnett/provider-cloudian/apis/cluster"
+	userv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/user/v1alpha1"
+	apisnamespaced "github.com/statnett/provider-cloudian/apis/namespaced"
+	userv1alpha1namespaced "github.com/statnett/provider-cloudian/apis/namespaced/user/v1alpha1"
+)
+
+func clusterUser(uid, groupID string) *userv1alpha1cluster.User {
+	u := &userv1alpha1cluster.User{ObjectMeta: metav1.ObjectMeta{Name: uid, UID: types.UID(uid)}}
+	u.Spec.ForProvider.GroupID = groupID
+	return u
+}
+
+func namespacedUser(uid, groupID string) *userv1alpha1namespaced.User {
+	u := &userv1alpha1namespaced.User{ObjectMeta: metav1.ObjectMeta{Namespace: "team", Name: uid, UID: types.UID(uid)}}
+	u.Spec.ForProvider.GroupID = groupID
+	return u
+}
+
+func clusterAccessKey(uid, groupID string) *userv1alpha1cluster.AccessKey {
+	ak := &userv1alpha1cluster.AccessKey{ObjectMeta: metav1.ObjectMeta{Name: uid, UID: types.UID(uid)}}
+	ak.Spec.ForProvider.GroupID = groupID
+	return ak
+}
+
+func TestInventory(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := apiscluster.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := apisnamespaced.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	informers := &informertest.FakeInformers{Scheme: scheme}
+	informer := func(obj client.Object) *controllertest.FakeInformer {
+		t.Helper()
+		inf, err := informers.FakeInformerFor(context.Background(), obj)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return inf
+	}
+
+	inv := New()
+	if err := inv.Watch(context.Background(), informers); err != nil {
+		t.Fatalf("inv.Watch(...): %v", err)
+	}
+	users := informer(&userv1alpha1cluster.User{})
+	namespacedUsers := informer(&userv1alpha1namespaced.User{})
+	accessKeys := informer(&userv1alpha1cluster.AccessKey{})
+
+	expect := func(reason, want string) {
+		t.Helper()
+		if err := testutil.CollectAndCompare(inv, strings.NewReader(want)); err != nil {
+			t.Errorf("%s: %v", reason, err)
+		}
+	}
+
+	users.Add(clusterUser("alice", "QA"))
+	users.Add(clusterUser("bob", "QA"))
+	namespacedUsers.Add(namespacedUser("carol", "QA"))
+	namespacedUsers.Add(namespacedUser("dave", "Ops"))
+	accessKeys.Add(clusterAccessKey("alice-key", "QA"))
+	expect("Users and AccessKeys of both scopes should be counted by group", `
+# HELP provider_cloudian_managed_accesskeys The number of AccessKeys managed by the provider, by Cloudian group.
+# TYPE provider_cloudian_managed_accesskeys gauge
+provider_cloudian_managed_accesskeys{group="QA"} 1
+# HELP provider_cloudian_managed_users The number of Users managed by the provider, by Cloudian group.
+# TYPE provider_cloudian_managed_users gauge
+provider_cloudian_managed_users{group="Ops"} 1
+provider_cloudian_managed_users{group="QA"} 3
+`)
+
+	// Objects seen again are only counted once, and objects whose group
+	// reference is not resolved yet are not counted.
+	users.Update(clusterUser("alice", "QA"), clusterUser("alice", "QA"))
+	users.Add(clusterUser("erin", ""))
+	users.Delete(clusterUser("bob", "QA"))
+	users.Delete(clusterUser("bob", "QA"))
+	namespacedUsers.Delete(namespacedUser("dave", "Ops"))
+	accessKeys.Delete(clusterAccessKey("alice-key", "QA"))
+	expect("Groups without Users or AccessKeys should no longer be reported", `
+# HELP provider_cloudian_managed_users The number of Users managed by the provider, by Cloudian group.
+# TYPE provider_cloudian_managed_users gauge
+provider_cloudian_managed_users{group="QA"} 2
+`)
+
+	users.Update(clusterUser("erin", ""), clusterUser("erin", "Ops"))
+	inv.users.handler().OnDelete(toolscache.DeletedFinalStateUnknown{Obj: clusterUser("alice", "QA")})
+	expect("Resolved groups and deletions missed by the informer should be counted", `
+# HELP provider_cloudian_managed_users The number of Users managed by the provider, by Cloudian group.
+# TYPE provider_cloudian_managed_users gauge
+provider_cloudian_managed_users{group="Ops"} 1
+provider_cloudian_managed_users{group="QA"} 1
+`)
+}
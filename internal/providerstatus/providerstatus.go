@@ -0,0 +1,272 @@
+// Package providerstatus maintains a ProviderStatus summarizing the managed
+// resources, the health of the ProviderConfigs and the errors of the provider
+// in one object. The summary is aggregated from the informer caches of the
+// manager and the conditions set by the health checks of the ProviderConfigs,
+// and costs no calls to Cloudian.
+package providerstatus
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/logging"
+	"github.com/crossplane/crossplane-runtime/v2/pkg/resource"
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	kmeta "k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/clock"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	userv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/user/v1alpha1"
+	apisv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/v1alpha1"
+	userv1alpha1namespaced "github.com/statnett/provider-cloudian/apis/namespaced/user/v1alpha1"
+	apisv1alpha1namespaced "github.com/statnett/provider-cloudian/apis/namespaced/v1alpha1"
+	"github.com/statnett/provider-cloudian/internal/controller/common/health"
+)
+
+// DefaultInterval is how often the summary is refreshed by default.
+const DefaultInterval = time.Minute
+
+// maxErrors is the most error summaries kept, so that the status stays small
+// however many resources fail.
+const maxErrors = 10
+
+const (
+	errList   = "cannot list %s"
+	errGet    = "cannot get ProviderStatus"
+	errCreate = "cannot create ProviderStatus"
+	errUpdate = "cannot update ProviderStatus status"
+)
+
+// A managedKind is a kind of managed resource, identified by its kind and
+// API group.
+type managedKind struct {
+	groupKind string
+	newList   func() resource.ManagedList
+}
+
+// A providerConfigKind is a kind of ProviderConfig checked for health.
+type providerConfigKind struct {
+	groupKind string
+	newList   func() client.ObjectList
+}
+
+// An Aggregator refreshes the ProviderStatus of the provider.
+type Aggregator struct {
+	kube            client.Client
+	log             logging.Logger
+	interval        time.Duration
+	clock           clock.PassiveClock
+	kinds           []managedKind
+	providerConfigs []providerConfigKind
+}
+
+// An Option configures an Aggregator.
+type Option func(*Aggregator)
+
+// WithInterval sets how often the summary is refreshed.
+func WithInterval(d time.Duration) Option {
+	return func(a *Aggregator) {
+		a.interval = d
+	}
+}
+
+// WithClock sets the clock the refresh time is read from.
+func WithClock(c clock.PassiveClock) Option {
+	return func(a *Aggregator) {
+		a.clock = c
+	}
+}
+
+// New returns an Aggregator that reads with the supplied client, usually
+// backed by the informer caches of the manager, and writes the ProviderStatus
+// with it.
+func New(kube client.Client, log logging.Logger, opts ...Option) *Aggregator {
+	a := &Aggregator{
+		kube:     kube,
+		log:      log,
+		interval: DefaultInterval,
+		clock:    clock.RealClock{},
+		kinds: []managedKind{
+			{userv1alpha1cluster.UserGroupKind, func() resource.ManagedList { return &userv1alpha1cluster.UserList{} }},
+			{userv1alpha1cluster.GroupGroupKind, func() resource.ManagedList { return &userv1alpha1cluster.GroupList{} }},
+			{userv1alpha1cluster.AccessKeyGroupKind, func() resource.ManagedList { return &userv1alpha1cluster.AccessKeyList{} }},
+			{userv1alpha1cluster.GroupQualityOfServiceLimitsGroupKind, func() resource.ManagedList { return &userv1alpha1cluster.GroupQualityOfServiceLimitsList{} }},
+			{userv1alpha1cluster.GroupRatingPlanGroupKind, func() resource.ManagedList { return &userv1alpha1cluster.GroupRatingPlanList{} }},
+			{userv1alpha1cluster.IAMUserGroupKind, func() resource.ManagedList { return &userv1alpha1cluster.IAMUserList{} }},
+			{userv1alpha1cluster.UserQualityOfServiceLimitsGroupKind, func() resource.ManagedList { return &userv1alpha1cluster.UserQualityOfServiceLimitsList{} }},
+			{userv1alpha1namespaced.UserGroupKind, func() resource.ManagedList { return &userv1alpha1namespaced.UserList{} }},
+			{userv1alpha1namespaced.GroupGroupKind, func() resource.ManagedList { return &userv1alpha1namespaced.GroupList{} }},
+			{userv1alpha1namespaced.AccessKeyGroupKind, func() resource.ManagedList { return &userv1alpha1namespaced.AccessKeyList{} }},
+			{userv1alpha1namespaced.GroupQualityOfServiceLimitsGroupKind, func() resource.ManagedList { return &userv1alpha1namespaced.GroupQualityOfServiceLimitsList{} }},
+			{userv1alpha1namespaced.GroupRatingPlanGroupKind, func() resource.ManagedList { return &userv1alpha1namespaced.GroupRatingPlanList{} }},
+			{userv1alpha1namespaced.IAMUserGroupKind, func() resource.ManagedList { return &userv1alpha1namespaced.IAMUserList{} }},
+			{userv1alpha1namespaced.UserQualityOfServiceLimitsGroupKind, func() resource.ManagedList { return &userv1alpha1namespaced.UserQualityOfServiceLimitsList{} }},
+		},
+		providerConfigs: []providerConfigKind{
+			{apisv1alpha1cluster.ProviderConfigGroupKind, func() client.ObjectList { return &apisv1alpha1cluster.ProviderConfigList{} }},
+			{apisv1alpha1namespaced.ProviderConfigGroupKind, func() client.ObjectList { return &apisv1alpha1namespaced.ProviderConfigList{} }},
+		},
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// Aggregate summarizes the managed resources and ProviderConfigs.
+func (a *Aggregator) Aggregate(ctx context.Context) (apisv1alpha1cluster.ProviderStatusStatus, error) {
+	status := apisv1alpha1cluster.ProviderStatusStatus{RefreshTime: &metav1.Time{Time: a.clock.Now()}}
+
+	errs := map[[2]string]*apisv1alpha1cluster.ErrorSummary{}
+	for _, k := range a.kinds {
+		l := k.newList()
+		if err := a.kube.List(ctx, l); err != nil {
+			return apisv1alpha1cluster.ProviderStatusStatus{}, errors.Wrapf(err, errList, k.groupKind)
+		}
+		summary := apisv1alpha1cluster.KindSummary{Kind: k.groupKind}
+		for _, mg := range l.GetItems() {
+			summary.Total++
+			if mg.GetCondition(xpv2.TypeReady).Status == corev1.ConditionTrue {
+				summary.Ready++
+			}
+			synced := mg.GetCondition(xpv2.TypeSynced)
+			switch synced.Status {
+			case corev1.ConditionTrue:
+				summary.Synced++
+			case corev1.ConditionFalse:
+				addError(errs, k.groupKind, synced)
+			}
+		}
+		status.Kinds = append(status.Kinds, summary)
+	}
+	status.Errors = topErrors(errs)
+
+	for _, k := range a.providerConfigs {
+		pcs, err := a.providerConfigHealth(ctx, k)
+		if err != nil {
+			return apisv1alpha1cluster.ProviderStatusStatus{}, err
+		}
+		status.ProviderConfigs = append(status.ProviderConfigs, pcs...)
+	}
+	return status, nil
+}
+
+func (a *Aggregator) providerConfigHealth(ctx context.Context, k providerConfigKind) ([]apisv1alpha1cluster.ProviderConfigHealth, error) {
+	l := k.newList()
+	if err := a.kube.List(ctx, l); err != nil {
+		return nil, errors.Wrapf(err, errList, k.groupKind)
+	}
+	items, err := kmeta.ExtractList(l)
+	if err != nil {
+		return nil, errors.Wrapf(err, errList, k.groupKind)
+	}
+
+	pcs := make([]apisv1alpha1cluster.ProviderConfigHealth, 0, len(items))
+	for _, item := range items {
+		pc, ok := item.(resource.ProviderConfig)
+		if !ok {
+			continue
+		}
+		c := pc.GetCondition(health.TypeHealthy)
+		h := apisv1alpha1cluster.ProviderConfigHealth{
+			Kind:      k.groupKind,
+			Namespace: pc.GetNamespace(),
+			Name:      pc.GetName(),
+			Healthy:   c.Status,
+			Message:   c.Message,
+		}
+		if !c.LastTransitionTime.IsZero() {
+			h.LastTransitionTime = &c.LastTransitionTime
+		}
+		pcs = append(pcs, h)
+	}
+	slices.SortFunc(pcs, func(a, b apisv1alpha1cluster.ProviderConfigHealth) int {
+		return cmp.Or(cmp.Compare(a.Namespace, b.Namespace), cmp.Compare(a.Name, b.Name))
+	})
+	return pcs, nil
+}
+
+// addError counts a Synced condition that is false by kind and reason,
+// keeping the message of the most recent.
+func addError(errs map[[2]string]*apisv1alpha1cluster.ErrorSummary, kind string, c xpv2.Condition) {
+	key := [2]string{kind, string(c.Reason)}
+	s, ok := errs[key]
+	if !ok {
+		s = &apisv1alpha1cluster.ErrorSummary{Kind: kind, Reason: string(c.Reason)}
+		errs[key] = s
+	}
+	s.Count++
+	if s.LastTransitionTime == nil || s.LastTransitionTime.Before(&c.LastTransitionTime) {
+		s.Message = c.Message
+		s.LastTransitionTime = c.LastTransitionTime.DeepCopy()
+	}
+}
+
+// topErrors returns the maxErrors most common error summaries, most common
+// first.
+func topErrors(errs map[[2]string]*apisv1alpha1cluster.ErrorSummary) []apisv1alpha1cluster.ErrorSummary {
+	summaries := make([]apisv1alpha1cluster.ErrorSummary, 0, len(errs))
+	for _, s := range errs {
+		summaries = append(summaries, *s)
+	}
+	slices.SortFunc(summaries, func(a, b apisv1alpha1cluster.ErrorSummary) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Kind, b.Kind), cmp.Compare(a.Reason, b.Reason))
+	})
+	if len(summaries) > maxErrors {
+		summaries = summaries[:maxErrors]
+	}
+	if len(summaries) == 0 {
+		return nil
+	}
+	return summaries
+}
+
+// Refresh aggregates the summary and writes it to the ProviderStatus,
+// creating it if missing.
+func (a *Aggregator) Refresh(ctx context.Context) error {
+	status, err := a.Aggregate(ctx)
+	if err != nil {
+		return err
+	}
+
+	ps := &apisv1alpha1cluster.ProviderStatus{}
+	err = a.kube.Get(ctx, types.NamespacedName{Name: apisv1alpha1cluster.ProviderStatusName}, ps)
+	switch {
+	case kerrors.IsNotFound(err):
+		ps = &apisv1alpha1cluster.ProviderStatus{ObjectMeta: metav1.ObjectMeta{Name: apisv1alpha1cluster.ProviderStatusName}}
+		if err := a.kube.Create(ctx, ps); err != nil {
+			return errors.Wrap(err, errCreate)
+		}
+	case err != nil:
+		return errors.Wrap(err, errGet)
+	}
+
+	ps.Status = status
+	return errors.Wrap(a.kube.Status().Update(ctx, ps), errUpdate)
+}
+
+// Start refreshes the summary every interval until the context is done,
+// logging rather than returning its errors so that a failed refresh does not
+// stop the manager. It implements manager.Runnable, and only runs on the
+// elected leader.
+func (a *Aggregator) Start(ctx context.Context) error {
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+	for {
+		if err := a.Refresh(ctx); err != nil {
+			a.log.Info("Cannot refresh the ProviderStatus", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package providerstatus
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/logging"
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clocktesting "k8s.io/utils/clock/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	apiscluster "github.com/statnett/provider-cloudian/apis/cluster"
+	userv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/user/v1alpha1"
+	apisv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/v1alpha1"
+	apisnamespaced "github.com/statnett/provider-cloudian/apis/namespaced"
+	userv1alpha1namespaced "github.com/statnett/provider-cloudian/apis/namespaced/user/v1alpha1"
+	apisv1alpha1namespaced "github.com/statnett/provider-cloudian/apis/namespaced/v1alpha1"
+	"github.com/statnett/provider-cloudian/internal/controller/common/health"
+)
+
+var now = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func scheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+	s := runtime.NewScheme()
+	for _, add := range []func(*runtime.Scheme) error{apiscluster.AddToScheme, apisnamespaced.AddToScheme} {
+		if err := add(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s
+}
+
+// failed returns a Synced condition that is false for `reason`, `ago` before
+// now.
+func failed(reason, message string, ago time.Duration) xpv2.Condition {
+	return xpv2.Condition{
+		Type:               xpv2.TypeSynced,
+		Status:             corev1.ConditionFalse,
+		Reason:             xpv2.ConditionReason(reason),
+		Message:            message,
+		LastTransitionTime: metav1.NewTime(now.Add(-ago)),
+	}
+}
+
+func user(name string, conditions ...xpv2.Condition) *userv1alpha1cluster.User {
+	u := &userv1alpha1cluster.User{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	u.SetConditions(conditions...)
+	return u
+}
+
+func newAggregator(t *testing.T, objs ...client.Object) (*Aggregator, client.Client) {
+	t.Helper()
+	kube := fake.NewClientBuilder().
+		WithScheme(scheme(t)).
+		WithObjects(objs...).
+		WithStatusSubresource(&apisv1alpha1cluster.ProviderStatus{}).
+		Build()
+	return New(kube, logging.NewNopLogger(), WithClock(clocktesting.NewFakePassiveClock(now))), kube
+}
+
+func TestAggregate(t *testing.T) {
+	reachable := &apisv1alpha1cluster.ProviderConfig{ObjectMeta: metav1.ObjectMeta{Name: "default"}}
+	reachable.SetConditions(health.Healthy())
+	unreachable := &apisv1alpha1namespaced.ProviderConfig{ObjectMeta: metav1.ObjectMeta{Namespace: "team", Name: "cloudian"}}
+	unreachable.SetConditions(health.Unhealthy(fmt.Errorf("connection refused")))
+	unchecked := &apisv1alpha1cluster.ProviderConfig{ObjectMeta: metav1.ObjectMeta{Name: "new"}}
+
+	group := &userv1alpha1namespaced.Group{ObjectMeta: metav1.ObjectMeta{Namespace: "team", Name: "qa"}}
+	group.SetConditions(xpv2.Available(), xpv2.ReconcileSuccess())
+
+	a, _ := newAggregator(t,
+		reachable, unreachable, unchecked, group,
+		user("alice", xpv2.Available(), xpv2.ReconcileSuccess()),
+		user("bob", failed("ReconcileError", "old error", time.Hour)),
+		user("carol", failed("ReconcileError", "new error", time.Minute)),
+		user("dave", failed("Throttled", "throttled", time.Minute)),
+		user("erin"),
+	)
+
+	got, err := a.Aggregate(context.Background())
+	if err != nil {
+		t.Fatalf("Aggregate(...): %v", err)
+	}
+
+	kinds := map[string]apisv1alpha1cluster.KindSummary{}
+	for _, k := range got.Kinds {
+		kinds[k.Kind] = k
+	}
+	if len(got.Kinds) != len(a.kinds) {
+		t.Errorf("Aggregate(...): want a summary of each of the %d kinds, got %d", len(a.kinds), len(got.Kinds))
+	}
+	wantKinds := map[string]apisv1alpha1cluster.KindSummary{
+		userv1alpha1cluster.UserGroupKind:     {Kind: userv1alpha1cluster.UserGroupKind, Total: 5, Ready: 1, Synced: 1},
+		userv1alpha1namespaced.GroupGroupKind: {Kind: userv1alpha1namespaced.GroupGroupKind, Total: 1, Ready: 1, Synced: 1},
+		userv1alpha1cluster.GroupGroupKind:    {Kind: userv1alpha1cluster.GroupGroupKind},
+	}
+	for kind, want := range wantKinds {
+		if diff := cmp.Diff(want, kinds[kind]); diff != "" {
+			t.Errorf("Aggregate(...): -want %s summary, +got:\n%s", kind, diff)
+		}
+	}
+
+	wantErrors := []apisv1alpha1cluster.ErrorSummary{
+		{Kind: userv1alpha1cluster.UserGroupKind, Reason: "ReconcileError", Count: 2, Message: "new error", LastTransitionTime: &metav1.Time{Time: now.Add(-time.Minute)}},
+		{Kind: userv1alpha1cluster.UserGroupKind, Reason: "Throttled", Count: 1, Message: "throttled", LastTransitionTime: &metav1.Time{Time: now.Add(-time.Minute)}},
+	}
+	if diff := cmp.Diff(wantErrors, got.Errors); diff != "" {
+		t.Errorf("Aggregate(...): want the errors by reason, most common first, -want, +got:\n%s", diff)
+	}
+
+	var healthy []string
+	for _, pc := range got.ProviderConfigs {
+		healthy = append(healthy, fmt.Sprintf("%s/%s=%s", pc.Namespace, pc.Name, pc.Healthy))
+	}
+	wantHealthy := []string{"/default=True", "/new=Unknown", "team/cloudian=False"}
+	if diff := cmp.Diff(wantHealthy, healthy); diff != "" {
+		t.Errorf("Aggregate(...): -want ProviderConfig health, +got:\n%s", diff)
+	}
+	if got.ProviderConfigs[2].Message != "connection refused" {
+		t.Errorf("Aggregate(...): want the message of an unhealthy ProviderConfig, got %q", got.ProviderConfigs[2].Message)
+	}
+}
+
+func TestTopErrors(t *testing.T) {
+	errs := map[[2]string]*apisv1alpha1cluster.ErrorSummary{}
+	for i := range maxErrors + 5 {
+		for range i + 1 {
+			addError(errs, "User", failed(fmt.Sprintf("Reason%02d", i), "", 0))
+		}
+	}
+	got := topErrors(errs)
+	if len(got) != maxErrors {
+		t.Fatalf("topErrors(...): want %d summaries, got %d", maxErrors, len(got))
+	}
+	if got[0].Reason != fmt.Sprintf("Reason%02d", maxErrors+4) || got[0].Count != maxErrors+5 {
+		t.Errorf("topErrors(...): want the most common reason first, got %s (%d)", got[0].Reason, got[0].Count)
+	}
+	if topErrors(map[[2]string]*apisv1alpha1cluster.ErrorSummary{}) != nil {
+		t.Error("topErrors(...): want no summaries without errors")
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	a, kube := newAggregator(t, user("alice", xpv2.Available()))
+	ctx := context.Background()
+
+	for i := range 2 {
+		if err := a.Refresh(ctx); err != nil {
+			t.Fatalf("refresh %d: Refresh(...): %v", i, err)
+		}
+		ps := &apisv1alpha1cluster.ProviderStatus{}
+		if err := kube.Get(ctx, types.NamespacedName{Name: apisv1alpha1cluster.ProviderStatusName}, ps); err != nil {
+			t.Fatalf("refresh %d: want the ProviderStatus created: %v", i, err)
+		}
+		if ps.Status.RefreshTime == nil || !ps.Status.RefreshTime.Time.Equal(now) {
+			t.Errorf("refresh %d: Refresh(...): want refresh time %s, got %v", i, now, ps.Status.RefreshTime)
+		}
+		if ps.Status.Kinds[0].Total != 1 {
+			t.Errorf("refresh %d: Refresh(...): want the Users counted, got %+v", i, ps.Status.Kinds[0])
+		}
+	}
+}
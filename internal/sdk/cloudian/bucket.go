@@ -0,0 +1,91 @@
+package cloudian
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// A Bucket is a bucket owned by a user, with its usage.
+type Bucket struct {
+	Name string
+	// CreationDate is zero when Cloudian reports none.
+	CreationDate time.Time
+	ObjectCount  int64
+	Bytes        int64
+}
+
+// bucketList is the list of buckets of a user, as listed by Cloudian.
+type bucketList struct {
+	UserID  string `json:"userId"`
+	Buckets []struct {
+		BucketName string `json:"bucketName"`
+		CreateTime string `json:"createTime"`
+	} `json:"buckets"`
+}
+
+// bucketUsage is the usage of a bucket, as reported by Cloudian.
+type bucketUsage struct {
+	BucketName  string `json:"bucketName"`
+	ByteCount   int64  `json:"byteCount"`
+	ObjectCount int64  `json:"objectCount"`
+}
+
+// ListUserBuckets lists the buckets owned by a user, with their usage. It
+// takes one request for the buckets, and one more for their usage when the
+// user has any.
+func (client Client) ListUserBuckets(ctx context.Context, guid GroupUserID) (_ []Bucket, err error) {
+	ctx, span := client.startSpan(ctx, "ListUserBuckets")
+	defer span.end(&err)
+
+	if err := validateGroupUserID(guid); err != nil {
+		return nil, err
+	}
+
+	var lists []bucketList
+	req := client.newRequest(ctx).
+		SetQueryParams(map[string]string{
+			paramGroupID: guid.GroupID,
+			"userId":     guid.UserID,
+		}).
+		SetResult(&lists)
+	if _, err := client.doJSON(req, resty.MethodGet, "/system/bucketlist", 200, 204); err != nil {
+		return nil, fmt.Errorf("GET list buckets failed: %w", err)
+	}
+
+	var buckets []Bucket
+	var names []string
+	for _, list := range lists {
+		if list.UserID != "" && list.UserID != guid.UserID {
+			continue
+		}
+		for _, b := range list.Buckets {
+			// Cloudian reports no creation date for some buckets.
+			created, _ := time.Parse(time.RFC3339, b.CreateTime)
+			buckets = append(buckets, Bucket{Name: b.BucketName, CreationDate: created})
+			names = append(names, b.BucketName)
+		}
+	}
+	if len(buckets) == 0 {
+		return nil, nil
+	}
+
+	var usages []bucketUsage
+	req = client.newRequest(ctx).
+		SetBody(names).
+		SetResult(&usages)
+	if _, err := client.doJSON(req, resty.MethodPost, "/usage/bucket", 200, 204); err != nil {
+		return nil, fmt.Errorf("POST bucket usage failed: %w", err)
+	}
+	byName := make(map[string]bucketUsage, len(usages))
+	for _, u := range usages {
+		byName[u.BucketName] = u
+	}
+	for i := range buckets {
+		buckets[i].ObjectCount = byName[buckets[i].Name].ObjectCount
+		buckets[i].Bytes = byName[buckets[i].Name].ByteCount
+	}
+	return buckets, nil
+}
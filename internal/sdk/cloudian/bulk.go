@@ -0,0 +1,123 @@
+package cloudian
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// DefaultConcurrency is how many requests DeleteGroupRecursive has in flight
+// at a time.
+const DefaultConcurrency = 8
+
+// A BulkError is the error of an operation on many users, which attempts all
+// of them even when some fail.
+type BulkError struct {
+	// Failed are the errors of the users the operation failed for.
+	Failed map[GroupUserID]error
+}
+
+// failedIDs returns the IDs of the failed users in a stable order.
+func (e *BulkError) failedIDs() []GroupUserID {
+	ids := make([]GroupUserID, 0, len(e.Failed))
+	for guid := range e.Failed {
+		ids = append(ids, guid)
+	}
+	slices.SortFunc(ids, func(a, b GroupUserID) int {
+		return strings.Compare(a.GroupID+"/"+a.UserID, b.GroupID+"/"+b.UserID)
+	})
+	return ids
+}
+
+func (e *BulkError) Error() string {
+	msgs := make([]string, 0, len(e.Failed))
+	for _, guid := range e.failedIDs() {
+		msgs = append(msgs, fmt.Sprintf("user %s/%s: %v", guid.GroupID, guid.UserID, e.Failed[guid]))
+	}
+	return fmt.Sprintf("%d users failed: %s", len(e.Failed), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the errors of the failed users, so that errors.Is and
+// errors.As find them.
+func (e *BulkError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failed))
+	for _, guid := range e.failedIDs() {
+		errs = append(errs, e.Failed[guid])
+	}
+	return errs
+}
+
+// CreateUsers creates many users, with at most `concurrency` requests in
+// flight. All users are attempted, returning a BulkError of those that could
+// not be created. Users not yet attempted when the context is cancelled fail
+// with the error of the context. The requests are Background work.
+func (client Client) CreateUsers(ctx context.Context, users []User, concurrency int) error {
+	guids := make([]GroupUserID, len(users))
+	for i, user := range users {
+		guids[i] = user.GroupUserID
+	}
+	return forEachUser(ctx, guids, concurrency, func(ctx context.Context, i int) error {
+		_, err := client.CreateUser(ctx, users[i])
+		return err
+	})
+}
+
+// DeleteUsers deletes many users, with at most `concurrency` requests in
+// flight. Users that do not exist are considered deleted. All users are
+// attempted, returning a BulkError of those that could not be deleted. Users
+// not yet attempted when the context is cancelled fail with the error of the
+// context. The requests are Background work.
+func (client Client) DeleteUsers(ctx context.Context, guids []GroupUserID, concurrency int) error {
+	return forEachUser(ctx, guids, concurrency, func(ctx context.Context, i int) error {
+		if err := client.DeleteUser(ctx, guids[i]); err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+		return nil
+	})
+}
+
+// forEachUser calls `fn` with the index of every user, with at most
+// `concurrency` calls in flight. It returns a BulkError of the users `fn`
+// failed for, or nil if none failed. A concurrency below 1 is taken as 1.
+// The calls are Background work.
+func forEachUser(ctx context.Context, guids []GroupUserID, concurrency int, fn func(context.Context, int) error) error {
+	ctx = Background(ctx)
+	var mu sync.Mutex
+	bulkErr := &BulkError{}
+	fail := func(guid GroupUserID, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if bulkErr.Failed == nil {
+			bulkErr.Failed = map[GroupUserID]error{}
+		}
+		bulkErr.Failed[guid] = err
+	}
+
+	// The calls never return errors to the group, so that a failed user does
+	// not cancel the others.
+	var g errgroup.Group
+	g.SetLimit(max(concurrency, 1))
+	for i, guid := range guids {
+		g.Go(func() error {
+			if err := ctx.Err(); err != nil {
+				fail(guid, err)
+				return nil
+			}
+			if err := fn(ctx, i); err != nil {
+				fail(guid, err)
+			}
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	if len(bulkErr.Failed) > 0 {
+		return bulkErr
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package cloudian
+
+import (
+	"sync"
+	"time"
+
+	"k8s.io/utils/clock"
+)
+
+// groupCache is a read-through cache of groups, safe for concurrent use. A nil
+// *groupCache caches nothing.
+type groupCache struct {
+	ttl   time.Duration
+	clock clock.PassiveClock
+
+	mu      sync.Mutex
+	entries map[string]groupCacheEntry
+	// generation is incremented by every invalidation, so that a fetch
+	// started before an invalidation does not repopulate the cache.
+	generation uint64
+}
+
+type groupCacheEntry struct {
+	group   Group
+	expires time.Time
+}
+
+func newGroupCache(ttl time.Duration) *groupCache {
+	return &groupCache{ttl: ttl, clock: clock.RealClock{}, entries: map[string]groupCacheEntry{}}
+}
+
+// get returns the cached group, if any. On a miss it returns the generation
+// to pass to put once the group has been fetched.
+func (c *groupCache) get(groupID string) (*Group, uint64, bool) {
+	if c == nil {
+		return nil, 0, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[groupID]
+	if !ok || !c.clock.Now().Before(e.expires) {
+		delete(c.entries, groupID)
+		return nil, c.generation, false
+	}
+	group := e.group
+	return &group, c.generation, true
+}
+
+func (c *groupCache) put(groupID string, generation uint64, group Group) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+	c.entries[groupID] = groupCacheEntry{group: group, expires: c.clock.Now().Add(c.ttl)}
+}
+
+func (c *groupCache) invalidate(groupID string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, groupID)
+	c.generation++
+}
@@ -0,0 +1,118 @@
+package cloudian
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// minEndpointRestrictionsVersion is the first HyperStore version that
+// restricts the S3 endpoints of groups.
+var minEndpointRestrictionsVersion = [2]int{7, 2}
+
+// Capabilities are the optional features supported by the Cloudian admin API
+// a client is connected to. Callers should not use features reported as
+// unsupported.
+type Capabilities struct {
+	// Version is the HyperStore version reported by the admin API.
+	Version string
+	// CredentialStatus is whether credentials can be activated and
+	// deactivated.
+	CredentialStatus bool
+	// EndpointRestrictions is whether the S3 endpoints of groups can be
+	// restricted.
+	EndpointRestrictions bool
+	// PolicyAssignment is whether bucket protection policies can be
+	// assigned.
+	PolicyAssignment bool
+}
+
+// capabilitiesCache holds the capabilities of a client once they have been
+// probed successfully.
+type capabilitiesCache struct {
+	mu           sync.Mutex
+	capabilities *Capabilities
+}
+
+// Capabilities probes the version and optional endpoints of the admin API.
+// The result is cached for the lifetime of the client, unless probing fails.
+func (client Client) Capabilities(ctx context.Context) (_ Capabilities, err error) {
+	ctx, span := client.startSpan(ctx, "Capabilities")
+	defer span.end(&err)
+
+	client.capabilities.mu.Lock()
+	defer client.capabilities.mu.Unlock()
+
+	if c := client.capabilities.capabilities; c != nil {
+		return *c, nil
+	}
+
+	version, err := client.Version(ctx)
+	if err != nil {
+		return Capabilities{}, err
+	}
+	c := Capabilities{
+		Version:              version,
+		EndpointRestrictions: versionAtLeast(version, minEndpointRestrictionsVersion),
+	}
+	if c.CredentialStatus, err = client.probe(ctx, "/user/credentials/status"); err != nil {
+		return Capabilities{}, err
+	}
+	if c.PolicyAssignment, err = client.probe(ctx, "/bppolicy/listpolicy"); err != nil {
+		return Capabilities{}, err
+	}
+
+	client.capabilities.capabilities = &c
+	return c, nil
+}
+
+// probe returns whether the admin API has an endpoint at `path`, without
+// changing anything. Endpoints that exist reject the probe with a client error
+// other than 404 when they do not accept GET requests without parameters.
+func (client Client) probe(ctx context.Context, path string) (bool, error) {
+	req := client.client.R().
+		SetContext(ctx)
+	_, err := client.doJSON(req, resty.MethodGet, path, 200, 204)
+
+	var statusErr *StatusError
+	switch {
+	case err == nil:
+		return true, nil
+	case !errors.As(err, &statusErr):
+		return false, err
+	case statusErr.StatusCode == http.StatusNotFound:
+		return false, nil
+	case statusErr.StatusCode == http.StatusBadRequest, statusErr.StatusCode == http.StatusMethodNotAllowed:
+		return true, nil
+	default:
+		return false, err
+	}
+}
+
+// versionAtLeast returns whether the major and minor version of a HyperStore
+// version such as "8.1.2" are at least `minimum`. Versions that cannot be
+// parsed are assumed to be older.
+func versionAtLeast(version string, minimum [2]int) bool {
+	fields := strings.Fields(version)
+	if len(fields) == 0 {
+		return false
+	}
+	parts := strings.SplitN(fields[0], ".", 3)
+	if len(parts) < 2 {
+		return false
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return false
+	}
+	minor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return false
+	}
+	return major > minimum[0] || major == minimum[0] && minor >= minimum[1]
+}
@@ -0,0 +1,216 @@
+// Package cloudiantest serves the Cloudian admin API in tests, with scripted
+// failures and latency to exercise how clients handle them end-to-end. A
+// FakeServer keeps groups, users, credentials, quality of service limits and
+// the S3 endpoints of the cluster in memory, so that clients can be tested
+// without a Cloudian installation.
+package cloudiantest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"k8s.io/utils/clock"
+)
+
+// A Step is how a scripted path answers requests, before the server falls
+// back to its handler.
+type Step struct {
+	status  int
+	body    string
+	header  http.Header
+	latency time.Duration
+	times   int
+	lasting time.Duration
+	handled bool
+}
+
+// Respond returns a Step answering the next request with the supplied status
+// code and body.
+func Respond(status int, body string) Step {
+	return Step{status: status, body: body, times: 1}
+}
+
+// Times makes the step answer the next `n` requests.
+func (s Step) Times(n int) Step {
+	s.times = n
+	return s
+}
+
+// For makes the step answer every request until `d` has passed since it
+// answered the first one, however many requests that is.
+func (s Step) For(d time.Duration) Step {
+	s.lasting = d
+	return s
+}
+
+// WithHeader sets a header of the answers of the step, such as Retry-After.
+func (s Step) WithHeader(key, value string) Step {
+	s.header = s.header.Clone()
+	if s.header == nil {
+		s.header = http.Header{}
+	}
+	s.header.Set(key, value)
+	return s
+}
+
+// Handled makes the handler handle the requests the step answers, and
+// discards its answers, as when Cloudian takes effect but its answer is lost.
+func (s Step) Handled() Step {
+	s.handled = true
+	return s
+}
+
+// After delays the answers of the step by `d`.
+func (s Step) After(d time.Duration) Step {
+	s.latency = d
+	return s
+}
+
+// script is the progress of a scripted path through its steps.
+type script struct {
+	steps    []Step
+	answered int
+	started  time.Time
+}
+
+// next returns the step answering a request made at `now`, if any remain.
+func (sc *script) next(now time.Time) (Step, bool) {
+	for len(sc.steps) > 0 {
+		step := &sc.steps[0]
+		if step.lasting > 0 {
+			if sc.started.IsZero() {
+				sc.started = now
+			}
+			if now.Sub(sc.started) < step.lasting {
+				return *step, true
+			}
+		} else if sc.answered < step.times {
+			sc.answered++
+			return *step, true
+		}
+		sc.steps, sc.answered, sc.started = sc.steps[1:], 0, time.Time{}
+	}
+	return Step{}, false
+}
+
+// A Server is an httptest.Server answering the admin API with a handler,
+// unless the request path is scripted.
+type Server struct {
+	*httptest.Server
+
+	handler http.Handler
+
+	mu        sync.Mutex
+	clock     clock.Clock
+	latency   time.Duration
+	scripts   map[string]*script
+	requests  map[string]int
+	mutations []string
+}
+
+// NewServer starts a Server falling back to the supplied handler. A nil
+// handler answers 204 No Content, which Cloudian answers for most objects
+// that do not exist. The server is closed when the test ends.
+func NewServer(tb testing.TB, handler http.Handler) *Server {
+	tb.Helper()
+	if handler == nil {
+		handler = http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+	s := &Server{handler: handler, clock: clock.RealClock{}, scripts: map[string]*script{}, requests: map[string]int{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	tb.Cleanup(s.Close)
+	return s
+}
+
+// Script makes requests to a path be answered by the supplied steps in
+// order, replacing any steps left of a previous script. The handler answers
+// once all steps are done.
+func (s *Server) Script(path string, steps ...Step) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scripts[path] = &script{steps: steps}
+}
+
+// SetClock sets the time source of the server, so that tests control how long
+// steps last and answers are delayed.
+func (s *Server) SetClock(c clock.Clock) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.clock = c
+}
+
+// SetLatency delays the answer to every request by `d`, in addition to the
+// latency of scripted steps.
+func (s *Server) SetLatency(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.latency = d
+}
+
+// Requests returns the number of requests received for a path.
+func (s *Server) Requests(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[path]
+}
+
+// Mutations returns the method and path of every request received that is not
+// a read, in the order they were received, such as "DELETE /user".
+func (s *Server) Mutations() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.mutations)
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests[r.URL.Path]++
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		s.mutations = append(s.mutations, r.Method+" "+r.URL.Path)
+	}
+	latency, clk := s.latency, s.clock
+	var step Step
+	var scripted bool
+	if sc, ok := s.scripts[r.URL.Path]; ok {
+		step, scripted = sc.next(clk.Now())
+	}
+	s.mu.Unlock()
+
+	if !sleep(r, clk, latency+step.latency) {
+		return
+	}
+	if !scripted {
+		s.handler.ServeHTTP(w, r)
+		return
+	}
+	if step.handled {
+		s.handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	for key, values := range step.header {
+		w.Header()[key] = values
+	}
+	w.WriteHeader(step.status)
+	_, _ = w.Write([]byte(step.body))
+}
+
+// sleep waits for `d` on the clock, or returns false if the request is
+// cancelled first.
+func sleep(r *http.Request, clk clock.Clock, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+	t := clk.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-r.Context().Done():
+		return false
+	case <-t.C():
+		return true
+	}
+}
@@ -0,0 +1,154 @@
+package cloudiantest
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	testingclock "k8s.io/utils/clock/testing"
+)
+
+// get returns the status code and body of a GET request, or 0 and the error
+// if it failed.
+func get(ctx context.Context, url string) (int, string) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err.Error()
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err.Error()
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestScript(t *testing.T) {
+	s := NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	s.Script("/group",
+		Respond(http.StatusServiceUnavailable, "").Times(2),
+		Respond(http.StatusInternalServerError, "boom"),
+	)
+
+	want := []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusInternalServerError, http.StatusOK, http.StatusOK}
+	for i, wantStatus := range want {
+		if got, body := get(context.Background(), s.URL+"/group"); got != wantStatus {
+			t.Errorf("request %d: want status %d, got %d %q", i, wantStatus, got, body)
+		}
+	}
+	if got, _ := get(context.Background(), s.URL+"/user"); got != http.StatusOK {
+		t.Errorf("unscripted path: want status %d, got %d", http.StatusOK, got)
+	}
+	if got := s.Requests("/group"); got != len(want) {
+		t.Errorf("Requests(/group): want %d, got %d", len(want), got)
+	}
+}
+
+func TestHandled(t *testing.T) {
+	handled := 0
+	s := NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		handled++
+		_, _ = w.Write([]byte("ok"))
+	}))
+	s.Script("/user/credentials", Respond(http.StatusBadGateway, "lost").Handled())
+
+	if got, body := get(context.Background(), s.URL+"/user/credentials"); got != http.StatusBadGateway || body != "lost" {
+		t.Errorf("handled request: want status %d and the scripted body, got %d %q", http.StatusBadGateway, got, body)
+	}
+	if handled != 1 {
+		t.Errorf("handled request: want it handled once, got %d", handled)
+	}
+}
+
+func TestMutations(t *testing.T) {
+	s := NewServer(t, nil)
+	for _, method := range []string{http.MethodGet, http.MethodPut, http.MethodHead, http.MethodDelete} {
+		req, err := http.NewRequestWithContext(context.Background(), method, s.URL+"/user", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+	}
+	if diff := cmp.Diff([]string{"PUT /user", "DELETE /user"}, s.Mutations()); diff != "" {
+		t.Errorf("Mutations(): -want, +got:\n%s", diff)
+	}
+}
+
+func TestScriptFor(t *testing.T) {
+	clock := testingclock.NewFakeClock(time.Now())
+	s := NewServer(t, nil)
+	s.SetClock(clock)
+	s.Script("/group", Respond(http.StatusServiceUnavailable, "maintenance").For(time.Hour))
+
+	for i := range 3 {
+		if got, body := get(context.Background(), s.URL+"/group"); got != http.StatusServiceUnavailable || body != "maintenance" {
+			t.Errorf("request %d: want maintenance, got %d %q", i, got, body)
+		}
+		clock.Step(10 * time.Minute)
+	}
+	clock.Step(30 * time.Minute)
+	if got, _ := get(context.Background(), s.URL+"/group"); got != http.StatusNoContent {
+		t.Errorf("after the step: want status %d, got %d", http.StatusNoContent, got)
+	}
+}
+
+func TestScriptReplaced(t *testing.T) {
+	s := NewServer(t, nil)
+	s.Script("/group", Respond(http.StatusServiceUnavailable, "").Times(5))
+	get(context.Background(), s.URL+"/group")
+	s.Script("/group")
+
+	if got, _ := get(context.Background(), s.URL+"/group"); got != http.StatusNoContent {
+		t.Errorf("replaced script: want status %d, got %d", http.StatusNoContent, got)
+	}
+}
+
+func TestLatency(t *testing.T) {
+	cases := map[string]struct {
+		latency time.Duration
+		step    time.Duration
+	}{
+		"Server": {latency: 50 * time.Millisecond},
+		"Step":   {step: 50 * time.Millisecond},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			s := NewServer(t, nil)
+			s.SetLatency(tc.latency)
+			s.Script("/group", Respond(http.StatusOK, "").After(tc.step))
+
+			start := time.Now()
+			get(context.Background(), s.URL+"/group")
+			if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+				t.Errorf("want the answer delayed by 50ms, got it after %s", elapsed)
+			}
+		})
+	}
+}
+
+func TestLatencyCancelled(t *testing.T) {
+	s := NewServer(t, nil)
+	s.SetLatency(5 * time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if got, _ := get(ctx, s.URL+"/group"); got != 0 {
+		t.Errorf("want the request cancelled, got status %d", got)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("want the delayed answer to stop when cancelled, got it after %s", elapsed)
+	}
+}
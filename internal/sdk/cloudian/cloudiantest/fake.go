@@ -0,0 +1,502 @@
+package cloudiantest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// A User is a user of a FakeServer, in the JSON form of the admin API.
+type User struct {
+	GroupID     string `json:"groupId"`
+	UserID      string `json:"userId"`
+	UserType    string `json:"userType"`
+	CanonicalID string `json:"canonicalUserId,omitempty"`
+	Address2    string `json:"address2,omitempty"`
+	Status      string `json:"userStatus,omitempty"`
+}
+
+// A Bucket is a bucket of a user of a FakeServer, with its usage.
+type Bucket struct {
+	Name string
+	// CreateTime is the creation date as reported by Cloudian.
+	CreateTime  string
+	ObjectCount int64
+	ByteCount   int64
+}
+
+// RegionS3Endpoints are the S3 endpoints of a region listed by the system
+// configuration of a FakeServer, in the JSON form of the admin API.
+type RegionS3Endpoints struct {
+	Region  string   `json:"regionName"`
+	HTTP    []string `json:"s3EndpointsHTTP"`
+	HTTPS   []string `json:"s3EndpointsHTTPS"`
+	Website []string `json:"s3WebSiteEndpoints"`
+}
+
+// credentials are a set of credentials of a user of a FakeServer.
+type credentials struct {
+	AccessKey string `json:"accessKey"`
+	SecretKey string `json:"secretKey"`
+
+	groupID, userID string
+}
+
+// A FakeServer is a Server keeping groups, users and their credentials in
+// memory, answering like Cloudian does: objects that do not exist are 204 No
+// Content, lists return one item more than the limit when there are more
+// pages, and PUT creates objects while POST updates them.
+type FakeServer struct {
+	*Server
+
+	mu          sync.Mutex
+	groups      map[string]json.RawMessage
+	users       map[string]User
+	credentials map[string]credentials
+	buckets     map[string][]Bucket
+	qos         map[string]map[string]int64
+	s3Endpoints []RegionS3Endpoints
+	failures    int
+	serial      int
+	maxCreds    int
+}
+
+// NewFakeServer starts an empty FakeServer. The server is closed when the
+// test ends.
+func NewFakeServer(tb testing.TB) *FakeServer {
+	tb.Helper()
+	f := &FakeServer{
+		groups:      map[string]json.RawMessage{},
+		users:       map[string]User{},
+		credentials: map[string]credentials{},
+		buckets:     map[string][]Bucket{},
+		qos:         map[string]map[string]int64{},
+	}
+	f.Server = NewServer(tb, http.HandlerFunc(f.serve))
+	return f
+}
+
+// FailNext makes the next `n` requests fail with 500 Internal Server Error,
+// whatever their path.
+func (f *FakeServer) FailNext(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures = n
+}
+
+// AddGroup adds an active group, or replaces the group with the same ID.
+func (f *FakeServer) AddGroup(groupID string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.groups[groupID], _ = json.Marshal(map[string]string{"groupId": groupID, "active": "true"})
+}
+
+// HasGroup returns whether a group exists.
+func (f *FakeServer) HasGroup(groupID string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, ok := f.groups[groupID]
+	return ok
+}
+
+// AddUser adds a user without credentials, or replaces the user with the
+// same ID.
+func (f *FakeServer) AddUser(user User) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.users[userKey(user.GroupID, user.UserID)] = user
+}
+
+// User returns a user, if it exists.
+func (f *FakeServer) User(groupID, userID string) (User, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	user, ok := f.users[userKey(groupID, userID)]
+	return user, ok
+}
+
+// AddCredentials adds a set of credentials to a user.
+func (f *FakeServer) AddCredentials(groupID, userID, accessKey, secretKey string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.credentials[accessKey] = credentials{AccessKey: accessKey, SecretKey: secretKey, groupID: groupID, userID: userID}
+}
+
+// SetMaxCredentials makes the server refuse to create credentials for users
+// that already have `n`, like Cloudian does. Zero allows any number.
+func (f *FakeServer) SetMaxCredentials(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.maxCreds = n
+}
+
+// AccessKeys returns the access keys of a user, sorted.
+func (f *FakeServer) AccessKeys(groupID, userID string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var keys []string
+	for _, creds := range f.userCredentials(groupID, userID) {
+		keys = append(keys, creds.AccessKey)
+	}
+	return keys
+}
+
+// AddBucket adds a bucket to a user.
+func (f *FakeServer) AddBucket(groupID, userID string, bucket Bucket) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	key := userKey(groupID, userID)
+	f.buckets[key] = append(f.buckets[key], bucket)
+}
+
+// SetS3Endpoints sets the S3 endpoints listed by the system configuration.
+// Until set, the system configuration lists none.
+func (f *FakeServer) SetS3Endpoints(regions ...RegionS3Endpoints) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.s3Endpoints = regions
+}
+
+func (f *FakeServer) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.failures > 0 {
+		f.failures--
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	q := r.URL.Query()
+	switch r.Method + " " + r.URL.Path {
+	case "GET /group":
+		writeRaw(w, f.groups[q.Get("groupId")])
+	case "PUT /group", "POST /group":
+		var group struct {
+			GroupID string `json:"groupId"`
+		}
+		body, ok := decode(w, r, &group)
+		if !ok {
+			return
+		}
+		_, exists := f.groups[group.GroupID]
+		if status := putOrPost(r.Method, exists); status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		f.groups[group.GroupID] = body
+		writeRaw(w, body)
+	case "DELETE /group":
+		if _, ok := f.groups[q.Get("groupId")]; !ok {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		for _, user := range f.users {
+			if user.GroupID == q.Get("groupId") {
+				w.WriteHeader(http.StatusConflict)
+				_, _ = w.Write([]byte("Group is not empty, delete its users first"))
+				return
+			}
+		}
+		delete(f.groups, q.Get("groupId"))
+	case "GET /group/list":
+		var ids []string
+		for id := range f.groups {
+			ids = append(ids, id)
+		}
+		var page []json.RawMessage
+		for _, id := range paginate(ids, q) {
+			page = append(page, f.groups[id])
+		}
+		writeList(w, page)
+	case "GET /user":
+		user, ok := f.users[userKey(q.Get("groupId"), q.Get("userId"))]
+		if !ok {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeJSON(w, user)
+	case "PUT /user", "POST /user":
+		var user User
+		if _, ok := decode(w, r, &user); !ok {
+			return
+		}
+		key := userKey(user.GroupID, user.UserID)
+		existing, exists := f.users[key]
+		if status := putOrPost(r.Method, exists); status != http.StatusOK {
+			w.WriteHeader(status)
+			return
+		}
+		if exists {
+			// The canonical ID is assigned by Cloudian, and never changes.
+			user.CanonicalID = existing.CanonicalID
+		} else {
+			// Cloudian creates a user with a set of credentials.
+			f.serial++
+			user.CanonicalID = fmt.Sprintf("%032x", f.serial)
+			f.newCredentials(user.GroupID, user.UserID)
+		}
+		f.users[key] = user
+		writeJSON(w, user)
+	case "DELETE /user":
+		key := userKey(q.Get("groupId"), q.Get("userId"))
+		if _, ok := f.users[key]; !ok {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		delete(f.users, key)
+		for _, creds := range f.userCredentials(q.Get("groupId"), q.Get("userId")) {
+			delete(f.credentials, creds.AccessKey)
+		}
+	case "GET /user/list":
+		var ids []string
+		for _, user := range f.users {
+			if user.GroupID == q.Get("groupId") {
+				ids = append(ids, user.UserID)
+			}
+		}
+		var page []User
+		for _, id := range paginate(ids, q) {
+			page = append(page, f.users[userKey(q.Get("groupId"), id)])
+		}
+		writeList(w, page)
+	case "GET /user/credentials":
+		creds, ok := f.credentials[q.Get("accessKey")]
+		if !ok {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeJSON(w, creds)
+	case "PUT /user/credentials":
+		if _, ok := f.users[userKey(q.Get("groupId"), q.Get("userId"))]; !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if f.atMaxCredentials(q.Get("groupId"), q.Get("userId")) {
+			writeMaxCredentials(w)
+			return
+		}
+		writeJSON(w, f.newCredentials(q.Get("groupId"), q.Get("userId")))
+	case "POST /user/credentials":
+		if _, ok := f.users[userKey(q.Get("groupId"), q.Get("userId"))]; !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		creds := credentials{AccessKey: q.Get("accessKey"), SecretKey: q.Get("secretKey"), groupID: q.Get("groupId"), userID: q.Get("userId")}
+		// Retrying with the access key of the user replaces its secret key.
+		if existing, ok := f.credentials[creds.AccessKey]; ok && (existing.groupID != creds.groupID || existing.userID != creds.userID) {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		if _, ok := f.credentials[creds.AccessKey]; !ok && f.atMaxCredentials(creds.groupID, creds.userID) {
+			writeMaxCredentials(w)
+			return
+		}
+		f.credentials[creds.AccessKey] = creds
+	case "DELETE /user/credentials":
+		if _, ok := f.credentials[q.Get("accessKey")]; !ok {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		delete(f.credentials, q.Get("accessKey"))
+	case "GET /user/credentials/list":
+		writeList(w, f.userCredentials(q.Get("groupId"), q.Get("userId")))
+	case "GET /system/bucketlist":
+		type bucket struct {
+			BucketName string `json:"bucketName"`
+			CreateTime string `json:"createTime,omitempty"`
+		}
+		var buckets []bucket
+		for _, b := range f.buckets[userKey(q.Get("groupId"), q.Get("userId"))] {
+			buckets = append(buckets, bucket{BucketName: b.Name, CreateTime: b.CreateTime})
+		}
+		if len(buckets) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeJSON(w, []map[string]any{{"userId": q.Get("userId"), "buckets": buckets}})
+	case "GET /system/s3endpoints":
+		writeList(w, f.s3Endpoints)
+	case "POST /usage/bucket":
+		var names []string
+		if _, ok := decode(w, r, &names); !ok {
+			return
+		}
+		type usage struct {
+			BucketName  string `json:"bucketName"`
+			ByteCount   int64  `json:"byteCount"`
+			ObjectCount int64  `json:"objectCount"`
+		}
+		var usages []usage
+		for _, buckets := range f.buckets {
+			for _, b := range buckets {
+				if slices.Contains(names, b.Name) {
+					usages = append(usages, usage{BucketName: b.Name, ByteCount: b.ByteCount, ObjectCount: b.ObjectCount})
+				}
+			}
+		}
+		writeList(w, usages)
+	case "GET /qos/limits":
+		limits := f.qos[qosKey(q)]
+		type limit struct {
+			Type  string `json:"type"`
+			Value int64  `json:"value"`
+		}
+		list := []limit{}
+		for param, typ := range qosLimitTypes {
+			value, ok := limits[param]
+			if !ok {
+				value = -1
+			}
+			list = append(list, limit{Type: typ, Value: value})
+		}
+		writeJSON(w, map[string]any{"qosLimitList": list})
+	case "POST /qos/limits":
+		limits := map[string]int64{}
+		for param := range qosLimitTypes {
+			value, err := strconv.ParseInt(q.Get(param), 10, 64)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			limits[param] = value
+		}
+		f.qos[qosKey(q)] = limits
+	case "DELETE /qos/limits":
+		delete(f.qos, qosKey(q))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// qosLimitTypes are the types Cloudian reports quality of service limits
+// as, by the query parameter they are set with.
+var qosLimitTypes = map[string]string{
+	"hlStorageQuotaKBytes": "STORAGE_QUOTA_KBYTES_LH",
+	"wlStorageQuotaKBytes": "STORAGE_QUOTA_KBYTES_LW",
+	"hlStorageQuotaCount":  "STORAGE_QUOTA_COUNT_LH",
+	"wlStorageQuotaCount":  "STORAGE_QUOTA_COUNT_LW",
+	"hlRequestRate":        "REQUEST_RATE_LH",
+	"wlRequestRate":        "REQUEST_RATE_LW",
+	"hlDataKBytesIn":       "DATAKBYTES_IN_LH",
+	"wlDataKBytesIn":       "DATAKBYTES_IN_LW",
+	"hlDataKBytesOut":      "DATAKBYTES_OUT_LH",
+	"wlDataKBytesOut":      "DATAKBYTES_OUT_LW",
+}
+
+// qosKey identifies the quality of service limits of a request by group,
+// user and region.
+func qosKey(q url.Values) string {
+	return q.Get("groupId") + "/" + q.Get("userId") + "/" + q.Get("region")
+}
+
+func (f *FakeServer) newCredentials(groupID, userID string) credentials {
+	f.serial++
+	creds := credentials{
+		AccessKey: fmt.Sprintf("%020x", f.serial),
+		SecretKey: fmt.Sprintf("secret-%d", f.serial),
+		groupID:   groupID,
+		userID:    userID,
+	}
+	f.credentials[creds.AccessKey] = creds
+	return creds
+}
+
+func (f *FakeServer) userCredentials(groupID, userID string) []credentials {
+	var found []credentials
+	for _, creds := range f.credentials {
+		if creds.groupID == groupID && creds.userID == userID {
+			found = append(found, creds)
+		}
+	}
+	slices.SortFunc(found, func(a, b credentials) int { return strings.Compare(a.AccessKey, b.AccessKey) })
+	return found
+}
+
+func (f *FakeServer) atMaxCredentials(groupID, userID string) bool {
+	return f.maxCreds > 0 && len(f.userCredentials(groupID, userID)) >= f.maxCreds
+}
+
+func writeMaxCredentials(w http.ResponseWriter) {
+	w.WriteHeader(http.StatusBadRequest)
+	_, _ = w.Write([]byte("Maximum number of credentials reached for user"))
+}
+
+func userKey(groupID, userID string) string {
+	return groupID + "/" + userID
+}
+
+// putOrPost returns how Cloudian answers creating (PUT) or updating (POST)
+// an object that does or does not exist.
+func putOrPost(method string, exists bool) int {
+	switch {
+	case method == http.MethodPut && exists:
+		return http.StatusConflict
+	case method == http.MethodPost && !exists:
+		return http.StatusBadRequest
+	default:
+		return http.StatusOK
+	}
+}
+
+// paginate returns the page of the sorted IDs matching the `prefix`, `offset`
+// and `limit` query parameters. Like Cloudian, the page starts at the offset
+// and has one ID more than the limit when there are more pages.
+func paginate(ids []string, q url.Values) []string {
+	slices.Sort(ids)
+	var page []string
+	for _, id := range ids {
+		if strings.HasPrefix(id, q.Get("prefix")) && id >= q.Get("offset") {
+			page = append(page, id)
+		}
+	}
+	if limit, err := strconv.Atoi(q.Get("limit")); err == nil && len(page) > limit+1 {
+		page = page[:limit+1]
+	}
+	return page
+}
+
+// decode decodes the body of a request into `v`, answering 400 Bad Request
+// if it cannot. It returns the body, and whether it was decoded.
+func decode(w http.ResponseWriter, r *http.Request, v any) (json.RawMessage, bool) {
+	var body json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return nil, false
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return nil, false
+	}
+	return body, true
+}
+
+// writeRaw writes an object, or 204 No Content if it does not exist.
+func writeRaw(w http.ResponseWriter, body json.RawMessage) {
+	if body == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(body)
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeList writes a list, or 204 No Content if it is empty.
+func writeList[T any](w http.ResponseWriter, items []T) {
+	if len(items) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeJSON(w, items)
+}
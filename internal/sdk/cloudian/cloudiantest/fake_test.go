@@ -0,0 +1,84 @@
+package cloudiantest
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// do returns the status code and body of a request, or 0 and the error if it
+// failed.
+func do(method, url, body string) (int, string) {
+	req, err := http.NewRequestWithContext(context.Background(), method, url, strings.NewReader(body))
+	if err != nil {
+		return 0, err.Error()
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err.Error()
+	}
+	defer func() { _ = resp.Body.Close() }()
+	b, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, strings.TrimSpace(string(b))
+}
+
+func TestFakeServer(t *testing.T) {
+	f := NewFakeServer(t)
+	f.AddGroup("QA")
+	f.AddGroup("Empty")
+	for _, id := range []string{"c", "a", "b"} {
+		f.AddUser(User{GroupID: "QA", UserID: id})
+	}
+
+	cases := []struct {
+		reason     string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantBody   string
+	}{
+		{reason: "Missing objects are 204 No Content", method: http.MethodGet, path: "/user?groupId=QA&userId=x", wantStatus: http.StatusNoContent},
+		{reason: "Updating a missing object is refused", method: http.MethodPost, path: "/user", body: `{"groupId":"QA","userId":"x"}`, wantStatus: http.StatusBadRequest},
+		{reason: "Creating an object", method: http.MethodPut, path: "/user", body: `{"groupId":"QA","userId":"x"}`, wantStatus: http.StatusOK},
+		{reason: "Creating an existing object is refused", method: http.MethodPut, path: "/user", body: `{"groupId":"QA","userId":"x"}`, wantStatus: http.StatusConflict},
+		{reason: "Lists have one item more than the limit when there are more pages", method: http.MethodGet, path: "/user/list?groupId=QA&limit=1", wantStatus: http.StatusOK,
+			wantBody: `[{"groupId":"QA","userId":"a","userType":""},{"groupId":"QA","userId":"b","userType":""}]`},
+		{reason: "Lists start at the offset, which is the first item of the next page", method: http.MethodGet, path: "/user/list?groupId=QA&limit=1&offset=b", wantStatus: http.StatusOK,
+			wantBody: `[{"groupId":"QA","userId":"b","userType":""},{"groupId":"QA","userId":"c","userType":""}]`},
+		{reason: "Empty lists are 204 No Content", method: http.MethodGet, path: "/user/list?groupId=QA&prefix=z", wantStatus: http.StatusNoContent},
+		{reason: "Deleting a missing object is 204 No Content", method: http.MethodDelete, path: "/group?groupId=x", wantStatus: http.StatusNoContent},
+		{reason: "Deleting a group with users is refused", method: http.MethodDelete, path: "/group?groupId=QA", wantStatus: http.StatusConflict},
+		{reason: "Deleting an object", method: http.MethodDelete, path: "/group?groupId=Empty", wantStatus: http.StatusOK},
+	}
+	for _, tc := range cases {
+		status, body := do(tc.method, f.URL+tc.path, tc.body)
+		if status != tc.wantStatus || (tc.wantBody != "" && body != tc.wantBody) {
+			t.Errorf("%s: %s %s: want %d %s, got %d %s", tc.reason, tc.method, tc.path, tc.wantStatus, tc.wantBody, status, body)
+		}
+	}
+
+	if user, ok := f.User("QA", "x"); !ok || user.CanonicalID == "" {
+		t.Errorf("User(QA, x): want a created user with a canonical ID, got %+v", user)
+	}
+	if got := f.AccessKeys("QA", "x"); len(got) != 1 {
+		t.Errorf("AccessKeys(QA, x): want the credentials created with the user, got %v", got)
+	}
+	if !f.HasGroup("QA") || f.HasGroup("Empty") {
+		t.Error("HasGroup(...): want only the group without users deleted")
+	}
+}
+
+func TestFakeServerFailNext(t *testing.T) {
+	f := NewFakeServer(t)
+	f.FailNext(2)
+
+	want := []int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusNoContent}
+	for i, wantStatus := range want {
+		if got, _ := do(http.MethodGet, f.URL+"/group?groupId=QA", ""); got != wantStatus {
+			t.Errorf("request %d: want status %d, got %d", i, wantStatus, got)
+		}
+	}
+}
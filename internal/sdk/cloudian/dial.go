@@ -0,0 +1,36 @@
+package cloudian
+
+import (
+	"context"
+	"net"
+
+	"golang.org/x/net/proxy"
+)
+
+// A DialContextFunc opens the connections of a client to Cloudian, as
+// net.Dialer.DialContext does.
+type DialContextFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// WithDialContext opens the connections to Cloudian with `dial` instead of
+// dialing the endpoint directly, as when Cloudian is only reachable through
+// kubectl port-forward or a tunnel. Requests are still sent to the endpoint,
+// whose hostname the TLS certificate of Cloudian is verified against.
+func WithDialContext(dial DialContextFunc) func(*Client) {
+	return func(c *Client) {
+		c.http.dial = dial
+	}
+}
+
+// WithSOCKS5 connects to Cloudian through the SOCKS5 proxy at `addr`, a host
+// and port such as localhost:1080, as opened by ssh -D over a bastion. The
+// proxy resolves the hostname of the endpoint. `auth` authenticates to the
+// proxy, unless nil.
+func WithSOCKS5(addr string, auth *proxy.Auth) func(*Client) {
+	dialer, err := proxy.SOCKS5("tcp", addr, auth, proxy.Direct)
+	if err != nil {
+		return WithDialContext(func(context.Context, string, string) (net.Conn, error) {
+			return nil, err
+		})
+	}
+	return WithDialContext(dialer.(proxy.ContextDialer).DialContext)
+}
@@ -0,0 +1,84 @@
+package cloudian
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+)
+
+const (
+	// maxErrorBody is how much of the body of an error response is looked
+	// at for the reason of the error.
+	maxErrorBody = 64 << 10
+	// maxReasonLength is how many characters of a body that is not a JSON
+	// error are kept as the reason of the error.
+	maxReasonLength = 256
+)
+
+var (
+	htmlTitle = regexp.MustCompile(`(?is)<title[^>]*>(.*?)</title>`)
+	htmlTag   = regexp.MustCompile(`(?s)<[^>]*>`)
+)
+
+// errorBody is the JSON body of an error response of the admin API.
+type errorBody struct {
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+// newStatusError returns the StatusError of an unexpected response, with the
+// reason Cloudian gave in its body.
+func newStatusError(method, path string, resp *resty.Response) *StatusError {
+	return &StatusError{Method: method, Path: path, StatusCode: resp.StatusCode(), Reason: errorReason(resp.Body())}
+}
+
+// errorReason returns the reason of an error response with the supplied body:
+// the reason and message of a JSON error, the title of an HTML error page, or
+// else the start of the body on a single line.
+func errorReason(body []byte) string {
+	body = bytes.TrimSpace(body[:min(len(body), maxErrorBody)])
+	if len(body) == 0 {
+		return ""
+	}
+	if body[0] == '{' {
+		var e errorBody
+		if err := json.Unmarshal(body, &e); err == nil {
+			if reason := joinReason(e.Reason, e.Message); reason != "" {
+				return reason
+			}
+		}
+	}
+	if body[0] == '<' {
+		if m := htmlTitle.FindSubmatch(body); m != nil && len(bytes.TrimSpace(m[1])) > 0 {
+			return snippet(m[1])
+		}
+		body = htmlTag.ReplaceAll(body, []byte(" "))
+	}
+	return snippet(body)
+}
+
+// joinReason joins the reason and message of a JSON error, when they differ.
+func joinReason(reason, message string) string {
+	reason, message = strings.TrimSpace(reason), strings.TrimSpace(message)
+	switch {
+	case reason == "" || reason == message:
+		return message
+	case message == "":
+		return reason
+	default:
+		return reason + ": " + message
+	}
+}
+
+// snippet returns the start of `b` on a single line, of at most
+// maxReasonLength characters.
+func snippet(b []byte) string {
+	s := strings.Join(strings.Fields(strings.ToValidUTF8(string(b), "�")), " ")
+	if r := []rune(s); len(r) > maxReasonLength {
+		return string(r[:maxReasonLength]) + "..."
+	}
+	return s
+}
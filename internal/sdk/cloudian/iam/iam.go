@@ -0,0 +1,331 @@
+// Package iam is a client of the IAM compatible API of Cloudian HyperStore,
+// which manages IAM users under a HyperStore user with scoped credentials of
+// their own. Requests are signed with AWS Signature Version 4 using the
+// credentials of the parent HyperStore user, so the package is kept apart
+// from the admin API client and only built by the controllers that need it.
+package iam
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"k8s.io/utils/clock"
+
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
+)
+
+const (
+	// DefaultRegion is the region requests are signed for, unless set with
+	// WithRegion.
+	DefaultRegion = "us-east-1"
+
+	// service is the name requests are signed for.
+	service = "iam"
+
+	// version is the version of the IAM API.
+	version = "2010-05-08"
+
+	// maxItems is the most items requested per page of a list.
+	maxItems = "100"
+)
+
+// ErrNotFound is returned when an IAM user does not exist. It matches
+// cloudian.ErrNotFound too, so callers handle both APIs alike.
+var ErrNotFound = fmt.Errorf("IAM user %w", cloudian.ErrNotFound)
+
+// ErrAlreadyExists is returned when creating an IAM user that already exists.
+var ErrAlreadyExists = errors.New("IAM user already exists")
+
+// ErrNoParentCredentials is returned when the parent HyperStore user has no
+// access keys to sign the requests with.
+var ErrNoParentCredentials = errors.New("parent user has no access keys")
+
+// Credentials sign the requests to the IAM API.
+type Credentials struct {
+	AccessKeyID string
+	SecretKey   cloudian.Secret
+}
+
+// A CredentialsFunc returns the credentials of a parent HyperStore user.
+type CredentialsFunc func(ctx context.Context, parent cloudian.GroupUserID) (Credentials, error)
+
+// StaticCredentials returns a CredentialsFunc that always returns `creds`,
+// such as the credentials of the parent of a single managed resource.
+func StaticCredentials(creds Credentials) CredentialsFunc {
+	return func(context.Context, cloudian.GroupUserID) (Credentials, error) {
+		return creds, nil
+	}
+}
+
+// ParentCredentials returns the first access key of a parent HyperStore user,
+// read with the admin API. Returns ErrNoParentCredentials if it has none.
+func ParentCredentials(ctx context.Context, admin *cloudian.Client, parent cloudian.GroupUserID) (Credentials, error) {
+	keys, err := admin.ListUserCredentials(ctx, parent)
+	if err != nil {
+		return Credentials{}, err
+	}
+	if len(keys) == 0 {
+		return Credentials{}, fmt.Errorf("user %s: %w", parent.UserID, ErrNoParentCredentials)
+	}
+	return Credentials{AccessKeyID: keys[0].AccessKey, SecretKey: keys[0].SecretKey}, nil
+}
+
+// A Client of the IAM API of a Cloudian cluster.
+type Client struct {
+	endpoint    string
+	region      string
+	credentials CredentialsFunc
+	tls         *tls.Config
+	timeout     time.Duration
+	http        *http.Client
+	clock       clock.PassiveClock
+}
+
+// WithRegion signs the requests for `region` instead of DefaultRegion.
+func WithRegion(region string) func(*Client) {
+	return func(c *Client) {
+		c.region = region
+	}
+}
+
+// WithCACert verifies the server certificate against the PEM encoded CA
+// certificates in `pem` instead of the system CA certificates.
+func WithCACert(pem []byte) func(*Client) {
+	return func(c *Client) {
+		pool := x509.NewCertPool()
+		pool.AppendCertsFromPEM(pem)
+		c.updateTLS(func(cfg *tls.Config) { cfg.RootCAs = pool })
+	}
+}
+
+// WithServerName verifies that the server certificate is valid for `name`
+// instead of the hostname of the endpoint.
+func WithServerName(name string) func(*Client) {
+	return func(c *Client) {
+		c.updateTLS(func(cfg *tls.Config) { cfg.ServerName = name })
+	}
+}
+
+func (c *Client) updateTLS(update func(*tls.Config)) {
+	if c.tls == nil {
+		c.tls = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	update(c.tls)
+}
+
+// WithTimeout limits the time of each HTTP request.
+func WithTimeout(d time.Duration) func(*Client) {
+	return func(c *Client) {
+		c.timeout = d
+	}
+}
+
+// WithClock sets the time source requests are signed with, so that tests
+// control their signatures.
+func WithClock(clock clock.PassiveClock) func(*Client) {
+	return func(c *Client) {
+		c.clock = clock
+	}
+}
+
+// NewClient returns a client of the IAM API at `endpoint`, signing the
+// requests with the credentials of the parent user of each.
+func NewClient(endpoint string, credentials CredentialsFunc, opts ...func(*Client)) *Client {
+	c := &Client{
+		endpoint:    strings.TrimSuffix(endpoint, "/"),
+		region:      DefaultRegion,
+		credentials: credentials,
+		clock:       clock.RealClock{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.http = &http.Client{Timeout: c.timeout}
+	if c.tls != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = c.tls
+		c.http.Transport = transport
+	}
+	return c
+}
+
+// A User is an IAM user under a HyperStore user.
+type User struct {
+	Name       string
+	ID         string
+	ARN        string
+	Path       string
+	CreateDate time.Time
+}
+
+type xmlUser struct {
+	Path       string    `xml:"Path"`
+	UserName   string    `xml:"UserName"`
+	UserID     string    `xml:"UserId"`
+	Arn        string    `xml:"Arn"`
+	CreateDate time.Time `xml:"CreateDate"`
+}
+
+func (u xmlUser) user() User {
+	return User{Name: u.UserName, ID: u.UserID, ARN: u.Arn, Path: u.Path, CreateDate: u.CreateDate}
+}
+
+// CreateIAMUser creates an IAM user named `name` under the parent user.
+// Returns ErrAlreadyExists if it exists.
+func (c *Client) CreateIAMUser(ctx context.Context, parent cloudian.GroupUserID, name string) (*User, error) {
+	var resp struct {
+		User xmlUser `xml:"CreateUserResult>User"`
+	}
+	if err := c.do(ctx, parent, url.Values{"Action": {"CreateUser"}, "UserName": {name}}, &resp); err != nil {
+		return nil, err
+	}
+	u := resp.User.user()
+	return &u, nil
+}
+
+// ListIAMUsers lists every IAM user under the parent user.
+func (c *Client) ListIAMUsers(ctx context.Context, parent cloudian.GroupUserID) ([]User, error) {
+	var users []User
+	params := url.Values{"Action": {"ListUsers"}, "MaxItems": {maxItems}}
+	for {
+		var resp struct {
+			Users       []xmlUser `xml:"ListUsersResult>Users>member"`
+			IsTruncated bool      `xml:"ListUsersResult>IsTruncated"`
+			Marker      string    `xml:"ListUsersResult>Marker"`
+		}
+		if err := c.do(ctx, parent, params, &resp); err != nil {
+			return nil, err
+		}
+		for _, u := range resp.Users {
+			users = append(users, u.user())
+		}
+		if !resp.IsTruncated || resp.Marker == "" {
+			return users, nil
+		}
+		params.Set("Marker", resp.Marker)
+	}
+}
+
+// GetIAMUser gets the IAM user named `name` under the parent user. Returns
+// ErrNotFound if it does not exist.
+func (c *Client) GetIAMUser(ctx context.Context, parent cloudian.GroupUserID, name string) (*User, error) {
+	var resp struct {
+		User xmlUser `xml:"GetUserResult>User"`
+	}
+	if err := c.do(ctx, parent, url.Values{"Action": {"GetUser"}, "UserName": {name}}, &resp); err != nil {
+		return nil, err
+	}
+	u := resp.User.user()
+	return &u, nil
+}
+
+// DeleteIAMUser deletes the IAM user named `name` under the parent user,
+// deleting its access keys first, as the IAM API refuses to delete users that
+// have any. Returns ErrNotFound if it does not exist.
+func (c *Client) DeleteIAMUser(ctx context.Context, parent cloudian.GroupUserID, name string) error {
+	keys, err := c.listAccessKeys(ctx, parent, name)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		err := c.do(ctx, parent, url.Values{"Action": {"DeleteAccessKey"}, "UserName": {name}, "AccessKeyId": {key}}, nil)
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return fmt.Errorf("error deleting access key %s: %w", key, err)
+		}
+	}
+	return c.do(ctx, parent, url.Values{"Action": {"DeleteUser"}, "UserName": {name}}, nil)
+}
+
+// CreateIAMAccessKey creates an access key for the IAM user named `name`
+// under the parent user. The secret key is only returned here.
+func (c *Client) CreateIAMAccessKey(ctx context.Context, parent cloudian.GroupUserID, name string) (*cloudian.SecurityInfo, error) {
+	var resp struct {
+		AccessKeyID     string `xml:"CreateAccessKeyResult>AccessKey>AccessKeyId"`
+		SecretAccessKey string `xml:"CreateAccessKeyResult>AccessKey>SecretAccessKey"`
+	}
+	if err := c.do(ctx, parent, url.Values{"Action": {"CreateAccessKey"}, "UserName": {name}}, &resp); err != nil {
+		return nil, err
+	}
+	return &cloudian.SecurityInfo{AccessKey: resp.AccessKeyID, SecretKey: cloudian.NewSecret(resp.SecretAccessKey)}, nil
+}
+
+// listAccessKeys lists the IDs of the access keys of an IAM user.
+func (c *Client) listAccessKeys(ctx context.Context, parent cloudian.GroupUserID, name string) ([]string, error) {
+	var ids []string
+	params := url.Values{"Action": {"ListAccessKeys"}, "UserName": {name}, "MaxItems": {maxItems}}
+	for {
+		var resp struct {
+			IDs         []string `xml:"ListAccessKeysResult>AccessKeyMetadata>member>AccessKeyId"`
+			IsTruncated bool     `xml:"ListAccessKeysResult>IsTruncated"`
+			Marker      string   `xml:"ListAccessKeysResult>Marker"`
+		}
+		if err := c.do(ctx, parent, params, &resp); err != nil {
+			return nil, err
+		}
+		ids = append(ids, resp.IDs...)
+		if !resp.IsTruncated || resp.Marker == "" {
+			return ids, nil
+		}
+		params.Set("Marker", resp.Marker)
+	}
+}
+
+// apiError is an error response of the IAM API.
+type apiError struct {
+	Code    string `xml:"Error>Code"`
+	Message string `xml:"Error>Message"`
+}
+
+// do sends an action to the IAM API, signed with the credentials of the
+// parent user, and decodes the response into `out` unless it is nil.
+func (c *Client) do(ctx context.Context, parent cloudian.GroupUserID, params url.Values, out any) error {
+	creds, err := c.credentials(ctx, parent)
+	if err != nil {
+		return fmt.Errorf("error getting credentials of user %s: %w", parent.UserID, err)
+	}
+
+	params.Set("Version", version)
+	body := []byte(params.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	sign(req, body, creds, c.region, service, c.clock.Now())
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= http.StatusBadRequest {
+		var apiErr apiError
+		_ = xml.Unmarshal(data, &apiErr)
+		switch apiErr.Code {
+		case "NoSuchEntity":
+			return fmt.Errorf("%s: %w", params.Get("Action"), ErrNotFound)
+		case "EntityAlreadyExists":
+			return fmt.Errorf("%s: %w", params.Get("Action"), ErrAlreadyExists)
+		}
+		return fmt.Errorf("%s: unexpected status %d: %s: %s", params.Get("Action"), resp.StatusCode, apiErr.Code, apiErr.Message)
+	}
+	if out == nil {
+		return nil
+	}
+	return xml.Unmarshal(data, out)
+}
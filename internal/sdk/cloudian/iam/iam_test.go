@@ -0,0 +1,146 @@
+package iam
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian/cloudiantest"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian/iam/iamtest"
+)
+
+var parent = cloudian.GroupUserID{GroupID: "QA", UserID: "alice"}
+
+func newClient(srv *iamtest.FakeServer) *Client {
+	return NewClient(srv.URL, StaticCredentials(Credentials{AccessKeyID: "AKIDPARENT", SecretKey: cloudian.NewSecret("secret")}))
+}
+
+func TestCreateIAMUser(t *testing.T) {
+	srv := iamtest.NewFakeServer(t)
+	c := newClient(srv)
+
+	user, err := c.CreateIAMUser(context.Background(), parent, "ci")
+	if err != nil {
+		t.Fatalf("CreateIAMUser(...): %v", err)
+	}
+	want := &User{Name: "ci", ID: "AKIDPARENT:ci", ARN: "arn:aws:iam::AKIDPARENT:user/ci"}
+	if diff := cmp.Diff(want, user); diff != "" {
+		t.Errorf("CreateIAMUser(...): -want, +got:\n%s", diff)
+	}
+
+	if _, err := c.CreateIAMUser(context.Background(), parent, "ci"); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("CreateIAMUser(...) of an existing user: want ErrAlreadyExists, got %v", err)
+	}
+}
+
+func TestListIAMUsers(t *testing.T) {
+	srv := iamtest.NewFakeServer(t)
+	for _, name := range []string{"ci", "backup", "reports"} {
+		srv.AddUser("AKIDPARENT", iamtest.User{Name: name})
+	}
+	srv.AddUser("AKIDOTHER", iamtest.User{Name: "other"})
+
+	users, err := newClient(srv).ListIAMUsers(context.Background(), parent)
+	if err != nil {
+		t.Fatalf("ListIAMUsers(...): %v", err)
+	}
+	var names []string
+	for _, u := range users {
+		names = append(names, u.Name)
+	}
+	if diff := cmp.Diff([]string{"ci", "backup", "reports"}, names); diff != "" {
+		t.Errorf("ListIAMUsers(...): want every page of the users of the parent, -want, +got:\n%s", diff)
+	}
+}
+
+func TestGetIAMUser(t *testing.T) {
+	srv := iamtest.NewFakeServer(t)
+	srv.AddUser("AKIDPARENT", iamtest.User{Name: "ci"})
+	c := newClient(srv)
+
+	user, err := c.GetIAMUser(context.Background(), parent, "ci")
+	if err != nil || user.Name != "ci" {
+		t.Errorf("GetIAMUser(...): want user ci, got %+v, %v", user, err)
+	}
+	_, err = c.GetIAMUser(context.Background(), parent, "missing")
+	if !errors.Is(err, ErrNotFound) || !errors.Is(err, cloudian.ErrNotFound) {
+		t.Errorf("GetIAMUser(...) of a missing user: want ErrNotFound, got %v", err)
+	}
+}
+
+func TestDeleteIAMUser(t *testing.T) {
+	srv := iamtest.NewFakeServer(t)
+	srv.AddUser("AKIDPARENT", iamtest.User{Name: "ci", AccessKeys: []string{"AKIA1", "AKIA2"}})
+	srv.AddUser("AKIDPARENT", iamtest.User{Name: "backup"})
+	c := newClient(srv)
+
+	if err := c.DeleteIAMUser(context.Background(), parent, "ci"); err != nil {
+		t.Fatalf("DeleteIAMUser(...): %v", err)
+	}
+	if diff := cmp.Diff([]iamtest.User{{Name: "backup"}}, srv.Users("AKIDPARENT")); diff != "" {
+		t.Errorf("DeleteIAMUser(...): -want users, +got:\n%s", diff)
+	}
+	if err := c.DeleteIAMUser(context.Background(), parent, "ci"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("DeleteIAMUser(...) of a deleted user: want ErrNotFound, got %v", err)
+	}
+}
+
+func TestCreateIAMAccessKey(t *testing.T) {
+	srv := iamtest.NewFakeServer(t)
+	srv.AddUser("AKIDPARENT", iamtest.User{Name: "ci"})
+	c := newClient(srv)
+
+	key, err := c.CreateIAMAccessKey(context.Background(), parent, "ci")
+	if err != nil {
+		t.Fatalf("CreateIAMAccessKey(...): %v", err)
+	}
+	if key.AccessKey == "" || key.SecretKey.Value() != "secret-"+key.AccessKey {
+		t.Errorf("CreateIAMAccessKey(...): want the created access key, got %s", key.AccessKey)
+	}
+	if _, err := c.CreateIAMAccessKey(context.Background(), parent, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("CreateIAMAccessKey(...) for a missing user: want ErrNotFound, got %v", err)
+	}
+}
+
+func TestUnexpectedError(t *testing.T) {
+	srv := iamtest.NewFakeServer(t)
+	c := NewClient(srv.URL, func(context.Context, cloudian.GroupUserID) (Credentials, error) {
+		return Credentials{}, errors.New("boom")
+	})
+	if _, err := c.ListIAMUsers(context.Background(), parent); err == nil {
+		t.Error("ListIAMUsers(...): want an error when the credentials of the parent cannot be read")
+	}
+	if len(srv.Actions()) > 0 {
+		t.Errorf("ListIAMUsers(...): want no unsigned request, got %v", srv.Actions())
+	}
+
+	failing := cloudiantest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	if _, err := NewClient(failing.URL, StaticCredentials(Credentials{})).ListIAMUsers(context.Background(), parent); err == nil {
+		t.Error("ListIAMUsers(...): want an error for a server error")
+	}
+}
+
+func TestParentCredentials(t *testing.T) {
+	srv := cloudiantest.NewFakeServer(t)
+	srv.AddUser(cloudiantest.User{GroupID: "QA", UserID: "alice"})
+	admin := cloudian.NewClient(srv.URL, "")
+
+	if _, err := ParentCredentials(context.Background(), admin, parent); !errors.Is(err, ErrNoParentCredentials) {
+		t.Errorf("ParentCredentials(...) of a user without access keys: want ErrNoParentCredentials, got %v", err)
+	}
+
+	srv.AddCredentials("QA", "alice", "00112233445566778899", "secret")
+	creds, err := ParentCredentials(context.Background(), admin, parent)
+	if err != nil {
+		t.Fatalf("ParentCredentials(...): %v", err)
+	}
+	if creds.AccessKeyID != "00112233445566778899" || creds.SecretKey.Value() != "secret" {
+		t.Errorf("ParentCredentials(...): want the access key of the parent, got %s", creds.AccessKeyID)
+	}
+}
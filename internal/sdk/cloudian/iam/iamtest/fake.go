@@ -0,0 +1,162 @@
+// Package iamtest contains a fake IAM API of Cloudian for tests.
+package iamtest
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// credential matches the access key ID of a Signature Version 4 signed
+// request.
+var credential = regexp.MustCompile(`^AWS4-HMAC-SHA256 Credential=([^/]+)/`)
+
+// A User is an IAM user of a FakeServer.
+type User struct {
+	Name       string
+	AccessKeys []string
+}
+
+// A FakeServer is an IAM API keeping IAM users in memory, by the access key
+// ID of the parent user that signed their requests. Signatures are not
+// verified. Lists return one item per page, so that paging is exercised.
+type FakeServer struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	users   map[string][]User
+	actions []string
+	serial  int
+}
+
+// NewFakeServer starts an empty FakeServer. The server is closed when the
+// test ends.
+func NewFakeServer(tb testing.TB) *FakeServer {
+	tb.Helper()
+	f := &FakeServer{users: map[string][]User{}}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	tb.Cleanup(f.Close)
+	return f
+}
+
+// AddUser adds an IAM user under the parent user with the access key ID
+// `parentKey`.
+func (f *FakeServer) AddUser(parentKey string, user User) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.users[parentKey] = append(f.users[parentKey], user)
+}
+
+// Users returns the IAM users under the parent user with the access key ID
+// `parentKey`.
+func (f *FakeServer) Users(parentKey string) []User {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.users[parentKey])
+}
+
+// Actions returns the actions requested so far, in order.
+func (f *FakeServer) Actions() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.actions)
+}
+
+func (f *FakeServer) serve(w http.ResponseWriter, r *http.Request) {
+	m := credential.FindStringSubmatch(r.Header.Get("Authorization"))
+	if m == nil || r.Header.Get("X-Amz-Date") == "" || r.ParseForm() != nil {
+		writeError(w, http.StatusForbidden, "SignatureDoesNotMatch")
+		return
+	}
+	parent, action, name := m[1], r.PostForm.Get("Action"), r.PostForm.Get("UserName")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.actions = append(f.actions, action)
+	users := f.users[parent]
+	i := slices.IndexFunc(users, func(u User) bool { return u.Name == name })
+
+	switch {
+	case action == "ListUsers":
+		start, _ := strconv.Atoi(r.PostForm.Get("Marker"))
+		page := ""
+		if start < len(users) {
+			page = userMember(parent, users[start].Name)
+		}
+		writeList(w, "ListUsers", "Users", page, start+1 < len(users), strconv.Itoa(start+1))
+	case action == "CreateUser" && i >= 0:
+		writeError(w, http.StatusConflict, "EntityAlreadyExists")
+	case action == "CreateUser":
+		f.users[parent] = append(users, User{Name: name})
+		writeResult(w, "CreateUser", "<User>"+userMember(parent, name)+"</User>")
+	case i < 0:
+		writeError(w, http.StatusNotFound, "NoSuchEntity")
+	case action == "GetUser":
+		writeResult(w, "GetUser", "<User>"+userMember(parent, name)+"</User>")
+	case action == "DeleteUser" && len(users[i].AccessKeys) > 0:
+		writeError(w, http.StatusConflict, "DeleteConflict")
+	case action == "DeleteUser":
+		f.users[parent] = slices.Delete(users, i, i+1)
+		writeResult(w, "DeleteUser", "")
+	case action == "CreateAccessKey":
+		f.serial++
+		id := fmt.Sprintf("AKIA%016d", f.serial)
+		users[i].AccessKeys = append(users[i].AccessKeys, id)
+		writeResult(w, "CreateAccessKey", "<AccessKey>"+element("UserName", name)+element("AccessKeyId", id)+
+			element("SecretAccessKey", "secret-"+id)+"</AccessKey>")
+	case action == "ListAccessKeys":
+		start, _ := strconv.Atoi(r.PostForm.Get("Marker"))
+		page := ""
+		if start < len(users[i].AccessKeys) {
+			page = element("UserName", name) + element("AccessKeyId", users[i].AccessKeys[start])
+		}
+		writeList(w, "ListAccessKeys", "AccessKeyMetadata", page, start+1 < len(users[i].AccessKeys), strconv.Itoa(start+1))
+	case action == "DeleteAccessKey":
+		users[i].AccessKeys = slices.DeleteFunc(users[i].AccessKeys, func(id string) bool { return id == r.PostForm.Get("AccessKeyId") })
+		writeResult(w, "DeleteAccessKey", "")
+	default:
+		writeError(w, http.StatusBadRequest, "InvalidAction")
+	}
+}
+
+func userMember(parent, name string) string {
+	return element("UserName", name) + element("UserId", parent+":"+name) + element("Arn", "arn:aws:iam::"+parent+":user/"+name)
+}
+
+// element returns an XML element named `name` with the escaped `text`.
+func element(name, text string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(text))
+	return "<" + name + ">" + b.String() + "</" + name + ">"
+}
+
+// writeList writes a page of a list, with one member at most.
+func writeList(w http.ResponseWriter, action, list, member string, truncated bool, marker string) {
+	result := "<" + list + ">"
+	if member != "" {
+		result += "<member>" + member + "</member>"
+	}
+	result += "</" + list + ">" + element("IsTruncated", strconv.FormatBool(truncated))
+	if truncated {
+		result += element("Marker", marker)
+	}
+	writeResult(w, action, result)
+}
+
+func writeResult(w http.ResponseWriter, action, result string) {
+	w.Header().Set("Content-Type", "text/xml")
+	_, _ = fmt.Fprintf(w, "<%[1]sResponse><%[1]sResult>%[2]s</%[1]sResult></%[1]sResponse>", action, result)
+}
+
+func writeError(w http.ResponseWriter, status int, code string) {
+	w.Header().Set("Content-Type", "text/xml")
+	w.WriteHeader(status)
+	_, _ = fmt.Fprintf(w, "<ErrorResponse><Error>%s%s</Error></ErrorResponse>", element("Code", code), element("Message", code))
+}
@@ -0,0 +1,129 @@
+package iam
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	algorithm  = "AWS4-HMAC-SHA256"
+	dateFormat = "20060102T150405Z"
+)
+
+// sign signs a request with AWS Signature Version 4, setting its X-Amz-Date
+// and Authorization headers. `body` is the payload of the request. The Host,
+// Content-Type and X-Amz-* headers are signed.
+func sign(req *http.Request, body []byte, creds Credentials, region, service string, t time.Time) {
+	t = t.UTC()
+	req.Header.Set("X-Amz-Date", t.Format(dateFormat))
+
+	headers, signed := canonicalHeaders(req)
+	payload := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		canonicalURI(req.URL),
+		canonicalQuery(req.URL.Query()),
+		headers,
+		signed,
+		hex.EncodeToString(payload[:]),
+	}, "\n")
+
+	day := t.Format("20060102")
+	scope := strings.Join([]string{day, region, service, "aws4_request"}, "/")
+	hashed := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{algorithm, t.Format(dateFormat), scope, hex.EncodeToString(hashed[:])}, "\n")
+
+	key := []byte("AWS4" + creds.SecretKey.Value())
+	for _, part := range []string{day, region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", algorithm+" Credential="+creds.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signed+", Signature="+signature)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// canonicalHeaders returns the canonical headers of a request and the list of
+// their names.
+func canonicalHeaders(req *http.Request) (string, string) {
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	values := map[string]string{"host": host}
+	for name, v := range req.Header {
+		name = strings.ToLower(name)
+		if name == "content-type" || strings.HasPrefix(name, "x-amz-") {
+			trimmed := make([]string, len(v))
+			for i, s := range v {
+				trimmed[i] = strings.Join(strings.Fields(s), " ")
+			}
+			values[name] = strings.Join(trimmed, ",")
+		}
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name + ":" + values[name] + "\n")
+	}
+	return b.String(), strings.Join(names, ";")
+}
+
+// canonicalURI returns the URI encoded path of a request.
+func canonicalURI(u *url.URL) string {
+	path := u.EscapedPath()
+	if path == "" {
+		return "/"
+	}
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		if unescaped, err := url.PathUnescape(s); err == nil {
+			segments[i] = escape(unescaped)
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// canonicalQuery returns the query parameters of a request sorted by name
+// and value, and URI encoded.
+func canonicalQuery(q url.Values) string {
+	var params []string
+	for name, values := range q {
+		for _, v := range values {
+			params = append(params, escape(name)+"="+escape(v))
+		}
+	}
+	slices.Sort(params)
+	return strings.Join(params, "&")
+}
+
+// escape URI encodes `s` as required by Signature Version 4, where only the
+// unreserved characters of RFC 3986 are left as is.
+func escape(s string) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.IndexByte("-._~", c) >= 0 {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteString("%" + strings.ToUpper(hex.EncodeToString([]byte{c})))
+	}
+	return b.String()
+}
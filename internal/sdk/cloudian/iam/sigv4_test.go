@@ -0,0 +1,80 @@
+package iam
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
+)
+
+// The examples of the AWS Signature Version 4 documentation.
+var exampleCredentials = Credentials{
+	AccessKeyID: "AKIDEXAMPLE",
+	SecretKey:   cloudian.NewSecret("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"),
+}
+
+func TestSign(t *testing.T) {
+	at := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+
+	cases := map[string]struct {
+		method      string
+		url         string
+		contentType string
+		service     string
+		want        string
+	}{
+		"GetVanilla": {
+			method:  http.MethodGet,
+			url:     "https://example.amazonaws.com/",
+			service: "service",
+			want: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+				"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		"ListUsers": {
+			method:      http.MethodGet,
+			url:         "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08",
+			contentType: "application/x-www-form-urlencoded; charset=utf-8",
+			service:     "iam",
+			want: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, " +
+				"SignedHeaders=content-type;host;x-amz-date, Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7",
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			req, err := http.NewRequest(tc.method, tc.url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			sign(req, nil, exampleCredentials, DefaultRegion, tc.service, at)
+			if got := req.Header.Get("Authorization"); got != tc.want {
+				t.Errorf("sign(...): want Authorization\n%s\ngot\n%s", tc.want, got)
+			}
+			if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+				t.Errorf("sign(...): want X-Amz-Date 20150830T123600Z, got %s", got)
+			}
+		})
+	}
+}
+
+func TestEscape(t *testing.T) {
+	cases := map[string]string{
+		"AZaz09-._~": "AZaz09-._~",
+		"a b":        "a%20b",
+		"a+b=c/d":    "a%2Bb%3Dc%2Fd",
+		"path/é":     "path%2F%C3%A9",
+		"":           "",
+	}
+	for in, want := range cases {
+		if got := escape(in); got != want {
+			t.Errorf("escape(%q): want %q, got %q", in, want, got)
+		}
+	}
+	if got := canonicalQuery(map[string][]string{"b": {"2", "1"}, "a b": {"x"}}); got != "a%20b=x&b=1&b=2" {
+		t.Errorf("canonicalQuery(...): want sorted and encoded parameters, got %q", got)
+	}
+}
@@ -0,0 +1,75 @@
+package cloudian
+
+import (
+	"context"
+	"math"
+
+	"golang.org/x/time/rate"
+)
+
+// DefaultBackgroundBudget is the fraction of the rate limit of a Client that
+// background requests may use, unless set with WithBackgroundBudget.
+const DefaultBackgroundBudget = 0.5
+
+// backgroundKey marks the context of background requests.
+type backgroundKey struct{}
+
+// Background returns a context whose requests are background work, such as
+// bulk operations on every member of a group. When the Client is rate
+// limited, background requests draw from a budget of the rate limit, so that
+// they cannot starve the interactive requests of regular reconciles.
+func Background(ctx context.Context) context.Context {
+	return context.WithValue(ctx, backgroundKey{}, true)
+}
+
+// IsBackground returns whether the requests made with the context are
+// background work.
+func IsBackground(ctx context.Context) bool {
+	background, _ := ctx.Value(backgroundKey{}).(bool)
+	return background
+}
+
+// rateConfig is what the limiter of a Client is built from once all the
+// options are applied, so that the options compose regardless of their order.
+type rateConfig struct {
+	rps        float64
+	burst      int
+	background float64
+}
+
+// limiter limits the rate of the requests of a Client, with two priority
+// classes. Background requests first wait for their own budget, which is a
+// fraction of the rate limit, and then for the rate limit shared with the
+// interactive requests. At most the burst of the budget is thus waiting
+// ahead of an interactive request, however much background work is queued.
+type limiter struct {
+	all        *rate.Limiter
+	background *rate.Limiter
+}
+
+// build returns the limiter configured by the options, or nil if the requests
+// are not rate limited.
+func (cfg rateConfig) build() *limiter {
+	if cfg.rps <= 0 {
+		return nil
+	}
+	fraction := cfg.background
+	if fraction <= 0 || fraction > 1 {
+		fraction = DefaultBackgroundBudget
+	}
+	return &limiter{
+		all:        rate.NewLimiter(rate.Limit(cfg.rps), cfg.burst),
+		background: rate.NewLimiter(rate.Limit(cfg.rps*fraction), max(int(math.Floor(float64(cfg.burst)*fraction)), 1)),
+	}
+}
+
+// wait blocks until a request made with the context may be sent, or the
+// context is done.
+func (l *limiter) wait(ctx context.Context) error {
+	if IsBackground(ctx) {
+		if err := l.background.Wait(ctx); err != nil {
+			return err
+		}
+	}
+	return l.all.Wait(ctx)
+}
@@ -0,0 +1,89 @@
+package cloudian
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// apiMetrics measure the requests sent to the admin API, by method, path and
+// status code. Paths never include IDs, which are sent as query parameters,
+// so the number of series is bounded by the number of endpoints used.
+type apiMetrics struct {
+	duration *prometheus.HistogramVec
+	requests *prometheus.CounterVec
+	skew     *prometheus.GaugeVec
+	license  *prometheus.CounterVec
+}
+
+// WithMetrics registers metrics of the requests sent to the admin API with
+// `registry`. Clients sharing a registry share their metrics.
+func WithMetrics(registry prometheus.Registerer) func(*Client) {
+	return func(c *Client) {
+		labels := []string{"method", "path", "code"}
+		c.metrics = &apiMetrics{
+			duration: register(registry, prometheus.NewHistogramVec(prometheus.HistogramOpts{
+				Name:    "cloudian_api_request_duration_seconds",
+				Help:    "The duration of requests to the Cloudian admin API.",
+				Buckets: prometheus.DefBuckets,
+			}, labels)),
+			requests: register(registry, prometheus.NewCounterVec(prometheus.CounterOpts{
+				Name: "cloudian_api_requests_total",
+				Help: "The number of requests to the Cloudian admin API.",
+			}, labels)),
+			skew: register(registry, prometheus.NewGaugeVec(prometheus.GaugeOpts{
+				Name: "cloudian_api_clock_skew_seconds",
+				Help: "How far the clock of the Cloudian admin API is estimated to be ahead of the provider's.",
+			}, []string{"endpoint"})),
+			license: register(registry, prometheus.NewCounterVec(prometheus.CounterOpts{
+				Name: "cloudian_api_license_exceeded_total",
+				Help: "The number of requests to the Cloudian admin API refused because the HyperStore license capacity is exceeded.",
+			}, []string{"method", "path"})),
+		}
+	}
+}
+
+// register registers a collector, or returns the identical one already
+// registered by another client.
+func register[T prometheus.Collector](registry prometheus.Registerer, c T) T {
+	var are prometheus.AlreadyRegisteredError
+	if err := registry.Register(c); errors.As(err, &are) {
+		if existing, ok := are.ExistingCollector.(T); ok {
+			return existing
+		}
+	}
+	return c
+}
+
+// observe records a request that took `d`. Requests that got no response
+// have code "error".
+func (m *apiMetrics) observe(method, path string, code int, d time.Duration) {
+	if m == nil {
+		return
+	}
+	status := "error"
+	if code != 0 {
+		status = strconv.Itoa(code)
+	}
+	m.duration.WithLabelValues(method, path, status).Observe(d.Seconds())
+	m.requests.WithLabelValues(method, path, status).Inc()
+}
+
+// observeSkew records the estimated clock skew of the admin API at `endpoint`.
+func (m *apiMetrics) observeSkew(endpoint string, skew time.Duration) {
+	if m == nil {
+		return
+	}
+	m.skew.WithLabelValues(endpoint).Set(skew.Seconds())
+}
+
+// observeLicenseExceeded records a request refused because the HyperStore
+// license capacity is exceeded.
+func (m *apiMetrics) observeLicenseExceeded(method, path string) {
+	if m == nil {
+		return
+	}
+	m.license.WithLabelValues(method, path).Inc()
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"strconv"
+
+	"github.com/go-resty/resty/v2"
 )
 
 const DefaultRegion = ""
@@ -139,7 +141,10 @@ func (qos *QualityOfService) queryParams(params map[string]string) error {
 // Default user-level QoS for the whole region (GroupID="*", UserID="ALL")
 // Group-level QoS for a specific group (GroupID="<groupId>", UserID="*")
 // Default group-level QoS for the whole region (GroupID="ALL", UserID="*")
-func (client Client) SetQOS(ctx context.Context, guid GroupUserID, region string, qos QualityOfService) error {
+func (client Client) SetQOS(ctx context.Context, guid GroupUserID, region string, qos QualityOfService) (err error) {
+	ctx, span := client.startSpan(ctx, "SetQOS", AttributeRegion.String(region))
+	defer span.end(&err)
+
 	for _, val := range qos.rawQueryParams() {
 		if val != nil && *val < -1 {
 			return fmt.Errorf("QoS limit values must be >= -1")
@@ -160,83 +165,66 @@ func (client Client) SetQOS(ctx context.Context, guid GroupUserID, region string
 		params["region"] = region
 	}
 
-	resp, err := client.newRequest(ctx).
+	req := client.newRequest(ctx).
 		SetQueryParam("userId", guid.UserID).
 		SetQueryParam("groupId", guid.GroupID).
-		SetQueryParams(params).
-		Post("/qos/limits")
-	if err != nil {
-		return err
-	}
-
-	switch resp.StatusCode() {
-	case 200:
-		return nil
-	default:
-		return fmt.Errorf("POST quota unexpected status: %d", resp.StatusCode())
-	}
+		SetQueryParams(params)
+	_, err = client.doJSON(req, resty.MethodPost, "/qos/limits", 200)
+	return err
 }
 
 // SetQOS gets QualityOfService limits for a Group or User, depending on the value of GroupID and UserID.
 // See SetQOS for details.
-func (client Client) GetQOS(ctx context.Context, guid GroupUserID, region string) (*QualityOfService, error) {
+func (client Client) GetQOS(ctx context.Context, guid GroupUserID, region string) (_ *QualityOfService, err error) {
+	ctx, span := client.startSpan(ctx, "GetQOS", AttributeRegion.String(region))
+	defer span.end(&err)
+
 	params := make(map[string]string)
 	if region != DefaultRegion {
 		params["region"] = region
 	}
 
-	resp, err := client.newRequest(ctx).
+	req := client.newRequest(ctx).
 		SetQueryParam("userId", guid.UserID).
 		SetQueryParam("groupId", guid.GroupID).
-		SetQueryParams(params).
-		Get("/qos/limits")
+		SetQueryParams(params)
+	resp, err := client.doJSON(req, resty.MethodGet, "/qos/limits", 200)
 	if err != nil {
 		return nil, err
 	}
 
-	switch resp.StatusCode() {
-	case 200:
-		qos := &QualityOfService{}
-		if err := qos.unmarshalQOSList(resp.Body()); err != nil {
-			return nil, err
-		}
-
-		if qos.allMinusOne() {
-			return nil, ErrNotFound
-		}
-		if qos.Warning.RequestsPerMin != nil &&
-			*qos.Warning.RequestsPerMin == -2 {
-			unlimited := int64(-1)
-			qos.Warning.RequestsPerMin = &unlimited
-		}
+	qos := &QualityOfService{}
+	if err := qos.unmarshalQOSList(resp.Body()); err != nil {
+		return nil, err
+	}
 
-		return qos, nil
-	default:
-		return nil, fmt.Errorf("GET quota unexpected status: %d", resp.StatusCode())
+	if qos.allMinusOne() {
+		return nil, ErrNotFound
 	}
+	if qos.Warning.RequestsPerMin != nil &&
+		*qos.Warning.RequestsPerMin == -2 {
+		unlimited := int64(-1)
+		qos.Warning.RequestsPerMin = &unlimited
+	}
+
+	return qos, nil
 }
 
 // DeleteQOS deletes QualityOfService limits for a Group or User, depending on the value of GroupID and UserID.
 // See SetQOS for details.
-func (client Client) DeleteQOS(ctx context.Context, guid GroupUserID, region string) error {
+func (client Client) DeleteQOS(ctx context.Context, guid GroupUserID, region string) (err error) {
+	ctx, span := client.startSpan(ctx, "DeleteQOS", AttributeRegion.String(region))
+	defer span.end(&err)
+
 	params := make(map[string]string)
 	if region != DefaultRegion {
 		params["region"] = region
 	}
 
-	resp, err := client.newRequest(ctx).
+	req := client.newRequest(ctx).
 		SetQueryParam("userId", guid.UserID).
 		SetQueryParam("groupId", guid.GroupID).
-		SetQueryParams(params).
-		Delete("/qos/limits")
-	if err != nil {
-		return err
-	}
-
-	switch resp.StatusCode() {
-	case 200:
-		return nil
-	default:
-		return fmt.Errorf("DELETE quota unexpected status: %d", resp.StatusCode())
-	}
+		SetQueryParams(params)
+	_, err = client.doJSON(req, resty.MethodDelete, "/qos/limits", 200)
+	return err
 }
@@ -0,0 +1,72 @@
+package cloudian
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// DefaultRatingPlanID is the rating plan Cloudian assigns to groups that have
+// not been assigned one.
+const DefaultRatingPlanID = "Default-RP"
+
+// AssignRatingPlanToGroup assigns a rating plan to a group within a region.
+func (client Client) AssignRatingPlanToGroup(ctx context.Context, groupID, ratingPlanID, region string) (err error) {
+	ctx, span := client.startSpan(ctx, "AssignRatingPlanToGroup", AttributeRegion.String(region))
+	defer span.end(&err)
+
+	if err := ValidateGroupID(groupID); err != nil {
+		return err
+	}
+
+	params := map[string]string{
+		paramGroupID:   groupID,
+		"ratingPlanId": ratingPlanID,
+	}
+	if region != DefaultRegion {
+		params["region"] = region
+	}
+
+	req := client.newRequest(ctx).
+		SetQueryParams(params)
+	_, err = client.doJSON(req, resty.MethodPost, "/ratingPlan/group", 200)
+	return err
+}
+
+// GetGroupRatingPlan gets the ID of the rating plan assigned to a group within
+// a region. Returns ErrNotFound if the group does not exist.
+func (client Client) GetGroupRatingPlan(ctx context.Context, groupID, region string) (_ string, err error) {
+	ctx, span := client.startSpan(ctx, "GetGroupRatingPlan", AttributeRegion.String(region))
+	defer span.end(&err)
+
+	if err := ValidateGroupID(groupID); err != nil {
+		return "", err
+	}
+
+	params := map[string]string{paramGroupID: groupID}
+	if region != DefaultRegion {
+		params["region"] = region
+	}
+
+	req := client.client.R().
+		SetContext(ctx).
+		SetQueryParams(params)
+	resp, err := client.doJSON(req, resty.MethodGet, "/ratingPlan/group", 200, 204)
+	if err != nil {
+		return "", err
+	}
+
+	if resp.StatusCode() == 204 {
+		// Cloudian-API returns 204 if the group does not exist
+		return "", ErrNotFound
+	}
+
+	// The ID is returned as plain text, which may be quoted.
+	id := strings.TrimSpace(resp.String())
+	if unquoted, err := strconv.Unquote(id); err == nil {
+		id = unquoted
+	}
+	return id, nil
+}
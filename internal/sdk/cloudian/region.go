@@ -0,0 +1,39 @@
+package cloudian
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// ErrUnknownRegion is returned when selecting a region the client has no
+// admin API endpoint for.
+var ErrUnknownRegion = errors.New("unknown region")
+
+// WithRegion routes the calls for `region` to the admin API of `regional`,
+// for installations where each region has its own admin endpoint.
+func WithRegion(region string, regional *Client) func(*Client) {
+	return func(c *Client) {
+		if c.regions == nil {
+			c.regions = map[string]*Client{}
+		}
+		c.regions[region] = regional
+	}
+}
+
+// ForRegion returns the client of the admin API of `region`. The default
+// region is served by this client, as is every region when the client was
+// given none with WithRegion. Otherwise regions it was not given fail with
+// ErrUnknownRegion.
+func (client Client) ForRegion(region string) (*Client, error) {
+	if region == DefaultRegion || len(client.regions) == 0 {
+		return &client, nil
+	}
+	if regional, ok := client.regions[region]; ok {
+		return regional, nil
+	}
+	known := slices.Sorted(maps.Keys(client.regions))
+	return nil, fmt.Errorf("%w %q, must be one of %s", ErrUnknownRegion, region, strings.Join(known, ", "))
+}
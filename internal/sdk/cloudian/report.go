@@ -0,0 +1,69 @@
+package cloudian
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// A Report is the outcome of an operation on many objects, which attempts
+// all of them even when some fail.
+type Report struct {
+	// Succeeded are the IDs of the objects the operation succeeded for.
+	Succeeded []string
+
+	// Failed are the errors of the objects the operation failed for, by ID.
+	Failed map[string]error
+}
+
+func (r *Report) succeed(id string) {
+	r.Succeeded = append(r.Succeeded, id)
+}
+
+func (r *Report) fail(id string, err error) {
+	if r.Failed == nil {
+		r.Failed = map[string]error{}
+	}
+	r.Failed[id] = err
+}
+
+// failedIDs returns the IDs of the failed objects in a stable order.
+func (r Report) failedIDs() []string {
+	ids := make([]string, 0, len(r.Failed))
+	for id := range r.Failed {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// Err returns the errors of the failed objects joined in the order of their
+// IDs, or nil if none failed.
+func (r Report) Err() error {
+	errs := make([]error, 0, len(r.Failed))
+	for _, id := range r.failedIDs() {
+		errs = append(errs, r.Failed[id])
+	}
+	return errors.Join(errs...)
+}
+
+// Summary describes the failures on a single line, short enough for a
+// condition message. At most `maxItems` errors are included, in the order of
+// their IDs, followed by how many were left out.
+func (r Report) Summary(maxItems int) string {
+	ids := r.failedIDs()
+	if len(ids) == 0 {
+		return fmt.Sprintf("all %d succeeded", len(r.Succeeded))
+	}
+
+	shown := ids[:min(max(maxItems, 0), len(ids))]
+	msgs := make([]string, 0, len(shown)+1)
+	for _, id := range shown {
+		msgs = append(msgs, r.Failed[id].Error())
+	}
+	if more := len(ids) - len(shown); more > 0 {
+		msgs = append(msgs, fmt.Sprintf("and %d more", more))
+	}
+	return fmt.Sprintf("%d of %d failed: %s", len(ids), len(ids)+len(r.Succeeded), strings.Join(msgs, "; "))
+}
@@ -0,0 +1,42 @@
+package cloudian
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader is the header carrying the ID of each request to the admin
+// API when enabled with WithRequestIDs, so that the requests can be found in
+// the access logs of Cloudian and of any proxy in front of it.
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDKey holds the request ID of a context.
+type requestIDKey struct{}
+
+// WithRequestID returns a context whose requests to the admin API carry `id`
+// in the RequestIDHeader, so that all the requests made for the same purpose,
+// such as one reconcile, share it.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID of a context, or "" if it has none.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// NewRequestID returns a fresh random request ID.
+func NewRequestID() string {
+	return uuid.NewString()
+}
+
+// WithRequestIDs makes every request to the admin API carry a
+// RequestIDHeader: the request ID of its context when it has one, otherwise a
+// fresh one.
+func WithRequestIDs() func(*Client) {
+	return func(c *Client) {
+		c.requestIDs = true
+	}
+}
@@ -0,0 +1,134 @@
+package cloudian
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"k8s.io/utils/clock"
+)
+
+// idempotency tells whether repeating a request to the admin API is safe,
+// for the requests that are not reads or deletions. Cloudian creates objects
+// with PUT and updates them with POST.
+var idempotency = map[string]bool{
+	// Creating a user or group again fails, as it exists.
+	resty.MethodPut + " /user":  false,
+	resty.MethodPut + " /group": false,
+	// Cloudian generates another key pair each time.
+	resty.MethodPut + " /user/credentials": false,
+	// Updates.
+	resty.MethodPost + " /user":             true,
+	resty.MethodPost + " /group":            true,
+	resty.MethodPost + " /qos/limits":       true,
+	resty.MethodPost + " /ratingPlan/group": true,
+	// The keys are given by the caller, so Cloudian does not create another
+	// set.
+	resty.MethodPost + " /user/credentials": true,
+	// Reads the usage of the buckets in the body.
+	resty.MethodPost + " /usage/bucket": true,
+}
+
+// Idempotent returns whether a request to the admin API may be repeated
+// without taking effect twice, or failing after having taken effect. Reads
+// and deletions are, and so are the requests known to update or read.
+func Idempotent(method, path string) bool {
+	if idempotent, ok := idempotency[method+" "+path]; ok {
+		return idempotent
+	}
+	return method == resty.MethodGet || method == resty.MethodHead || method == resty.MethodDelete
+}
+
+// retryConfig is how a client retries requests failing transiently. The zero
+// value retries nothing.
+type retryConfig struct {
+	attempts int
+	backoff  time.Duration
+}
+
+// WithRetries retries the requests failing transiently, up to `attempts`
+// attempts in total, waiting `backoff` before the first retry and twice as
+// long before each next one, or as long as Cloudian asks when throttled. Each
+// wait is stretched by up to a fifth at random, so that the clients retrying
+// after the same failure do not all retry at once.
+// Only requests that are Idempotent are retried once they may have reached
+// Cloudian, unless their context allows it with AllowRetry.
+func WithRetries(attempts int, backoff time.Duration) func(*Client) {
+	return func(c *Client) {
+		c.retries = retryConfig{attempts: attempts, backoff: backoff}
+	}
+}
+
+type allowRetryKey struct{}
+
+// AllowRetry returns a context whose requests are retried by a client with
+// WithRetries even when they are not Idempotent, for callers that do not mind
+// a request taking effect twice, or can tell whether it did.
+func AllowRetry(ctx context.Context) context.Context {
+	return context.WithValue(ctx, allowRetryKey{}, true)
+}
+
+// next returns how long to wait before retrying a request that failed with
+// `err` after `attempt` attempts, or false if it must not be retried.
+func (cfg retryConfig) next(ctx context.Context, method, path string, attempt int, err error) (time.Duration, bool) {
+	if err == nil || attempt >= cfg.attempts || ctx.Err() != nil {
+		return 0, false
+	}
+	wait := cfg.backoff << (attempt - 1)
+	wait += rand.N(wait/5 + 1)
+
+	var throttled *ThrottledError
+	if errors.As(err, &throttled) {
+		// Cloudian refused the request, so repeating it is safe.
+		return max(wait, throttled.RetryAfter), true
+	}
+	if !transient(err) {
+		return 0, false
+	}
+	if allowed, _ := ctx.Value(allowRetryKey{}).(bool); !allowed && !Idempotent(method, path) {
+		return 0, false
+	}
+	return wait, true
+}
+
+// transient returns whether a request failing with `err` may succeed when
+// repeated: when it failed to reach Cloudian or to get an answer in time, or
+// when Cloudian or a proxy in front of it was unavailable.
+func transient(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		return false
+	}
+	switch statusErr.StatusCode {
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// sleep waits for `d` on the clock of the client, or returns the error of the
+// context if it is done first. A clock set with WithClock that has no timers
+// only tells the time, and the real clock is slept on.
+func (client Client) sleep(ctx context.Context, d time.Duration) error {
+	clk, ok := client.clock.(clock.WithTicker)
+	if !ok {
+		clk = clock.RealClock{}
+	}
+	t := clk.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C():
+		return nil
+	}
+}
@@ -0,0 +1,115 @@
+package cloudian
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// S3EndpointsTTL is how long GetS3Endpoints caches the S3 endpoints of the
+// cluster, which only change when the cluster is reconfigured.
+const S3EndpointsTTL = time.Hour
+
+// S3Endpoints are the S3 endpoints of a Cloudian cluster, by region.
+type S3Endpoints struct {
+	Regions []RegionS3Endpoints
+}
+
+// RegionS3Endpoints are the S3 endpoints of one region of a Cloudian cluster,
+// as listed by the system configuration of the admin API.
+type RegionS3Endpoints struct {
+	Region  string   `json:"regionName"`
+	HTTP    []string `json:"s3EndpointsHTTP"`
+	HTTPS   []string `json:"s3EndpointsHTTPS"`
+	Website []string `json:"s3WebSiteEndpoints"`
+}
+
+// UniformS3Endpoints returns the S3 endpoints of a cluster whose `endpoints`
+// serve HTTP, HTTPS and website requests alike, as the S3 endpoints of a
+// ProviderConfig do.
+func UniformS3Endpoints(endpoints []string) S3Endpoints {
+	if len(endpoints) == 0 {
+		return S3Endpoints{}
+	}
+	return S3Endpoints{Regions: []RegionS3Endpoints{{HTTP: endpoints, HTTPS: endpoints, Website: endpoints}}}
+}
+
+// HTTP returns the HTTP endpoints of every region, sorted and without
+// duplicates.
+func (e S3Endpoints) HTTP() []string {
+	return e.collect(func(r RegionS3Endpoints) []string { return r.HTTP })
+}
+
+// HTTPS returns the HTTPS endpoints of every region, sorted and without
+// duplicates.
+func (e S3Endpoints) HTTPS() []string {
+	return e.collect(func(r RegionS3Endpoints) []string { return r.HTTPS })
+}
+
+// Website returns the website endpoints of every region, sorted and without
+// duplicates.
+func (e S3Endpoints) Website() []string {
+	return e.collect(func(r RegionS3Endpoints) []string { return r.Website })
+}
+
+func (e S3Endpoints) collect(endpoints func(RegionS3Endpoints) []string) []string {
+	var all []string
+	for _, r := range e.Regions {
+		all = append(all, endpoints(r)...)
+	}
+	return NormalizeSet(all)
+}
+
+// s3EndpointsCache holds the S3 endpoints of the cluster once they have been
+// read successfully, until they expire.
+type s3EndpointsCache struct {
+	mu        sync.Mutex
+	endpoints *S3Endpoints
+	expires   time.Time
+}
+
+// GetS3Endpoints returns the S3 endpoints of the cluster, from its system
+// configuration. The result is cached for S3EndpointsTTL, unless reading it
+// fails. An admin API without the system configuration reports no endpoints.
+func (client Client) GetS3Endpoints(ctx context.Context) (_ S3Endpoints, err error) {
+	ctx, span := client.startSpan(ctx, "GetS3Endpoints")
+	defer span.end(&err)
+
+	client.s3Endpoints.mu.Lock()
+	defer client.s3Endpoints.mu.Unlock()
+
+	now := client.clock.Now()
+	if e := client.s3Endpoints.endpoints; e != nil && now.Before(client.s3Endpoints.expires) {
+		return *e, nil
+	}
+
+	req := client.newRequest(ctx)
+	resp, err := client.doJSON(req, resty.MethodGet, "/system/s3endpoints", 200, 204, 404)
+	if err != nil {
+		return S3Endpoints{}, err
+	}
+
+	var e S3Endpoints
+	if resp.StatusCode() == 200 && len(resp.Body()) > 0 {
+		if err := json.Unmarshal(resp.Body(), &e.Regions); err != nil {
+			return S3Endpoints{}, err
+		}
+	}
+	for i, r := range e.Regions {
+		e.Regions[i].HTTP = slices.DeleteFunc(r.HTTP, isAllEndpoints)
+		e.Regions[i].HTTPS = slices.DeleteFunc(r.HTTPS, isAllEndpoints)
+		e.Regions[i].Website = slices.DeleteFunc(r.Website, isAllEndpoints)
+	}
+
+	client.s3Endpoints.endpoints = &e
+	client.s3Endpoints.expires = now.Add(S3EndpointsTTL)
+	return e, nil
+}
+
+func isAllEndpoints(endpoint string) bool {
+	return endpoint == AllEndpoints
+}
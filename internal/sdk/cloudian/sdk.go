@@ -1,23 +1,118 @@
 package cloudian
 
 import (
+	"bytes"
+	"cmp"
 	"context"
 	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"iter"
+	"log"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-resty/resty/v2"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/utils/clock"
+	"k8s.io/utils/ptr"
+
+	"github.com/statnett/provider-cloudian/internal/version"
 )
 
 const (
 	ListLimit = 100
 
+	// MaxKeys is the most credentials ListUserCredentials lists for a user.
+	// Cloudian allows few credentials per user, so more are not expected.
+	MaxKeys = 1000
+
+	// DefaultCallTimeout is the deadline applied to each request whose
+	// context has none.
+	DefaultCallTimeout = 30 * time.Second
+
 	paramGroupID = "groupId"
+	paramPrefix  = "prefix"
 )
 
 type Client struct {
-	client *resty.Client
+	client       *resty.Client
+	warnf        func(format string, v ...any)
+	callTimeout  time.Duration
+	groups       *groupCache
+	capabilities *capabilitiesCache
+	s3Endpoints  *s3EndpointsCache
+	metrics      *apiMetrics
+	userAgent    string
+	requestIDs   bool
+	tracer       trace.Tracer
+	retries      retryConfig
+	skew         *clockSkew
+	clock        clock.PassiveClock
+	regions      map[string]*Client
+	rateLimit    rateConfig
+	http         httpConfig
+	// ownTransport is whether the transport of the client is its own,
+	// rather than one shared with other clients.
+	ownTransport bool
+}
+
+// httpConfig is what the HTTP client of a Client is built from once all the
+// options are applied, so that the options compose regardless of their order.
+type httpConfig struct {
+	client  *http.Client
+	tls     *tls.Config
+	dial    DialContextFunc
+	timeout time.Duration
+}
+
+// build returns the HTTP client configured by the options, and whether its
+// transport is its own rather than a shared one.
+func (cfg httpConfig) build() (*http.Client, bool) {
+	hc := &http.Client{}
+	if cfg.client != nil {
+		copied := *cfg.client
+		hc = &copied
+	}
+	if cfg.tls != nil || cfg.dial != nil {
+		var transport *http.Transport
+		switch t := hc.Transport.(type) {
+		case nil:
+			transport = http.DefaultTransport.(*http.Transport).Clone()
+		case *http.Transport:
+			transport = t.Clone()
+		}
+		if transport != nil {
+			if cfg.tls != nil {
+				transport.TLSClientConfig = cfg.tls
+			}
+			if cfg.dial != nil {
+				transport.DialContext = cfg.dial
+			}
+			hc.Transport = transport
+		}
+	}
+	if cfg.timeout > 0 {
+		hc.Timeout = cfg.timeout
+	}
+	_, own := hc.Transport.(*http.Transport)
+	return hc, own && hc.Transport != cfg.transport()
+}
+
+// transport returns the transport of the HTTP client supplied by the options,
+// which other clients may share.
+func (cfg httpConfig) transport() http.RoundTripper {
+	if cfg.client == nil {
+		return nil
+	}
+	return cfg.client.Transport
 }
 
 type Group struct {
@@ -31,6 +126,78 @@ type Group struct {
 	LDAPSearchUserBase string `json:"ldapSearchUserBase"`
 	LDAPServerURL      string `json:"ldapServerURL"`
 	LDAPUserDNTemplate string `json:"ldapUserDNTemplate"`
+	// S3EndpointsHTTP, S3EndpointsHTTPS and S3WebSiteEndpoints are the S3
+	// endpoints members of the group may use. Empty allows every endpoint.
+	// They are sets: Cloudian neither preserves nor uses their order.
+	S3EndpointsHTTP    []string `json:"s3EndpointsHTTP"`
+	S3EndpointsHTTPS   []string `json:"s3EndpointsHTTPS"`
+	S3WebSiteEndpoints []string `json:"s3WebSiteEndpoints"`
+}
+
+// AllEndpoints is how Cloudian allows every S3 endpoint to a group.
+const AllEndpoints = "ALL"
+
+// Equal returns whether two groups are the same, comparing their S3
+// endpoints with EqualEndpoints, so that their order and duplicates are
+// irrelevant. `all` are the S3 endpoints of the cluster, when known.
+func (g Group) Equal(o Group, all ...string) bool {
+	return g.EqualIn(o, UniformS3Endpoints(all))
+}
+
+// EqualIn is like Equal for a cluster with `all` S3 endpoints, so that
+// allowing each of the endpoints of a kind matches allowing all of them.
+func (g Group) EqualIn(o Group, all S3Endpoints) bool {
+	if !EqualEndpoints(g.S3EndpointsHTTP, o.S3EndpointsHTTP, all.HTTP()...) ||
+		!EqualEndpoints(g.S3EndpointsHTTPS, o.S3EndpointsHTTPS, all.HTTPS()...) ||
+		!EqualEndpoints(g.S3WebSiteEndpoints, o.S3WebSiteEndpoints, all.Website()...) {
+		return false
+	}
+	g.S3EndpointsHTTP, g.S3EndpointsHTTPS, g.S3WebSiteEndpoints = nil, nil, nil
+	o.S3EndpointsHTTP, o.S3EndpointsHTTPS, o.S3WebSiteEndpoints = nil, nil, nil
+	return reflect.DeepEqual(g, o)
+}
+
+// EqualEndpoints returns whether two lists of S3 endpoints allow the same
+// endpoints, once normalized with NormalizeEndpoints.
+func EqualEndpoints(a, b []string, all ...string) bool {
+	return slices.Equal(NormalizeEndpoints(a, all...), NormalizeEndpoints(b, all...))
+}
+
+// NormalizeEndpoints returns a sorted list of S3 endpoints without
+// duplicates. Lists that are empty or include AllEndpoints are normalized to
+// just AllEndpoints, and so are lists including every one of `all`, the S3
+// endpoints of the cluster, when known.
+func NormalizeEndpoints(endpoints []string, all ...string) []string {
+	normalized := NormalizeSet(endpoints)
+	covered := len(all) > 0 && !slices.ContainsFunc(all, func(e string) bool {
+		_, found := slices.BinarySearch(normalized, e)
+		return !found
+	})
+	if len(normalized) == 0 || slices.Contains(normalized, AllEndpoints) || covered {
+		return []string{AllEndpoints}
+	}
+	return normalized
+}
+
+// NormalizeSet returns a sorted copy of a slice without duplicates, for
+// slices whose order Cloudian does not preserve or give a meaning to.
+func NormalizeSet[S ~[]E, E cmp.Ordered](s S) S {
+	normalized := slices.Clone(s)
+	slices.Sort(normalized)
+	return slices.Compact(normalized)
+}
+
+// EqualSets returns whether two slices have the same elements, regardless of
+// their order and duplicates. Nil and empty slices are equal.
+func EqualSets[S ~[]E, E cmp.Ordered](a, b S) bool {
+	return slices.Equal(NormalizeSet(a), NormalizeSet(b))
+}
+
+func endpointsOrAll(endpoints []string) []string {
+	if len(endpoints) == 0 {
+		return []string{AllEndpoints}
+	}
+	return endpoints
 }
 
 // groupInternal is the SDK's internal representation of a cloudion group.
@@ -70,9 +237,9 @@ func toInternal(g Group) groupInternal {
 		LDAPSearchUserBase: g.LDAPSearchUserBase,
 		LDAPServerURL:      g.LDAPServerURL,
 		LDAPUserDNTemplate: g.LDAPUserDNTemplate,
-		S3EndpointsHTTP:    []string{"ALL"},
-		S3EndpointsHTTPS:   []string{"ALL"},
-		S3WebSiteEndpoints: []string{"ALL"},
+		S3EndpointsHTTP:    endpointsOrAll(g.S3EndpointsHTTP),
+		S3EndpointsHTTPS:   endpointsOrAll(g.S3EndpointsHTTPS),
+		S3WebSiteEndpoints: endpointsOrAll(g.S3WebSiteEndpoints),
 	}
 }
 
@@ -88,6 +255,9 @@ func fromInternal(g groupInternal) Group {
 		LDAPSearchUserBase: g.LDAPSearchUserBase,
 		LDAPServerURL:      g.LDAPServerURL,
 		LDAPUserDNTemplate: g.LDAPUserDNTemplate,
+		S3EndpointsHTTP:    endpointsOrAll(g.S3EndpointsHTTP),
+		S3EndpointsHTTPS:   endpointsOrAll(g.S3EndpointsHTTPS),
+		S3WebSiteEndpoints: endpointsOrAll(g.S3WebSiteEndpoints),
 	}
 }
 
@@ -99,6 +269,16 @@ const (
 	UserTypeStandard    UserType = "User"
 )
 
+// UserStatus is the status of a user. Cloudian may report statuses other than
+// the ones below.
+type UserStatus string
+
+const (
+	UserStatusActive   UserStatus = "Active"
+	UserStatusInactive UserStatus = "Inactive"
+	UserStatusLocked   UserStatus = "Locked"
+)
+
 type GroupUserID struct {
 	GroupID string `json:"groupId"`
 	UserID  string `json:"userId"`
@@ -106,309 +286,1055 @@ type GroupUserID struct {
 
 type User struct {
 	GroupUserID `json:",inline"`
-	UserType    UserType `json:"userType"`
-	CanonicalID string   `json:"canonicalUserId,omitempty"`
+	UserType    UserType   `json:"userType"`
+	CanonicalID string     `json:"canonicalUserId,omitempty"`
+	Address2    string     `json:"address2,omitempty"`
+	Status      UserStatus `json:"userStatus,omitempty"`
 }
 
 // SecurityInfo is the Cloudian API's term for secure credentials
 type SecurityInfo struct {
 	AccessKey string `json:"accessKey"` // #nosec G117 -- AccessKey is intentionally part of API payload
-	SecretKey string `json:"secretKey"`
+	SecretKey Secret `json:"secretKey"`
 }
 
 var ErrNotFound = errors.New("not found")
 
+// ErrTooManyCredentials is returned when a user has more than MaxKeys credentials.
+var ErrTooManyCredentials = errors.New("too many credentials")
+
+// ErrUserHasCredentials is returned when refusing to delete a user that still has credentials.
+var ErrUserHasCredentials = errors.New("user has credentials")
+
+// ErrLicenseExceeded is returned along with the StatusError when Cloudian
+// refuses a request because the capacity of the HyperStore license is
+// exceeded. Retrying does not help until capacity is added.
+var ErrLicenseExceeded = errors.New("HyperStore license capacity exceeded")
+
+// ErrKeyQuotaExceeded is returned along with the StatusError when Cloudian
+// refuses to create credentials because the user already has as many
+// credentials as allowed. Retrying does not help until some are deleted.
+var ErrKeyQuotaExceeded = errors.New("access key quota exceeded")
+
+// ErrConflict is returned by UpdateGroupIfMatches when the group changed since
+// it was observed, as when another replica of the provider updated it.
+var ErrConflict = errors.New("group changed since it was observed")
+
+// ErrGroupNotEmpty is returned along with the StatusError when Cloudian
+// refuses to delete a group because it still has users. Retrying does not
+// help until they are deleted.
+var ErrGroupNotEmpty = errors.New("group still has users")
+
+// ErrGroupNotFound is returned along with the StatusError when Cloudian
+// refuses to create a user because its group does not exist. Retrying helps
+// once the group is created.
+var ErrGroupNotFound = errors.New("group does not exist")
+
+// ErrUserNotFound is returned along with the StatusError when Cloudian
+// refuses to create credentials because their user does not exist. Retrying
+// helps once the user is created.
+var ErrUserNotFound = errors.New("user does not exist")
+
+// ThrottledError is returned along with the StatusError when Cloudian responds
+// 429 Too Many Requests. RetryAfter is how long Cloudian asked to wait before
+// retrying, or zero if it did not say.
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	if e.RetryAfter <= 0 {
+		return "throttled"
+	}
+	return fmt.Sprintf("throttled, retry after %s", e.RetryAfter)
+}
+
+// StatusError is returned when the Cloudian API responds with a non-2xx status
+// code that the endpoint is not expected to respond with.
+type StatusError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	// Reason is what Cloudian said about the error in the body of its
+	// response, such as an invalid group ID or a missing region, if
+	// anything.
+	Reason string
+}
+
+func (e *StatusError) Error() string {
+	msg := fmt.Sprintf("%s %s unexpected status: %d", e.Method, e.Path, e.StatusCode)
+	if e.Reason == "" {
+		return msg
+	}
+	return msg + ": " + e.Reason
+}
+
+// WithRateLimit limits the rate of requests sent to the Cloudian API to `rps`
+// requests per second, allowing bursts of up to `burst` requests. Requests wait
+// for their turn until their context is done. Background requests are limited
+// to a budget of the rate, see WithBackgroundBudget.
+func WithRateLimit(rps float64, burst int) func(*Client) {
+	return func(c *Client) {
+		c.rateLimit.rps, c.rateLimit.burst = rps, burst
+	}
+}
+
+// WithBackgroundBudget limits the requests made with a Background context to
+// `fraction` of the rate limit, and their bursts to `fraction` of the burst,
+// instead of DefaultBackgroundBudget. It has no effect without WithRateLimit.
+func WithBackgroundBudget(fraction float64) func(*Client) {
+	return func(c *Client) {
+		c.rateLimit.background = fraction
+	}
+}
+
+// WithCACert verifies the server certificate against the PEM encoded CA
+// certificates in `pem` instead of the system CA certificates.
+func WithCACert(pem []byte) func(*Client) {
+	return func(c *Client) {
+		pool := x509.NewCertPool()
+		pool.AppendCertsFromPEM(pem)
+		c.updateTLS(func(cfg *tls.Config) { cfg.RootCAs = pool })
+	}
+}
+
+// WithServerName verifies that the server certificate is valid for `name`
+// instead of the hostname of the endpoint. The certificate chain is still
+// verified.
+func WithServerName(name string) func(*Client) {
+	return func(c *Client) {
+		c.updateTLS(func(cfg *tls.Config) { cfg.ServerName = name })
+	}
+}
+
 // WithInsecureTLSVerify skips the TLS validation of the server certificate when `insecure` is true.
 func WithInsecureTLSVerify(insecure bool) func(*Client) {
 	return func(c *Client) {
-		c.client.SetTLSClientConfig(&tls.Config{InsecureSkipVerify: insecure}) //nolint:gosec
+		c.updateTLS(func(cfg *tls.Config) { cfg.InsecureSkipVerify = insecure }) //nolint:gosec
+	}
+}
+
+// updateTLS applies `update` to the TLS configuration of the client.
+func (client *Client) updateTLS(update func(*tls.Config)) {
+	if client.http.tls == nil {
+		client.http.tls = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	update(client.http.tls)
+}
+
+// WithTimeout limits the time of each HTTP request, including reading the
+// response body, to `d`, regardless of the request context.
+func WithTimeout(d time.Duration) func(*Client) {
+	return func(c *Client) {
+		c.http.timeout = d
+	}
+}
+
+// WithHTTPClient sends the requests with a copy of `hc`, such as one with an
+// instrumented round tripper. The other options still apply to the copy, but
+// the TLS and dial options only when its Transport is nil or an
+// *http.Transport.
+func WithHTTPClient(hc *http.Client) func(*Client) {
+	return func(c *Client) {
+		c.http.client = hc
+	}
+}
+
+// WithCallTimeout sets the deadline applied to each request whose context has
+// none. It defaults to DefaultCallTimeout. A zero `d` disables the deadline.
+func WithCallTimeout(d time.Duration) func(*Client) {
+	return func(c *Client) {
+		c.callTimeout = d
+	}
+}
+
+// WithGroupCache caches groups read by GetGroup for `ttl`. Cached groups are
+// invalidated when the client creates, updates or deletes them.
+func WithGroupCache(ttl time.Duration) func(*Client) {
+	return func(c *Client) {
+		c.groups = newGroupCache(ttl)
+	}
+}
+
+// WithClock sets the time source of the client, so that tests control the
+// expiry of cached groups, the estimated clock skew and, when the clock also
+// has timers, the waits between retries.
+func WithClock(clock clock.PassiveClock) func(*Client) {
+	return func(c *Client) {
+		c.clock = clock
+	}
+}
+
+// DefaultUserAgent identifies the requests of this provider in the access logs
+// of Cloudian, unless overridden with WithUserAgent.
+var DefaultUserAgent = "provider-cloudian/" + version.Version
+
+// WithUserAgent sets the User-Agent header of the requests to the admin API.
+func WithUserAgent(ua string) func(*Client) {
+	return func(c *Client) {
+		c.userAgent = ua
+	}
+}
+
+// BaseURL returns the base URL of the admin API at `endpoint`, an url with
+// protocol, hostname, port and optionally a path, with the path segments of
+// `pathPrefix` joined to its path, as when the admin API is served behind a
+// reverse proxy. Trailing slashes are dropped, so that the paths of requests
+// can be appended.
+func BaseURL(endpoint, pathPrefix string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid endpoint %q: %w", endpoint, err)
+	}
+	switch {
+	case u.Scheme != "http" && u.Scheme != "https":
+		return "", fmt.Errorf("invalid endpoint %q: want an http or https url", endpoint)
+	case u.Host == "":
+		return "", fmt.Errorf("invalid endpoint %q: no hostname", endpoint)
+	case u.User != nil, u.RawQuery != "", u.Fragment != "":
+		return "", fmt.Errorf("invalid endpoint %q: want no user, query or fragment", endpoint)
+	}
+	u = u.JoinPath(pathPrefix)
+	u.Path = strings.TrimRight(u.Path, "/")
+	u.RawPath = ""
+	return u.String(), nil
+}
+
+// NewClientWithValidation is like NewClient, but returns an error if
+// `baseURL` is not a valid endpoint of the admin API, rather than failing
+// every request.
+func NewClientWithValidation(baseURL string, authHeader string, opts ...func(*Client)) (*Client, error) {
+	normalized, err := BaseURL(baseURL, "")
+	if err != nil {
+		return nil, err
 	}
+	return NewClient(normalized, authHeader, opts...), nil
 }
 
 func NewClient(baseURL string, authHeader string, opts ...func(*Client)) *Client {
 	c := &Client{
-		client: resty.New().
-			SetBaseURL(baseURL).
-			SetHeader("Authorization", authHeader),
+		warnf:        log.Printf,
+		callTimeout:  DefaultCallTimeout,
+		capabilities: &capabilitiesCache{},
+		s3Endpoints:  &s3EndpointsCache{},
+		userAgent:    DefaultUserAgent,
+		skew:         &clockSkew{},
+		clock:        clock.RealClock{},
 	}
 	for _, opt := range opts {
 		opt(c)
 	}
+	hc, own := c.http.build()
+	c.ownTransport = own
+	c.client = resty.NewWithClient(hc).
+		SetBaseURL(baseURL).
+		SetHeader("Authorization", authHeader).
+		SetHeader("User-Agent", c.userAgent)
+	if c.requestIDs {
+		c.client.OnBeforeRequest(func(_ *resty.Client, r *resty.Request) error {
+			id := RequestID(r.Context())
+			if id == "" {
+				id = NewRequestID()
+			}
+			r.SetHeader(RequestIDHeader, id)
+			return nil
+		})
+	}
+	if limiter := c.rateLimit.build(); limiter != nil {
+		c.client.OnBeforeRequest(func(_ *resty.Client, r *resty.Request) error {
+			return limiter.wait(r.Context())
+		})
+	}
+	if c.groups != nil {
+		c.groups.clock = c.clock
+	}
 	return c
 }
 
-// List all users of a group.
-func (client Client) ListUsers(ctx context.Context, groupID string, userID *string) ([]User, error) {
+// CloseIdleConnections closes the idle connections of the client and of its
+// regions, once it is no longer used. Transports shared with other clients
+// are left alone.
+func (client Client) CloseIdleConnections() {
+	if client.ownTransport {
+		client.client.GetClient().CloseIdleConnections()
+	}
+	for _, region := range client.regions {
+		region.CloseIdleConnections()
+	}
+}
+
+// PageProgress reports the progress of a paginated listing.
+type PageProgress struct {
+	GroupID string
+	// Pages is the number of pages fetched so far.
+	Pages int
+	// Items is the number of items listed so far.
+	Items int
+}
+
+type pageCallbackKey struct{}
+
+type pageCallback struct {
+	every int
+	fn    func(PageProgress)
+}
+
+// WithPageCallback returns a context that makes paginated listings call `fn`
+// after every `every` pages fetched.
+func WithPageCallback(ctx context.Context, every int, fn func(PageProgress)) context.Context {
+	return context.WithValue(ctx, pageCallbackKey{}, pageCallback{every: every, fn: fn})
+}
+
+func reportPage(ctx context.Context, progress PageProgress) {
+	cb, ok := ctx.Value(pageCallbackKey{}).(pageCallback)
+	if !ok || cb.every <= 0 || progress.Pages%cb.every != 0 {
+		return
+	}
+	cb.fn(progress)
+}
+
+// List all users of a group, starting from `userID` if set.
+func (client Client) ListUsers(ctx context.Context, groupID string, userID *string) (_ []User, err error) {
+	ctx, span := client.startSpan(ctx, "ListUsers")
+	defer span.end(&err)
+
+	return client.listUsers(ctx, groupID, "", userID)
+}
+
+// SearchUsers lists the users of a group whose user ID starts with `prefix`.
+func (client Client) SearchUsers(ctx context.Context, groupID, prefix string) (_ []User, err error) {
+	ctx, span := client.startSpan(ctx, "SearchUsers")
+	defer span.end(&err)
+
+	return client.listUsers(ctx, groupID, prefix, nil)
+}
+
+// CountUsers counts the users of a group. As Cloudian does not tell how many
+// users a group has, every page of users is fetched, without keeping them.
+func (client Client) CountUsers(ctx context.Context, groupID string) (_ int, err error) {
+	ctx, span := client.startSpan(ctx, "CountUsers")
+	defer span.end(&err)
+
+	count := 0
+	for _, err := range client.usersIter(ctx, groupID, "", nil) {
+		if err != nil {
+			return 0, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// HasUsers returns whether a group has any users, fetching a single user at
+// most.
+func (client Client) HasUsers(ctx context.Context, groupID string) (_ bool, err error) {
+	ctx, span := client.startSpan(ctx, "HasUsers")
+	defer span.end(&err)
+
+	users, _, err := client.ListUsersPage(ctx, groupID, "", 1)
+	if err != nil {
+		return false, err
+	}
+	return len(users) > 0, nil
+}
+
+func (client Client) listUsers(ctx context.Context, groupID, prefix string, userID *string) ([]User, error) {
+	var users []User
+	for user, err := range client.usersIter(ctx, groupID, prefix, userID) {
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+	return users, nil
+}
+
+// UsersIter iterates over the users of a group, fetching them a page at a
+// time when the previous page has been consumed, so that at most one page is
+// kept in memory however large the group. Stopping the iteration stops the
+// fetching. An error is yielded last.
+func (client Client) UsersIter(ctx context.Context, groupID string) iter.Seq2[User, error] {
+	return client.usersIter(ctx, groupID, "", nil)
+}
+
+func (client Client) usersIter(ctx context.Context, groupID, prefix string, userID *string) iter.Seq2[User, error] {
+	return func(yield func(User, error) bool) {
+		if err := ValidateGroupID(groupID); err != nil {
+			yield(User{}, err)
+			return
+		}
+
+		offset := ptr.Deref(userID, "")
+		items := 0
+		for pages := 1; ; pages++ {
+			page, next, err := client.listUsersPage(ctx, groupID, prefix, offset, ListLimit)
+			if err != nil {
+				yield(User{}, err)
+				return
+			}
+			for _, user := range page {
+				if !yield(user, nil) {
+					return
+				}
+			}
+			items += len(page)
+			if next == "" {
+				return
+			}
+			offset = next
+			reportPage(ctx, PageProgress{GroupID: groupID, Pages: pages, Items: items})
+		}
+	}
+}
+
+// ListUsersPage lists at most `limit` users of a group, starting from the
+// user ID `offset`, or from the first user if empty. It returns the offset of
+// the next page, which is empty on the last page. Cloudian does not tell how
+// many users a group has in total.
+func (client Client) ListUsersPage(ctx context.Context, groupID, offset string, limit int) (_ []User, _ string, err error) {
+	ctx, span := client.startSpan(ctx, "ListUsersPage")
+	defer span.end(&err)
+
+	if err := ValidateGroupID(groupID); err != nil {
+		return nil, "", err
+	}
+	if offset != "" {
+		if err := ValidateUserID(offset); err != nil {
+			return nil, "", fmt.Errorf("offset: %w", err)
+		}
+	}
+	if limit < 1 {
+		return nil, "", fmt.Errorf("limit must be positive, got %d", limit)
+	}
+	return client.listUsersPage(ctx, groupID, "", offset, limit)
+}
+
+// listUsersPage lists a page of at most `limit` users, and returns the
+// offset of the next page, if any.
+func (client Client) listUsersPage(ctx context.Context, groupID, prefix, offset string, limit int) ([]User, string, error) {
 	params := map[string]string{
 		paramGroupID: groupID,
 		"userType":   "all",
 		"userStatus": "all",
-		"limit":      strconv.Itoa(ListLimit),
+		"limit":      strconv.Itoa(limit),
+	}
+	if prefix != "" {
+		params[paramPrefix] = prefix
 	}
-	if userID != nil {
-		params["offset"] = *userID
+	if offset != "" {
+		params["offset"] = offset
 	}
 
 	var users []User
-	_, err := client.newRequest(ctx).
+	req := client.newRequest(ctx).
 		SetQueryParams(params).
-		SetResult(&users).
-		Get("/user/list")
-	if err != nil {
-		return nil, fmt.Errorf("GET list users failed: %w", err)
+		SetResult(&users)
+	if _, err := client.doJSON(req, resty.MethodGet, "/user/list", 200, 204); err != nil {
+		return nil, "", fmt.Errorf("GET list users failed: %w", err)
 	}
 
-	// Paginated API endpoint where limit+1 elements indicates more pages
-	if len(users) > ListLimit {
-		// Fetch remaining users starting from the user after the limit
-		moreUsers, err := client.ListUsers(ctx, groupID, &users[ListLimit].UserID)
-		if err != nil {
-			return nil, err
-		}
-		// Exclude first user as it's a duplicate from previous fetch
-		users = append(users, moreUsers[1:]...)
+	// Paginated API endpoint where limit+1 elements indicates more pages,
+	// and the next page starts from the user after the limit
+	if len(users) <= limit {
+		return users, "", nil
 	}
-
-	return users, nil
+	return users[:limit], users[limit].UserID, nil
 }
 
-// Delete a single user. Errors if the user does not exist.
-func (client Client) DeleteUser(ctx context.Context, guid GroupUserID) error {
-	resp, err := client.newRequest(ctx).
+// Delete a single user. Returns ErrNotFound if the user does not exist.
+func (client Client) DeleteUser(ctx context.Context, guid GroupUserID) (err error) {
+	ctx, span := client.startSpan(ctx, "DeleteUser")
+	defer span.end(&err)
+
+	if err := validateGroupUserID(guid); err != nil {
+		return err
+	}
+
+	req := client.newRequest(ctx).
 		SetQueryParams(map[string]string{
 			paramGroupID: guid.GroupID,
 			"userId":     guid.UserID,
-		}).
-		Delete("/user")
-	if err != nil {
-		return err
+		})
+	return client.doDelete(req, "/user")
+}
+
+// Create a single user of type `User` into a groupId. Returns the created user
+// as reported by Cloudian, or the given user if Cloudian reports nothing.
+func (client Client) CreateUser(ctx context.Context, user User) (_ *User, err error) {
+	ctx, span := client.startSpan(ctx, "CreateUser")
+	defer span.end(&err)
+
+	if err := validateGroupUserID(user.GroupUserID); err != nil {
+		return nil, err
 	}
 
-	switch resp.StatusCode() {
-	case 200:
-		return nil
-	default:
-		return fmt.Errorf("DELETE user unexpected status: %d", resp.StatusCode())
+	req := client.newRequest(ctx).
+		SetBody(user)
+	resp, err := client.doJSON(req, resty.MethodPut, "/user", 200)
+	if err != nil {
+		return nil, client.missingGroup(ctx, user.GroupID, err)
 	}
 
+	if len(resp.Body()) == 0 {
+		return &user, nil
+	}
+	var created User
+	if err := json.Unmarshal(resp.Body(), &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
 }
 
-// Create a single user of type `User` into a groupId
-func (client Client) CreateUser(ctx context.Context, user User) error {
-	resp, err := client.newRequest(ctx).
-		SetBody(user).
-		Put("/user")
-	if err != nil {
+// UpdateUser updates a single user.
+func (client Client) UpdateUser(ctx context.Context, user User) (err error) {
+	ctx, span := client.startSpan(ctx, "UpdateUser")
+	defer span.end(&err)
+
+	if err := validateGroupUserID(user.GroupUserID); err != nil {
 		return err
 	}
 
-	switch resp.StatusCode() {
-	case 200:
-		return nil
-	default:
-		return fmt.Errorf("CREATE user unexpected status: %d", resp.StatusCode())
-	}
+	req := client.newRequest(ctx).
+		SetBody(user)
+	_, err = client.doJSON(req, resty.MethodPost, "/user", 200)
+	return err
 }
 
 // GetUser gets a user. Returns an error even in the case of a user not found.
 // This error can then be checked against ErrNotFound: errors.Is(err, ErrNotFound)
-func (client Client) GetUser(ctx context.Context, guid GroupUserID) (*User, error) {
+func (client Client) GetUser(ctx context.Context, guid GroupUserID) (_ *User, err error) {
+	ctx, span := client.startSpan(ctx, "GetUser")
+	defer span.end(&err)
+
+	if err := validateGroupUserID(guid); err != nil {
+		return nil, err
+	}
+
 	var user User
 
-	resp, err := client.newRequest(ctx).
+	req := client.newRequest(ctx).
 		SetQueryParams(map[string]string{
 			paramGroupID: guid.GroupID,
 			"userId":     guid.UserID,
-		}).
-		SetResult(&user).
-		Get("/user")
+		})
+	resp, err := client.doJSON(req, resty.MethodGet, "/user", 200, 204)
 	if err != nil {
 		return nil, err
 	}
 
-	switch resp.StatusCode() {
-	case 200:
-		return &user, nil
-	case 204:
+	if resp.StatusCode() == 204 {
 		// Cloudian-API returns 204 if the user does not exist
 		return nil, ErrNotFound
-	default:
-		return nil, fmt.Errorf("error: GET user unexpected status code: %d", resp.StatusCode())
 	}
+	if err := client.decodeObject(resp, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
 }
 
-// CreateUserCredentials creates a new set of credentials for a user.
-func (client Client) CreateUserCredentials(ctx context.Context, guid GroupUserID) (*SecurityInfo, error) {
-	var securityInfo SecurityInfo
+// CreateUserCredentials creates a new set of credentials for a user. It fails
+// with ErrKeyQuotaExceeded when the user already has as many as allowed.
+// Cloudian generates another set each time it is asked to, so when retrying
+// with WithRetries, the credentials of the user are listed first and again
+// before each retry, and a set created by a failed attempt is returned
+// instead of creating another.
+func (client Client) CreateUserCredentials(ctx context.Context, guid GroupUserID) (_ *SecurityInfo, err error) {
+	ctx, span := client.startSpan(ctx, "CreateUserCredentials")
+	defer span.end(&err)
 
-	resp, err := client.newRequest(ctx).
-		SetResult(&securityInfo).
-		SetQueryParams(map[string]string{paramGroupID: guid.GroupID, "userId": guid.UserID}).
-		Put("/user/credentials")
+	if err := validateGroupUserID(guid); err != nil {
+		return nil, err
+	}
+	if client.retries.attempts <= 1 {
+		return client.createUserCredentials(ctx, guid)
+	}
+
+	before, err := client.ListUserCredentials(ctx, guid)
 	if err != nil {
 		return nil, err
 	}
+	for attempt := 1; ; attempt++ {
+		creds, err := client.createUserCredentials(ctx, guid)
+		if err == nil {
+			return creds, nil
+		}
+		wait, retry := client.retries.next(AllowRetry(ctx), resty.MethodPut, "/user/credentials", attempt, err)
+		if !retry {
+			return nil, err
+		}
+		// Wait first, so that an attempt that is still being handled has
+		// created its credentials when they are listed.
+		if err := client.sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+		after, listErr := client.ListUserCredentials(ctx, guid)
+		if listErr != nil {
+			return nil, err
+		}
+		if created := createdCredentials(before, after); created != nil {
+			return created, nil
+		}
+	}
+}
 
-	switch resp.StatusCode() {
-	case 200:
-		return &securityInfo, nil
-	default:
-		return nil, fmt.Errorf("CREATE user credentials unexpected status: %d", resp.StatusCode())
+// createdCredentials returns the first credentials of `after` not in
+// `before`, if any.
+func createdCredentials(before, after []SecurityInfo) *SecurityInfo {
+	for _, creds := range after {
+		if !slices.ContainsFunc(before, func(b SecurityInfo) bool { return b.AccessKey == creds.AccessKey }) {
+			return &creds
+		}
 	}
+	return nil
 }
 
-// GetUserCredentials fetches all the credentials of a user.
-func (client Client) GetUserCredentials(ctx context.Context, accessKey string) (*SecurityInfo, error) {
+// createUserCredentials asks Cloudian to create a new set of credentials for
+// a user, once.
+func (client Client) createUserCredentials(ctx context.Context, guid GroupUserID) (*SecurityInfo, error) {
 	var securityInfo SecurityInfo
 
-	resp, err := client.newRequest(ctx).
-		SetQueryParams(map[string]string{"accessKey": accessKey}).
+	req := client.newRequest(ctx).
 		SetResult(&securityInfo).
-		Get("/user/credentials")
+		SetQueryParams(map[string]string{paramGroupID: guid.GroupID, "userId": guid.UserID})
+	if _, err := client.doJSON(req, resty.MethodPut, "/user/credentials", 200); err != nil {
+		return nil, client.missingUser(ctx, guid, err)
+	}
+
+	return &securityInfo, nil
+}
+
+// CreateUserCredentialsWithKey creates a set of credentials for a user with
+// the supplied access key and secret key, rather than having Cloudian generate
+// them. Retrying with the same keys does not create another set. It fails
+// with ErrKeyQuotaExceeded when the user already has as many as allowed. The
+// keys are query parameters, as Cloudian only accepts them there, which are
+// redacted from the errors of requests failing before a response.
+func (client Client) CreateUserCredentialsWithKey(ctx context.Context, guid GroupUserID, accessKey string, secretKey Secret) (err error) {
+	ctx, span := client.startSpan(ctx, "CreateUserCredentialsWithKey")
+	defer span.end(&err)
+
+	if err := validateGroupUserID(guid); err != nil {
+		return err
+	}
+
+	req := client.newRequest(ctx).
+		SetQueryParams(map[string]string{
+			paramGroupID: guid.GroupID,
+			"userId":     guid.UserID,
+			"accessKey":  accessKey,
+			"secretKey":  secretKey.Value(),
+		})
+	if _, err := client.doJSON(req, resty.MethodPost, "/user/credentials", 200); err != nil {
+		return client.missingUser(ctx, guid, err)
+	}
+	return nil
+}
+
+// GetUserCredentials fetches all the credentials of a user.
+func (client Client) GetUserCredentials(ctx context.Context, accessKey string) (_ *SecurityInfo, err error) {
+	ctx, span := client.startSpan(ctx, "GetUserCredentials")
+	defer span.end(&err)
+
+	var securityInfo SecurityInfo
+
+	req := client.newRequest(ctx).
+		SetQueryParams(map[string]string{"accessKey": accessKey})
+	resp, err := client.doJSON(req, resty.MethodGet, "/user/credentials", 200, 204)
 	if err != nil {
 		return nil, err
 	}
 
-	switch resp.StatusCode() {
-	case 200:
-		return &securityInfo, nil
-	case 204:
+	if resp.StatusCode() == 204 {
 		// Cloudian-API returns 204 if no security credentials found
 		return nil, ErrNotFound
-	default:
-		return nil, fmt.Errorf("error: list credentials unexpected status code: %d", resp.StatusCode())
 	}
+	if err := client.decodeObject(resp, &securityInfo); err != nil {
+		return nil, err
+	}
+	return &securityInfo, nil
 }
 
-// ListUserCredentials fetches all the credentials of a user.
-func (client Client) ListUserCredentials(ctx context.Context, guid GroupUserID) ([]SecurityInfo, error) {
-	var securityInfo []SecurityInfo
+// ListUserCredentials fetches all the credentials of a user. The endpoint is
+// not paginated, as Cloudian caps the number of credentials per user instead.
+// Listing the credentials of a user with more than MaxKeys fails with
+// ErrTooManyCredentials rather than growing without bound.
+func (client Client) ListUserCredentials(ctx context.Context, guid GroupUserID) (_ []SecurityInfo, err error) {
+	ctx, span := client.startSpan(ctx, "ListUserCredentials")
+	defer span.end(&err)
 
-	resp, err := client.newRequest(ctx).
-		SetQueryParams(map[string]string{paramGroupID: guid.GroupID, "userId": guid.UserID}).
-		SetResult(&securityInfo).
-		Get("/user/credentials/list")
+	if err := validateGroupUserID(guid); err != nil {
+		return nil, err
+	}
+
+	req := client.newRequest(ctx).
+		SetQueryParams(map[string]string{paramGroupID: guid.GroupID, "userId": guid.UserID})
+	resp, err := client.doJSON(req, resty.MethodGet, "/user/credentials/list", 200, 204)
 	if err != nil {
 		return nil, err
 	}
 
-	switch resp.StatusCode() {
-	case 200:
+	// Cloudian-API returns 204 if no security credentials found, and some
+	// versions an empty body instead.
+	securityInfo := []SecurityInfo{}
+	body := bytes.TrimSpace(resp.Body())
+	if resp.StatusCode() == 204 || len(body) == 0 {
 		return securityInfo, nil
-	case 204:
-		// Cloudian-API returns 204 if no security credentials found
-		return nil, nil
-	default:
-		return nil, fmt.Errorf("error: list credentials unexpected status code: %d", resp.StatusCode())
 	}
-}
 
-// DeleteUserCredentials deletes a set of credentials for a user.
-func (client Client) DeleteUserCredentials(ctx context.Context, accessKey string) error {
-	resp, err := client.newRequest(ctx).
-		SetQueryParams(map[string]string{"accessKey": accessKey}).
-		Delete("/user/credentials")
+	dec := json.NewDecoder(bytes.NewReader(body))
+	tok, err := dec.Token()
 	if err != nil {
-		return err
+		return nil, err
 	}
+	if tok != json.Delim('[') {
+		return nil, fmt.Errorf("credentials of user %s: want a list, got %v", guid.UserID, tok)
+	}
+	for dec.More() {
+		if len(securityInfo) == MaxKeys {
+			return nil, fmt.Errorf("user %s: %w: more than %d", guid.UserID, ErrTooManyCredentials, MaxKeys)
+		}
+		var info SecurityInfo
+		if err := dec.Decode(&info); err != nil {
+			return nil, err
+		}
+		securityInfo = append(securityInfo, info)
+	}
+	return securityInfo, nil
+}
 
-	switch resp.StatusCode() {
-	case 200:
-		return nil
+// InitialUserCredentials fetches the credentials Cloudian creates along with a
+// user, secret key included, which are the only credentials of a user just
+// created. Returns ErrNotFound if the user has no credentials, and an error if
+// it has more than the initial ones.
+func (client Client) InitialUserCredentials(ctx context.Context, guid GroupUserID) (_ *SecurityInfo, err error) {
+	ctx, span := client.startSpan(ctx, "InitialUserCredentials")
+	defer span.end(&err)
+
+	creds, err := client.ListUserCredentials(ctx, guid)
+	if err != nil {
+		return nil, err
+	}
+	switch len(creds) {
+	case 0:
+		return nil, ErrNotFound
+	case 1:
+		return &creds[0], nil
 	default:
-		return fmt.Errorf("DELETE credentials unexpected status: %d", resp.StatusCode())
+		return nil, fmt.Errorf("user has %d credentials, not only the initial ones", len(creds))
 	}
 }
 
-// Delete a group and all its members.
-func (client Client) DeleteGroupRecursive(ctx context.Context, groupID string) error {
-	users, err := client.ListUsers(ctx, groupID, nil)
+// DeleteUserCredentials deletes a set of credentials for a user. Returns
+// ErrNotFound if the credentials do not exist.
+func (client Client) DeleteUserCredentials(ctx context.Context, accessKey string) (err error) {
+	ctx, span := client.startSpan(ctx, "DeleteUserCredentials")
+	defer span.end(&err)
+
+	req := client.newRequest(ctx).
+		SetQueryParams(map[string]string{"accessKey": accessKey})
+	return client.doDelete(req, "/user/credentials")
+}
+
+// DeleteAllUserCredentials deletes every set of credentials of a user and
+// returns how many were deleted. Credentials that are already gone are not
+// counted. All credentials are attempted before returning the errors
+// encountered.
+func (client Client) DeleteAllUserCredentials(ctx context.Context, guid GroupUserID) (_ int, err error) {
+	ctx, span := client.startSpan(ctx, "DeleteAllUserCredentials")
+	defer span.end(&err)
+
+	creds, err := client.ListUserCredentials(ctx, guid)
 	if err != nil {
-		return fmt.Errorf("error listing users: %w", err)
+		return 0, fmt.Errorf("error listing credentials: %w", err)
 	}
 
-	for _, user := range users {
-		if err := client.DeleteUser(ctx, user.GroupUserID); err != nil {
-			return fmt.Errorf("error deleting user: %w", err)
+	deleted := 0
+	var errs []error
+	for _, cred := range creds {
+		err := client.DeleteUserCredentials(ctx, cred.AccessKey)
+		switch {
+		case errors.Is(err, ErrNotFound):
+		case err != nil:
+			errs = append(errs, fmt.Errorf("error deleting credentials %s: %w", cred.AccessKey, err))
+		default:
+			deleted++
 		}
 	}
+	return deleted, errors.Join(errs...)
+}
+
+// Delete a group and all its members, with DefaultConcurrency requests in
+// flight. Members that still have credentials are only deleted when `force`
+// is true, in which case their credentials are deleted first. All members are
+// attempted before returning the errors encountered. When there are any, the
+// group is kept. The report tells which members were deleted, by user ID. The
+// members are listed and deleted a page at a time, so that groups of any size
+// can be deleted. The requests are Background work.
+func (client Client) DeleteGroupRecursive(ctx context.Context, groupID string, force bool) (_ Report, err error) {
+	ctx, span := client.startSpan(ctx, "DeleteGroupRecursive")
+	defer span.end(&err)
 
-	return client.DeleteGroup(ctx, groupID)
+	ctx = Background(ctx)
+	var report Report
+	page := make([]GroupUserID, 0, ListLimit)
+	for user, err := range client.UsersIter(ctx, groupID) {
+		if err != nil {
+			slices.Sort(report.Succeeded)
+			return report, fmt.Errorf("error listing users: %w", err)
+		}
+		page = append(page, user.GroupUserID)
+		if len(page) == ListLimit {
+			client.deleteMembers(ctx, &report, page, force)
+			page = page[:0]
+		}
+	}
+	client.deleteMembers(ctx, &report, page, force)
+
+	slices.Sort(report.Succeeded)
+	if err := report.Err(); err != nil {
+		return report, err
+	}
+
+	return report, client.DeleteGroup(ctx, groupID)
 }
 
-// Deletes a group if it is without members.
-func (client Client) DeleteGroup(ctx context.Context, groupID string) error {
-	resp, err := client.newRequest(ctx).
-		SetQueryParams(map[string]string{paramGroupID: groupID}).
-		Delete("/group")
-	if err != nil {
-		return err
+// deleteMembers deletes the supplied members of a group, recording the
+// outcome in the report. Members that still have credentials are only
+// deleted when `force` is true, after deleting their credentials.
+func (client Client) deleteMembers(ctx context.Context, report *Report, guids []GroupUserID, force bool) {
+	if !force {
+		err := forEachUser(ctx, guids, DefaultConcurrency, func(ctx context.Context, i int) error {
+			creds, err := client.ListUserCredentials(ctx, guids[i])
+			if err != nil {
+				return fmt.Errorf("error listing credentials: %w", err)
+			}
+			if len(creds) > 0 {
+				return ErrUserHasCredentials
+			}
+			return nil
+		})
+		guids = failBulk(report, guids, err, "user %s: %w")
+	} else {
+		err := forEachUser(ctx, guids, DefaultConcurrency, func(ctx context.Context, i int) error {
+			_, err := client.DeleteAllUserCredentials(ctx, guids[i])
+			return err
+		})
+		guids = failBulk(report, guids, err, "error deleting credentials of user %s: %w")
 	}
 
-	switch resp.StatusCode() {
-	case 200:
-		return nil
-	default:
-		return fmt.Errorf("DELETE group unexpected statusCode: %d", resp.StatusCode())
+	err := client.DeleteUsers(ctx, guids, DefaultConcurrency)
+	for _, guid := range failBulk(report, guids, err, "error deleting user %s: %w") {
+		report.succeed(guid.UserID)
 	}
 }
 
-// Creates a group.
-func (client Client) CreateGroup(ctx context.Context, group Group) error {
-	resp, err := client.newRequest(ctx).
-		SetBody(toInternal(group)).
-		Put("/group")
-	if err != nil {
-		return err
+// failBulk records the users failed by a BulkError in the report, wrapping
+// their errors with `format` and the user ID, and returns the users that did
+// not fail.
+func failBulk(report *Report, guids []GroupUserID, err error, format string) []GroupUserID {
+	var bulkErr *BulkError
+	if !errors.As(err, &bulkErr) {
+		return guids
 	}
+	var ok []GroupUserID
+	for _, guid := range guids {
+		if failed, found := bulkErr.Failed[guid]; found {
+			report.fail(guid.UserID, fmt.Errorf(format, guid.UserID, failed))
+			continue
+		}
+		ok = append(ok, guid)
+	}
+	return ok
+}
 
-	switch resp.StatusCode() {
-	case 200:
+// Deletes a group if it is without members. Returns ErrNotFound if the group
+// does not exist, and ErrGroupNotEmpty if Cloudian refuses to delete it as it
+// still has members.
+func (client Client) DeleteGroup(ctx context.Context, groupID string) (err error) {
+	ctx, span := client.startSpan(ctx, "DeleteGroup")
+	defer span.end(&err)
+
+	if err := ValidateGroupID(groupID); err != nil {
 		return err
-	default:
-		return fmt.Errorf("CREATE group unexpected status: %d", resp.StatusCode())
 	}
+
+	defer client.groups.invalidate(groupID)
+
+	req := client.newRequest(ctx).
+		SetQueryParams(map[string]string{paramGroupID: groupID})
+	return client.doDelete(req, "/group")
 }
 
-// Updates a group if it does not exists.
-func (client Client) UpdateGroup(ctx context.Context, group Group) error {
-	resp, err := client.newRequest(ctx).
-		SetBody(toInternal(group)).
-		Post("/group")
+// Creates a group. Returns the created group as reported by Cloudian, or the
+// given group if Cloudian reports nothing.
+func (client Client) CreateGroup(ctx context.Context, group Group) (_ *Group, err error) {
+	ctx, span := client.startSpan(ctx, "CreateGroup")
+	defer span.end(&err)
+
+	if err := ValidateGroupID(group.GroupID); err != nil {
+		return nil, err
+	}
+
+	defer client.groups.invalidate(group.GroupID)
+
+	req := client.newRequest(ctx).
+		SetBody(toInternal(group))
+	resp, err := client.doJSON(req, resty.MethodPut, "/group", 200)
 	if err != nil {
+		return nil, err
+	}
+
+	if len(resp.Body()) == 0 {
+		return &group, nil
+	}
+	var created groupInternal
+	if err := json.Unmarshal(resp.Body(), &created); err != nil {
+		return nil, err
+	}
+	retVal := fromInternal(created)
+	return &retVal, nil
+}
+
+// Updates a group if it does not exists.
+func (client Client) UpdateGroup(ctx context.Context, group Group) (err error) {
+	ctx, span := client.startSpan(ctx, "UpdateGroup")
+	defer span.end(&err)
+
+	if err := ValidateGroupID(group.GroupID); err != nil {
 		return err
 	}
 
-	switch resp.StatusCode() {
-	case 200:
+	defer client.groups.invalidate(group.GroupID)
+
+	req := client.newRequest(ctx).
+		SetBody(toInternal(group))
+	_, err = client.doJSON(req, resty.MethodPost, "/group", 200)
+	return err
+}
+
+// UpdateGroupIfMatches updates a group as UpdateGroup does, unless it no
+// longer equals `observed`, in which case it returns ErrConflict without
+// updating it. Cloudian has no conditional updates, so the group is read
+// right before it is updated, bypassing the cache: this narrows the window
+// for losing concurrent changes rather than closing it.
+func (client Client) UpdateGroupIfMatches(ctx context.Context, desired Group, observed Group) (err error) {
+	ctx, span := client.startSpan(ctx, "UpdateGroupIfMatches")
+	defer span.end(&err)
+
+	if err := ValidateGroupID(desired.GroupID); err != nil {
 		return err
-	default:
-		return fmt.Errorf("update group unexpected status: %d", resp.StatusCode())
+	}
+
+	client.groups.invalidate(desired.GroupID)
+	current, err := client.GetGroup(ctx, desired.GroupID)
+	if err != nil {
+		return err
+	}
+	if !current.Equal(observed) {
+		return ErrConflict
+	}
+	return client.UpdateGroup(ctx, desired)
+}
+
+// ListGroupsByPrefix lists the groups whose group ID starts with `prefix`, or
+// all groups if `prefix` is empty.
+func (client Client) ListGroupsByPrefix(ctx context.Context, prefix string) (_ []Group, err error) {
+	ctx, span := client.startSpan(ctx, "ListGroupsByPrefix")
+	defer span.end(&err)
+
+	var groups []Group
+	var offset string
+	for {
+		params := map[string]string{"limit": strconv.Itoa(ListLimit)}
+		if prefix != "" {
+			params[paramPrefix] = prefix
+		}
+		if offset != "" {
+			params["offset"] = offset
+		}
+
+		var page []groupInternal
+		req := client.newRequest(ctx).
+			SetQueryParams(params).
+			SetResult(&page)
+		if _, err := client.doJSON(req, resty.MethodGet, "/group/list", 200, 204); err != nil {
+			return nil, fmt.Errorf("GET list groups failed: %w", err)
+		}
+
+		// Paginated API endpoint where limit+1 elements indicates more pages
+		for _, g := range page[:min(len(page), ListLimit)] {
+			groups = append(groups, fromInternal(g))
+		}
+		if len(page) <= ListLimit {
+			return groups, nil
+		}
+
+		// The next page starts from the group after the limit
+		offset = page[ListLimit].GroupID
 	}
 }
 
 // Get a group. Returns an error even in the case of a group not found.
 // This error can then be checked against ErrNotFound: errors.Is(err, ErrNotFound)
-func (client Client) GetGroup(ctx context.Context, groupID string) (*Group, error) {
+func (client Client) GetGroup(ctx context.Context, groupID string) (_ *Group, err error) {
+	ctx, span := client.startSpan(ctx, "GetGroup")
+	defer span.end(&err)
+
+	if err := ValidateGroupID(groupID); err != nil {
+		return nil, err
+	}
+
+	cached, generation, ok := client.groups.get(groupID)
+	if ok {
+		return cached, nil
+	}
+
 	var group groupInternal
-	resp, err := client.newRequest(ctx).
-		SetQueryParams(map[string]string{paramGroupID: groupID}).
-		SetResult(&group).
-		Get("/group")
+	req := client.newRequest(ctx).
+		SetQueryParams(map[string]string{paramGroupID: groupID})
+	resp, err := client.doJSON(req, resty.MethodGet, "/group", 200, 204)
 	if err != nil {
 		return nil, err
 	}
 
-	switch resp.StatusCode() {
-	case 200:
-		retVal := fromInternal(group)
-		return &retVal, nil
-	case 204:
+	if resp.StatusCode() == 204 {
 		// Cloudian-API returns 204 if the group does not exist
 		return nil, ErrNotFound
-	default:
-		return nil, fmt.Errorf("error: GET group unexpected status code: %d", resp.StatusCode())
 	}
+	if err := client.decodeObject(resp, &group); err != nil {
+		return nil, err
+	}
+	retVal := fromInternal(group)
+	client.groups.put(groupID, generation, retVal)
+	return &retVal, nil
+}
+
+// Version returns the HyperStore version reported by the admin API.
+// It is a cheap call, useful for verifying that the endpoint and credentials are valid.
+func (client Client) Version(ctx context.Context) (_ string, err error) {
+	ctx, span := client.startSpan(ctx, "Version")
+	defer span.end(&err)
+
+	req := client.client.R().
+		SetContext(ctx)
+	resp, err := client.doJSON(req, resty.MethodGet, "/system/version", 200)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(resp.String()), nil
+}
+
+// Ping returns an error if the admin API cannot be reached with the
+// credentials of the client.
+func (client Client) Ping(ctx context.Context) error {
+	_, err := client.Version(ctx)
+	return err
+}
+
+// UserAgent returns the User-Agent header of the requests to the admin API.
+func (client Client) UserAgent() string {
+	return client.userAgent
 }
 
 func (client Client) newRequest(ctx context.Context) *resty.Request {
@@ -417,3 +1343,217 @@ func (client Client) newRequest(ctx context.Context) *resty.Request {
 		SetHeader("Content-Type", "application/json").
 		ForceContentType("application/json") // TODO figure out why this is needed
 }
+
+// decodeObject decodes the single object in the body of a response into `v`.
+// Some HyperStore releases return single objects wrapped in an array, in
+// which case the first element is decoded, and an empty array is ErrNotFound.
+func (client Client) decodeObject(resp *resty.Response, v any) error {
+	body := bytes.TrimSpace(resp.Body())
+	switch {
+	case len(body) == 0:
+		return nil
+	case body[0] != '[':
+		return json.Unmarshal(body, v)
+	}
+
+	client.warnf("%s %s returned an array instead of an object", resp.Request.Method, resp.Request.RawRequest.URL.Path)
+	var items []json.RawMessage
+	if err := json.Unmarshal(body, &items); err != nil {
+		return err
+	}
+	if len(items) == 0 {
+		return ErrNotFound
+	}
+	return json.Unmarshal(items[0], v)
+}
+
+// doDelete executes a delete request. Cloudian responds 204 or 404 when there
+// is nothing to delete, which is returned as ErrNotFound.
+func (client Client) doDelete(req *resty.Request, path string) error {
+	resp, err := client.doJSON(req, resty.MethodDelete, path, 200, 204)
+	var statusErr *StatusError
+	switch {
+	case errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case err != nil:
+		return err
+	case resp.StatusCode() == http.StatusNoContent:
+		return ErrNotFound
+	default:
+		return nil
+	}
+}
+
+// doJSON executes the request and checks the response status code against
+// the status codes the endpoint is expected to respond with.
+// An unexpected 2xx status code is logged as a warning, and the response body
+// is decoded as usual. An unexpected non-2xx status code is returned as a *StatusError.
+// Requests whose context has no deadline are given one of the client's call timeout,
+// and requests that time out return an error wrapping context.DeadlineExceeded.
+// Requests failing transiently are retried when the client is configured to
+// with WithRetries, as long as repeating them is safe.
+func (client Client) doJSON(req *resty.Request, method, path string, expect ...int) (*resty.Response, error) {
+	ctx := req.Context()
+	for attempt := 1; ; attempt++ {
+		resp, err := client.doJSONOnce(req, method, path, expect...)
+		req.SetContext(ctx)
+		wait, retry := client.retries.next(ctx, method, path, attempt, err)
+		if !retry {
+			return resp, err
+		}
+		if err := client.sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// doJSONOnce executes the request once, as doJSON does.
+func (client Client) doJSONOnce(req *resty.Request, method, path string, expect ...int) (*resty.Response, error) {
+	if _, ok := req.Context().Deadline(); !ok && client.callTimeout > 0 {
+		ctx, cancel := context.WithTimeout(req.Context(), client.callTimeout)
+		defer cancel()
+		req.SetContext(ctx)
+	}
+
+	start := client.clock.Now()
+	resp, err := req.Execute(method, path)
+	if err != nil {
+		client.metrics.observe(method, path, 0, client.clock.Since(start))
+		client.traceRequest(req.Context(), method, path, 0)
+		return nil, redactQuery(err)
+	}
+	client.metrics.observe(method, path, resp.StatusCode(), client.clock.Since(start))
+	client.traceRequest(req.Context(), method, path, resp.StatusCode())
+	if skew, ok := client.skew.observe(resp.Header().Get("Date"), start, client.clock.Now()); ok {
+		client.metrics.observeSkew(client.client.BaseURL, skew)
+	}
+
+	switch {
+	case slices.Contains(expect, resp.StatusCode()):
+		return resp, nil
+	case resp.IsSuccess():
+		client.warnf("%s %s unexpected status: %d", method, path, resp.StatusCode())
+		return resp, nil
+	case resp.StatusCode() == http.StatusTooManyRequests:
+		throttled := &ThrottledError{RetryAfter: retryAfter(resp.Header(), client.clock.Now())}
+		return nil, fmt.Errorf("%w: %w", throttled, newStatusError(method, path, resp))
+	case isLicenseExceeded(resp):
+		client.metrics.observeLicenseExceeded(method, path)
+		return nil, fmt.Errorf("%w: %w", ErrLicenseExceeded, newStatusError(method, path, resp))
+	case isKeyQuotaExceeded(method, path, resp):
+		return nil, fmt.Errorf("%w: %w", ErrKeyQuotaExceeded, newStatusError(method, path, resp))
+	case isGroupNotEmpty(method, path, resp):
+		return nil, fmt.Errorf("%w: %w", ErrGroupNotEmpty, newStatusError(method, path, resp))
+	default:
+		return nil, newStatusError(method, path, resp)
+	}
+}
+
+// redactQuery removes the query from the URL of a transport error, which
+// would otherwise carry the query parameters of the request into its message,
+// such as the secret key given to CreateUserCredentialsWithKey.
+func redactQuery(err error) error {
+	var urlErr *url.Error
+	if !errors.As(err, &urlErr) {
+		return err
+	}
+	if u, parseErr := url.Parse(urlErr.URL); parseErr == nil {
+		u.RawQuery = ""
+		urlErr.URL = u.String()
+	} else {
+		urlErr.URL, _, _ = strings.Cut(urlErr.URL, "?")
+	}
+	return err
+}
+
+// retryAfter returns how long a response asks to wait before retrying, given
+// in its Retry-After header as seconds or as an HTTP date, or zero if it does
+// not say. A date is counted from the Date of the response when present, so
+// that clock skew does not stretch or cut the wait.
+func retryAfter(header http.Header, now time.Time) time.Duration {
+	value := header.Get("Retry-After")
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	at, err := http.ParseTime(value)
+	if err != nil {
+		return 0
+	}
+	if date, err := http.ParseTime(header.Get("Date")); err == nil {
+		now = date
+	}
+	return max(at.Sub(now), 0)
+}
+
+// isLicenseExceeded returns whether a response refuses a request because the
+// HyperStore license capacity is exceeded. Cloudian answers these with a
+// client error telling that the license capacity is exceeded.
+func isLicenseExceeded(resp *resty.Response) bool {
+	return resp.IsError() && resp.StatusCode() < http.StatusInternalServerError &&
+		resp.StatusCode() != http.StatusTooManyRequests &&
+		strings.Contains(strings.ToLower(resp.String()), "license capacity exceeded")
+}
+
+// isGroupNotEmpty returns whether a response refuses to delete a group
+// because it still has users. Cloudian answers these with 409 Conflict, or
+// another client error, telling that the group is not empty. Other conflicts,
+// such as with a deletion already in progress, are not.
+func isGroupNotEmpty(method, path string, resp *resty.Response) bool {
+	if method != resty.MethodDelete || path != "/group" || !resp.IsError() || resp.StatusCode() >= http.StatusInternalServerError {
+		return false
+	}
+	switch resp.StatusCode() {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusTooManyRequests:
+		return false
+	}
+	return strings.Contains(strings.ToLower(resp.String()), "group is not empty")
+}
+
+// refused returns whether Cloudian refused a request as invalid, without a
+// more specific reason. Cloudian refuses requests about the members of groups
+// or users that do not exist this way, rather than answering 404 Not Found.
+func refused(err error) bool {
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || errors.Is(err, ErrKeyQuotaExceeded) || errors.Is(err, ErrLicenseExceeded) {
+		return false
+	}
+	return statusErr.StatusCode == http.StatusBadRequest || statusErr.StatusCode == http.StatusNotFound
+}
+
+// missingGroup returns `err`, the error of a request about a member of the
+// group, along with ErrGroupNotFound when Cloudian refused it and confirms
+// that the group does not exist.
+func (client Client) missingGroup(ctx context.Context, groupID string, err error) error {
+	if !refused(err) {
+		return err
+	}
+	if _, getErr := client.GetGroup(ctx, groupID); errors.Is(getErr, ErrNotFound) {
+		return fmt.Errorf("%w: %w", ErrGroupNotFound, err)
+	}
+	return err
+}
+
+// missingUser returns `err`, the error of a request about the credentials of
+// the user, along with ErrUserNotFound when Cloudian refused it and confirms
+// that the user does not exist.
+func (client Client) missingUser(ctx context.Context, guid GroupUserID, err error) error {
+	if !refused(err) {
+		return err
+	}
+	if _, getErr := client.GetUser(ctx, guid); errors.Is(getErr, ErrNotFound) {
+		return fmt.Errorf("%w: %w", ErrUserNotFound, err)
+	}
+	return err
+}
+
+// isKeyQuotaExceeded returns whether a response refuses to create credentials
+// because the user already has as many as allowed. Cloudian answers these
+// with a client error telling that the maximum number of credentials is
+// reached.
+func isKeyQuotaExceeded(method, path string, resp *resty.Response) bool {
+	if (method != resty.MethodPut && method != resty.MethodPost) || path != "/user/credentials" ||
+		!resp.IsError() || resp.StatusCode() >= http.StatusInternalServerError {
+		return false
+	}
+	return strings.Contains(strings.ToLower(resp.String()), "maximum number of credentials")
+}
@@ -2,15 +2,39 @@ package cloudian
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"encoding/pem"
 	"errors"
 	"fmt"
+	"io"
+	"math/big"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	testingclock "k8s.io/utils/clock/testing"
+
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian/cloudiantest"
 )
 
 func TestGenericError(t *testing.T) {
@@ -31,8 +55,11 @@ func TestWrappedErrNotFound(t *testing.T) {
 
 func TestGetGroup(t *testing.T) {
 	expected := Group{
-		GroupID: "QA",
-		Active:  true,
+		GroupID:            "QA",
+		Active:             true,
+		S3EndpointsHTTP:    []string{AllEndpoints},
+		S3EndpointsHTTPS:   []string{"s3-a.example.com"},
+		S3WebSiteEndpoints: []string{AllEndpoints},
 	}
 	cloudianClient, testServer := mockBy(func(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(toInternal(expected))
@@ -48,12 +75,80 @@ func TestGetGroup(t *testing.T) {
 	}
 }
 
-func TestGetGroupNotFound(t *testing.T) {
+func TestUpdateGroupKeepsS3Endpoints(t *testing.T) {
+	var updated groupInternal
 	cloudianClient, testServer := mockBy(func(w http.ResponseWriter, r *http.Request) {
-		w.WriteHeader(http.StatusNoContent)
+		_ = json.NewDecoder(r.Body).Decode(&updated)
 	})
 	defer testServer.Close()
 
+	group := Group{GroupID: "QA", S3EndpointsHTTPS: []string{"s3-a.example.com"}}
+	if err := cloudianClient.UpdateGroup(context.TODO(), group); err != nil {
+		t.Fatalf("UpdateGroup(): %v", err)
+	}
+	want := groupInternal{
+		Active:             "false",
+		GroupID:            "QA",
+		S3EndpointsHTTP:    []string{AllEndpoints},
+		S3EndpointsHTTPS:   []string{"s3-a.example.com"},
+		S3WebSiteEndpoints: []string{AllEndpoints},
+	}
+	if diff := cmp.Diff(want, updated); diff != "" {
+		t.Errorf("UpdateGroup() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestNormalizeEndpoints(t *testing.T) {
+	all := []string{"s3-a", "s3-b"}
+	cases := map[string]struct {
+		endpoints []string
+		all       []string
+		want      []string
+	}{
+		"Empty":         {want: []string{AllEndpoints}},
+		"All":           {endpoints: []string{"s3-a", AllEndpoints}, want: []string{AllEndpoints}},
+		"Sorted":        {endpoints: []string{"s3-b", "s3-a", "s3-b"}, want: []string{"s3-a", "s3-b"}},
+		"EveryEndpoint": {endpoints: []string{"s3-b", "s3-a"}, all: all, want: []string{AllEndpoints}},
+		"SomeEndpoints": {endpoints: []string{"s3-b"}, all: all, want: []string{"s3-b"}},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if diff := cmp.Diff(tc.want, NormalizeEndpoints(tc.endpoints, tc.all...)); diff != "" {
+				t.Errorf("NormalizeEndpoints(%q, %q) mismatch (-want +got):\n%s", tc.endpoints, tc.all, diff)
+			}
+		})
+	}
+}
+
+func TestEqualSets(t *testing.T) {
+	cases := map[string]struct {
+		a, b []string
+		want bool
+	}{
+		"NilAndEmpty":  {b: []string{}, want: true},
+		"Reordered":    {a: []string{"s3-a", "s3-b"}, b: []string{"s3-b", "s3-a"}, want: true},
+		"Duplicates":   {a: []string{"s3-a", "s3-b", "s3-a"}, b: []string{"s3-b", "s3-a"}, want: true},
+		"Different":    {a: []string{"s3-a", "s3-b"}, b: []string{"s3-a", "s3-c"}},
+		"Subset":       {a: []string{"s3-a"}, b: []string{"s3-a", "s3-b"}},
+		"DuplicateGap": {a: []string{"s3-a", "s3-a"}, b: []string{"s3-a", "s3-b"}},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := EqualSets(tc.a, tc.b); got != tc.want {
+				t.Errorf("EqualSets(%q, %q): want %t, got %t", tc.a, tc.b, tc.want, got)
+			}
+			if got := EqualSets(tc.b, tc.a); got != tc.want {
+				t.Errorf("EqualSets(%q, %q): want %t, got %t", tc.b, tc.a, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestGetGroupNotFound(t *testing.T) {
+	cloudianClient := NewClient(cloudiantest.NewFakeServer(t).URL, "")
+
 	_, err := cloudianClient.GetGroup(context.TODO(), "QA")
 
 	if !errors.Is(err, ErrNotFound) {
@@ -62,7 +157,7 @@ func TestGetGroupNotFound(t *testing.T) {
 }
 
 func TestCreateCredentials(t *testing.T) {
-	expected := SecurityInfo{AccessKey: "123", SecretKey: "abc"}
+	expected := SecurityInfo{AccessKey: "123", SecretKey: NewSecret("abc")}
 	cloudianClient, testServer := mockBy(func(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(expected)
 	})
@@ -77,13 +172,48 @@ func TestCreateCredentials(t *testing.T) {
 	}
 }
 
-func TestGetUserCredentials(t *testing.T) {
-	expected := SecurityInfo{AccessKey: "123", SecretKey: "abc"}
+func TestCreateCredentialsWithKey(t *testing.T) {
+	var got url.Values
 	cloudianClient, testServer := mockBy(func(w http.ResponseWriter, r *http.Request) {
-		json.NewEncoder(w).Encode(expected)
+		if r.Method != http.MethodPost {
+			t.Errorf("CreateUserCredentialsWithKey(): want method %s, got %s", http.MethodPost, r.Method)
+		}
+		got = r.URL.Query()
 	})
 	defer testServer.Close()
 
+	err := cloudianClient.CreateUserCredentialsWithKey(context.TODO(), GroupUserID{GroupID: "QA", UserID: "user1"}, "123", NewSecret("a+b/c"))
+	if err != nil {
+		t.Errorf("Error creating credentials: %v", err)
+	}
+	want := url.Values{"groupId": {"QA"}, "userId": {"user1"}, "accessKey": {"123"}, "secretKey": {"a+b/c"}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("CreateUserCredentialsWithKey() query mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCreateCredentialsWithKeyTransportError(t *testing.T) {
+	cloudianClient, testServer := mockBy(func(http.ResponseWriter, *http.Request) {})
+	testServer.Close()
+
+	err := cloudianClient.CreateUserCredentialsWithKey(context.TODO(), GroupUserID{GroupID: "QA", UserID: "user1"}, "123", NewSecret("TOPSECRET"))
+	if err == nil {
+		t.Fatal("CreateUserCredentialsWithKey(): want an error from a closed server")
+	}
+	if strings.Contains(err.Error(), "TOPSECRET") {
+		t.Errorf("CreateUserCredentialsWithKey(): want the secret key redacted from the error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "/user/credentials") {
+		t.Errorf("CreateUserCredentialsWithKey(): want the path kept in the error, got %v", err)
+	}
+}
+
+func TestGetUserCredentials(t *testing.T) {
+	expected := SecurityInfo{AccessKey: "123", SecretKey: NewSecret("abc")}
+	fake := cloudiantest.NewFakeServer(t)
+	fake.AddCredentials("QA", "user1", expected.AccessKey, expected.SecretKey.Value())
+	cloudianClient := NewClient(fake.URL, "")
+
 	credentials, err := cloudianClient.GetUserCredentials(context.TODO(), "123")
 	if err != nil {
 		t.Errorf("Error getting credentials: %v", err)
@@ -95,16 +225,17 @@ func TestGetUserCredentials(t *testing.T) {
 
 func TestListUserCredentials(t *testing.T) {
 	expected := []SecurityInfo{
-		{AccessKey: "123", SecretKey: "abc"},
-		{AccessKey: "456", SecretKey: "def"},
+		{AccessKey: "123", SecretKey: NewSecret("abc")},
+		{AccessKey: "456", SecretKey: NewSecret("def")},
 	}
-	cloudianClient, testServer := mockBy(func(w http.ResponseWriter, r *http.Request) {
-		json.NewEncoder(w).Encode(expected)
-	})
-	defer testServer.Close()
+	fake := cloudiantest.NewFakeServer(t)
+	for _, creds := range expected {
+		fake.AddCredentials("QA", "user1", creds.AccessKey, creds.SecretKey.Value())
+	}
+	cloudianClient := NewClient(fake.URL, "")
 
 	credentials, err := cloudianClient.ListUserCredentials(
-		context.TODO(), GroupUserID{UserID: "", GroupID: ""},
+		context.TODO(), GroupUserID{UserID: "user1", GroupID: "QA"},
 	)
 	if err != nil {
 		t.Errorf("Error listing credentials: %v", err)
@@ -114,32 +245,157 @@ func TestListUserCredentials(t *testing.T) {
 	}
 }
 
-func TestListUsers(t *testing.T) {
-	var expected []User
-	for i := 0; i < 500; i++ {
-		expected = append(expected, User{GroupUserID: GroupUserID{GroupID: "QA", UserID: strconv.Itoa(i)}})
+func TestListUserCredentialsSizes(t *testing.T) {
+	many := make([]SecurityInfo, MaxKeys)
+	for i := range many {
+		many[i] = SecurityInfo{AccessKey: strconv.Itoa(i), SecretKey: NewSecret("secret")}
+	}
+	encode := func(creds []SecurityInfo) string {
+		b, _ := json.Marshal(creds)
+		return string(b)
 	}
 
-	cloudianClient, testServer := mockBy(func(w http.ResponseWriter, r *http.Request) {
-		index := 0
+	cases := map[string]struct {
+		status     int
+		body       string
+		want       []SecurityInfo
+		wantErr    error
+		wantAnyErr bool
+	}{
+		"NoContent":  {status: http.StatusNoContent, want: []SecurityInfo{}},
+		"EmptyBody":  {status: http.StatusOK, want: []SecurityInfo{}},
+		"EmptyList":  {status: http.StatusOK, body: "[]", want: []SecurityInfo{}},
+		"One":        {status: http.StatusOK, body: encode(many[:1]), want: many[:1]},
+		"Many":       {status: http.StatusOK, body: encode(many), want: many},
+		"TooMany":    {status: http.StatusOK, body: encode(append(many, SecurityInfo{AccessKey: "runaway"})), wantErr: ErrTooManyCredentials},
+		"NotAList":   {status: http.StatusOK, body: `{"accessKey":"123"}`, wantAnyErr: true},
+		"BadElement": {status: http.StatusOK, body: `[{"accessKey":1}]`, wantAnyErr: true},
+	}
 
-		if offset := r.URL.Query().Get("offset"); offset != "" {
-			var err error
-			index, err = strconv.Atoi(r.URL.Query().Get("offset"))
-			if err != nil {
-				panic(err)
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cloudianClient, testServer := mockBy(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.body))
+			})
+			defer testServer.Close()
+
+			got, err := cloudianClient.ListUserCredentials(context.TODO(), GroupUserID{GroupID: "QA", UserID: "user1"})
+			switch {
+			case tc.wantAnyErr && err == nil:
+				t.Fatal("ListUserCredentials(): want error, got none")
+			case !tc.wantAnyErr && !errors.Is(err, tc.wantErr):
+				t.Fatalf("ListUserCredentials(): want error %v, got %v", tc.wantErr, err)
 			}
-		}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("ListUserCredentials() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
 
-		// return one more than limit to indicate more pages
-		end := index + ListLimit + 1
-		if end > len(expected) {
-			end = len(expected)
-		}
-		json.NewEncoder(w).Encode(expected[index:end])
+func TestDeleteAllUserCredentials(t *testing.T) {
+	cases := map[string]struct {
+		reason      string
+		status      map[string]int
+		wantDeleted int
+		wantErr     bool
+	}{
+		"All": {
+			reason:      "Every access key of the user should be deleted.",
+			wantDeleted: 3,
+		},
+		"PartialFailure": {
+			reason:      "A failure to delete the second access key should not stop the third from being deleted.",
+			status:      map[string]int{"2": http.StatusInternalServerError},
+			wantDeleted: 2,
+			wantErr:     true,
+		},
+		"AlreadyGone": {
+			reason:      "An access key deleted concurrently should not be counted nor fail.",
+			status:      map[string]int{"2": http.StatusNoContent},
+			wantDeleted: 2,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var attempted []string
+			cloudianClient, testServer := mockBy(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.URL.Path == "/user/credentials/list":
+					json.NewEncoder(w).Encode([]SecurityInfo{{AccessKey: "1"}, {AccessKey: "2"}, {AccessKey: "3"}})
+				case r.Method == http.MethodDelete && r.URL.Path == "/user/credentials":
+					accessKey := r.URL.Query().Get("accessKey")
+					attempted = append(attempted, accessKey)
+					if status, ok := tc.status[accessKey]; ok {
+						w.WriteHeader(status)
+					}
+				}
+			})
+			defer testServer.Close()
+
+			deleted, err := cloudianClient.DeleteAllUserCredentials(context.TODO(), GroupUserID{GroupID: "QA", UserID: "user1"})
+			if deleted != tc.wantDeleted {
+				t.Errorf("\n%s\nDeleteAllUserCredentials(): want %d deleted, got %d", tc.reason, tc.wantDeleted, deleted)
+			}
+			if (err != nil) != tc.wantErr {
+				t.Errorf("\n%s\nDeleteAllUserCredentials(): want error %t, got %v", tc.reason, tc.wantErr, err)
+			}
+			var statusErr *StatusError
+			if tc.wantErr && (!errors.As(err, &statusErr) || !strings.Contains(err.Error(), "credentials 2")) {
+				t.Errorf("\n%s\nDeleteAllUserCredentials(): want the StatusError of the second access key, got %v", tc.reason, err)
+			}
+			if diff := cmp.Diff([]string{"1", "2", "3"}, attempted); diff != "" {
+				t.Errorf("\n%s\nDeleteAllUserCredentials() attempted mismatch (-want +got):\n%s", tc.reason, diff)
+			}
+		})
+	}
+
+	cloudianClient, testServer := mockBy(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	defer testServer.Close()
+	if _, err := cloudianClient.DeleteAllUserCredentials(context.TODO(), GroupUserID{GroupID: "QA", UserID: "user1"}); err == nil {
+		t.Error("DeleteAllUserCredentials(): want the failure to list credentials, got none")
+	}
+}
+
+func BenchmarkListUserCredentials(b *testing.B) {
+	creds := make([]SecurityInfo, 5)
+	for i := range creds {
+		creds[i] = SecurityInfo{AccessKey: strconv.Itoa(i), SecretKey: NewSecret("secret")}
+	}
+	body, _ := json.Marshal(creds)
+	cloudianClient, testServer := mockBy(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(body)
 	})
 	defer testServer.Close()
 
+	for b.Loop() {
+		if _, err := cloudianClient.ListUserCredentials(context.TODO(), GroupUserID{GroupID: "QA", UserID: "user1"}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// addUsers adds the supplied users to a fake admin API.
+func addUsers(fake *cloudiantest.FakeServer, users ...User) {
+	for _, u := range users {
+		fake.AddUser(cloudiantest.User{GroupID: u.GroupID, UserID: u.UserID, UserType: string(u.UserType)})
+	}
+}
+
+func TestListUsers(t *testing.T) {
+	var expected []User
+	for i := 0; i < 500; i++ {
+		expected = append(expected, User{GroupUserID: GroupUserID{GroupID: "QA", UserID: fmt.Sprintf("%03d", i)}})
+	}
+
+	fake := cloudiantest.NewFakeServer(t)
+	addUsers(fake, expected...)
+	cloudianClient := NewClient(fake.URL, "")
+
 	users, err := cloudianClient.ListUsers(context.Background(), "QA", nil)
 	if err != nil {
 		t.Errorf("Error listing users: %v", err)
@@ -150,6 +406,335 @@ func TestListUsers(t *testing.T) {
 
 }
 
+func TestUsersIter(t *testing.T) {
+	var expected []User
+	for i := range 2*ListLimit + 50 {
+		expected = append(expected, User{GroupUserID: GroupUserID{GroupID: "QA", UserID: fmt.Sprintf("%03d", i)}})
+	}
+
+	fake := cloudiantest.NewFakeServer(t)
+	addUsers(fake, expected...)
+	cloudianClient := NewClient(fake.URL, "")
+
+	var got []User
+	for user, err := range cloudianClient.UsersIter(context.Background(), "QA") {
+		if err != nil {
+			t.Fatalf("UsersIter(): %v", err)
+		}
+		got = append(got, user)
+		if len(got) == ListLimit+1 {
+			break
+		}
+	}
+	if diff := cmp.Diff(expected[:ListLimit+1], got); diff != "" {
+		t.Errorf("UsersIter() mismatch (-want +got):\n%s", diff)
+	}
+	if n := fake.Requests("/user/list"); n != 2 {
+		t.Errorf("UsersIter(): want the pages after stopping not fetched, got %d requests", n)
+	}
+
+	// An error ends the iteration.
+	var users int
+	var iterErr error
+	for _, err := range cloudianClient.UsersIter(context.Background(), "QA") {
+		if err != nil {
+			iterErr = err
+			continue
+		}
+		if users++; users == ListLimit {
+			fake.FailNext(1)
+		}
+	}
+	if users != ListLimit || iterErr == nil {
+		t.Errorf("UsersIter(): want %d users and an error, got %d users and %v", ListLimit, users, iterErr)
+	}
+}
+
+func TestCountUsers(t *testing.T) {
+	fake := cloudiantest.NewFakeServer(t)
+	for i := range ListLimit + 5 {
+		addUsers(fake, User{GroupUserID: GroupUserID{GroupID: "QA", UserID: fmt.Sprintf("%03d", i)}})
+	}
+	cloudianClient := NewClient(fake.URL, "")
+
+	count, err := cloudianClient.CountUsers(context.Background(), "QA")
+	if err != nil || count != ListLimit+5 {
+		t.Errorf("CountUsers(): want %d, got %d, %v", ListLimit+5, count, err)
+	}
+	if n := fake.Requests("/user/list"); n != 2 {
+		t.Errorf("CountUsers(): want every page fetched, got %d requests", n)
+	}
+	if count, err := cloudianClient.CountUsers(context.Background(), "empty"); err != nil || count != 0 {
+		t.Errorf("CountUsers() of an empty group: want 0, got %d, %v", count, err)
+	}
+
+	has, err := cloudianClient.HasUsers(context.Background(), "QA")
+	if err != nil || !has {
+		t.Errorf("HasUsers(): want true, got %t, %v", has, err)
+	}
+	if n := fake.Requests("/user/list"); n != 4 {
+		t.Errorf("HasUsers(): want a single page fetched, got %d requests in total", n)
+	}
+	if has, err := cloudianClient.HasUsers(context.Background(), "empty"); err != nil || has {
+		t.Errorf("HasUsers() of an empty group: want false, got %t, %v", has, err)
+	}
+
+	fake.FailNext(1)
+	if _, err := cloudianClient.CountUsers(context.Background(), "QA"); err == nil {
+		t.Error("CountUsers(): want an error when a page cannot be fetched")
+	}
+}
+
+func TestDeleteGroupRecursivePages(t *testing.T) {
+	fake := cloudiantest.NewFakeServer(t)
+	fake.AddGroup("QA")
+	var want []string
+	for i := range 2*ListLimit + 1 {
+		id := fmt.Sprintf("%03d", i)
+		addUsers(fake, User{GroupUserID: GroupUserID{GroupID: "QA", UserID: id}})
+		want = append(want, id)
+	}
+	cloudianClient := NewClient(fake.URL, "")
+
+	report, err := cloudianClient.DeleteGroupRecursive(context.TODO(), "QA", true)
+	if err != nil {
+		t.Fatalf("Error deleting group: %v", err)
+	}
+	if diff := cmp.Diff(want, report.Succeeded); diff != "" {
+		t.Errorf("DeleteGroupRecursive() deleted members mismatch (-want +got):\n%s", diff)
+	}
+	if fake.HasGroup("QA") {
+		t.Error("DeleteGroupRecursive(): group was not deleted")
+	}
+}
+
+func TestListUsersPage(t *testing.T) {
+	var all []User
+	for i := range 25 {
+		all = append(all, User{GroupUserID: GroupUserID{GroupID: "QA", UserID: fmt.Sprintf("%02d", i)}})
+	}
+
+	fake := cloudiantest.NewFakeServer(t)
+	addUsers(fake, all...)
+	cloudianClient := NewClient(fake.URL, "")
+
+	cases := map[string]struct {
+		reason   string
+		offset   string
+		want     []User
+		wantNext string
+		wantErr  error
+	}{
+		"First": {
+			reason:   "The first page should start from the first user and tell where the next starts.",
+			want:     all[:10],
+			wantNext: "10",
+		},
+		"Middle": {
+			reason:   "A page should start from its offset and tell where the next starts.",
+			offset:   "10",
+			want:     all[10:20],
+			wantNext: "20",
+		},
+		"Last": {
+			reason: "The last page should have no next offset.",
+			offset: "20",
+			want:   all[20:],
+		},
+		"InvalidOffset": {
+			reason:  "An offset that cannot be a user ID should be refused.",
+			offset:  "not/a/user",
+			wantErr: ErrInvalidID,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, next, err := cloudianClient.ListUsersPage(context.Background(), "QA", tc.offset, 10)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("\n%s\nListUsersPage(...): want error %v, got %v", tc.reason, tc.wantErr, err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("\n%s\nListUsersPage(...): -want users, +got users:\n%s", tc.reason, diff)
+			}
+			if next != tc.wantNext {
+				t.Errorf("\n%s\nListUsersPage(...): want next offset %q, got %q", tc.reason, tc.wantNext, next)
+			}
+		})
+	}
+}
+
+func TestListUserBuckets(t *testing.T) {
+	fake := cloudiantest.NewFakeServer(t)
+	fake.AddBucket("QA", "alice", cloudiantest.Bucket{Name: "reports", CreateTime: "2024-05-03T10:00:00Z", ObjectCount: 3, ByteCount: 2048})
+	fake.AddBucket("QA", "alice", cloudiantest.Bucket{Name: "scratch"})
+	fake.AddBucket("QA", "bob", cloudiantest.Bucket{Name: "backups", ObjectCount: 1, ByteCount: 1})
+	cloudianClient := NewClient(fake.URL, "")
+
+	got, err := cloudianClient.ListUserBuckets(context.Background(), GroupUserID{GroupID: "QA", UserID: "alice"})
+	if err != nil {
+		t.Fatalf("ListUserBuckets(): %v", err)
+	}
+	want := []Bucket{
+		{Name: "reports", CreationDate: time.Date(2024, 5, 3, 10, 0, 0, 0, time.UTC), ObjectCount: 3, Bytes: 2048},
+		{Name: "scratch"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ListUserBuckets(): -want, +got:\n%s", diff)
+	}
+
+	got, err = cloudianClient.ListUserBuckets(context.Background(), GroupUserID{GroupID: "QA", UserID: "carol"})
+	if err != nil || got != nil {
+		t.Errorf("ListUserBuckets() of a user without buckets: want none, got %v, %v", got, err)
+	}
+	if n := fake.Requests("/usage/bucket"); n != 1 {
+		t.Errorf("ListUserBuckets(): want the usage requested only for users with buckets, got %d requests", n)
+	}
+}
+
+func TestListUsersPageCallback(t *testing.T) {
+	var expected []User
+	for i := 0; i < 10*ListLimit+1; i++ {
+		expected = append(expected, User{GroupUserID: GroupUserID{GroupID: "QA", UserID: fmt.Sprintf("%04d", i)}})
+	}
+
+	fake := cloudiantest.NewFakeServer(t)
+	addUsers(fake, expected...)
+	cloudianClient := NewClient(fake.URL, "")
+
+	var progress []PageProgress
+	ctx := WithPageCallback(context.Background(), 3, func(p PageProgress) {
+		progress = append(progress, p)
+	})
+
+	users, err := cloudianClient.ListUsers(ctx, "QA", nil)
+	if err != nil {
+		t.Fatalf("Error listing users: %v", err)
+	}
+	if len(users) != len(expected) {
+		t.Errorf("ListUsers() got %d users, expected %d", len(users), len(expected))
+	}
+
+	want := []PageProgress{
+		{GroupID: "QA", Pages: 3, Items: 3 * ListLimit},
+		{GroupID: "QA", Pages: 6, Items: 6 * ListLimit},
+		{GroupID: "QA", Pages: 9, Items: 9 * ListLimit},
+	}
+	if diff := cmp.Diff(want, progress); diff != "" {
+		t.Errorf("WithPageCallback() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestSearchUsers(t *testing.T) {
+	var all, expected []User
+	for i := 0; i < 2*ListLimit; i++ {
+		user := User{GroupUserID: GroupUserID{GroupID: "QA", UserID: fmt.Sprintf("svc-%03d", i)}, UserType: UserTypeStandard}
+		all = append(all, user, User{GroupUserID: GroupUserID{GroupID: "QA", UserID: fmt.Sprintf("app-%03d", i)}})
+		expected = append(expected, user)
+	}
+
+	fake := cloudiantest.NewFakeServer(t)
+	addUsers(fake, all...)
+	cloudianClient := NewClient(fake.URL, "")
+
+	users, err := cloudianClient.SearchUsers(context.Background(), "QA", "svc-")
+	if err != nil {
+		t.Fatalf("Error searching users: %v", err)
+	}
+	if diff := cmp.Diff(expected, users); diff != "" {
+		t.Errorf("SearchUsers() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestUpdateGroupIfMatches(t *testing.T) {
+	fake := cloudiantest.NewFakeServer(t)
+	cloudianClient := NewClient(fake.URL, "", WithGroupCache(time.Hour))
+	ctx := context.TODO()
+
+	if _, err := cloudianClient.CreateGroup(ctx, Group{GroupID: "QA", GroupName: "Quality", Active: true}); err != nil {
+		t.Fatal(err)
+	}
+	observed, err := cloudianClient.GetGroup(ctx, "QA")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Another replica renames the group behind the cache of this client.
+	other := NewClient(fake.URL, "")
+	renamed := *observed
+	renamed.GroupName = "Renamed"
+	if err := other.UpdateGroup(ctx, renamed); err != nil {
+		t.Fatal(err)
+	}
+
+	desired := *observed
+	desired.Active = false
+	if err := cloudianClient.UpdateGroupIfMatches(ctx, desired, *observed); !errors.Is(err, ErrConflict) {
+		t.Fatalf("UpdateGroupIfMatches() of a changed group: want ErrConflict, got %v", err)
+	}
+	got, err := other.GetGroup(ctx, "QA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(renamed, *got); diff != "" {
+		t.Errorf("UpdateGroupIfMatches() of a changed group: want the group kept (-want +got):\n%s", diff)
+	}
+
+	// Once observed again, the group is updated.
+	observed, err = cloudianClient.GetGroup(ctx, "QA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	desired = *observed
+	desired.Active = false
+	if err := cloudianClient.UpdateGroupIfMatches(ctx, desired, *observed); err != nil {
+		t.Fatalf("UpdateGroupIfMatches() of an unchanged group: %v", err)
+	}
+	if got, err := other.GetGroup(ctx, "QA"); err != nil || got.Active {
+		t.Errorf("UpdateGroupIfMatches() of an unchanged group: want the group deactivated, got %+v, %v", got, err)
+	}
+}
+
+func TestListGroupsByPrefix(t *testing.T) {
+	var all []groupInternal
+	var expected []Group
+	for i := 0; i < ListLimit+10; i++ {
+		group := groupInternal{GroupID: fmt.Sprintf("team-%03d", i), Active: "true"}
+		all = append(all, group, groupInternal{GroupID: fmt.Sprintf("other-%03d", i)})
+		expected = append(expected, fromInternal(group))
+	}
+
+	var prefixes []string
+	cloudianClient, testServer := mockBy(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/group/list" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		prefixes = append(prefixes, r.URL.Query().Get("prefix"))
+		var matching []groupInternal
+		for _, g := range all {
+			if strings.HasPrefix(g.GroupID, r.URL.Query().Get("prefix")) {
+				matching = append(matching, g)
+			}
+		}
+		index := slices.IndexFunc(matching, func(g groupInternal) bool { return g.GroupID == r.URL.Query().Get("offset") })
+		index = max(index, 0)
+		json.NewEncoder(w).Encode(matching[index:min(index+ListLimit+1, len(matching))])
+	})
+	defer testServer.Close()
+
+	groups, err := cloudianClient.ListGroupsByPrefix(context.Background(), "team-")
+	if err != nil {
+		t.Fatalf("Error listing groups: %v", err)
+	}
+	if diff := cmp.Diff(expected, groups); diff != "" {
+		t.Errorf("ListGroupsByPrefix() mismatch (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"team-", "team-"}, prefixes); diff != "" {
+		t.Errorf("ListGroupsByPrefix() prefixes mismatch (-want +got):\n%s", diff)
+	}
+}
+
 func mockBy(handler http.HandlerFunc) (*Client, *httptest.Server) {
 	mockServer := httptest.NewServer(handler)
 	return NewClient(mockServer.URL, ""), mockServer
@@ -159,27 +744,15 @@ func TestClient_GetUser(t *testing.T) {
 	tests := []struct {
 		name    string
 		user    User
-		status  int
 		wantErr error
 	}{
-		{name: "Exists", user: User{GroupUserID: GroupUserID{UserID: strconv.Itoa(http.StatusOK)}, CanonicalID: "123"}},
-		{name: "Not found", user: User{GroupUserID: GroupUserID{UserID: strconv.Itoa(http.StatusNoContent)}, CanonicalID: "123"}, wantErr: ErrNotFound},
+		{name: "Exists", user: User{GroupUserID: GroupUserID{GroupID: "QA", UserID: "alice"}, UserType: UserTypeStandard, CanonicalID: "123"}},
+		{name: "Not found", user: User{GroupUserID: GroupUserID{GroupID: "QA", UserID: "bob"}}, wantErr: ErrNotFound},
 	}
 
-	client, testServer := mockBy(func(w http.ResponseWriter, r *http.Request) {
-		userId := r.URL.Query().Get("userId")
-		statusCode, _ := strconv.Atoi(userId)
-		if statusCode == http.StatusOK {
-			for _, tt := range tests {
-				if tt.user.UserID == userId {
-					json.NewEncoder(w).Encode(tt.user)
-					break
-				}
-			}
-		}
-		w.WriteHeader(statusCode)
-	})
-	defer testServer.Close()
+	fake := cloudiantest.NewFakeServer(t)
+	fake.AddUser(cloudiantest.User{GroupID: "QA", UserID: "alice", UserType: string(UserTypeStandard), CanonicalID: "123"})
+	client := NewClient(fake.URL, "")
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -202,3 +775,2317 @@ func TestClient_GetUser(t *testing.T) {
 		})
 	}
 }
+
+func TestVersion(t *testing.T) {
+	cloudianClient, testServer := mockBy(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/system/version" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte("8.1.2\n"))
+	})
+	defer testServer.Close()
+
+	version, err := cloudianClient.Version(context.TODO())
+	if err != nil {
+		t.Errorf("Error getting version: %v", err)
+	}
+	if version != "8.1.2" {
+		t.Errorf("Version() got = %q, expected %q", version, "8.1.2")
+	}
+}
+
+func TestSecretFormat(t *testing.T) {
+	secret := NewSecret("s3cr3t")
+	for _, format := range []string{"%v", "%+v", "%#v", "%s", "%q", "%x", "%10s"} {
+		if got := fmt.Sprintf(format, secret); strings.Contains(got, "s3cr3t") || !strings.Contains(got, redacted) {
+			t.Errorf("Sprintf(%q, secret): want the value redacted, got %q", format, got)
+		}
+	}
+	if got := fmt.Sprintf("%+v", SecurityInfo{AccessKey: "123", SecretKey: secret}); strings.Contains(got, "s3cr3t") {
+		t.Errorf("Sprintf(%q, SecurityInfo{...}): want the secret key redacted, got %q", "%+v", got)
+	}
+}
+
+func TestSecretJSON(t *testing.T) {
+	want := SecurityInfo{AccessKey: "123", SecretKey: NewSecret(`a+b/c"d`)}
+	b, err := json.Marshal(want)
+	if err != nil {
+		t.Fatalf("json.Marshal(...): %v", err)
+	}
+	if got := string(b); got != `{"accessKey":"123","secretKey":"a+b/c\"d"}` {
+		t.Errorf("json.Marshal(...): want the secret key as is, got %s", got)
+	}
+
+	var got SecurityInfo
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("json.Unmarshal(...): %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("json.Unmarshal(...): -want, +got:\n%s", diff)
+	}
+}
+
+func TestSecretEqual(t *testing.T) {
+	cases := map[string]struct {
+		a, b string
+		want bool
+	}{
+		"Same":      {a: "secret", b: "secret", want: true},
+		"Different": {a: "secret", b: "secreT"},
+		"Prefix":    {a: "secret", b: "secretkey"},
+		"Empty":     {a: "", b: "", want: true},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := NewSecret(tc.a).Equal(NewSecret(tc.b)); got != tc.want {
+				t.Errorf("Equal(): want %t, got %t", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestPing(t *testing.T) {
+	server := cloudiantest.NewServer(t, nil)
+	server.Script("/system/version", cloudiantest.Respond(http.StatusUnauthorized, ""), cloudiantest.Respond(http.StatusOK, "8.1.2"))
+	cloudianClient := NewClient(server.URL, "")
+
+	var statusErr *StatusError
+	if err := cloudianClient.Ping(context.TODO()); !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Ping(): want a %d StatusError, got %v", http.StatusUnauthorized, err)
+	}
+	if err := cloudianClient.Ping(context.TODO()); err != nil {
+		t.Errorf("Ping(): %v", err)
+	}
+}
+
+func TestForRegion(t *testing.T) {
+	cases := map[string]struct {
+		reason  string
+		regions []string
+		region  string
+		want    string
+		wantErr error
+	}{
+		"Default": {
+			reason:  "The default region should be served by the default endpoint.",
+			regions: []string{"eu"},
+			region:  DefaultRegion,
+			want:    "default",
+		},
+		"Regional": {
+			reason:  "A region with an endpoint of its own should be served by it.",
+			regions: []string{"eu"},
+			region:  "eu",
+			want:    "eu",
+		},
+		"NoRegions": {
+			reason: "Every region should be served by the default endpoint when there are no regional ones.",
+			region: "eu",
+			want:   "default",
+		},
+		"Unknown": {
+			reason:  "A region without an endpoint should be refused when there are regional ones.",
+			regions: []string{"eu"},
+			region:  "us",
+			wantErr: ErrUnknownRegion,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			servers := map[string]*cloudiantest.Server{"default": cloudiantest.NewServer(t, nil)}
+			var opts []func(*Client)
+			for _, region := range tc.regions {
+				servers[region] = cloudiantest.NewServer(t, nil)
+				opts = append(opts, WithRegion(region, NewClient(servers[region].URL, "")))
+			}
+			cloudianClient := NewClient(servers["default"].URL, "", opts...)
+			for _, server := range servers {
+				server.Script("/system/version", cloudiantest.Respond(http.StatusOK, "8.1.2"))
+			}
+
+			regional, err := cloudianClient.ForRegion(tc.region)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("\n%s\nForRegion(%q): want error %v, got %v", tc.reason, tc.region, tc.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if err := regional.Ping(context.TODO()); err != nil {
+				t.Fatalf("\n%s\nPing(): %v", tc.reason, err)
+			}
+			for name, server := range servers {
+				want := 0
+				if name == tc.want {
+					want = 1
+				}
+				if got := server.Requests("/system/version"); got != want {
+					t.Errorf("\n%s\nForRegion(%q): want %d requests to the %s endpoint, got %d", tc.reason, tc.region, want, name, got)
+				}
+			}
+		})
+	}
+}
+
+func TestWithRateLimit(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("8.1.2"))
+	}))
+	defer testServer.Close()
+
+	cloudianClient := NewClient(testServer.URL, "", WithRateLimit(1, 1))
+
+	if _, err := cloudianClient.Version(context.TODO()); err != nil {
+		t.Fatalf("Error getting version: %v", err)
+	}
+
+	// The burst is spent, so the next request must wait and gives up when the context is done.
+	ctx, cancel := context.WithTimeout(context.TODO(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := cloudianClient.Version(ctx); err == nil {
+		t.Error("Version() expected error when rate limited past the context deadline")
+	}
+}
+
+func TestBackgroundBudget(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {}))
+	defer testServer.Close()
+	cloudianClient := NewClient(testServer.URL, "", WithRateLimit(100, 10), WithBackgroundBudget(0.5))
+
+	// The burst of the budget is 5 requests, and the rest are sent at 50 per second.
+	guids := make([]GroupUserID, 10)
+	for i := range guids {
+		guids[i] = GroupUserID{GroupID: "QA", UserID: strconv.Itoa(i)}
+	}
+	start := time.Now()
+	if err := cloudianClient.DeleteUsers(context.TODO(), guids, len(guids)); err != nil {
+		t.Fatalf("DeleteUsers(): %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("DeleteUsers(): want background requests limited to their budget, all were sent in %s", elapsed)
+	}
+}
+
+func TestBackgroundDoesNotDelayInteractive(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("8.1.2"))
+	}))
+	defer testServer.Close()
+	const rps = 20
+	cloudianClient := NewClient(testServer.URL, "", WithRateLimit(rps, 1))
+
+	guids := make([]GroupUserID, 20)
+	for i := range guids {
+		guids[i] = GroupUserID{GroupID: "QA", UserID: strconv.Itoa(i)}
+	}
+	ctx, cancel := context.WithCancel(context.TODO())
+	defer cancel()
+	bulk := make(chan error)
+	go func() { bulk <- cloudianClient.DeleteUsers(ctx, guids, DefaultConcurrency) }()
+	time.Sleep(2 * time.Second / rps)
+
+	// Without the budget, an interactive request would wait behind every
+	// background request in flight. With it, at most the burst of the budget
+	// is ahead of it, besides the request it waits for its own turn after.
+	const bound = 5 * time.Second / rps
+	for range 3 {
+		start := time.Now()
+		if _, err := cloudianClient.Version(context.TODO()); err != nil {
+			t.Fatalf("Version(): %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > bound {
+			t.Errorf("Version(): want interactive request sent within %s while a bulk operation runs, took %s", bound, elapsed)
+		}
+	}
+
+	cancel()
+	<-bulk
+}
+
+func TestExpectedStatuses(t *testing.T) {
+	guid := GroupUserID{GroupID: "QA", UserID: "user1"}
+
+	endpoints := map[string]func(*Client) error{
+		"GetUser": func(c *Client) error {
+			_, err := c.GetUser(context.TODO(), guid)
+			return err
+		},
+		"DeleteUser": func(c *Client) error {
+			return c.DeleteUser(context.TODO(), guid)
+		},
+		"GetUserCredentials": func(c *Client) error {
+			_, err := c.GetUserCredentials(context.TODO(), "123")
+			return err
+		},
+		"GetGroup": func(c *Client) error {
+			_, err := c.GetGroup(context.TODO(), "QA")
+			return err
+		},
+		"CreateGroup": func(c *Client) error {
+			_, err := c.CreateGroup(context.TODO(), NewGroup("QA"))
+			return err
+		},
+		"DeleteQOS": func(c *Client) error {
+			return c.DeleteQOS(context.TODO(), guid, DefaultRegion)
+		},
+	}
+
+	cases := map[string]struct {
+		status       int
+		wantNotFound map[string]bool
+		wantWarning  map[string]bool
+	}{
+		"OK": {
+			status: http.StatusOK,
+		},
+		"Created": {
+			status:      http.StatusCreated,
+			wantWarning: map[string]bool{"GetUser": true, "DeleteUser": true, "GetUserCredentials": true, "GetGroup": true, "CreateGroup": true, "DeleteQOS": true},
+		},
+		"Accepted": {
+			status:      http.StatusAccepted,
+			wantWarning: map[string]bool{"GetUser": true, "DeleteUser": true, "GetUserCredentials": true, "GetGroup": true, "CreateGroup": true, "DeleteQOS": true},
+		},
+		"NoContent": {
+			status:       http.StatusNoContent,
+			wantNotFound: map[string]bool{"GetUser": true, "DeleteUser": true, "GetUserCredentials": true, "GetGroup": true},
+			wantWarning:  map[string]bool{"CreateGroup": true, "DeleteQOS": true},
+		},
+	}
+
+	for name, tc := range cases {
+		for endpoint, call := range endpoints {
+			t.Run(name+"/"+endpoint, func(t *testing.T) {
+				cloudianClient, testServer := mockBy(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(tc.status)
+					if tc.status != http.StatusNoContent {
+						_, _ = w.Write([]byte("{}"))
+					}
+				})
+				defer testServer.Close()
+
+				warned := false
+				cloudianClient.warnf = func(string, ...any) { warned = true }
+
+				err := call(cloudianClient)
+				if tc.wantNotFound[endpoint] {
+					if !errors.Is(err, ErrNotFound) {
+						t.Errorf("Expected error to be ErrNotFound, got %v", err)
+					}
+				} else if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				if warned != tc.wantWarning[endpoint] {
+					t.Errorf("Expected warning %t, got %t", tc.wantWarning[endpoint], warned)
+				}
+			})
+		}
+	}
+}
+
+func TestUnexpectedStatusError(t *testing.T) {
+	cloudianClient, testServer := mockBy(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	defer testServer.Close()
+
+	err := cloudianClient.DeleteGroup(context.TODO(), "QA")
+
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("Expected error to be a StatusError, got %v", err)
+	}
+	want := StatusError{Method: http.MethodDelete, Path: "/group", StatusCode: http.StatusInternalServerError}
+	if diff := cmp.Diff(want, *statusErr); diff != "" {
+		t.Errorf("DeleteGroup() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestWithCACert(t *testing.T) {
+	testServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("8.1.2"))
+	}))
+	defer testServer.Close()
+
+	caCert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: testServer.Certificate().Raw})
+
+	if _, err := NewClient(testServer.URL, "").Version(context.TODO()); err == nil {
+		t.Error("Version() expected error when the server certificate is signed by an unknown CA")
+	}
+	if _, err := NewClient(testServer.URL, "", WithCACert(caCert)).Version(context.TODO()); err != nil {
+		t.Errorf("Error getting version with CA certificate: %v", err)
+	}
+}
+
+func TestWithServerName(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		DNSNames:              []string{"cloudian.example.com"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testServer := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("8.1.2"))
+	}))
+	testServer.TLS = &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}, MinVersion: tls.VersionTLS12}
+	testServer.StartTLS()
+	defer testServer.Close()
+
+	caCert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+
+	cases := map[string]struct {
+		reason  string
+		opts    []func(*Client)
+		wantErr bool
+	}{
+		"EndpointHostname": {
+			reason:  "The certificate is not valid for the IP address of the endpoint.",
+			opts:    []func(*Client){WithCACert(caCert)},
+			wantErr: true,
+		},
+		"ServerName": {
+			reason: "The certificate should be verified against the server name.",
+			opts:   []func(*Client){WithCACert(caCert), WithServerName("cloudian.example.com")},
+		},
+		"ServerNameBeforeCACert": {
+			reason: "The server name should be kept when the CA certificates are set after it.",
+			opts:   []func(*Client){WithServerName("cloudian.example.com"), WithCACert(caCert)},
+		},
+		"UnknownCA": {
+			reason:  "The certificate chain should still be verified with a server name.",
+			opts:    []func(*Client){WithServerName("cloudian.example.com")},
+			wantErr: true,
+		},
+		"OtherServerName": {
+			reason:  "The certificate is not valid for another server name.",
+			opts:    []func(*Client){WithCACert(caCert), WithServerName("other.example.com")},
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewClient(testServer.URL, "", tc.opts...).Version(context.TODO())
+			if gotErr := err != nil; gotErr != tc.wantErr {
+				t.Errorf("%s\nVersion(): want error %t, got %v", tc.reason, tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestContextDeadline(t *testing.T) {
+	server := cloudiantest.NewServer(t, nil)
+	server.SetLatency(5 * time.Second)
+	cloudianClient := NewClient(server.URL, "")
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := cloudianClient.GetGroup(ctx, "QA")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected error to be context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("GetGroup() took %s, expected to be cancelled after 50ms", elapsed)
+	}
+}
+
+func TestTimeouts(t *testing.T) {
+	testServer := cloudiantest.NewServer(t, nil)
+	testServer.SetLatency(5 * time.Second)
+
+	cases := map[string]func(*Client){
+		"CallTimeout": WithCallTimeout(50 * time.Millisecond),
+		"HTTPTimeout": func(c *Client) {
+			WithCallTimeout(0)(c)
+			WithTimeout(50 * time.Millisecond)(c)
+		},
+	}
+
+	for name, opt := range cases {
+		t.Run(name, func(t *testing.T) {
+			cloudianClient := NewClient(testServer.URL, "", opt)
+
+			start := time.Now()
+			_, err := cloudianClient.GetGroup(context.TODO(), "QA")
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("Expected error to wrap context.DeadlineExceeded, got %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("GetGroup() took %s, expected to time out after 50ms", elapsed)
+			}
+		})
+	}
+}
+
+func TestTransportOptionsCompose(t *testing.T) {
+	testServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("slow") {
+			time.Sleep(time.Second)
+		}
+		_, _ = w.Write([]byte("8.1.2"))
+	}))
+	defer testServer.Close()
+
+	cases := map[string][]func(*Client){
+		"InsecureFirst": {WithInsecureTLSVerify(true), WithTimeout(5 * time.Second)},
+		"TimeoutFirst":  {WithTimeout(5 * time.Second), WithInsecureTLSVerify(true)},
+	}
+
+	for name, opts := range cases {
+		t.Run(name, func(t *testing.T) {
+			cloudianClient := NewClient(testServer.URL, "", opts...)
+			if got := cloudianClient.client.GetClient().Timeout; got != 5*time.Second {
+				t.Errorf("NewClient(...): want timeout 5s, got %s", got)
+			}
+			if _, err := cloudianClient.Version(context.TODO()); err != nil {
+				t.Errorf("Version(): want the certificate not verified, got %v", err)
+			}
+
+			// A shorter timeout is still applied along with the TLS options.
+			cloudianClient = NewClient(testServer.URL, "", append(opts, WithCallTimeout(0), WithTimeout(50*time.Millisecond))...)
+			req := cloudianClient.newRequest(context.TODO()).SetQueryParam("slow", "true")
+			if _, err := cloudianClient.doJSON(req, http.MethodGet, "/system/version", 200); !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("doJSON(...): want the request to time out, got %v", err)
+			}
+		})
+	}
+}
+
+// countingTransport counts the requests it sends.
+type countingTransport struct {
+	base     http.RoundTripper
+	requests atomic.Int32
+}
+
+func (c *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	c.requests.Add(1)
+	return c.base.RoundTrip(r)
+}
+
+func TestWithHTTPClient(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("8.1.2"))
+	}))
+	defer testServer.Close()
+
+	transport := &countingTransport{base: http.DefaultTransport}
+	hc := &http.Client{Transport: transport}
+	cloudianClient := NewClient(testServer.URL, "", WithHTTPClient(hc), WithTimeout(5*time.Second))
+
+	if _, err := cloudianClient.Version(context.TODO()); err != nil {
+		t.Fatalf("Version(): %v", err)
+	}
+	if n := transport.requests.Load(); n != 1 {
+		t.Errorf("Version(): want the request sent through the given client, got %d requests", n)
+	}
+	if hc.Timeout != 0 {
+		t.Errorf("NewClient(...): want the given client left unchanged, got timeout %s", hc.Timeout)
+	}
+	if got := cloudianClient.client.GetClient().Timeout; got != 5*time.Second {
+		t.Errorf("NewClient(...): want timeout 5s applied to the copy, got %s", got)
+	}
+}
+
+// groupServer serves a single group whose name is changed by updates, and
+// counts the reads.
+func groupServer(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var (
+		mu    sync.Mutex
+		name  = "initial"
+		reads atomic.Int32
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch r.Method {
+		case http.MethodGet:
+			reads.Add(1)
+			_ = json.NewEncoder(w).Encode(groupInternal{GroupID: "QA", GroupName: name})
+		case http.MethodPost:
+			var g groupInternal
+			_ = json.NewDecoder(r.Body).Decode(&g)
+			name = g.GroupName
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &reads
+}
+
+func TestWithDialContext(t *testing.T) {
+	var host string
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host = r.Host
+		_, _ = w.Write([]byte("8.1.2"))
+	}))
+	defer testServer.Close()
+
+	var dialed []string
+	dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialed = append(dialed, addr)
+		var d net.Dialer
+		return d.DialContext(ctx, network, testServer.Listener.Addr().String())
+	}
+	// Like kubectl port-forward to an endpoint unreachable from here.
+	cloudianClient := NewClient("http://cloudian.invalid:19443", "", WithDialContext(dial), WithTimeout(5*time.Second))
+
+	if _, err := cloudianClient.Version(context.TODO()); err != nil {
+		t.Fatalf("Version(): %v", err)
+	}
+	if diff := cmp.Diff([]string{"cloudian.invalid:19443"}, dialed); diff != "" {
+		t.Errorf("Version(): dialed addresses mismatch (-want +got):\n%s", diff)
+	}
+	if host != "cloudian.invalid:19443" {
+		t.Errorf("Version(): want the request sent to the endpoint, got host %q", host)
+	}
+}
+
+// serveSOCKS5 serves one connection of a SOCKS5 client without
+// authentication, connecting it to `target` whatever it asks for, and returns
+// the address it asked for.
+func serveSOCKS5(t *testing.T, l net.Listener, target string) <-chan string {
+	t.Helper()
+	requested := make(chan string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+
+		// Greeting: version, methods. Choose no authentication.
+		buf := make([]byte, 262)
+		if _, err := io.ReadFull(conn, buf[:2]); err != nil {
+			return
+		}
+		if _, err := io.ReadFull(conn, buf[:buf[1]]); err != nil {
+			return
+		}
+		_, _ = conn.Write([]byte{5, 0})
+
+		// Request: version, CONNECT, reserved, a domain name and a port.
+		if _, err := io.ReadFull(conn, buf[:5]); err != nil || buf[3] != 3 {
+			return
+		}
+		n := int(buf[4])
+		if _, err := io.ReadFull(conn, buf[:n+2]); err != nil {
+			return
+		}
+		requested <- net.JoinHostPort(string(buf[:n]), strconv.Itoa(int(buf[n])<<8|int(buf[n+1])))
+
+		upstream, err := net.Dial("tcp", target)
+		if err != nil {
+			return
+		}
+		defer func() { _ = upstream.Close() }()
+		_, _ = conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+		go func() { _, _ = io.Copy(upstream, conn) }()
+		_, _ = io.Copy(conn, upstream)
+	}()
+	return requested
+}
+
+func TestWithSOCKS5(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("8.1.2"))
+	}))
+	defer testServer.Close()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = l.Close() }()
+	requested := serveSOCKS5(t, l, testServer.Listener.Addr().String())
+
+	cloudianClient := NewClient("http://cloudian.internal:19443", "", WithSOCKS5(l.Addr().String(), nil), WithTimeout(5*time.Second))
+	if _, err := cloudianClient.Version(context.TODO()); err != nil {
+		t.Fatalf("Version(): %v", err)
+	}
+	if got := <-requested; got != "cloudian.internal:19443" {
+		t.Errorf("Version(): want the proxy to connect to the endpoint, got %q", got)
+	}
+}
+
+func TestGroupCache(t *testing.T) {
+	server, reads := groupServer(t)
+	clock := testingclock.NewFakePassiveClock(time.Now())
+	cloudianClient := NewClient(server.URL, "", WithGroupCache(time.Minute), WithClock(clock))
+
+	for range 3 {
+		if _, err := cloudianClient.GetGroup(context.TODO(), "QA"); err != nil {
+			t.Fatalf("GetGroup(): %v", err)
+		}
+	}
+	if got := reads.Load(); got != 1 {
+		t.Errorf("GetGroup() read the group %d times, want 1", got)
+	}
+
+	if err := cloudianClient.UpdateGroup(context.TODO(), Group{GroupID: "QA", GroupName: "updated"}); err != nil {
+		t.Fatalf("UpdateGroup(): %v", err)
+	}
+	group, err := cloudianClient.GetGroup(context.TODO(), "QA")
+	if err != nil {
+		t.Fatalf("GetGroup(): %v", err)
+	}
+	if group.GroupName != "updated" {
+		t.Errorf("GetGroup() after UpdateGroup(): want group name %q, got %q", "updated", group.GroupName)
+	}
+
+	clock.SetTime(clock.Now().Add(time.Minute))
+	if _, err := cloudianClient.GetGroup(context.TODO(), "QA"); err != nil {
+		t.Fatalf("GetGroup(): %v", err)
+	}
+	if got := reads.Load(); got != 3 {
+		t.Errorf("GetGroup() read the group %d times after update and expiry, want 3", got)
+	}
+}
+
+func TestGroupCacheConcurrent(t *testing.T) {
+	server, _ := groupServer(t)
+	cloudianClient := NewClient(server.URL, "", WithGroupCache(time.Minute))
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Go(func() {
+			if _, err := cloudianClient.GetGroup(context.TODO(), "QA"); err != nil {
+				t.Errorf("GetGroup(): %v", err)
+			}
+		})
+		wg.Go(func() {
+			if err := cloudianClient.UpdateGroup(context.TODO(), Group{GroupID: "QA", GroupName: strconv.Itoa(i)}); err != nil {
+				t.Errorf("UpdateGroup(): %v", err)
+			}
+		})
+	}
+	wg.Wait()
+
+	if err := cloudianClient.UpdateGroup(context.TODO(), Group{GroupID: "QA", GroupName: "last"}); err != nil {
+		t.Fatalf("UpdateGroup(): %v", err)
+	}
+	group, err := cloudianClient.GetGroup(context.TODO(), "QA")
+	if err != nil {
+		t.Fatalf("GetGroup(): %v", err)
+	}
+	if group.GroupName != "last" {
+		t.Errorf("GetGroup() after concurrent updates: want group name %q, got %q", "last", group.GroupName)
+	}
+}
+
+func TestDeleteGroupRecursive(t *testing.T) {
+	cases := map[string]struct {
+		force              bool
+		wantDeleted        []string
+		wantFailed         []string
+		wantHasCredentials bool
+	}{
+		"KeepUsersWithCredentials": {
+			wantDeleted:        []string{"user/plain"},
+			wantFailed:         []string{"broken", "keyed"},
+			wantHasCredentials: true,
+		},
+		"Force": {
+			force:       true,
+			wantDeleted: []string{"credentials/123", "user/keyed", "user/plain"},
+			wantFailed:  []string{"broken"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var mu sync.Mutex
+			var deleted []string
+			cloudianClient, testServer := mockBy(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				userID := r.URL.Query().Get("userId")
+				switch {
+				case r.URL.Path == "/user/list":
+					json.NewEncoder(w).Encode([]User{
+						{GroupUserID: GroupUserID{GroupID: "QA", UserID: "keyed"}},
+						{GroupUserID: GroupUserID{GroupID: "QA", UserID: "broken"}},
+						{GroupUserID: GroupUserID{GroupID: "QA", UserID: "plain"}},
+					})
+				case r.URL.Path == "/user/credentials/list" && userID == "keyed":
+					json.NewEncoder(w).Encode([]SecurityInfo{{AccessKey: "123"}})
+				case r.URL.Path == "/user/credentials/list":
+					w.WriteHeader(http.StatusNoContent)
+				case r.Method == http.MethodDelete && r.URL.Path == "/user/credentials":
+					deleted = append(deleted, "credentials/"+r.URL.Query().Get("accessKey"))
+				case r.URL.Path == "/user" && userID == "broken":
+					w.WriteHeader(http.StatusInternalServerError)
+				case r.Method == http.MethodDelete && r.URL.Path == "/user":
+					deleted = append(deleted, "user/"+userID)
+				case r.Method == http.MethodDelete && r.URL.Path == "/group":
+					deleted = append(deleted, "group/"+r.URL.Query().Get("groupId"))
+				}
+			})
+			defer testServer.Close()
+
+			report, err := cloudianClient.DeleteGroupRecursive(context.TODO(), "QA", tc.force)
+			// Members are deleted concurrently.
+			slices.Sort(deleted)
+			// The failing deletion does not stop the other users from being deleted.
+			var statusErr *StatusError
+			if !errors.As(err, &statusErr) {
+				t.Errorf("Expected error to contain a StatusError, got %v", err)
+			}
+			if got := errors.Is(err, ErrUserHasCredentials); got != tc.wantHasCredentials {
+				t.Errorf("Expected error to contain ErrUserHasCredentials %t, got %v", tc.wantHasCredentials, err)
+			}
+			if diff := cmp.Diff(tc.wantDeleted, deleted); diff != "" {
+				t.Errorf("DeleteGroupRecursive() deleted mismatch (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantFailed, report.failedIDs()); diff != "" {
+				t.Errorf("DeleteGroupRecursive() failed members mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestDeleteGroupRecursiveEmptiesGroup(t *testing.T) {
+	fake := cloudiantest.NewFakeServer(t)
+	fake.AddGroup("QA")
+	addUsers(fake,
+		User{GroupUserID: GroupUserID{GroupID: "QA", UserID: "a"}},
+		User{GroupUserID: GroupUserID{GroupID: "QA", UserID: "b"}},
+	)
+	cloudianClient := NewClient(fake.URL, "")
+
+	report, err := cloudianClient.DeleteGroupRecursive(context.TODO(), "QA", false)
+	if err != nil {
+		t.Fatalf("Error deleting group: %v", err)
+	}
+	if diff := cmp.Diff([]string{"a", "b"}, report.Succeeded); diff != "" {
+		t.Errorf("DeleteGroupRecursive() deleted members mismatch (-want +got):\n%s", diff)
+	}
+	for _, id := range []string{"a", "b"} {
+		if _, ok := fake.User("QA", id); ok {
+			t.Errorf("DeleteGroupRecursive(): user %s was not deleted", id)
+		}
+	}
+	if fake.HasGroup("QA") {
+		t.Error("DeleteGroupRecursive(): group was not deleted")
+	}
+}
+
+func TestDeleteGroupRecursiveServerErrors(t *testing.T) {
+	fake := cloudiantest.NewFakeServer(t)
+	fake.AddGroup("QA")
+	addUsers(fake, User{GroupUserID: GroupUserID{GroupID: "QA", UserID: "a"}})
+	cloudianClient := NewClient(fake.URL, "")
+
+	// Listing the credentials of the member fails.
+	fake.Script("/user/credentials/list", cloudiantest.Respond(http.StatusInternalServerError, ""))
+	if _, err := cloudianClient.DeleteGroupRecursive(context.TODO(), "QA", false); err == nil {
+		t.Fatal("DeleteGroupRecursive(): want an error when the admin API fails")
+	}
+	if !fake.HasGroup("QA") {
+		t.Error("DeleteGroupRecursive(): group with a member left was deleted")
+	}
+
+	// Listing the members fails.
+	fake.FailNext(1)
+	if _, err := cloudianClient.DeleteGroupRecursive(context.TODO(), "QA", false); err == nil {
+		t.Fatal("DeleteGroupRecursive(): want an error when the admin API fails")
+	}
+
+	if _, err := cloudianClient.DeleteGroupRecursive(context.TODO(), "QA", false); err != nil {
+		t.Fatalf("DeleteGroupRecursive(): want the retry to succeed, got %v", err)
+	}
+	if fake.HasGroup("QA") {
+		t.Error("DeleteGroupRecursive(): group was not deleted on retry")
+	}
+}
+
+func TestBulkConcurrency(t *testing.T) {
+	const concurrency = 4
+	var guids []GroupUserID
+	var users []User
+	for i := range 20 {
+		guid := GroupUserID{GroupID: "QA", UserID: fmt.Sprintf("%02d", i)}
+		guids = append(guids, guid)
+		users = append(users, User{GroupUserID: guid})
+	}
+
+	cases := map[string]func(*Client) error{
+		"CreateUsers": func(c *Client) error { return c.CreateUsers(context.TODO(), users, concurrency) },
+		"DeleteUsers": func(c *Client) error { return c.DeleteUsers(context.TODO(), guids, concurrency) },
+	}
+
+	for name, bulk := range cases {
+		t.Run(name, func(t *testing.T) {
+			var inFlight, peak atomic.Int32
+			server := cloudiantest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				n := inFlight.Add(1)
+				defer inFlight.Add(-1)
+				for {
+					p := peak.Load()
+					if n <= p || peak.CompareAndSwap(p, n) {
+						break
+					}
+				}
+				time.Sleep(10 * time.Millisecond)
+			}))
+
+			if err := bulk(NewClient(server.URL, "")); err != nil {
+				t.Fatalf("%s(): %v", name, err)
+			}
+			if got := peak.Load(); got > concurrency || got < 2 {
+				t.Errorf("%s(): want at most %d concurrent requests, and more than one, got %d", name, concurrency, got)
+			}
+			if got := server.Requests("/user"); got != len(users) {
+				t.Errorf("%s(): want %d requests, got %d", name, len(users), got)
+			}
+		})
+	}
+}
+
+func TestBulkError(t *testing.T) {
+	fake := cloudiantest.NewFakeServer(t)
+	addUsers(fake, User{GroupUserID: GroupUserID{GroupID: "QA", UserID: "b"}})
+	cloudianClient := NewClient(fake.URL, "")
+
+	users := []User{
+		{GroupUserID: GroupUserID{GroupID: "QA", UserID: "a"}},
+		{GroupUserID: GroupUserID{GroupID: "QA", UserID: "b"}},
+		{GroupUserID: GroupUserID{GroupID: "QA", UserID: "c"}},
+	}
+	err := cloudianClient.CreateUsers(context.TODO(), users, 2)
+	var bulkErr *BulkError
+	if !errors.As(err, &bulkErr) {
+		t.Fatalf("CreateUsers(): want a BulkError, got %v", err)
+	}
+	if diff := cmp.Diff([]GroupUserID{{GroupID: "QA", UserID: "b"}}, bulkErr.failedIDs()); diff != "" {
+		t.Errorf("CreateUsers() failed users mismatch (-want +got):\n%s", diff)
+	}
+	var statusErr *StatusError
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusConflict {
+		t.Errorf("CreateUsers(): want the error to contain a %d StatusError, got %v", http.StatusConflict, err)
+	}
+	for _, id := range []string{"a", "c"} {
+		if _, ok := fake.User("QA", id); !ok {
+			t.Errorf("CreateUsers(): user %s was not created", id)
+		}
+	}
+
+	// Users that do not exist are already deleted.
+	guids := []GroupUserID{{GroupID: "QA", UserID: "a"}, {GroupID: "QA", UserID: "missing"}}
+	if err := cloudianClient.DeleteUsers(context.TODO(), guids, 2); err != nil {
+		t.Errorf("DeleteUsers(): %v", err)
+	}
+	if _, ok := fake.User("QA", "a"); ok {
+		t.Error("DeleteUsers(): user a was not deleted")
+	}
+}
+
+func TestBulkCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server := cloudiantest.NewServer(t, http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		cancel()
+	}))
+
+	var guids []GroupUserID
+	for i := range 5 {
+		guids = append(guids, GroupUserID{GroupID: "QA", UserID: strconv.Itoa(i)})
+	}
+	err := NewClient(server.URL, "").DeleteUsers(ctx, guids, 1)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("DeleteUsers(): want context.Canceled, got %v", err)
+	}
+	var bulkErr *BulkError
+	if !errors.As(err, &bulkErr) || len(bulkErr.Failed) < len(guids)-1 {
+		t.Errorf("DeleteUsers(): want the users after the cancellation to fail, got %v", err)
+	}
+	if got := server.Requests("/user"); got != 1 {
+		t.Errorf("DeleteUsers(): want no requests after the cancellation, got %d", got)
+	}
+}
+
+func TestReport(t *testing.T) {
+	var report Report
+	report.succeed("ok")
+	for _, id := range []string{"c", "a", "d", "b"} {
+		report.fail(id, fmt.Errorf("user %s: boom", id))
+	}
+
+	if got, want := report.Err().Error(), "user a: boom\nuser b: boom\nuser c: boom\nuser d: boom"; got != want {
+		t.Errorf("Err(): want %q, got %q", want, got)
+	}
+
+	cases := map[string]struct {
+		maxItems int
+		want     string
+	}{
+		"All":       {maxItems: 10, want: "4 of 5 failed: user a: boom; user b: boom; user c: boom; user d: boom"},
+		"Exact":     {maxItems: 4, want: "4 of 5 failed: user a: boom; user b: boom; user c: boom; user d: boom"},
+		"Truncated": {maxItems: 2, want: "4 of 5 failed: user a: boom; user b: boom; and 2 more"},
+		"None":      {maxItems: 0, want: "4 of 5 failed: and 4 more"},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if got := report.Summary(tc.maxItems); got != tc.want {
+				t.Errorf("Summary(%d): want %q, got %q", tc.maxItems, tc.want, got)
+			}
+		})
+	}
+
+	var empty Report
+	empty.succeed("ok")
+	if err := empty.Err(); err != nil {
+		t.Errorf("Err(): want nil without failures, got %v", err)
+	}
+	if got, want := empty.Summary(3), "all 1 succeeded"; got != want {
+		t.Errorf("Summary(3): want %q, got %q", want, got)
+	}
+}
+
+func TestCreateUser(t *testing.T) {
+	user := User{GroupUserID: GroupUserID{GroupID: "QA", UserID: "user1"}, UserType: UserTypeStandard}
+
+	fake := cloudiantest.NewFakeServer(t)
+	cloudianClient := NewClient(fake.URL, "")
+
+	created, err := cloudianClient.CreateUser(context.TODO(), user)
+	if err != nil {
+		t.Fatalf("Error creating user: %v", err)
+	}
+	stored, _ := fake.User("QA", "user1")
+	want := user
+	want.CanonicalID = stored.CanonicalID
+	if diff := cmp.Diff(want, *created); diff != "" || want.CanonicalID == "" {
+		t.Errorf("CreateUser() mismatch (-want +got):\n%s", diff)
+	}
+
+	// Creating a user again is refused by Cloudian.
+	var statusErr *StatusError
+	if _, err := cloudianClient.CreateUser(context.TODO(), user); !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusConflict {
+		t.Errorf("CreateUser() of an existing user: want status %d, got %v", http.StatusConflict, err)
+	}
+}
+
+func TestCreateUserEmptyResponse(t *testing.T) {
+	user := User{GroupUserID: GroupUserID{GroupID: "QA", UserID: "user1"}, UserType: UserTypeStandard}
+
+	cloudianClient, testServer := mockBy(func(w http.ResponseWriter, r *http.Request) {})
+	defer testServer.Close()
+
+	created, err := cloudianClient.CreateUser(context.TODO(), user)
+	if err != nil {
+		t.Fatalf("Error creating user: %v", err)
+	}
+	if diff := cmp.Diff(user, *created); diff != "" {
+		t.Errorf("CreateUser() mismatch (-want +got):\n%s", diff)
+	}
+}
+
+func TestCreateGroup(t *testing.T) {
+	group := Group{GroupID: "QA", GroupName: "Quality", Active: true}
+
+	cloudianClient, testServer := mockBy(func(w http.ResponseWriter, r *http.Request) {
+		var created groupInternal
+		json.NewDecoder(r.Body).Decode(&created)
+		json.NewEncoder(w).Encode(created)
+	})
+	defer testServer.Close()
+
+	created, err := cloudianClient.CreateGroup(context.TODO(), group)
+	if err != nil {
+		t.Fatalf("Error creating group: %v", err)
+	}
+	if !group.Equal(*created) {
+		t.Errorf("CreateGroup(): want %+v, got %+v", group, *created)
+	}
+}
+
+func TestValidateIDs(t *testing.T) {
+	cases := map[string]struct {
+		id          string
+		wantGroupOK bool
+		wantUserOK  bool
+	}{
+		"Simple":             {id: "QA-team_1", wantGroupOK: true, wantUserOK: true},
+		"Empty":              {id: ""},
+		"TooLong":            {id: strings.Repeat("a", MaxIDLength+1)},
+		"MaxLength":          {id: strings.Repeat("a", MaxIDLength), wantGroupOK: true, wantUserOK: true},
+		"LeadingWhitespace":  {id: " QA"},
+		"TrailingWhitespace": {id: "QA "},
+		"Slash":              {id: "QA/team"},
+		"Period":             {id: "first.last", wantUserOK: true},
+		"Email":              {id: "first.last@example.com", wantUserOK: true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if err := ValidateGroupID(tc.id); (err == nil) != tc.wantGroupOK || (err != nil && !errors.Is(err, ErrInvalidID)) {
+				t.Errorf("ValidateGroupID(%q): want valid %t, got %v", tc.id, tc.wantGroupOK, err)
+			}
+			if err := ValidateUserID(tc.id); (err == nil) != tc.wantUserOK || (err != nil && !errors.Is(err, ErrInvalidID)) {
+				t.Errorf("ValidateUserID(%q): want valid %t, got %v", tc.id, tc.wantUserOK, err)
+			}
+		})
+	}
+}
+
+func TestInvalidIDNotSent(t *testing.T) {
+	cloudianClient, testServer := mockBy(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+	})
+	defer testServer.Close()
+
+	if _, err := cloudianClient.CreateUser(context.TODO(), User{GroupUserID: GroupUserID{GroupID: "QA", UserID: "user 1"}}); !errors.Is(err, ErrInvalidID) {
+		t.Errorf("CreateUser() with invalid user ID: want ErrInvalidID, got %v", err)
+	}
+	if _, err := cloudianClient.CreateGroup(context.TODO(), Group{GroupID: "QA/team"}); !errors.Is(err, ErrInvalidID) {
+		t.Errorf("CreateGroup() with invalid group ID: want ErrInvalidID, got %v", err)
+	}
+}
+
+func TestGroupRatingPlan(t *testing.T) {
+	plans := map[string]string{"QA": "Gold-RP"}
+	cloudianClient, testServer := mockBy(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ratingPlan/group" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			return
+		}
+		groupID := r.URL.Query().Get("groupId")
+		switch r.Method {
+		case http.MethodGet:
+			plan, ok := plans[groupID]
+			if !ok {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			_, _ = w.Write([]byte(plan))
+		case http.MethodPost:
+			plans[groupID] = r.URL.Query().Get("ratingPlanId")
+		}
+	})
+	defer testServer.Close()
+
+	plan, err := cloudianClient.GetGroupRatingPlan(context.TODO(), "QA", DefaultRegion)
+	if err != nil {
+		t.Fatalf("GetGroupRatingPlan(): %v", err)
+	}
+	if plan != "Gold-RP" {
+		t.Errorf("GetGroupRatingPlan(): want %q, got %q", "Gold-RP", plan)
+	}
+
+	if err := cloudianClient.AssignRatingPlanToGroup(context.TODO(), "QA", "Silver-RP", DefaultRegion); err != nil {
+		t.Fatalf("AssignRatingPlanToGroup(): %v", err)
+	}
+	if plans["QA"] != "Silver-RP" {
+		t.Errorf("AssignRatingPlanToGroup(): want plan %q, got %q", "Silver-RP", plans["QA"])
+	}
+
+	if _, err := cloudianClient.GetGroupRatingPlan(context.TODO(), "missing", DefaultRegion); !errors.Is(err, ErrNotFound) {
+		t.Errorf("GetGroupRatingPlan() of missing group: want ErrNotFound, got %v", err)
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	cases := map[string]struct {
+		version string
+		probe   int
+		want    Capabilities
+	}{
+		"Old": {
+			version: "7.1.4",
+			probe:   http.StatusNotFound,
+			want:    Capabilities{Version: "7.1.4"},
+		},
+		"New": {
+			version: "8.1.2 Compiled: 2024-05-01",
+			probe:   http.StatusMethodNotAllowed,
+			want: Capabilities{
+				Version:              "8.1.2 Compiled: 2024-05-01",
+				CredentialStatus:     true,
+				EndpointRestrictions: true,
+				PolicyAssignment:     true,
+			},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var requests atomic.Int32
+			cloudianClient, testServer := mockBy(func(w http.ResponseWriter, r *http.Request) {
+				requests.Add(1)
+				if r.URL.Path == "/system/version" {
+					_, _ = w.Write([]byte(tc.version))
+					return
+				}
+				w.WriteHeader(tc.probe)
+			})
+			defer testServer.Close()
+
+			for range 2 {
+				got, err := cloudianClient.Capabilities(context.Background())
+				if err != nil {
+					t.Fatalf("Capabilities(): %v", err)
+				}
+				if diff := cmp.Diff(tc.want, got); diff != "" {
+					t.Errorf("Capabilities() mismatch (-want +got):\n%s", diff)
+				}
+			}
+			if got := requests.Load(); got != 3 {
+				t.Errorf("Capabilities(): want 3 requests, as the second call is cached, got %d", got)
+			}
+		})
+	}
+}
+
+func TestCapabilitiesProbeFailureIsNotCached(t *testing.T) {
+	server := cloudiantest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/system/version" {
+			_, _ = w.Write([]byte("8.1.2"))
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	server.Script("/system/version", cloudiantest.Respond(http.StatusServiceUnavailable, ""))
+	cloudianClient := NewClient(server.URL, "")
+
+	if _, err := cloudianClient.Capabilities(context.Background()); err == nil {
+		t.Fatal("Capabilities(): want error while the admin API is unavailable")
+	}
+
+	got, err := cloudianClient.Capabilities(context.Background())
+	if err != nil {
+		t.Fatalf("Capabilities(): %v", err)
+	}
+	if !got.CredentialStatus || !got.PolicyAssignment {
+		t.Errorf("Capabilities(): want probed endpoints to be supported, got %+v", got)
+	}
+}
+
+// s3EndpointsFixture is the system configuration of a cluster of two regions
+// sharing an HTTPS endpoint, with website requests allowed on every endpoint
+// in one of them.
+const s3EndpointsFixture = `[
+  {
+    "regionName": "region1",
+    "s3EndpointsHTTP": ["s3-region1.example.com"],
+    "s3EndpointsHTTPS": ["s3.example.com", "s3-region1.example.com"],
+    "s3WebSiteEndpoints": ["ALL"]
+  },
+  {
+    "regionName": "region2",
+    "s3EndpointsHTTP": ["s3-region2.example.com"],
+    "s3EndpointsHTTPS": ["s3.example.com"],
+    "s3WebSiteEndpoints": ["s3-website-region2.example.com"]
+  }
+]`
+
+func TestGetS3Endpoints(t *testing.T) {
+	cases := map[string]struct {
+		status    int
+		body      string
+		wantHTTP  []string
+		wantHTTPS []string
+		wantWeb   []string
+		wantErr   bool
+	}{
+		"Regions": {
+			status:    http.StatusOK,
+			body:      s3EndpointsFixture,
+			wantHTTP:  []string{"s3-region1.example.com", "s3-region2.example.com"},
+			wantHTTPS: []string{"s3-region1.example.com", "s3.example.com"},
+			wantWeb:   []string{"s3-website-region2.example.com"},
+		},
+		"NoEndpoints": {
+			status: http.StatusNoContent,
+		},
+		"Unsupported": {
+			status: http.StatusNotFound,
+		},
+		"Forbidden": {
+			status:  http.StatusForbidden,
+			wantErr: true,
+		},
+		"Malformed": {
+			status:  http.StatusOK,
+			body:    `{"regionName": "region1"}`,
+			wantErr: true,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			server := cloudiantest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+			}))
+			server.Script("/system/s3endpoints", cloudiantest.Respond(tc.status, tc.body))
+			got, err := NewClient(server.URL, "").GetS3Endpoints(context.Background())
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("GetS3Endpoints(): want error %t, got %v", tc.wantErr, err)
+			}
+			if diff := cmp.Diff(tc.wantHTTP, got.HTTP()); diff != "" {
+				t.Errorf("GetS3Endpoints(): -want HTTP endpoints, +got:\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantHTTPS, got.HTTPS()); diff != "" {
+				t.Errorf("GetS3Endpoints(): -want HTTPS endpoints, +got:\n%s", diff)
+			}
+			if diff := cmp.Diff(tc.wantWeb, got.Website()); diff != "" {
+				t.Errorf("GetS3Endpoints(): -want website endpoints, +got:\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGetS3EndpointsCache(t *testing.T) {
+	server := cloudiantest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(s3EndpointsFixture))
+	}))
+	server.Script("/system/s3endpoints", cloudiantest.Respond(http.StatusServiceUnavailable, ""))
+	clock := testingclock.NewFakePassiveClock(time.Now())
+	cloudianClient := NewClient(server.URL, "", WithClock(clock))
+	ctx := context.Background()
+
+	if _, err := cloudianClient.GetS3Endpoints(ctx); err == nil {
+		t.Fatal("GetS3Endpoints(): want error while the admin API is unavailable")
+	}
+	for range 3 {
+		if _, err := cloudianClient.GetS3Endpoints(ctx); err != nil {
+			t.Fatalf("GetS3Endpoints(): %v", err)
+		}
+	}
+	if got := server.Requests("/system/s3endpoints"); got != 2 {
+		t.Errorf("GetS3Endpoints(): want 2 requests, as the failure is not cached but the endpoints are, got %d", got)
+	}
+
+	// Copies of the client, such as the client of the default region, share
+	// the cache.
+	regional, err := cloudianClient.ForRegion(DefaultRegion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock.SetTime(clock.Now().Add(S3EndpointsTTL - time.Second))
+	if _, err := regional.GetS3Endpoints(ctx); err != nil {
+		t.Fatalf("GetS3Endpoints(): %v", err)
+	}
+	if got := server.Requests("/system/s3endpoints"); got != 2 {
+		t.Errorf("GetS3Endpoints(): want the endpoints cached until they expire, got %d requests", got)
+	}
+
+	clock.SetTime(clock.Now().Add(time.Second))
+	if _, err := cloudianClient.GetS3Endpoints(ctx); err != nil {
+		t.Fatalf("GetS3Endpoints(): %v", err)
+	}
+	if got := server.Requests("/system/s3endpoints"); got != 3 {
+		t.Errorf("GetS3Endpoints(): want the endpoints read again once expired, got %d requests", got)
+	}
+}
+
+func TestVersionAtLeast(t *testing.T) {
+	cases := map[string]bool{
+		"7.2":     true,
+		"7.2.1":   true,
+		"8.0":     true,
+		"7.1.9":   false,
+		"6.9":     false,
+		"garbage": false,
+		"":        false,
+	}
+	for version, want := range cases {
+		if got := versionAtLeast(version, [2]int{7, 2}); got != want {
+			t.Errorf("versionAtLeast(%q): want %t, got %t", version, want, got)
+		}
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("groupId") != "QA" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(groupInternal{GroupID: "QA"})
+	}))
+	defer server.Close()
+
+	// Clients sharing a registry, like those of different ProviderConfigs,
+	// share their metrics.
+	registry := prometheus.NewRegistry()
+	for _, groupID := range []string{"QA", "QA", "Ops"} {
+		cloudianClient := NewClient(server.URL, "", WithMetrics(registry))
+		_, _ = cloudianClient.GetGroup(context.TODO(), groupID)
+	}
+
+	want := `
+# HELP cloudian_api_requests_total The number of requests to the Cloudian admin API.
+# TYPE cloudian_api_requests_total counter
+cloudian_api_requests_total{code="200",method="GET",path="/group"} 2
+cloudian_api_requests_total{code="204",method="GET",path="/group"} 1
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(want), "cloudian_api_requests_total"); err != nil {
+		t.Error(err)
+	}
+	if got := testutil.CollectAndCount(registry, "cloudian_api_request_duration_seconds"); got != 2 {
+		t.Errorf("want request durations by status code, got %d series", got)
+	}
+}
+
+func TestGetSingleObjectShapes(t *testing.T) {
+	get := map[string]func(*Client) (any, error){
+		"GetGroup": func(c *Client) (any, error) {
+			return c.GetGroup(context.TODO(), "QA")
+		},
+		"GetUser": func(c *Client) (any, error) {
+			return c.GetUser(context.TODO(), GroupUserID{GroupID: "QA", UserID: "alice"})
+		},
+		"GetUserCredentials": func(c *Client) (any, error) {
+			return c.GetUserCredentials(context.TODO(), "00AABBCC")
+		},
+	}
+	want := map[string]any{
+		"GetGroup":           &Group{GroupID: "QA", Active: true, S3EndpointsHTTP: []string{AllEndpoints}, S3EndpointsHTTPS: []string{AllEndpoints}, S3WebSiteEndpoints: []string{AllEndpoints}},
+		"GetUser":            &User{GroupUserID: GroupUserID{GroupID: "QA", UserID: "alice"}, CanonicalID: "123"},
+		"GetUserCredentials": &SecurityInfo{AccessKey: "00AABBCC", SecretKey: NewSecret("secret")},
+	}
+	objects := map[string]string{
+		"GetGroup":           `{"groupId":"QA","active":"true","s3endpointshttp":["ALL"],"s3endpointshttps":["ALL"],"s3websiteendpoints":["ALL"]}`,
+		"GetUser":            `{"groupId":"QA","userId":"alice","canonicalUserId":"123"}`,
+		"GetUserCredentials": `{"accessKey":"00AABBCC","secretKey":"secret"}`,
+	}
+
+	for name, fn := range get {
+		cases := map[string]struct {
+			body    string
+			warn    bool
+			wantErr error
+		}{
+			"Object":     {body: objects[name]},
+			"Array":      {body: "[" + objects[name] + "]", warn: true},
+			"EmptyArray": {body: "[]", warn: true, wantErr: ErrNotFound},
+		}
+		for shape, tc := range cases {
+			t.Run(name+"/"+shape, func(t *testing.T) {
+				cloudianClient, testServer := mockBy(func(w http.ResponseWriter, r *http.Request) {
+					_, _ = w.Write([]byte(tc.body))
+				})
+				defer testServer.Close()
+				warned := false
+				cloudianClient.warnf = func(string, ...any) { warned = true }
+
+				got, err := fn(cloudianClient)
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("%s(): want error %v, got %v", name, tc.wantErr, err)
+				}
+				if warned != tc.warn {
+					t.Errorf("%s(): want warning %t, got %t", name, tc.warn, warned)
+				}
+				if tc.wantErr == nil {
+					if diff := cmp.Diff(want[name], got); diff != "" {
+						t.Errorf("%s() mismatch (-want +got):\n%s", name, diff)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestDeleteStatusCodes(t *testing.T) {
+	deletes := map[string]func(*Client) error{
+		"DeleteUser": func(c *Client) error {
+			return c.DeleteUser(context.TODO(), GroupUserID{GroupID: "QA", UserID: "alice"})
+		},
+		"DeleteUserCredentials": func(c *Client) error {
+			return c.DeleteUserCredentials(context.TODO(), "00AABBCC")
+		},
+		"DeleteGroup": func(c *Client) error {
+			return c.DeleteGroup(context.TODO(), "QA")
+		},
+	}
+	cases := map[int]struct {
+		wantErr       error
+		wantStatusErr bool
+	}{
+		http.StatusOK:                  {},
+		http.StatusNoContent:           {wantErr: ErrNotFound},
+		http.StatusNotFound:            {wantErr: ErrNotFound},
+		http.StatusBadRequest:          {wantStatusErr: true},
+		http.StatusInternalServerError: {wantStatusErr: true},
+	}
+
+	for name, del := range deletes {
+		for status, tc := range cases {
+			t.Run(fmt.Sprintf("%s/%d", name, status), func(t *testing.T) {
+				cloudianClient, testServer := mockBy(func(w http.ResponseWriter, r *http.Request) {
+					if r.Method != http.MethodDelete {
+						t.Errorf("want method %s, got %s", http.MethodDelete, r.Method)
+					}
+					w.WriteHeader(status)
+				})
+				defer testServer.Close()
+
+				err := del(cloudianClient)
+				var statusErr *StatusError
+				switch {
+				case tc.wantStatusErr && !errors.As(err, &statusErr):
+					t.Errorf("%s(): want *StatusError, got %v", name, err)
+				case !tc.wantStatusErr && !errors.Is(err, tc.wantErr):
+					t.Errorf("%s(): want error %v, got %v", name, tc.wantErr, err)
+				}
+			})
+		}
+	}
+}
+
+func TestDeleteGroupNotEmpty(t *testing.T) {
+	fake := cloudiantest.NewFakeServer(t)
+	fake.AddGroup("QA")
+	fake.AddGroup("Empty")
+	fake.AddUser(cloudiantest.User{GroupID: "QA", UserID: "alice"})
+	cloudianClient := NewClient(fake.URL, "")
+
+	err := cloudianClient.DeleteGroup(context.TODO(), "QA")
+	var statusErr *StatusError
+	if !errors.Is(err, ErrGroupNotEmpty) || !errors.As(err, &statusErr) {
+		t.Errorf("DeleteGroup() of a group with users: want ErrGroupNotEmpty and *StatusError, got %v", err)
+	}
+	if !fake.HasGroup("QA") {
+		t.Error("DeleteGroup() of a group with users: want the group kept")
+	}
+
+	if err := cloudianClient.DeleteGroup(context.TODO(), "Empty"); err != nil {
+		t.Errorf("DeleteGroup() of an empty group: %v", err)
+	}
+	if err := cloudianClient.DeleteGroup(context.TODO(), "Empty"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("DeleteGroup() of a deleted group: want ErrNotFound, got %v", err)
+	}
+}
+
+func TestCreateInMissingGroupOrUser(t *testing.T) {
+	fake := cloudiantest.NewFakeServer(t)
+	fake.AddGroup("QA")
+	fake.AddUser(cloudiantest.User{GroupID: "QA", UserID: "alice"})
+	cloudianClient := NewClient(fake.URL, "")
+
+	// The fake creates users in any group, so refuse them like Cloudian.
+	fake.Script("/user", cloudiantest.Respond(http.StatusBadRequest, "Invalid group").Times(2))
+	_, err := cloudianClient.CreateUser(context.TODO(), User{GroupUserID: GroupUserID{GroupID: "Pending", UserID: "bob"}})
+	var statusErr *StatusError
+	if !errors.Is(err, ErrGroupNotFound) || !errors.As(err, &statusErr) {
+		t.Errorf("CreateUser() in a missing group: want ErrGroupNotFound and *StatusError, got %v", err)
+	}
+	_, err = cloudianClient.CreateUser(context.TODO(), User{GroupUserID: GroupUserID{GroupID: "QA", UserID: "bob"}})
+	if err == nil || errors.Is(err, ErrGroupNotFound) {
+		t.Errorf("CreateUser() refused in an existing group: want an error other than ErrGroupNotFound, got %v", err)
+	}
+
+	if _, err := cloudianClient.CreateUserCredentials(context.TODO(), GroupUserID{GroupID: "QA", UserID: "bob"}); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("CreateUserCredentials() of a missing user: want ErrUserNotFound, got %v", err)
+	}
+	err = cloudianClient.CreateUserCredentialsWithKey(context.TODO(), GroupUserID{GroupID: "QA", UserID: "bob"}, "00AABBCC", NewSecret("secret"))
+	if !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("CreateUserCredentialsWithKey() of a missing user: want ErrUserNotFound, got %v", err)
+	}
+
+	fake.SetMaxCredentials(1)
+	if _, err := cloudianClient.CreateUserCredentials(context.TODO(), GroupUserID{GroupID: "QA", UserID: "alice"}); err != nil {
+		t.Fatalf("CreateUserCredentials(): %v", err)
+	}
+	_, err = cloudianClient.CreateUserCredentials(context.TODO(), GroupUserID{GroupID: "QA", UserID: "alice"})
+	if !errors.Is(err, ErrKeyQuotaExceeded) || errors.Is(err, ErrUserNotFound) {
+		t.Errorf("CreateUserCredentials() over the quota: want ErrKeyQuotaExceeded only, got %v", err)
+	}
+}
+
+func TestIsGroupNotEmpty(t *testing.T) {
+	cases := map[string]struct {
+		status int
+		body   string
+		want   bool
+	}{
+		"NotEmpty":     {status: http.StatusConflict, body: "Group is not empty, delete its users first", want: true},
+		"Conflict":     {status: http.StatusConflict, body: "Group deletion already in progress"},
+		"Explained":    {status: http.StatusBadRequest, body: "Group is not empty, delete its users first", want: true},
+		"Unexplained":  {status: http.StatusBadRequest, body: "Invalid group ID"},
+		"Unrelated":    {status: http.StatusBadRequest, body: "Invalid userId of group member"},
+		"Unauthorized": {status: http.StatusUnauthorized, body: "User not authorized"},
+		"ServerError":  {status: http.StatusInternalServerError, body: "Group is not empty"},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cloudianClient, testServer := mockBy(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+				_, _ = w.Write([]byte(tc.body))
+			})
+			defer testServer.Close()
+
+			if got := errors.Is(cloudianClient.DeleteGroup(context.TODO(), "QA"), ErrGroupNotEmpty); got != tc.want {
+				t.Errorf("DeleteGroup(): want ErrGroupNotEmpty %t, got %t", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestStatusErrorReason(t *testing.T) {
+	long := strings.Repeat("x", 2*maxReasonLength)
+	cases := map[string]struct {
+		body string
+		want string
+	}{
+		"JSON":          {body: `{"reason":"InvalidGroupId","message":"Group ID contains invalid characters"}`, want: "InvalidGroupId: Group ID contains invalid characters"},
+		"JSONMessage":   {body: `{"message":"Region is required"}`, want: "Region is required"},
+		"JSONSame":      {body: `{"reason":"Quota exceeded","message":"Quota exceeded"}`, want: "Quota exceeded"},
+		"JSONUnknown":   {body: `{"code":42}`, want: `{"code":42}`},
+		"HTMLPage":      {body: "<html>\n<head><title>502 Bad Gateway</title></head>\n<body><h1>502 Bad Gateway</h1></body>\n</html>", want: "502 Bad Gateway"},
+		"HTMLUntitled":  {body: "<html><body><h1>Service\n Unavailable</h1></body></html>", want: "Service Unavailable"},
+		"Text":          {body: "Invalid group ID\n", want: "Invalid group ID"},
+		"Empty":         {},
+		"Long":          {body: long, want: long[:maxReasonLength] + "..."},
+		"TruncatedJSON": {body: `{"message":"` + strings.Repeat("y", maxErrorBody) + `"}`, want: `{"message":"` + strings.Repeat("y", maxReasonLength-len(`{"message":"`)) + "..."},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cloudianClient, testServer := mockBy(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+				_, _ = w.Write([]byte(tc.body))
+			})
+			defer testServer.Close()
+
+			_, err := cloudianClient.GetGroup(context.TODO(), "QA")
+			var statusErr *StatusError
+			if !errors.As(err, &statusErr) {
+				t.Fatalf("GetGroup(): want a StatusError, got %v", err)
+			}
+			if statusErr.Reason != tc.want {
+				t.Errorf("GetGroup(): want reason %q, got %q", tc.want, statusErr.Reason)
+			}
+			want := "GET /group unexpected status: 400"
+			if tc.want != "" {
+				want += ": " + tc.want
+			}
+			if err.Error() != want {
+				t.Errorf("GetGroup(): want error %q, got %q", want, err.Error())
+			}
+		})
+	}
+}
+
+func TestInitialUserCredentials(t *testing.T) {
+	cases := map[string]struct {
+		body    string
+		want    *SecurityInfo
+		wantErr error
+	}{
+		"Initial": {
+			body: `[{"accessKey":"00AABBCC","secretKey":"secret"}]`,
+			want: &SecurityInfo{AccessKey: "00AABBCC", SecretKey: NewSecret("secret")},
+		},
+		"None": {wantErr: ErrNotFound},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			cloudianClient, testServer := mockBy(func(w http.ResponseWriter, r *http.Request) {
+				if tc.body == "" {
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+				_, _ = w.Write([]byte(tc.body))
+			})
+			defer testServer.Close()
+
+			got, err := cloudianClient.InitialUserCredentials(context.TODO(), GroupUserID{GroupID: "QA", UserID: "alice"})
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("InitialUserCredentials(): want error %v, got %v", tc.wantErr, err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("InitialUserCredentials() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+
+	t.Run("Several", func(t *testing.T) {
+		cloudianClient, testServer := mockBy(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`[{"accessKey":"00AABBCC","secretKey":"a"},{"accessKey":"00DDEEFF","secretKey":"b"}]`))
+		})
+		defer testServer.Close()
+
+		if _, err := cloudianClient.InitialUserCredentials(context.TODO(), GroupUserID{GroupID: "QA", UserID: "alice"}); err == nil {
+			t.Error("InitialUserCredentials(): want an error when the user has more than the initial credentials")
+		}
+	})
+}
+
+func TestUserAgent(t *testing.T) {
+	cases := map[string]struct {
+		opts []func(*Client)
+		want string
+	}{
+		"Default":    {want: DefaultUserAgent},
+		"Overridden": {opts: []func(*Client){WithUserAgent("backup-script/1.0")}, want: "backup-script/1.0"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var got string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.Header.Get("User-Agent")
+				w.WriteHeader(http.StatusNoContent)
+			}))
+			defer server.Close()
+
+			cloudianClient := NewClient(server.URL, "", tc.opts...)
+			if cloudianClient.UserAgent() != tc.want {
+				t.Errorf("UserAgent(): want %q, got %q", tc.want, cloudianClient.UserAgent())
+			}
+			calls := map[string]func(){
+				"GetGroup":           func() { _, _ = cloudianClient.GetGroup(context.TODO(), "QA") },
+				"Version":            func() { _, _ = cloudianClient.Version(context.TODO()) },
+				"GetGroupRatingPlan": func() { _, _ = cloudianClient.GetGroupRatingPlan(context.TODO(), "QA", DefaultRegion) },
+			}
+			for call, f := range calls {
+				got = ""
+				f()
+				if got != tc.want {
+					t.Errorf("%s(): want User-Agent %q, got %q", call, tc.want, got)
+				}
+			}
+		})
+	}
+
+	if !strings.HasPrefix(DefaultUserAgent, "provider-cloudian/") {
+		t.Errorf("DefaultUserAgent: want the provider name and version, got %q", DefaultUserAgent)
+	}
+}
+
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	fake := cloudiantest.NewFakeServer(t)
+	fake.AddGroup("QA")
+	cloudianClient := NewClient(fake.URL, "", WithTracerProvider(tp))
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "Reconcile")
+	if _, err := cloudianClient.GetGroup(ctx, "QA"); err != nil {
+		t.Fatalf("GetGroup(): %v", err)
+	}
+	if _, err := cloudianClient.GetUser(ctx, GroupUserID{GroupID: "QA", UserID: "alice"}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetUser(): want ErrNotFound, got %v", err)
+	}
+	fake.FailNext(1)
+	if err := cloudianClient.DeleteQOS(ctx, GroupUserID{GroupID: "QA", UserID: "*"}, "region1"); err == nil {
+		t.Fatal("DeleteQOS(): want error while the admin API fails")
+	}
+	parent.End()
+
+	type span struct {
+		Name       string
+		Status     codes.Code
+		Attributes map[attribute.Key]attribute.Value
+		Requests   int
+	}
+	var got []span
+	for _, s := range recorder.Ended() {
+		if s.Name() == "Reconcile" {
+			continue
+		}
+		if s.Parent().SpanID() != parent.SpanContext().SpanID() {
+			t.Errorf("%s: want a child span of the span of the context", s.Name())
+		}
+		attrs := map[attribute.Key]attribute.Value{}
+		for _, a := range s.Attributes() {
+			attrs[a.Key] = a.Value
+		}
+		requests := 0
+		for _, e := range s.Events() {
+			if e.Name == "request" {
+				requests++
+			}
+		}
+		got = append(got, span{Name: s.Name(), Status: s.Status().Code, Attributes: attrs, Requests: requests})
+	}
+	want := []span{
+		{Name: "GetGroup", Attributes: map[attribute.Key]attribute.Value{
+			"http.response.status_code": attribute.IntValue(200),
+		}, Requests: 1},
+		{Name: "GetUser", Attributes: map[attribute.Key]attribute.Value{
+			"http.response.status_code": attribute.IntValue(204),
+			AttributeNotFound:           attribute.BoolValue(true),
+		}, Requests: 1},
+		{Name: "DeleteQOS", Status: codes.Error, Attributes: map[attribute.Key]attribute.Value{
+			"http.response.status_code": attribute.IntValue(500),
+			AttributeRegion:             attribute.StringValue("region1"),
+		}, Requests: 1},
+	}
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(attribute.Value{})); diff != "" {
+		t.Errorf("-want spans, +got:\n%s", diff)
+	}
+}
+
+func TestClockSkew(t *testing.T) {
+	// Half a second past a whole second, so that the Date header truncated to
+	// the second and the half second added back estimate the skew exactly.
+	clock := testingclock.NewFakePassiveClock(time.Date(2026, 1, 1, 12, 0, 0, int(500*time.Millisecond), time.UTC))
+	var skew atomic.Int64
+	var date atomic.Value
+	server := cloudiantest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if d, _ := date.Load().(string); d != "" {
+			w.Header().Set("Date", d)
+		} else {
+			w.Header().Set("Date", clock.Now().Add(time.Duration(skew.Load())).Format(http.TimeFormat))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	registry := prometheus.NewRegistry()
+	cloudianClient := NewClient(server.URL, "", WithMetrics(registry), WithClock(clock))
+
+	if got := cloudianClient.ClockSkew(); got != 0 {
+		t.Errorf("ClockSkew(): want 0 before any response, got %s", got)
+	}
+
+	skew.Store(int64(4 * time.Minute))
+	_, _ = cloudianClient.GetGroup(context.TODO(), "QA")
+	if got := cloudianClient.ClockSkew(); got != 4*time.Minute {
+		t.Errorf("ClockSkew(): want 4m after the first response, got %s", got)
+	}
+	if got := cloudianClient.Now().Sub(clock.Now()); got != 4*time.Minute {
+		t.Errorf("Now(): want 4m ahead, got %s", got)
+	}
+	if got := testutil.ToFloat64(cloudianClient.metrics.skew.WithLabelValues(server.URL)); got != 240 {
+		t.Errorf("cloudian_api_clock_skew_seconds: want 240, got %v", got)
+	}
+
+	// A single response from a clock that is back in sync only moves the
+	// estimate part of the way.
+	skew.Store(0)
+	_, _ = cloudianClient.GetGroup(context.TODO(), "QA")
+	if got, want := cloudianClient.ClockSkew(), time.Duration((1-skewSmoothing)*float64(4*time.Minute)); got != want {
+		t.Errorf("ClockSkew(): want the estimate smoothed to %s, got %s", want, got)
+	}
+
+	before := cloudianClient.ClockSkew()
+	date.Store("not a date")
+	_, _ = cloudianClient.GetGroup(context.TODO(), "QA")
+	if got := cloudianClient.ClockSkew(); got != before {
+		t.Errorf("ClockSkew(): want invalid Date headers ignored, got %s after %s", got, before)
+	}
+}
+
+func TestLicenseExceeded(t *testing.T) {
+	cases := map[string]struct {
+		status int
+		body   string
+		want   bool
+	}{
+		"LicenseExceeded": {status: http.StatusForbidden, body: "License capacity exceeded, cannot create user", want: true},
+		"Forbidden":       {status: http.StatusForbidden, body: "Access denied"},
+		"OtherLicense":    {status: http.StatusBadRequest, body: "Feature not included in the license"},
+		"ServerError":     {status: http.StatusInternalServerError, body: "license server unreachable"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fake := cloudiantest.NewFakeServer(t)
+			fake.Script("/user", cloudiantest.Respond(tc.status, tc.body))
+			registry := prometheus.NewRegistry()
+			cloudianClient := NewClient(fake.URL, "", WithMetrics(registry))
+
+			_, err := cloudianClient.CreateUser(context.TODO(), User{GroupUserID: GroupUserID{GroupID: "QA", UserID: "user1"}})
+			if got := errors.Is(err, ErrLicenseExceeded); got != tc.want {
+				t.Errorf("CreateUser(): want ErrLicenseExceeded %t, got %v", tc.want, err)
+			}
+			var statusErr *StatusError
+			if !errors.As(err, &statusErr) || statusErr.StatusCode != tc.status {
+				t.Errorf("CreateUser(): want a StatusError with status %d, got %v", tc.status, err)
+			}
+			if got := testutil.CollectAndCount(registry, "cloudian_api_license_exceeded_total"); (got == 1) != tc.want {
+				t.Errorf("want license exceeded counted %t, got %d series", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestBaseURL(t *testing.T) {
+	cases := map[string]struct {
+		endpoint   string
+		pathPrefix string
+		want       string
+		wantErr    bool
+	}{
+		"Host":              {endpoint: "https://cloudian.example.com:19443", want: "https://cloudian.example.com:19443"},
+		"TrailingSlash":     {endpoint: "https://cloudian.example.com:19443/", want: "https://cloudian.example.com:19443"},
+		"Path":              {endpoint: "https://gateway.example.com/cloudian-admin/", want: "https://gateway.example.com/cloudian-admin"},
+		"PathPrefix":        {endpoint: "https://gateway.example.com", pathPrefix: "/cloudian-admin/", want: "https://gateway.example.com/cloudian-admin"},
+		"PathAndPrefix":     {endpoint: "https://gateway.example.com/proxy/", pathPrefix: "cloudian-admin", want: "https://gateway.example.com/proxy/cloudian-admin"},
+		"NoScheme":          {endpoint: "cloudian.example.com:19443", wantErr: true},
+		"UnsupportedScheme": {endpoint: "ftp://cloudian.example.com", wantErr: true},
+		"NoHost":            {endpoint: "https:///admin", wantErr: true},
+		"Query":             {endpoint: "https://cloudian.example.com?x=1", wantErr: true},
+		"Unparsable":        {endpoint: "https://cloudian example.com", wantErr: true},
+		"Empty":             {wantErr: true},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			got, err := BaseURL(tc.endpoint, tc.pathPrefix)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("BaseURL(%q, %q): want error %t, got %v", tc.endpoint, tc.pathPrefix, tc.wantErr, err)
+			}
+			if got != tc.want {
+				t.Errorf("BaseURL(%q, %q): want %q, got %q", tc.endpoint, tc.pathPrefix, tc.want, got)
+			}
+		})
+	}
+}
+
+func TestNewClientWithValidation(t *testing.T) {
+	var path string
+	srv := cloudiantest.NewServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	client, err := NewClientWithValidation(srv.URL+"/cloudian-admin/", "")
+	if err != nil {
+		t.Fatalf("NewClientWithValidation(...): %v", err)
+	}
+	if _, err := client.GetGroup(context.TODO(), "QA"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetGroup(): %v", err)
+	}
+	if path != "/cloudian-admin/group" {
+		t.Errorf("GetGroup(): want the request under the path of the endpoint, got %q", path)
+	}
+
+	if _, err := NewClientWithValidation("cloudian.example.com", ""); err == nil {
+		t.Error("NewClientWithValidation(...): want an error for an endpoint without a scheme")
+	}
+}
+
+func TestThrottled(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	cases := map[string]struct {
+		header map[string]string
+		want   time.Duration
+	}{
+		"Seconds":        {header: map[string]string{"Retry-After": "30"}, want: 30 * time.Second},
+		"HTTPDate":       {header: map[string]string{"Retry-After": now.Add(time.Minute).Format(http.TimeFormat), "Date": now.Format(http.TimeFormat)}, want: time.Minute},
+		"HTTPDateSkewed": {header: map[string]string{"Retry-After": now.Add(time.Hour + time.Minute).Format(http.TimeFormat), "Date": now.Add(time.Hour).Format(http.TimeFormat)}, want: time.Minute},
+		"HTTPDatePassed": {header: map[string]string{"Retry-After": now.Add(-time.Minute).Format(http.TimeFormat), "Date": now.Format(http.TimeFormat)}},
+		"Missing":        {},
+		"Invalid":        {header: map[string]string{"Retry-After": "soon"}},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			step := cloudiantest.Respond(http.StatusTooManyRequests, "")
+			for key, value := range tc.header {
+				step = step.WithHeader(key, value)
+			}
+			fake := cloudiantest.NewFakeServer(t)
+			fake.Script("/group", step)
+			cloudianClient := NewClient(fake.URL, "", WithClock(testingclock.NewFakePassiveClock(now)))
+
+			_, err := cloudianClient.GetGroup(context.TODO(), "QA")
+			var throttled *ThrottledError
+			if !errors.As(err, &throttled) {
+				t.Fatalf("GetGroup(): want a ThrottledError, got %v", err)
+			}
+			if throttled.RetryAfter != tc.want {
+				t.Errorf("GetGroup(): want RetryAfter %s, got %s", tc.want, throttled.RetryAfter)
+			}
+			var statusErr *StatusError
+			if !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusTooManyRequests {
+				t.Errorf("GetGroup(): want a StatusError with status %d, got %v", http.StatusTooManyRequests, err)
+			}
+		})
+	}
+}
+
+func TestKeyQuotaExceeded(t *testing.T) {
+	cases := map[string]struct {
+		status int
+		body   string
+		want   bool
+	}{
+		"Maximum":       {status: http.StatusBadRequest, body: "Maximum number of credentials reached for user", want: true},
+		"BadRequest":    {status: http.StatusBadRequest, body: "Invalid user"},
+		"MaximumLength": {status: http.StatusBadRequest, body: "Maximum length of access key exceeded"},
+		"Limit":         {status: http.StatusForbidden, body: "Rate limit exceeded"},
+		"ServerError":   {status: http.StatusInternalServerError, body: "Maximum number of credentials reached for user"},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			fake := cloudiantest.NewFakeServer(t)
+			fake.Script("/user/credentials", cloudiantest.Respond(tc.status, tc.body))
+			cloudianClient := NewClient(fake.URL, "")
+
+			_, err := cloudianClient.CreateUserCredentials(context.TODO(), GroupUserID{GroupID: "QA", UserID: "user1"})
+			if got := errors.Is(err, ErrKeyQuotaExceeded); got != tc.want {
+				t.Errorf("CreateUserCredentials(): want ErrKeyQuotaExceeded %t, got %v", tc.want, err)
+			}
+			var statusErr *StatusError
+			if !errors.As(err, &statusErr) || statusErr.StatusCode != tc.status {
+				t.Errorf("CreateUserCredentials(): want a StatusError with status %d, got %v", tc.status, err)
+			}
+		})
+	}
+
+	// Refusing other requests with the same words is not about credentials.
+	fake := cloudiantest.NewFakeServer(t)
+	fake.Script("/user", cloudiantest.Respond(http.StatusBadRequest, "Maximum length of user ID exceeded"))
+	_, err := NewClient(fake.URL, "").CreateUser(context.TODO(), User{GroupUserID: GroupUserID{GroupID: "QA", UserID: "user1"}})
+	if errors.Is(err, ErrKeyQuotaExceeded) {
+		t.Errorf("CreateUser(): want no ErrKeyQuotaExceeded, got %v", err)
+	}
+
+	// Nor is refusing to delete credentials.
+	fake.Script("/user/credentials", cloudiantest.Respond(http.StatusBadRequest, "Maximum number of credentials reached for user"))
+	err = NewClient(fake.URL, "").DeleteUserCredentials(context.TODO(), "00AABBCC")
+	if err == nil || errors.Is(err, ErrKeyQuotaExceeded) {
+		t.Errorf("DeleteUserCredentials(): want an error other than ErrKeyQuotaExceeded, got %v", err)
+	}
+}
+
+func TestCreateUserCredentialsQuota(t *testing.T) {
+	fake := cloudiantest.NewFakeServer(t)
+	fake.AddUser(cloudiantest.User{GroupID: "QA", UserID: "user1"})
+	fake.SetMaxCredentials(1)
+	cloudianClient := NewClient(fake.URL, "")
+	guid := GroupUserID{GroupID: "QA", UserID: "user1"}
+
+	if err := cloudianClient.CreateUserCredentialsWithKey(context.TODO(), guid, "00112233445566778899", NewSecret("secret")); err != nil {
+		t.Fatalf("CreateUserCredentialsWithKey(): %v", err)
+	}
+	// Retrying with the same access key does not create another set.
+	if err := cloudianClient.CreateUserCredentialsWithKey(context.TODO(), guid, "00112233445566778899", NewSecret("secret")); err != nil {
+		t.Errorf("CreateUserCredentialsWithKey(): want retries within the quota, got %v", err)
+	}
+	if err := cloudianClient.CreateUserCredentialsWithKey(context.TODO(), guid, "99887766554433221100", NewSecret("secret")); !errors.Is(err, ErrKeyQuotaExceeded) {
+		t.Errorf("CreateUserCredentialsWithKey(): want ErrKeyQuotaExceeded, got %v", err)
+	}
+	if _, err := cloudianClient.CreateUserCredentials(context.TODO(), guid); !errors.Is(err, ErrKeyQuotaExceeded) {
+		t.Errorf("CreateUserCredentials(): want ErrKeyQuotaExceeded, got %v", err)
+	}
+}
+
+func TestGetUsage(t *testing.T) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	query := UsageQuery{
+		GroupID:     "QA",
+		Operation:   UsageStorageBytes,
+		Granularity: UsageDaily,
+		Start:       start,
+		End:         start.AddDate(0, 0, 2),
+	}
+
+	cases := map[string]struct {
+		query     func(q UsageQuery) UsageQuery
+		body      string
+		wantQuery url.Values
+		want      []UsageEntry
+	}{
+		"Cloudian7": {
+			// Recorded from HyperStore 7.5, which quotes the numbers.
+			body: `[{"averageValue":"1048576","count":"24","maxValue":"2097152","timestamp":"1714521600000","value":"1048576","whole":"true"},` +
+				`{"averageValue":"2097152","count":"24","maxValue":"2097152","timestamp":"1714608000000","value":"2097152","whole":"true"}]`,
+			wantQuery: url.Values{"id": {"QA"}, "operation": {"SB"}, "granularity": {"day"}, "startTime": {"202405010000"}, "endTime": {"202405030000"}, "limit": {strconv.Itoa(ListLimit)}},
+			want: []UsageEntry{
+				{Timestamp: start, Value: 1048576, Unit: UnitBytes},
+				{Timestamp: start.AddDate(0, 0, 1), Value: 2097152, Unit: UnitBytes},
+			},
+		},
+		"Numbers": {
+			body: `[{"count":24,"timestamp":1714521600000,"value":1048576}]`,
+			want: []UsageEntry{{Timestamp: start, Value: 1048576, Unit: UnitBytes}},
+		},
+		"UserRequestsInRegion": {
+			query: func(q UsageQuery) UsageQuery {
+				q.UserID = "alice"
+				q.Operation = UsageHTTPGet
+				q.Region = "north"
+				return q
+			},
+			body:      `[{"count":"17","timestamp":"1714521600000","value":"52428800"}]`,
+			wantQuery: url.Values{"id": {"QA|alice"}, "operation": {"HG"}, "granularity": {"day"}, "startTime": {"202405010000"}, "endTime": {"202405030000"}, "limit": {strconv.Itoa(ListLimit)}, "region": {"north"}},
+			want:      []UsageEntry{{Timestamp: start, Value: 17, Unit: UnitRequests}},
+		},
+		"NoUsage": {},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var gotQuery url.Values
+			cloudianClient, testServer := mockBy(func(w http.ResponseWriter, r *http.Request) {
+				gotQuery = r.URL.Query()
+				if tc.body == "" {
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+				_, _ = w.Write([]byte(tc.body))
+			})
+			defer testServer.Close()
+
+			q := query
+			if tc.query != nil {
+				q = tc.query(q)
+			}
+			got, err := cloudianClient.GetUsage(context.TODO(), q)
+			if err != nil {
+				t.Fatalf("GetUsage(): %v", err)
+			}
+			if diff := cmp.Diff(tc.want, got); diff != "" {
+				t.Errorf("GetUsage() mismatch (-want +got):\n%s", diff)
+			}
+			if tc.wantQuery != nil {
+				if diff := cmp.Diff(tc.wantQuery, gotQuery); diff != "" {
+					t.Errorf("GetUsage() query mismatch (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestGetUsagePages(t *testing.T) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	var offsets []string
+	cloudianClient, testServer := mockBy(func(w http.ResponseWriter, r *http.Request) {
+		offsets = append(offsets, r.URL.Query().Get("offset"))
+		first := start
+		if offset := r.URL.Query().Get("offset"); offset != "" {
+			ms, _ := strconv.ParseInt(offset, 10, 64)
+			first = time.UnixMilli(ms)
+		}
+		// Hourly usage of five days, one more than the limit when there
+		// are more pages.
+		var page []map[string]any
+		for ts := first; ts.Before(start.AddDate(0, 0, 5)) && len(page) <= ListLimit; ts = ts.Add(time.Hour) {
+			page = append(page, map[string]any{"timestamp": ts.UnixMilli(), "value": 1})
+		}
+		_ = json.NewEncoder(w).Encode(page)
+	})
+	defer testServer.Close()
+
+	got, err := cloudianClient.GetUsage(context.TODO(), UsageQuery{
+		GroupID:     AllGroups,
+		Operation:   UsageBytesOut,
+		Granularity: UsageHourly,
+		Start:       start,
+		End:         start.AddDate(0, 0, 5),
+	})
+	if err != nil {
+		t.Fatalf("GetUsage(): %v", err)
+	}
+	if len(got) != 120 || !got[119].Timestamp.Equal(start.Add(119*time.Hour)) {
+		t.Errorf("GetUsage(): want 120 hourly entries, got %d ending %v", len(got), got[len(got)-1].Timestamp)
+	}
+	if want := []string{"", strconv.FormatInt(start.Add(ListLimit*time.Hour).UnixMilli(), 10)}; !slices.Equal(want, offsets) {
+		t.Errorf("GetUsage(): want offsets %v, got %v", want, offsets)
+	}
+}
+
+func TestGetUsageInvalidQuery(t *testing.T) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	valid := UsageQuery{GroupID: "QA", Operation: UsageStorageBytes, Granularity: UsageDaily, Start: start, End: start.Add(time.Hour)}
+
+	cases := map[string]func(q *UsageQuery){
+		"InvalidGroup":  func(q *UsageQuery) { q.GroupID = "Q A" },
+		"UserOfAll":     func(q *UsageQuery) { q.GroupID, q.UserID = AllGroups, "alice" },
+		"NoOperation":   func(q *UsageQuery) { q.Operation = "" },
+		"NoGranularity": func(q *UsageQuery) { q.Granularity = "" },
+		"EmptyRange":    func(q *UsageQuery) { q.End = q.Start },
+	}
+	for name, mutate := range cases {
+		t.Run(name, func(t *testing.T) {
+			cloudianClient, testServer := mockBy(func(http.ResponseWriter, *http.Request) {
+				t.Error("GetUsage(): want an invalid query not sent")
+			})
+			defer testServer.Close()
+
+			q := valid
+			mutate(&q)
+			if _, err := cloudianClient.GetUsage(context.TODO(), q); err == nil {
+				t.Error("GetUsage(): want an error for an invalid query")
+			}
+		})
+	}
+}
+
+func TestIdempotent(t *testing.T) {
+	for _, tc := range []struct {
+		method, path string
+		want         bool
+	}{
+		{http.MethodGet, "/group", true},
+		{http.MethodDelete, "/user", true},
+		{http.MethodPost, "/group", true},
+		{http.MethodPost, "/user/credentials", true},
+		{http.MethodPut, "/group", false},
+		{http.MethodPut, "/user/credentials", false},
+		{http.MethodPost, "/unknown", false},
+	} {
+		if got := Idempotent(tc.method, tc.path); got != tc.want {
+			t.Errorf("Idempotent(%s, %s): want %t, got %t", tc.method, tc.path, tc.want, got)
+		}
+	}
+}
+
+func TestRetries(t *testing.T) {
+	fake := cloudiantest.NewFakeServer(t)
+	fake.AddGroup("QA")
+	cloudianClient := NewClient(fake.URL, "", WithRetries(3, time.Millisecond))
+
+	// Reads are retried.
+	fake.FailNext(2)
+	if _, err := cloudianClient.GetGroup(context.TODO(), "QA"); err != nil {
+		t.Fatalf("GetGroup(): want success after 2 failures, got %v", err)
+	}
+	if got := fake.Requests("/group"); got != 3 {
+		t.Errorf("GetGroup(): want 3 requests, got %d", got)
+	}
+
+	// Creating a user is not, as the user may have been created.
+	user := User{GroupUserID: GroupUserID{GroupID: "QA", UserID: "user1"}, UserType: UserTypeStandard}
+	fake.FailNext(1)
+	if _, err := cloudianClient.CreateUser(context.TODO(), user); err == nil {
+		t.Fatal("CreateUser(): want the failure, got success")
+	}
+	if got := fake.Requests("/user"); got != 1 {
+		t.Errorf("CreateUser(): want 1 request, got %d", got)
+	}
+
+	// Unless the caller allows it.
+	fake.FailNext(1)
+	if _, err := cloudianClient.CreateUser(AllowRetry(context.TODO()), user); err != nil {
+		t.Fatalf("CreateUser() allowed to retry: want success after 1 failure, got %v", err)
+	}
+	if got := fake.Requests("/user"); got != 3 {
+		t.Errorf("CreateUser() allowed to retry: want 3 requests, got %d", got)
+	}
+
+	// Errors that are not transient are not retried.
+	if _, err := cloudianClient.CreateUser(AllowRetry(context.TODO()), user); err == nil {
+		t.Fatal("CreateUser() of an existing user: want the conflict, got success")
+	}
+	if got := fake.Requests("/user"); got != 4 {
+		t.Errorf("CreateUser() of an existing user: want 4 requests, got %d", got)
+	}
+}
+
+func TestRetriesWaitOnClock(t *testing.T) {
+	fake := cloudiantest.NewFakeServer(t)
+	fake.AddGroup("QA")
+	clock := testingclock.NewFakeClock(time.Now())
+	cloudianClient := NewClient(fake.URL, "", WithRetries(2, time.Minute), WithClock(clock))
+
+	fake.FailNext(1)
+	done := make(chan error, 1)
+	go func() {
+		_, err := cloudianClient.GetGroup(context.TODO(), "QA")
+		done <- err
+	}()
+	for !clock.HasWaiters() {
+		time.Sleep(time.Millisecond)
+	}
+
+	// The backoff is waited for, and at most a fifth more.
+	clock.Step(time.Minute - time.Second)
+	select {
+	case err := <-done:
+		t.Fatalf("GetGroup(): want a wait of the backoff, got %v before it", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	clock.Step(time.Second + time.Minute/5)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("GetGroup(): want success after 1 failure, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("GetGroup(): want the retry after the backoff and its jitter, still waiting")
+	}
+	if got := fake.Requests("/group"); got != 2 {
+		t.Errorf("GetGroup(): want 2 requests, got %d", got)
+	}
+}
+
+func TestRetriesDisabledByDefault(t *testing.T) {
+	fake := cloudiantest.NewFakeServer(t)
+	fake.AddGroup("QA")
+	cloudianClient := NewClient(fake.URL, "")
+
+	fake.FailNext(1)
+	if _, err := cloudianClient.GetGroup(context.TODO(), "QA"); err == nil {
+		t.Fatal("GetGroup(): want the failure, got success")
+	}
+	if got := fake.Requests("/group"); got != 1 {
+		t.Errorf("GetGroup(): want 1 request, got %d", got)
+	}
+}
+
+func TestCreateCredentialsRetries(t *testing.T) {
+	guid := GroupUserID{GroupID: "QA", UserID: "user1"}
+
+	for name, tc := range map[string]struct {
+		step cloudiantest.Step
+		// wantCreates is how many times the credentials are asked for.
+		wantCreates int
+	}{
+		"NotCreated": {
+			step:        cloudiantest.Respond(http.StatusServiceUnavailable, ""),
+			wantCreates: 2,
+		},
+		// The credentials were created, but the answer was lost on the way.
+		"AnswerLost": {
+			step:        cloudiantest.Respond(http.StatusBadGateway, "").Handled(),
+			wantCreates: 1,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			fake := cloudiantest.NewFakeServer(t)
+			fake.AddUser(cloudiantest.User{GroupID: "QA", UserID: "user1"})
+			fake.Script("/user/credentials", tc.step)
+			cloudianClient := NewClient(fake.URL, "", WithRetries(3, time.Millisecond))
+
+			creds, err := cloudianClient.CreateUserCredentials(context.TODO(), guid)
+			if err != nil {
+				t.Fatalf("CreateUserCredentials(): %v", err)
+			}
+			keys := fake.AccessKeys("QA", "user1")
+			if len(keys) != 1 || creds.AccessKey != keys[0] {
+				t.Errorf("CreateUserCredentials(): want the only credentials of the user %v, got %q", keys, creds.AccessKey)
+			}
+			if got := fake.Requests("/user/credentials"); got != tc.wantCreates {
+				t.Errorf("CreateUserCredentials(): want %d requests to create credentials, got %d", tc.wantCreates, got)
+			}
+		})
+	}
+}
+
+func TestCreateCredentialsNotRetriedByDefault(t *testing.T) {
+	fake := cloudiantest.NewFakeServer(t)
+	fake.AddUser(cloudiantest.User{GroupID: "QA", UserID: "user1"})
+	fake.Script("/user/credentials", cloudiantest.Respond(http.StatusBadGateway, "").Handled())
+	cloudianClient := NewClient(fake.URL, "")
+
+	if _, err := cloudianClient.CreateUserCredentials(context.TODO(), GroupUserID{GroupID: "QA", UserID: "user1"}); err == nil {
+		t.Fatal("CreateUserCredentials(): want the failure, got success")
+	}
+	if got := fake.Requests("/user/credentials"); got != 1 {
+		t.Errorf("CreateUserCredentials(): want 1 request, got %d", got)
+	}
+	if got := fake.Requests("/user/credentials/list"); got != 0 {
+		t.Errorf("CreateUserCredentials(): want no credentials listed, got %d requests", got)
+	}
+}
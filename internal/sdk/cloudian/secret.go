@@ -0,0 +1,58 @@
+package cloudian
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+)
+
+// redacted replaces the value of a Secret wherever it is formatted.
+const redacted = "REDACTED"
+
+// A Secret is a secret key of credentials. It is redacted when formatted, so
+// that it does not leak into logs and errors, but encoded as is in JSON so
+// that it round-trips through the admin API.
+type Secret struct {
+	value string
+}
+
+// NewSecret returns a Secret holding `value`.
+func NewSecret(value string) Secret {
+	return Secret{value: value}
+}
+
+// Value returns the secret value.
+func (s Secret) Value() string {
+	return s.value
+}
+
+// Equal reports whether both secrets hold the same value, in time independent
+// of where they differ.
+func (s Secret) Equal(other Secret) bool {
+	return subtle.ConstantTimeCompare([]byte(s.value), []byte(other.value)) == 1
+}
+
+func (s Secret) String() string {
+	return redacted
+}
+
+func (s Secret) GoString() string {
+	return "cloudian.Secret{" + redacted + "}"
+}
+
+// Format redacts the secret with every verb and flag.
+func (s Secret) Format(f fmt.State, verb rune) {
+	if verb == 'v' && f.Flag('#') {
+		_, _ = fmt.Fprint(f, s.GoString())
+		return
+	}
+	_, _ = fmt.Fprint(f, redacted)
+}
+
+func (s Secret) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.value)
+}
+
+func (s *Secret) UnmarshalJSON(b []byte) error {
+	return json.Unmarshal(b, &s.value)
+}
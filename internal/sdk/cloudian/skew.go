@@ -0,0 +1,63 @@
+package cloudian
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// skewSmoothing is the weight of each new sample in the estimated clock skew,
+// so that a single slow response does not move the estimate much.
+const skewSmoothing = 0.2
+
+// clockSkew estimates how far the clock of Cloudian is ahead of the local
+// clock, from the Date header of its responses.
+type clockSkew struct {
+	mu      sync.Mutex
+	offset  time.Duration
+	sampled bool
+}
+
+// observe adds a sample from a response with the supplied Date header to a
+// request sent at `sent` and answered at `received`. Responses without a
+// valid Date header are ignored.
+func (s *clockSkew) observe(date string, sent, received time.Time) (time.Duration, bool) {
+	serverTime, err := http.ParseTime(date)
+	if err != nil {
+		return 0, false
+	}
+	// The Date header is truncated to the second, and the response was sent
+	// at some point between the request leaving and the response arriving.
+	serverTime = serverTime.Add(500 * time.Millisecond)
+	localTime := sent.Add(received.Sub(sent) / 2)
+	sample := serverTime.Sub(localTime)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.sampled {
+		s.offset, s.sampled = sample, true
+	} else {
+		s.offset += time.Duration(skewSmoothing * float64(sample-s.offset))
+	}
+	return s.offset, true
+}
+
+func (s *clockSkew) get() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.offset
+}
+
+// ClockSkew returns how far the clock of Cloudian is estimated to be ahead of
+// the local clock, smoothed over the responses received so far. It is zero
+// until a response with a Date header has been received.
+func (client Client) ClockSkew() time.Duration {
+	return client.skew.get()
+}
+
+// Now returns the current time on the clock of Cloudian, as estimated from
+// ClockSkew. Timestamps reported by Cloudian should be compared with it
+// rather than with the local time.
+func (client Client) Now() time.Time {
+	return client.clock.Now().Add(client.ClockSkew())
+}
@@ -0,0 +1,85 @@
+package cloudian
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/statnett/provider-cloudian/internal/version"
+)
+
+// tracerName is the instrumentation scope of the spans of the client.
+const tracerName = "github.com/statnett/provider-cloudian/internal/sdk/cloudian"
+
+// Attributes of the spans of the client, beyond the semantic conventions of
+// HTTP.
+const (
+	// AttributeNotFound is set on the span of an operation that returned
+	// ErrNotFound, which is not an error of the span.
+	AttributeNotFound = attribute.Key("cloudian.not_found")
+	// AttributeRegion is the region of the operation, when it has one.
+	AttributeRegion = attribute.Key("cloudian.region")
+)
+
+// WithTracerProvider makes the client record a span of each operation on the
+// admin API, named after the operation, such as GetGroup, as a child of the
+// span of its context. Each request of the operation is an event of the span.
+// Without it the client records nothing.
+func WithTracerProvider(tp trace.TracerProvider) func(*Client) {
+	return func(c *Client) {
+		c.tracer = tp.Tracer(tracerName, trace.WithInstrumentationVersion(version.Version))
+	}
+}
+
+// operationSpan is the span of an operation, or nil when not tracing.
+type operationSpan struct {
+	trace.Span
+}
+
+// startSpan starts the span of operation `name`, when tracing.
+func (client Client) startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, *operationSpan) {
+	if client.tracer == nil {
+		return ctx, nil
+	}
+	ctx, span := client.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	return ctx, &operationSpan{Span: span}
+}
+
+// end ends the span with the error of its operation, if any.
+func (s *operationSpan) end(err *error) {
+	if s == nil {
+		return
+	}
+	switch {
+	case *err == nil:
+	case errors.Is(*err, ErrNotFound):
+		s.SetAttributes(AttributeNotFound.Bool(true))
+	default:
+		s.RecordError(*err)
+		s.SetStatus(codes.Error, (*err).Error())
+	}
+	s.End()
+}
+
+// traceRequest records a request of the operation whose span is in `ctx`, if
+// any, along with the status code Cloudian responded with.
+func (client Client) traceRequest(ctx context.Context, method, path string, statusCode int) {
+	if client.tracer == nil {
+		return
+	}
+	attrs := []attribute.KeyValue{
+		attribute.String("http.request.method", method),
+		attribute.String("url.path", path),
+	}
+	if statusCode > 0 {
+		attrs = append(attrs, attribute.Int("http.response.status_code", statusCode))
+	}
+	span := trace.SpanFromContext(ctx)
+	span.AddEvent("request", trace.WithAttributes(attrs...))
+	if statusCode > 0 {
+		span.SetAttributes(attribute.Int("http.response.status_code", statusCode))
+	}
+}
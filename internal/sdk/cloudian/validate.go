@@ -0,0 +1,51 @@
+package cloudian
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// MaxIDLength is the maximum length of group and user IDs.
+const MaxIDLength = 64
+
+// ErrInvalidID is returned for group and user IDs that Cloudian would reject.
+var ErrInvalidID = errors.New("invalid ID")
+
+var (
+	groupIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	userIDPattern  = regexp.MustCompile(`^[A-Za-z0-9_.@-]+$`)
+)
+
+// ValidateGroupID returns an error wrapping ErrInvalidID if Cloudian would
+// reject the group ID. Group IDs consist of letters, digits, dashes and
+// underscores.
+func ValidateGroupID(groupID string) error {
+	return validateID("group", groupID, groupIDPattern)
+}
+
+// ValidateUserID returns an error wrapping ErrInvalidID if Cloudian would
+// reject the user ID. User IDs consist of letters, digits, dashes,
+// underscores, periods and at signs.
+func ValidateUserID(userID string) error {
+	return validateID("user", userID, userIDPattern)
+}
+
+func validateID(kind, id string, pattern *regexp.Regexp) error {
+	switch {
+	case id == "":
+		return fmt.Errorf("%w: %s ID must not be empty", ErrInvalidID, kind)
+	case len(id) > MaxIDLength:
+		return fmt.Errorf("%w: %s ID %q is longer than %d characters", ErrInvalidID, kind, id, MaxIDLength)
+	case !pattern.MatchString(id):
+		return fmt.Errorf("%w: %s ID %q must match %s", ErrInvalidID, kind, id, pattern)
+	}
+	return nil
+}
+
+func validateGroupUserID(guid GroupUserID) error {
+	if err := ValidateGroupID(guid.GroupID); err != nil {
+		return err
+	}
+	return ValidateUserID(guid.UserID)
+}
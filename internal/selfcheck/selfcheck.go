@@ -0,0 +1,239 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package selfcheck verifies at startup that the provider is able to read its
+// own custom resources and secrets, and to reach the Cloudian admin API of
+// every configured ProviderConfig.
+package selfcheck
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/logging"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	userv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/user/v1alpha1"
+	apisv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/v1alpha1"
+	pcv1alpha1common "github.com/statnett/provider-cloudian/apis/common/providerconfig/v1alpha1"
+	userv1alpha1namespaced "github.com/statnett/provider-cloudian/apis/namespaced/user/v1alpha1"
+	apisv1alpha1namespaced "github.com/statnett/provider-cloudian/apis/namespaced/v1alpha1"
+	controllercommon "github.com/statnett/provider-cloudian/internal/controller/common"
+	"github.com/statnett/provider-cloudian/internal/sdk/cloudian"
+)
+
+// Modes accepted by the --self-check flag.
+const (
+	ModeOff    = "off"
+	ModeOn     = "on"
+	ModeStrict = "strict"
+)
+
+// SecretName is the secret read to verify that the provider is allowed to
+// read secrets in its namespace. It does not need to exist.
+const SecretName = "provider-cloudian-self-check"
+
+const (
+	errListKind      = "cannot list %T"
+	errGetSecret     = "cannot read secrets in namespace %s"
+	errListPCs       = "cannot list %T"
+	errGetCreds      = "cannot get credentials"
+	errNewClient     = "cannot create new Service"
+	errReachEndpoint = "cannot reach Cloudian admin API at %s"
+)
+
+// A Result is the outcome of a single check.
+type Result struct {
+	// Name identifies the check.
+	Name string
+	// Err is nil if the check passed.
+	Err error
+}
+
+// A Checker runs the self-check.
+type Checker struct {
+	kube         client.Client
+	namespace    string
+	lists        []client.ObjectList
+	newServiceFn func(spec pcv1alpha1common.ProviderConfigSpec, creds controllercommon.Credentials) (*cloudian.Client, error)
+}
+
+// An Option configures a Checker.
+type Option func(*Checker)
+
+// WithLists overrides the resource lists used to verify that the provider's
+// CRDs are installed and readable.
+func WithLists(lists ...client.ObjectList) Option {
+	return func(c *Checker) {
+		c.lists = lists
+	}
+}
+
+// WithNewServiceFn overrides how Cloudian clients are constructed.
+func WithNewServiceFn(fn func(spec pcv1alpha1common.ProviderConfigSpec, creds controllercommon.Credentials) (*cloudian.Client, error)) Option {
+	return func(c *Checker) {
+		c.newServiceFn = fn
+	}
+}
+
+// New returns a Checker that uses the supplied client, which must not depend
+// on a started cache, and reads secrets from the supplied namespace.
+func New(kube client.Client, namespace string, opts ...Option) *Checker {
+	c := &Checker{
+		kube:      kube,
+		namespace: namespace,
+		lists: []client.ObjectList{
+			&apisv1alpha1cluster.ProviderConfigList{},
+			&userv1alpha1cluster.UserList{},
+			&userv1alpha1cluster.GroupList{},
+			&userv1alpha1cluster.AccessKeyList{},
+			&userv1alpha1cluster.GroupQualityOfServiceLimitsList{},
+			&userv1alpha1cluster.GroupRatingPlanList{},
+			&userv1alpha1cluster.IAMUserList{},
+			&userv1alpha1cluster.UserQualityOfServiceLimitsList{},
+			&apisv1alpha1namespaced.ProviderConfigList{},
+			&apisv1alpha1namespaced.ClusterProviderConfigList{},
+			&userv1alpha1namespaced.UserList{},
+			&userv1alpha1namespaced.GroupList{},
+			&userv1alpha1namespaced.AccessKeyList{},
+			&userv1alpha1namespaced.GroupQualityOfServiceLimitsList{},
+			&userv1alpha1namespaced.GroupRatingPlanList{},
+			&userv1alpha1namespaced.IAMUserList{},
+			&userv1alpha1namespaced.UserQualityOfServiceLimitsList{},
+		},
+		newServiceFn: controllercommon.NewCloudianService,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Run performs all checks and returns their results.
+func (c *Checker) Run(ctx context.Context) []Result {
+	results := make([]Result, 0, len(c.lists)+1)
+	for _, l := range c.lists {
+		results = append(results, Result{
+			Name: fmt.Sprintf("list %T", l),
+			Err:  c.checkList(ctx, l),
+		})
+	}
+	results = append(results, Result{
+		Name: "read secrets",
+		Err:  c.checkSecret(ctx),
+	})
+	return append(results, c.checkEndpoints(ctx)...)
+}
+
+func (c *Checker) checkList(ctx context.Context, l client.ObjectList) error {
+	return errors.Wrapf(c.kube.List(ctx, l, client.Limit(1)), errListKind, l)
+}
+
+func (c *Checker) checkSecret(ctx context.Context) error {
+	err := c.kube.Get(ctx, types.NamespacedName{Namespace: c.namespace, Name: SecretName}, &corev1.Secret{})
+	if kerrors.IsNotFound(err) {
+		// We are allowed to read secrets, there just isn't one by this name.
+		return nil
+	}
+	return errors.Wrapf(err, errGetSecret, c.namespace)
+}
+
+// providerConfig is a ProviderConfig of any scope.
+type providerConfig struct {
+	name string
+	spec pcv1alpha1common.ProviderConfigSpec
+}
+
+func (c *Checker) providerConfigs(ctx context.Context) ([]providerConfig, error) {
+	var pcs []providerConfig
+
+	cpcs := &apisv1alpha1cluster.ProviderConfigList{}
+	if err := c.kube.List(ctx, cpcs); err != nil {
+		return nil, errors.Wrapf(err, errListPCs, cpcs)
+	}
+	for _, pc := range cpcs.Items {
+		pcs = append(pcs, providerConfig{name: "ProviderConfig " + pc.Name, spec: pc.Spec})
+	}
+
+	npcs := &apisv1alpha1namespaced.ProviderConfigList{}
+	if err := c.kube.List(ctx, npcs); err != nil {
+		return nil, errors.Wrapf(err, errListPCs, npcs)
+	}
+	for _, pc := range npcs.Items {
+		pcs = append(pcs, providerConfig{name: "ProviderConfig " + pc.Namespace + "/" + pc.Name, spec: pc.Spec})
+	}
+
+	ncpcs := &apisv1alpha1namespaced.ClusterProviderConfigList{}
+	if err := c.kube.List(ctx, ncpcs); err != nil {
+		return nil, errors.Wrapf(err, errListPCs, ncpcs)
+	}
+	for _, pc := range ncpcs.Items {
+		pcs = append(pcs, providerConfig{name: "ClusterProviderConfig " + pc.Name, spec: pc.Spec})
+	}
+
+	return pcs, nil
+}
+
+func (c *Checker) checkEndpoints(ctx context.Context) []Result {
+	pcs, err := c.providerConfigs(ctx)
+	if err != nil {
+		return []Result{{Name: "list provider configs", Err: err}}
+	}
+
+	results := make([]Result, 0, len(pcs))
+	for _, pc := range pcs {
+		results = append(results, Result{
+			Name: "reach " + pc.name,
+			Err:  c.checkEndpoint(ctx, pc.spec),
+		})
+	}
+	return results
+}
+
+func (c *Checker) checkEndpoint(ctx context.Context, spec pcv1alpha1common.ProviderConfigSpec) error {
+	creds, err := controllercommon.ExtractCredentials(ctx, c.kube, spec)
+	if err != nil {
+		return errors.Wrap(err, errGetCreds)
+	}
+
+	svc, err := c.newServiceFn(spec, creds)
+	if err != nil {
+		return errors.Wrap(err, errNewClient)
+	}
+
+	return errors.Wrapf(svc.Ping(ctx), errReachEndpoint, spec.Endpoint)
+}
+
+// Report logs the results and returns whether any check failed.
+func Report(log logging.Logger, results []Result) bool {
+	failed := false
+	for _, r := range results {
+		if r.Err != nil {
+			failed = true
+			log.Info("Self-check failed", "check", r.Name, "error", r.Err.Error())
+			continue
+		}
+		log.Debug("Self-check passed", "check", r.Name)
+	}
+	if !failed {
+		log.Info("Self-check passed", "checks", len(results))
+	}
+	return failed
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package selfcheck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	xpv2 "github.com/crossplane/crossplane/apis/v2/core/v2"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	apiscluster "github.com/statnett/provider-cloudian/apis/cluster"
+	apisv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/v1alpha1"
+	pcv1alpha1common "github.com/statnett/provider-cloudian/apis/common/providerconfig/v1alpha1"
+	apisnamespaced "github.com/statnett/provider-cloudian/apis/namespaced"
+)
+
+const namespace = "crossplane-system"
+
+func scheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+	s := runtime.NewScheme()
+	for _, add := range []func(*runtime.Scheme) error{clientgoscheme.AddToScheme, apiscluster.AddToScheme, apisnamespaced.AddToScheme} {
+		if err := add(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s
+}
+
+func newProviderConfig(endpoint string) *apisv1alpha1cluster.ProviderConfig {
+	return &apisv1alpha1cluster.ProviderConfig{
+		ObjectMeta: metav1.ObjectMeta{Name: "default"},
+		Spec: pcv1alpha1common.ProviderConfigSpec{
+			Endpoint: endpoint,
+			AuthHeader: pcv1alpha1common.ProviderCredentials{
+				Source: xpv2.CredentialsSourceSecret,
+				CommonCredentialSelectors: xpv2.CommonCredentialSelectors{
+					SecretRef: &xpv2.SecretKeySelector{
+						SecretReference: xpv2.SecretReference{Namespace: namespace, Name: "cloudian"},
+						Key:             "authHeader",
+					},
+				},
+			},
+		},
+	}
+}
+
+func credentials() *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "cloudian"},
+		Data:       map[string][]byte{"authHeader": []byte("Basic Zm9vOmJhcg==")},
+	}
+}
+
+func failed(results []Result) []string {
+	var names []string
+	for _, r := range results {
+		if r.Err != nil {
+			names = append(names, r.Name)
+		}
+	}
+	return names
+}
+
+func TestRun(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/system/version" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte("8.1.2\n"))
+	}))
+	defer healthy.Close()
+
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer broken.Close()
+
+	forbidden := func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+		if _, ok := obj.(*corev1.Secret); ok {
+			return kerrors.NewForbidden(schema.GroupResource{Resource: "secrets"}, key.Name, nil)
+		}
+		return c.Get(ctx, key, obj, opts...)
+	}
+
+	cases := map[string]struct {
+		reason      string
+		scheme      *runtime.Scheme
+		objects     []client.Object
+		funcs       interceptor.Funcs
+		wantFailed  int
+		wantResults int
+	}{
+		"AllPass": {
+			reason:      "No checks should fail when everything is in place.",
+			scheme:      scheme(t),
+			objects:     []client.Object{newProviderConfig(healthy.URL), credentials()},
+			wantResults: 19,
+		},
+		"MissingCRD": {
+			reason:      "Listing kinds unknown to the API server should fail.",
+			scheme:      clientgoscheme.Scheme,
+			wantFailed:  18,
+			wantResults: 19,
+		},
+		"SecretForbidden": {
+			reason:      "Not being allowed to read secrets should fail the secret check and the credentials of each endpoint.",
+			scheme:      scheme(t),
+			objects:     []client.Object{newProviderConfig(healthy.URL), credentials()},
+			funcs:       interceptor.Funcs{Get: forbidden},
+			wantFailed:  2,
+			wantResults: 19,
+		},
+		"MissingCredentials": {
+			reason:      "A ProviderConfig referencing a missing secret should fail.",
+			scheme:      scheme(t),
+			objects:     []client.Object{newProviderConfig(healthy.URL)},
+			wantFailed:  1,
+			wantResults: 19,
+		},
+		"EndpointUnreachable": {
+			reason:      "An endpoint rejecting the request should fail.",
+			scheme:      scheme(t),
+			objects:     []client.Object{newProviderConfig(broken.URL), credentials()},
+			wantFailed:  1,
+			wantResults: 19,
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			kube := fake.NewClientBuilder().
+				WithScheme(tc.scheme).
+				WithObjects(tc.objects...).
+				WithInterceptorFuncs(tc.funcs).
+				Build()
+
+			results := New(kube, namespace).Run(context.Background())
+			if len(results) != tc.wantResults {
+				t.Errorf("\n%s\nRun(...): want %d results, got %d", tc.reason, tc.wantResults, len(results))
+			}
+			if got := failed(results); len(got) != tc.wantFailed {
+				t.Errorf("\n%s\nRun(...): want %d failed checks, got %d: %v", tc.reason, tc.wantFailed, len(got), got)
+			}
+		})
+	}
+}
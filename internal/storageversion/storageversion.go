@@ -0,0 +1,188 @@
+// Package storageversion rewrites the custom resources of the provider in the
+// storage version of their CRD after an upgrade, so that older API versions
+// can be removed without depending on conversion forever. It works like the
+// kube-storage-version-migrator, scoped to the groups of this provider.
+package storageversion
+
+import (
+	"context"
+	"slices"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/logging"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	userv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/user/v1alpha1"
+	apisv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/v1alpha1"
+	userv1alpha1namespaced "github.com/statnett/provider-cloudian/apis/namespaced/user/v1alpha1"
+	apisv1alpha1namespaced "github.com/statnett/provider-cloudian/apis/namespaced/v1alpha1"
+)
+
+// pageSize is how many objects are listed at a time.
+const pageSize = 100
+
+const (
+	errListCRDs     = "cannot list CustomResourceDefinitions"
+	errNoStorage    = "CustomResourceDefinition %s has no storage version"
+	errListObjects  = "cannot list %s"
+	errMigrate      = "cannot migrate %s %s"
+	errStoredStatus = "cannot update the stored versions of CustomResourceDefinition %s"
+	errMigrateCRDs  = "cannot migrate the custom resources of %d CustomResourceDefinitions"
+)
+
+// A Migrator rewrites the custom resources whose CRD still records stored
+// versions other than its storage version.
+type Migrator struct {
+	kube     client.Client
+	log      logging.Logger
+	groups   []string
+	migrated *prometheus.CounterVec
+}
+
+// An Option configures a Migrator.
+type Option func(*Migrator)
+
+// WithGroups overrides the API groups whose custom resources are migrated.
+func WithGroups(groups ...string) Option {
+	return func(m *Migrator) {
+		m.groups = groups
+	}
+}
+
+// New returns a Migrator that uses the supplied client, which must not depend
+// on a started cache.
+func New(kube client.Client, log logging.Logger, opts ...Option) *Migrator {
+	m := &Migrator{
+		kube: kube,
+		log:  log,
+		groups: []string{
+			apisv1alpha1cluster.Group,
+			userv1alpha1cluster.MetadataGroup,
+			apisv1alpha1namespaced.Group,
+			userv1alpha1namespaced.MetadataGroup,
+		},
+		migrated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "provider_cloudian_storage_version_migrated_total",
+			Help: "The number of custom resources rewritten in the storage version of their CRD.",
+		}, []string{"resource"}),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Describe implements prometheus.Collector.
+func (m *Migrator) Describe(ch chan<- *prometheus.Desc) {
+	m.migrated.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (m *Migrator) Collect(ch chan<- prometheus.Metric) {
+	m.migrated.Collect(ch)
+}
+
+// Run migrates the custom resources of every CRD of the provider that needs
+// it. The CRDs are all attempted, logging why any of them failed.
+func (m *Migrator) Run(ctx context.Context) error {
+	crds := &apiextensionsv1.CustomResourceDefinitionList{}
+	if err := m.kube.List(ctx, crds); err != nil {
+		return errors.Wrap(err, errListCRDs)
+	}
+
+	failed := 0
+	for i := range crds.Items {
+		crd := &crds.Items[i]
+		if !slices.Contains(m.groups, crd.Spec.Group) {
+			continue
+		}
+		if err := m.migrate(ctx, crd); err != nil {
+			m.log.Info("Cannot migrate custom resources to the storage version", "crd", crd.Name, "error", err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return errors.Errorf(errMigrateCRDs, failed)
+	}
+	return nil
+}
+
+// Start runs the migration once, logging rather than returning its errors so
+// that a failed migration does not stop the manager. It implements
+// manager.Runnable.
+func (m *Migrator) Start(ctx context.Context) error {
+	if err := m.Run(ctx); err != nil {
+		m.log.Info("Storage version migration failed, it is retried on the next start", "error", err)
+	}
+	return nil
+}
+
+func (m *Migrator) migrate(ctx context.Context, crd *apiextensionsv1.CustomResourceDefinition) error {
+	storage := storageVersion(crd)
+	if storage == "" {
+		return errors.Errorf(errNoStorage, crd.Name)
+	}
+	if slices.Equal(crd.Status.StoredVersions, []string{storage}) {
+		return nil
+	}
+
+	log := m.log.WithValues("crd", crd.Name, "storedVersions", crd.Status.StoredVersions, "storageVersion", storage)
+	log.Info("Migrating custom resources to the storage version")
+
+	gvk := schema.GroupVersionKind{Group: crd.Spec.Group, Version: storage, Kind: crd.Spec.Names.ListKind}
+	count := 0
+	opts := []client.ListOption{client.Limit(pageSize)}
+	for {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(gvk)
+		if err := m.kube.List(ctx, list, opts...); err != nil {
+			return errors.Wrapf(err, errListObjects, crd.Name)
+		}
+		for i := range list.Items {
+			if err := m.rewrite(ctx, &list.Items[i]); err != nil {
+				return errors.Wrapf(err, errMigrate, crd.Spec.Names.Kind, client.ObjectKeyFromObject(&list.Items[i]))
+			}
+			m.migrated.WithLabelValues(crd.Name).Inc()
+			count++
+		}
+		log.Debug("Migrated a page of custom resources", "migrated", count)
+		if list.GetContinue() == "" {
+			break
+		}
+		opts = []client.ListOption{client.Limit(pageSize), client.Continue(list.GetContinue())}
+	}
+
+	// Only the storage version is stored once every object is rewritten,
+	// which lets older versions be removed from the CRD.
+	crd.Status.StoredVersions = []string{storage}
+	if err := m.kube.Status().Update(ctx, crd); err != nil {
+		return errors.Wrapf(err, errStoredStatus, crd.Name)
+	}
+	log.Info("Migrated custom resources to the storage version", "migrated", count)
+	return nil
+}
+
+// rewrite writes an object back unchanged, which makes the API server store
+// it in the storage version. Objects deleted or changed since they were listed
+// need no rewrite, as the change already stored them in the storage version.
+func (m *Migrator) rewrite(ctx context.Context, obj *unstructured.Unstructured) error {
+	err := m.kube.Update(ctx, obj)
+	if kerrors.IsNotFound(err) || kerrors.IsConflict(err) {
+		return nil
+	}
+	return err
+}
+
+func storageVersion(crd *apiextensionsv1.CustomResourceDefinition) string {
+	for _, v := range crd.Spec.Versions {
+		if v.Storage {
+			return v.Name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,269 @@
+package storageversion
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/crossplane/crossplane-runtime/v2/pkg/logging"
+	"github.com/google/go-cmp/cmp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+
+	apiscluster "github.com/statnett/provider-cloudian/apis/cluster"
+	userv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/user/v1alpha1"
+)
+
+func scheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+	s := runtime.NewScheme()
+	for _, add := range []func(*runtime.Scheme) error{apiextensionsv1.AddToScheme, apiscluster.AddToScheme} {
+		if err := add(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s
+}
+
+// newCRD returns the CRD of Users, served in v1alpha1 and an older version,
+// which has stored objects in the supplied versions.
+func newCRD(group string, storedVersions ...string) *apiextensionsv1.CustomResourceDefinition {
+	return &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "users." + group},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: group,
+			Names: apiextensionsv1.CustomResourceDefinitionNames{Kind: "User", ListKind: "UserList"},
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+				{Name: "v1alpha0", Served: true},
+				{Name: "v1alpha1", Served: true, Storage: true},
+			},
+		},
+		Status: apiextensionsv1.CustomResourceDefinitionStatus{StoredVersions: storedVersions},
+	}
+}
+
+func newUser(name string) *userv1alpha1cluster.User {
+	return &userv1alpha1cluster.User{ObjectMeta: metav1.ObjectMeta{Name: name}}
+}
+
+func TestRun(t *testing.T) {
+	conflict := func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+		if obj.GetName() == "changed" {
+			return kerrors.NewConflict(schema.GroupResource{Resource: "users"}, obj.GetName(), nil)
+		}
+		return c.Update(ctx, obj, opts...)
+	}
+
+	cases := map[string]struct {
+		reason             string
+		crd                *apiextensionsv1.CustomResourceDefinition
+		users              []client.Object
+		funcs              interceptor.Funcs
+		wantErr            bool
+		wantUpdated        []string
+		wantStoredVersions []string
+	}{
+		"Migrated": {
+			reason:             "Every object should be rewritten before only the storage version is recorded as stored.",
+			crd:                newCRD(userv1alpha1cluster.MetadataGroup, "v1alpha0", "v1alpha1"),
+			users:              []client.Object{newUser("a"), newUser("b")},
+			wantUpdated:        []string{"a", "b"},
+			wantStoredVersions: []string{"v1alpha1"},
+		},
+		"UpToDate": {
+			reason:             "Objects should not be rewritten when only the storage version is stored.",
+			crd:                newCRD(userv1alpha1cluster.MetadataGroup, "v1alpha1"),
+			users:              []client.Object{newUser("a")},
+			wantStoredVersions: []string{"v1alpha1"},
+		},
+		"OtherGroup": {
+			reason:             "CRDs of other providers should be left alone.",
+			crd:                newCRD("example.org", "v1alpha0", "v1alpha1"),
+			wantStoredVersions: []string{"v1alpha0", "v1alpha1"},
+		},
+		"Conflict": {
+			reason:             "Objects changed since they were listed are already stored in the storage version.",
+			crd:                newCRD(userv1alpha1cluster.MetadataGroup, "v1alpha0", "v1alpha1"),
+			users:              []client.Object{newUser("a"), newUser("changed")},
+			funcs:              interceptor.Funcs{Update: conflict},
+			wantUpdated:        []string{"a", "changed"},
+			wantStoredVersions: []string{"v1alpha1"},
+		},
+		"Failed": {
+			reason: "The stored versions should be kept when an object cannot be rewritten.",
+			crd:    newCRD(userv1alpha1cluster.MetadataGroup, "v1alpha0", "v1alpha1"),
+			users:  []client.Object{newUser("a")},
+			funcs: interceptor.Funcs{Update: func(context.Context, client.WithWatch, client.Object, ...client.UpdateOption) error {
+				return kerrors.NewForbidden(schema.GroupResource{Resource: "users"}, "a", nil)
+			}},
+			wantErr:            true,
+			wantUpdated:        []string{"a"},
+			wantStoredVersions: []string{"v1alpha0", "v1alpha1"},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var updated []string
+			update := tc.funcs.Update
+			if update == nil {
+				update = func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+					return c.Update(ctx, obj, opts...)
+				}
+			}
+			tc.funcs.Update = func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+				updated = append(updated, obj.GetName())
+				return update(ctx, c, obj, opts...)
+			}
+			kube := fake.NewClientBuilder().
+				WithScheme(scheme(t)).
+				WithObjects(append(tc.users, tc.crd)...).
+				WithStatusSubresource(tc.crd).
+				WithInterceptorFuncs(tc.funcs).
+				Build()
+
+			m := New(kube, logging.NewNopLogger())
+			if err := m.Run(context.Background()); (err != nil) != tc.wantErr {
+				t.Errorf("\n%s\nRun(...): want error %t, got %v", tc.reason, tc.wantErr, err)
+			}
+			if diff := cmp.Diff(tc.wantUpdated, updated); diff != "" {
+				t.Errorf("\n%s\nRun(...): updated objects -want, +got:\n%s", tc.reason, diff)
+			}
+
+			crd := &apiextensionsv1.CustomResourceDefinition{}
+			if err := kube.Get(context.Background(), client.ObjectKeyFromObject(tc.crd), crd); err != nil {
+				t.Fatalf("Get(...): %v", err)
+			}
+			if diff := cmp.Diff(tc.wantStoredVersions, crd.Status.StoredVersions); diff != "" {
+				t.Errorf("\n%s\nRun(...): stored versions -want, +got:\n%s", tc.reason, diff)
+			}
+			if got, want := testutil.ToFloat64(m.migrated.WithLabelValues(tc.crd.Name)), float64(len(tc.wantUpdated)); !tc.wantErr && got != want {
+				t.Errorf("\n%s\nRun(...): want %v migrated, got %v", tc.reason, want, got)
+			}
+		})
+	}
+}
+
+// newWidgetCRD returns a CRD of Widgets served in v1alpha0, its storage
+// version, and v1alpha1.
+func newWidgetCRD() *apiextensionsv1.CustomResourceDefinition {
+	validation := &apiextensionsv1.CustomResourceValidation{OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
+		Type:                   "object",
+		XPreserveUnknownFields: ptr.To(true),
+	}}
+	return &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "widgets.example.org"},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: "example.org",
+			Scope: apiextensionsv1.ClusterScoped,
+			Names: apiextensionsv1.CustomResourceDefinitionNames{Plural: "widgets", Singular: "widget", Kind: "Widget", ListKind: "WidgetList"},
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+				{Name: "v1alpha0", Served: true, Storage: true, Schema: validation},
+				{Name: "v1alpha1", Served: true, Schema: validation},
+			},
+		},
+	}
+}
+
+func newWidget(name string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(schema.GroupVersionKind{Group: "example.org", Version: "v1alpha1", Kind: "Widget"})
+	u.SetName(name)
+	return u
+}
+
+// TestRunEnvtest migrates custom resources stored in an older version against
+// a real API server, which stores them again only when their encoding in the
+// storage version differs. It needs the binaries of envtest, found through
+// KUBEBUILDER_ASSETS.
+func TestRunEnvtest(t *testing.T) {
+	if os.Getenv("KUBEBUILDER_ASSETS") == "" {
+		t.Skip("KUBEBUILDER_ASSETS is not set")
+	}
+	env := &envtest.Environment{CRDs: []*apiextensionsv1.CustomResourceDefinition{newWidgetCRD()}}
+	cfg, err := env.Start()
+	if err != nil {
+		t.Fatalf("env.Start(): %v", err)
+	}
+	t.Cleanup(func() { _ = env.Stop() })
+	kube, err := client.New(cfg, client.Options{Scheme: scheme(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// Widgets created now are stored in v1alpha0.
+	for _, name := range []string{"alice", "probe"} {
+		if err := kube.Create(ctx, newWidget(name)); err != nil {
+			t.Fatalf("Create(...): %v", err)
+		}
+	}
+	alice := newWidget("alice")
+	if err := kube.Get(ctx, client.ObjectKeyFromObject(alice), alice); err != nil {
+		t.Fatalf("Get(...): %v", err)
+	}
+
+	crd := &apiextensionsv1.CustomResourceDefinition{}
+	if err := kube.Get(ctx, client.ObjectKey{Name: "widgets.example.org"}, crd); err != nil {
+		t.Fatalf("Get(...): %v", err)
+	}
+	crd.Spec.Versions[0].Storage = false
+	crd.Spec.Versions[1].Storage = true
+	if err := kube.Update(ctx, crd); err != nil {
+		t.Fatalf("Update(...): %v", err)
+	}
+
+	// The API server switches to the new storage version asynchronously,
+	// which rewriting the probe unchanged tells once it is stored again.
+	err = wait.PollUntilContextTimeout(ctx, 100*time.Millisecond, 30*time.Second, true, func(ctx context.Context) (bool, error) {
+		probe := newWidget("probe")
+		if err := kube.Get(ctx, client.ObjectKeyFromObject(probe), probe); err != nil {
+			return false, err
+		}
+		before := probe.GetResourceVersion()
+		if err := kube.Update(ctx, probe); err != nil {
+			return false, err
+		}
+		return probe.GetResourceVersion() != before, nil
+	})
+	if err != nil {
+		t.Fatalf("waiting for the storage version v1alpha1: %v", err)
+	}
+
+	m := New(kube, logging.NewNopLogger(), WithGroups("example.org"))
+	if err := m.Run(ctx); err != nil {
+		t.Fatalf("Run(...): %v", err)
+	}
+
+	migrated := newWidget("alice")
+	if err := kube.Get(ctx, client.ObjectKeyFromObject(migrated), migrated); err != nil {
+		t.Fatalf("Get(...): %v", err)
+	}
+	if migrated.GetResourceVersion() == alice.GetResourceVersion() {
+		t.Errorf("Run(...): want alice stored again in v1alpha1, got resource version %s unchanged", alice.GetResourceVersion())
+	}
+	if err := kube.Get(ctx, client.ObjectKeyFromObject(crd), crd); err != nil {
+		t.Fatalf("Get(...): %v", err)
+	}
+	if diff := cmp.Diff([]string{"v1alpha1"}, crd.Status.StoredVersions); diff != "" {
+		t.Errorf("Run(...): stored versions -want, +got:\n%s", diff)
+	}
+
+	// The API server refuses to remove versions that are still stored.
+	crd.Spec.Versions = crd.Spec.Versions[1:]
+	if err := kube.Update(ctx, crd); err != nil {
+		t.Errorf("Update(...): want v1alpha0 removable after the migration, got %v", err)
+	}
+}
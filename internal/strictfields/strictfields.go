@@ -0,0 +1,275 @@
+/*
+Copyright 2025 The Crossplane Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package strictfields rejects managed resources applied with spec fields
+// that their API does not know, suggesting the closest known field.
+//
+// The API server prunes unknown fields before admission, so the validating
+// webhook cannot see them in the object itself. It checks the configuration
+// last applied with kubectl instead, which keeps the fields as submitted.
+package strictfields
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	userv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/user/v1alpha1"
+	userv1alpha1namespaced "github.com/statnett/provider-cloudian/apis/namespaced/user/v1alpha1"
+)
+
+// Path is the path the validating webhook is served at.
+const Path = "/validate-unknown-fields"
+
+// Kinds are the kinds checked for unknown fields, by the type of their spec.
+var Kinds = map[schema.GroupVersionKind]reflect.Type{
+	userv1alpha1cluster.GroupGroupVersionKind:                          reflect.TypeFor[userv1alpha1cluster.GroupSpec](),
+	userv1alpha1cluster.GroupQualityOfServiceLimitsGroupVersionKind:    reflect.TypeFor[userv1alpha1cluster.GroupQualityOfServiceLimitsSpec](),
+	userv1alpha1cluster.UserQualityOfServiceLimitsGroupVersionKind:     reflect.TypeFor[userv1alpha1cluster.UserQualityOfServiceLimitsSpec](),
+	userv1alpha1namespaced.GroupGroupVersionKind:                       reflect.TypeFor[userv1alpha1namespaced.GroupSpec](),
+	userv1alpha1namespaced.GroupQualityOfServiceLimitsGroupVersionKind: reflect.TypeFor[userv1alpha1namespaced.GroupQualityOfServiceLimitsSpec](),
+	userv1alpha1namespaced.UserQualityOfServiceLimitsGroupVersionKind:  reflect.TypeFor[userv1alpha1namespaced.UserQualityOfServiceLimitsSpec](),
+}
+
+// An UnknownField is a field of a submitted object that its API does not
+// know.
+type UnknownField struct {
+	// Path of the field, such as spec.forProvider.groupName.
+	Path string
+	// Suggestion is the closest known field at the same level, if any is
+	// close enough to be a likely typo.
+	Suggestion string
+}
+
+func (f UnknownField) String() string {
+	if f.Suggestion == "" {
+		return "unknown field " + f.Path
+	}
+	return fmt.Sprintf("unknown field %s, did you mean %s?", f.Path, f.Suggestion)
+}
+
+// Unknown returns the fields of a submitted value that are not known by the
+// supplied type, sorted by path. Fields are known by their JSON name.
+func Unknown(path string, submitted any, t reflect.Type) []UnknownField {
+	var unknown []UnknownField
+	walk(path, submitted, t, &unknown)
+	sort.Slice(unknown, func(i, j int) bool { return unknown[i].Path < unknown[j].Path })
+	return unknown
+}
+
+var unmarshalerType = reflect.TypeFor[json.Unmarshaler]()
+
+func walk(path string, submitted any, t reflect.Type, unknown *[]UnknownField) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	// Types decoding themselves, such as quantities and times, are leaves.
+	if reflect.PointerTo(t).Implements(unmarshalerType) {
+		return
+	}
+	switch v := submitted.(type) {
+	case map[string]any:
+		walkObject(path, v, t, unknown)
+	case []any:
+		if k := t.Kind(); k == reflect.Slice || k == reflect.Array {
+			for i, item := range v {
+				walk(fmt.Sprintf("%s[%d]", path, i), item, t.Elem(), unknown)
+			}
+		}
+	}
+}
+
+// walkObject walks the fields of a submitted object, which is either a
+// struct with known fields or a map with arbitrary keys.
+func walkObject(path string, obj map[string]any, t reflect.Type, unknown *[]UnknownField) {
+	if t.Kind() == reflect.Map {
+		for k, v := range obj {
+			walk(path+"."+k, v, t.Elem(), unknown)
+		}
+		return
+	}
+	if t.Kind() != reflect.Struct {
+		return
+	}
+	fields := jsonFields(t)
+	for name, v := range obj {
+		field, ok := fields[name]
+		if !ok {
+			*unknown = append(*unknown, UnknownField{Path: path + "." + name, Suggestion: closest(name, fields)})
+			continue
+		}
+		walk(path+"."+name, v, field, unknown)
+	}
+}
+
+// jsonFields returns the types of the fields of a struct by JSON name,
+// including the fields of inlined structs.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for f := range t.Fields() {
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" && (f.Anonymous || strings.Contains(opts, "inline")) {
+			embedded := f.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			for k, v := range jsonFields(embedded) {
+				fields[k] = v
+			}
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// closest returns the known field closest to an unknown one, if it is close
+// enough to be a likely typo.
+func closest(name string, fields map[string]reflect.Type) string {
+	best, bestDistance := "", len(name)/2+1
+	for known := range fields {
+		d := levenshtein(strings.ToLower(name), strings.ToLower(known))
+		if d < bestDistance || (d == bestDistance && best != "" && known < best) {
+			best, bestDistance = known, d
+		}
+	}
+	return best
+}
+
+// levenshtein returns the number of single character insertions, deletions
+// and substitutions that change `a` into `b`.
+func levenshtein(a, b string) int {
+	s, t := []rune(a), []rune(b)
+	prev := make([]int, len(t)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := range s {
+		cur := make([]int, len(t)+1)
+		cur[0] = i + 1
+		for j := range t {
+			cost := 1
+			if s[i] == t[j] {
+				cost = 0
+			}
+			cur[j+1] = min(prev[j+1]+1, cur[j]+1, prev[j]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(t)]
+}
+
+// A Validator rejects objects applied with unknown spec fields.
+type Validator struct {
+	kinds map[schema.GroupVersionKind]reflect.Type
+}
+
+// NewValidator returns a Validator of the supplied kinds.
+func NewValidator(kinds map[schema.GroupVersionKind]reflect.Type) *Validator {
+	return &Validator{kinds: kinds}
+}
+
+// Setup serves the Validator of Kinds from the webhook server of the manager.
+func Setup(mgr ctrl.Manager) {
+	mgr.GetWebhookServer().Register(Path, &webhook.Admission{Handler: NewValidator(Kinds)})
+}
+
+// Handle rejects the objects whose last applied configuration has unknown
+// spec fields. Updates that do not apply a new configuration are allowed, so
+// that objects applied before strict mode was enabled are not stuck.
+func (v *Validator) Handle(_ context.Context, req admission.Request) admission.Response {
+	t, ok := v.kinds[schema.GroupVersionKind(req.Kind)]
+	if !ok {
+		return admission.Allowed("")
+	}
+	submitted, err := newlyApplied(req)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if submitted == nil {
+		return admission.Allowed("")
+	}
+
+	unknown := Unknown("spec", submitted["spec"], t)
+	if len(unknown) == 0 {
+		return admission.Allowed("")
+	}
+	problems := make([]string, len(unknown))
+	for i, f := range unknown {
+		problems[i] = f.String()
+	}
+	return admission.Denied(fmt.Sprintf("%s has fields that would be silently dropped: %s", req.Kind.Kind, strings.Join(problems, "; ")))
+}
+
+// newlyApplied returns the configuration applied with kubectl by a create or
+// update request, or nil if it applies none or the same as before.
+func newlyApplied(req admission.Request) (map[string]any, error) {
+	if req.Operation != admissionv1.Create && req.Operation != admissionv1.Update {
+		return nil, nil
+	}
+	applied, err := lastApplied(req.Object.Raw)
+	if err != nil || applied == "" {
+		return nil, err
+	}
+	if req.Operation == admissionv1.Update {
+		if old, err := lastApplied(req.OldObject.Raw); err == nil && old == applied {
+			return nil, nil
+		}
+	}
+	submitted := map[string]any{}
+	if err := json.Unmarshal([]byte(applied), &submitted); err != nil {
+		// Not written by kubectl, so there is nothing to check.
+		return nil, nil //nolint:nilerr // Only configurations applied by kubectl are checked.
+	}
+	return submitted, nil
+}
+
+// lastApplied returns the configuration last applied with kubectl to the
+// supplied raw object, if any.
+func lastApplied(raw []byte) (string, error) {
+	if len(raw) == 0 {
+		return "", nil
+	}
+	obj := struct {
+		Metadata struct {
+			Annotations map[string]string `json:"annotations"`
+		} `json:"metadata"`
+	}{}
+	if err := json.Unmarshal(raw, &obj); err != nil {
+		return "", err
+	}
+	return obj.Metadata.Annotations[corev1.LastAppliedConfigAnnotation], nil
+}
@@ -0,0 +1,263 @@
+package strictfields
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	admissionv1 "k8s.io/api/admission/v1"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+	"sigs.k8s.io/yaml"
+
+	userv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/user/v1alpha1"
+	userv1alpha1namespaced "github.com/statnett/provider-cloudian/apis/namespaced/user/v1alpha1"
+)
+
+func TestUnknown(t *testing.T) {
+	cases := map[string]struct {
+		reason string
+		kind   reflect.Type
+		spec   string
+		want   []UnknownField
+	}{
+		"ValidQoS": {
+			reason: "Known fields, including those of inlined structs, should not be reported.",
+			kind:   Kinds[userv1alpha1cluster.UserQualityOfServiceLimitsGroupVersionKind],
+			spec: `
+providerConfigRef: {name: example}
+managementPolicies: ["*"]
+forProvider:
+  userIdRef: {name: bar}
+  hard: {storageQuotaBytes: 2Ti, requestsPerMin: 50}`,
+		},
+		"TypoQoS": {
+			reason: "A misnamed limit should be reported with the closest known limit.",
+			kind:   Kinds[userv1alpha1cluster.UserQualityOfServiceLimitsGroupVersionKind],
+			spec: `
+forProvider:
+  userIdRef: {name: bar}
+  hard: {storageQuotaGiB: 2048, requestPerMin: 50}`,
+			want: []UnknownField{
+				{Path: "spec.forProvider.hard.requestPerMin", Suggestion: "requestsPerMin"},
+				{Path: "spec.forProvider.hard.storageQuotaGiB", Suggestion: "storageQuotaBytes"},
+			},
+		},
+		"TypoGroupQoS": {
+			reason: "A misnamed field of a namespaced kind should be reported too.",
+			kind:   Kinds[userv1alpha1namespaced.GroupQualityOfServiceLimitsGroupVersionKind],
+			spec: `
+forProvider:
+  groupIdRef: {name: foo}
+  warnings: {storageQuotaBytes: 1Ti}`,
+			want: []UnknownField{{Path: "spec.forProvider.warnings", Suggestion: "warning"}},
+		},
+		"TypoGroup": {
+			reason: "A misnamed Group field should be reported with the closest known field.",
+			kind:   Kinds[userv1alpha1cluster.GroupGroupVersionKind],
+			spec: `
+forProvider:
+  active: true
+  groupname: QA
+  ldapEnabeld: false`,
+			want: []UnknownField{
+				{Path: "spec.forProvider.groupname", Suggestion: "groupName"},
+				{Path: "spec.forProvider.ldapEnabeld", Suggestion: "ldapEnabled"},
+			},
+		},
+		"Unrelated": {
+			reason: "A field unlike any known field should be reported without suggestion.",
+			kind:   Kinds[userv1alpha1cluster.GroupGroupVersionKind],
+			spec: `
+forProvider:
+  groupName: QA
+  colour: blue`,
+			want: []UnknownField{{Path: "spec.forProvider.colour"}},
+		},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			var spec any
+			if err := yaml.Unmarshal([]byte(tc.spec), &spec); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tc.want, Unknown("spec", spec, tc.kind)); diff != "" {
+				t.Errorf("\n%s\nUnknown(...): -want, +got:\n%s", tc.reason, diff)
+			}
+		})
+	}
+}
+
+// applied returns a raw Group as applied by kubectl with the supplied spec.
+func applied(t *testing.T, spec map[string]any) []byte {
+	t.Helper()
+	submitted, err := json.Marshal(map[string]any{
+		"apiVersion": userv1alpha1cluster.GroupGroupVersionKind.GroupVersion().String(),
+		"kind":       userv1alpha1cluster.GroupKind,
+		"metadata":   map[string]any{"name": "qa"},
+		"spec":       spec,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The API server has pruned the unknown fields of the object itself.
+	obj := &userv1alpha1cluster.Group{ObjectMeta: metav1.ObjectMeta{
+		Name:        "qa",
+		Annotations: map[string]string{corev1.LastAppliedConfigAnnotation: string(submitted)},
+	}}
+	obj.SetGroupVersionKind(userv1alpha1cluster.GroupGroupVersionKind)
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestHandle(t *testing.T) {
+	typo := map[string]any{"forProvider": map[string]any{"groupName": "QA", "activ": true}}
+	valid := map[string]any{"forProvider": map[string]any{"groupName": "QA", "active": true}}
+	unapplied, err := json.Marshal(&userv1alpha1cluster.Group{ObjectMeta: metav1.ObjectMeta{Name: "qa"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]struct {
+		reason    string
+		kind      metav1.GroupVersionKind
+		operation admissionv1.Operation
+		object    []byte
+		old       []byte
+		wantDeny  string
+	}{
+		"CreateTypo": {
+			reason:    "Applying a Group with a typo should be rejected with a suggestion.",
+			operation: admissionv1.Create,
+			object:    applied(t, typo),
+			wantDeny:  "unknown field spec.forProvider.activ, did you mean active?",
+		},
+		"CreateValid": {
+			reason:    "Applying a Group with known fields only should be allowed.",
+			operation: admissionv1.Create,
+			object:    applied(t, valid),
+		},
+		"NotApplied": {
+			reason:    "Objects not applied with kubectl should be allowed.",
+			operation: admissionv1.Create,
+			object:    unapplied,
+		},
+		"UpdateTypo": {
+			reason:    "Applying a typo to an existing Group should be rejected.",
+			operation: admissionv1.Update,
+			object:    applied(t, typo),
+			old:       applied(t, valid),
+			wantDeny:  "did you mean active?",
+		},
+		"UpdateNotReapplied": {
+			reason:    "Updating a Group whose typo was applied before, such as by the provider, should be allowed.",
+			operation: admissionv1.Update,
+			object:    applied(t, typo),
+			old:       applied(t, typo),
+		},
+		"OtherKind": {
+			reason:    "Kinds that are not checked should be allowed.",
+			kind:      metav1.GroupVersionKind(userv1alpha1cluster.UserGroupVersionKind),
+			operation: admissionv1.Create,
+			object:    applied(t, typo),
+		},
+	}
+
+	v := NewValidator(Kinds)
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			kind := tc.kind
+			if kind.Kind == "" {
+				kind = metav1.GroupVersionKind(userv1alpha1cluster.GroupGroupVersionKind)
+			}
+			req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{
+				Kind:      kind,
+				Operation: tc.operation,
+				Object:    runtime.RawExtension{Raw: tc.object},
+				OldObject: runtime.RawExtension{Raw: tc.old},
+			}}
+			resp := v.Handle(context.Background(), req)
+			if tc.wantDeny == "" {
+				if !resp.Allowed {
+					t.Errorf("\n%s\nv.Handle(...): want allowed, got %v", tc.reason, resp.Result)
+				}
+				return
+			}
+			if resp.Allowed || !strings.Contains(resp.Result.Message, tc.wantDeny) {
+				t.Errorf("\n%s\nv.Handle(...): want denied with %q, got %v", tc.reason, tc.wantDeny, resp.Result)
+			}
+		})
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	cases := map[[2]string]int{
+		{"", ""}:                            0,
+		{"active", "active"}:                0,
+		{"activ", "active"}:                 1,
+		{"requestPerMin", "requestsPerMin"}: 1,
+		{"kitten", "sitting"}:               3,
+	}
+	for in, want := range cases {
+		if got := levenshtein(in[0], in[1]); got != want {
+			t.Errorf("levenshtein(%q, %q): want %d, got %d", in[0], in[1], want, got)
+		}
+	}
+}
+
+func TestWebhookConfiguration(t *testing.T) {
+	b, err := os.ReadFile("../../package/webhookconfigurations/manifests.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+	if err := yaml.UnmarshalStrict(b, conf); err != nil {
+		t.Fatal(err)
+	}
+	if len(conf.Webhooks) != 1 || conf.Webhooks[0].ClientConfig.Service == nil || *conf.Webhooks[0].ClientConfig.Service.Path != Path {
+		t.Fatalf("want a single webhook served at %s, got %+v", Path, conf.Webhooks)
+	}
+	rules := conf.Webhooks[0].Rules
+
+	for gvk := range Kinds {
+		// The CRDs are named after the group and plural of their kind.
+		paths, err := filepath.Glob(filepath.Join("../../package/crds", gvk.Group+"_*.yaml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		covered := false
+		for _, path := range paths {
+			b, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			crd := &apiextensionsv1.CustomResourceDefinition{}
+			if err := yaml.Unmarshal(b, crd); err != nil {
+				t.Fatal(err)
+			}
+			if crd.Spec.Names.Kind != gvk.Kind {
+				continue
+			}
+			covered = slices.ContainsFunc(rules, func(r admissionregistrationv1.RuleWithOperations) bool {
+				return slices.Contains(r.APIGroups, gvk.Group) && slices.Contains(r.APIVersions, gvk.Version) && slices.Contains(r.Resources, crd.Spec.Names.Plural)
+			})
+		}
+		if !covered {
+			t.Errorf("want the webhook to validate %s", gvk)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+// Package client gives Go programs built on the Cloudian provider typed access
+// to its resources, so that they get, list and watch them as the types of the
+// apis package rather than as unstructured objects.
+package client
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	apiscluster "github.com/statnett/provider-cloudian/apis/cluster"
+	userv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/user/v1alpha1"
+	apisv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/v1alpha1"
+	apisnamespaced "github.com/statnett/provider-cloudian/apis/namespaced"
+	userv1alpha1namespaced "github.com/statnett/provider-cloudian/apis/namespaced/user/v1alpha1"
+	apisv1alpha1namespaced "github.com/statnett/provider-cloudian/apis/namespaced/v1alpha1"
+)
+
+const (
+	errAddToScheme = "cannot add the provider's kinds to the scheme"
+	errNewClient   = "cannot create Kubernetes client"
+)
+
+// NewScheme returns a scheme with every kind of the provider, cluster scoped
+// and namespaced.
+func NewScheme() (*runtime.Scheme, error) {
+	s := runtime.NewScheme()
+	for _, add := range []func(*runtime.Scheme) error{apiscluster.AddToScheme, apisnamespaced.AddToScheme} {
+		if err := add(s); err != nil {
+			return nil, errors.Wrap(err, errAddToScheme)
+		}
+	}
+	return s, nil
+}
+
+// A Typed client gets, lists and watches the resources of one kind, whose
+// objects are O and lists of them L.
+type Typed[O client.Object, L client.ObjectList] struct {
+	c       client.WithWatch
+	newObj  func() O
+	newList func() L
+}
+
+// newTyped returns a client of the resources of kind T, listed as L.
+func newTyped[T, L any, PT interface {
+	*T
+	client.Object
+}, PL interface {
+	*L
+	client.ObjectList
+}](c client.WithWatch) Typed[PT, PL] {
+	return Typed[PT, PL]{
+		c:       c,
+		newObj:  func() PT { return new(T) },
+		newList: func() PL { return new(L) },
+	}
+}
+
+// Get returns the resource with the supplied key. The namespace of the key is
+// empty for cluster scoped kinds.
+func (t Typed[O, L]) Get(ctx context.Context, key client.ObjectKey, opts ...client.GetOption) (O, error) {
+	obj := t.newObj()
+	if err := t.c.Get(ctx, key, obj, opts...); err != nil {
+		var zero O
+		return zero, err
+	}
+	return obj, nil
+}
+
+// List returns the resources matching the supplied options, such as
+// client.InNamespace or client.MatchingLabels.
+func (t Typed[O, L]) List(ctx context.Context, opts ...client.ListOption) (L, error) {
+	list := t.newList()
+	if err := t.c.List(ctx, list, opts...); err != nil {
+		var zero L
+		return zero, err
+	}
+	return list, nil
+}
+
+// Watch watches the resources matching the supplied options. The objects of
+// its events are O, except for the *metav1.Status of error events.
+func (t Typed[O, L]) Watch(ctx context.Context, opts ...client.ListOption) (watch.Interface, error) {
+	return t.c.Watch(ctx, t.newList(), opts...)
+}
+
+// Cluster are the clients of the cluster scoped kinds.
+type Cluster struct {
+	AccessKeys                  Typed[*userv1alpha1cluster.AccessKey, *userv1alpha1cluster.AccessKeyList]
+	Groups                      Typed[*userv1alpha1cluster.Group, *userv1alpha1cluster.GroupList]
+	GroupQualityOfServiceLimits Typed[*userv1alpha1cluster.GroupQualityOfServiceLimits, *userv1alpha1cluster.GroupQualityOfServiceLimitsList]
+	GroupRatingPlans            Typed[*userv1alpha1cluster.GroupRatingPlan, *userv1alpha1cluster.GroupRatingPlanList]
+	IAMUsers                    Typed[*userv1alpha1cluster.IAMUser, *userv1alpha1cluster.IAMUserList]
+	Users                       Typed[*userv1alpha1cluster.User, *userv1alpha1cluster.UserList]
+	UserQualityOfServiceLimits  Typed[*userv1alpha1cluster.UserQualityOfServiceLimits, *userv1alpha1cluster.UserQualityOfServiceLimitsList]
+
+	ProviderConfigs      Typed[*apisv1alpha1cluster.ProviderConfig, *apisv1alpha1cluster.ProviderConfigList]
+	ProviderConfigUsages Typed[*apisv1alpha1cluster.ProviderConfigUsage, *apisv1alpha1cluster.ProviderConfigUsageList]
+	ProviderStatuses     Typed[*apisv1alpha1cluster.ProviderStatus, *apisv1alpha1cluster.ProviderStatusList]
+}
+
+// Namespaced are the clients of the namespaced kinds, along with the cluster
+// scoped provider configurations they may refer to.
+type Namespaced struct {
+	AccessKeys                  Typed[*userv1alpha1namespaced.AccessKey, *userv1alpha1namespaced.AccessKeyList]
+	Groups                      Typed[*userv1alpha1namespaced.Group, *userv1alpha1namespaced.GroupList]
+	GroupQualityOfServiceLimits Typed[*userv1alpha1namespaced.GroupQualityOfServiceLimits, *userv1alpha1namespaced.GroupQualityOfServiceLimitsList]
+	GroupRatingPlans            Typed[*userv1alpha1namespaced.GroupRatingPlan, *userv1alpha1namespaced.GroupRatingPlanList]
+	IAMUsers                    Typed[*userv1alpha1namespaced.IAMUser, *userv1alpha1namespaced.IAMUserList]
+	Users                       Typed[*userv1alpha1namespaced.User, *userv1alpha1namespaced.UserList]
+	UserQualityOfServiceLimits  Typed[*userv1alpha1namespaced.UserQualityOfServiceLimits, *userv1alpha1namespaced.UserQualityOfServiceLimitsList]
+
+	ProviderConfigs             Typed[*apisv1alpha1namespaced.ProviderConfig, *apisv1alpha1namespaced.ProviderConfigList]
+	ProviderConfigUsages        Typed[*apisv1alpha1namespaced.ProviderConfigUsage, *apisv1alpha1namespaced.ProviderConfigUsageList]
+	ClusterProviderConfigs      Typed[*apisv1alpha1namespaced.ClusterProviderConfig, *apisv1alpha1namespaced.ClusterProviderConfigList]
+	ClusterProviderConfigUsages Typed[*apisv1alpha1namespaced.ClusterProviderConfigUsage, *apisv1alpha1namespaced.ClusterProviderConfigUsageList]
+}
+
+// A Clientset has a typed client of every kind of the provider.
+type Clientset struct {
+	Cluster    Cluster
+	Namespaced Namespaced
+}
+
+// New returns a Clientset talking to the API server of the supplied config.
+func New(cfg *rest.Config) (*Clientset, error) {
+	s, err := NewScheme()
+	if err != nil {
+		return nil, err
+	}
+	c, err := client.NewWithWatch(cfg, client.Options{Scheme: s})
+	if err != nil {
+		return nil, errors.Wrap(err, errNewClient)
+	}
+	return NewForClient(c), nil
+}
+
+// NewForClient returns a Clientset using the supplied client, whose scheme
+// must include the kinds of the provider, as those of NewScheme do.
+func NewForClient(c client.WithWatch) *Clientset {
+	return &Clientset{
+		Cluster: Cluster{
+			AccessKeys:                  newTyped[userv1alpha1cluster.AccessKey, userv1alpha1cluster.AccessKeyList](c),
+			Groups:                      newTyped[userv1alpha1cluster.Group, userv1alpha1cluster.GroupList](c),
+			GroupQualityOfServiceLimits: newTyped[userv1alpha1cluster.GroupQualityOfServiceLimits, userv1alpha1cluster.GroupQualityOfServiceLimitsList](c),
+			GroupRatingPlans:            newTyped[userv1alpha1cluster.GroupRatingPlan, userv1alpha1cluster.GroupRatingPlanList](c),
+			IAMUsers:                    newTyped[userv1alpha1cluster.IAMUser, userv1alpha1cluster.IAMUserList](c),
+			Users:                       newTyped[userv1alpha1cluster.User, userv1alpha1cluster.UserList](c),
+			UserQualityOfServiceLimits:  newTyped[userv1alpha1cluster.UserQualityOfServiceLimits, userv1alpha1cluster.UserQualityOfServiceLimitsList](c),
+
+			ProviderConfigs:      newTyped[apisv1alpha1cluster.ProviderConfig, apisv1alpha1cluster.ProviderConfigList](c),
+			ProviderConfigUsages: newTyped[apisv1alpha1cluster.ProviderConfigUsage, apisv1alpha1cluster.ProviderConfigUsageList](c),
+			ProviderStatuses:     newTyped[apisv1alpha1cluster.ProviderStatus, apisv1alpha1cluster.ProviderStatusList](c),
+		},
+		Namespaced: Namespaced{
+			AccessKeys:                  newTyped[userv1alpha1namespaced.AccessKey, userv1alpha1namespaced.AccessKeyList](c),
+			Groups:                      newTyped[userv1alpha1namespaced.Group, userv1alpha1namespaced.GroupList](c),
+			GroupQualityOfServiceLimits: newTyped[userv1alpha1namespaced.GroupQualityOfServiceLimits, userv1alpha1namespaced.GroupQualityOfServiceLimitsList](c),
+			GroupRatingPlans:            newTyped[userv1alpha1namespaced.GroupRatingPlan, userv1alpha1namespaced.GroupRatingPlanList](c),
+			IAMUsers:                    newTyped[userv1alpha1namespaced.IAMUser, userv1alpha1namespaced.IAMUserList](c),
+			Users:                       newTyped[userv1alpha1namespaced.User, userv1alpha1namespaced.UserList](c),
+			UserQualityOfServiceLimits:  newTyped[userv1alpha1namespaced.UserQualityOfServiceLimits, userv1alpha1namespaced.UserQualityOfServiceLimitsList](c),
+
+			ProviderConfigs:             newTyped[apisv1alpha1namespaced.ProviderConfig, apisv1alpha1namespaced.ProviderConfigList](c),
+			ProviderConfigUsages:        newTyped[apisv1alpha1namespaced.ProviderConfigUsage, apisv1alpha1namespaced.ProviderConfigUsageList](c),
+			ClusterProviderConfigs:      newTyped[apisv1alpha1namespaced.ClusterProviderConfig, apisv1alpha1namespaced.ClusterProviderConfigList](c),
+			ClusterProviderConfigUsages: newTyped[apisv1alpha1namespaced.ClusterProviderConfigUsage, apisv1alpha1namespaced.ClusterProviderConfigUsageList](c),
+		},
+	}
+}
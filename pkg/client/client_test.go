@@ -0,0 +1,141 @@
+package client
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+
+	userv1alpha1cluster "github.com/statnett/provider-cloudian/apis/cluster/user/v1alpha1"
+	userv1alpha1namespaced "github.com/statnett/provider-cloudian/apis/namespaced/user/v1alpha1"
+)
+
+// consume is how a downstream operator reads the resources of the provider:
+// it lists the namespaced users of a team, gets their cluster scoped group
+// and watches for the next user.
+func consume(t *testing.T, cs *Clientset, created func()) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	users, err := cs.Namespaced.Users.List(ctx, client.InNamespace("team-a"))
+	if err != nil {
+		t.Fatalf("Users.List(...): %v", err)
+	}
+	if len(users.Items) != 1 || users.Items[0].Name != "alice" {
+		t.Fatalf("Users.List(...): want user alice of team-a, got %+v", users.Items)
+	}
+
+	group, err := cs.Cluster.Groups.Get(ctx, client.ObjectKey{Name: "qa"})
+	if err != nil {
+		t.Fatalf("Groups.Get(...): %v", err)
+	}
+	if group.Spec.ForProvider.GroupID != "QA" {
+		t.Errorf("Groups.Get(...): want group QA, got %q", group.Spec.ForProvider.GroupID)
+	}
+
+	w, err := cs.Namespaced.Users.Watch(ctx, client.InNamespace("team-a"))
+	if err != nil {
+		t.Fatalf("Users.Watch(...): %v", err)
+	}
+	defer w.Stop()
+	created()
+	for {
+		select {
+		case e := <-w.ResultChan():
+			u, ok := e.Object.(*userv1alpha1namespaced.User)
+			if !ok {
+				t.Fatalf("Users.Watch(...): want *User events, got %T", e.Object)
+			}
+			if e.Type == watch.Added && u.Name == "bob" {
+				return
+			}
+		case <-ctx.Done():
+			t.Fatal("Users.Watch(...): want an event adding bob")
+		}
+	}
+}
+
+func newNamespacedUser(name string) *userv1alpha1namespaced.User {
+	u := &userv1alpha1namespaced.User{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: name}}
+	u.Spec.ForProvider.GroupID = "QA"
+	return u
+}
+
+func newClusterGroup() *userv1alpha1cluster.Group {
+	g := &userv1alpha1cluster.Group{ObjectMeta: metav1.ObjectMeta{Name: "qa"}}
+	g.Spec.ForProvider.GroupID = "QA"
+	return g
+}
+
+func TestClientset(t *testing.T) {
+	s, err := NewScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := fake.NewClientBuilder().WithScheme(s).WithObjects(newNamespacedUser("alice"), newClusterGroup()).Build()
+
+	consume(t, NewForClient(c), func() {
+		if err := c.Create(context.Background(), newNamespacedUser("bob")); err != nil {
+			t.Fatalf("c.Create(...): %v", err)
+		}
+	})
+}
+
+// TestClientsetEnvtest runs the consumer against a real API server serving
+// the CRDs of the provider. It needs the binaries of envtest, found through
+// KUBEBUILDER_ASSETS.
+func TestClientsetEnvtest(t *testing.T) {
+	if os.Getenv("KUBEBUILDER_ASSETS") == "" {
+		t.Skip("KUBEBUILDER_ASSETS is not set")
+	}
+	env := &envtest.Environment{
+		CRDDirectoryPaths:     []string{filepath.Join("..", "..", "package", "crds")},
+		ErrorIfCRDPathMissing: true,
+	}
+	cfg, err := env.Start()
+	if err != nil {
+		t.Fatalf("env.Start(): %v", err)
+	}
+	t.Cleanup(func() { _ = env.Stop() })
+
+	cs, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New(...): %v", err)
+	}
+	s, err := NewScheme()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := corev1.AddToScheme(s); err != nil {
+		t.Fatal(err)
+	}
+	c, err := client.New(cfg, client.Options{Scheme: s})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for _, o := range []client.Object{
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a"}},
+		newNamespacedUser("alice"),
+		newClusterGroup(),
+	} {
+		if err := c.Create(ctx, o); err != nil {
+			t.Fatalf("c.Create(...): %v", err)
+		}
+	}
+
+	consume(t, cs, func() {
+		if err := c.Create(ctx, newNamespacedUser("bob")); err != nil {
+			t.Fatalf("c.Create(...): %v", err)
+		}
+	})
+}